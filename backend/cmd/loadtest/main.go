@@ -0,0 +1,249 @@
+// Command loadtest seeds a running server with synthetic users and fires a mix of
+// calculate and feedback requests at it, reporting latency percentiles, so the capacity of
+// a small host such as a Raspberry Pi can be measured before and after a change.
+//
+// Seeded records are stored with the simulator source, under user IDs starting with
+// -prefix, so they can be told apart from real history and weighted down by
+// PREDICTION_SOURCE_WEIGHTS.
+//
+// Start the server with BENCH_FROZEN_TIME set to compare runs: with its clock stopped, the
+// same seed gets the same predictions, however long apart the runs are.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/client"
+)
+
+// seedBatch is the most records one /api/feedback/batch request accepts
+const seedBatch = 500
+
+type options struct {
+	users       int
+	records     int
+	requests    int
+	concurrency int
+	feedback    float64
+	prefix      string
+	seed        int64
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "server to load")
+	var opts options
+	flag.IntVar(&opts.users, "users", 20, "synthetic users")
+	flag.IntVar(&opts.records, "records", 100, "records seeded per user; 0 skips seeding")
+	flag.IntVar(&opts.requests, "requests", 2000, "calculate and feedback requests fired after seeding")
+	flag.IntVar(&opts.concurrency, "concurrency", 8, "requests in flight at once")
+	flag.Float64Var(&opts.feedback, "feedback", 0.3, "share (0-1) of the requests that submit feedback instead of calculating")
+	flag.StringVar(&opts.prefix, "prefix", "loadtest-", "prefix of the synthetic user IDs")
+	flag.Int64Var(&opts.seed, "seed", 1, "random seed, so runs are comparable")
+	flag.Parse()
+
+	if opts.users < 1 || opts.records < 0 || opts.requests < 0 || opts.concurrency < 1 || opts.feedback < 0 || opts.feedback > 1 {
+		log.Fatal("loadtest: -users and -concurrency must be positive, -records and -requests not negative and -feedback between 0 and 1")
+	}
+
+	c := client.New(*baseURL)
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(opts.seed))
+
+	if opts.records > 0 {
+		start := time.Now()
+		if err := seed(ctx, c, rng, opts); err != nil {
+			log.Fatalf("loadtest: seeding: %v", err)
+		}
+		log.Printf("Seeded %d users × %d records in %s", opts.users, opts.records, time.Since(start).Round(time.Millisecond))
+	}
+
+	if opts.requests > 0 {
+		start := time.Now()
+		results := fire(ctx, c, rng, opts)
+		report(results, time.Since(start))
+	}
+}
+
+// shower is one synthetic shower, heated for somewhere around what the user needs
+type shower struct {
+	duration    float64
+	temperature float64
+	heatingTime float64
+}
+
+func newShower(rng *rand.Rand, userIndex int) shower {
+	s := shower{
+		duration:    math.Round((5+rng.Float64()*15)*10) / 10,
+		temperature: math.Round((-5+rng.Float64()*35)*10) / 10,
+	}
+	s.heatingTime = math.Max(5, math.Round(s.idealHeating(userIndex)*(0.8+rng.Float64()*0.4)))
+	return s
+}
+
+// idealHeating is the heating the user's heater needs for the shower: users differ in how
+// much heating a minute of showering takes, and everyone needs more when it is cold outside
+func (s shower) idealHeating(userIndex int) float64 {
+	perMinute := 1 + float64(userIndex%5)*0.25
+	return s.duration * perMinute * (1 + (20-s.temperature)/60)
+}
+
+// satisfaction rates a shower against the heating it actually needed
+func (s shower) satisfaction(rng *rand.Rand, userIndex int) float64 {
+	ideal := s.idealHeating(userIndex)
+	rating := models.SatisfactionPerfect + (s.heatingTime-ideal)/ideal*100 + rng.NormFloat64()*5
+	return math.Max(models.SatisfactionMin, math.Min(models.SatisfactionMax, math.Round(rating)))
+}
+
+func (o options) userID(index int) string {
+	return fmt.Sprintf("%s%d", o.prefix, index)
+}
+
+// seed stores opts.records showers for each user, one a day going back from today
+func seed(ctx context.Context, c *client.Client, rng *rand.Rand, opts options) error {
+	now := time.Now()
+	for u := 0; u < opts.users; u++ {
+		records := make([]map[string]interface{}, 0, opts.records)
+		for i := 0; i < opts.records; i++ {
+			s := newShower(rng, u)
+			records = append(records, map[string]interface{}{
+				"date":               now.AddDate(0, 0, -opts.records+i),
+				"showerDuration":     s.duration,
+				"averageTemperature": s.temperature,
+				"heatingTime":        s.heatingTime,
+				"satisfaction":       s.satisfaction(rng, u),
+				"source":             models.RecordSourceSimulator,
+			})
+		}
+		for len(records) > 0 {
+			n := min(seedBatch, len(records))
+			body := map[string]interface{}{"userId": opts.userID(u), "records": records[:n]}
+			if err := c.SubmitFeedbackBatch(ctx, body, nil); err != nil {
+				return fmt.Errorf("user %s: %w", opts.userID(u), err)
+			}
+			records = records[n:]
+		}
+	}
+	return nil
+}
+
+// result is the outcome of one request
+type result struct {
+	kind    string
+	latency time.Duration
+	err     error
+}
+
+// request is a request to fire, drawn up front so the random sequence doesn't depend on
+// goroutine scheduling
+type request struct {
+	kind         string
+	user         int
+	shower       shower
+	satisfaction float64
+}
+
+// fire sends opts.requests requests over opts.concurrency workers
+func fire(ctx context.Context, c *client.Client, rng *rand.Rand, opts options) []result {
+	requests := make(chan request)
+	go func() {
+		defer close(requests)
+		for i := 0; i < opts.requests; i++ {
+			r := request{kind: "calculate", user: rng.Intn(opts.users)}
+			r.shower = newShower(rng, r.user)
+			if rng.Float64() < opts.feedback {
+				r.kind = "feedback"
+				r.satisfaction = r.shower.satisfaction(rng, r.user)
+			}
+			requests <- r
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]result, 0, opts.requests)
+	for w := 0; w < opts.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range requests {
+				start := time.Now()
+				var err error
+				if r.kind == "feedback" {
+					err = c.SubmitFeedback(ctx, map[string]interface{}{
+						"userId":             opts.userID(r.user),
+						"showerDuration":     r.shower.duration,
+						"averageTemperature": r.shower.temperature,
+						"heatingTime":        r.shower.heatingTime,
+						"satisfaction":       r.satisfaction,
+						"source":             models.RecordSourceSimulator,
+					}, nil)
+				} else {
+					err = c.CalculateHeatingTime(ctx, map[string]interface{}{
+						"userId":      opts.userID(r.user),
+						"duration":    r.shower.duration,
+						"temperature": r.shower.temperature,
+					}, nil)
+				}
+				mu.Lock()
+				results = append(results, result{kind: r.kind, latency: time.Since(start), err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// report prints throughput, errors and latency percentiles per request kind
+func report(results []result, elapsed time.Duration) {
+	byKind := map[string][]result{"all": results}
+	for _, r := range results {
+		byKind[r.kind] = append(byKind[r.kind], r)
+	}
+
+	fmt.Printf("%d requests in %s (%.1f/s)\n\n", len(results), elapsed.Round(time.Millisecond), float64(len(results))/elapsed.Seconds())
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "kind\trequests\terrors\tp50\tp90\tp99\tmax\t")
+	for _, kind := range []string{"calculate", "feedback", "all"} {
+		rs := byKind[kind]
+		if len(rs) == 0 {
+			continue
+		}
+		latencies := make([]time.Duration, 0, len(rs))
+		errors := 0
+		for _, r := range rs {
+			latencies = append(latencies, r.latency)
+			if r.err != nil {
+				errors++
+			}
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", kind, len(rs), errors,
+			percentile(latencies, 0.5), percentile(latencies, 0.9), percentile(latencies, 0.99), latencies[len(latencies)-1].Round(time.Microsecond))
+	}
+	w.Flush()
+
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Printf("\nfirst error: %v\n", r.err)
+			break
+		}
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0-1) of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)].Round(time.Microsecond)
+}
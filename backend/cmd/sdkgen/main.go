@@ -0,0 +1,153 @@
+// Command sdkgen generates the endpoint methods of pkg/client from the server's route table,
+// so the client can never call a route the server doesn't register.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"heat-logger/internal/config"
+	router "heat-logger/internal/routes"
+	"heat-logger/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/logger"
+)
+
+var handlerName = regexp.MustCompile(`\(\*(\w+)\)\.(\w+)-fm$`)
+
+type endpoint struct {
+	Name   string
+	Method string
+	Path   string
+	Params []string
+	Body   bool
+}
+
+func main() {
+	out := flag.String("out", "pkg/client/routes_gen.go", "file to write")
+	check := flag.Bool("check", false, "fail if the file is out of date instead of writing it")
+	flag.Parse()
+
+	code, err := generate()
+	if err != nil {
+		log.Fatalf("sdkgen: %v", err)
+	}
+
+	if *check {
+		existing, err := os.ReadFile(*out)
+		if err != nil || !bytes.Equal(existing, code) {
+			log.Fatalf("sdkgen: %s is out of date, run `make sdk`", *out)
+		}
+		return
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		log.Fatalf("sdkgen: %v", err)
+	}
+}
+
+// generate builds the router with every optional feature enabled and renders its routes
+func generate() ([]byte, error) {
+	gin.SetMode(gin.ReleaseMode)
+	tmp, err := os.MkdirTemp("", "sdkgen")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	cfg := &config.Config{
+		Database:    config.DatabaseConfig{Path: ":memory:"},
+		CORS:        config.CORSConfig{AllowedOrigins: []string{"http://localhost"}},
+		Prediction:  config.PredictionConfig{Version: "v2", Canary: config.CanaryConfig{Version: "v1"}},
+		Export:      config.ExportConfig{Dir: tmp},
+		OIDC:        config.OIDCConfig{IssuerURL: "http://sdkgen.invalid", ClientID: "sdkgen"},
+		Share:       config.ShareConfig{Secret: "sdkgen"},
+		Metrics:     config.MetricsConfig{Enabled: true},
+		Sync:        config.SyncConfig{Token: "sdkgen"},
+		Webhook:     config.WebhookConfig{Secret: "sdkgen"},
+		Grid:        config.DemandResponseConfig{Token: "sdkgen"},
+		PublicStats: config.PublicStatsConfig{Enabled: true},
+	}
+	logger.Default = logger.Discard
+	if err := database.InitDatabase(cfg); err != nil {
+		return nil, err
+	}
+
+	var endpoints []endpoint
+	seen := map[string]bool{}
+	for _, route := range router.SetupRouter(cfg).Routes() {
+		ep := endpoint{Method: route.Method, Path: route.Path, Body: route.Method != "GET"}
+		if m := handlerName.FindStringSubmatch(route.Handler); m != nil {
+			ep.Name = m[2]
+			if seen[ep.Name] {
+				ep.Name = strings.TrimSuffix(m[1], "Handler") + ep.Name
+			}
+		} else {
+			ep.Name = nameFromPath(route.Path)
+		}
+		if seen[ep.Name] {
+			return nil, fmt.Errorf("duplicate client method %s for %s %s", ep.Name, route.Method, route.Path)
+		}
+		seen[ep.Name] = true
+
+		for _, segment := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(segment, ":") {
+				ep.Params = append(ep.Params, segment[1:])
+			}
+		}
+		endpoints = append(endpoints, ep)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+
+	var buf bytes.Buffer
+	if err := clientTemplate.Execute(&buf, endpoints); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// nameFromPath names inline handlers after the last static path segment, e.g. /api/health => Health
+func nameFromPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	last := segments[len(segments)-1]
+	r := []rune(last)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+var clientTemplate = template.Must(template.New("client").Funcs(template.FuncMap{
+	"pathExpr": func(ep endpoint) string {
+		expr := `"` + ep.Path + `"`
+		for _, p := range ep.Params {
+			expr = strings.Replace(expr, ":"+p, `" + url.PathEscape(`+p+`) + "`, 1)
+		}
+		return strings.TrimSuffix(expr, ` + ""`)
+	},
+}).Parse(`// Code generated by cmd/sdkgen; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/url"
+)
+{{range .}}
+// {{.Name}} calls {{.Method}} {{.Path}}
+func (c *Client) {{.Name}}(ctx context.Context, {{range .Params}}{{.}} string, {{end}}{{if .Body}}body{{else}}query url.Values{{end}}, out interface{}) error {
+	return c.do(ctx, "{{.Method}}", {{pathExpr .}}, {{if .Body}}nil, body{{else}}query, nil{{end}}, out)
+}
+{{end}}`))
@@ -0,0 +1,11 @@
+//go:build !release
+
+package main
+
+import "io/fs"
+
+// bundle returns nothing outside release builds: the web app is served by Vite and settings
+// come from the environment and .env files only
+func bundle() (fs.FS, string) {
+	return nil, ""
+}
@@ -0,0 +1,23 @@
+//go:build release
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Release builds carry the web app and their default settings, so the binary is all an
+// install needs. `make release` builds the app into web first.
+
+//go:embed all:web
+var web embed.FS
+
+//go:embed release.env
+var releaseEnv string
+
+// bundle returns the web app's built files and the settings the build bakes in
+func bundle() (fs.FS, string) {
+	files, _ := fs.Sub(web, "web") // only fails for an invalid directory name
+	return files, releaseEnv
+}
@@ -1,30 +1,97 @@
 package main
 
 import (
+	"flag"
 	"heat-logger/internal/config"
+	"heat-logger/internal/demo"
+	"heat-logger/internal/handler"
 	router "heat-logger/internal/routes"
+	"heat-logger/internal/services"
 	"heat-logger/pkg/database"
+	"heat-logger/pkg/logfile"
 	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Load configuration
+	dataDir := flag.String("data-dir", "", "directory for the database, backups, exports and a .env file (default: the working directory, or DATA_DIR)")
+	demoFlag := flag.Bool("demo", false, "fill an empty database with a year of example showers")
+	flag.Parse()
+	if *dataDir != "" {
+		os.Setenv("DATA_DIR", *dataDir)
+	}
+
+	// Load configuration, over the settings a release build bakes in
+	frontend, defaults := bundle()
+	config.SetDefaultEnv(defaults)
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal("Failed to load configuration:", err)
 	}
+	// gin reads GIN_MODE as it loads, before any .env file or baked-in setting could set it
+	gin.SetMode(cfg.App.GinMode)
+	if cfg.App.DataDir != "" {
+		if err := os.MkdirAll(cfg.App.DataDir, 0o755); err != nil {
+			log.Fatal("Failed to create data directory:", err)
+		}
+	}
 
-	// Initialize database
-	if err := database.InitDatabase(cfg); err != nil {
-		log.Fatal("Failed to initialize database:", err)
+	// Write the server and request logs to a rotating file instead of stderr, if one is set
+	if cfg.Logging.File != "" {
+		logFile := logfile.New(cfg.Logging.File, logfile.OptionsFrom(cfg.Logging.Rotation))
+		log.SetOutput(logFile)
+		gin.DefaultWriter = logFile
+		gin.DefaultErrorWriter = logFile
 	}
 
-	// Setup and start router
-	r := router.SetupRouter(cfg)
+	// Listen straight away so probes can tell a slow database from a dead server; /readyz
+	// fails until the database is migrated and the router is in place
+	gate := handler.NewStartupGate()
+	go func() {
+		// Initialize database
+		if err := database.InitDatabase(cfg); err != nil {
+			log.Fatal("Failed to initialize database:", err)
+		}
+		if *demoFlag {
+			if err := seedDemo(); err != nil {
+				log.Printf("Warning: Failed to seed demo showers: %v", err)
+			}
+		}
+
+		// Setup router, serving the web app too when the build bundles it
+		r := router.SetupRouter(cfg)
+		if frontend != nil {
+			if err := router.ServeFrontend(r, frontend); err != nil {
+				log.Printf("Warning: Web app not bundled, serving the API only: %v", err)
+			}
+		}
+		gate.Open(r)
+		log.Printf("Server ready")
+	}()
 
 	log.Printf("Using predictor version: %s", cfg.Prediction.Version)
 	log.Printf("Starting server on %s", cfg.GetServerAddress())
-	if err := r.Run(cfg.GetServerAddress()); err != nil {
+	if err := http.ListenAndServe(cfg.GetServerAddress(), gate); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// seedDemo fills an empty database with the demo showers
+func seedDemo() error {
+	showers, err := demo.Showers(time.Now())
+	if err != nil {
+		return err
+	}
+	seeded, err := services.NewRecordService().SeedRecords(showers)
+	if err != nil {
+		return err
+	}
+	if seeded > 0 {
+		log.Printf("Seeded %d demo showers", seeded)
+	}
+	return nil
+}
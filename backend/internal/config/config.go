@@ -5,34 +5,231 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server     ServerConfig
-	Database   DatabaseConfig
-	Prediction PredictionConfig
-	CORS       CORSConfig
-	Logging    LoggingConfig
-	App        AppConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	Prediction    PredictionConfig
+	CORS          CORSConfig
+	Logging       LoggingConfig
+	App           AppConfig
+	Export        ExportConfig
+	Admin         AdminConfig
+	Setup         SetupConfig
+	OIDC          OIDCConfig
+	Share         ShareConfig
+	Households    HouseholdConfig
+	Metrics       MetricsConfig
+	SLO           SLOConfig
+	Sync          SyncConfig
+	Device        DeviceConfig
+	Records       RecordsConfig
+	Temperature   TemperatureConfig
+	Prices        PricesConfig
+	Solar         SolarConfig
+	Feedback      FeedbackConfig
+	Notifications NotificationConfig
+	Efficiency    EfficiencyConfig
+	Webhook       WebhookConfig
+	Showers       ShowerDetectionConfig
+	Inference     SatisfactionInferenceConfig
+	Sanitation    SanitationConfig
+	Reports       ReportsConfig
+	Events        EventsConfig
+	Hooks         HooksConfig
+	Grid          DemandResponseConfig
+	Trace         TraceConfig
+	PublicStats   PublicStatsConfig
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port int
-	Host string
+	Port         int
+	Host         string
+	MaxBodyKB    float64            // largest request body accepted, in KiB; 0 disables the limit
+	BodyLimitsKB map[string]float64 // per route overrides of MaxBodyKB, e.g. /api/sync/import=16384
 }
 
 // DatabaseConfig holds database-related configuration
 type DatabaseConfig struct {
-	Path   string
-	Driver string
+	Path               string
+	Driver             string
+	SlowQueryThreshold time.Duration // queries at least this slow are logged; 0 disables
+	BackupDir          string        // where admin backups are written
+	Health             DatabaseHealthConfig
+	Startup            DatabaseStartupConfig
+	Logs               LogStoreConfig
+}
+
+// Where prediction and audit logs are kept
+const (
+	LogStoreDatabase = "database" // in the operational database
+	LogStoreSQLite   = "sqlite"   // in a SQLite file of their own
+	LogStoreJSONL    = "jsonl"    // appended to JSONL files, and kept in the operational database for a while
+)
+
+// LogStoreConfig routes prediction and audit logs, which grow much faster than records, away
+// from the operational database
+type LogStoreConfig struct {
+	Store     string        // LogStoreDatabase, LogStoreSQLite or LogStoreJSONL
+	Path      string        // the SQLite file, or the directory JSONL files are written to
+	Retention time.Duration // with JSONL, how long entries stay queryable in the operational database
+}
+
+// What the server does when the database isn't available within the startup timeout
+const (
+	StartupExit = "exit" // give up so a supervisor can restart the server
+	StartupWait = "wait" // keep retrying; /readyz keeps failing until the database is up
+)
+
+// DatabaseStartupConfig controls how long startup waits for a slow database, e.g. on a NAS
+// that mounts after the server starts
+type DatabaseStartupConfig struct {
+	Timeout    time.Duration // how long to keep retrying; 0 tries once
+	Backoff    time.Duration // wait before the first retry, doubling up to MaxBackoff
+	MaxBackoff time.Duration
+	OnTimeout  string // StartupExit or StartupWait
+}
+
+// DatabaseHealthConfig controls read-only fallback when the database stops accepting writes
+type DatabaseHealthConfig struct {
+	FailureThreshold int           // consecutive storage failures before switching to read-only
+	ProbeInterval    time.Duration // how often a read-only database is probed for recovery
+	BufferSize       int           // feedback records held in memory while read-only
 }
 
 // PredictionConfig holds prediction service configuration
 type PredictionConfig struct {
-	Version   string
-	ModelPath string
+	Version       string
+	ModelPath     string
+	Timeout       time.Duration
+	Workers       int                // predictions run at once; 0 runs every request on its own goroutine
+	Queue         int                // requests waiting for a worker before further ones are shed
+	SourceWeights map[string]float64 // per record source weight overrides, e.g. simulator=0.25
+	Confidence    map[string]float64 // per feedback source confidence overrides, e.g. inferred=0.5
+	Inlet         InletConfig
+	Canary        CanaryConfig
+	Guard         GuardConfig
+	SafetyFloor   SafetyFloorConfig
+	Warmup        WarmupConfig
+	Reliability   ReliabilityConfig
+	GlobalRefresh time.Duration // how often v2 reloads its in-memory snapshot of everyone's records; 0 reads them on every prediction
+	IndexMemoryMB float64       // memory v2's in-memory candidate index may take, unless it shares the warm-up budget; 0 disables it
+	Seed          int64         // seeds the randomness predictions use; 0 picks a seed at startup
+	Calibration   bool          // stretch other users' ratings to the population's spread before learning from them
+	V2            V2Config
+	MinMinutes    float64 // predictions are clamped to [MinMinutes, MaxMinutes]
+	MaxMinutes    float64
+}
+
+// HeatingBounds returns the range predictions are clamped to, using the defaults when unset
+func (c PredictionConfig) HeatingBounds() (float64, float64) {
+	if c.MinMinutes <= 0 || c.MaxMinutes <= c.MinMinutes {
+		return 5, 120
+	}
+	return c.MinMinutes, c.MaxMinutes
+}
+
+// InletConfig describes the seasonal cold-water inlet temperature used when it isn't measured
+type InletConfig struct {
+	Mean      float64 // yearly average, °C
+	Amplitude float64 // seasonal swing around the average, °C
+	PeakDay   int     // day of the year the inlet is warmest
+}
+
+// V2Config tunes the v2 Gaussian kNN predictor
+type V2Config struct {
+	SigmaDuration          float64 // minutes
+	SigmaTemp              float64 // °C
+	SigmaInlet             float64 // °C
+	K                      int     // neighbors in the final estimate
+	MinK                   int
+	AnchorEpsilon          float64 // distance of satisfaction from 50 that still counts as a perfect anchor
+	AnchorBoost            float64 // weight multiplier for anchors
+	AnchorBlend            float64 // 0-1, how far anchors pull the estimate
+	RecencyHalfLifeDays    float64
+	UserBoost              float64
+	FixedUserBoost         bool
+	AdaptiveBoostWindow    int
+	StepCapFraction        float64
+	NeverCold              bool
+	Rounding               string  // smart, nearest or ceil
+	RoundingStep           float64 // minutes
+	RoundingHysteresis     float64 // fraction of a step
+	ExplorationMultipliers []float64
+	ExplorationJitter      float64 // fraction each exploration probe varies by at random
+	DisableExploration     bool
+	HardwareChangeDecay    float64
+	ChangePointMinSegment  int
+	ChangePointMinShift    float64
+	ChangePointMinScore    float64
+	AnchorDiagnostics      int     // recent predictions kept for anchor diagnostics
+	MinContributors        int     // distinct other users a prediction led by their records needs
+	MinPersonalRecords     int     // training records a user needs before their own history is used; 0 uses it from the first
+	CrossRegionWeight      float64 // weight kept by other users' records from another climate region
+	GlobalDurationBand     float64 // minutes other users' shower durations are rounded to in the global pool; 0 keeps them exact
+	GlobalTemperatureBand  float64 // °C other users' temperatures are rounded to in the global pool; 0 keeps them exact
+	BucketSplit            int     // training records that split a context bucket; 0 keeps the fixed sigmas
+}
+
+// CanaryConfig controls serving a second predictor version to a share of users
+type CanaryConfig struct {
+	Version    string  // predictor version under test; empty disables canarying
+	Percent    int     // share of users (0-100) served by the canary
+	Margin     float64 // rollback when the canary's rolling relative error exceeds the incumbent's by more than this
+	Window     int     // feedback samples in each version's rolling error
+	MinSamples int     // samples each version needs before rollback is considered
+}
+
+// Enabled reports whether a canary version is configured
+func (c CanaryConfig) Enabled() bool {
+	return c.Version != ""
+}
+
+// WarmupConfig holds the record set cache the predictors read through and its warm-up on startup
+type WarmupConfig struct {
+	Users    int     // most active users whose record sets are loaded at startup; 0 disables the cache
+	MemoryMB float64 // memory the cached record sets, and the candidate index when there is one, may take
+}
+
+// Enabled reports whether record sets are cached and warmed up
+func (c WarmupConfig) Enabled() bool {
+	return c.Users > 0
+}
+
+// ReliabilityConfig holds how users whose feedback contradicts itself are kept out of
+// everyone else's predictions
+type ReliabilityConfig struct {
+	MinPairs int     // comparable pairs of showers needed before a user's score counts
+	Exclude  float64 // users scoring at or below this (0-1) are left out of the global pool
+	Full     float64 // users scoring at least this count fully; 0 disables scoring
+}
+
+// Enabled reports whether contributors are scored
+func (c ReliabilityConfig) Enabled() bool {
+	return c.Full > 0
+}
+
+// GuardConfig holds the rate-of-change guard on served predictions
+type GuardConfig struct {
+	MaxChange float64       // largest change, in percent, from the last value served for the same context without feedback in between; 0 disables the guard
+	Window    time.Duration // how far back the last served value is looked for
+}
+
+// Enabled reports whether predictions are checked against the last value served
+func (c GuardConfig) Enabled() bool {
+	return c.MaxChange > 0
+}
+
+// SafetyFloorConfig keeps heating from dropping too low in cold weather, when pipes and water
+// left barely warm are at risk, however hot recent showers were rated
+type SafetyFloorConfig struct {
+	Below   float64 // °C under which predictions are raised to the floor
+	Minutes float64 // floor of devices that don't set their own; 0 leaves them without one
 }
 
 // CORSConfig holds CORS-related configuration
@@ -44,58 +241,973 @@ type CORSConfig struct {
 
 // LoggingConfig holds logging-related configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level    string
+	Format   string
+	File     string // the server log is written here instead of stderr when set
+	Rotation LogRotationConfig
+}
+
+// LogRotationConfig decides when log files, the server log and JSONL log archives, are rotated
+// and how long rotated files are kept
+type LogRotationConfig struct {
+	MaxSizeMB int           // a file is rotated before it grows past this; 0 never rotates by size
+	Interval  time.Duration // a file is rotated once per interval, e.g. daily; 0 never rotates by time
+	Compress  bool          // rotated files are gzipped
+	MaxFiles  int           // rotated files kept per log, the oldest deleted first; 0 keeps any number
+	MaxAge    time.Duration // rotated files older than this are deleted; 0 keeps them
+}
+
+// ExportConfig holds asynchronous export job configuration
+type ExportConfig struct {
+	Dir      string
+	JobTTL   time.Duration
+	Throttle time.Duration
+}
+
+// AdminConfig holds admin API configuration
+type AdminConfig struct {
+	Token string `secret:"true"` // bearer token required by admin endpoints; empty disables them
+}
+
+// SetupConfig holds the first-run setup wizard configuration
+type SetupConfig struct {
+	Wizard bool // a fresh install answers only /api/setup until the wizard is finished
+}
+
+// OIDCConfig holds OpenID Connect login configuration
+type OIDCConfig struct {
+	IssuerURL         string // empty disables OIDC login
+	ClientID          string
+	ClientSecret      string `secret:"true"`
+	RedirectURL       string // must point at /api/auth/oidc/callback
+	Scopes            []string
+	GroupsClaim       string
+	AdminGroups       []string // members of any of these groups get the admin role
+	UserGroups        []string // when set, only members of these (or admin) groups may log in
+	PostLoginRedirect string
+	SessionTTL        time.Duration
+}
+
+// Enabled reports whether an OIDC provider is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+// ShareConfig holds signed share link configuration
+type ShareConfig struct {
+	Secret string        `secret:"true"` // HMAC key for share links; a random key is used when empty
+	MaxTTL time.Duration // longest a share link may stay valid, also the default
+}
+
+// HouseholdConfig holds household invite configuration
+type HouseholdConfig struct {
+	InviteTTL time.Duration // longest an invite may stay valid, also the default
+}
+
+// EventsConfig controls the live event streams dashboards and heater controllers subscribe to
+type EventsConfig struct {
+	ClientBuffer int           // events held for each client; a client that falls this far behind is disconnected
+	MaxClients   int           // concurrent subscribers across the SSE and WebSocket endpoints
+	WriteTimeout time.Duration // a client whose connection accepts no event for this long is disconnected
+	Heartbeat    time.Duration // how often an idle stream is pinged so proxies keep it open
+}
+
+// ReportsConfig controls delivery of scheduled report subscriptions
+type ReportsConfig struct {
+	SMTPHost      string // mail server reports are emailed through; empty disables email delivery
+	SMTPPort      int
+	SMTPUsername  string // empty to send without authenticating
+	SMTPPassword  string `secret:"true"`
+	SMTPFrom      string // sender address of report emails
+	WebhookSecret string `secret:"true"` // signs webhook deliveries with HMAC-SHA256 when set
+}
+
+// EmailEnabled reports whether reports can be delivered by email
+func (c ReportsConfig) EmailEnabled() bool {
+	return c.SMTPHost != ""
+}
+
+// HooksConfig enables the hooks compiled into the server, which add a deployment's own rules
+// to predictions and feedback
+type HooksConfig struct {
+	Enabled  []string           // hook names, in the order they run
+	Settings map[string]float64 // settings by hook, e.g. peak_cap.minutes=20
+}
+
+// MetricsConfig holds Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool // collect query metrics and serve them at /metrics, which has no authentication
+}
+
+// SLOConfig sets the objectives predictions are held to; a zero target leaves an objective untracked
+type SLOConfig struct {
+	AccuracyTarget    float64       // share of rated predictions whose satisfaction lands within AccuracyTolerance of 50
+	AccuracyTolerance float64       // satisfaction points either side of a perfect 50
+	LatencyTarget     float64       // share of predictions answered within LatencyThreshold
+	LatencyThreshold  time.Duration // slower answers spend the latency budget
+	Period            time.Duration // window the error budget is spent over
+}
+
+// SyncConfig holds multi-instance sync configuration
+type SyncConfig struct {
+	InstanceID string        // identifies this deployment; generated and stored on first start when empty
+	Token      string        `secret:"true"` // shared bearer token for /api/sync endpoints; empty disables sync
+	PeerURL    string        // base URL of the other instance that records are pushed to and pulled from
+	Interval   time.Duration // how often to sync with the peer automatically; 0 disables
+}
+
+// Enabled reports whether the sync endpoints are served
+func (c SyncConfig) Enabled() bool {
+	return c.Token != ""
+}
+
+// DeviceConfig holds water heater state tracking configuration
+type DeviceConfig struct {
+	ReadyTemperature    float64            // a heating device reporting at least this water temperature (°C) is ready
+	CoolDown            time.Duration      // how long a device stays cooling after it is switched off
+	InsulationHalfLife  time.Duration      // time for a tank to lose half its stored heat; 0 ignores residual heat
+	InsulationHalfLives map[string]float64 // per device half-life overrides, in hours
+	Resolutions         map[string]float64 // per device timer resolution, in minutes
+	TankSize            float64            // litres of hot water the tank holds; 0 skips capacity checks
+	FlowRate            float64            // litres of hot water a shower draws per minute
+	HeaterPower         float64            // kW the heating element draws
+}
+
+// ShowerDetectionConfig controls how showers are recognised in bathroom humidity readings
+type ShowerDetectionConfig struct {
+	Rise        float64       // rise in relative humidity (percentage points) over the baseline that starts a shower; 0 disables detection
+	Window      time.Duration // how far back the baseline, the lowest recent reading, looks
+	MinDuration time.Duration // shorter spikes, e.g. a hand wash, are ignored
+	MaxDuration time.Duration // a spike that doesn't fall within this long is a humid day, not a shower
+}
+
+// Enabled reports whether humidity readings are checked for showers
+func (c ShowerDetectionConfig) Enabled() bool {
+	return c.Rise > 0
+}
+
+// SatisfactionInferenceConfig controls how feedback is inferred for showers the user didn't rate
+type SatisfactionInferenceConfig struct {
+	Window       time.Duration // a manual boost this soon after a recorded shower means the water ran cold; 0 disables inference
+	Satisfaction float64       // the satisfaction inferred for such a shower
+}
+
+// Enabled reports whether manual boosts are turned into feedback
+func (c SatisfactionInferenceConfig) Enabled() bool {
+	return c.Window > 0
+}
+
+// SanitationConfig controls the weekly legionella cycles devices can schedule
+type SanitationConfig struct {
+	Minutes    float64       // heating time of a cycle for devices that don't set their own
+	ExcludeFor time.Duration // showers on the device this long after a cycle ends aren't learned from
+}
+
+// RecordsConfig holds feedback storage rules
+type RecordsConfig struct {
+	OnePerDay   bool   // feedback edits the user's record for the day and device instead of adding another
+	ChecksumKey string `secret:"true"` // HMAC key records are signed with so edits outside the API show up; empty disables signing
+
+	// Extra validation rules narrowing the built-in input ranges, e.g. to the local climate.
+	// Unset bounds keep the built-in ones.
+	MinDuration    *float64 // minutes
+	MaxDuration    *float64
+	MinTemperature *float64 // °C
+	MaxTemperature *float64
+
+	InfluenceDays int // records older than this are excluded from training by a daily sweep; 0 keeps them all
+	InfluenceKeep int // each user's newest records the sweep keeps however old they are
+
+	TombstoneRetention time.Duration // how long deletions stay in the change feed; 0 keeps them forever
+}
+
+// TemperatureConfig holds the temperature providers used when a prediction request has no temperature
+type TemperatureConfig struct {
+	Sources       []string      // default provider priority; users may set their own order
+	MaxAge        time.Duration // MQTT sensor readings older than this are ignored
+	Weather       WeatherConfig
+	HomeAssistant HomeAssistantConfig
+}
+
+// WeatherConfig locates the house for the Open-Meteo current weather API
+type WeatherConfig struct {
+	URL       string
+	Latitude  *float64 // both coordinates are required to enable the weather provider
+	Longitude *float64
+}
+
+// HomeAssistantConfig holds the Home Assistant entity used as a temperature source
+type HomeAssistantConfig struct {
+	URL    string // e.g. http://homeassistant.local:8123; empty disables the provider
+	Token  string `secret:"true"` // long-lived access token
+	Entity string // temperature sensor entity, e.g. sensor.bathroom_temperature
+}
+
+// PricesConfig holds the spot electricity price provider used to schedule heating
+type PricesConfig struct {
+	Provider string  // "awattar" or "nordpool"; empty disables prices
+	URL      string  // provider API base URL; empty uses the provider's public API
+	Area     string  // Nord Pool delivery area, e.g. FI or SE3
+	Currency string  // Nord Pool price currency, and the flat rate's without a provider
+	FlatRate float64 // per kWh, for hours spot prices don't reach; 0 for none
+}
+
+// Enabled reports whether a price provider is configured
+func (c PricesConfig) Enabled() bool {
+	return c.Provider != ""
+}
+
+// DemandResponseConfig holds the receiver for utility demand-response signals, which cap or
+// shift heating while the grid is under strain
+type DemandResponseConfig struct {
+	Token       string        `secret:"true"` // bearer token of the bridge that posts signals; empty disables the receiver
+	Recovery    time.Duration // showers this long after an event ends are still ignored by the learner, the tank recovering
+	MaxDuration time.Duration // longest an event may last, so a lost end signal can't hold heating back for good
+}
+
+// Enabled reports whether demand-response signals are received
+func (c DemandResponseConfig) Enabled() bool {
+	return c.Token != ""
+}
+
+// TraceConfig holds how long the steps of a heating run are kept under its correlation ID
+type TraceConfig struct {
+	Retention time.Duration // how long trace events are kept; 0 keeps them forever
+}
+
+// PublicStatsConfig holds the unauthenticated instance-wide statistics, e.g. for a homepage.
+// Figures are only published when they draw on enough users that no household's showers can
+// be read from them.
+type PublicStatsConfig struct {
+	Enabled    bool          // off by default: the endpoint needs no login
+	MinUsers   int           // distinct users a published figure must draw on
+	MinRecords int           // records a temperature band needs to be published
+	BandWidth  float64       // °C per temperature band
+	Refresh    time.Duration // how long figures are cached, so they can't be watched change shower by shower; 0 recomputes every request
+}
+
+// SolarConfig describes the house's PV system for solar-aware heating schedules
+type SolarConfig struct {
+	BaseLoad    float64       // W the house uses anyway; only production above it can heat water
+	FeedInPrice float64       // per kWh earned by exporting instead, in the price provider's currency
+	LiveWindow  time.Duration // how long a live production reading overrides the forecast
 }
 
 // AppConfig holds general application configuration
 type AppConfig struct {
 	Environment string
 	GinMode     string
+	DataDir     string     // where the database, backups, exports and models go by default; empty for the current directory
+	FrozenTime  *time.Time // bench mode: the server's clock stays at this time, for reproducible runs
+}
+
+// FeedbackConfig controls reminders to give feedback after a scheduled shower
+type FeedbackConfig struct {
+	PromptDelay time.Duration // after the scheduled shower time before a reminder is due
+	Lookback    time.Duration // showers further back than this are no longer prompted for
+}
+
+// NotificationConfig controls how notifications reach users and how often
+type NotificationConfig struct {
+	WebhookURL string `secret:"true"` // notifications for users without routes of their own are POSTed here; empty for none
+	QuietHours string // default HH:MM-HH:MM window notifications are held in, or "off"
+	DailyLimit int    // default cap on notifications sent per user per day; 0 for none
+	Email      SMTPConfig
+	Telegram   TelegramConfig
+	WebPush    WebPushConfig
+	Ntfy       NtfyConfig
+	Matrix     MatrixConfig
+	Commands   bool // the Telegram and Matrix bots answer commands such as !heat and !rate
+}
+
+// Enabled reports whether notifications of users without routes of their own are sent anywhere
+func (c NotificationConfig) Enabled() bool {
+	return c.WebhookURL != ""
+}
+
+// SMTPConfig holds the mail server notifications are emailed through
+type SMTPConfig struct {
+	Host     string // empty disables the email channel
+	Port     int
+	Username string // empty to send without authenticating
+	Password string `secret:"true"`
+	From     string
+}
+
+// TelegramConfig holds the bot notifications are sent to Telegram chats by
+type TelegramConfig struct {
+	BotToken string `secret:"true"` // empty disables the Telegram channel
+	APIURL   string
+}
+
+// MatrixConfig holds the account notifications are posted to Matrix rooms as
+type MatrixConfig struct {
+	HomeserverURL string // e.g. https://matrix.org
+	AccessToken   string `secret:"true"` // empty disables the Matrix channel
+}
+
+// NtfyConfig holds the ntfy server topics without a server of their own are published to
+type NtfyConfig struct {
+	URL string // e.g. https://ntfy.sh
+}
+
+// WebPushConfig holds the VAPID key pair browsers check web push notifications against
+type WebPushConfig struct {
+	PrivateKey string `secret:"true"` // base64url P-256 private key; empty disables the web push channel
+	Subject    string // contact for push services, a mailto: or https: URL
+}
+
+// EfficiencyConfig controls detection of water heaters that need more and more heating for
+// the same showers, e.g. from scale buildup or a failing element
+type EfficiencyConfig struct {
+	Weeks      int           // how far back the trend is fitted; 0 disables detection
+	MinRecords int           // fewer records in the window than this are not judged
+	MinRise    float64       // minimum fitted rise in required heating over the window (0.15 => 15%)
+	MinScore   float64       // minimum t-score of the trend for it to count
+	Renotify   time.Duration // a device already reported isn't reported again for this long
+}
+
+// Enabled reports whether devices are checked
+func (c EfficiencyConfig) Enabled() bool {
+	return c.Weeks > 0
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
-	// First try to load from .env file
-	LoadDefaultEnvFile()
+	// First try to load from .env files
+	loadEnvFiles()
+	// then a prediction profile exported from another instance, which neither overrides
+	if path := os.Getenv("PREDICTION_PROFILE"); path != "" {
+		if err := LoadPredictionProfile(path); err != nil {
+			return nil, fmt.Errorf("PREDICTION_PROFILE: %w", err)
+		}
+	}
+
+	envMu.Lock()
+	config, err := fromEnv()
+	envMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Set Gin mode
+	os.Setenv("GIN_MODE", config.App.GinMode)
+
+	return config, nil
+}
+
+// Defaults returns the configuration an instance gets with no environment variables, .env file
+// or prediction profile at all
+func Defaults() (*Config, error) {
+	envMu.Lock()
+	defer envMu.Unlock()
+	getenv = func(string) string { return "" }
+	defer func() { getenv = os.Getenv }()
+	return fromEnv()
+}
 
+// fromEnv builds and validates the configuration from the variables getenv reads; callers hold envMu
+func fromEnv() (*Config, error) {
 	config := &Config{
 		Server: ServerConfig{
-			Port: getEnvAsInt("SERVER_PORT", 8080),
-			Host: getEnv("SERVER_HOST", "localhost"),
+			Port:      getEnvAsInt("SERVER_PORT", 8080),
+			Host:      getEnv("SERVER_HOST", "localhost"),
+			MaxBodyKB: getEnvAsFloat("SERVER_MAX_BODY_KB", 64),
 		},
 		Database: DatabaseConfig{
-			Path:   getEnv("DATABASE_PATH", "./data.db"),
-			Driver: getEnv("DATABASE_DRIVER", "sqlite"),
+			Path:               getEnv("DATABASE_PATH", dataPath("data.db")),
+			Driver:             getEnv("DATABASE_DRIVER", "sqlite"),
+			SlowQueryThreshold: getEnvAsDuration("DATABASE_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+			BackupDir:          getEnv("DATABASE_BACKUP_DIR", dataPath("backups")),
+			Health: DatabaseHealthConfig{
+				FailureThreshold: getEnvAsInt("DATABASE_FAILURE_THRESHOLD", 3),
+				ProbeInterval:    getEnvAsDuration("DATABASE_PROBE_INTERVAL", 15*time.Second),
+				BufferSize:       getEnvAsInt("DATABASE_WRITE_BUFFER", 100),
+			},
+			Startup: DatabaseStartupConfig{
+				Timeout:    getEnvAsDuration("DATABASE_STARTUP_TIMEOUT", 2*time.Minute),
+				Backoff:    getEnvAsDuration("DATABASE_STARTUP_BACKOFF", time.Second),
+				MaxBackoff: getEnvAsDuration("DATABASE_STARTUP_MAX_BACKOFF", 30*time.Second),
+				OnTimeout:  getEnv("DATABASE_STARTUP_ON_TIMEOUT", StartupExit),
+			},
+			Logs: LogStoreConfig{
+				Store:     getEnv("DATABASE_LOGS_STORE", LogStoreDatabase),
+				Path:      getEnv("DATABASE_LOGS_PATH", ""),
+				Retention: getEnvAsDuration("DATABASE_LOGS_RETENTION", 30*24*time.Hour),
+			},
 		},
 		Prediction: PredictionConfig{
-			Version:   getEnv("PREDICTOR_VERSION", "v2"),
-			ModelPath: getEnv("PREDICTION_MODEL_PATH", "./models/"),
+			Version:    getEnv("PREDICTOR_VERSION", "v2"),
+			ModelPath:  getEnv("PREDICTION_MODEL_PATH", dataPath("models")+"/"),
+			Timeout:    getEnvAsDuration("PREDICTION_TIMEOUT", 2*time.Second),
+			Workers:    getEnvAsInt("PREDICTION_WORKERS", 4),
+			Queue:      getEnvAsInt("PREDICTION_QUEUE", 32),
+			MinMinutes: getEnvAsFloat("PREDICTION_MIN_MINUTES", 5),
+			MaxMinutes: getEnvAsFloat("PREDICTION_MAX_MINUTES", 120),
+			Inlet: InletConfig{
+				Mean:      getEnvAsFloat("PREDICTION_INLET_MEAN", 18),
+				Amplitude: getEnvAsFloat("PREDICTION_INLET_AMPLITUDE", 5),
+				PeakDay:   getEnvAsInt("PREDICTION_INLET_PEAK_DAY", 220),
+			},
+			Canary: CanaryConfig{
+				Version:    getEnv("PREDICTION_CANARY_VERSION", ""),
+				Percent:    getEnvAsInt("PREDICTION_CANARY_PERCENT", 0),
+				Margin:     getEnvAsFloat("PREDICTION_CANARY_MARGIN", 0.1),
+				Window:     getEnvAsInt("PREDICTION_CANARY_WINDOW", 50),
+				MinSamples: getEnvAsInt("PREDICTION_CANARY_MIN_SAMPLES", 10),
+			},
+			Guard: GuardConfig{
+				MaxChange: getEnvAsFloat("PREDICTION_GUARD_MAX_CHANGE", 50),
+				Window:    getEnvAsDuration("PREDICTION_GUARD_WINDOW", 48*time.Hour),
+			},
+			SafetyFloor: SafetyFloorConfig{
+				Below:   getEnvAsFloat("PREDICTION_SAFETY_FLOOR_BELOW", 5),
+				Minutes: getEnvAsFloat("PREDICTION_SAFETY_FLOOR_MINUTES", 0),
+			},
+			Warmup: WarmupConfig{
+				Users:    getEnvAsInt("PREDICTION_WARMUP_USERS", 0),
+				MemoryMB: getEnvAsFloat("PREDICTION_WARMUP_MEMORY_MB", 64),
+			},
+			Reliability: ReliabilityConfig{
+				MinPairs: getEnvAsInt("PREDICTION_RELIABILITY_MIN_PAIRS", 20),
+				Exclude:  getEnvAsFloat("PREDICTION_RELIABILITY_EXCLUDE", 0.55),
+				Full:     getEnvAsFloat("PREDICTION_RELIABILITY_FULL", 0.75),
+			},
+			GlobalRefresh: getEnvAsDuration("PREDICTION_GLOBAL_REFRESH", 5*time.Minute),
+			IndexMemoryMB: getEnvAsFloat("PREDICTION_INDEX_MEMORY_MB", 32),
+			Seed:          int64(getEnvAsInt("PREDICTION_SEED", 0)),
+			Calibration:   getEnvAsBool("PREDICTION_RATING_CALIBRATION", false),
+			V2: V2Config{
+				SigmaDuration:         getEnvAsFloat("PREDICTION_V2_SIGMA_DURATION", 4),
+				SigmaTemp:             getEnvAsFloat("PREDICTION_V2_SIGMA_TEMP", 3),
+				SigmaInlet:            getEnvAsFloat("PREDICTION_V2_SIGMA_INLET", 2.5),
+				K:                     getEnvAsInt("PREDICTION_V2_K", 25),
+				MinK:                  getEnvAsInt("PREDICTION_V2_MIN_K", 6),
+				AnchorEpsilon:         getEnvAsFloat("PREDICTION_V2_ANCHOR_EPSILON", 3),
+				AnchorBoost:           getEnvAsFloat("PREDICTION_V2_ANCHOR_BOOST", 1.5),
+				AnchorBlend:           getEnvAsFloat("PREDICTION_V2_ANCHOR_BLEND", 0.35),
+				RecencyHalfLifeDays:   getEnvAsFloat("PREDICTION_V2_RECENCY_HALF_LIFE_DAYS", 5),
+				UserBoost:             getEnvAsFloat("PREDICTION_V2_USER_BOOST", 2),
+				FixedUserBoost:        getEnvAsBool("PREDICTION_V2_FIXED_USER_BOOST", false),
+				AdaptiveBoostWindow:   getEnvAsInt("PREDICTION_V2_ADAPTIVE_BOOST_WINDOW", 10),
+				StepCapFraction:       getEnvAsFloat("PREDICTION_V2_STEP_CAP", 0.35),
+				NeverCold:             getEnvAsBool("PREDICTION_V2_NEVER_COLD", false),
+				Rounding:              getEnv("PREDICTION_V2_ROUNDING", "smart"),
+				RoundingStep:          getEnvAsFloat("PREDICTION_V2_ROUNDING_STEP", 1),
+				RoundingHysteresis:    getEnvAsFloat("PREDICTION_V2_ROUNDING_HYSTERESIS", 0.25),
+				ExplorationJitter:     getEnvAsFloat("PREDICTION_V2_EXPLORATION_JITTER", 0),
+				DisableExploration:    getEnvAsBool("PREDICTION_V2_DISABLE_EXPLORATION", false),
+				HardwareChangeDecay:   getEnvAsFloat("PREDICTION_V2_HARDWARE_CHANGE_DECAY", 0.05),
+				ChangePointMinSegment: getEnvAsInt("PREDICTION_V2_CHANGE_POINT_MIN_SEGMENT", 6),
+				ChangePointMinShift:   getEnvAsFloat("PREDICTION_V2_CHANGE_POINT_MIN_SHIFT", 0.2),
+				ChangePointMinScore:   getEnvAsFloat("PREDICTION_V2_CHANGE_POINT_MIN_SCORE", 3),
+				AnchorDiagnostics:     getEnvAsInt("PREDICTION_V2_ANCHOR_DIAGNOSTICS", 500),
+				MinContributors:       getEnvAsInt("PREDICTION_V2_MIN_CONTRIBUTORS", 3),
+				MinPersonalRecords:    getEnvAsInt("PREDICTION_V2_MIN_PERSONAL_RECORDS", 3),
+				CrossRegionWeight:     getEnvAsFloat("PREDICTION_V2_CROSS_REGION_WEIGHT", 0.3),
+				GlobalDurationBand:    getEnvAsFloat("PREDICTION_V2_GLOBAL_DURATION_BAND", 2),
+				GlobalTemperatureBand: getEnvAsFloat("PREDICTION_V2_GLOBAL_TEMPERATURE_BAND", 2),
+				BucketSplit:           getEnvAsInt("PREDICTION_V2_BUCKET_SPLIT", 0),
+			},
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173", "http://localhost:3000", "http://127.0.0.1:5173"}),
 			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization"}),
+			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Correlation-ID"}),
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "text"),
+			File:   getEnv("LOG_FILE", ""),
+			Rotation: LogRotationConfig{
+				MaxSizeMB: getEnvAsInt("LOG_MAX_SIZE_MB", 64),
+				Interval:  getEnvAsDuration("LOG_ROTATE_INTERVAL", 24*time.Hour),
+				Compress:  getEnvAsBool("LOG_COMPRESS", true),
+				MaxFiles:  getEnvAsInt("LOG_MAX_FILES", 10),
+				MaxAge:    getEnvAsDuration("LOG_MAX_AGE", 30*24*time.Hour),
+			},
 		},
 		App: AppConfig{
 			Environment: getEnv("ENVIRONMENT", "development"),
 			GinMode:     getEnv("GIN_MODE", "debug"),
+			DataDir:     getEnv("DATA_DIR", ""),
+		},
+		Export: ExportConfig{
+			Dir:      getEnv("EXPORT_DIR", dataPath("exports")),
+			JobTTL:   getEnvAsDuration("EXPORT_JOB_TTL", 24*time.Hour),
+			Throttle: getEnvAsDuration("EXPORT_THROTTLE", time.Minute),
+		},
+		Admin: AdminConfig{
+			Token: getEnv("ADMIN_TOKEN", ""),
+		},
+		Setup: SetupConfig{
+			Wizard: getEnvAsBool("SETUP_WIZARD", true),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:         strings.TrimSuffix(getEnv("OIDC_ISSUER_URL", ""), "/"),
+			ClientID:          getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:       getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+			Scopes:            getEnvAsSlice("OIDC_SCOPES", []string{"openid", "profile", "email", "groups"}),
+			GroupsClaim:       getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			AdminGroups:       getEnvAsSlice("OIDC_ADMIN_GROUPS", nil),
+			UserGroups:        getEnvAsSlice("OIDC_USER_GROUPS", nil),
+			PostLoginRedirect: getEnv("OIDC_POST_LOGIN_REDIRECT", "/"),
+			SessionTTL:        getEnvAsDuration("SESSION_TTL", 7*24*time.Hour),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvAsBool("METRICS_ENABLED", false),
+		},
+		SLO: SLOConfig{
+			AccuracyTarget:    getEnvAsFloat("SLO_ACCURACY_TARGET", 0.95),
+			AccuracyTolerance: getEnvAsFloat("SLO_ACCURACY_TOLERANCE", 10),
+			LatencyTarget:     getEnvAsFloat("SLO_LATENCY_TARGET", 0.99),
+			LatencyThreshold:  getEnvAsDuration("SLO_LATENCY_THRESHOLD", 150*time.Millisecond),
+			Period:            getEnvAsDuration("SLO_PERIOD", 30*24*time.Hour),
+		},
+		Share: ShareConfig{
+			Secret: getEnv("SHARE_LINK_SECRET", ""),
+			MaxTTL: getEnvAsDuration("SHARE_LINK_MAX_TTL", 7*24*time.Hour),
+		},
+		Households: HouseholdConfig{
+			InviteTTL: getEnvAsDuration("HOUSEHOLD_INVITE_TTL", 7*24*time.Hour),
+		},
+		Reports: ReportsConfig{
+			SMTPHost:      getEnv("REPORTS_SMTP_HOST", ""),
+			SMTPPort:      getEnvAsInt("REPORTS_SMTP_PORT", 587),
+			SMTPUsername:  getEnv("REPORTS_SMTP_USERNAME", ""),
+			SMTPPassword:  getEnv("REPORTS_SMTP_PASSWORD", ""),
+			SMTPFrom:      getEnv("REPORTS_SMTP_FROM", ""),
+			WebhookSecret: getEnv("REPORTS_WEBHOOK_SECRET", ""),
+		},
+		Events: EventsConfig{
+			ClientBuffer: getEnvAsInt("EVENTS_CLIENT_BUFFER", 64),
+			MaxClients:   getEnvAsInt("EVENTS_MAX_CLIENTS", 100),
+			WriteTimeout: getEnvAsDuration("EVENTS_WRITE_TIMEOUT", 10*time.Second),
+			Heartbeat:    getEnvAsDuration("EVENTS_HEARTBEAT", 30*time.Second),
+		},
+		Sync: SyncConfig{
+			InstanceID: getEnv("INSTANCE_ID", ""),
+			Token:      getEnv("SYNC_TOKEN", ""),
+			PeerURL:    strings.TrimSuffix(getEnv("SYNC_PEER_URL", ""), "/"),
+			Interval:   getEnvAsDuration("SYNC_INTERVAL", 0),
+		},
+		Device: DeviceConfig{
+			ReadyTemperature:   getEnvAsFloat("DEVICE_READY_TEMPERATURE", 45),
+			CoolDown:           getEnvAsDuration("DEVICE_COOLDOWN", 30*time.Minute),
+			InsulationHalfLife: getEnvAsDuration("DEVICE_INSULATION_HALF_LIFE", 4*time.Hour),
+			TankSize:           getEnvAsFloat("DEVICE_TANK_SIZE", 120),
+			FlowRate:           getEnvAsFloat("DEVICE_FLOW_RATE", 3),
+			HeaterPower:        getEnvAsFloat("DEVICE_HEATER_POWER", 2),
+		},
+		Records: RecordsConfig{
+			OnePerDay:   getEnvAsBool("RECORDS_ONE_PER_DAY", false),
+			ChecksumKey: getEnv("RECORDS_CHECKSUM_KEY", ""),
+
+			InfluenceDays: getEnvAsInt("RECORDS_INFLUENCE_DAYS", 0),
+			InfluenceKeep: getEnvAsInt("RECORDS_INFLUENCE_KEEP", 20),
+
+			TombstoneRetention: getEnvAsDuration("RECORDS_TOMBSTONE_RETENTION", 30*24*time.Hour),
+		},
+		Temperature: TemperatureConfig{
+			Sources: getEnvAsSlice("TEMPERATURE_SOURCES", []string{"manual", "home_assistant", "mqtt", "weather"}),
+			MaxAge:  getEnvAsDuration("TEMPERATURE_MAX_AGE", time.Hour),
+			Weather: WeatherConfig{
+				URL: strings.TrimSuffix(getEnv("WEATHER_API_URL", "https://api.open-meteo.com"), "/"),
+			},
+			HomeAssistant: HomeAssistantConfig{
+				URL:    strings.TrimSuffix(getEnv("HOME_ASSISTANT_URL", ""), "/"),
+				Token:  getEnv("HOME_ASSISTANT_TOKEN", ""),
+				Entity: getEnv("HOME_ASSISTANT_TEMPERATURE_ENTITY", ""),
+			},
+		},
+		Prices: PricesConfig{
+			Provider: getEnv("PRICES_PROVIDER", ""),
+			URL:      strings.TrimSuffix(getEnv("PRICES_API_URL", ""), "/"),
+			Area:     getEnv("PRICES_AREA", "FI"),
+			Currency: getEnv("PRICES_CURRENCY", "EUR"),
+			FlatRate: getEnvAsFloat("PRICES_FLAT_RATE", 0),
+		},
+		Solar: SolarConfig{
+			BaseLoad:    getEnvAsFloat("SOLAR_BASE_LOAD", 300),
+			FeedInPrice: getEnvAsFloat("SOLAR_FEED_IN_PRICE", 0),
+			LiveWindow:  getEnvAsDuration("SOLAR_LIVE_WINDOW", 30*time.Minute),
+		},
+		Feedback: FeedbackConfig{
+			PromptDelay: getEnvAsDuration("FEEDBACK_PROMPT_DELAY", 45*time.Minute),
+			Lookback:    getEnvAsDuration("FEEDBACK_PROMPT_LOOKBACK", 24*time.Hour),
+		},
+		Notifications: NotificationConfig{
+			WebhookURL: getEnv("NOTIFICATION_WEBHOOK_URL", ""),
+			QuietHours: getEnv("NOTIFICATION_QUIET_HOURS", "22:00-07:00"),
+			DailyLimit: getEnvAsInt("NOTIFICATION_DAILY_LIMIT", 5),
+			Telegram: TelegramConfig{
+				BotToken: getEnv("NOTIFICATION_TELEGRAM_BOT_TOKEN", ""),
+				APIURL:   strings.TrimSuffix(getEnv("NOTIFICATION_TELEGRAM_API_URL", "https://api.telegram.org"), "/"),
+			},
+			Ntfy: NtfyConfig{
+				URL: strings.TrimSuffix(getEnv("NOTIFICATION_NTFY_URL", "https://ntfy.sh"), "/"),
+			},
+			WebPush: WebPushConfig{
+				PrivateKey: getEnv("NOTIFICATION_VAPID_PRIVATE_KEY", ""),
+				Subject:    getEnv("NOTIFICATION_VAPID_SUBJECT", ""),
+			},
+			Matrix: MatrixConfig{
+				HomeserverURL: strings.TrimSuffix(getEnv("NOTIFICATION_MATRIX_HOMESERVER_URL", ""), "/"),
+				AccessToken:   getEnv("NOTIFICATION_MATRIX_ACCESS_TOKEN", ""),
+			},
+			Commands: getEnvAsBool("NOTIFICATION_BOT_COMMANDS", false),
+		},
+		Efficiency: EfficiencyConfig{
+			Weeks:      getEnvAsInt("BOILER_EFFICIENCY_WEEKS", 12),
+			MinRecords: getEnvAsInt("BOILER_EFFICIENCY_MIN_RECORDS", 10),
+			MinRise:    getEnvAsFloat("BOILER_EFFICIENCY_MIN_RISE", 0.15),
+			MinScore:   getEnvAsFloat("BOILER_EFFICIENCY_MIN_SCORE", 3),
+			Renotify:   getEnvAsDuration("BOILER_EFFICIENCY_RENOTIFY", 30*24*time.Hour),
+		},
+		Webhook: WebhookConfig{
+			Secret: getEnv("WEBHOOK_SECRET", ""),
+		},
+		Hooks: HooksConfig{
+			Enabled: getEnvAsSlice("HOOKS", nil),
+		},
+		Grid: DemandResponseConfig{
+			Token:       getEnv("DEMAND_RESPONSE_TOKEN", ""),
+			Recovery:    getEnvAsDuration("DEMAND_RESPONSE_RECOVERY", 2*time.Hour),
+			MaxDuration: getEnvAsDuration("DEMAND_RESPONSE_MAX_DURATION", 12*time.Hour),
+		},
+		PublicStats: PublicStatsConfig{
+			Enabled:    getEnvAsBool("PUBLIC_STATS_ENABLED", false),
+			MinUsers:   getEnvAsInt("PUBLIC_STATS_MIN_USERS", 5),
+			MinRecords: getEnvAsInt("PUBLIC_STATS_MIN_RECORDS", 20),
+			BandWidth:  getEnvAsFloat("PUBLIC_STATS_BAND_WIDTH", 5),
+			Refresh:    getEnvAsDuration("PUBLIC_STATS_REFRESH", time.Hour),
+		},
+		Trace: TraceConfig{
+			Retention: getEnvAsDuration("TRACE_RETENTION", 30*24*time.Hour),
+		},
+		Showers: ShowerDetectionConfig{
+			Rise:        getEnvAsFloat("SHOWER_DETECTION_RISE", 10),
+			Window:      getEnvAsDuration("SHOWER_DETECTION_WINDOW", 10*time.Minute),
+			MinDuration: getEnvAsDuration("SHOWER_DETECTION_MIN_DURATION", 2*time.Minute),
+			MaxDuration: getEnvAsDuration("SHOWER_DETECTION_MAX_DURATION", 45*time.Minute),
+		},
+		Inference: SatisfactionInferenceConfig{
+			Window:       getEnvAsDuration("SATISFACTION_INFERENCE_WINDOW", 20*time.Minute),
+			Satisfaction: getEnvAsFloat("SATISFACTION_INFERENCE_VALUE", 30),
+		},
+		Sanitation: SanitationConfig{
+			Minutes:    getEnvAsFloat("SANITATION_MINUTES", 90),
+			ExcludeFor: getEnvAsDuration("SANITATION_EXCLUDE_FOR", 6*time.Hour),
 		},
 	}
 
-	// Set Gin mode
-	os.Setenv("GIN_MODE", config.App.GinMode)
+	if config.Server.MaxBodyKB < 0 {
+		return nil, fmt.Errorf("SERVER_MAX_BODY_KB must not be negative")
+	}
+	bodyLimits, err := getEnvAsFloatMap("SERVER_BODY_LIMITS_KB")
+	if err != nil {
+		return nil, err
+	}
+	config.Server.BodyLimitsKB = bodyLimits
+
+	sourceWeights, err := getEnvAsFloatMap("PREDICTION_SOURCE_WEIGHTS")
+	if err != nil {
+		return nil, err
+	}
+	config.Prediction.SourceWeights = sourceWeights
+	if config.Prediction.Confidence, err = getEnvAsFloatMap("PREDICTION_FEEDBACK_CONFIDENCE"); err != nil {
+		return nil, err
+	}
+	for source, confidence := range config.Prediction.Confidence {
+		if confidence <= 0 || confidence > 1 {
+			return nil, fmt.Errorf("PREDICTION_FEEDBACK_CONFIDENCE for %s must be above 0 and at most 1", source)
+		}
+	}
+
+	if config.Prediction.V2.ExplorationMultipliers, err = getEnvAsFloatSlice("PREDICTION_V2_EXPLORATION", []float64{1.10, 0.95, 1.20}); err != nil {
+		return nil, err
+	}
+	if err := config.Prediction.V2.validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Device.InsulationHalfLives, err = getEnvAsFloatMap("DEVICE_INSULATION_HALF_LIVES"); err != nil {
+		return nil, err
+	}
+	if config.Device.Resolutions, err = getEnvAsFloatMap("DEVICE_RESOLUTIONS"); err != nil {
+		return nil, err
+	}
+	for device, resolution := range config.Device.Resolutions {
+		if resolution < 0.1 || resolution > 10 {
+			return nil, fmt.Errorf("DEVICE_RESOLUTIONS: %s must be between 0.1 and 10 minutes", device)
+		}
+	}
+
+	if g := config.Grid; g.Recovery < 0 || g.MaxDuration <= 0 {
+		return nil, fmt.Errorf("DEMAND_RESPONSE_RECOVERY must not be negative and DEMAND_RESPONSE_MAX_DURATION must be positive")
+	}
+	if config.Trace.Retention < 0 {
+		return nil, fmt.Errorf("TRACE_RETENTION must not be negative")
+	}
+	if p := config.PublicStats; p.MinUsers < 2 || p.MinRecords < 1 || p.BandWidth <= 0 || p.Refresh < 0 {
+		return nil, fmt.Errorf("PUBLIC_STATS_MIN_USERS must be at least 2, PUBLIC_STATS_MIN_RECORDS and PUBLIC_STATS_BAND_WIDTH positive and PUBLIC_STATS_REFRESH not negative")
+	}
+	if config.Hooks.Settings, err = getEnvAsFloatMap("HOOKS_SETTINGS"); err != nil {
+		return nil, err
+	}
+	for i, name := range config.Hooks.Enabled {
+		config.Hooks.Enabled[i] = strings.TrimSpace(name)
+	}
+
+	for _, source := range config.Temperature.Sources {
+		switch source {
+		case "manual", "weather", "mqtt", "home_assistant":
+		default:
+			return nil, fmt.Errorf("TEMPERATURE_SOURCES: unknown source %q", source)
+		}
+	}
+	if config.Records.MinDuration, err = getEnvAsOptionalFloat("RECORDS_MIN_DURATION"); err != nil {
+		return nil, err
+	}
+	if config.Records.MaxDuration, err = getEnvAsOptionalFloat("RECORDS_MAX_DURATION"); err != nil {
+		return nil, err
+	}
+	if config.Records.MinTemperature, err = getEnvAsOptionalFloat("RECORDS_MIN_TEMPERATURE"); err != nil {
+		return nil, err
+	}
+	if config.Records.MaxTemperature, err = getEnvAsOptionalFloat("RECORDS_MAX_TEMPERATURE"); err != nil {
+		return nil, err
+	}
+	if r := config.Records; r.MinDuration != nil && r.MaxDuration != nil && *r.MinDuration > *r.MaxDuration {
+		return nil, fmt.Errorf("RECORDS_MIN_DURATION must not be above RECORDS_MAX_DURATION")
+	}
+	if r := config.Records; r.MinTemperature != nil && r.MaxTemperature != nil && *r.MinTemperature > *r.MaxTemperature {
+		return nil, fmt.Errorf("RECORDS_MIN_TEMPERATURE must not be above RECORDS_MAX_TEMPERATURE")
+	}
+
+	if config.Records.InfluenceDays < 0 || config.Records.InfluenceKeep < 0 {
+		return nil, fmt.Errorf("RECORDS_INFLUENCE_DAYS and RECORDS_INFLUENCE_KEEP must not be negative")
+	}
+	if config.Records.TombstoneRetention < 0 {
+		return nil, fmt.Errorf("RECORDS_TOMBSTONE_RETENTION must not be negative")
+	}
+
+	if config.Temperature.Weather.Latitude, err = getEnvAsOptionalFloat("WEATHER_LATITUDE"); err != nil {
+		return nil, err
+	}
+	if config.Temperature.Weather.Longitude, err = getEnvAsOptionalFloat("WEATHER_LONGITUDE"); err != nil {
+		return nil, err
+	}
+
+	switch config.Prices.Provider {
+	case "", "awattar", "nordpool":
+	default:
+		return nil, fmt.Errorf("PRICES_PROVIDER must be awattar or nordpool, got %q", config.Prices.Provider)
+	}
+	if config.Prices.FlatRate < 0 {
+		return nil, fmt.Errorf("PRICES_FLAT_RATE must not be negative")
+	}
+
+	if !ValidQuietHours(config.Notifications.QuietHours) {
+		return nil, fmt.Errorf("NOTIFICATION_QUIET_HOURS must look like 22:00-07:00 or be off, got %q", config.Notifications.QuietHours)
+	}
+	if config.Notifications.DailyLimit < 0 {
+		return nil, fmt.Errorf("NOTIFICATION_DAILY_LIMIT must not be negative")
+	}
+	// Notifications are emailed through the report mail server unless they have their own
+	config.Notifications.Email = SMTPConfig{
+		Host:     getEnv("NOTIFICATION_SMTP_HOST", config.Reports.SMTPHost),
+		Port:     getEnvAsInt("NOTIFICATION_SMTP_PORT", config.Reports.SMTPPort),
+		Username: getEnv("NOTIFICATION_SMTP_USERNAME", config.Reports.SMTPUsername),
+		Password: getEnv("NOTIFICATION_SMTP_PASSWORD", config.Reports.SMTPPassword),
+		From:     getEnv("NOTIFICATION_SMTP_FROM", config.Reports.SMTPFrom),
+	}
+	if e := config.Notifications.Email; e.Host != "" && (e.From == "" || e.Port <= 0) {
+		return nil, fmt.Errorf("NOTIFICATION_SMTP_FROM and a positive NOTIFICATION_SMTP_PORT are required when NOTIFICATION_SMTP_HOST is set")
+	}
+	if w := config.Notifications.WebPush; w.PrivateKey != "" && w.Subject == "" {
+		return nil, fmt.Errorf("NOTIFICATION_VAPID_SUBJECT is required when NOTIFICATION_VAPID_PRIVATE_KEY is set")
+	}
+	if m := config.Notifications.Matrix; m.AccessToken != "" && m.HomeserverURL == "" {
+		return nil, fmt.Errorf("NOTIFICATION_MATRIX_HOMESERVER_URL is required when NOTIFICATION_MATRIX_ACCESS_TOKEN is set")
+	}
+	if e := config.Efficiency; e.Weeks < 0 || (e.Enabled() && (e.MinRecords < 3 || e.MinRise <= 0 || e.MinScore <= 0)) {
+		return nil, fmt.Errorf("BOILER_EFFICIENCY_WEEKS must not be negative, BOILER_EFFICIENCY_MIN_RECORDS must be at least 3 and BOILER_EFFICIENCY_MIN_RISE and _MIN_SCORE positive")
+	}
+
+	if config.Prediction.MinMinutes <= 0 || config.Prediction.MaxMinutes <= config.Prediction.MinMinutes {
+		return nil, fmt.Errorf("PREDICTION_MIN_MINUTES must be positive and below PREDICTION_MAX_MINUTES")
+	}
+	if mode := config.App.GinMode; mode != "debug" && mode != "release" && mode != "test" {
+		return nil, fmt.Errorf("GIN_MODE must be debug, release or test, got %q", mode)
+	}
+	if onTimeout := config.Database.Startup.OnTimeout; onTimeout != StartupExit && onTimeout != StartupWait {
+		return nil, fmt.Errorf("DATABASE_STARTUP_ON_TIMEOUT must be exit or wait, got %q", onTimeout)
+	}
+	switch logs := &config.Database.Logs; logs.Store {
+	case LogStoreDatabase:
+	case LogStoreSQLite:
+		if logs.Path == "" {
+			logs.Path = dataPath("logs.db")
+		}
+		if logs.Path == config.Database.Path {
+			return nil, fmt.Errorf("DATABASE_LOGS_PATH must not be the DATABASE_PATH")
+		}
+	case LogStoreJSONL:
+		if logs.Path == "" {
+			logs.Path = dataPath("logs")
+		}
+		if logs.Retention < 24*time.Hour {
+			return nil, fmt.Errorf("DATABASE_LOGS_RETENTION must be at least 24h")
+		}
+	default:
+		return nil, fmt.Errorf("DATABASE_LOGS_STORE must be database, sqlite or jsonl, got %q", logs.Store)
+	}
+	if rotation := config.Logging.Rotation; rotation.MaxSizeMB < 0 || rotation.Interval < 0 || rotation.MaxFiles < 0 || rotation.MaxAge < 0 {
+		return nil, fmt.Errorf("LOG_MAX_SIZE_MB, LOG_ROTATE_INTERVAL, LOG_MAX_FILES and LOG_MAX_AGE must not be negative")
+	} else if rotation.Interval > 0 && rotation.Interval < time.Minute {
+		return nil, fmt.Errorf("LOG_ROTATE_INTERVAL must be at least 1m")
+	}
+	if config.Prediction.Workers < 0 || config.Prediction.Queue < 0 {
+		return nil, fmt.Errorf("PREDICTION_WORKERS and PREDICTION_QUEUE must not be negative")
+	}
+
+	if canary := config.Prediction.Canary; canary.Enabled() {
+		if canary.Version != "v1" && canary.Version != "v2" {
+			return nil, fmt.Errorf("PREDICTION_CANARY_VERSION must be v1 or v2, got %q", canary.Version)
+		}
+		if canary.Percent < 0 || canary.Percent > 100 {
+			return nil, fmt.Errorf("PREDICTION_CANARY_PERCENT must be between 0 and 100")
+		}
+	}
+
+	if warmup := config.Prediction.Warmup; warmup.Users < 0 || (warmup.Enabled() && warmup.MemoryMB <= 0) {
+		return nil, fmt.Errorf("PREDICTION_WARMUP_USERS must not be negative and PREDICTION_WARMUP_MEMORY_MB must be positive")
+	}
+	if r := config.Prediction.Reliability; r.Enabled() && (r.MinPairs < 1 || r.Exclude < 0 || r.Exclude >= r.Full || r.Full > 1) {
+		return nil, fmt.Errorf("PREDICTION_RELIABILITY_MIN_PAIRS must be positive and 0 <= PREDICTION_RELIABILITY_EXCLUDE < PREDICTION_RELIABILITY_FULL <= 1")
+	}
+	if config.Prediction.GlobalRefresh < 0 {
+		return nil, fmt.Errorf("PREDICTION_GLOBAL_REFRESH must not be negative")
+	}
+	if config.Prediction.IndexMemoryMB < 0 {
+		return nil, fmt.Errorf("PREDICTION_INDEX_MEMORY_MB must not be negative")
+	}
+	if guard := config.Prediction.Guard; guard.MaxChange < 0 || (guard.Enabled() && guard.Window <= 0) {
+		return nil, fmt.Errorf("PREDICTION_GUARD_MAX_CHANGE must not be negative and PREDICTION_GUARD_WINDOW must be positive")
+	}
+	if floor := config.Prediction.SafetyFloor; floor.Minutes < 0 || floor.Minutes > config.Prediction.MaxMinutes {
+		return nil, fmt.Errorf("PREDICTION_SAFETY_FLOOR_MINUTES must be between 0 and PREDICTION_MAX_MINUTES")
+	}
+	if sd := config.Showers; sd.Rise < 0 || (sd.Enabled() && (sd.Window <= 0 || sd.MinDuration <= 0 || sd.MaxDuration <= sd.MinDuration)) {
+		return nil, fmt.Errorf("SHOWER_DETECTION_RISE must not be negative, SHOWER_DETECTION_WINDOW and _MIN_DURATION positive and _MAX_DURATION above _MIN_DURATION")
+	}
+	if si := config.Inference; si.Window < 0 || (si.Enabled() && (si.Satisfaction < 1 || si.Satisfaction >= 50)) {
+		return nil, fmt.Errorf("SATISFACTION_INFERENCE_WINDOW must not be negative and SATISFACTION_INFERENCE_VALUE must be from 1 to below 50, i.e. too cold")
+	}
+	if sc := config.Sanitation; sc.Minutes <= 0 || sc.ExcludeFor < 0 {
+		return nil, fmt.Errorf("SANITATION_MINUTES must be positive and SANITATION_EXCLUDE_FOR must not be negative")
+	}
+	if slo := config.SLO; slo.AccuracyTarget < 0 || slo.AccuracyTarget >= 1 || slo.LatencyTarget < 0 || slo.LatencyTarget >= 1 {
+		return nil, fmt.Errorf("SLO_ACCURACY_TARGET and SLO_LATENCY_TARGET must be at least 0 and below 1")
+	}
+	if slo := config.SLO; slo.AccuracyTolerance <= 0 || slo.LatencyThreshold <= 0 || slo.Period < time.Hour {
+		return nil, fmt.Errorf("SLO_ACCURACY_TOLERANCE and SLO_LATENCY_THRESHOLD must be positive and SLO_PERIOD at least 1h")
+	}
+	if config.Households.InviteTTL <= 0 {
+		return nil, fmt.Errorf("HOUSEHOLD_INVITE_TTL must be positive")
+	}
+	if r := config.Reports; r.EmailEnabled() && (r.SMTPFrom == "" || r.SMTPPort <= 0) {
+		return nil, fmt.Errorf("REPORTS_SMTP_FROM and a positive REPORTS_SMTP_PORT are required when REPORTS_SMTP_HOST is set")
+	}
+	if e := config.Events; e.ClientBuffer <= 0 || e.MaxClients <= 0 || e.WriteTimeout <= 0 || e.Heartbeat <= 0 {
+		return nil, fmt.Errorf("EVENTS_CLIENT_BUFFER, EVENTS_MAX_CLIENTS, EVENTS_WRITE_TIMEOUT and EVENTS_HEARTBEAT must be positive")
+	}
+	if path := getEnv("WEBHOOK_RULES_FILE", ""); path != "" {
+		if config.Webhook.Rules, err = LoadWebhookRules(path); err != nil {
+			return nil, fmt.Errorf("WEBHOOK_RULES_FILE: %w", err)
+		}
+	}
+
+	if raw := getEnv("BENCH_FROZEN_TIME", ""); raw != "" {
+		frozen, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("BENCH_FROZEN_TIME must be an RFC3339 time, got %q", raw)
+		}
+		config.App.FrozenTime = &frozen
+	}
+
+	if config.OIDC.Enabled() && config.OIDC.ClientID == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+	}
 
 	return config, nil
 }
 
+// validate rejects v2 settings the predictor would silently replace with its defaults
+func (c V2Config) validate() error {
+	switch {
+	case c.SigmaDuration <= 0 || c.SigmaTemp <= 0 || c.SigmaInlet <= 0:
+		return fmt.Errorf("PREDICTION_V2_SIGMA_* must be positive")
+	case c.K <= 0 || c.MinK <= 0:
+		return fmt.Errorf("PREDICTION_V2_K and PREDICTION_V2_MIN_K must be positive")
+	case c.AnchorEpsilon <= 0 || c.AnchorBoost <= 0:
+		return fmt.Errorf("PREDICTION_V2_ANCHOR_EPSILON and PREDICTION_V2_ANCHOR_BOOST must be positive")
+	case c.AnchorBlend < 0 || c.AnchorBlend > 1:
+		return fmt.Errorf("PREDICTION_V2_ANCHOR_BLEND must be between 0 and 1")
+	case c.StepCapFraction <= 0 || c.StepCapFraction >= 1:
+		return fmt.Errorf("PREDICTION_V2_STEP_CAP must be between 0 and 1")
+	case c.HardwareChangeDecay <= 0 || c.HardwareChangeDecay > 1:
+		return fmt.Errorf("PREDICTION_V2_HARDWARE_CHANGE_DECAY must be above 0 and at most 1")
+	case c.RecencyHalfLifeDays <= 0 || c.UserBoost <= 0 || c.AdaptiveBoostWindow <= 0:
+		return fmt.Errorf("PREDICTION_V2_RECENCY_HALF_LIFE_DAYS, PREDICTION_V2_USER_BOOST and PREDICTION_V2_ADAPTIVE_BOOST_WINDOW must be positive")
+	case c.ChangePointMinSegment <= 0 || c.ChangePointMinShift <= 0 || c.ChangePointMinScore <= 0:
+		return fmt.Errorf("PREDICTION_V2_CHANGE_POINT_* must be positive")
+	case c.Rounding != "smart" && c.Rounding != "nearest" && c.Rounding != "ceil":
+		return fmt.Errorf("PREDICTION_V2_ROUNDING must be smart, nearest or ceil, got %q", c.Rounding)
+	case c.RoundingStep < 0.1 || c.RoundingStep > 10:
+		return fmt.Errorf("PREDICTION_V2_ROUNDING_STEP must be between 0.1 and 10 minutes")
+	case c.RoundingHysteresis < 0 || c.RoundingHysteresis > 0.5:
+		return fmt.Errorf("PREDICTION_V2_ROUNDING_HYSTERESIS must be between 0 and 0.5")
+	case c.ExplorationJitter < 0 || c.ExplorationJitter > 0.5:
+		return fmt.Errorf("PREDICTION_V2_EXPLORATION_JITTER must be between 0 and 0.5")
+	case c.AnchorDiagnostics <= 0:
+		return fmt.Errorf("PREDICTION_V2_ANCHOR_DIAGNOSTICS must be positive")
+	case c.MinContributors < 1:
+		return fmt.Errorf("PREDICTION_V2_MIN_CONTRIBUTORS must be at least 1")
+	case c.MinPersonalRecords < 0:
+		return fmt.Errorf("PREDICTION_V2_MIN_PERSONAL_RECORDS must not be negative")
+	case c.CrossRegionWeight <= 0 || c.CrossRegionWeight > 1:
+		return fmt.Errorf("PREDICTION_V2_CROSS_REGION_WEIGHT must be above 0 and at most 1")
+	case c.BucketSplit < 0:
+		return fmt.Errorf("PREDICTION_V2_BUCKET_SPLIT must not be negative")
+	case c.GlobalDurationBand < 0 || c.GlobalTemperatureBand < 0:
+		return fmt.Errorf("PREDICTION_V2_GLOBAL_DURATION_BAND and PREDICTION_V2_GLOBAL_TEMPERATURE_BAND must not be negative")
+	}
+	return nil
+}
+
+// ValidQuietHours reports whether quietHours is "off" or an HH:MM-HH:MM window
+func ValidQuietHours(quietHours string) bool {
+	if quietHours == "off" {
+		return true
+	}
+	start, end, ok := strings.Cut(quietHours, "-")
+	_, startErr := time.Parse("15:04", start)
+	_, endErr := time.Parse("15:04", end)
+	return ok && startErr == nil && endErr == nil
+}
+
+var (
+	// getenv reads the variables the configuration is built from; Defaults swaps it for an empty environment
+	getenv = os.Getenv
+	envMu  sync.Mutex
+)
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
@@ -103,7 +1215,7 @@ func getEnv(key, defaultValue string) string {
 
 // getEnvAsInt gets an environment variable as an integer or returns a default value
 func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
+	if value := getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
 			return intValue
 		}
@@ -111,9 +1223,90 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsBool gets an environment variable as a boolean or returns a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsFloat gets an environment variable as a float or returns a default value
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsOptionalFloat parses an environment variable as a float, returning nil when it is unset
+func getEnvAsOptionalFloat(key string) (*float64, error) {
+	value := getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number, got %q", key, value)
+	}
+	return &floatValue, nil
+}
+
+// getEnvAsDuration gets an environment variable as a duration (e.g. "500ms", "2s") or returns a default value
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := getenv(key); value != "" {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsFloatMap parses an environment variable of comma-separated key=number pairs
+func getEnvAsFloatMap(key string) (map[string]float64, error) {
+	value := getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+	result := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected key=value, got %q", key, pair)
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("%s: %q must be a non-negative number", key, raw)
+		}
+		result[strings.TrimSpace(name)] = number
+	}
+	return result, nil
+}
+
+// getEnvAsFloatSlice parses an environment variable of comma-separated positive numbers
+func getEnvAsFloatSlice(key string, defaultValue []float64) ([]float64, error) {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	var result []float64
+	for _, raw := range strings.Split(value, ",") {
+		number, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || number <= 0 {
+			return nil, fmt.Errorf("%s: %q must be a positive number", key, raw)
+		}
+		result = append(result, number)
+	}
+	return result, nil
+}
+
 // getEnvAsSlice gets an environment variable as a slice or returns a default value
 func getEnvAsSlice(key string, defaultValue []string) []string {
-	if value := os.Getenv(key); value != "" {
+	if value := getenv(key); value != "" {
 		return strings.Split(value, ",")
 	}
 	return defaultValue
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// Redacted stands in for the value of a secret setting that is set
+const Redacted = "[redacted]"
+
+// Setting is one configuration value, keyed by its dotted field path, e.g. Prediction.V2.K
+type Setting struct {
+	Key     string      `json:"key"`
+	Value   interface{} `json:"value"`
+	Default interface{} `json:"default"`
+	Secret  bool        `json:"secret,omitempty"` // the values only tell whether it is set
+}
+
+// setting is a configuration field before it is shown
+type setting struct {
+	key    string
+	value  reflect.Value
+	secret bool
+}
+
+// Settings flattens the configuration into its dotted field paths and their values. Fields
+// tagged secret show Redacted when set, and passwords in URLs are redacted too.
+func (c *Config) Settings() map[string]interface{} {
+	settings := map[string]interface{}{}
+	for _, s := range flatten(reflect.ValueOf(*c), "", false) {
+		settings[s.key] = s.show()
+	}
+	return settings
+}
+
+// Diff returns the settings that differ from defaults, in field order, redacted like Settings
+func (c *Config) Diff(defaults *Config) []Setting {
+	current := flatten(reflect.ValueOf(*c), "", false)
+	base := flatten(reflect.ValueOf(*defaults), "", false)
+	diff := []Setting{}
+	for i, s := range current {
+		if reflect.DeepEqual(s.value.Interface(), base[i].value.Interface()) {
+			continue
+		}
+		diff = append(diff, Setting{Key: s.key, Value: s.show(), Default: base[i].show(), Secret: s.secret})
+	}
+	return diff
+}
+
+// flatten lists the leaf fields of a configuration struct in field order
+func flatten(v reflect.Value, prefix string, secret bool) []setting {
+	if v.Kind() != reflect.Struct || v.Type() == reflect.TypeOf(time.Time{}) {
+		return []setting{{key: prefix, value: v, secret: secret}}
+	}
+	var settings []setting
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := field.Name
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		settings = append(settings, flatten(v.Field(i), key, secret || field.Tag.Get("secret") == "true")...)
+	}
+	return settings
+}
+
+// show returns the value as the admin API reports it
+func (s setting) show() interface{} {
+	v := s.value
+	if s.secret {
+		if v.IsZero() {
+			return ""
+		}
+		return Redacted
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case string:
+		return redactURL(value)
+	default:
+		return value
+	}
+}
+
+// redactURL hides the password of a URL with credentials in it; other strings are returned as they are
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); !ok {
+		return value
+	}
+	u.User = url.UserPassword(u.User.Username(), "redacted")
+	return u.String()
+}
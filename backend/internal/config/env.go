@@ -2,10 +2,21 @@ package config
 
 import (
 	"bufio"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
+// defaultEnv holds the settings a build bakes in, in .env format
+var defaultEnv string
+
+// SetDefaultEnv sets the settings, in .env format, a build bakes in. Load applies them last,
+// so the environment and every .env file take precedence.
+func SetDefaultEnv(env string) {
+	defaultEnv = env
+}
+
 // LoadEnvFile loads environment variables from a .env file
 func LoadEnvFile(filename string) error {
 	file, err := os.Open(filename)
@@ -14,8 +25,12 @@ func LoadEnvFile(filename string) error {
 		return nil
 	}
 	defer file.Close()
+	return loadEnv(file)
+}
 
-	scanner := bufio.NewScanner(file)
+// loadEnv sets the variables of a .env file that aren't set yet
+func loadEnv(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
@@ -51,3 +66,26 @@ func LoadEnvFile(filename string) error {
 func LoadDefaultEnvFile() error {
 	return LoadEnvFile(".env")
 }
+
+// loadEnvFiles loads the .env file in the current directory, then the one in DATA_DIR, then
+// the settings the build bakes in; the first to set a variable wins
+func loadEnvFiles() error {
+	if err := LoadDefaultEnvFile(); err != nil {
+		return err
+	}
+	if dir := os.Getenv("DATA_DIR"); dir != "" {
+		if err := LoadEnvFile(filepath.Join(dir, ".env")); err != nil {
+			return err
+		}
+	}
+	return loadEnv(strings.NewReader(defaultEnv))
+}
+
+// dataPath returns the default location of a file or directory the instance keeps, such as
+// its database: under DATA_DIR when it is set, else in the current directory
+func dataPath(name string) string {
+	if dir := getEnv("DATA_DIR", ""); dir != "" {
+		return filepath.Join(dir, name)
+	}
+	return "./" + name
+}
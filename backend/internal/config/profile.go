@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ProfileSettings are the environment variables a prediction profile carries: how the model is
+// tuned, as opposed to how one deployment runs it (canary, workers, timeouts, model path).
+var ProfileSettings = []string{
+	"PREDICTOR_VERSION",
+	"PREDICTION_MIN_MINUTES",
+	"PREDICTION_MAX_MINUTES",
+	"PREDICTION_SOURCE_WEIGHTS",
+	"PREDICTION_FEEDBACK_CONFIDENCE",
+	"PREDICTION_INLET_MEAN",
+	"PREDICTION_INLET_AMPLITUDE",
+	"PREDICTION_INLET_PEAK_DAY",
+	"PREDICTION_V2_K",
+	"PREDICTION_V2_MIN_K",
+	"PREDICTION_V2_SIGMA_DURATION",
+	"PREDICTION_V2_SIGMA_TEMP",
+	"PREDICTION_V2_SIGMA_INLET",
+	"PREDICTION_V2_RECENCY_HALF_LIFE_DAYS",
+	"PREDICTION_V2_USER_BOOST",
+	"PREDICTION_V2_FIXED_USER_BOOST",
+	"PREDICTION_V2_ADAPTIVE_BOOST_WINDOW",
+	"PREDICTION_V2_ANCHOR_BLEND",
+	"PREDICTION_V2_ANCHOR_BOOST",
+	"PREDICTION_V2_ANCHOR_EPSILON",
+	"PREDICTION_V2_STEP_CAP",
+	"PREDICTION_V2_NEVER_COLD",
+	"PREDICTION_V2_ROUNDING",
+	"PREDICTION_V2_ROUNDING_STEP",
+	"PREDICTION_V2_ROUNDING_HYSTERESIS",
+	"PREDICTION_V2_HARDWARE_CHANGE_DECAY",
+	"PREDICTION_V2_CHANGE_POINT_MIN_SEGMENT",
+	"PREDICTION_V2_CHANGE_POINT_MIN_SHIFT",
+	"PREDICTION_V2_CHANGE_POINT_MIN_SCORE",
+	"PREDICTION_V2_EXPLORATION",
+	"PREDICTION_V2_DISABLE_EXPLORATION",
+}
+
+// PredictionSettings returns the profile settings this instance was started with that differ
+// from the defaults, i.e. the ones set in the environment or a .env file
+func PredictionSettings() map[string]string {
+	settings := map[string]string{}
+	for _, key := range ProfileSettings {
+		if value := os.Getenv(key); value != "" {
+			settings[key] = value
+		}
+	}
+	return settings
+}
+
+// LoadPredictionProfile applies the global settings of an exported prediction profile (JSON
+// with a "global" object of variable names to values). Like a .env file it never overrides a
+// variable that is already set; variables that aren't profile settings are rejected.
+func LoadPredictionProfile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var profile struct {
+		Global map[string]string `json:"global"`
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return fmt.Errorf("invalid prediction profile: %w", err)
+	}
+	if unknown := UnknownProfileSettings(profile.Global); len(unknown) > 0 {
+		return fmt.Errorf("prediction profile sets %v, which are not prediction settings", unknown)
+	}
+	for key, value := range profile.Global {
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// UnknownProfileSettings returns the keys of settings that aren't in ProfileSettings, sorted
+func UnknownProfileSettings(settings map[string]string) []string {
+	known := map[string]bool{}
+	for _, key := range ProfileSettings {
+		known[key] = true
+	}
+	var unknown []string
+	for key := range settings {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Webhook rule actions: what an event pushed by a third-party service turns into
+const (
+	WebhookActionDeviceEvent   = "device_event"     // an event for the device state machine, e.g. the boiler turned on
+	WebhookActionSensorReading = "sensor_reading"   // an outdoor temperature reading
+	WebhookActionHumidity      = "humidity_reading" // a bathroom humidity reading, checked for showers
+	WebhookActionRecord        = "record"           // a shower record, held for the user's review like other sensor records
+)
+
+// WebhookConfig holds the receiver for events pushed by services such as IFTTT or Shelly cloud
+type WebhookConfig struct {
+	Secret string        `secret:"true"` // HMAC key for X-Webhook-Signature, or the bearer token of services that can't sign; empty disables the receiver
+	Rules  []WebhookRule // loaded from WEBHOOK_RULES_FILE
+}
+
+// Enabled reports whether the webhook receiver is served
+func (c WebhookConfig) Enabled() bool {
+	return c.Secret != ""
+}
+
+// WebhookRule maps the events a service posts to /api/webhooks/:source onto an action. Paths
+// are dotted keys into the JSON payload, e.g. "params.switch.output".
+type WebhookRule struct {
+	Name     string            `json:"name"`
+	Source   string            `json:"source"`   // the :source the service posts to
+	Match    map[string]string `json:"match"`    // paths and the values they must have; empty matches every event
+	Action   string            `json:"action"`   // one of the WebhookAction constants
+	UserID   string            `json:"userId"`   // owner of readings and records; empty for a house-wide sensor
+	DeviceID string            `json:"deviceId"` // device the event or record belongs to
+	Event    string            `json:"event"`    // device event type, for device_event
+	Fields   map[string]string `json:"fields"`   // target field to the path of its value, e.g. "temperature": "value1"
+}
+
+// requiredWebhookFields are the fields each action can't do without
+var requiredWebhookFields = map[string][]string{
+	WebhookActionDeviceEvent:   nil,
+	WebhookActionSensorReading: {"temperature"},
+	WebhookActionHumidity:      {"humidity"},
+	WebhookActionRecord:        {"showerDuration", "heatingTime"},
+}
+
+// validate rejects rules that could never be applied
+func (r WebhookRule) validate() error {
+	required, ok := requiredWebhookFields[r.Action]
+	switch {
+	case r.Source == "":
+		return fmt.Errorf("webhook rule %q needs a source", r.Name)
+	case !ok:
+		return fmt.Errorf("webhook rule %q: action must be device_event, sensor_reading, humidity_reading or record, got %q", r.Name, r.Action)
+	case r.Action == WebhookActionDeviceEvent && (r.DeviceID == "" || r.Event == ""):
+		return fmt.Errorf("webhook rule %q needs a deviceId and an event", r.Name)
+	case (r.Action == WebhookActionRecord || r.Action == WebhookActionHumidity) && r.UserID == "":
+		return fmt.Errorf("webhook rule %q needs a userId", r.Name)
+	}
+	for _, field := range required {
+		if r.Fields[field] == "" {
+			return fmt.Errorf("webhook rule %q needs fields.%s", r.Name, field)
+		}
+	}
+	return nil
+}
+
+// LoadWebhookRules reads a JSON array of webhook rules. Rules without a name are named after
+// their position.
+func LoadWebhookRules(filename string) ([]WebhookRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var rules []WebhookRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid webhook rules: %w", err)
+	}
+	for i := range rules {
+		if rules[i].Name == "" {
+			rules[i].Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if err := rules[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
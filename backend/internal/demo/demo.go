@@ -0,0 +1,118 @@
+// Package demo holds the example showers a fresh install can be seeded with, and the CSV
+// format they are kept in: one shower per row, dated in days before the moment they are
+// loaded at, so the history always ends yesterday.
+//
+// The demo showers are a year of four households on different boilers. They follow a simple
+// heating model with noise, so the satisfaction of each one says how far its heating time was
+// from what it needed.
+package demo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// Columns is the header every shower CSV starts with
+const Columns = "user_id,days_ago,time,device_id,member_id,shower_duration,average_temperature,inlet_temperature,heating_time,satisfaction"
+
+// UserPrefix starts the IDs of the users demo showers are stored under
+const UserPrefix = "demo-"
+
+//go:embed showers.csv
+var showers string
+
+// ParseShowers reads a shower CSV with its showers dated relative to now, in file order. Each
+// record's line in the file is at the same index of lines.
+func ParseShowers(r io.Reader, now time.Time) (records []models.DailyRecord, lines []int, err error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.Join(header, ",") != Columns {
+		return nil, nil, fmt.Errorf("unexpected columns %v", header)
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		record, err := parseRow(row, midnight)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return records, lines, nil
+}
+
+// parseRow turns a CSV row into a record dated days before midnight
+func parseRow(row []string, midnight time.Time) (models.DailyRecord, error) {
+	var record models.DailyRecord
+	daysAgo, err := strconv.Atoi(row[1])
+	if err != nil {
+		return record, fmt.Errorf("days_ago: %w", err)
+	}
+	clock, err := time.Parse("15:04", row[2])
+	if err != nil {
+		return record, fmt.Errorf("time: %w", err)
+	}
+	numbers := make([]float64, 4)
+	for i, column := range []int{5, 6, 8, 9} {
+		if numbers[i], err = strconv.ParseFloat(row[column], 64); err != nil {
+			return record, fmt.Errorf("column %d: %w", column+1, err)
+		}
+	}
+
+	record = models.DailyRecord{
+		UserID:             row[0],
+		Date:               midnight.AddDate(0, 0, -daysAgo).Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute),
+		DeviceID:           row[3],
+		MemberID:           row[4],
+		ShowerDuration:     numbers[0],
+		AverageTemperature: numbers[1],
+		HeatingTime:        numbers[2],
+		Satisfaction:       numbers[3],
+		FeedbackConfidence: 1,
+		Source:             models.RecordSourceManual,
+		TemperatureSource:  models.TemperatureSourceManual,
+	}
+	if row[7] != "" {
+		inlet, err := strconv.ParseFloat(row[7], 64)
+		if err != nil {
+			return record, fmt.Errorf("inlet_temperature: %w", err)
+		}
+		record.InletTemperature = &inlet
+	}
+	return record, nil
+}
+
+// Showers returns the demo showers dated up to now, oldest first, under demo users. They are
+// marked simulated, so real feedback outweighs them once there is some.
+func Showers(now time.Time) ([]models.DailyRecord, error) {
+	records, lines, err := ParseShowers(strings.NewReader(showers), now)
+	if err != nil {
+		return nil, fmt.Errorf("demo showers: %w", err)
+	}
+	for i := range records {
+		records[i].ID = fmt.Sprintf("%sshower-%d", UserPrefix, lines[i])
+		records[i].UserID = UserPrefix + records[i].UserID
+		records[i].Source = models.RecordSourceSimulator
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Date.Before(records[j].Date) })
+	return records, nil
+}
@@ -0,0 +1,47 @@
+package demo
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestShowers_SeedAFreshInstall(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "demo.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := services.NewRecordService()
+	now := time.Now()
+
+	showers, err := Showers(now)
+	require.NoError(t, err)
+	seeded, err := records.SeedRecords(showers)
+	require.NoError(t, err)
+	assert.Greater(t, seeded, 900)
+	stored, err := records.GetAllRecords()
+	require.NoError(t, err)
+	require.Len(t, stored, seeded)
+	users := make(map[string]bool)
+	for _, record := range stored {
+		assert.True(t, strings.HasPrefix(record.UserID, UserPrefix))
+		assert.Equal(t, models.RecordSourceSimulator, record.Source)
+		assert.False(t, record.Date.After(now))
+		users[record.UserID] = true
+	}
+	assert.Len(t, users, 4)
+
+	// A database with showers in it is left alone
+	seeded, err = records.SeedRecords(showers)
+	require.NoError(t, err)
+	assert.Zero(t, seeded)
+}
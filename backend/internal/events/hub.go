@@ -0,0 +1,163 @@
+// Package events fans live events, such as a water heater changing state, out to the clients
+// streaming them over SSE or WebSocket.
+//
+// Publishing never waits for a client. Each subscription has a bounded buffer, and a client that
+// lets it fill up, e.g. a stalled browser tab, is disconnected, so it can't hold back delivery
+// to the others.
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooManyClients is returned when subscribing while the hub already has its most clients
+var ErrTooManyClients = errors.New("too many event subscribers")
+
+// Event types
+const (
+	TypeDeviceState   = "device.state"   // a device's state changed; Data is its status
+	TypeRecordCreated = "record.created" // a record was stored; Data is the record
+)
+
+// IsValidType reports whether t is a type of event the hub publishes
+func IsValidType(t string) bool {
+	return t == TypeDeviceState || t == TypeRecordCreated
+}
+
+// Event is something that happened, delivered to the clients subscribed to it
+type Event struct {
+	ID       uint64      `json:"id"` // increasing across the hub's events
+	Type     string      `json:"type"`
+	UserID   string      `json:"userId,omitempty"`
+	DeviceID string      `json:"deviceId,omitempty"`
+	At       time.Time   `json:"at"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// Filter selects the events a client receives. Empty fields match every event; a set user or
+// device only matches events naming it.
+type Filter struct {
+	UserID   string
+	DeviceID string
+	Types    []string
+}
+
+// Matches reports whether an event passes the filter
+func (f Filter) Matches(e Event) bool {
+	if f.UserID != "" && e.UserID != f.UserID {
+		return false
+	}
+	if f.DeviceID != "" && e.DeviceID != f.DeviceID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Hub delivers published events to its subscriptions
+type Hub struct {
+	mu         sync.Mutex
+	clients    map[*Subscription]struct{}
+	buffer     int
+	maxClients int
+	lastID     uint64
+	now        func() time.Time
+}
+
+// NewHub creates a hub buffering up to buffer events per client for at most maxClients clients
+func NewHub(buffer, maxClients int) *Hub {
+	return &Hub{
+		clients:    map[*Subscription]struct{}{},
+		buffer:     buffer,
+		maxClients: maxClients,
+		now:        time.Now,
+	}
+}
+
+// Subscribe starts delivering the events matching filter to a new subscription. Close it when
+// the client goes away.
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) >= h.maxClients {
+		return nil, ErrTooManyClients
+	}
+	sub := &Subscription{hub: h, filter: filter, events: make(chan Event, h.buffer)}
+	h.clients[sub] = struct{}{}
+	return sub, nil
+}
+
+// Publish stamps an event with its ID, and its time when unset, and queues it for every
+// matching subscription. Subscriptions whose buffer is full are dropped.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	e.ID = h.lastID
+	if e.At.IsZero() {
+		e.At = h.now()
+	}
+	for sub := range h.clients {
+		if !sub.filter.Matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			sub.dropped = true
+			h.remove(sub)
+		}
+	}
+}
+
+// Clients returns the number of open subscriptions
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// remove ends a subscription; the caller holds the lock
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.clients[sub]; ok {
+		delete(h.clients, sub)
+		close(sub.events)
+	}
+}
+
+// Subscription is one client's feed of events
+type Subscription struct {
+	hub     *Hub
+	filter  Filter
+	events  chan Event
+	dropped bool // guarded by the hub's lock
+}
+
+// Events returns the client's events. The channel is closed when the subscription ends, by
+// Close or because the client fell behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped reports whether the hub ended the subscription because its buffer filled up
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+// Close ends the subscription. Closing it again does nothing.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
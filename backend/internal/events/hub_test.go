@@ -0,0 +1,63 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	event := Event{Type: TypeRecordCreated, UserID: "user1", DeviceID: "boiler"}
+	assert.True(t, Filter{}.Matches(event))
+	assert.True(t, Filter{UserID: "user1", DeviceID: "boiler", Types: []string{TypeDeviceState, TypeRecordCreated}}.Matches(event))
+	assert.False(t, Filter{UserID: "user2"}.Matches(event))
+	assert.False(t, Filter{DeviceID: "annex"}.Matches(event))
+	assert.False(t, Filter{Types: []string{TypeDeviceState}}.Matches(event))
+	// Device events name no user, so users only get their own
+	assert.False(t, Filter{UserID: "user1"}.Matches(Event{Type: TypeDeviceState, DeviceID: "boiler"}))
+}
+
+func TestHub_DropsSlowClients(t *testing.T) {
+	hub := NewHub(2, 10)
+	stalled, err := hub.Subscribe(Filter{})
+	require.NoError(t, err)
+	controller, err := hub.Subscribe(Filter{DeviceID: "boiler"})
+	require.NoError(t, err)
+
+	// The controller keeps up while the stalled tab reads nothing
+	var received []uint64
+	for i := 0; i < 5; i++ {
+		hub.Publish(Event{Type: TypeDeviceState, DeviceID: "boiler"})
+		received = append(received, (<-controller.Events()).ID)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, received)
+	assert.False(t, controller.Dropped())
+
+	// The stalled tab got what fit in its buffer before it was cut off
+	assert.True(t, stalled.Dropped())
+	var buffered []uint64
+	for event := range stalled.Events() {
+		buffered = append(buffered, event.ID)
+	}
+	assert.Equal(t, []uint64{1, 2}, buffered)
+	assert.Equal(t, 1, hub.Clients())
+
+	controller.Close()
+	controller.Close()
+	assert.Zero(t, hub.Clients())
+	_, open := <-controller.Events()
+	assert.False(t, open)
+}
+
+func TestHub_LimitsClients(t *testing.T) {
+	hub := NewHub(1, 1)
+	sub, err := hub.Subscribe(Filter{})
+	require.NoError(t, err)
+	_, err = hub.Subscribe(Filter{})
+	assert.ErrorIs(t, err, ErrTooManyClients)
+
+	sub.Close()
+	_, err = hub.Subscribe(Filter{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,619 @@
+// Package graphql executes read-only GraphQL queries over the values the services return.
+//
+// A Schema names the query's root fields and the resolvers behind them. Below the root the
+// types are the Go types the resolvers return: a struct's fields are queried by their JSON
+// names, maps by their keys, and values that marshal themselves, such as times, are scalars.
+// There is no introspection, and mutations are rejected: writes go through the REST API.
+package graphql
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Argument is an argument a root field takes
+type Argument struct {
+	Name    string
+	Type    string      // e.g. String!, Int or [String!]
+	Default interface{} // used when the argument is left out, e.g. 50 for an Int
+}
+
+// Field is a root field of the query
+type Field struct {
+	Args    []Argument
+	Resolve func(ctx context.Context, args Args) (interface{}, error)
+}
+
+// Schema is the root fields a query can select
+type Schema struct {
+	Query map[string]Field
+}
+
+// Args are a field's arguments, coerced to their types: String and ID to string, Int to int,
+// Float to float64, Boolean to bool and lists to []interface{}. Left out arguments without a
+// default are nil.
+type Args map[string]interface{}
+
+// String returns a String argument, empty when it is null
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns an Int argument, 0 when it is null
+func (a Args) Int(name string) int {
+	n, _ := a[name].(int)
+	return n
+}
+
+// Strings returns a list of String argument
+func (a Args) Strings(name string) []string {
+	list, _ := a[name].([]interface{})
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// Request is a GraphQL request as clients POST it
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error is an error in a response, with the path of the field it nulled
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response is the result of a request. Data is nil when the request couldn't be executed at
+// all; otherwise fields that failed are null and have an entry in Errors.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// requestError is an error that stops a request before execution
+type requestError string
+
+func (e requestError) Error() string { return string(e) }
+
+// Execute runs the request's query operation against the schema
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err == nil {
+		err = doc.checkFragments()
+	}
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	vars, err := coerceVariables(op.variables, req.Variables)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{doc: doc, vars: vars}
+	fields, err := e.collect(op.selections, nil)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	data := object{}
+	for _, f := range fields {
+		data = append(data, objectField{f.key, e.resolveRoot(ctx, s, f)})
+	}
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// checkFragments rejects fragments that spread themselves, directly or through others, so a
+// cycle fails the whole request rather than only the fields that reach it
+func (d *document) checkFragments() error {
+	done := map[string]bool{}
+	visiting := map[string]bool{}
+	var visit func(name string) error
+	var walk func(selections []selection) error
+	visit = func(name string) error {
+		if done[name] {
+			return nil
+		}
+		if visiting[name] {
+			return requestError(fmt.Sprintf("fragment %q spreads itself", name))
+		}
+		frag, ok := d.fragments[name]
+		if !ok {
+			return requestError(fmt.Sprintf("unknown fragment %q", name))
+		}
+		visiting[name] = true
+		if err := walk(frag.selections); err != nil {
+			return err
+		}
+		delete(visiting, name)
+		done[name] = true
+		return nil
+	}
+	walk = func(selections []selection) error {
+		for _, s := range selections {
+			var err error
+			switch {
+			case s.field != nil:
+				err = walk(s.field.selections)
+			case s.inline != nil:
+				err = walk(s.inline)
+			default:
+				err = visit(s.spread)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for name := range d.fragments {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operation picks the operation a request runs
+func (d *document) operation(name string) (*operation, error) {
+	var op *operation
+	switch {
+	case name != "":
+		for _, candidate := range d.operations {
+			if candidate.name == name {
+				op = candidate
+			}
+		}
+		if op == nil {
+			return nil, requestError(fmt.Sprintf("unknown operation %q", name))
+		}
+	case len(d.operations) > 1:
+		return nil, requestError("the document has several operations, so operationName is required")
+	default:
+		op = d.operations[0]
+	}
+	if op.kind != "query" {
+		return nil, requestError("only queries are supported; changes go through the REST API")
+	}
+	return op, nil
+}
+
+// coerceVariables checks the request's variables against the operation's definitions,
+// applying defaults
+func coerceVariables(defs []variableDefinition, given map[string]interface{}) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	for _, def := range defs {
+		value, ok := given[def.name]
+		if !ok && def.hasDefault {
+			value, ok = def.defaultValue, true
+		}
+		if !ok && def.typ.nonNull {
+			return nil, requestError(fmt.Sprintf("variable $%s of type %s is required", def.name, def.typ))
+		}
+		if !ok {
+			continue
+		}
+		coerced, err := coerce(value, def.typ, nil)
+		if err != nil {
+			return nil, requestError(fmt.Sprintf("variable $%s: %v", def.name, err))
+		}
+		vars[def.name] = coerced
+	}
+	return vars, nil
+}
+
+// coerce converts a value from a document or the request's JSON to t, resolving variables
+// when vars isn't nil
+func coerce(value interface{}, t *typeRef, vars map[string]interface{}) (interface{}, error) {
+	if name, ok := value.(variable); ok {
+		v, set := vars[string(name)]
+		if !set && t.nonNull {
+			return nil, fmt.Errorf("variable $%s is required", name)
+		}
+		// Checked again in case the variable's type differs from the argument's
+		return coerce(v, t, nil)
+	}
+	if value == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("expected %s, found null", t)
+		}
+		return nil, nil
+	}
+	if t.elem != nil {
+		list, ok := value.([]interface{})
+		if !ok {
+			// A single value stands for a list of one
+			list = []interface{}{value}
+		}
+		coerced := make([]interface{}, len(list))
+		for i, item := range list {
+			var err error
+			if coerced[i], err = coerce(item, t.elem, vars); err != nil {
+				return nil, err
+			}
+		}
+		return coerced, nil
+	}
+	switch t.name {
+	case "String", "ID":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		if n, ok := value.(int64); ok && t.name == "ID" {
+			return fmt.Sprint(n), nil
+		}
+	case "Int":
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case int64:
+			if n >= math.MinInt32 && n <= math.MaxInt32 {
+				return int(n), nil
+			}
+		case float64: // from JSON variables
+			if n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+				return int(n), nil
+			}
+		}
+	case "Float":
+		switch n := value.(type) {
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+	case "Boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %s", t.name)
+	}
+	return nil, fmt.Errorf("expected %s, found %v", t, value)
+}
+
+// executor runs one operation, gathering the errors of the fields that failed
+type executor struct {
+	doc    *document
+	vars   map[string]interface{}
+	errors []Error
+}
+
+// collectedField is a field of the response, with the selections of every field merged into it
+type collectedField struct {
+	key        string
+	name       string
+	arguments  map[string]interface{}
+	selections []selection
+}
+
+// collect flattens fragments into the fields to resolve, in the order they are selected.
+// Fields with the same response key are merged. visiting holds the fragments being spread,
+// to catch cycles.
+func (e *executor) collect(selections []selection, visiting map[string]bool) ([]*collectedField, error) {
+	var fields []*collectedField
+	byKey := map[string]*collectedField{}
+	var walk func(selections []selection) error
+	walk = func(selections []selection) error {
+		for _, s := range selections {
+			include, err := e.included(s.directives)
+			if err != nil {
+				return err
+			}
+			if !include {
+				continue
+			}
+			switch {
+			case s.field != nil:
+				if existing, ok := byKey[s.field.key()]; ok {
+					if existing.name != s.field.name {
+						return requestError(fmt.Sprintf("fields %s and %s can't both be named %q", existing.name, s.field.name, existing.key))
+					}
+					existing.selections = append(existing.selections, s.field.selections...)
+					continue
+				}
+				f := &collectedField{key: s.field.key(), name: s.field.name, arguments: s.field.arguments, selections: s.field.selections}
+				byKey[f.key] = f
+				fields = append(fields, f)
+			case s.inline != nil:
+				if err := walk(s.inline); err != nil {
+					return err
+				}
+			default:
+				frag, ok := e.doc.fragments[s.spread]
+				if !ok {
+					return requestError(fmt.Sprintf("unknown fragment %q", s.spread))
+				}
+				if visiting[s.spread] {
+					return requestError(fmt.Sprintf("fragment %q spreads itself", s.spread))
+				}
+				if visiting == nil {
+					visiting = map[string]bool{}
+				}
+				visiting[s.spread] = true
+				err := walk(frag.selections)
+				delete(visiting, s.spread)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return fields, walk(selections)
+}
+
+// included applies @skip and @include
+func (e *executor) included(directives []directive) (bool, error) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			return false, requestError(fmt.Sprintf("unknown directive @%s", d.name))
+		}
+		cond, err := coerce(d.arguments["if"], &typeRef{name: "Boolean", nonNull: true}, e.vars)
+		if err != nil {
+			return false, requestError(fmt.Sprintf("@%s(if:): %v", d.name, err))
+		}
+		if cond.(bool) == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *executor) fail(path []interface{}, format string, args ...interface{}) interface{} {
+	e.errors = append(e.errors, Error{Message: fmt.Sprintf(format, args...), Path: append([]interface{}{}, path...)})
+	return nil
+}
+
+// resolveRoot resolves a root field and completes its value
+func (e *executor) resolveRoot(ctx context.Context, s *Schema, f *collectedField) interface{} {
+	path := []interface{}{f.key}
+	if f.name == "__typename" {
+		return "Query"
+	}
+	field, ok := s.Query[f.name]
+	if !ok {
+		return e.fail(path, "cannot query field %q on type Query", f.name)
+	}
+	args := Args{}
+	for name := range f.arguments {
+		known := false
+		for _, arg := range field.Args {
+			known = known || arg.Name == name
+		}
+		if !known {
+			return e.fail(path, "unknown argument %q on field Query.%s", name, f.name)
+		}
+	}
+	for _, arg := range field.Args {
+		value, given := f.arguments[arg.Name]
+		if name, ok := value.(variable); ok {
+			_, given = e.vars[string(name)]
+		}
+		if !given {
+			value = arg.Default
+		}
+		t := mustParseType(arg.Type)
+		coerced, err := coerce(value, t, e.vars)
+		if err != nil {
+			return e.fail(path, "argument %q: %v", arg.Name, err)
+		}
+		args[arg.Name] = coerced
+	}
+
+	result, err := field.Resolve(ctx, args)
+	if err != nil {
+		return e.fail(path, "%v", err)
+	}
+	return e.complete(path, reflect.ValueOf(result), f)
+}
+
+// mustParseType parses a schema argument's type
+func mustParseType(s string) *typeRef {
+	p := &parser{src: s}
+	p.next()
+	return p.typeRef()
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// complete shapes a resolved value as the field's selections ask
+func (e *executor) complete(path []interface{}, v reflect.Value, f *collectedField) interface{} {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(marshalerType) || v.Type().Implements(textMarshalerType) {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch {
+	case isScalar(v.Type()):
+		if len(f.selections) > 0 {
+			return e.fail(path, "field %q is a scalar and can't have a selection", f.name)
+		}
+		return v.Interface()
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return []interface{}{}
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = e.complete(append(path, i), v.Index(i), f)
+		}
+		return list
+	case v.Kind() == reflect.Map:
+		// Free-form maps are returned whole without a selection
+		if v.IsNil() {
+			return nil
+		}
+		if len(f.selections) == 0 {
+			return v.Interface()
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return e.fail(path, "field %q can't have a selection", f.name)
+		}
+		fields, err := e.collect(f.selections, nil)
+		if err != nil {
+			return e.fail(path, "%v", err)
+		}
+		result := object{}
+		for _, sub := range fields {
+			entry := v.MapIndex(reflect.ValueOf(sub.name).Convert(v.Type().Key()))
+			result = append(result, objectField{sub.key, e.complete(append(path, sub.key), entry, sub)})
+		}
+		return result
+	case v.Kind() == reflect.Struct:
+		if len(f.selections) == 0 {
+			return e.fail(path, "field %q of type %s must have a selection of subfields", f.name, v.Type().Name())
+		}
+		fields, err := e.collect(f.selections, nil)
+		if err != nil {
+			return e.fail(path, "%v", err)
+		}
+		index := structFields(v.Type())
+		result := object{}
+		for _, sub := range fields {
+			subPath := append(path, sub.key)
+			if sub.name == "__typename" {
+				result = append(result, objectField{sub.key, v.Type().Name()})
+				continue
+			}
+			if len(sub.arguments) > 0 {
+				result = append(result, objectField{sub.key, e.fail(subPath, "field %q takes no arguments", sub.name)})
+				continue
+			}
+			fieldIndex, ok := index[sub.name]
+			if !ok {
+				result = append(result, objectField{sub.key, e.fail(subPath, "cannot query field %q on type %s", sub.name, v.Type().Name())})
+				continue
+			}
+			value, err := v.FieldByIndexErr(fieldIndex)
+			if err != nil {
+				// Behind a nil embedded pointer
+				result = append(result, objectField{sub.key, nil})
+				continue
+			}
+			result = append(result, objectField{sub.key, e.complete(subPath, value, sub)})
+		}
+		return result
+	default:
+		return e.fail(path, "field %q has a value GraphQL can't represent", f.name)
+	}
+}
+
+// isScalar reports whether values of t are leaves of the response
+func isScalar(t reflect.Type) bool {
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(marshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+var structFieldCache sync.Map // reflect.Type -> map[string][]int
+
+// structFields indexes a struct's fields by their JSON names, promoting the fields of embedded
+// structs as encoding/json does
+func structFields(t reflect.Type) map[string][]int {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+	index := map[string][]int{}
+	depth := map[string]int{}
+	var walk func(t reflect.Type, prefix []int)
+	walk = func(t reflect.Type, prefix []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			fieldIndex := append(append([]int{}, prefix...), i)
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, fieldIndex)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			// Shallower fields win over promoted ones
+			if d, taken := depth[name]; !taken || len(fieldIndex) < d {
+				index[name], depth[name] = fieldIndex, len(fieldIndex)
+			}
+		}
+	}
+	walk(t, nil)
+	structFieldCache.Store(t, index)
+	return index
+}
+
+// object is a response object, keeping its fields in the order they were selected
+type object []objectField
+
+type objectField struct {
+	key   string
+	value interface{}
+}
+
+// MarshalJSON implements json.Marshaler
+func (o object) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,119 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type testRecord struct {
+	testBase
+	Minutes float64                `json:"minutes"`
+	Note    *string                `json:"note,omitempty"`
+	Secret  string                 `json:"-"`
+	Tags    []string               `json:"tags"`
+	Extra   map[string]interface{} `json:"extra,omitempty"`
+}
+
+func testSchema() *Schema {
+	at := time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC)
+	note := "cold morning"
+	records := []testRecord{
+		{testBase{"r1", at}, 12, &note, "s", []string{"winter"}, map[string]interface{}{"inlet": 9.5}},
+		{testBase{"r2", at.Add(24 * time.Hour)}, 8, nil, "s", nil, nil},
+	}
+	return &Schema{Query: map[string]Field{
+		"records": {
+			Args: []Argument{{Name: "userId", Type: "String!"}, {Name: "limit", Type: "Int", Default: 10}},
+			Resolve: func(_ context.Context, args Args) (interface{}, error) {
+				if args.String("userId") != "user1" {
+					return nil, errors.New("no such user")
+				}
+				return records[:min(args.Int("limit"), len(records))], nil
+			},
+		},
+		"latest": {
+			Resolve: func(context.Context, Args) (interface{}, error) { return &records[1], nil },
+		},
+	}}
+}
+
+func execute(t *testing.T, query string, variables map[string]interface{}) (string, *Response) {
+	resp := testSchema().Execute(context.Background(), Request{Query: query, Variables: variables})
+	data, err := json.Marshal(resp.Data)
+	require.NoError(t, err)
+	return string(data), resp
+}
+
+func TestExecute_SelectsFieldsByJSONName(t *testing.T) {
+	data, resp := execute(t, `
+		# The dashboard's query
+		query Dashboard($user: String!, $limit: Int = 1) {
+			records(userId: $user, limit: $limit) { ...ids minutes note tags extra }
+			newest: latest { __typename id, createdAt note }
+		}
+		fragment ids on Record { id }`, map[string]interface{}{"user": "user1"})
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, `{"records":[{"id":"r1","minutes":12,"note":"cold morning","tags":["winter"],"extra":{"inlet":9.5}}],`+
+		`"newest":{"__typename":"testRecord","id":"r2","createdAt":"2026-03-02T07:00:00Z","note":null}}`, data)
+
+	data, resp = execute(t, `{ records(userId: "user1") { id ... @skip(if: true) { minutes } extra { inlet } } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, `{"records":[{"id":"r1","extra":{"inlet":9.5}},{"id":"r2","extra":null}]}`, data)
+}
+
+func TestExecute_FieldErrorsNullTheField(t *testing.T) {
+	data, resp := execute(t, `{ records(userId: "nobody") { id } latest { id secret minutes { x } } }`, nil)
+	assert.Equal(t, `{"records":null,"latest":{"id":"r2","secret":null,"minutes":null}}`, data)
+	require.Len(t, resp.Errors, 3)
+	assert.Equal(t, Error{Message: "no such user", Path: []interface{}{"records"}}, resp.Errors[0])
+	assert.Equal(t, Error{Message: `cannot query field "secret" on type testRecord`, Path: []interface{}{"latest", "secret"}}, resp.Errors[1])
+	assert.Contains(t, resp.Errors[2].Message, "can't have a selection")
+
+	_, resp = execute(t, `{ latest }`, nil)
+	assert.Contains(t, resp.Errors[0].Message, "must have a selection of subfields")
+	_, resp = execute(t, `{ records(userId: "user1", limit: "ten") { id } }`, nil)
+	assert.Equal(t, `argument "limit": expected Int, found ten`, resp.Errors[0].Message)
+	_, resp = execute(t, `{ records { id } }`, nil)
+	assert.Contains(t, resp.Errors[0].Message, "expected String!, found null")
+}
+
+func TestExecute_RejectsRequestsThatCantRun(t *testing.T) {
+	for query, message := range map[string]string{
+		`mutation { deleteEverything }`:                         "only queries are supported",
+		`{ records(userId: "user1") { id }`:                     "syntax error at 1:34",
+		`query A { latest { id } } query B { latest { id } }`:   "operationName is required",
+		`query($u: String!) { records(userId: $u) { id } }`:     "variable $u of type String! is required",
+		`{ latest { ...loop } } fragment loop on R { ...loop }`: `fragment "loop" spreads itself`,
+		`{ latest @cached { id } }`:                             "unknown directive @cached",
+	} {
+		_, resp := execute(t, query, nil)
+		assert.Nil(t, resp.Data, query)
+		require.Len(t, resp.Errors, 1, query)
+		assert.Contains(t, resp.Errors[0].Message, message, query)
+	}
+}
+
+func TestParse_Values(t *testing.T) {
+	doc, err := parse(`{ f(a: -1.5e2, b: "tab\there é", c: [1 2], d: {x: null, y: RED}, e: """
+		  block
+		    indented
+		""") }`)
+	require.NoError(t, err)
+	args := doc.operations[0].selections[0].field.arguments
+	assert.Equal(t, -150.0, args["a"])
+	assert.Equal(t, "tab\there é", args["b"])
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, args["c"])
+	assert.Equal(t, map[string]interface{}{"x": nil, "y": enumValue("RED")}, args["d"])
+	assert.Equal(t, "block\n  indented", args["e"])
+}
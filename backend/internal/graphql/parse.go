@@ -0,0 +1,541 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed request: its operations and the fragments they spread
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	variables  []variableDefinition
+	selections []selection
+}
+
+type variableDefinition struct {
+	name         string
+	typ          *typeRef
+	defaultValue interface{} // nil when there is none
+	hasDefault   bool
+}
+
+// typeRef is a type as written in a variable definition, e.g. [String!]!
+type typeRef struct {
+	name    string   // for a named type
+	elem    *typeRef // for a list
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type fragment struct {
+	name       string
+	selections []selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	field      *field
+	spread     string      // name of the spread fragment
+	inline     []selection // an inline fragment's selections
+	directives []directive
+}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  map[string]interface{}
+	selections []selection
+}
+
+// key is the field's name in the response
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type directive struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+// Values in a document are Go values (string, int64, float64, bool, nil, []interface{} and
+// map[string]interface{}) except for these
+type (
+	variable  string // $name
+	enumValue string
+)
+
+// SyntaxError is a request that isn't valid GraphQL
+type SyntaxError struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// parser is a recursive descent parser over the executable part of the GraphQL grammar
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func parse(src string) (doc *document, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, syntaxErr
+		}
+	}()
+	p := &parser{src: src}
+	p.next()
+	doc = &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.tok.kind == tokenPunct && p.tok.value == "{":
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.selectionSet()})
+		case p.tok.kind == tokenName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			doc.operations = append(doc.operations, p.operation())
+		case p.tok.kind == tokenName && p.tok.value == "fragment":
+			f := p.fragment()
+			if _, taken := doc.fragments[f.name]; taken {
+				p.fail("fragment %q is defined twice", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			p.fail("expected an operation or fragment, found %s", p.describe())
+		}
+	}
+	if len(doc.operations) == 0 {
+		p.fail("the document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	line, column := 1, 1
+	for _, r := range p.src[:min(p.tok.pos, len(p.src))] {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	panic(&SyntaxError{Message: fmt.Sprintf(format, args...), Line: line, Column: column})
+}
+
+func (p *parser) describe() string {
+	if p.tok.kind == tokenEOF {
+		return "the end of the document"
+	}
+	return strconv.Quote(p.tok.value)
+}
+
+// next reads the next token, skipping whitespace, commas and comments
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if strings.HasPrefix(p.src[p.pos:], "\ufeff") {
+			p.pos += 3
+		} else {
+			break
+		}
+	}
+	start := p.pos
+	p.tok = token{kind: tokenEOF, pos: start}
+	if p.pos >= len(p.src) {
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokenPunct, value: "...", pos: start}
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokenPunct, value: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenName, value: p.src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		p.number()
+	case c == '"':
+		p.string()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.tok.pos = start
+		p.fail("unexpected character %q", r)
+	}
+}
+
+func (p *parser) number() {
+	start := p.pos
+	kind := tokenInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		from := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == from {
+			p.tok.pos = p.pos
+			p.fail("expected a digit")
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokenFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokenFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	p.tok = token{kind: kind, value: p.src[start:p.pos], pos: start}
+}
+
+func (p *parser) string() {
+	start := p.pos
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			p.tok.pos = start
+			p.fail("unterminated block string")
+		}
+		p.tok = token{kind: tokenString, value: blockString(p.src[p.pos+3 : p.pos+3+end]), pos: start}
+		p.pos += 6 + end
+		return
+	}
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.tok.pos = start
+			p.fail("unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			break
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			p.tok.pos = p.pos
+			p.fail("unterminated string")
+		}
+		escape := p.src[p.pos+1]
+		p.pos += 2
+		switch escape {
+		case '"', '\\', '/':
+			b.WriteByte(escape)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			code, err := strconv.ParseUint(p.src[p.pos:min(p.pos+4, len(p.src))], 16, 32)
+			if err != nil || p.pos+4 > len(p.src) {
+				p.tok.pos = p.pos - 2
+				p.fail("invalid unicode escape")
+			}
+			b.WriteRune(rune(code))
+			p.pos += 4
+		default:
+			p.tok.pos = p.pos - 2
+			p.fail("invalid escape \\%c", escape)
+		}
+	}
+	p.tok = token{kind: tokenString, value: b.String(), pos: start}
+}
+
+// blockString removes the indentation block strings share and their leading and trailing
+// blank lines
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(raw, "\r\n", "\n"), `\"""`, `"""`), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		lines[i] = lines[i][min(indent, len(lines[i])):]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) skip(punct string) bool {
+	if p.peek(punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) {
+	if !p.skip(punct) {
+		p.fail("expected %q, found %s", punct, p.describe())
+	}
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokenName {
+		p.fail("expected a name, found %s", p.describe())
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+func (p *parser) operation() *operation {
+	op := &operation{kind: p.name()}
+	if p.tok.kind == tokenName {
+		op.name = p.name()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			p.expect("$")
+			def := variableDefinition{name: p.name()}
+			p.expect(":")
+			def.typ = p.typeRef()
+			if p.skip("=") {
+				def.defaultValue, def.hasDefault = p.value(true), true
+			}
+			op.variables = append(op.variables, def)
+		}
+	}
+	p.directives()
+	op.selections = p.selectionSet()
+	return op
+}
+
+func (p *parser) typeRef() *typeRef {
+	t := &typeRef{}
+	if p.skip("[") {
+		t.elem = p.typeRef()
+		p.expect("]")
+	} else {
+		t.name = p.name()
+	}
+	t.nonNull = p.skip("!")
+	return t
+}
+
+func (p *parser) fragment() *fragment {
+	p.next()
+	f := &fragment{name: p.name()}
+	if f.name == "on" {
+		p.fail("a fragment can't be named on")
+	}
+	if p.tok.kind != tokenName || p.tok.value != "on" {
+		p.fail("expected a type condition, found %s", p.describe())
+	}
+	p.next()
+	p.name()
+	p.directives()
+	f.selections = p.selectionSet()
+	return f
+}
+
+func (p *parser) selectionSet() []selection {
+	p.expect("{")
+	var selections []selection
+	for !p.skip("}") {
+		if p.skip("...") {
+			if p.tok.kind == tokenName && p.tok.value != "on" {
+				s := selection{spread: p.name()}
+				s.directives = p.directives()
+				selections = append(selections, s)
+				continue
+			}
+			// Every value has one type here, so a type condition always holds
+			if p.tok.kind == tokenName {
+				p.next()
+				p.name()
+			}
+			s := selection{directives: p.directives()}
+			s.inline = p.selectionSet()
+			selections = append(selections, s)
+			continue
+		}
+		f := &field{name: p.name()}
+		if p.skip(":") {
+			f.alias, f.name = f.name, p.name()
+		}
+		f.arguments = p.arguments()
+		s := selection{field: f, directives: p.directives()}
+		if p.peek("{") {
+			f.selections = p.selectionSet()
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		p.fail("a selection set can't be empty")
+	}
+	return selections
+}
+
+func (p *parser) arguments() map[string]interface{} {
+	if !p.skip("(") {
+		return nil
+	}
+	arguments := map[string]interface{}{}
+	for !p.skip(")") {
+		name := p.name()
+		if _, taken := arguments[name]; taken {
+			p.fail("argument %q is given twice", name)
+		}
+		p.expect(":")
+		arguments[name] = p.value(false)
+	}
+	return arguments
+}
+
+func (p *parser) directives() []directive {
+	var directives []directive
+	for p.skip("@") {
+		directives = append(directives, directive{name: p.name(), arguments: p.arguments()})
+	}
+	return directives
+}
+
+// value parses a value; a constant one, such as a variable's default, can't hold variables
+func (p *parser) value(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.fail("a default value can't use a variable")
+			}
+			p.next()
+			return variable(p.name())
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.skip("]") {
+				list = append(list, p.value(constant))
+			}
+			return list
+		case "{":
+			p.next()
+			object := map[string]interface{}{}
+			for !p.skip("}") {
+				name := p.name()
+				p.expect(":")
+				object[name] = p.value(constant)
+			}
+			return object
+		}
+	case tokenInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			p.fail("integer %s is out of range", tok.value)
+		}
+		return n
+	case tokenFloat:
+		p.next()
+		f, _ := strconv.ParseFloat(tok.value, 64)
+		return f
+	case tokenString:
+		p.next()
+		return tok.value
+	case tokenName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.value)
+	}
+	p.fail("expected a value, found %s", p.describe())
+	return nil
+}
@@ -0,0 +1,660 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles HTTP requests for the admin API
+type AdminHandler struct {
+	userService    *services.UserService
+	auditService   *services.AuditService
+	rescoreService *services.RescoreService
+	recordService  *services.RecordService
+	operations     *services.OperationService
+	maintenance    *services.MaintenanceService
+	canary         *services.CanaryPredictor // nil when no canary version is configured
+	maxHeatingTime float64                   // heating times above it are flagged by the integrity check
+}
+
+// NewAdminHandler creates a new admin handler instance
+func NewAdminHandler(userService *services.UserService, auditService *services.AuditService, rescoreService *services.RescoreService, recordService *services.RecordService, operations *services.OperationService, maintenance *services.MaintenanceService, canary *services.CanaryPredictor, maxHeatingTime float64) *AdminHandler {
+	return &AdminHandler{
+		userService:    userService,
+		auditService:   auditService,
+		rescoreService: rescoreService,
+		recordService:  recordService,
+		operations:     operations,
+		maintenance:    maintenance,
+		canary:         canary,
+		maxHeatingTime: maxHeatingTime,
+	}
+}
+
+// audit records an admin action; failures are logged but never block the action itself
+func (h *AdminHandler) audit(c *gin.Context, action, target, details string) {
+	if err := h.auditService.Record(adminActor(c), action, target, details); err != nil {
+		log.Printf("Failed to write audit entry for %s on %s: %v", action, target, err)
+	}
+}
+
+// ListUsers handles GET /api/admin/users
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	users, err := h.userService.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve users") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "users.list", "", "")
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+	})
+}
+
+// GetUser handles GET /api/admin/users/:id
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, err := h.userService.GetUser(c.Param("id"))
+	if err != nil {
+		h.userError(c, err, "Failed to retrieve user")
+		return
+	}
+
+	h.audit(c, "users.view", user.UserID, "")
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// DisableUser handles POST /api/admin/users/:id/disable
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+// EnableUser handles POST /api/admin/users/:id/enable
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *AdminHandler) setDisabled(c *gin.Context, disabled bool) {
+	userID := c.Param("id")
+	if err := h.userService.SetDisabled(userID, disabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to update user") + ": " + err.Error(),
+		})
+		return
+	}
+
+	action := "users.enable"
+	message := "User enabled successfully"
+	if disabled {
+		action = "users.disable"
+		message = "User disabled successfully"
+	}
+	h.audit(c, action, userID, "")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, message),
+	})
+}
+
+// ResetUserModel handles POST /api/admin/users/:id/model/reset
+func (h *AdminHandler) ResetUserModel(c *gin.Context) {
+	userID := c.Param("id")
+	excluded, err := h.userService.ResetModel(userID, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to reset model") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "users.model_reset", userID, fmt.Sprintf("excluded %d records from training", excluded))
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"message":         t(c, "Model reset successfully"),
+		"excludedRecords": excluded,
+	})
+}
+
+// MergeUser handles POST /api/admin/users/:id/merge?dryRun=, moving the history, settings and
+// devices of the user in from into this one, e.g. after a couple logged under two IDs for
+// months. The merged user is disabled. A dry run reports what would move.
+func (h *AdminHandler) MergeUser(c *gin.Context) {
+	var req struct {
+		From string `json:"from" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	into := c.Param("id")
+	dryRun := isDryRun(c)
+	report, err := h.userService.MergeUsers(into, req.From, dryRun)
+	if errors.Is(err, services.ErrMergeSameUser) {
+		validationError(c, "from", "ne", "A user can't be merged into itself")
+		return
+	}
+	if err != nil {
+		h.userError(c, err, "Failed to merge users")
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun": true,
+			"merge":  report,
+		})
+		return
+	}
+	h.audit(c, "users.merge", into, fmt.Sprintf("merged %s: %d records, %d renamed", req.From, report.Counts["records"], len(report.Renamed)))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Users merged successfully"),
+		"merge":   report,
+	})
+}
+
+// ExportPredictionProfile handles GET /api/admin/prediction-profile: the instance's global
+// prediction settings and every user's overrides
+func (h *AdminHandler) ExportPredictionProfile(c *gin.Context) {
+	settings, err := h.userService.PredictionSettings("")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to export prediction profile") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "prediction_profile.export", "", "")
+	c.JSON(http.StatusOK, services.PredictionProfile{
+		Version:    services.PredictionProfileVersion,
+		ExportedAt: time.Now().UTC(),
+		Global:     config.PredictionSettings(),
+		Users:      settings,
+	})
+}
+
+// ImportPredictionProfile handles POST /api/admin/prediction-profile?dryRun=. The user overrides are
+// applied at once, optionally renamed through userMap; global settings take effect on a restart
+// with PREDICTION_PROFILE, so the response lists the ones that differ from this instance.
+func (h *AdminHandler) ImportPredictionProfile(c *gin.Context) {
+	var req struct {
+		services.PredictionProfile
+		UserMap map[string]string `json:"userMap"` // exported user ID to the user ID on this instance
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	for i, user := range req.Users {
+		if target, ok := req.UserMap[user.UserID]; ok {
+			req.Users[i].UserID = target
+		}
+	}
+	if predictionProfileError(c, req.Validate()) {
+		return
+	}
+
+	if isDryRun(c) {
+		preview, err := h.userService.PreviewPredictionSettings(req.Users)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": t(c, "Failed to import prediction profile") + ": " + err.Error(),
+			})
+			return
+		}
+		changes := req.GlobalChanges(config.PredictionSettings())
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun":          true,
+			"counts":          preview.Counts,
+			"sampleIds":       preview.SampleIDs,
+			"globalChanges":   changes,
+			"restartRequired": len(changes) > 0,
+		})
+		return
+	}
+
+	if err := h.userService.ImportPredictionSettings(req.Users); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to import prediction profile") + ": " + err.Error(),
+		})
+		return
+	}
+
+	changes := req.GlobalChanges(config.PredictionSettings())
+	h.audit(c, "prediction_profile.import", "", fmt.Sprintf("%d users, %d global settings differ", len(req.Users), len(changes)))
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"users":           len(req.Users),
+		"globalChanges":   changes,
+		"restartRequired": len(changes) > 0,
+	})
+}
+
+// ImpersonateUser handles POST /api/admin/users/:id/impersonate.
+// Users are identified by their user ID alone, so impersonation hands the support
+// client the identity to act under; the reason is kept in the audit log.
+func (h *AdminHandler) ImpersonateUser(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUser(c.Param("id"))
+	if err != nil {
+		h.userError(c, err, "Failed to impersonate user")
+		return
+	}
+
+	h.audit(c, "users.impersonate", user.UserID, req.Reason)
+	c.JSON(http.StatusOK, gin.H{
+		"userId": user.UserID,
+		"user":   user,
+	})
+}
+
+// ListAuditLog handles GET /api/admin/audit
+func (h *AdminHandler) ListAuditLog(c *gin.Context) {
+	entries, err := h.auditService.List(200)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve audit log") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
+
+// CreateRescoreJob handles POST /api/admin/rescore-jobs
+func (h *AdminHandler) CreateRescoreJob(c *gin.Context) {
+	var req struct {
+		Days int `json:"days" binding:"omitempty,min=1,max=365"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 30
+	}
+
+	job, err := h.rescoreService.CreateJob(adminActor(c), req.Days)
+	if err != nil {
+		if errors.Is(err, services.ErrRescoreJobActive) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": t(c, "A re-score job is already in progress"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to create re-score job") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "rescore.create", job.ID, fmt.Sprintf("predictor %s, last %d days", job.Predictor, req.Days))
+	c.JSON(http.StatusAccepted, gin.H{
+		"job": job,
+	})
+}
+
+// ListRescoreJobs handles GET /api/admin/rescore-jobs
+func (h *AdminHandler) ListRescoreJobs(c *gin.Context) {
+	jobs, err := h.rescoreService.ListJobs(50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve re-score jobs") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": jobs,
+	})
+}
+
+// GetRescoreJob handles GET /api/admin/rescore-jobs/:id
+func (h *AdminHandler) GetRescoreJob(c *gin.Context) {
+	job, err := h.rescoreService.GetJob(c.Param("id"))
+	if err != nil {
+		h.rescoreError(c, err, "Failed to retrieve re-score job")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job": job,
+	})
+}
+
+// ListRescoreResults handles GET /api/admin/rescore-jobs/:id/results
+func (h *AdminHandler) ListRescoreResults(c *gin.Context) {
+	results, err := h.rescoreService.ListResults(c.Param("id"))
+	if err != nil {
+		h.rescoreError(c, err, "Failed to retrieve re-score results")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+	})
+}
+
+// ListOperations handles GET /api/admin/operations?kind=: the most recent background
+// operations of every kind (re-scores, backups, compaction), newest first
+func (h *AdminHandler) ListOperations(c *gin.Context) {
+	operations, err := h.operations.List(c.Query("kind"), 50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve operations") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"operations": operations,
+	})
+}
+
+// GetOperation handles GET /api/admin/operations/:id
+func (h *AdminHandler) GetOperation(c *gin.Context) {
+	operation, err := h.operations.Get(c.Param("id"))
+	if err != nil {
+		h.operationError(c, err, "Failed to retrieve operation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"operation": operation,
+	})
+}
+
+// CancelOperation handles POST /api/admin/operations/:id/cancel. The operation stops in the
+// background; its status turns canceled once it has.
+func (h *AdminHandler) CancelOperation(c *gin.Context) {
+	operation, err := h.operations.Cancel(c.Param("id"))
+	if err != nil {
+		h.operationError(c, err, "Failed to cancel operation")
+		return
+	}
+
+	h.audit(c, "operation.cancel", operation.ID, operation.Kind)
+	c.JSON(http.StatusAccepted, gin.H{
+		"operation": operation,
+	})
+}
+
+// CreateBackup handles POST /api/admin/backups, copying the database in the background
+func (h *AdminHandler) CreateBackup(c *gin.Context) {
+	operation, err := h.maintenance.Backup(adminActor(c))
+	if err != nil {
+		h.operationError(c, err, "Failed to start backup")
+		return
+	}
+
+	h.audit(c, "backup.create", operation.ID, "")
+	c.JSON(http.StatusAccepted, gin.H{
+		"operation": operation,
+	})
+}
+
+// CompactDatabase handles POST /api/admin/compact, rebuilding the database file in the background
+func (h *AdminHandler) CompactDatabase(c *gin.Context) {
+	operation, err := h.maintenance.Compact(adminActor(c))
+	if err != nil {
+		h.operationError(c, err, "Failed to start compaction")
+		return
+	}
+
+	h.audit(c, "database.compact", operation.ID, "")
+	c.JSON(http.StatusAccepted, gin.H{
+		"operation": operation,
+	})
+}
+
+// ArchiveRecords handles POST /api/admin/records/archive?dryRun=, excluding records older than
+// RECORDS_INFLUENCE_DAYS from training in the background, as the daily sweep does. A dry run
+// reports what would be archived.
+func (h *AdminHandler) ArchiveRecords(c *gin.Context) {
+	if isDryRun(c) {
+		preview, err := h.maintenance.PreviewArchive()
+		if err != nil {
+			h.archiveError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun":    true,
+			"counts":    preview.Counts,
+			"sampleIds": preview.SampleIDs,
+		})
+		return
+	}
+
+	operation, err := h.maintenance.Archive(adminActor(c))
+	if err != nil {
+		h.archiveError(c, err)
+		return
+	}
+
+	h.audit(c, "records.archive", operation.ID, "")
+	c.JSON(http.StatusAccepted, gin.H{
+		"operation": operation,
+	})
+}
+
+// archiveError maps record archiving errors to responses
+func (h *AdminHandler) archiveError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrArchivingDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Record archiving is not configured")})
+		return
+	}
+	h.operationError(c, err, "Failed to start record archiving")
+}
+
+// CheckIntegrity handles GET /api/admin/integrity: every record whose values are impossible,
+// with the fix a bulk fix would store
+func (h *AdminHandler) CheckIntegrity(c *gin.Context) {
+	report, err := h.recordService.ScanIntegrity(h.maxHeatingTime, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to check history integrity") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "integrity.check", "", fmt.Sprintf("%d records with %d issues", report.Records, len(report.Issues)))
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// GetDataQuality handles GET /api/admin/data-quality
+func (h *AdminHandler) GetDataQuality(c *gin.Context) {
+	report, err := h.recordService.DataQuality(h.maxHeatingTime, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to summarise data quality") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dataQuality": report,
+	})
+}
+
+// integrityRequest selects the flagged records a bulk action applies to; no IDs means all of them
+type integrityRequest struct {
+	RecordIDs []string `json:"recordIds"`
+}
+
+// FixIntegrity handles POST /api/admin/integrity/fix. Records with an issue that can't be fixed,
+// such as a future date, are skipped and can be excluded instead.
+func (h *AdminHandler) FixIntegrity(c *gin.Context) {
+	var req integrityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	fixed, skipped, err := h.recordService.FixIntegrity(req.RecordIDs, h.maxHeatingTime, time.Now(), "admin:"+adminActor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to fix records") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "integrity.fix", "", fmt.Sprintf("fixed %d records, skipped %d", fixed, skipped))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"fixed":   fixed,
+		"skipped": skipped,
+	})
+}
+
+// ExcludeIntegrity handles POST /api/admin/integrity/exclude
+func (h *AdminHandler) ExcludeIntegrity(c *gin.Context) {
+	var req integrityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	excluded, err := h.recordService.ExcludeIntegrity(req.RecordIDs, h.maxHeatingTime, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to exclude records") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "integrity.exclude", "", fmt.Sprintf("excluded %d records from training", excluded))
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"excludedRecords": excluded,
+	})
+}
+
+// SignIntegrity handles POST /api/admin/integrity/sign: accepts the current values of records
+// that fail their checksum or predate signing
+func (h *AdminHandler) SignIntegrity(c *gin.Context) {
+	var req integrityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	signed, err := h.recordService.SignIntegrity(req.RecordIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to sign records") + ": " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "integrity.sign", "", fmt.Sprintf("signed %d records", signed))
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"signedRecords": signed,
+	})
+}
+
+// GetCanary handles GET /api/admin/canary
+func (h *AdminHandler) GetCanary(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"canary": h.canary.Status(),
+	})
+}
+
+// UpdateCanary handles POST /api/admin/canary. Setting a percentage clears a previous
+// automatic rollback; 0 stops serving the canary.
+func (h *AdminHandler) UpdateCanary(c *gin.Context) {
+	var req struct {
+		Percent *int     `json:"percent" binding:"required,min=0,max=100"`
+		Margin  *float64 `json:"margin" binding:"omitempty,min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	margin := h.canary.Status().Margin
+	if req.Margin != nil {
+		margin = *req.Margin
+	}
+	h.canary.Configure(*req.Percent, margin)
+
+	status := h.canary.Status()
+	h.audit(c, "canary.update", status.Canary, fmt.Sprintf("percent %d, margin %g", status.Percent, status.Margin))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Canary updated successfully"),
+		"canary":  status,
+	})
+}
+
+func (h *AdminHandler) rescoreError(c *gin.Context, err error, message string) {
+	if errors.Is(err, services.ErrRescoreJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": t(c, "Re-score job not found"),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": t(c, message) + ": " + err.Error(),
+	})
+}
+
+func (h *AdminHandler) operationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrOperationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": t(c, "Operation not found"),
+		})
+	case errors.Is(err, services.ErrOperationActive):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": t(c, "An operation of that kind is already in progress"),
+		})
+	case errors.Is(err, services.ErrOperationFinished):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": t(c, "Operation has already finished"),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, message) + ": " + err.Error(),
+		})
+	}
+}
+
+func (h *AdminHandler) userError(c *gin.Context, err error, message string) {
+	if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": t(c, "User not found"),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": t(c, message) + ": " + err.Error(),
+	})
+}
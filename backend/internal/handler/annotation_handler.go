@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnnotationHandler handles HTTP requests for household event annotations
+type AnnotationHandler struct {
+	annotationService *services.AnnotationService
+}
+
+// NewAnnotationHandler creates a new annotation handler instance
+func NewAnnotationHandler(annotationService *services.AnnotationService) *AnnotationHandler {
+	return &AnnotationHandler{
+		annotationService: annotationService,
+	}
+}
+
+// CreateAnnotation handles POST /api/annotations
+func (h *AnnotationHandler) CreateAnnotation(c *gin.Context) {
+	var req struct {
+		UserID          string    `json:"userId" binding:"required"`
+		Date            time.Time `json:"date"`
+		Kind            string    `json:"kind" binding:"required"`
+		Note            string    `json:"note"`
+		HardwareChanged *bool     `json:"hardwareChanged"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if !services.IsValidAnnotationKind(req.Kind) {
+		validationError(c, "kind", "oneof", "Unknown annotation kind")
+		return
+	}
+
+	annotation := models.Annotation{
+		UserID:          req.UserID,
+		Date:            req.Date,
+		Kind:            req.Kind,
+		Note:            req.Note,
+		HardwareChanged: services.ImpliesHardwareChange(req.Kind),
+	}
+	if req.HardwareChanged != nil {
+		annotation.HardwareChanged = *req.HardwareChanged
+	}
+
+	if err := h.annotationService.CreateAnnotation(&annotation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to save annotation") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"annotation": annotation,
+	})
+}
+
+// ListAnnotations handles GET /api/annotations?userId=&from=&to=
+func (h *AnnotationHandler) ListAnnotations(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		validationError(c, "from", "date", err.Error())
+		return
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		validationError(c, "to", "date", err.Error())
+		return
+	}
+
+	annotations, err := h.annotationService.ListAnnotations(userID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve annotations") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"annotations": annotations,
+	})
+}
+
+// DeleteAnnotation handles POST /api/annotations/delete
+func (h *AnnotationHandler) DeleteAnnotation(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.annotationService.DeleteAnnotation(req.UserID, req.ID); err != nil {
+		if errors.Is(err, services.ErrAnnotationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": t(c, "Annotation not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to delete annotation") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Annotation deleted successfully"),
+	})
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionCookie   = "heat_session"
+	oidcStateCookie = "heat_oidc_state"
+	oidcNonceCookie = "heat_oidc_nonce"
+)
+
+// AuthHandler handles OpenID Connect login and browser sessions
+type AuthHandler struct {
+	oidcService    *services.OIDCService
+	sessionService *services.SessionService
+	userService    *services.UserService
+	cfg            config.OIDCConfig
+}
+
+// NewAuthHandler creates a new auth handler instance
+func NewAuthHandler(oidcService *services.OIDCService, sessionService *services.SessionService, userService *services.UserService, cfg config.OIDCConfig) *AuthHandler {
+	return &AuthHandler{
+		oidcService:    oidcService,
+		sessionService: sessionService,
+		userService:    userService,
+		cfg:            cfg,
+	}
+}
+
+// secureCookies is true when the app is served over HTTPS
+func (h *AuthHandler) secureCookies() bool {
+	return strings.HasPrefix(h.cfg.RedirectURL, "https://")
+}
+
+// Login handles GET /api/auth/oidc/login by redirecting to the identity provider
+func (h *AuthHandler) Login(c *gin.Context) {
+	state, err := randomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to start login")})
+		return
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to start login")})
+		return
+	}
+
+	target, err := h.oidcService.AuthCodeURL(c.Request.Context(), state, nonce)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": t(c, "Identity provider unavailable") + ": " + err.Error()})
+		return
+	}
+
+	// Lax so the cookies survive the top-level redirect back from the provider
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, 600, "/api/auth", "", h.secureCookies(), true)
+	c.SetCookie(oidcNonceCookie, nonce, 600, "/api/auth", "", h.secureCookies(), true)
+	c.Redirect(http.StatusFound, target)
+}
+
+// Callback handles GET /api/auth/oidc/callback
+func (h *AuthHandler) Callback(c *gin.Context) {
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": t(c, "Login failed") + ": " + errParam})
+		return
+	}
+
+	state, _ := c.Cookie(oidcStateCookie)
+	nonce, _ := c.Cookie(oidcNonceCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth", "", h.secureCookies(), true)
+	c.SetCookie(oidcNonceCookie, "", -1, "/api/auth", "", h.secureCookies(), true)
+	if state == "" || c.Query("state") != state {
+		c.JSON(http.StatusBadRequest, gin.H{"error": t(c, "Login state mismatch, please try again")})
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": t(c, "Authorization code is required")})
+		return
+	}
+
+	identity, err := h.oidcService.Exchange(c.Request.Context(), code, nonce)
+	if err != nil {
+		if errors.Is(err, services.ErrOIDCAccessDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": t(c, "Your account is not allowed to use this app")})
+			return
+		}
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": t(c, "Login failed")})
+		return
+	}
+
+	disabled, err := h.userService.IsDisabled(identity.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to check account status") + ": " + err.Error()})
+		return
+	}
+	if disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": t(c, "This account has been disabled")})
+		return
+	}
+
+	if err := h.userService.UpsertIdentity(identity.Subject, identity.Email, identity.Name, identity.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to save user") + ": " + err.Error()})
+		return
+	}
+
+	token, _, err := h.sessionService.Create(identity.Subject, identity.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to create session") + ": " + err.Error()})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, token, int(h.cfg.SessionTTL.Seconds()), "/", "", h.secureCookies(), true)
+	c.Redirect(http.StatusFound, h.cfg.PostLoginRedirect)
+}
+
+// Me handles GET /api/auth/me and returns the logged-in user
+func (h *AuthHandler) Me(c *gin.Context) {
+	token, _ := c.Cookie(sessionCookie)
+	session, err := h.sessionService.Lookup(token)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": t(c, "Not logged in")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to load session") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userId":    session.UserID,
+		"role":      session.Role,
+		"expiresAt": session.ExpiresAt,
+	})
+}
+
+// Logout handles POST /api/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if token, err := c.Cookie(sessionCookie); err == nil {
+		if err := h.sessionService.Delete(token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to end session") + ": " + err.Error()})
+			return
+		}
+	}
+	c.SetCookie(sessionCookie, "", -1, "/", "", h.secureCookies(), true)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Logged out"),
+	})
+}
+
+func randomToken() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"heat-logger/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler reports the configuration the server runs with
+type ConfigHandler struct {
+	cfg *config.Config
+}
+
+// NewConfigHandler creates a new config handler instance
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{cfg: cfg}
+}
+
+// GetEffectiveConfig handles GET /api/admin/config: every setting the server runs with, secrets
+// redacted, and the ones that differ from the defaults. Comparing the diffs of two instances
+// shows what sets them apart.
+func (h *ConfigHandler) GetEffectiveConfig(c *gin.Context) {
+	defaults, err := config.Defaults()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load default configuration") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"config": h.cfg.Settings(),
+		"diff":   h.cfg.Diff(defaults),
+	})
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CorrelationHandler handles HTTP requests for the traces of heating runs
+type CorrelationHandler struct {
+	service *services.CorrelationService
+}
+
+// NewCorrelationHandler creates a new correlation handler instance
+func NewCorrelationHandler(service *services.CorrelationService) *CorrelationHandler {
+	return &CorrelationHandler{service: service}
+}
+
+// GetTrace handles GET /api/admin/trace?correlationId=&recordId=, returning the prediction, the
+// schedule, the device events and the feedback recorded under a correlation ID. With recordId
+// instead, it follows a feedback record back to the prediction it answers.
+func (h *CorrelationHandler) GetTrace(c *gin.Context) {
+	var trace *services.CorrelationTrace
+	var err error
+	switch {
+	case c.Query("correlationId") != "":
+		trace, err = h.service.Trace(c.Query("correlationId"))
+	case c.Query("recordId") != "":
+		trace, err = h.service.TraceRecord(c.Query("recordId"))
+	default:
+		validationError(c, "correlationId", "required_without", "Either correlationId or recordId is required")
+		return
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrTraceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Nothing was recorded under that correlation ID")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve trace") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DemandResponseHandler handles HTTP requests for the utility's demand-response events
+type DemandResponseHandler struct {
+	service *services.DemandResponseService
+}
+
+// NewDemandResponseHandler creates a new demand-response handler instance
+func NewDemandResponseHandler(service *services.DemandResponseService) *DemandResponseHandler {
+	return &DemandResponseHandler{service: service}
+}
+
+// ReceiveDemandResponseSignal handles POST /api/grid/demand-response, sent by the utility or the
+// bridge relaying its MQTT topic. A signal repeating an event's id updates that event. The end
+// is given as end or as durationMinutes from the start, which defaults to now.
+func (h *DemandResponseHandler) ReceiveDemandResponseSignal(c *gin.Context) {
+	var req struct {
+		ID              string    `json:"id"`
+		Action          string    `json:"action" binding:"required,oneof=cap shift"`
+		MaxMinutes      float64   `json:"maxMinutes" binding:"gte=0"`
+		Reason          string    `json:"reason"`
+		Start           time.Time `json:"start"`
+		End             time.Time `json:"end"`
+		DurationMinutes float64   `json:"durationMinutes" binding:"gte=0"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.End.IsZero() {
+		if req.DurationMinutes == 0 {
+			validationError(c, "end", "required_without", "Either end or durationMinutes is required")
+			return
+		}
+		start := req.Start
+		if start.IsZero() {
+			start = time.Now()
+		}
+		req.End = start.Add(time.Duration(req.DurationMinutes * float64(time.Minute)))
+	}
+
+	event, created, err := h.service.Signal(services.DemandResponseSignal{
+		ExternalID: req.ID,
+		Action:     req.Action,
+		MaxMinutes: req.MaxMinutes,
+		Reason:     req.Reason,
+		Start:      req.Start,
+		End:        req.End,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDemandResponse):
+			validationError(c, "end", "gtfield", err.Error())
+		case errors.Is(err, services.ErrDemandResponseTooLong):
+			validationError(c, "end", "max", err.Error())
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": t(c, "Failed to save demand-response event") + ": " + err.Error(),
+			})
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"event":   event,
+	})
+}
+
+// EndDemandResponseEvent handles POST /api/grid/demand-response/:id/end, ending an event early
+func (h *DemandResponseHandler) EndDemandResponseEvent(c *gin.Context) {
+	event, err := h.service.End(c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDemandResponseNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Demand-response event not found")})
+		case errors.Is(err, services.ErrDemandResponseEnded):
+			c.JSON(http.StatusConflict, gin.H{"error": t(c, "Demand-response event has already ended")})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": t(c, "Failed to end demand-response event") + ": " + err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"event":   event,
+	})
+}
+
+// ListDemandResponseEvents handles GET /api/grid/demand-response: the events that haven't ended
+func (h *DemandResponseHandler) ListDemandResponseEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"events": h.service.Upcoming(),
+	})
+}
@@ -0,0 +1,463 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceHandler handles HTTP requests for live water heater state
+type DeviceHandler struct {
+	deviceService     *services.DeviceStateService
+	boilerService     *services.BoilerService
+	efficiencyService *services.BoilerEfficiencyService
+	sanitationService *services.SanitationService
+	usageService      *services.DeviceUsageService
+}
+
+// NewDeviceHandler creates a new device handler instance
+func NewDeviceHandler(deviceService *services.DeviceStateService, boilerService *services.BoilerService, efficiencyService *services.BoilerEfficiencyService, sanitationService *services.SanitationService, usageService *services.DeviceUsageService) *DeviceHandler {
+	return &DeviceHandler{
+		deviceService:     deviceService,
+		boilerService:     boilerService,
+		efficiencyService: efficiencyService,
+		sanitationService: sanitationService,
+		usageService:      usageService,
+	}
+}
+
+// GetDeviceState handles GET /api/devices/:id/state
+func (h *DeviceHandler) GetDeviceState(c *gin.Context) {
+	c.JSON(http.StatusOK, h.deviceService.State(c.Param("id")))
+}
+
+// GetDeviceRemaining handles GET /api/devices/:id/remaining
+func (h *DeviceHandler) GetDeviceRemaining(c *gin.Context) {
+	c.JSON(http.StatusOK, h.deviceService.Remaining(c.Param("id")))
+}
+
+// ReportDeviceEvent handles POST /api/devices/:id/events, used by the scheduler, smart plugs and
+// sensors. A schedule_start carrying the correlation ID of the prediction it heats for, in the
+// body or the X-Correlation-ID header, traces the run's events until the device goes idle.
+func (h *DeviceHandler) ReportDeviceEvent(c *gin.Context) {
+	var event services.DeviceEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	switch {
+	case (event.Type == services.DeviceEventScheduleStart || event.Type == services.DeviceEventSanitationStart) && event.HeatingTime <= 0:
+		validationError(c, "heatingTime", "min", "Heating time must be greater than 0")
+		return
+	case event.Type == services.DeviceEventTemperature && event.Temperature == nil:
+		validationError(c, "temperature", "required", "Temperature is required")
+		return
+	case event.CorrelationID != "" && !validCorrelationID(event.CorrelationID):
+		validationError(c, "correlationId", "format", "Correlation ID must be at most 64 letters, digits, '.', '_', ':' or '-'")
+		return
+	}
+	// The correlation ID may also come in the header, as sent to /api/calculate
+	if id := correlationID(c); event.CorrelationID == "" && id == c.GetHeader(CorrelationHeader) {
+		event.CorrelationID = id
+	}
+
+	status, err := h.deviceService.Apply(c.Param("id"), event)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownDeviceEvent) {
+			validationError(c, "type", "oneof", tf(c, "Unknown device event %q", event.Type))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to update device state") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"state":   status,
+	})
+}
+
+// GetBoilerProfile handles GET /api/devices/:id/profile?userId=
+func (h *DeviceHandler) GetBoilerProfile(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	profile, err := h.boilerService.SharedProfile(userID, c.Param("id"))
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile": profile,
+	})
+}
+
+// GetDeviceEfficiency handles GET /api/devices/:id/efficiency?userId=
+func (h *DeviceHandler) GetDeviceEfficiency(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	report, err := h.efficiencyService.Report(userID, c.Param("id"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to check boiler efficiency") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CompareDeviceEfficiency handles GET /api/stats/devices/compare?userId=, the heating each of the
+// household's devices needs per litre-degree of hot water, most efficient first
+func (h *DeviceHandler) CompareDeviceEfficiency(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	comparison, err := h.efficiencyService.Compare(userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to compare devices") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// SetDeviceResolution handles POST /api/devices/:id/resolution
+func (h *DeviceHandler) SetDeviceResolution(c *gin.Context) {
+	var req struct {
+		UserID     string   `json:"userId" binding:"required"`
+		Resolution *float64 `json:"resolution"` // null restores the configured resolution
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	profile, err := h.boilerService.SetResolution(req.UserID, c.Param("id"), req.Resolution)
+	if errors.Is(err, services.ErrInvalidResolution) {
+		validationError(c, "resolution", "range", "Resolution must be between 0.1 and 10 minutes")
+		return
+	}
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// SetDeviceOutputMode handles POST /api/devices/:id/output
+func (h *DeviceHandler) SetDeviceOutputMode(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		Mode   string `json:"mode"` // minutes or temperature
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	profile, err := h.boilerService.SetOutputMode(req.UserID, c.Param("id"), req.Mode)
+	if errors.Is(err, services.ErrInvalidOutputMode) {
+		validationError(c, "mode", "oneof", "Output mode must be minutes or temperature")
+		return
+	}
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// SetDeviceSafetyFloor handles POST /api/devices/:id/safety-floor, the heating time the device
+// never drops below in cold weather
+func (h *DeviceHandler) SetDeviceSafetyFloor(c *gin.Context) {
+	var req struct {
+		UserID  string   `json:"userId" binding:"required"`
+		Minutes *float64 `json:"minutes"` // null restores the configured floor, 0 turns it off
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	profile, err := h.boilerService.SetSafetyFloor(req.UserID, c.Param("id"), req.Minutes)
+	if errors.Is(err, services.ErrInvalidSafetyFloor) {
+		validationError(c, "minutes", "range", "Safety floor must be between 0 and the maximum heating time")
+		return
+	}
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// GetDeviceSanitation handles GET /api/devices/:id/sanitation?userId=, the device's weekly
+// legionella cycle, when it next runs and the cycles that ran
+func (h *DeviceHandler) GetDeviceSanitation(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	status, err := h.sanitationService.Status(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load sanitation schedule") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sanitation": status,
+	})
+}
+
+// SetDeviceSanitation handles POST /api/devices/:id/sanitation
+func (h *DeviceHandler) SetDeviceSanitation(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		services.SanitationSchedule
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	profile, err := h.sanitationService.SetSchedule(req.UserID, c.Param("id"), req.SanitationSchedule)
+	switch {
+	case errors.Is(err, services.ErrInvalidSanitationDay):
+		validationError(c, "day", "oneof", "Sanitation day must be monday to sunday")
+		return
+	case errors.Is(err, services.ErrInvalidSanitationTime):
+		validationError(c, "time", "format", "Times must be in HH:MM format")
+		return
+	case errors.Is(err, services.ErrInvalidSanitationMinutes):
+		validationError(c, "minutes", "range", "Sanitation minutes must be between 1 and 240")
+		return
+	case err != nil:
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// ExportDeviceUsage handles GET /api/devices/:id/usage.csv?userId=&month=&from=&to=&locale=&timezone=,
+// the device's heating per day for utility reimbursement; month is shorthand for one month
+func (h *DeviceHandler) ExportDeviceUsage(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+	format, ok := exportFormat(c, c.Query("locale"), c.Query("timezone"))
+	if !ok {
+		return
+	}
+	from, to := c.Query("from"), c.Query("to")
+	if month := c.Query("month"); month != "" {
+		from, to = month, month
+	}
+
+	usage, err := h.usageService.Usage(userID, c.Param("id"), from, to, format.Location())
+	switch {
+	case errors.Is(err, services.ErrInvalidUsageMonth):
+		validationError(c, "month", "format", "Months must be in YYYY-MM format")
+		return
+	case errors.Is(err, services.ErrInvalidUsageRange):
+		validationError(c, "to", "range", "Usage range must run forward and span at most 24 months")
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve usage") + ": " + err.Error(),
+		})
+		return
+	}
+
+	filename := "usage_" + c.Param("id") + "_" + usage.From.Format("2006-01") + ".csv"
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	if err := services.WriteDeviceUsageCSV(c.Writer, usage, format); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to write CSV data"),
+		})
+		return
+	}
+}
+
+// ListBoilerPresets handles GET /api/boiler-presets
+func (h *DeviceHandler) ListBoilerPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"presets": services.BoilerPresets,
+	})
+}
+
+// ApplyBoilerPreset handles POST /api/devices/:id/preset, the onboarding step that sets a
+// device up from the kind of heater it is
+func (h *DeviceHandler) ApplyBoilerPreset(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		Preset string `json:"preset" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	profile, err := h.boilerService.ApplyPreset(req.UserID, c.Param("id"), req.Preset)
+	if errors.Is(err, services.ErrUnknownBoilerPreset) {
+		validationError(c, "preset", "oneof", tf(c, "Unknown boiler preset %q", req.Preset))
+		return
+	}
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// StartBoilerCalibration handles POST /api/devices/:id/calibration/start
+func (h *DeviceHandler) StartBoilerCalibration(c *gin.Context) {
+	var req struct {
+		UserID         string  `json:"userId" binding:"required"`
+		HeatingMinutes float64 `json:"heatingMinutes" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.HeatingMinutes < 1 || req.HeatingMinutes > 240 {
+		validationError(c, "heatingMinutes", "range", "Heating time must be between 1 and 240 minutes")
+		return
+	}
+
+	profile, err := h.boilerService.StartCalibration(req.UserID, c.Param("id"), req.HeatingMinutes)
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+		"next":    t(c, "Heat the boiler from cold for the chosen time, then start a shower and report it"),
+	})
+}
+
+// StartCalibrationShower handles POST /api/devices/:id/calibration/shower
+func (h *DeviceHandler) StartCalibrationShower(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	profile, err := h.boilerService.StartCalibrationShower(req.UserID, c.Param("id"))
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+		"next":    t(c, "Keep the shower running at its usual flow and report when the water goes cold"),
+	})
+}
+
+// FinishBoilerCalibration handles POST /api/devices/:id/calibration/cold
+func (h *DeviceHandler) FinishBoilerCalibration(c *gin.Context) {
+	var req struct {
+		UserID           string   `json:"userId" binding:"required"`
+		ShowerMinutes    float64  `json:"showerMinutes"` // measured from the shower step when omitted
+		FlowRate         *float64 `json:"flowRate"`
+		InletTemperature *float64 `json:"inletTemperature"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.ShowerMinutes < 0 || req.ShowerMinutes > 240 {
+		validationError(c, "showerMinutes", "range", "Shower time must be between 0 and 240 minutes")
+		return
+	}
+	if req.FlowRate != nil && (*req.FlowRate <= 0 || *req.FlowRate > 30) {
+		validationError(c, "flowRate", "range", "Flow rate must be between 0 and 30 litres per minute")
+		return
+	}
+	if req.InletTemperature != nil && !validInletTemperature(*req.InletTemperature) {
+		validationError(c, "inletTemperature", "range", "Inlet temperature must be between 0 and 40 degrees Celsius")
+		return
+	}
+
+	profile, err := h.boilerService.FinishCalibration(req.UserID, c.Param("id"), services.CalibrationResult{
+		ShowerMinutes:    req.ShowerMinutes,
+		FlowRate:         req.FlowRate,
+		InletTemperature: req.InletTemperature,
+	})
+	if err != nil {
+		h.boilerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// boilerError writes the response for a failed boiler profile operation
+func (h *DeviceHandler) boilerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrBoilerProfileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Boiler profile not found")})
+	case errors.Is(err, services.ErrCalibrationStep):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Calibration is not at this step; start it again")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to update boiler profile") + ": " + err.Error()})
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DiagnosticsHandler reports how the predictors behave on live traffic
+type DiagnosticsHandler struct {
+	anchors      *services.AnchorDiagnostics
+	contributors *services.ContributorReliabilityService // nil when contributors aren't scored
+	slo          *services.SLOService
+}
+
+// NewDiagnosticsHandler creates a new diagnostics handler instance
+func NewDiagnosticsHandler(anchors *services.AnchorDiagnostics, contributors *services.ContributorReliabilityService, slo *services.SLOService) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		anchors:      anchors,
+		contributors: contributors,
+		slo:          slo,
+	}
+}
+
+// GetAnchorDiagnostics handles GET /api/admin/diagnostics/anchors?last=
+func (h *DiagnosticsHandler) GetAnchorDiagnostics(c *gin.Context) {
+	last := 0
+	if raw := c.Query("last"); raw != "" {
+		var err error
+		if last, err = strconv.Atoi(raw); err != nil || last < 1 {
+			validationError(c, "last", "min", "last must be a positive number of predictions")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, h.anchors.Summary(last))
+}
+
+// GetContributorReliability handles GET /api/admin/diagnostics/contributors
+func (h *DiagnosticsHandler) GetContributorReliability(c *gin.Context) {
+	if h.contributors == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":      false,
+			"contributors": []services.ContributorReliability{},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":      true,
+		"contributors": h.contributors.List(),
+		"excluded":     len(h.contributors.Excluded()),
+	})
+}
+
+// GetSLO handles GET /api/admin/slo
+func (h *DiagnosticsHandler) GetSLO(c *gin.Context) {
+	report, err := h.slo.Report()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to compute objectives") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"slo": report,
+	})
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/events"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// droppedEvent is the last message a client gets when the hub disconnects it for falling behind
+const droppedEvent = "dropped"
+
+// EventsHandler streams live events to dashboards and heater controllers, over Server-Sent
+// Events or WebSocket. Both endpoints subscribe to the same hub with the same filters.
+type EventsHandler struct {
+	hub *events.Hub
+	cfg config.EventsConfig
+}
+
+// NewEventsHandler creates an events handler streaming from hub
+func NewEventsHandler(hub *events.Hub, cfg config.EventsConfig) *EventsHandler {
+	return &EventsHandler{hub: hub, cfg: cfg}
+}
+
+// subscribe subscribes to the events the ?userId=&deviceId=&types= query asks for, writing
+// the error response and returning nil when it can't
+func (h *EventsHandler) subscribe(c *gin.Context) *events.Subscription {
+	filter := events.Filter{UserID: c.Query("userId"), DeviceID: c.Query("deviceId")}
+	if types := c.Query("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			if !events.IsValidType(t) {
+				validationError(c, "types", "oneof", tf(c, "Unknown event type %q", t))
+				return nil
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	sub, err := h.hub.Subscribe(filter)
+	if errors.Is(err, events.ErrTooManyClients) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": t(c, "Too many event subscribers, try again later"),
+		})
+		return nil
+	}
+	return sub
+}
+
+// StreamEvents handles GET /api/events?userId=&deviceId=&types=, a Server-Sent Events stream.
+// Each event is sent with its id and type; idle streams get a comment every heartbeat.
+func (h *EventsHandler) StreamEvents(c *gin.Context) {
+	sub := h.subscribe(c)
+	if sub == nil {
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	write := func(message string) bool {
+		// A client that stops reading is let go rather than holding the connection forever
+		rc.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout))
+		if _, err := io.WriteString(c.Writer, message); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !write(": connected\n\n") {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				write("event: " + droppedEvent + "\ndata: {}\n\n")
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil || !write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)) {
+				return
+			}
+		}
+	}
+}
+
+// StreamEventsWebSocket handles GET /api/events/ws?userId=&deviceId=&types=, sending each event
+// as a JSON text message. Messages from the client are ignored; idle connections are pinged
+// every heartbeat.
+func (h *EventsHandler) StreamEventsWebSocket(c *gin.Context) {
+	sub := h.subscribe(c)
+	if sub == nil {
+		return
+	}
+	defer sub.Close()
+
+	server := websocket.Server{
+		// Controllers send no Origin, and the stream carries nothing the REST API doesn't
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			// Reading answers the client's control frames and notices when it goes away
+			closed := make(chan struct{})
+			go func() {
+				io.Copy(io.Discard, ws)
+				close(closed)
+			}()
+
+			send := func(payloadType byte, v interface{}) bool {
+				ws.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout))
+				if payloadType == websocket.PingFrame {
+					ws.PayloadType = websocket.PingFrame
+					_, err := ws.Write(nil)
+					ws.PayloadType = websocket.TextFrame
+					return err == nil
+				}
+				return websocket.JSON.Send(ws, v) == nil
+			}
+
+			heartbeat := time.NewTicker(h.cfg.Heartbeat)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case <-closed:
+					return
+				case <-heartbeat.C:
+					if !send(websocket.PingFrame, nil) {
+						return
+					}
+				case event, ok := <-sub.Events():
+					if !ok {
+						send(websocket.TextFrame, gin.H{"type": droppedEvent})
+						return
+					}
+					if !send(websocket.TextFrame, event) {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler handles HTTP requests for asynchronous history exports
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new export handler instance
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// CreateExportJob handles POST /api/history/export-jobs
+func (h *ExportHandler) CreateExportJob(c *gin.Context) {
+	var req struct {
+		UserID   string `json:"userId" binding:"required"`
+		Locale   string `json:"locale"`
+		Timezone string `json:"timezone"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	format, ok := exportFormat(c, req.Locale, req.Timezone)
+	if !ok {
+		return
+	}
+
+	job, err := h.exportService.CreateJob(req.UserID, format)
+	if err != nil {
+		if errors.Is(err, services.ErrExportThrottled) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": t(c, "Please wait before requesting another export"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to create export job") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job": job,
+	})
+}
+
+// ListExportJobs handles GET /api/history/export-jobs?userId=
+func (h *ExportHandler) ListExportJobs(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	jobs, err := h.exportService.ListJobs(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve export jobs") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": jobs,
+	})
+}
+
+// GetExportJob handles GET /api/history/export-jobs/:id?userId=
+func (h *ExportHandler) GetExportJob(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	job, err := h.exportService.GetJob(userID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrExportJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": t(c, "Export job not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve export job") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job": job,
+	})
+}
+
+// DownloadExportJob handles GET /api/history/export-jobs/:id/download?userId=
+func (h *ExportHandler) DownloadExportJob(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	path, err := h.exportService.DownloadPath(userID, c.Param("id"))
+	if err != nil {
+		exportDownloadError(c, err)
+		return
+	}
+
+	c.FileAttachment(path, "heating_history_"+c.Param("id")+".csv")
+}
+
+// exportFormat resolves the locale and time zone an export is written in, writing a validation
+// error and reporting false when either is unknown
+func exportFormat(c *gin.Context, locale, timezone string) (services.ExportFormat, bool) {
+	format, err := services.NewExportFormat(locale, timezone)
+	switch {
+	case errors.Is(err, services.ErrUnsupportedLocale):
+		validationError(c, "locale", "oneof", tf(c, "Unsupported locale %q", locale))
+		return format, false
+	case errors.Is(err, services.ErrUnknownTimezone):
+		validationError(c, "timezone", "format", "Unknown time zone")
+		return format, false
+	}
+	return format, true
+}
+
+// exportDownloadError maps export download errors to responses
+func exportDownloadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrExportJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Export job not found")})
+	case errors.Is(err, services.ErrExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Export is not ready yet")})
+	case errors.Is(err, services.ErrExportExpired):
+		c.JSON(http.StatusGone, gin.H{"error": t(c, "Export has expired")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to download export") + ": " + err.Error()})
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeedbackPromptHandler handles HTTP requests for showers waiting for feedback
+type FeedbackPromptHandler struct {
+	promptService *services.FeedbackPromptService
+}
+
+// NewFeedbackPromptHandler creates a new feedback prompt handler instance
+func NewFeedbackPromptHandler(promptService *services.FeedbackPromptService) *FeedbackPromptHandler {
+	return &FeedbackPromptHandler{
+		promptService: promptService,
+	}
+}
+
+// GetPendingFeedback handles GET /api/feedback/pending?userId=, listing the user's scheduled
+// showers that passed without feedback
+func (h *FeedbackPromptHandler) GetPendingFeedback(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	prompts, err := h.promptService.Pending(userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve pending feedback") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending": prompts,
+	})
+}
+
+// DismissPendingFeedback handles POST /api/feedback/pending/:id/dismiss, for showers there is
+// nothing to say about
+func (h *FeedbackPromptHandler) DismissPendingFeedback(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.promptService.Dismiss(req.UserID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrFeedbackPromptNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Feedback prompt not found")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to dismiss feedback prompt") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
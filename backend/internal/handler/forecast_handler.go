@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForecastHandler handles HTTP requests for heating forecasts
+type ForecastHandler struct {
+	forecastService *services.ForecastService
+}
+
+// NewForecastHandler creates a new forecast handler instance
+func NewForecastHandler(forecastService *services.ForecastService) *ForecastHandler {
+	return &ForecastHandler{
+		forecastService: forecastService,
+	}
+}
+
+// GetWeekForecast handles GET /api/forecast/week?userId=&memberId=&duration=, the heating
+// minutes, energy and cost expected each day of the next seven for the user's scheduled showers
+func (h *ForecastHandler) GetWeekForecast(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+	var duration float64
+	if raw := c.Query("duration"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || !validShowerDuration(c, parsed) {
+			durationRangeError(c, "duration")
+			return
+		}
+		duration = parsed
+	}
+
+	forecast, err := h.forecastService.Week(c.Request.Context(), services.WeekForecastRequest{
+		UserID:   userID,
+		MemberID: c.Query("memberId"),
+		Duration: duration,
+		From:     time.Now(),
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrNoSchedule):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Add a shower schedule to forecast the week")})
+		return
+	case errors.Is(err, services.ErrNoTemperature):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": t(c, "No temperature forecast or reading is available")})
+		return
+	default:
+		predictionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"forecast": forecast,
+	})
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FrontendHandler serves the web app bundled into release builds
+type FrontendHandler struct {
+	files fs.FS
+	index []byte
+}
+
+// NewFrontendHandler creates a new frontend handler for the app's built files, which must
+// include its index.html
+func NewFrontendHandler(files fs.FS) (*FrontendHandler, error) {
+	index, err := fs.ReadFile(files, "index.html")
+	if err != nil {
+		return nil, err
+	}
+	return &FrontendHandler{files: files, index: index}, nil
+}
+
+// ServeFrontend is the router's fallback: it serves the app's files, and its index page for
+// any other page so the app's own routes survive a reload. Unknown API routes stay 404s.
+func (h *FrontendHandler) ServeFrontend(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") ||
+		(c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(c.Request.URL.Path), "/")
+	if name != "" && name != "index.html" {
+		if info, err := fs.Stat(h.files, name); err == nil && !info.IsDir() {
+			// Vite fingerprints everything under assets, so it never changes under its name
+			if strings.HasPrefix(name, "assets/") {
+				c.Header("Cache-Control", "public, max-age=31536000, immutable")
+			}
+			c.FileFromFS(name, http.FS(h.files))
+			return
+		}
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", h.index)
+}
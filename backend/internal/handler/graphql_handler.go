@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"heat-logger/internal/graphql"
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxGraphQLHistory is the most records one history field returns
+const maxGraphQLHistory = 500
+
+// GraphQLHandler serves read-only GraphQL queries, so a dashboard can fetch its history,
+// stats, schedules and device state in one request
+type GraphQLHandler struct {
+	schema *graphql.Schema
+}
+
+// NewGraphQLHandler creates a GraphQL handler over the services the dashboard reads
+func NewGraphQLHandler(records *services.RecordService, stats *services.StatsService, templates *services.ScheduleTemplateService, devices *services.DeviceStateService) *GraphQLHandler {
+	userID := graphql.Argument{Name: "userId", Type: "String!"}
+	schema := &graphql.Schema{Query: map[string]graphql.Field{
+		// The user's records, newest first, optionally from some sources only
+		"history": {
+			Args: []graphql.Argument{userID, {Name: "limit", Type: "Int", Default: 50}, {Name: "sources", Type: "[String!]"}},
+			Resolve: func(_ context.Context, args graphql.Args) (interface{}, error) {
+				limit := args.Int("limit")
+				if limit < 1 || limit > maxGraphQLHistory {
+					return nil, fmt.Errorf("limit must be between 1 and %d", maxGraphQLHistory)
+				}
+				sources := map[string]bool{}
+				for _, source := range args.Strings("sources") {
+					if !models.IsValidRecordSource(source) {
+						return nil, fmt.Errorf("unknown record source %q", source)
+					}
+					sources[source] = true
+				}
+				all, err := records.GetRecordsByUser(args.String("userId"))
+				if err != nil {
+					return nil, err
+				}
+				history := make([]models.DailyRecord, 0, len(all))
+				for _, record := range all {
+					if len(sources) == 0 || sources[record.Source] {
+						history = append(history, record)
+					}
+				}
+				sort.SliceStable(history, func(i, j int) bool { return history[i].Date.After(history[j].Date) })
+				return history[:min(limit, len(history))], nil
+			},
+		},
+		"summary": {
+			Args: []graphql.Argument{userID},
+			Resolve: func(_ context.Context, args graphql.Args) (interface{}, error) {
+				return stats.Summary(args.String("userId"))
+			},
+		},
+		"heatmap": {
+			Args: []graphql.Argument{userID},
+			Resolve: func(_ context.Context, args graphql.Args) (interface{}, error) {
+				return stats.Heatmap(args.String("userId"))
+			},
+		},
+		// The schedule templates of the user's household
+		"schedules": {
+			Args: []graphql.Argument{userID},
+			Resolve: func(_ context.Context, args graphql.Args) (interface{}, error) {
+				return templates.ListHouseholdTemplates(args.String("userId"))
+			},
+		},
+		"nextShower": {
+			Args: []graphql.Argument{userID},
+			Resolve: func(_ context.Context, args graphql.Args) (interface{}, error) {
+				return templates.NextShower(args.String("userId"), time.Now())
+			},
+		},
+		"device": {
+			Args: []graphql.Argument{{Name: "id", Type: "String!"}},
+			Resolve: func(_ context.Context, args graphql.Args) (interface{}, error) {
+				return devices.State(args.String("id")), nil
+			},
+		},
+	}}
+	return &GraphQLHandler{schema: schema}
+}
+
+// ExecuteGraphQL handles POST /api/graphql with a {query, variables, operationName} body.
+// Field errors are reported in the response's errors next to the data that could be read;
+// requests that can't run at all, such as mutations, get a 400.
+func (h *GraphQLHandler) ExecuteGraphQL(c *gin.Context) {
+	var req graphql.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.Query == "" {
+		validationError(c, "query", "required", "Query is required")
+		return
+	}
+
+	resp := h.schema.Execute(c.Request.Context(), req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, resp)
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HouseholdHandler handles HTTP requests for joining households through invites
+type HouseholdHandler struct {
+	householdService *services.HouseholdService
+}
+
+// NewHouseholdHandler creates a new household handler instance
+func NewHouseholdHandler(householdService *services.HouseholdService) *HouseholdHandler {
+	return &HouseholdHandler{
+		householdService: householdService,
+	}
+}
+
+// householdError maps household service errors to responses
+func householdError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrHouseholdInviteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Invite not found")})
+	case errors.Is(err, services.ErrHouseholdInviteExpired):
+		c.JSON(http.StatusGone, gin.H{"error": t(c, "This invite has expired")})
+	case errors.Is(err, services.ErrHouseholdInviteUsed):
+		c.JSON(http.StatusGone, gin.H{"error": t(c, "This invite has already been used or was revoked")})
+	case errors.Is(err, services.ErrHouseholdInviteEmail):
+		c.JSON(http.StatusForbidden, gin.H{"error": t(c, "This invite is for another email address")})
+	case errors.Is(err, services.ErrHouseholdNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": t(c, "Only household owners can do this")})
+	case errors.Is(err, services.ErrHouseholdJoined):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "You already belong to a household; leave it first")})
+	case errors.Is(err, services.ErrHouseholdHasMembers):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Others have joined your household, so it can't join another")})
+	case errors.Is(err, services.ErrHouseholdOwnAccount):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "You can't leave your own household")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, message) + ": " + err.Error()})
+	}
+}
+
+// GetMyHousehold handles GET /api/households/mine?userId=
+func (h *HouseholdHandler) GetMyHousehold(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	household, err := h.householdService.Household(userID)
+	if err != nil {
+		householdError(c, err, "Failed to load household")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"household": household,
+	})
+}
+
+// CreateHouseholdInvite handles POST /api/households/invites. The link in the response is
+// shared with the invitee; with an email address, a mailto link for it is returned as well,
+// and the invite is sent to the user with that address if notifications are configured.
+func (h *HouseholdHandler) CreateHouseholdInvite(c *gin.Context) {
+	var req struct {
+		UserID         string `json:"userId" binding:"required"`
+		Email          string `json:"email" binding:"omitempty,email"`
+		Role           string `json:"role" binding:"omitempty,oneof=owner member"`
+		ExpiresInHours int    `json:"expiresInHours" binding:"omitempty,min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	token, invite, err := h.householdService.CreateInvite(req.UserID, req.Email, req.Role, time.Duration(req.ExpiresInHours)*time.Hour)
+	if err != nil {
+		householdError(c, err, "Failed to create invite")
+		return
+	}
+	path := "/api/households/invites/" + token
+	link := requestOrigin(c) + path
+
+	notified, err := h.householdService.NotifyInvitee(invite, link)
+	if err != nil {
+		// The invite stands; it can still be shared by hand
+		log.Printf("Failed to send household invite %s: %v", invite.ID, err)
+	}
+
+	response := gin.H{
+		"success":  true,
+		"invite":   invite,
+		"token":    token,
+		"url":      link,
+		"path":     path,
+		"notified": notified,
+	}
+	if invite.Email != "" {
+		response["mailto"] = inviteMailto(c, invite.Email, link)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// inviteMailto returns a mailto link for an email carrying the invite link
+func inviteMailto(c *gin.Context, email, link string) string {
+	escape := func(s string) string {
+		// mailto wants spaces as %20, not the + of form encoding
+		return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	}
+	return "mailto:" + email +
+		"?subject=" + escape(t(c, "Join my household on Heat Logger")) +
+		"&body=" + escape(tf(c, "Open this link to join my household: %s", link))
+}
+
+// ListHouseholdInvites handles GET /api/households/invites?userId=
+func (h *HouseholdHandler) ListHouseholdInvites(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	invites, err := h.householdService.ListInvites(userID)
+	if err != nil {
+		householdError(c, err, "Failed to retrieve invites")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invites": invites,
+	})
+}
+
+// RevokeHouseholdInvite handles POST /api/households/invites/revoke
+func (h *HouseholdHandler) RevokeHouseholdInvite(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	invite, err := h.householdService.RevokeInvite(req.UserID, req.ID)
+	if err != nil {
+		householdError(c, err, "Failed to revoke invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"invite":  invite,
+	})
+}
+
+// GetHouseholdInvite handles GET /api/households/invites/:token, showing who the invite is
+// from and the role it assigns before it is accepted
+func (h *HouseholdHandler) GetHouseholdInvite(c *gin.Context) {
+	invite, err := h.householdService.Invite(c.Param("token"))
+	if err != nil {
+		householdError(c, err, "Failed to load invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invite": invite,
+	})
+}
+
+// AcceptHouseholdInvite handles POST /api/households/invites/:token/accept
+func (h *HouseholdHandler) AcceptHouseholdInvite(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	membership, err := h.householdService.AcceptInvite(req.UserID, c.Param("token"))
+	if err != nil {
+		householdError(c, err, "Failed to join household")
+		return
+	}
+	household, err := h.householdService.Household(req.UserID)
+	if err != nil {
+		householdError(c, err, "Failed to load household")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"membership": membership,
+		"household":  household,
+	})
+}
+
+// LeaveHousehold handles POST /api/households/leave
+func (h *HouseholdHandler) LeaveHousehold(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.householdService.Leave(req.UserID); err != nil {
+		householdError(c, err, "Failed to leave household")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HumidityHandler handles HTTP requests for bathroom humidity sensors
+type HumidityHandler struct {
+	showerDetection *services.ShowerDetectionService
+}
+
+// NewHumidityHandler creates a new humidity handler instance
+func NewHumidityHandler(showerDetection *services.ShowerDetectionService) *HumidityHandler {
+	return &HumidityHandler{
+		showerDetection: showerDetection,
+	}
+}
+
+// ReportHumidityReading handles POST /api/humidity/readings, used by bathroom humidity sensors.
+// A reading that completes a shower returns it with the pending record created for it.
+func (h *HumidityHandler) ReportHumidityReading(c *gin.Context) {
+	var req struct {
+		UserID   string    `json:"userId" binding:"required"`
+		DeviceID string    `json:"deviceId"` // water heater the bathroom's shower uses
+		Humidity *float64  `json:"humidity" binding:"required,min=0,max=100"`
+		At       time.Time `json:"at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.At.IsZero() {
+		req.At = time.Now()
+	}
+
+	shower, err := h.showerDetection.Report(c.Request.Context(), req.UserID, req.DeviceID, *req.Humidity, req.At)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to record detected shower") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"shower":  shower,
+	})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// localeKey is the context key holding the negotiated locale
+const localeKey = "locale"
+
+// defaultLocale is used when the client accepts none of the supported locales.
+// Catalog keys are the English messages themselves, so English needs no catalog.
+const defaultLocale = "en"
+
+// catalogs maps a locale to translations of user-facing handler messages
+var catalogs = map[string]map[string]string{
+	"he": hebrewMessages,
+}
+
+// Localize negotiates the response locale from Accept-Language for the handlers' messages
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := negotiateLocale(c.GetHeader("Accept-Language"))
+		c.Set(localeKey, locale)
+		c.Header("Content-Language", locale)
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// t translates a message into the request's locale, falling back to the message itself
+func t(c *gin.Context, message string) string {
+	locale := c.GetString(localeKey)
+	if locale == "" {
+		locale = negotiateLocale(c.GetHeader("Accept-Language"))
+	}
+	if translated, ok := catalogs[locale][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// tf translates a format string and then applies the arguments
+func tf(c *gin.Context, format string, args ...interface{}) string {
+	return fmt.Sprintf(t(c, format), args...)
+}
+
+// negotiateLocale picks the supported locale with the highest q-value in an Accept-Language header
+func negotiateLocale(header string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if primary == "iw" { // legacy code for Hebrew still sent by some clients
+			primary = "he"
+		}
+		if _, ok := catalogs[primary]; ok || primary == defaultLocale {
+			if q > 0 {
+				candidates = append(candidates, candidate{primary, q})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return defaultLocale
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
@@ -0,0 +1,369 @@
+package handler
+
+// hebrewMessages is the Hebrew message catalog
+var hebrewMessages = map[string]string{
+	// Validation
+	"Invalid request data":                                  "נתוני הבקשה אינם תקינים",
+	"UserID is required":                                    "נדרש מזהה משתמש",
+	"Query is required":                                     "נדרשת שאילתה",
+	"Shower duration is required":                           "נדרש משך המקלחת",
+	"Temperature is required":                               "נדרשת טמפרטורה",
+	"Shower duration must be between %g and %g minutes":     "משך המקלחת חייב להיות בין %g ל-%g דקות",
+	"Shower duration must be greater than 0":                "משך המקלחת חייב להיות גדול מ-0",
+	"Temperature must be between %g and %g degrees Celsius": "הטמפרטורה חייבת להיות בין %g ל-%g מעלות צלזיוס",
+	"Heating time must be greater than 0":                   "זמן החימום חייב להיות גדול מ-0",
+	"Satisfaction rating must be between 1 and 100":         "דירוג שביעות הרצון חייב להיות בין 1 ל-100",
+	"Unknown record source %q":                              "מקור רשומה לא מוכר %q",
+	"%s must be a date (YYYY-MM-DD) or RFC3339 timestamp":   "%s חייב להיות תאריך (YYYY-MM-DD) או חותמת זמן RFC3339",
+	"The start of the date range must not be after its end": "תחילת טווח התאריכים לא יכולה להיות אחרי סופו",
+	"Expiry must not be negative":                           "זמן התפוגה לא יכול להיות שלילי",
+	"Kind must be 'stats' or 'export'":                      "הסוג חייב להיות 'stats' או 'export'",
+	"Export job ID is required for export links":            "נדרש מזהה ייצוא עבור קישור לייצוא",
+	"Member name must be between 1 and 32 characters":       "שם בן המשפחה חייב להכיל בין 1 ל-32 תווים",
+	"Preset name must be between 1 and 64 characters":       "שם התבנית חייב להכיל בין 1 ל-64 תווים",
+	"Preset ID is required":                                 "נדרש מזהה תבנית",
+	"Request body must be valid JSON":                       "גוף הבקשה חייב להיות JSON תקין",
+	"%s is required":                                        "השדה %s הוא חובה",
+	"%s is invalid":                                         "השדה %s אינו תקין",
+	"%s must be at least %s":                                "השדה %s חייב להיות לפחות %s",
+	"%s must be at most %s":                                 "השדה %s חייב להיות לכל היותר %s",
+	"%s must be one of: %s":                                 "השדה %s חייב להיות אחד מ: %s",
+	"%s must be a %s":                                       "השדה %s חייב להיות מסוג %s",
+	"Unknown annotation kind":                               "סוג הערה לא מוכר",
+
+	// Records and predictions
+	"Failed to calculate heating time":                           "חישוב זמן החימום נכשל",
+	"Prediction took too long, please try again":                 "החישוב לקח יותר מדי זמן, נסו שוב",
+	"Failed to save feedback":                                    "שמירת המשוב נכשלה",
+	"Feedback queued and will be saved when storage recovers":    "המשוב נשמר בתור ויישמר כשהאחסון יתאושש",
+	"Storage is temporarily unavailable, please try again later": "האחסון אינו זמין זמנית, נסו שוב מאוחר יותר",
+	"Feedback saved successfully":                                "המשוב נשמר בהצלחה",
+	"Failed to retrieve history":                                 "טעינת ההיסטוריה נכשלה",
+	"Record not found":                                           "הרשומה לא נמצאה",
+	"Failed to delete record":                                    "מחיקת הרשומה נכשלה",
+	"Record deleted successfully":                                "הרשומה נמחקה בהצלחה",
+	"Failed to delete all records":                               "מחיקת כל הרשומות נכשלה",
+	"All records deleted successfully":                           "כל הרשומות נמחקו בהצלחה",
+	"Failed to write CSV data":                                   "כתיבת קובץ ה-CSV נכשלה",
+
+	// Exports and sharing
+	"Failed to create export job":                  "יצירת הייצוא נכשלה",
+	"Please wait before requesting another export": "יש להמתין לפני בקשת ייצוא נוסף",
+	"Failed to retrieve export jobs":               "טעינת רשימת הייצואים נכשלה",
+	"Failed to retrieve export job":                "טעינת הייצוא נכשלה",
+	"Export job not found":                         "הייצוא לא נמצא",
+	"Export is not ready yet":                      "הייצוא עדיין לא מוכן",
+	"Export has expired":                           "תוקף הייצוא פג",
+	"Failed to download export":                    "הורדת הייצוא נכשלה",
+	"Link not found":                               "הקישור לא נמצא",
+	"This link has expired":                        "תוקף הקישור פג",
+	"Failed to load stats":                         "טעינת הסטטיסטיקה נכשלה",
+	"Failed to detect change points":               "זיהוי נקודות השינוי נכשל",
+
+	// Presets, members and annotations
+	"Preset not found":                       "התבנית לא נמצאה",
+	"A preset with this name already exists": "כבר קיימת תבנית בשם זה",
+	"Failed to load preset":                  "טעינת התבנית נכשלה",
+	"Failed to save preset":                  "שמירת התבנית נכשלה",
+	"Failed to update preset":                "עדכון התבנית נכשל",
+	"Failed to delete preset":                "מחיקת התבנית נכשלה",
+	"Failed to retrieve presets":             "טעינת התבניות נכשלה",
+	"Preset deleted successfully":            "התבנית נמחקה בהצלחה",
+	"Member not found":                       "בן המשפחה לא נמצא",
+	"A member with this name already exists": "כבר קיים בן משפחה בשם זה",
+	"Failed to load member":                  "טעינת בן המשפחה נכשלה",
+	"Failed to save member":                  "שמירת בן המשפחה נכשלה",
+	"Failed to rename member":                "שינוי שם בן המשפחה נכשל",
+	"Failed to delete member":                "מחיקת בן המשפחה נכשלה",
+	"Failed to retrieve members":             "טעינת בני המשפחה נכשלה",
+	"Member deleted successfully":            "בן המשפחה נמחק בהצלחה",
+	"Annotation not found":                   "ההערה לא נמצאה",
+	"Failed to save annotation":              "שמירת ההערה נכשלה",
+	"Failed to delete annotation":            "מחיקת ההערה נכשלה",
+	"Failed to retrieve annotations":         "טעינת ההערות נכשלה",
+	"Annotation deleted successfully":        "ההערה נמחקה בהצלחה",
+	"Failed to retrieve contexts":            "טעינת ההקשרים נכשלה",
+	"Failed to reset model":                  "איפוס המודל נכשל",
+	"Model reset successfully":               "המודל אופס בהצלחה",
+	"Excluded %d records from training":      "%d רשומות הוצאו מהאימון",
+	"Failed to check account status":         "בדיקת מצב החשבון נכשלה",
+	"This account has been disabled":         "חשבון זה הושבת",
+
+	// Accounts and login
+	"Not logged in":                               "לא מחוברים",
+	"Logged out":                                  "התנתקתם",
+	"Login failed":                                "ההתחברות נכשלה",
+	"Login state mismatch, please try again":      "מצב ההתחברות אינו תואם, נסו שוב",
+	"Authorization code is required":              "נדרש קוד הרשאה",
+	"Identity provider unavailable":               "ספק הזהות אינו זמין",
+	"Your account is not allowed to use this app": "לחשבון שלכם אין הרשאה להשתמש באפליקציה",
+	"Failed to start login":                       "התחלת ההתחברות נכשלה",
+	"Failed to create session":                    "יצירת החיבור נכשלה",
+	"Failed to load session":                      "טעינת החיבור נכשלה",
+	"Failed to end session":                       "סיום החיבור נכשל",
+	"Failed to save user":                         "שמירת המשתמש נכשלה",
+
+	// Admin
+	"Admin API is disabled":                                           "ממשק הניהול מושבת",
+	"Invalid admin credentials":                                       "פרטי הניהול אינם תקינים",
+	"User not found":                                                  "המשתמש לא נמצא",
+	"Failed to retrieve users":                                        "טעינת המשתמשים נכשלה",
+	"Failed to retrieve user":                                         "טעינת המשתמש נכשלה",
+	"Failed to update user":                                           "עדכון המשתמש נכשל",
+	"User enabled successfully":                                       "המשתמש הופעל בהצלחה",
+	"User disabled successfully":                                      "המשתמש הושבת בהצלחה",
+	"Failed to impersonate user":                                      "התחזות למשתמש נכשלה",
+	"Failed to retrieve audit log":                                    "טעינת יומן הביקורת נכשלה",
+	"A re-score job is already in progress":                           "עבודת חישוב מחדש כבר פועלת",
+	"Failed to create re-score job":                                   "יצירת עבודת חישוב מחדש נכשלה",
+	"Failed to retrieve re-score jobs":                                "שליפת עבודות החישוב מחדש נכשלה",
+	"Failed to retrieve re-score job":                                 "שליפת עבודת החישוב מחדש נכשלה",
+	"Failed to retrieve re-score results":                             "שליפת תוצאות החישוב מחדש נכשלה",
+	"Re-score job not found":                                          "עבודת החישוב מחדש לא נמצאה",
+	"Canary updated successfully":                                     "הגדרות הקנרי עודכנו בהצלחה",
+	"Failed to export changes":                                        "ייצוא השינויים נכשל",
+	"Failed to import changes":                                        "ייבוא השינויים נכשל",
+	"Instance ID is required":                                         "נדרש מזהה מופע",
+	"Cannot import changes exported by this instance":                 "לא ניתן לייבא שינויים שיוצאו ממופע זה",
+	"No sync peer is configured":                                      "לא הוגדר מופע עמית לסנכרון",
+	"Sync with peer failed":                                           "הסנכרון עם המופע העמית נכשל",
+	"Invalid sync credentials":                                        "פרטי הסנכרון שגויים",
+	"Sync cursor is invalid, sync again without one":                  "סמן הסנכרון אינו תקין, יש לסנכרן מחדש בלעדיו",
+	"Since must be a cursor from a previous sync or an RFC 3339 time": "since חייב להיות סמן מסנכרון קודם או זמן בתבנית RFC 3339",
+	"Limit must be between 1 and 500":                                 "המגבלה חייבת להיות בין 1 ל-500",
+	"Sync cursor has expired, sync again without one":                 "פג תוקפו של סמן הסנכרון, יש לסנכרן מחדש בלעדיו",
+	"Unknown event type %q":                                           "סוג אירוע לא מוכר %q",
+	"Too many event subscribers, try again later":                     "יותר מדי מנויים לאירועים, נסו שוב מאוחר יותר",
+	"Failed to retrieve changes":                                      "שליפת השינויים נכשלה",
+	"Record ID must be a client-generated UUID":                       "מזהה הרשומה חייב להיות UUID שנוצר בצד הלקוח",
+	"Record belongs to a different user":                              "הרשומה שייכת למשתמש אחר",
+	"Date is required":                                                "נדרש תאריך",
+	"Feedback already saved":                                          "המשוב כבר נשמר",
+	"Record ID is already used by a different record":                 "מזהה הרשומה כבר בשימוש ברשומה אחרת",
+	"Unknown device event %q":                                         "אירוע מכשיר לא מוכר %q",
+	"Failed to update device state":                                   "עדכון מצב המכשיר נכשל",
+	"Unknown temperature source %q":                                   "מקור טמפרטורה לא מוכר %q",
+	"Failed to determine temperature":                                 "קביעת הטמפרטורה נכשלה",
+	"No temperature source has a reading":                             "לאף מקור טמפרטורה אין קריאה",
+	"Failed to load temperature sources":                              "טעינת מקורות הטמפרטורה נכשלה",
+	"Failed to save temperature sources":                              "שמירת מקורות הטמפרטורה נכשלה",
+	"Inlet temperature must be between 0 and 40 degrees Celsius":      "טמפרטורת המים הנכנסים חייבת להיות בין 0 ל-40 מעלות צלזיוס",
+	"A sequence must have between 1 and 10 showers":                   "רצף חייב לכלול בין 1 ל-10 מקלחות",
+	"One tank of hot water won't last this long":                      "מיכל אחד של מים חמים לא יספיק למקלחת ארוכה כל כך",
+	"Heating time must be between 1 and 240 minutes":                  "זמן החימום חייב להיות בין 1 ל-240 דקות",
+	"Heat the boiler from cold for the chosen time, then start a shower and report it": "חממו את הדוד ממצב קר למשך הזמן שנבחר, ואז פתחו מקלחת ודווחו על כך",
+	"Keep the shower running at its usual flow and report when the water goes cold":    "השאירו את המקלחת פתוחה בזרימה הרגילה ודווחו כשהמים מתקררים",
+	"Shower time must be between 0 and 240 minutes":                                    "זמן המקלחת חייב להיות בין 0 ל-240 דקות",
+	"Flow rate must be between 0 and 30 litres per minute":                             "קצב הזרימה חייב להיות בין 0 ל-30 ליטר לדקה",
+	"Boiler profile not found":                                                         "פרופיל הדוד לא נמצא",
+	"Calibration is not at this step; start it again":                                  "הכיול אינו בשלב הזה; התחילו אותו מחדש",
+	"Failed to update boiler profile":                                                  "עדכון פרופיל הדוד נכשל",
+	"Much too cold":                                                                    "קר מדי",
+	"A bit cold":                                                                       "קצת קר",
+	"Perfect":                                                                          "מושלם",
+	"A bit hot":                                                                        "קצת חם",
+	"Much too hot":                                                                     "חם מדי",
+	"Date must be in YYYY-MM-DD format":                                                "התאריך חייב להיות בפורמט YYYY-MM-DD",
+	"Date must fall on the day in the URL":                                             "התאריך חייב להיות ביום שבכתובת",
+	"That day already has several records":                                             "ליום הזה כבר יש כמה רשומות",
+	"Revision not found":                                                               "הגרסה לא נמצאה",
+	"Failed to load record revisions":                                                  "טעינת גרסאות הרשומה נכשלה",
+	"Record is not waiting for review":                                                 "הרשומה לא ממתינה לבדיקה",
+	"Failed to review record":                                                          "בדיקת הרשומה נכשלה",
+	"Ready-by time must be after the earliest start":                                   "זמן המוכנות חייב להיות אחרי זמן ההתחלה המוקדם ביותר",
+	"Energy prices are not configured":                                                 "מחירי החשמל לא הוגדרו",
+	"No energy prices are available for that time":                                     "אין מחירי חשמל לזמן הזה",
+	"Failed to load energy prices":                                                     "טעינת מחירי החשמל נכשלה",
+	"Neither energy prices nor a solar forecast are available":                         "אין מחירי חשמל וגם לא תחזית סולארית",
+	"Each forecast point needs an end after its start and non-negative watts":          "לכל נקודת תחזית צריך זמן סיום אחרי זמן ההתחלה והספק לא שלילי",
+	"Production can't be negative":                                                     "הייצור לא יכול להיות שלילי",
+	"Ready-by time is required without a user's schedule":                              "נדרש זמן מוכנות כשאין לוח זמנים של משתמש",
+	"Schedule name must be between 1 and 64 characters":                                "שם לוח הזמנים חייב להיות באורך 1 עד 64 תווים",
+	"Times must be in HH:MM format":                                                    "השעות חייבות להיות בפורמט HH:MM",
+	"%s has more than one exception":                                                   "ל-%s יש יותר מחריגה אחת",
+	"Schedule template not found":                                                      "לוח הזמנים לא נמצא",
+	"A schedule template with this name already exists":                                "כבר קיים לוח זמנים בשם הזה",
+	"No shower is scheduled":                                                           "לא מתוכננת מקלחת",
+	"Failed to save schedule template":                                                 "שמירת לוח הזמנים נכשלה",
+	"Failed to retrieve schedule templates":                                            "טעינת לוחות הזמנים נכשלה",
+	"Schedule template ID is required":                                                 "נדרש מזהה לוח זמנים",
+	"Failed to update schedule template":                                               "עדכון לוח הזמנים נכשל",
+	"Failed to delete schedule template":                                               "מחיקת לוח הזמנים נכשלה",
+	"Schedule template deleted successfully":                                           "לוח הזמנים נמחק בהצלחה",
+	"Snoozing that long would start heating after the shower; skip it instead":         "דחייה כה ארוכה תתחיל את החימום אחרי המקלחת; אפשר לדלג עליה במקום",
+	"Failed to snooze heating":                                                         "דחיית החימום נכשלה",
+	"Failed to skip heating":                                                           "דילוג על החימום נכשל",
+	"Heating skipped":                                                                  "החימום בוטל",
+	"Heating snoozed until %s":                                                         "החימום נדחה עד %s",
+	"Failed to retrieve pending feedback":                                              "טעינת המשובים הממתינים נכשלה",
+	"Feedback prompt not found":                                                        "תזכורת המשוב לא נמצאה",
+	"Failed to dismiss feedback prompt":                                                "סגירת תזכורת המשוב נכשלה",
+	"Failed to load notification settings":                                             "טעינת הגדרות ההתראות נכשלה",
+	"Failed to save notification settings":                                             "שמירת הגדרות ההתראות נכשלה",
+	"Quiet hours must look like 22:00-07:00 or be off":                                 "שעות השקט צריכות להיות בפורמט 22:00-07:00 או off",
+	"Failed to load notifications":                                                     "טעינת ההתראות נכשלה",
+	"Status must be sent, suppressed or failed":                                        "הסטטוס חייב להיות sent, suppressed או failed",
+	"Format must be csv or json":                                                       "הפורמט חייב להיות csv או json",
+	"Failed to retrieve prediction log":                                                "טעינת יומן החיזויים נכשלה",
+	"Failed to load step cap":                                                          "טעינת מגבלת הצעד נכשלה",
+	"Failed to save step cap":                                                          "שמירת מגבלת הצעד נכשלה",
+	"Failed to load usage":                                                             "טעינת נתוני השימוש נכשלה",
+	"Failed to load default configuration":                                             "טעינת הגדרות ברירת המחדל נכשלה",
+	"Invalid demand-response credentials":                                              "פרטי הזיהוי של תגובת הביקוש שגויים",
+	"Either end or durationMinutes is required":                                        "יש לשלוח end או durationMinutes",
+	"Failed to save demand-response event":                                             "שמירת אירוע תגובת הביקוש נכשלה",
+	"Demand-response event not found":                                                  "אירוע תגובת הביקוש לא נמצא",
+	"Demand-response event has already ended":                                          "אירוע תגובת הביקוש כבר הסתיים",
+	"Failed to end demand-response event":                                              "סיום אירוע תגובת הביקוש נכשל",
+	"Demand-response events leave no time to heat before then":                         "אירועי תגובת ביקוש לא משאירים זמן לחמם עד אז",
+	"Step cap must be greater than 0 and at most 1":                                    "מגבלת הצעד חייבת להיות גדולה מ-0 ולכל היותר 1",
+	"last must be a positive number of predictions":                                    "last חייב להיות מספר חיובי של חיזויים",
+	"Failed to load rounding preferences":                                              "טעינת העדפות העיגול נכשלה",
+	"Failed to save rounding preferences":                                              "שמירת העדפות העיגול נכשלה",
+	"Rounding mode must be smart, nearest or ceil, step between 0.1 and 10 minutes and hysteresis between 0 and 0.5": "מצב העיגול חייב להיות smart, nearest או ceil, הצעד בין 0.1 ל-10 דקות וההיסטרזיס בין 0 ל-0.5",
+	"Resolution must be between 0.1 and 10 minutes":                                                                  "הרזולוציה חייבת להיות בין 0.1 ל-10 דקות",
+	"Safety floor must be between 0 and the maximum heating time":                                                    "רצפת הבטיחות חייבת להיות בין 0 לזמן החימום המרבי",
+	"Sanitation day must be monday to sunday":                                                                        "יום החיטוי חייב להיות בין שני לראשון",
+	"Sanitation minutes must be between 1 and 240":                                                                   "זמן החיטוי חייב להיות בין 1 ל-240 דקות",
+	"Months must be in YYYY-MM format":                                                                               "חודשים חייבים להיות בפורמט YYYY-MM",
+	"Usage range must run forward and span at most 24 months":                                                        "טווח הצריכה חייב להתקדם בזמן ולהשתרע על 24 חודשים לכל היותר",
+	"Failed to retrieve usage":                                                                                       "שליפת הצריכה נכשלה",
+	"Failed to load sanitation schedule":                                                                             "טעינת לוח החיטוי נכשלה",
+	"Output mode must be minutes or temperature":                                                                     "מצב הפלט חייב להיות minutes או temperature",
+	"Failed to summarise records":                                                                                    "סיכום הרשומות נכשל",
+	"Use either a cron expression or weekday and weekend times":                                                      "יש להשתמש בביטוי cron או בשעות לימי חול ולסוף שבוע, לא בשניהם",
+	"Invalid cron expression: %s":                                                                                    "ביטוי cron לא תקין: %s",
+	"Unknown time zone":                                                                                              "אזור זמן לא מוכר",
+	"Failed to build heatmap":                                                                                        "יצירת מפת החום נכשלה",
+	"Failed to build distribution":                                                                                   "בניית ההתפלגות נכשלה",
+	"Context buckets are not enabled":                                                                                "דליי ההקשר אינם מופעלים",
+	"Failed to load context buckets":                                                                                 "טעינת דליי ההקשר נכשלה",
+	"Failed to check boiler efficiency":                                                                              "בדיקת יעילות הדוד נכשלה",
+	"Failed to compare devices":                                                                                      "השוואת המכשירים נכשלה",
+	"Either correlationId or recordId is required":                                                                   "יש לציין correlationId או recordId",
+	"Nothing was recorded under that correlation ID":                                                                 "לא נרשם דבר תחת מזהה הקישור הזה",
+	"Failed to retrieve trace":                                                                                       "אחזור המעקב נכשל",
+	"Failed to compute public statistics":                                                                            "חישוב הסטטיסטיקה הציבורית נכשל",
+	"Correlation ID must be at most 64 letters, digits, '.', '_', ':' or '-'":                                        "מזהה הקישור יכול להכיל עד 64 אותיות, ספרות, '.', '_', ':' או '-'",
+	"Failed to export prediction profile":                                                                            "ייצוא פרופיל החיזוי נכשל",
+	"Failed to import prediction profile":                                                                            "ייבוא פרופיל החיזוי נכשל",
+	"The profile has no settings for that user":                                                                      "בפרופיל אין הגדרות למשתמש הזה",
+	"The profile has several users; choose one with from":                                                            "בפרופיל יש כמה משתמשים; יש לבחור אחד בעזרת from",
+	"Unknown boiler preset %q":                                                                                       "סוג דוד לא מוכר %q",
+	"Database backend must be one of %s in this build":                                                               "שרת מסד הנתונים חייב להיות אחד מ-%s בגרסה זו",
+	"Latitude must be between -90 and 90 and longitude between -180 and 180, or both left out":                       "קו הרוחב חייב להיות בין -90 ל-90 וקו האורך בין -180 ל-180, או ששניהם יושמטו",
+	"Setup is already complete":                                                                                      "ההגדרה הראשונית כבר הושלמה",
+	"The setup admin was already created":                                                                            "מנהל ההגדרה כבר נוצר",
+	"Finish the %s setup step first":                                                                                 "יש להשלים קודם את שלב ההגדרה %s",
+	"Failed to save setup":                                                                                           "שמירת ההגדרה נכשלה",
+	"Setup isn't complete yet, finish it through /api/setup":                                                         "ההגדרה הראשונית טרם הושלמה, יש להשלים אותה דרך /api/setup",
+	"Unsupported locale %q":                                                                                          "אזור השפה %q אינו נתמך",
+	"Failed to check history integrity":                                                                              "בדיקת תקינות ההיסטוריה נכשלה",
+	"Failed to summarise data quality":                                                                               "סיכום איכות הנתונים נכשל",
+	"Failed to compute objectives":                                                                                   "חישוב יעדי השירות נכשל",
+	"Failed to fix records":                                                                                          "תיקון הרשומות נכשל",
+	"Failed to exclude records":                                                                                      "החרגת הרשומות נכשלה",
+	"Failed to sign records":                                                                                         "חתימת הרשומות נכשלה",
+	"Invalid webhook signature":                                                                                      "חתימת ה-webhook אינה תקינה",
+	"Rule %q has no usable number at %s":                                                                             "לכלל %q אין מספר תקין ב-%s",
+	"Failed to apply webhook":                                                                                        "החלת ה-webhook נכשלה",
+	"Failed to record detected shower":                                                                               "שמירת המקלחת שזוהתה נכשלה",
+	"Failed to retrieve operations":                                                                                  "טעינת הפעולות נכשלה",
+	"Failed to retrieve operation":                                                                                   "טעינת הפעולה נכשלה",
+	"Failed to cancel operation":                                                                                     "ביטול הפעולה נכשל",
+	"Failed to start backup":                                                                                         "הפעלת הגיבוי נכשלה",
+	"Failed to start compaction":                                                                                     "הפעלת דחיסת מסד הנתונים נכשלה",
+	"Operation not found":                                                                                            "הפעולה לא נמצאה",
+	"An operation of that kind is already in progress":                                                               "פעולה מסוג זה כבר מתבצעת",
+	"Operation has already finished":                                                                                 "הפעולה כבר הסתיימה",
+	"Request body must be sent as application/json":                                                                  "גוף הבקשה חייב להישלח כ-application/json",
+	"Request body must not exceed %d KiB":                                                                            "גוף הבקשה לא יכול לעלות על %d KiB",
+	"Safer heating times":                                                                                            "זמני חימום בטוחים יותר",
+	"When a suggested heating time jumps far from yesterday's without any new feedback, the previous time is kept and the jump is checked by the administrator.": "כאשר זמן החימום המוצע קופץ הרבה מזה של אתמול בלי משוב חדש, נשמר הזמן הקודם והקפיצה נבדקת על ידי המנהל.",
+	"Prediction model updated": "מודל החיזוי עודכן",
+	"Feedback the app guessed now counts less than the ratings you gave yourself. Expect heating times to adjust over the next few days.":                                        "משוב שהאפליקציה ניחשה נחשב כעת פחות מהדירוגים שנתת בעצמך. זמני החימום עשויים להשתנות בימים הקרובים.",
+	"Turning the heater back on by hand right after a shower you haven't rated now counts as that shower being too cold. Expect heating times to adjust over the next few days.": "הדלקה ידנית של הדוד מיד אחרי מקלחת שלא דירגת נחשבת כעת כסימן שהמקלחת הייתה קרה מדי. זמני החימום עשויים להשתנות בימים הקרובים.",
+	"Showers detected automatically": "זיהוי מקלחות אוטומטי",
+	"With a bathroom humidity sensor, showers are recognised on their own and wait in your history for a quick review.": "עם חיישן לחות בחדר הרחצה, מקלחות מזוהות מעצמן וממתינות בהיסטוריה לבדיקה קצרה.",
+	"Invite not found":                                                           "ההזמנה לא נמצאה",
+	"This invite has expired":                                                    "תוקף ההזמנה פג",
+	"This invite has already been used or was revoked":                           "ההזמנה כבר נוצלה או בוטלה",
+	"This invite is for another email address":                                   "ההזמנה מיועדת לכתובת דוא\"ל אחרת",
+	"Only household owners can do this":                                          "רק בעלי משק הבית יכולים לעשות זאת",
+	"You already belong to a household; leave it first":                          "כבר הצטרפת למשק בית; יש לעזוב אותו קודם",
+	"Others have joined your household, so it can't join another":                "אחרים הצטרפו למשק הבית שלך, ולכן הוא אינו יכול להצטרף למשק בית אחר",
+	"You can't leave your own household":                                         "אי אפשר לעזוב את משק הבית שלך",
+	"Failed to load household":                                                   "טעינת משק הבית נכשלה",
+	"Failed to create invite":                                                    "יצירת ההזמנה נכשלה",
+	"Failed to retrieve invites":                                                 "אחזור ההזמנות נכשל",
+	"Failed to revoke invite":                                                    "ביטול ההזמנה נכשל",
+	"Failed to load invite":                                                      "טעינת ההזמנה נכשלה",
+	"Failed to join household":                                                   "ההצטרפות למשק הבית נכשלה",
+	"Failed to leave household":                                                  "עזיבת משק הבית נכשלה",
+	"Join my household on Heat Logger":                                           "הצטרפות למשק הבית שלי ב-Heat Logger",
+	"Open this link to join my household: %s":                                    "פתחו את הקישור הזה כדי להצטרף למשק הבית שלי: %s",
+	"Failed to save region":                                                      "שמירת האזור נכשלה",
+	"Region must be tropical, subtropical, temperate, cold or polar":             "האזור חייב להיות tropical, subtropical, temperate, cold או polar",
+	"Choose 1 to 8 different metrics from GET /api/stats/reports/options":        "יש לבחור בין 1 ל-8 מדדים שונים מתוך GET /api/stats/reports/options",
+	"Group by at most 2 different groupings from GET /api/stats/reports/options": "ניתן לקבץ לפי 2 קיבוצים שונים לכל היותר מתוך GET /api/stats/reports/options",
+	"Each filter range must start before it ends":                                "כל טווח סינון חייב להתחיל לפני סופו",
+	"Failed to run report":                                                       "הפקת הדוח נכשלה",
+	"Cron must be a five-field cron expression that fires, in a known time zone": "ה-cron חייב להיות ביטוי cron בן חמישה שדות שמתממש, באזור זמן מוכר",
+	"Channel must be email or webhook":                                           "הערוץ חייב להיות email או webhook",
+	"Target must be an email address for email or an http(s) URL for webhook":    "היעד חייב להיות כתובת דוא\"ל עבור email או כתובת http(s) עבור webhook",
+	"Format must be csv or pdf":                                                  "הפורמט חייב להיות csv או pdf",
+	"Email delivery is not configured on this server":                            "שליחה בדוא\"ל אינה מוגדרת בשרת זה",
+	"Saved report not found":                                                     "הדוח השמור לא נמצא",
+	"A saved report with this name already exists":                               "כבר קיים דוח שמור בשם זה",
+	"Report subscription not found":                                              "המינוי לדוח לא נמצא",
+	"Failed to save report":                                                      "שמירת הדוח נכשלה",
+	"Failed to retrieve saved reports":                                           "טעינת הדוחות השמורים נכשלה",
+	"Failed to delete saved report":                                              "מחיקת הדוח השמור נכשלה",
+	"ReportID is required":                                                       "נדרש מזהה דוח",
+	"ID is required":                                                             "נדרש מזהה",
+	"Failed to create report subscription":                                       "יצירת המינוי לדוח נכשלה",
+	"Failed to retrieve report subscriptions":                                    "טעינת המינויים לדוחות נכשלה",
+	"Failed to update report subscription":                                       "עדכון המינוי לדוח נכשל",
+	"Failed to delete report subscription":                                       "מחיקת המינוי לדוח נכשלה",
+	"The file is not valid CSV":                                                  "הקובץ אינו CSV תקין",
+	"Map date, showerDuration, averageTemperature, heatingTime and satisfaction each to a different column": "יש למפות את date, showerDuration, averageTemperature, heatingTime ו-satisfaction כל אחד לעמודה אחרת",
+	"Temperature units must be C, F or K and duration units min, s or h":                                    "יחידות הטמפרטורה חייבות להיות C, F או K ויחידות משך הזמן min, s או h",
+	"Separator, date format or time zone is not supported":                                                  "המפריד, תבנית התאריך או אזור הזמן אינם נתמכים",
+	"The file must start with a header row":                                                                 "הקובץ חייב להתחיל בשורת כותרות",
+	"The file lacks a column the mapping reads":                                                             "בקובץ חסרה עמודה שהמיפוי קורא ממנה",
+	"A file can import at most 5000 rows at once":                                                           "ניתן לייבא לכל היותר 5000 שורות בבת אחת",
+	"Import mapping not found":                                                                              "מיפוי הייבוא לא נמצא",
+	"Failed to read the sample":                                                                             "קריאת הדוגמה נכשלה",
+	"Failed to read the file":                                                                               "קריאת הקובץ נכשלה",
+	"Send either a mapping or the ID of a saved mapping":                                                    "יש לשלוח מיפוי או מזהה של מיפוי שמור, אך לא את שניהם",
+	"Failed to load import mapping":                                                                         "טעינת מיפוי הייבוא נכשלה",
+	"Failed to save import mapping":                                                                         "שמירת מיפוי הייבוא נכשלה",
+	"Failed to retrieve import mappings":                                                                    "טעינת מיפויי הייבוא נכשלה",
+	"Failed to delete import mapping":                                                                       "מחיקת מיפוי הייבוא נכשלה",
+	"Value is missing":                                                                                      "חסר ערך",
+	"Value is not a number":                                                                                 "הערך אינו מספר",
+	"Value doesn't match the date format":                                                                   "הערך אינו תואם את תבנית התאריך",
+	"Add a shower schedule to forecast the week":                                                            "יש להוסיף לוח זמנים למקלחות כדי לחזות את השבוע",
+	"No temperature forecast or reading is available":                                                       "אין תחזית טמפרטורה או קריאה זמינה",
+	"Target satisfaction must be between 30 and 70":                                                         "שביעות הרצון היעד חייבת להיות בין 30 ל-70",
+	"Failed to save target satisfaction":                                                                    "שמירת שביעות הרצון היעד נכשלה",
+	"Prediction not found":                                                                                  "התחזית לא נמצאה",
+	"Failed to retrieve prediction":                                                                         "טעינת התחזית נכשלה",
+	"Verdict must be perfect, too_cold or too_hot":                                                          "התשובה חייבת להיות perfect,‏ too_cold או too_hot",
+	"That prediction already has feedback":                                                                  "כבר ניתן משוב על התחזית הזו",
+	"Failed to check schedule conflicts":                                                                    "בדיקת ההתנגשויות בלוח הזמנים נכשלה",
+	"The schedule overlaps another shower on the same water heater":                                         "לוח הזמנים חופף למקלחת אחרת על אותו דוד",
+	"A user can't be merged into itself":                                                                    "אי אפשר למזג משתמש לתוך עצמו",
+	"Failed to merge users":                                                                                 "מיזוג המשתמשים נכשל",
+	"Users merged successfully":                                                                             "המשתמשים מוזגו בהצלחה",
+	"Record archiving is not configured":                                                                    "ארכוב הרשומות אינו מוגדר",
+	"Failed to start record archiving":                                                                      "הפעלת ארכוב הרשומות נכשלה",
+	"Failed to load notification routes":                                                                    "טעינת נתיבי ההתראות נכשלה",
+	"Failed to save notification route":                                                                     "שמירת נתיב ההתראות נכשלה",
+	"Failed to delete notification route":                                                                   "מחיקת נתיב ההתראות נכשלה",
+	"Notification route not found":                                                                          "נתיב ההתראות לא נמצא",
+	"Notification route deleted successfully":                                                               "נתיב ההתראות נמחק בהצלחה",
+	"Unknown notification channel":                                                                          "ערוץ התראות לא מוכר",
+	"The target can't be used with this channel":                                                            "לא ניתן להשתמש ביעד הזה עם הערוץ הזה",
+	"Unknown notification kind":                                                                             "סוג התראה לא מוכר",
+}
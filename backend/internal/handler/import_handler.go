@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles HTTP requests for importing records from CSV files laid out like the
+// user's old spreadsheet
+type ImportHandler struct {
+	csvImports *services.CSVImportService
+	records    *RecordHandler // stores imported rows the way uploaded feedback is stored
+}
+
+// NewImportHandler creates a new import handler instance
+func NewImportHandler(csvImports *services.CSVImportService, records *RecordHandler) *ImportHandler {
+	return &ImportHandler{
+		csvImports: csvImports,
+		records:    records,
+	}
+}
+
+// csvImportError maps CSV import errors to responses
+func csvImportError(c *gin.Context, err error, message string) {
+	var parseErr *csv.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		validationError(c, "csv", "csv", "The file is not valid CSV")
+	case errors.Is(err, services.ErrImportMappingFields):
+		validationError(c, "mapping.columns", "required", "Map date, showerDuration, averageTemperature, heatingTime and satisfaction each to a different column")
+	case errors.Is(err, services.ErrImportMappingUnit):
+		validationError(c, "mapping.columns", "oneof", "Temperature units must be C, F or K and duration units min, s or h")
+	case errors.Is(err, services.ErrImportMappingFormat):
+		validationError(c, "mapping", "oneof", "Separator, date format or time zone is not supported")
+	case errors.Is(err, services.ErrImportNoHeader):
+		validationError(c, "csv", "required", "The file must start with a header row")
+	case errors.Is(err, services.ErrImportColumnMissing):
+		validationError(c, "csv", "columns", "The file lacks a column the mapping reads")
+	case errors.Is(err, services.ErrImportTooManyRows):
+		validationError(c, "csv", "max", "A file can import at most 5000 rows at once")
+	case errors.Is(err, services.ErrImportMappingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Import mapping not found")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, message) + ": " + err.Error()})
+	}
+}
+
+// ProposeCSVMapping handles POST /api/imports/csv/propose. The client sends the header and
+// first rows of a file; the server proposes which column feeds each field and in what unit,
+// with a preview of the rows as they would import.
+func (h *ImportHandler) ProposeCSVMapping(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		Sample string `json:"sample" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	proposal, err := h.csvImports.Propose(req.UserID, req.Sample)
+	if err != nil {
+		csvImportError(c, err, "Failed to read the sample")
+		return
+	}
+	for i := range proposal.Preview {
+		if proposal.Preview[i].Problem != "" {
+			proposal.Preview[i].Problem = t(c, proposal.Preview[i].Problem)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"proposal":    proposal,
+		"dateFormats": services.ImportDateFormats(),
+	})
+}
+
+// ImportCSV handles POST /api/imports/csv?dryRun=, importing a whole file through the mapping
+// the user confirmed or one they saved. saveAs keeps the mapping for the next file. Each row
+// gets a result; rows of a file imported before come back as duplicates. A dry run reads and
+// checks the rows without storing them.
+func (h *ImportHandler) ImportCSV(c *gin.Context) {
+	var req struct {
+		UserID    string               `json:"userId" binding:"required"`
+		CSV       string               `json:"csv" binding:"required"`
+		Mapping   *services.CSVMapping `json:"mapping"`
+		MappingID string               `json:"mappingId"`
+		SaveAs    string               `json:"saveAs"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if (req.Mapping == nil) == (req.MappingID == "") {
+		validationError(c, "mapping", "required_without", "Send either a mapping or the ID of a saved mapping")
+		return
+	}
+	if h.records.rejectDisabledUser(c, req.UserID) {
+		return
+	}
+
+	var mapping services.CSVMapping
+	if req.Mapping != nil {
+		mapping = *req.Mapping
+	} else {
+		saved, err := h.csvImports.Mapping(req.UserID, req.MappingID)
+		if err != nil {
+			csvImportError(c, err, "Failed to load import mapping")
+			return
+		}
+		mapping = saved
+	}
+
+	rows, err := h.csvImports.Parse(req.UserID, req.CSV, mapping)
+	if err != nil {
+		csvImportError(c, err, "Failed to read the file")
+		return
+	}
+
+	dryRun := c.Query("dryRun") == "true"
+	results := make([]csvImportResult, len(rows))
+	var valid []*models.DailyRecord
+	var validIndex []int
+	for i := range rows {
+		results[i] = csvImportResult{Line: rows[i].Line, syncUploadResult: syncUploadResult{Status: syncRejected}}
+		if rows[i].Record == nil {
+			results[i].Error = t(c, rows[i].Problem)
+			continue
+		}
+		results[i].ID = rows[i].Record.ID
+		if results[i].Error = h.records.checkUploadedRecord(c, req.UserID, rows[i].Record); results[i].Error != "" {
+			continue
+		}
+		if dryRun {
+			results[i].Status = importValid
+			continue
+		}
+		valid = append(valid, rows[i].Record)
+		validIndex = append(validIndex, i)
+	}
+
+	if !dryRun {
+		for n, err := range h.records.recordService.CreateRecords(valid) {
+			i := validIndex[n]
+			results[i].Status, results[i].Error = uploadOutcome(c, err)
+		}
+	}
+
+	response := gin.H{
+		"success": true,
+		"dryRun":  dryRun,
+		"results": results,
+	}
+	if req.SaveAs != "" && !dryRun {
+		saved, err := h.csvImports.SaveMapping(req.UserID, req.SaveAs, mapping)
+		if err != nil {
+			csvImportError(c, err, "Failed to save import mapping")
+			return
+		}
+		response["mapping"] = saved
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// importValid is the status of a row a dry run would import
+const importValid = "valid"
+
+// csvImportResult reports what happened to one row of an imported file
+type csvImportResult struct {
+	Line int `json:"line"`
+	syncUploadResult
+}
+
+// ListCSVMappings handles GET /api/imports/csv/mappings?userId=
+func (h *ImportHandler) ListCSVMappings(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	mappings, err := h.csvImports.ListMappings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve import mappings") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mappings": mappings,
+	})
+}
+
+// DeleteCSVMapping handles POST /api/imports/csv/mappings/delete
+func (h *ImportHandler) DeleteCSVMapping(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.csvImports.DeleteMapping(req.UserID, req.ID); err != nil {
+		csvImportError(c, err, "Failed to delete import mapping")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
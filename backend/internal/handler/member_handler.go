@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MemberHandler handles HTTP requests for household member profiles
+type MemberHandler struct {
+	memberService *services.MemberService
+}
+
+// NewMemberHandler creates a new member handler instance
+func NewMemberHandler(memberService *services.MemberService) *MemberHandler {
+	return &MemberHandler{
+		memberService: memberService,
+	}
+}
+
+// memberError maps member service errors to responses
+func memberError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Member not found")})
+	case errors.Is(err, services.ErrMemberNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "A member with this name already exists")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, message) + ": " + err.Error()})
+	}
+}
+
+// validMemberName trims the name and writes a 400 when it is empty or too long
+func validMemberName(c *gin.Context, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 32 {
+		validationError(c, "name", "length", "Member name must be between 1 and 32 characters")
+		return "", false
+	}
+	return name, true
+}
+
+// CreateMember handles POST /api/members
+func (h *MemberHandler) CreateMember(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		Name   string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	name, ok := validMemberName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	member := &models.Member{UserID: req.UserID, Name: name}
+	if err := h.memberService.CreateMember(member); err != nil {
+		memberError(c, err, "Failed to save member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"member":  member,
+	})
+}
+
+// ListMembers handles GET /api/members?userId=
+func (h *MemberHandler) ListMembers(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	members, err := h.memberService.ListMembers(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve members") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+	})
+}
+
+// RenameMember handles POST /api/members/rename
+func (h *MemberHandler) RenameMember(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+		Name   string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	name, ok := validMemberName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	member, err := h.memberService.RenameMember(req.UserID, req.ID, name)
+	if err != nil {
+		memberError(c, err, "Failed to rename member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"member":  member,
+	})
+}
+
+// DeleteMember handles POST /api/members/delete
+func (h *MemberHandler) DeleteMember(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.memberService.DeleteMember(req.UserID, req.ID); err != nil {
+		memberError(c, err, "Failed to delete member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Member deleted successfully"),
+	})
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// satisfactionScaleVersion changes whenever the meaning of satisfaction values changes
+const satisfactionScaleVersion = 1
+
+// satisfactionBand is a labelled range of the satisfaction scale
+type satisfactionBand struct {
+	From    float64 `json:"from"`
+	To      float64 `json:"to"`
+	Meaning string  `json:"meaning"` // stable key for clients
+	Label   string  `json:"label"`   // translated for display
+}
+
+// satisfactionBands splits the scale the way the predictors read it
+var satisfactionBands = []satisfactionBand{
+	{From: models.SatisfactionMin, To: 29, Meaning: "much_too_cold", Label: "Much too cold"},
+	{From: 30, To: 44, Meaning: "too_cold", Label: "A bit cold"},
+	{From: 45, To: 55, Meaning: "perfect", Label: "Perfect"},
+	{From: 56, To: 70, Meaning: "too_hot", Label: "A bit hot"},
+	{From: 71, To: models.SatisfactionMax, Meaning: "much_too_hot", Label: "Much too hot"},
+}
+
+// MetaHandler describes how the server interprets client input
+type MetaHandler struct {
+	prediction config.PredictionConfig
+	targets    services.SatisfactionTargetLookup
+}
+
+// NewMetaHandler creates a new meta handler instance
+func NewMetaHandler(prediction config.PredictionConfig, targets services.SatisfactionTargetLookup) *MetaHandler {
+	return &MetaHandler{
+		prediction: prediction,
+		targets:    targets,
+	}
+}
+
+// limitRange is an inclusive range a request value must fall in
+type limitRange struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Unit string  `json:"unit"`
+}
+
+// GetSatisfactionScale handles GET /api/meta/satisfaction-scale
+func (h *MetaHandler) GetSatisfactionScale(c *gin.Context) {
+	bands := make([]satisfactionBand, len(satisfactionBands))
+	for i, band := range satisfactionBands {
+		band.Label = t(c, band.Label)
+		bands[i] = band
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":   satisfactionScaleVersion,
+		"min":       models.SatisfactionMin,
+		"max":       models.SatisfactionMax,
+		"neutral":   models.SatisfactionPerfect,
+		"direction": "higher_is_hotter", // below neutral the water was too cold, above it too hot
+		"bands":     bands,
+	})
+}
+
+// adjustmentCurveVersion changes whenever the mapping from ratings to adjustments changes
+const adjustmentCurveVersion = 1
+
+// adjustmentPoint is how the v2 predictor reads one rating
+type adjustmentPoint struct {
+	Satisfaction  float64 `json:"satisfaction"`
+	Multiplier    float64 `json:"multiplier"`    // the heating time the rating implies, relative to the time rated
+	ChangePercent float64 `json:"changePercent"` // the same as a change, e.g. -13 for a 13% shorter heating time
+}
+
+// GetAdjustmentCurve handles GET /api/meta/adjustment-curve?userId=: for every rating, how the
+// v2 predictor changes the heating time it was given for, so clients can preview what a rating
+// does before it is sent. With a userId the ratings are read against the user's satisfaction
+// target. The step cap bounds how far a single prediction moves toward the result.
+func (h *MetaHandler) GetAdjustmentCurve(c *gin.Context) {
+	target := float64(models.SatisfactionPerfect)
+	if userID := c.Query("userId"); userID != "" && h.targets != nil {
+		target = h.targets.Target(userID)
+	}
+
+	points := make([]adjustmentPoint, 0, models.SatisfactionMax-models.SatisfactionMin+1)
+	for s := models.SatisfactionMin; s <= models.SatisfactionMax; s++ {
+		multiplier := services.ImpliedMultiplierFor(float64(s), target)
+		points = append(points, adjustmentPoint{
+			Satisfaction:  float64(s),
+			Multiplier:    math.Round(multiplier*10000) / 10000,
+			ChangePercent: math.Round((multiplier-1)*1000) / 10,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":   adjustmentCurveVersion,
+		"predictor": "v2",
+		"target":    target,                          // the rating that keeps the heating time
+		"stepCap":   h.prediction.V2.StepCapFraction, // the default; users may set their own
+		"points":    points,
+	})
+}
+
+// GetValidationLimits handles GET /api/meta/limits
+func (h *MetaHandler) GetValidationLimits(c *gin.Context) {
+	minMinutes, maxMinutes := h.prediction.HeatingBounds()
+	limits := limitsOf(c) // narrowed by the operator's rules
+
+	c.JSON(http.StatusOK, gin.H{
+		"duration":         limits.Duration,
+		"temperature":      limits.Temperature,
+		"inletTemperature": limitRange{Min: minInletTemperature, Max: maxInletTemperature, Unit: "°C"},
+		"satisfaction":     limitRange{Min: models.SatisfactionMin, Max: models.SatisfactionMax},
+		"heatingTime":      limitRange{Min: minMinutes, Max: maxMinutes, Unit: "min"}, // what predictions are clamped to
+		"sequenceShowers":  maxSequenceShowers,
+	})
+}
+
+// schemaVersion changes whenever a field is renamed, removed or changes meaning
+const schemaVersion = 1
+
+// GetSchema handles GET /api/meta/schema. It describes the fields of everything the API exports
+// or imports, read from the model structs so it can't drift from them.
+func (h *MetaHandler) GetSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version": schemaVersion,
+		"entities": gin.H{
+			"record":     models.DescribeFields(models.DailyRecord{}),          // history, CSV and sync
+			"preset":     models.DescribeFields(models.Preset{}),               // sync bundle
+			"member":     models.DescribeFields(models.Member{}),               // sync bundle
+			"prediction": models.DescribeFields(services.PredictionLogEntry{}), // prediction export
+		},
+	})
+}
+
+// changelogJSON holds the release notes, newest first, embedded at build time
+//
+//go:embed changelog.json
+var changelogJSON []byte
+
+// changelogEntry is one user-facing release note
+type changelogEntry struct {
+	ID             string `json:"id"` // stable key clients remember as the last note shown
+	Date           string `json:"date"`
+	ModelChanged   bool   `json:"modelChanged"`             // the predictors learn or answer differently
+	AdjustmentDays int    `json:"adjustmentDays,omitempty"` // days heating times may take to settle after a model change
+	Title          string `json:"title"`
+	Text           string `json:"text"`
+}
+
+// changelog is the parsed release notes; a malformed file stops the server at startup
+var changelog = func() []changelogEntry {
+	var entries []changelogEntry
+	if err := json.Unmarshal(changelogJSON, &entries); err != nil {
+		panic("changelog.json: " + err.Error())
+	}
+	return entries
+}()
+
+// GetChangelog handles GET /api/meta/changelog?since=. With since, the id of the last note a
+// client showed, only newer notes are returned; an unknown id returns them all. modelChanged
+// and adjustmentDays summarise the returned notes so a client can show one notice for them.
+func (h *MetaHandler) GetChangelog(c *gin.Context) {
+	entries := changelog
+	if since := c.Query("since"); since != "" {
+		for i, entry := range changelog {
+			if entry.ID == since {
+				entries = changelog[:i]
+				break
+			}
+		}
+	}
+
+	notes := make([]changelogEntry, len(entries))
+	modelChanged, adjustmentDays := false, 0
+	for i, entry := range entries {
+		entry.Title = t(c, entry.Title)
+		entry.Text = t(c, entry.Text)
+		notes[i] = entry
+		modelChanged = modelChanged || entry.ModelChanged
+		adjustmentDays = max(adjustmentDays, entry.AdjustmentDays)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":        notes,
+		"modelChanged":   modelChanged,
+		"adjustmentDays": adjustmentDays,
+	})
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"heat-logger/internal/services"
+	"heat-logger/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics returns the GET /metrics handler, writing the query metrics and the prediction
+// objectives in the Prometheus text format
+func Metrics(slo *services.SLOService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		if metrics := database.GetQueryMetrics(); metrics != nil {
+			metrics.WritePrometheus(c.Writer)
+		}
+		slo.WritePrometheus(c.Writer)
+	}
+}
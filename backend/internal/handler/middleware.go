@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"mime"
+	"net/http"
+	"strings"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// adminActorKey is the context key holding the name of the authenticated admin
+const adminActorKey = "adminActor"
+
+// tokenActor is the actor audited for requests authenticated with the configured admin token
+const tokenActor = "token"
+
+// AdminAuth requires a login session with the admin role, the token handed out by the setup
+// wizard or the configured admin bearer token. An empty token disables access with a
+// configured token.
+func AdminAuth(token string, sessions *services.SessionService, setup *services.SetupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cookie, err := c.Cookie(sessionCookie); err == nil {
+			if session, err := sessions.Lookup(cookie); err == nil && session.Role == models.RoleAdmin {
+				c.Set(adminActorKey, session.UserID)
+				c.Next()
+				return
+			}
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if userID, ok := setup.AdminByToken(provided); ok {
+			c.Set(adminActorKey, userID)
+			c.Next()
+			return
+		}
+
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": t(c, "Admin API is disabled"),
+			})
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": t(c, "Invalid admin credentials"),
+			})
+			return
+		}
+
+		// The shared token names nobody, and a name sent along with it can't be verified
+		c.Set(adminActorKey, tokenActor)
+		c.Next()
+	}
+}
+
+// RequireSetup answers every route it guards with 503 until the setup wizard is finished, so a
+// fresh install isn't used half configured
+func RequireSetup(setup *services.SetupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if setup.Complete() || c.FullPath() == "/api/health/db" {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": t(c, "Setup isn't complete yet, finish it through /api/setup"),
+			"next":  setup.Status().Next,
+		})
+	}
+}
+
+// SyncAuth requires the shared sync bearer token that instances use to talk to each other
+func SyncAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": t(c, "Invalid sync credentials"),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// DemandResponseAuth requires the bearer token the utility's demand-response bridge signals with
+func DemandResponseAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": t(c, "Invalid demand-response credentials"),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// readOnlyExempt lists write endpoints that stay available while the database is read-only:
+// calculation and scheduling don't write, feedback and sensor records are buffered, share links
+// are stateless and device state, sensor readings and solar production are kept in memory.
+var readOnlyExempt = map[string]bool{
+	"/api/calculate":          true,
+	"/api/calculate/sequence": true,
+	"/api/schedule/recommend": true,
+	"/api/feedback":           true,
+	"/api/sync":               true,
+	"/api/share-links":        true,
+
+	"/api/devices/:id/events":   true,
+	"/api/temperature/readings": true,
+	"/api/humidity/readings":    true,
+	"/api/solar/forecast":       true,
+	"/api/solar/production":     true,
+	"/api/webhooks/:source":     true,
+}
+
+// RejectWritesWhenReadOnly answers mutating requests with 503 while the database is read-only
+func RejectWritesWhenReadOnly(health *services.DBHealthMonitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if readOnlyExempt[c.FullPath()] || !health.ReadOnly() {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": t(c, "Storage is temporarily unavailable, please try again later"),
+		})
+	}
+}
+
+// DefaultBodyLimitsKB raises the body size limit, in KiB, of the endpoints taking many items at once
+func DefaultBodyLimitsKB() map[string]float64 {
+	return map[string]float64{
+		"/api/feedback/batch":              1024,
+		"/api/imports/csv":                 1024,
+		"/api/sync":                        1024,
+		"/api/sync/import":                 16384,
+		"/api/admin/prediction-profile":    1024,
+		"/api/users/me/prediction-profile": 1024,
+	}
+}
+
+// LimitRequestBody answers requests with a body larger than maxKB KiB, or the route's limit in
+// limitsKB merged over DefaultBodyLimitsKB, with 413. A maxKB of 0 turns the limits off, and a
+// route limit of 0 leaves that route unlimited. A body without a declared length is cut off
+// while it is read, which the handler's bind reports.
+func LimitRequestBody(maxKB float64, limitsKB map[string]float64) gin.HandlerFunc {
+	limits := DefaultBodyLimitsKB()
+	for path, limit := range limitsKB {
+		limits[path] = limit
+	}
+	return func(c *gin.Context) {
+		limitKB, ok := limits[c.FullPath()]
+		if !ok {
+			limitKB = maxKB
+		}
+		if maxKB <= 0 || limitKB <= 0 {
+			c.Next()
+			return
+		}
+		limit := int64(limitKB * 1024)
+		if c.Request.ContentLength > limit {
+			bodyTooLarge(c, limit)
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// RequireJSON answers requests whose body isn't declared as application/json, or a JSON-based
+// application/*+json type, with 415; every endpoint but the webhook receiver reads JSON, and
+// rejecting anything else up front keeps form posts and uploads out. Webhooks are sent in
+// whatever format their sender chose and checked by their signature.
+func RequireJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength == 0 || c.FullPath() == "/api/webhooks/:source" {
+			c.Next()
+			return
+		}
+		if mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type")); err == nil && jsonMediaType(mediaType) {
+			c.Next()
+			return
+		}
+		message := t(c, "Request body must be sent as application/json")
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   message,
+			"details": []FieldError{{Rule: "content_type", Message: message}},
+		})
+	}
+}
+
+// jsonMediaType reports whether a parsed media type is application/json or a structured
+// syntax suffix type such as application/merge-patch+json
+func jsonMediaType(mediaType string) bool {
+	return mediaType == "application/json" ||
+		strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")
+}
+
+// correlationKey is the context key holding the request's correlation ID
+const correlationKey = "correlationId"
+
+// CorrelationHeader carries the correlation ID that ties a prediction to the heating run after it
+const CorrelationHeader = "X-Correlation-ID"
+
+// maxCorrelationIDLength is the longest correlation ID a client may supply
+const maxCorrelationIDLength = 64
+
+// Correlation gives every request a correlation ID, the one in its X-Correlation-ID header or a
+// new one, and echoes it in the response. IDs that are too long or contain anything but
+// letters, digits, '.', '_', ':' and '-' are replaced rather than rejected: tracing must not
+// cost anyone their answer.
+func Correlation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(CorrelationHeader)
+		if !validCorrelationID(id) {
+			id = uuid.New().String()
+		}
+		c.Set(correlationKey, id)
+		c.Header(CorrelationHeader, id)
+		c.Next()
+	}
+}
+
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("._:-", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// correlationID returns the correlation ID assigned by Correlation
+func correlationID(c *gin.Context) string {
+	return c.GetString(correlationKey)
+}
+
+// adminActor returns the admin name recorded by AdminAuth
+func adminActor(c *gin.Context) string {
+	return c.GetString(adminActorKey)
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/notify"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notificationLogLimit is how many log entries GET /api/users/me/notifications returns
+const notificationLogLimit = 100
+
+// NotificationHandler handles HTTP requests for a user's notification limits, routes and log
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+// NewNotificationHandler creates a new notification handler instance
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// GetNotificationSettings handles GET /api/users/me/notification-settings?userId=
+func (h *NotificationHandler) GetNotificationSettings(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	settings, err := h.notificationService.Settings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load notification settings") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings": settings,
+	})
+}
+
+// SetNotificationSettings handles POST /api/users/me/notification-settings. Omitting quietHours
+// or dailyLimit restores the deployment default for it.
+func (h *NotificationHandler) SetNotificationSettings(c *gin.Context) {
+	var req struct {
+		UserID     string `json:"userId" binding:"required"`
+		QuietHours string `json:"quietHours"`
+		DailyLimit *int   `json:"dailyLimit" binding:"omitempty,min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.notificationService.SetSettings(req.UserID, req.QuietHours, req.DailyLimit); err != nil {
+		if errors.Is(err, services.ErrInvalidQuietHours) {
+			validationError(c, "quietHours", "format", "Quiet hours must look like 22:00-07:00 or be off")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to save notification settings") + ": " + err.Error(),
+		})
+		return
+	}
+
+	settings, err := h.notificationService.Settings(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load notification settings") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"settings": settings,
+	})
+}
+
+// GetNotificationLog handles GET /api/users/me/notifications?userId=&status=, listing the
+// notifications sent, suppressed or failed most recently
+func (h *NotificationHandler) GetNotificationLog(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", models.NotificationSent, models.NotificationSuppressed, models.NotificationFailed:
+	default:
+		validationError(c, "status", "oneof", "Status must be sent, suppressed or failed")
+		return
+	}
+
+	entries, err := h.notificationService.Log(userID, status, notificationLogLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load notifications") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": entries,
+	})
+}
+
+// ListNotificationChannels handles GET /api/notification-channels, listing the channels this
+// deployment can deliver over and what their route targets are
+func (h *NotificationHandler) ListNotificationChannels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"channels": h.notificationService.Channels(),
+		"kinds":    models.NotificationKinds,
+	})
+}
+
+// ListNotificationRoutes handles GET /api/users/me/notification-routes?userId=. Users without
+// routes get their notifications at the deployment's webhook.
+func (h *NotificationHandler) ListNotificationRoutes(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	routes, err := h.notificationService.Routes(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load notification routes") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"routes": routes,
+	})
+}
+
+// CreateNotificationRoute handles POST /api/users/me/notification-routes, sending the user's
+// notifications of the given kinds (all but the opt-in ones when empty) over a channel to a
+// target. The token, for channels that take one, is never returned.
+func (h *NotificationHandler) CreateNotificationRoute(c *gin.Context) {
+	var req struct {
+		UserID  string   `json:"userId" binding:"required"`
+		Channel string   `json:"channel" binding:"required"`
+		Target  string   `json:"target" binding:"required"`
+		Token   string   `json:"token"`
+		Kinds   []string `json:"kinds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	route := &models.NotificationRoute{
+		UserID:  req.UserID,
+		Channel: req.Channel,
+		Target:  strings.TrimSpace(req.Target),
+		Token:   req.Token,
+		Kinds:   strings.Join(req.Kinds, ","),
+	}
+	if err := h.notificationService.AddRoute(route); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownNotificationChannel):
+			validationError(c, "channel", "oneof", "Unknown notification channel")
+		case errors.Is(err, notify.ErrInvalidTarget):
+			validationError(c, "target", "format", "The target can't be used with this channel")
+		case errors.Is(err, services.ErrUnknownNotificationKind):
+			validationError(c, "kinds", "oneof", "Unknown notification kind")
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": t(c, "Failed to save notification route") + ": " + err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"route":   route,
+	})
+}
+
+// DeleteNotificationRoute handles POST /api/users/me/notification-routes/delete
+func (h *NotificationHandler) DeleteNotificationRoute(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.notificationService.DeleteRoute(req.UserID, req.ID); err != nil {
+		if errors.Is(err, services.ErrNotificationRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Notification route not found")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to delete notification route") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Notification route deleted successfully"),
+	})
+}
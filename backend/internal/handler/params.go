@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseTimeQuery parses an optional RFC3339 or YYYY-MM-DD query parameter
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed, nil
+		}
+	}
+	return nil, fmt.Errorf(t(c, "%s must be a date (YYYY-MM-DD) or RFC3339 timestamp"), key)
+}
+
+// parseSourceQuery parses an optional comma-separated ?source= filter, rejecting unknown sources
+func parseSourceQuery(c *gin.Context) ([]string, error) {
+	value := c.Query("source")
+	if value == "" {
+		return nil, nil
+	}
+	var sources []string
+	for _, source := range strings.Split(value, ",") {
+		source = strings.TrimSpace(source)
+		if !models.IsValidRecordSource(source) {
+			return nil, fmt.Errorf(t(c, "Unknown record source %q"), source)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// isDryRun reports whether a destructive request only asks, with ?dryRun=true, what it would affect
+func isDryRun(c *gin.Context) bool {
+	return c.Query("dryRun") == "true"
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PredictionLogHandler handles HTTP requests for the log of served predictions
+type PredictionLogHandler struct {
+	predictionLog *services.PredictionLogService
+}
+
+// NewPredictionLogHandler creates a new prediction log handler instance
+func NewPredictionLogHandler(predictionLog *services.PredictionLogService) *PredictionLogHandler {
+	return &PredictionLogHandler{
+		predictionLog: predictionLog,
+	}
+}
+
+// ExportPredictionLog handles GET /api/predictions/export?userId=&from=&to=&format=&locale=&timezone=,
+// returning each prediction's inputs, model version and output with the feedback that followed
+// it as CSV (the default) or JSON. from is inclusive and to exclusive; locale and timezone only
+// change the CSV.
+func (h *PredictionLogHandler) ExportPredictionLog(c *gin.Context) {
+	filter := services.PredictionLogFilter{UserID: c.Query("userId")}
+	var err error
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		validationError(c, "from", "date", err.Error())
+		return
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		validationError(c, "to", "date", err.Error())
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		validationError(c, "format", "oneof", "Format must be csv or json")
+		return
+	}
+	csvFormat, ok := exportFormat(c, c.Query("locale"), c.Query("timezone"))
+	if !ok {
+		return
+	}
+
+	entries, err := h.predictionLog.Export(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve prediction log") + ": " + err.Error(),
+		})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"predictions": entries,
+		})
+		return
+	}
+
+	filename := "prediction_log_" + time.Now().Format("2006-01-02") + ".csv"
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	if err := services.WritePredictionLogCSV(c.Writer, entries, csvFormat); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to write CSV data"),
+		})
+		return
+	}
+}
+
+// ListHeldPredictions handles GET /api/admin/predictions/held: the most recent predictions the
+// rate-of-change guard held back, for an admin to check the model
+func (h *PredictionLogHandler) ListHeldPredictions(c *gin.Context) {
+	entries, err := h.predictionLog.ListHeld(100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve prediction log") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"predictions": entries,
+	})
+}
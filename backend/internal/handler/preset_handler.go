@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PresetHandler handles HTTP requests for named shower presets
+type PresetHandler struct {
+	presetService *services.PresetService
+}
+
+// NewPresetHandler creates a new preset handler instance
+func NewPresetHandler(presetService *services.PresetService) *PresetHandler {
+	return &PresetHandler{
+		presetService: presetService,
+	}
+}
+
+type presetRequest struct {
+	ID          string   `json:"id"`
+	UserID      string   `json:"userId" binding:"required"`
+	Name        string   `json:"name" binding:"required"`
+	Duration    float64  `json:"duration"`
+	Temperature *float64 `json:"temperature"`
+	Usage       string   `json:"usage"`
+}
+
+// bindPreset binds and validates a preset payload, writing the error response on failure
+func bindPreset(c *gin.Context) (*models.Preset, bool) {
+	var req presetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return nil, false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 64 {
+		validationError(c, "name", "length", "Preset name must be between 1 and 64 characters")
+		return nil, false
+	}
+
+	if !validShowerDuration(c, req.Duration) {
+		durationRangeError(c, "duration")
+		return nil, false
+	}
+
+	if req.Temperature != nil && !validTemperature(c, *req.Temperature) {
+		temperatureRangeError(c, "temperature")
+		return nil, false
+	}
+
+	return &models.Preset{
+		ID:          req.ID,
+		UserID:      req.UserID,
+		Name:        name,
+		Duration:    req.Duration,
+		Temperature: req.Temperature,
+		Usage:       req.Usage,
+	}, true
+}
+
+// presetError maps preset service errors to responses
+func presetError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPresetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Preset not found")})
+	case errors.Is(err, services.ErrPresetNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "A preset with this name already exists")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, message) + ": " + err.Error()})
+	}
+}
+
+// CreatePreset handles POST /api/presets
+func (h *PresetHandler) CreatePreset(c *gin.Context) {
+	preset, ok := bindPreset(c)
+	if !ok {
+		return
+	}
+	preset.ID = ""
+
+	if err := h.presetService.CreatePreset(preset); err != nil {
+		presetError(c, err, "Failed to save preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"preset":  preset,
+	})
+}
+
+// ListPresets handles GET /api/presets?userId=
+func (h *PresetHandler) ListPresets(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	presets, err := h.presetService.ListPresets(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve presets") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"presets": presets,
+	})
+}
+
+// UpdatePreset handles POST /api/presets/update
+func (h *PresetHandler) UpdatePreset(c *gin.Context) {
+	preset, ok := bindPreset(c)
+	if !ok {
+		return
+	}
+	if preset.ID == "" {
+		validationError(c, "id", "required", "Preset ID is required")
+		return
+	}
+
+	if err := h.presetService.UpdatePreset(preset); err != nil {
+		presetError(c, err, "Failed to update preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"preset":  preset,
+	})
+}
+
+// DeletePreset handles POST /api/presets/delete
+func (h *PresetHandler) DeletePreset(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.presetService.DeletePreset(req.UserID, req.ID); err != nil {
+		presetError(c, err, "Failed to delete preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Preset deleted successfully"),
+	})
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PriceHandler handles HTTP requests for spot electricity prices and heating schedules
+type PriceHandler struct {
+	priceService *services.PriceService
+	scheduler    *services.HeatingScheduler
+	templates    *services.ScheduleTemplateService
+	correlations *services.CorrelationService
+}
+
+// NewPriceHandler creates a new price handler instance
+func NewPriceHandler(priceService *services.PriceService, scheduler *services.HeatingScheduler, templates *services.ScheduleTemplateService, correlations *services.CorrelationService) *PriceHandler {
+	return &PriceHandler{
+		priceService: priceService,
+		scheduler:    scheduler,
+		templates:    templates,
+		correlations: correlations,
+	}
+}
+
+// GetEnergyPrices handles GET /api/prices?date=, returning the day's hourly prices (today by default)
+func (h *PriceHandler) GetEnergyPrices(c *gin.Context) {
+	day := h.scheduler.Now()
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			validationError(c, "date", "format", "Date must be in YYYY-MM-DD format")
+			return
+		}
+		day = parsed
+	}
+
+	prices, err := h.priceService.DayPrices(c.Request.Context(), day)
+	if err != nil {
+		priceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"currency": h.priceService.Currency(),
+		"prices":   prices,
+	})
+}
+
+// RecommendHeatingSchedule handles POST /api/schedule/recommend, picking the cheapest time to
+// heat for heatingTime minutes so the water is ready by readyBy. Spot prices and forecast solar
+// surplus make a run cheaper; heat lost while the tank waits for the shower makes it dearer.
+// Without readyBy the user's next shower from their schedule templates is planned for, starting
+// no earlier than it was snoozed to. Sent with the X-Correlation-ID of the prediction it heats
+// for, the schedule is recorded in that prediction's trace.
+func (h *PriceHandler) RecommendHeatingSchedule(c *gin.Context) {
+	var req struct {
+		UserID      string     `json:"userId"`
+		DeviceID    string     `json:"deviceId"`
+		HeatingTime float64    `json:"heatingTime" binding:"required,gt=0"`
+		ReadyBy     time.Time  `json:"readyBy"`
+		Earliest    *time.Time `json:"earliest"` // defaults to now
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	earliest := h.scheduler.Now()
+	if req.Earliest != nil {
+		earliest = *req.Earliest
+	}
+	var shower *services.ScheduledShower
+	if req.ReadyBy.IsZero() {
+		if req.UserID == "" {
+			validationError(c, "readyBy", "required", "Ready-by time is required without a user's schedule")
+			return
+		}
+		next, err := h.templates.NextShower(req.UserID, earliest)
+		if err != nil {
+			scheduleTemplateError(c, err, "Failed to retrieve schedule templates")
+			return
+		}
+		shower = next
+		req.ReadyBy = next.ReadyBy
+		if req.DeviceID == "" {
+			req.DeviceID = next.DeviceID
+		}
+		if next.SnoozedUntil != nil && next.SnoozedUntil.After(earliest) {
+			earliest = *next.SnoozedUntil
+		}
+	}
+	if !req.ReadyBy.After(earliest) {
+		validationError(c, "readyBy", "gtfield", "Ready-by time must be after the earliest start")
+		return
+	}
+
+	scheduleReq := services.ScheduleRequest{
+		UserID:   req.UserID,
+		DeviceID: req.DeviceID,
+		Minutes:  req.HeatingTime,
+		Earliest: earliest,
+		ReadyBy:  req.ReadyBy,
+	}
+	schedule, err := h.scheduler.Recommend(c.Request.Context(), scheduleReq)
+	if err != nil {
+		priceError(c, err)
+		return
+	}
+	// Only schedules for a prediction are traced, not every ID handed out
+	if id := correlationID(c); id == c.GetHeader(CorrelationHeader) {
+		h.correlations.RecordSchedule(id, scheduleReq, schedule)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"schedule":      schedule,
+		"shower":        shower,
+		"correlationId": correlationID(c),
+	})
+}
+
+// priceError writes the response for prices that couldn't be loaded
+func priceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPricesDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Energy prices are not configured")})
+	case errors.Is(err, services.ErrNoScheduleData):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Neither energy prices nor a solar forecast are available")})
+	case errors.Is(err, services.ErrNoPrices):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "No energy prices are available for that time")})
+	case errors.Is(err, services.ErrHeatingCurtailed):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Demand-response events leave no time to heat before then")})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": t(c, "Failed to load energy prices") + ": " + err.Error()})
+	}
+}
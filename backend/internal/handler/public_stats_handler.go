@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicStatsHandler handles HTTP requests for the statistics anyone may see
+type PublicStatsHandler struct {
+	service *services.PublicStatsService
+	maxAge  int // seconds browsers and proxies may cache the figures
+}
+
+// NewPublicStatsHandler creates a new public statistics handler instance
+func NewPublicStatsHandler(service *services.PublicStatsService, maxAge int) *PublicStatsHandler {
+	return &PublicStatsHandler{service: service, maxAge: maxAge}
+}
+
+// GetPublicStats handles GET /api/public/stats, the instance's anonymized totals for embedding
+// on a homepage. It needs no login, so only figures drawing on enough users are included.
+func (h *PublicStatsHandler) GetPublicStats(c *gin.Context) {
+	stats, err := h.service.Stats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to compute public statistics") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", h.maxAge))
+	c.JSON(http.StatusOK, stats)
+}
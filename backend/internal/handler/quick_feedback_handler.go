@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuickFeedbackHandler handles one-tap feedback on a served prediction
+type QuickFeedbackHandler struct {
+	predictionLog *services.PredictionLogService
+	targets       services.SatisfactionTargetLookup
+	records       *RecordHandler // stores the feedback the way submitted feedback is stored
+}
+
+// NewQuickFeedbackHandler creates a new quick feedback handler instance
+func NewQuickFeedbackHandler(predictionLog *services.PredictionLogService, targets services.SatisfactionTargetLookup, records *RecordHandler) *QuickFeedbackHandler {
+	return &QuickFeedbackHandler{
+		predictionLog: predictionLog,
+		targets:       targets,
+		records:       records,
+	}
+}
+
+// SubmitQuickFeedback handles POST /api/feedback/quick. Instead of a full record the client
+// sends the predictionId /api/calculate returned and a verdict of perfect, too_cold or too_hot;
+// the server fills in the shower from the logged prediction and maps the verdict to a rating.
+// Each prediction takes one verdict: re-sending it is a duplicate, a different one a conflict.
+func (h *QuickFeedbackHandler) SubmitQuickFeedback(c *gin.Context) {
+	var req struct {
+		UserID       string `json:"userId" binding:"required"`
+		PredictionID string `json:"predictionId" binding:"required"`
+		Verdict      string `json:"verdict" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if h.records.rejectDisabledUser(c, req.UserID) {
+		return
+	}
+
+	prediction, err := h.predictionLog.Get(req.UserID, req.PredictionID)
+	if errors.Is(err, services.ErrPredictionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Prediction not found")})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to retrieve prediction") + ": " + err.Error()})
+		return
+	}
+
+	record, err := services.QuickFeedbackRecord(*prediction, req.Verdict, h.targets.Target(req.UserID))
+	if err != nil {
+		validationError(c, "verdict", "oneof", "Verdict must be perfect, too_cold or too_hot")
+		return
+	}
+
+	// The prediction is claimed first so the record isn't linked to another one once stored
+	if err := h.predictionLog.Claim(prediction.ID, record.ID); err != nil {
+		if errors.Is(err, services.ErrPredictionAnswered) {
+			c.JSON(http.StatusConflict, gin.H{"error": t(c, "That prediction already has feedback")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to save feedback") + ": " + err.Error()})
+		return
+	}
+	err = h.records.recordService.CreateRecord(record)
+	switch {
+	case err == nil, errors.Is(err, services.ErrDuplicateRecord), errors.Is(err, services.ErrWriteBuffered):
+	case errors.Is(err, services.ErrRecordIDConflict):
+		// An earlier verdict on the same prediction was stored
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "That prediction already has feedback")})
+		return
+	default:
+		h.predictionLog.Release(prediction.ID, record.ID)
+	}
+	if err != nil {
+		feedbackSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Feedback saved successfully"),
+		"record":  record,
+	})
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
-	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,196 +13,894 @@ import (
 	"heat-logger/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
 )
 
 // // RecordHandler handles HTTP requests for daily records
 type RecordHandler struct {
 	recordService *services.RecordService
+	userService   *services.UserService
+	presetService *services.PresetService
+	memberService *services.MemberService
 	predictor     services.Predictor
+	temperatures  *services.TemperatureService
+	showerPlans   *services.ShowerPlanService
+	boilers       *services.BoilerService
+	predictionLog *services.PredictionLogService
 }
 
 // NewRecordHandler creates a new record handler instance
-func NewRecordHandler(recordService *services.RecordService, predictor services.Predictor) *RecordHandler {
+func NewRecordHandler(recordService *services.RecordService, userService *services.UserService, presetService *services.PresetService, memberService *services.MemberService, predictor services.Predictor, temperatures *services.TemperatureService, showerPlans *services.ShowerPlanService, boilers *services.BoilerService, predictionLog *services.PredictionLogService) *RecordHandler {
 	return &RecordHandler{
 		recordService: recordService,
+		userService:   userService,
+		presetService: presetService,
+		memberService: memberService,
 		predictor:     predictor,
+		temperatures:  temperatures,
+		showerPlans:   showerPlans,
+		boilers:       boilers,
+		predictionLog: predictionLog,
 	}
 }
 
+// loadPreset fetches the user's preset, writing the error response and returning nil on failure
+func (h *RecordHandler) loadPreset(c *gin.Context, userID, presetID string) *models.Preset {
+	preset, err := h.presetService.GetPreset(userID, presetID)
+	if err != nil {
+		if errors.Is(err, services.ErrPresetNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": t(c, "Preset not found"),
+			})
+			return nil
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load preset") + ": " + err.Error(),
+		})
+		return nil
+	}
+	return preset
+}
+
+// rejectUnknownMember writes an error and returns true when memberID is set but isn't one of the user's members
+func (h *RecordHandler) rejectUnknownMember(c *gin.Context, userID, memberID string) bool {
+	if memberID == "" {
+		return false
+	}
+	if _, err := h.memberService.GetMember(userID, memberID); err != nil {
+		if errors.Is(err, services.ErrMemberNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": t(c, "Member not found"),
+			})
+			return true
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load member") + ": " + err.Error(),
+		})
+		return true
+	}
+	return false
+}
+
+// rejectDisabledUser writes a 403 and returns true when the user's account is disabled
+func (h *RecordHandler) rejectDisabledUser(c *gin.Context, userID string) bool {
+	disabled, err := h.userService.IsDisabled(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to check account status") + ": " + err.Error(),
+		})
+		return true
+	}
+	if disabled {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": t(c, "This account has been disabled"),
+		})
+		return true
+	}
+	return false
+}
+
+// feedbackRangeError returns the first out-of-range measurement of a feedback record, untranslated
+func feedbackRangeError(record *models.DailyRecord) *FieldError {
+	switch {
+	case record.ShowerDuration <= 0:
+		return &FieldError{Field: "showerDuration", Rule: "min", Message: "Shower duration must be greater than 0"}
+	case record.HeatingTime <= 0:
+		return &FieldError{Field: "heatingTime", Rule: "min", Message: "Heating time must be greater than 0"}
+	case record.Satisfaction < models.SatisfactionMin || record.Satisfaction > models.SatisfactionMax:
+		return &FieldError{Field: "satisfaction", Rule: "range", Message: "Satisfaction rating must be between 1 and 100"}
+	case record.InletTemperature != nil && !validInletTemperature(*record.InletTemperature):
+		return &FieldError{Field: "inletTemperature", Rule: "range", Message: "Inlet temperature must be between 0 and 40 degrees Celsius"}
+	}
+	return nil
+}
+
 // CalculateHeatingTime handles POST /api/calculate
 func (h *RecordHandler) CalculateHeatingTime(c *gin.Context) {
-	var req services.PredictionRequest
+	var body struct {
+		UserID      string   `json:"userId"`
+		PresetID    string   `json:"presetId"`
+		MemberID    string   `json:"memberId"`
+		DeviceID    string   `json:"deviceId"`
+		Duration    *float64 `json:"duration"`
+		Temperature *float64 `json:"temperature"`
+		Inlet       *float64 `json:"inletTemperature"`
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data: " + err.Error(),
-		})
+		AllowLargeAdjustment bool `json:"allowLargeAdjustment"` // one-shot bypass of the step cap
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	// Validate UserID
+	if body.UserID == "" {
+		validationError(c, "userId", "required", "UserID is required")
 		return
 	}
 
+	// Fill the context from the preset where the client didn't supply it
+	if body.PresetID != "" {
+		preset := h.loadPreset(c, body.UserID, body.PresetID)
+		if preset == nil {
+			return
+		}
+		if body.Duration == nil {
+			body.Duration = &preset.Duration
+		}
+		if body.Temperature == nil {
+			body.Temperature = preset.Temperature
+		}
+	}
+
+	if body.Duration == nil {
+		validationError(c, "duration", "required", "Shower duration is required")
+		return
+	}
+
+	// The user's temperature sources decide whether the typed value or a sensor reading is used
+	temperature, err := h.temperatures.Resolve(c.Request.Context(), services.TemperatureQuery{
+		UserID: body.UserID,
+		Manual: body.Temperature,
+	})
+	if errors.Is(err, services.ErrNoTemperature) {
+		validationError(c, "temperature", "required", "Temperature is required")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to determine temperature") + ": " + err.Error()})
+		return
+	}
+
+	req := services.PredictionRequest{
+		UserID:      body.UserID,
+		Duration:    *body.Duration,
+		Temperature: temperature.Value,
+		MemberID:    body.MemberID,
+		DeviceID:    body.DeviceID,
+
+		InletTemperature: body.Inlet,
+
+		AllowLargeAdjustment: body.AllowLargeAdjustment,
+	}
+
 	// Validate input ranges
-	if req.Duration < 1 || req.Duration > 60 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Shower duration must be between 1 and 60 minutes",
-		})
+	if !validShowerDuration(c, req.Duration) {
+		durationRangeError(c, "duration")
 		return
 	}
 
-	if req.Temperature < -50 || req.Temperature > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Temperature must be between -50 and 50 degrees Celsius",
-		})
+	if !validTemperature(c, req.Temperature) {
+		temperatureRangeError(c, "temperature")
 		return
 	}
 
-	// Validate UserID
-	if req.UserID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "UserID is required",
-		})
+	if req.InletTemperature != nil && !validInletTemperature(*req.InletTemperature) {
+		validationError(c, "inletTemperature", "range", "Inlet temperature must be between 0 and 40 degrees Celsius")
+		return
+	}
+
+	if h.rejectDisabledUser(c, req.UserID) || h.rejectUnknownMember(c, req.UserID, req.MemberID) {
 		return
 	}
 
 	// Get prediction
-	prediction, err := h.predictor.Predict(req)
+	started := time.Now()
+	prediction, err := h.predictor.Predict(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate heating time: " + err.Error()})
+		predictionError(c, err)
 		return
 	}
+	elapsed := time.Since(started)
+
+	// Copy before annotating: cached predictions are shared. The explanation and the meta block
+	// are opt-in to keep the default response small.
+	response := *prediction
+	if c.Query("verbose") == "true" {
+		response.Meta = services.NewPredictionMeta(prediction, h.predictionLog.VersionFor(req.UserID), elapsed)
+	}
+	response.Temperature = temperature
+	response.CorrelationID = correlationID(c)
+	if enough, maxDuration := h.boilers.Tank(req.UserID, req.DeviceID).Enough(req.Duration); !enough {
+		response.Warning = t(c, "One tank of hot water won't last this long")
+		response.MaxDuration = &maxDuration
+	}
+	if c.Query("explain") != "true" {
+		response.Explanation = nil
+	}
+	response.HeatingSeconds = int(math.Round(response.HeatingTime * 60))
+	if target, ok := h.boilers.TargetTemperature(req, response.HeatingTime); ok {
+		response.TargetTemperature = &target
+	}
+	prediction = &response
+	if prediction.Shed {
+		c.Header("Warning", `199 heat-logger "Server busy; prediction served from cache or default"`)
+	}
+	h.predictionLog.Record(req, prediction)
+	if req.AllowLargeAdjustment {
+		log.Printf("Step cap bypassed for user %s: predicted %.1f minutes", req.UserID, prediction.HeatingTime)
+	}
 
 	c.JSON(http.StatusOK, prediction)
 }
 
-// SubmitFeedback handles POST /api/feedback
-func (h *RecordHandler) SubmitFeedback(c *gin.Context) {
-	var record models.DailyRecord
+// CalculateShowerSequence handles POST /api/calculate/sequence
+func (h *RecordHandler) CalculateShowerSequence(c *gin.Context) {
+	var body struct {
+		UserID      string                    `json:"userId"`
+		DeviceID    string                    `json:"deviceId"`
+		Temperature *float64                  `json:"temperature"`
+		Inlet       *float64                  `json:"inletTemperature"`
+		Showers     []services.SequenceShower `json:"showers"`
+	}
 
-	if err := c.ShouldBindJSON(&record); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data: " + err.Error(),
-		})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		bindError(c, err)
 		return
 	}
 
-	// Validate required fields
-	if record.UserID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "UserID is required",
-		})
+	if body.UserID == "" {
+		validationError(c, "userId", "required", "UserID is required")
 		return
 	}
 
-	if record.ShowerDuration <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Shower duration must be greater than 0",
-		})
+	if len(body.Showers) == 0 || len(body.Showers) > maxSequenceShowers {
+		validationError(c, "showers", "range", "A sequence must have between 1 and 10 showers")
 		return
 	}
+	for i, shower := range body.Showers {
+		if !validShowerDuration(c, shower.Duration) {
+			durationRangeError(c, fmt.Sprintf("showers[%d].duration", i))
+			return
+		}
+	}
 
-	if record.HeatingTime <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Heating time must be greater than 0",
-		})
+	if body.Inlet != nil && !validInletTemperature(*body.Inlet) {
+		validationError(c, "inletTemperature", "range", "Inlet temperature must be between 0 and 40 degrees Celsius")
 		return
 	}
 
-	if record.Satisfaction < 1 || record.Satisfaction > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Satisfaction rating must be between 1 and 100",
-		})
+	temperature, err := h.temperatures.Resolve(c.Request.Context(), services.TemperatureQuery{
+		UserID: body.UserID,
+		Manual: body.Temperature,
+	})
+	if errors.Is(err, services.ErrNoTemperature) {
+		validationError(c, "temperature", "required", "Temperature is required")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to determine temperature") + ": " + err.Error()})
+		return
+	}
+	if !validTemperature(c, temperature.Value) {
+		temperatureRangeError(c, "temperature")
+		return
+	}
+
+	if h.rejectDisabledUser(c, body.UserID) {
+		return
+	}
+	for _, shower := range body.Showers {
+		if h.rejectUnknownMember(c, body.UserID, shower.MemberID) {
+			return
+		}
+	}
+
+	plan, err := h.showerPlans.Plan(c.Request.Context(), services.SequenceRequest{
+		UserID:           body.UserID,
+		Temperature:      temperature.Value,
+		DeviceID:         body.DeviceID,
+		Showers:          body.Showers,
+		Tank:             h.boilers.Tank(body.UserID, body.DeviceID),
+		InletTemperature: body.Inlet,
+	})
+	if err != nil {
+		predictionError(c, err)
 		return
 	}
 
-	// Set date if not provided
+	c.JSON(http.StatusOK, gin.H{
+		"plan":        plan,
+		"temperature": temperature,
+	})
+}
+
+// predictionError writes the response for a failed prediction
+func predictionError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPredictionTimeout) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": t(c, "Prediction took too long, please try again")})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to calculate heating time") + ": " + err.Error()})
+}
+
+// SubmitFeedback handles POST /api/feedback
+func (h *RecordHandler) SubmitFeedback(c *gin.Context) {
+	var record models.DailyRecord
+
+	temperatureSent, err := bindFeedback(c, &record)
+	if err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if !h.prepareFeedback(c, &record, temperatureSent) {
+		return
+	}
+
+	// Set date if not provided. A retry keeps the date the first attempt was stored with.
+	if record.Date.IsZero() && record.ID != "" {
+		if existing, err := h.recordService.GetRecordByID(record.ID); err == nil {
+			record.Date = existing.Date
+		}
+	}
 	if record.Date.IsZero() {
 		record.Date = time.Now()
 	}
 
+	if h.recordService.OneRecordPerDay() {
+		h.upsertFeedback(c, &record, record.Date.Local())
+		return
+	}
+
 	// Create record
-	err := h.recordService.CreateRecord(&record)
+	if err := h.recordService.CreateRecord(&record); err != nil {
+		feedbackSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Feedback saved successfully"),
+	})
+}
+
+// UpsertDayRecord handles PUT /api/records/:date, which stores the feedback as the user's only
+// record for that day and device: re-submitting the same evening edits the record rather than
+// adding another
+func (h *RecordHandler) UpsertDayRecord(c *gin.Context) {
+	day, err := time.ParseInLocation("2006-01-02", c.Param("date"), time.Local)
 	if err != nil {
+		validationError(c, "date", "format", "Date must be in YYYY-MM-DD format")
+		return
+	}
+
+	var record models.DailyRecord
+	temperatureSent, err := bindFeedback(c, &record)
+	if err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if !h.prepareFeedback(c, &record, temperatureSent) {
+		return
+	}
+	if !record.Date.IsZero() && record.Date.Local().Format("2006-01-02") != c.Param("date") {
+		validationError(c, "date", "range", "Date must fall on the day in the URL")
+		return
+	}
+
+	h.upsertFeedback(c, &record, day)
+}
+
+// bindFeedback binds submitted feedback, reporting whether it carried an average temperature:
+// 0 °C is a real reading, so only a missing one may be taken from a preset
+func bindFeedback(c *gin.Context, record *models.DailyRecord) (temperatureSent bool, err error) {
+	if err := c.ShouldBindBodyWith(record, binding.JSON); err != nil {
+		return false, err
+	}
+	var sent struct {
+		AverageTemperature *float64 `json:"averageTemperature"`
+	}
+	if err := c.ShouldBindBodyWith(&sent, binding.JSON); err != nil {
+		return false, err
+	}
+	return sent.AverageTemperature != nil, nil
+}
+
+// prepareFeedback validates submitted feedback and fills it from its preset, writing the error
+// response and returning false when it is rejected
+func (h *RecordHandler) prepareFeedback(c *gin.Context, record *models.DailyRecord, temperatureSent bool) bool {
+	// Validate required fields
+	if record.UserID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return false
+	}
+
+	if record.Source != "" && !models.IsValidRecordSource(record.Source) {
+		validationError(c, "source", "oneof", tf(c, "Unknown record source %q", record.Source))
+		return false
+	}
+	// The server decides whether a record needs review
+	record.ReviewStatus = ""
+	// Feedback sent with the X-Correlation-ID of its prediction answers that prediction
+	record.CorrelationID = ""
+	if id := correlationID(c); id == c.GetHeader(CorrelationHeader) {
+		record.CorrelationID = id
+	}
+	if record.TemperatureSource != "" && !models.IsValidTemperatureSource(record.TemperatureSource) {
+		validationError(c, "temperatureSource", "oneof", tf(c, "Unknown temperature source %q", record.TemperatureSource))
+		return false
+	}
+
+	// Clients may name the record themselves so a retried request can't save it twice
+	if record.ID != "" {
+		id, err := uuid.Parse(record.ID)
+		if err != nil {
+			validationError(c, "id", "uuid", t(c, "Record ID must be a client-generated UUID"))
+			return false
+		}
+		record.ID = id.String()
+	}
+
+	if h.rejectDisabledUser(c, record.UserID) || h.rejectUnknownMember(c, record.UserID, record.MemberID) {
+		return false
+	}
+
+	// Feedback against a preset takes its context from the preset
+	if record.PresetID != "" {
+		preset := h.loadPreset(c, record.UserID, record.PresetID)
+		if preset == nil {
+			return false
+		}
+		if record.ShowerDuration <= 0 {
+			record.ShowerDuration = preset.Duration
+		}
+		if !temperatureSent && preset.Temperature != nil {
+			record.AverageTemperature = *preset.Temperature
+		}
+	}
+
+	if problem := feedbackRangeError(record); problem != nil {
+		validationError(c, problem.Field, problem.Rule, problem.Message)
+		return false
+	}
+	return true
+}
+
+// upsertFeedback stores the feedback as the user's record for the day and device
+func (h *RecordHandler) upsertFeedback(c *gin.Context, record *models.DailyRecord, day time.Time) {
+	created, err := h.recordService.UpsertDayRecord(record, day)
+	if errors.Is(err, services.ErrSeveralRecordsOnDay) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": t(c, "That day already has several records"),
+		})
+		return
+	}
+	if err != nil {
+		feedbackSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"updated": !created,
+		"message": t(c, "Feedback saved successfully"),
+		"record":  record,
+	})
+}
+
+// feedbackSaveError writes the response for feedback that couldn't be stored as sent
+func feedbackSaveError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDuplicateRecord):
+		c.JSON(http.StatusOK, gin.H{
+			"success":   true,
+			"duplicate": true,
+			"message":   t(c, "Feedback already saved"),
+		})
+	case errors.Is(err, services.ErrRecordIDConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": t(c, "Record ID is already used by a different record"),
+		})
+	case errors.Is(err, services.ErrWriteBuffered):
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"queued":  true,
+			"message": t(c, "Feedback queued and will be saved when storage recovers"),
+		})
+	case errors.Is(err, services.ErrDatabaseReadOnly):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": t(c, "Storage is temporarily unavailable, please try again later"),
+		})
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save feedback: " + err.Error(),
+			"error": t(c, "Failed to save feedback") + ": " + err.Error(),
 		})
+	}
+}
+
+// SubmitFeedbackBatch handles POST /api/feedback/batch, for clients catching up on feedback
+// recorded while they were offline. Each record is validated on its own; the valid ones are
+// stored in one transaction and every record gets a result in the order it was sent.
+func (h *RecordHandler) SubmitFeedbackBatch(c *gin.Context) {
+	var body struct {
+		UserID  string               `json:"userId" binding:"required"`
+		Records []models.DailyRecord `json:"records" binding:"required,min=1,max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		bindError(c, err)
 		return
 	}
 
+	if h.rejectDisabledUser(c, body.UserID) {
+		return
+	}
+
+	results := make([]feedbackBatchResult, len(body.Records))
+	var valid []*models.DailyRecord
+	var validIndex []int
+	for i := range body.Records {
+		record := &body.Records[i]
+		results[i] = feedbackBatchResult{Index: i, syncUploadResult: syncUploadResult{ID: record.ID, Status: syncRejected}}
+		if results[i].Error = h.checkUploadedRecord(c, body.UserID, record); results[i].Error != "" {
+			continue
+		}
+		valid = append(valid, record)
+		validIndex = append(validIndex, i)
+	}
+
+	for n, err := range h.recordService.CreateRecords(valid) {
+		i := validIndex[n]
+		results[i].ID = body.Records[i].ID
+		results[i].Status, results[i].Error = uploadOutcome(c, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Feedback saved successfully",
+		"results": results,
 	})
 }
 
-// GetHistory handles GET /api/history
+// feedbackBatchResult reports what happened to one record of a feedback batch
+type feedbackBatchResult struct {
+	Index int `json:"index"`
+	syncUploadResult
+}
+
+// GetHistory handles GET /api/history?source=
 func (h *RecordHandler) GetHistory(c *gin.Context) {
-	records, err := h.recordService.GetAllRecords()
+	sources, err := parseSourceQuery(c)
+	if err != nil {
+		validationError(c, "source", "oneof", err.Error())
+		return
+	}
+
+	records, err := h.recordService.GetRecordsBySources(sources)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve history") + ": " + err.Error(),
+		})
+		return
+	}
+	total, err := h.recordService.Count(services.RecordQuery{Sources: sources})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve history: " + err.Error(),
+			"error": t(c, "Failed to retrieve history") + ": " + err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"history": records,
+		"total":   total,
+	})
+}
+
+// maxHistoryChanges is the most changes one page of the change feed holds
+const maxHistoryChanges = 500
+
+// GetHistoryChanges handles GET /api/history/changes?userId=&since=&limit=, the incremental
+// history sync for the PWA. since is the cursor from the previous call or an RFC 3339 time;
+// without it the whole history is returned as created. Records are listed as created when they
+// were stored after since and as updated otherwise, with their content as it is now.
+func (h *RecordHandler) GetHistoryChanges(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	var cursor services.SyncCursor
+	if since := c.Query("since"); since != "" {
+		if at, err := time.Parse(time.RFC3339, since); err == nil {
+			cursor = services.SyncCursor{UpdatedAt: at}
+		} else if cursor, err = services.DecodeSyncCursor(since); err != nil {
+			validationError(c, "since", "format", "Since must be a cursor from a previous sync or an RFC 3339 time")
+			return
+		}
+	}
+
+	limit := maxHistoryChanges
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxHistoryChanges {
+			validationError(c, "limit", "range", "Limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+
+	changes, err := h.recordService.GetHistoryChanges(userID, cursor, limit)
+	if errors.Is(err, services.ErrSyncCursorExpired) {
+		syncCursorExpired(c, "since")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve changes") + ": " + err.Error(),
+		})
+		return
+	}
+
+	created, updated := []models.DailyRecord{}, []models.DailyRecord{}
+	for _, record := range changes.Records {
+		if record.CreatedAt.Before(cursor.UpdatedAt) {
+			updated = append(updated, record)
+		} else {
+			created = append(created, record)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"created": created,
+		"updated": updated,
+		"deleted": changes.Deleted,
+		"cursor":  changes.Next.Encode(),
+		"hasMore": changes.HasMore,
 	})
 }
 
 // DeleteRecord handles POST /api/history/delete
 func (h *RecordHandler) DeleteRecord(c *gin.Context) {
 	var req struct {
-		ID string `json:"id" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+		UserID string `json:"userId"` // who is deleting it, for the tombstone; the owner by default
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data: " + err.Error(),
-		})
+		bindError(c, err)
 		return
 	}
 
-	err := h.recordService.DeleteRecord(req.ID)
+	err := h.recordService.DeleteRecord(req.ID, req.UserID)
 	if err != nil {
-		if err.Error() == "record not found" {
+		if errors.Is(err, services.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Record not found",
+				"error": t(c, "Record not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete record: " + err.Error(),
+			"error": t(c, "Failed to delete record") + ": " + err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Record deleted successfully",
+		"message": t(c, "Record deleted successfully"),
 	})
 }
 
-// DeleteAllRecords handles POST /api/history/deleteall
+// GetRecordRevisions handles GET /api/history/:id/revisions?userId=
+func (h *RecordHandler) GetRecordRevisions(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	revisions, err := h.recordService.ListRevisions(userID, c.Param("id"))
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+	})
+}
+
+// RevertRecord handles POST /api/history/:id/revert
+func (h *RecordHandler) RevertRecord(c *gin.Context) {
+	var req struct {
+		UserID     string `json:"userId" binding:"required"`
+		RevisionID string `json:"revisionId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if h.rejectDisabledUser(c, req.UserID) {
+		return
+	}
+
+	record, err := h.recordService.RevertRecord(req.UserID, c.Param("id"), req.RevisionID, req.UserID)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"record":  record,
+	})
+}
+
+// revisionError writes the response for a failed revision lookup or revert
+func revisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Record not found")})
+	case errors.Is(err, services.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Revision not found")})
+	case errors.Is(err, services.ErrDatabaseReadOnly):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": t(c, "Storage is temporarily unavailable, please try again later")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to load record revisions") + ": " + err.Error()})
+	}
+}
+
+// GetPendingRecords handles GET /api/history/pending?userId=, listing automatically created
+// records the user still has to confirm or correct
+func (h *RecordHandler) GetPendingRecords(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	records, err := h.recordService.GetPendingRecords(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve history") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+	})
+}
+
+// ReviewRecord handles POST /api/history/:id/review. Values left out of the body are confirmed
+// as recorded; the ones sent replace what the sensor or import guessed.
+func (h *RecordHandler) ReviewRecord(c *gin.Context) {
+	var req struct {
+		UserID             string   `json:"userId" binding:"required"`
+		ShowerDuration     *float64 `json:"showerDuration"`
+		AverageTemperature *float64 `json:"averageTemperature"`
+		InletTemperature   *float64 `json:"inletTemperature"`
+		HeatingTime        *float64 `json:"heatingTime"`
+		Satisfaction       *float64 `json:"satisfaction"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if h.rejectDisabledUser(c, req.UserID) {
+		return
+	}
+
+	record, err := h.recordService.GetRecordByID(c.Param("id"))
+	if err != nil || record.UserID != req.UserID {
+		reviewError(c, services.ErrRecordNotFound)
+		return
+	}
+	if req.ShowerDuration != nil {
+		record.ShowerDuration = *req.ShowerDuration
+	}
+	if req.AverageTemperature != nil {
+		record.AverageTemperature = *req.AverageTemperature
+	}
+	if req.InletTemperature != nil {
+		record.InletTemperature = req.InletTemperature
+	}
+	if req.HeatingTime != nil {
+		record.HeatingTime = *req.HeatingTime
+	}
+	if req.Satisfaction != nil {
+		record.Satisfaction = *req.Satisfaction
+	}
+	if problem := feedbackRangeError(record); problem != nil {
+		validationError(c, problem.Field, problem.Rule, problem.Message)
+		return
+	}
+
+	if err := h.recordService.ReviewRecord(req.UserID, record, req.UserID); err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"record":  record,
+	})
+}
+
+// reviewError writes the response for a review that couldn't be stored
+func reviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Record not found")})
+	case errors.Is(err, services.ErrRecordNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Record is not waiting for review")})
+	case errors.Is(err, services.ErrDatabaseReadOnly):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": t(c, "Storage is temporarily unavailable, please try again later")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to review record") + ": " + err.Error()})
+	}
+}
+
+// DeleteAllRecords handles POST /api/history/deleteall?dryRun=
 func (h *RecordHandler) DeleteAllRecords(c *gin.Context) {
+	if isDryRun(c) {
+		preview, err := h.recordService.PreviewDeleteAllRecords()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": t(c, "Failed to delete all records") + ": " + err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"dryRun": true, "counts": preview.Counts, "sampleIds": preview.SampleIDs})
+		return
+	}
+
 	err := h.recordService.DeleteAllRecords()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete all records: " + err.Error(),
+			"error": t(c, "Failed to delete all records") + ": " + err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "All records deleted successfully",
+		"message": t(c, "All records deleted successfully"),
 	})
 }
 
-// ExportHistory handles GET /api/history/export
+// ExportHistory handles GET /api/history/export?source=&locale=&timezone=
 func (h *RecordHandler) ExportHistory(c *gin.Context) {
-	records, err := h.recordService.GetAllRecords()
+	sources, err := parseSourceQuery(c)
+	if err != nil {
+		validationError(c, "source", "oneof", err.Error())
+		return
+	}
+	format, ok := exportFormat(c, c.Query("locale"), c.Query("timezone"))
+	if !ok {
+		return
+	}
+
+	records, err := h.recordService.GetRecordsBySources(sources)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve history: " + err.Error(),
+			"error": t(c, "Failed to retrieve history") + ": " + err.Error(),
 		})
 		return
 	}
@@ -209,34 +910,172 @@ func (h *RecordHandler) ExportHistory(c *gin.Context) {
 	c.Header("Content-Type", "text/csv")
 	c.Header("Content-Disposition", "attachment; filename="+filename)
 
-	// Create CSV writer
-	writer := csv.NewWriter(c.Writer)
-	defer writer.Flush()
+	if err := services.WriteRecordsCSV(c.Writer, records, format); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to write CSV data"),
+		})
+		return
+	}
+}
+
+// Outcomes of a record uploaded through /api/sync or /api/feedback/batch
+const (
+	syncCreated   = "created"   // stored as sent
+	syncDuplicate = "duplicate" // already stored with the same content, e.g. a retried upload
+	syncConflict  = "conflict"  // the ID is taken by a different record; the server copy wins
+	syncQueued    = "queued"    // accepted while storage is read-only, saved when it recovers
+	syncRejected  = "rejected"  // invalid, see error
+)
+
+// syncUploadResult reports what happened to one uploaded record
+type syncUploadResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Sync handles POST /api/sync, the delta sync used by offline-first clients. The client sends
+// the cursor from its previous sync and the records it created offline under its own UUIDs;
+// the server stores them and returns the user's records changed since the cursor.
+//
+// Sync never edits a stored record, so conflicts resolve the same way every time: an ID the
+// server already has keeps the server's copy, which the client receives in the changes. Records
+// edited through a day upsert reappear in the changes with their new content.
+func (h *RecordHandler) Sync(c *gin.Context) {
+	var body struct {
+		UserID  string               `json:"userId" binding:"required"`
+		Cursor  string               `json:"cursor"`
+		Records []models.DailyRecord `json:"records" binding:"max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	cursor, err := services.DecodeSyncCursor(body.Cursor)
+	if err != nil {
+		validationError(c, "cursor", "format", "Sync cursor is invalid, sync again without one")
+		return
+	}
+	if errors.Is(h.recordService.CheckSyncCursor(cursor), services.ErrSyncCursorExpired) {
+		syncCursorExpired(c, "cursor")
+		return
+	}
+
+	if h.rejectDisabledUser(c, body.UserID) {
+		return
+	}
+
+	results := make([]syncUploadResult, 0, len(body.Records))
+	for i := range body.Records {
+		results = append(results, h.storeSyncedRecord(c, body.UserID, &body.Records[i]))
+	}
 
-	// Write header
-	header := []string{"User ID", "Date", "Shower Duration", "Average Temperature", "Heating Time", "Satisfaction"}
-	if err := writer.Write(header); err != nil {
+	changes, err := h.recordService.GetHistoryChanges(body.UserID, cursor, maxHistoryChanges)
+	if errors.Is(err, services.ErrSyncCursorExpired) {
+		syncCursorExpired(c, "cursor")
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to write CSV header",
+			"error": t(c, "Failed to retrieve changes") + ": " + err.Error(),
 		})
 		return
 	}
 
-	// Write data rows
-	for _, record := range records {
-		row := []string{
-			record.UserID,
-			record.Date.Format("2006-01-02 15:04:05"),
-			strconv.FormatFloat(record.ShowerDuration, 'f', 1, 64),
-			strconv.FormatFloat(record.AverageTemperature, 'f', 1, 64),
-			strconv.FormatFloat(record.HeatingTime, 'f', 1, 64),
-			strconv.FormatFloat(record.Satisfaction, 'f', 1, 64),
+	if err := h.userService.MarkSynced(body.UserID, time.Now()); err != nil {
+		log.Printf("Failed to record the sync of %s: %v", body.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cursor":  changes.Next.Encode(),
+		"hasMore": changes.HasMore,
+		"changes": changes.Records,
+		"deleted": changes.Deleted,
+		"results": results,
+	})
+}
+
+// syncCursorExpired writes a 410 for a cursor older than the deletions the server remembers
+func syncCursorExpired(c *gin.Context, field string) {
+	message := t(c, "Sync cursor has expired, sync again without one")
+	c.JSON(http.StatusGone, gin.H{
+		"error":   message,
+		"details": []FieldError{{Field: field, Rule: "expired", Message: message}},
+	})
+}
+
+// storeSyncedRecord validates and stores one record uploaded by an offline client
+func (h *RecordHandler) storeSyncedRecord(c *gin.Context, userID string, record *models.DailyRecord) syncUploadResult {
+	result := syncUploadResult{ID: record.ID, Status: syncRejected}
+
+	if _, err := uuid.Parse(record.ID); err != nil {
+		result.Error = t(c, "Record ID must be a client-generated UUID")
+		return result
+	}
+	if result.Error = h.checkUploadedRecord(c, userID, record); result.Error != "" {
+		return result
+	}
+
+	result.Status, result.Error = uploadOutcome(c, h.recordService.CreateRecord(record))
+	return result
+}
+
+// checkUploadedRecord validates a record uploaded in bulk for userID and prepares it for
+// storage, returning the translated reason it was rejected or ""
+func (h *RecordHandler) checkUploadedRecord(c *gin.Context, userID string, record *models.DailyRecord) string {
+	if record.ID != "" {
+		id, err := uuid.Parse(record.ID)
+		if err != nil {
+			return t(c, "Record ID must be a client-generated UUID")
 		}
-		if err := writer.Write(row); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to write CSV data",
-			})
-			return
+		record.ID = id.String()
+	}
+	if record.UserID != "" && record.UserID != userID {
+		return t(c, "Record belongs to a different user")
+	}
+	record.UserID = userID
+	if record.Source != "" && !models.IsValidRecordSource(record.Source) {
+		return tf(c, "Unknown record source %q", record.Source)
+	}
+	if record.TemperatureSource != "" && !models.IsValidTemperatureSource(record.TemperatureSource) {
+		return tf(c, "Unknown temperature source %q", record.TemperatureSource)
+	}
+	if problem := feedbackRangeError(record); problem != nil {
+		return t(c, problem.Message)
+	}
+	if record.Date.IsZero() {
+		return t(c, "Date is required")
+	}
+	if record.MemberID != "" {
+		if _, err := h.memberService.GetMember(userID, record.MemberID); err != nil {
+			return t(c, "Member not found")
 		}
 	}
+
+	// The server owns the change feed timestamps
+	record.CreatedAt = time.Time{}
+	record.UpdatedAt = time.Time{}
+	record.ExcludedFromTraining = false
+	record.ReviewStatus = ""
+	return ""
+}
+
+// uploadOutcome maps the result of storing an uploaded record to its status and error
+func uploadOutcome(c *gin.Context, err error) (string, string) {
+	switch {
+	case err == nil:
+		return syncCreated, ""
+	case errors.Is(err, services.ErrDuplicateRecord):
+		return syncDuplicate, ""
+	case errors.Is(err, services.ErrRecordIDConflict):
+		return syncConflict, ""
+	case errors.Is(err, services.ErrWriteBuffered):
+		return syncQueued, ""
+	case errors.Is(err, services.ErrDatabaseReadOnly):
+		return syncRejected, t(c, "Storage is temporarily unavailable, please try again later")
+	default:
+		return syncRejected, t(c, "Failed to save feedback") + ": " + err.Error()
+	}
 }
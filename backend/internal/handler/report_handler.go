@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler handles HTTP requests for saved custom reports and their scheduled delivery
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+
+// NewReportHandler creates a new report handler instance
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// reportError maps report service errors to responses
+func reportError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrReportMetric):
+		validationError(c, "metrics", "oneof", "Choose 1 to 8 different metrics from GET /api/stats/reports/options")
+	case errors.Is(err, services.ErrReportGroup):
+		validationError(c, "groupBy", "oneof", "Group by at most 2 different groupings from GET /api/stats/reports/options")
+	case errors.Is(err, services.ErrReportFilter):
+		validationError(c, "filters", "range", "Each filter range must start before it ends")
+	case errors.Is(err, services.ErrReportSchedule):
+		validationError(c, "cron", "cron", "Cron must be a five-field cron expression that fires, in a known time zone")
+	case errors.Is(err, services.ErrReportChannel):
+		validationError(c, "channel", "oneof", "Channel must be email or webhook")
+	case errors.Is(err, services.ErrReportTarget):
+		validationError(c, "target", "target", "Target must be an email address for email or an http(s) URL for webhook")
+	case errors.Is(err, services.ErrReportFormat):
+		validationError(c, "format", "oneof", "Format must be csv or pdf")
+	case errors.Is(err, services.ErrReportEmailDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Email delivery is not configured on this server")})
+	case errors.Is(err, services.ErrSavedReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Saved report not found")})
+	case errors.Is(err, services.ErrSavedReportNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "A saved report with this name already exists")})
+	case errors.Is(err, services.ErrReportSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Report subscription not found")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, message) + ": " + err.Error()})
+	}
+}
+
+// SaveReport handles POST /api/stats/reports/saved
+func (h *ReportHandler) SaveReport(c *gin.Context) {
+	var req struct {
+		services.ReportQuery
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	saved, err := h.reportService.SaveReport(req.UserID, req.Name, req.ReportQuery)
+	if err != nil {
+		reportError(c, err, "Failed to save report")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"report":  saved,
+	})
+}
+
+// ListSavedReports handles GET /api/stats/reports/saved?userId=
+func (h *ReportHandler) ListSavedReports(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	reports, err := h.reportService.ListReports(userID)
+	if err != nil {
+		reportError(c, err, "Failed to retrieve saved reports")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+	})
+}
+
+// RunSavedReport handles GET /api/stats/reports/saved/:id?userId=
+func (h *ReportHandler) RunSavedReport(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	saved, report, err := h.reportService.RunReport(userID, c.Param("id"))
+	if err != nil {
+		reportError(c, err, "Failed to run report")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": saved,
+		"result": report,
+	})
+}
+
+// DeleteSavedReport handles POST /api/stats/reports/saved/delete, which also ends the report's
+// subscriptions
+func (h *ReportHandler) DeleteSavedReport(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.reportService.DeleteReport(req.UserID, req.ID); err != nil {
+		reportError(c, err, "Failed to delete saved report")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+type reportSubscriptionRequest struct {
+	ID       string `json:"id"`
+	UserID   string `json:"userId" binding:"required"`
+	ReportID string `json:"reportId"`
+	Cron     string `json:"cron" binding:"required"`
+	Timezone string `json:"timezone"`
+	Channel  string `json:"channel" binding:"required"`
+	Target   string `json:"target" binding:"required"`
+	Format   string `json:"format"` // csv unless set
+}
+
+func (r reportSubscriptionRequest) subscription() models.ReportSubscription {
+	format := r.Format
+	if format == "" {
+		format = models.ReportFormatCSV
+	}
+	return models.ReportSubscription{
+		UserID:   r.UserID,
+		ReportID: r.ReportID,
+		Cron:     r.Cron,
+		Timezone: r.Timezone,
+		Channel:  r.Channel,
+		Target:   r.Target,
+		Format:   format,
+	}
+}
+
+// CreateReportSubscription handles POST /api/report-subscriptions
+func (h *ReportHandler) CreateReportSubscription(c *gin.Context) {
+	var req reportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.ReportID == "" {
+		validationError(c, "reportId", "required", "ReportID is required")
+		return
+	}
+
+	subscription := req.subscription()
+	if err := h.reportService.CreateSubscription(&subscription, time.Now()); err != nil {
+		reportError(c, err, "Failed to create report subscription")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":      true,
+		"subscription": subscription,
+	})
+}
+
+// ListReportSubscriptions handles GET /api/report-subscriptions?userId=
+func (h *ReportHandler) ListReportSubscriptions(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	subscriptions, err := h.reportService.ListSubscriptions(userID)
+	if err != nil {
+		reportError(c, err, "Failed to retrieve report subscriptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+	})
+}
+
+// UpdateReportSubscription handles POST /api/report-subscriptions/update
+func (h *ReportHandler) UpdateReportSubscription(c *gin.Context) {
+	var req reportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.ID == "" {
+		validationError(c, "id", "required", "ID is required")
+		return
+	}
+
+	subscription, err := h.reportService.UpdateSubscription(req.UserID, req.ID, req.subscription(), time.Now())
+	if err != nil {
+		reportError(c, err, "Failed to update report subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"subscription": subscription,
+	})
+}
+
+// DeleteReportSubscription handles POST /api/report-subscriptions/delete
+func (h *ReportHandler) DeleteReportSubscription(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.reportService.DeleteSubscription(req.UserID, req.ID); err != nil {
+		reportError(c, err, "Failed to delete report subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
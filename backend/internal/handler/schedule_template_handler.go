@@ -0,0 +1,476 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+	"heat-logger/pkg/cron"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleTemplateHandler handles HTTP requests for recurring shower schedules
+type ScheduleTemplateHandler struct {
+	templateService *services.ScheduleTemplateService
+	shareService    *services.ShareService
+	conflicts       *services.ScheduleConflictService
+}
+
+// NewScheduleTemplateHandler creates a new schedule template handler instance
+func NewScheduleTemplateHandler(templateService *services.ScheduleTemplateService, shareService *services.ShareService, conflicts *services.ScheduleConflictService) *ScheduleTemplateHandler {
+	return &ScheduleTemplateHandler{
+		templateService: templateService,
+		shareService:    shareService,
+		conflicts:       conflicts,
+	}
+}
+
+// defaultSnoozeMinutes is how long an action link snoozes heating for unless told otherwise
+const defaultSnoozeMinutes = 15
+
+type scheduleTemplateRequest struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userId" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	DeviceID    string `json:"deviceId"`
+	WeekdayTime string `json:"weekdayTime"`
+	WeekendTime string `json:"weekendTime"`
+	Cron        string `json:"cron"`
+	Timezone    string `json:"timezone"`
+	// AllowConflicts saves the template even when it overlaps another on the same device
+	AllowConflicts bool `json:"allowConflicts"`
+	Exceptions     []struct {
+		Date string `json:"date" binding:"required"`
+		Time string `json:"time"`
+	} `json:"exceptions" binding:"dive"`
+}
+
+// validClock reports whether clock is empty or a HH:MM time of day
+func validClock(clock string) bool {
+	if clock == "" {
+		return true
+	}
+	_, err := time.Parse("15:04", clock)
+	return err == nil
+}
+
+// previewRuns is how many upcoming showers template responses preview
+const previewRuns = 5
+
+// validScheduleTimes checks a template's times, cron expression and time zone, writing the
+// error response on failure
+func validScheduleTimes(c *gin.Context, weekdayTime, weekendTime, expr, timezone string) bool {
+	if !validClock(weekdayTime) {
+		validationError(c, "weekdayTime", "format", "Times must be in HH:MM format")
+		return false
+	}
+	if !validClock(weekendTime) {
+		validationError(c, "weekendTime", "format", "Times must be in HH:MM format")
+		return false
+	}
+	if expr != "" {
+		if weekdayTime != "" || weekendTime != "" {
+			validationError(c, "cron", "conflict", "Use either a cron expression or weekday and weekend times")
+			return false
+		}
+		if _, err := cron.Parse(expr); err != nil {
+			validationError(c, "cron", "format", tf(c, "Invalid cron expression: %s", err.Error()))
+			return false
+		}
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			validationError(c, "timezone", "format", "Unknown time zone")
+			return false
+		}
+	}
+	return true
+}
+
+// bindScheduleTemplate binds and validates a template payload, writing the error response on
+// failure. allowConflicts reports whether the user chose to keep overlaps with other schedules.
+func bindScheduleTemplate(c *gin.Context) (template *models.ScheduleTemplate, allowConflicts bool, ok bool) {
+	var req scheduleTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return nil, false, false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 64 {
+		validationError(c, "name", "length", "Schedule name must be between 1 and 64 characters")
+		return nil, false, false
+	}
+	if !validScheduleTimes(c, req.WeekdayTime, req.WeekendTime, req.Cron, req.Timezone) {
+		return nil, false, false
+	}
+
+	template = &models.ScheduleTemplate{
+		ID:          req.ID,
+		UserID:      req.UserID,
+		Name:        name,
+		DeviceID:    req.DeviceID,
+		WeekdayTime: req.WeekdayTime,
+		WeekendTime: req.WeekendTime,
+		Cron:        req.Cron,
+		Timezone:    req.Timezone,
+		Exceptions:  []models.ScheduleException{},
+	}
+	seen := make(map[string]bool)
+	for _, exception := range req.Exceptions {
+		if _, err := time.Parse("2006-01-02", exception.Date); err != nil {
+			validationError(c, "exceptions", "format", "Date must be in YYYY-MM-DD format")
+			return nil, false, false
+		}
+		if !validClock(exception.Time) {
+			validationError(c, "exceptions", "format", "Times must be in HH:MM format")
+			return nil, false, false
+		}
+		if seen[exception.Date] {
+			validationError(c, "exceptions", "unique", tf(c, "%s has more than one exception", exception.Date))
+			return nil, false, false
+		}
+		seen[exception.Date] = true
+		template.Exceptions = append(template.Exceptions, models.ScheduleException{Date: exception.Date, Time: exception.Time})
+	}
+	return template, req.AllowConflicts, true
+}
+
+// scheduleTemplateError maps schedule template service errors to responses
+func scheduleTemplateError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrScheduleTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Schedule template not found")})
+	case errors.Is(err, services.ErrScheduleTemplateNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "A schedule template with this name already exists")})
+	case errors.Is(err, services.ErrNoScheduledShower):
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "No shower is scheduled")})
+	case errors.Is(err, services.ErrSnoozePastShower):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Snoozing that long would start heating after the shower; skip it instead")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, message) + ": " + err.Error()})
+	}
+}
+
+// checkConflicts looks for other schedules of the household that would share the template's
+// boiler at the same time. Unless the user allowed them, conflicts are answered with 409 and
+// staggered times to pick from, and false is returned.
+func (h *ScheduleTemplateHandler) checkConflicts(c *gin.Context, template *models.ScheduleTemplate, allow bool) ([]services.ScheduleConflict, bool) {
+	conflicts, err := h.conflicts.Check(c.Request.Context(), *template, time.Now())
+	if err != nil {
+		scheduleTemplateError(c, err, "Failed to check schedule conflicts")
+		return nil, false
+	}
+	if len(conflicts) > 0 && !allow {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     t(c, "The schedule overlaps another shower on the same water heater"),
+			"conflicts": conflicts,
+		})
+		return nil, false
+	}
+	return conflicts, true
+}
+
+// CreateScheduleTemplate handles POST /api/schedule-templates. A template that overlaps another
+// on the same device is refused with the conflicts unless allowConflicts is set.
+func (h *ScheduleTemplateHandler) CreateScheduleTemplate(c *gin.Context) {
+	template, allowConflicts, ok := bindScheduleTemplate(c)
+	if !ok {
+		return
+	}
+	template.ID = ""
+	conflicts, ok := h.checkConflicts(c, template, allowConflicts)
+	if !ok {
+		return
+	}
+
+	if err := h.templateService.CreateTemplate(template); err != nil {
+		scheduleTemplateError(c, err, "Failed to save schedule template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"template":  template,
+		"nextRuns":  services.Preview(*template, time.Now(), previewRuns),
+		"conflicts": conflicts,
+	})
+}
+
+// ListScheduleTemplates handles GET /api/schedule-templates?userId=
+func (h *ScheduleTemplateHandler) ListScheduleTemplates(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	templates, err := h.templateService.ListHouseholdTemplates(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve schedule templates") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+	})
+}
+
+// UpdateScheduleTemplate handles POST /api/schedule-templates/update, checking for conflicts
+// like CreateScheduleTemplate
+func (h *ScheduleTemplateHandler) UpdateScheduleTemplate(c *gin.Context) {
+	template, allowConflicts, ok := bindScheduleTemplate(c)
+	if !ok {
+		return
+	}
+	if template.ID == "" {
+		validationError(c, "id", "required", "Schedule template ID is required")
+		return
+	}
+	conflicts, ok := h.checkConflicts(c, template, allowConflicts)
+	if !ok {
+		return
+	}
+
+	if err := h.templateService.UpdateTemplate(template); err != nil {
+		scheduleTemplateError(c, err, "Failed to update schedule template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"template":  template,
+		"nextRuns":  services.Preview(*template, time.Now(), previewRuns),
+		"conflicts": conflicts,
+	})
+}
+
+// PreviewScheduleTemplate handles POST /api/schedule-templates/preview, listing when a schedule
+// would have the water ready without saving it
+func (h *ScheduleTemplateHandler) PreviewScheduleTemplate(c *gin.Context) {
+	var req struct {
+		WeekdayTime string `json:"weekdayTime"`
+		WeekendTime string `json:"weekendTime"`
+		Cron        string `json:"cron"`
+		Timezone    string `json:"timezone"`
+		Count       int    `json:"count" binding:"omitempty,min=1,max=50"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if !validScheduleTimes(c, req.WeekdayTime, req.WeekendTime, req.Cron, req.Timezone) {
+		return
+	}
+	if req.Count == 0 {
+		req.Count = previewRuns
+	}
+
+	template := models.ScheduleTemplate{WeekdayTime: req.WeekdayTime, WeekendTime: req.WeekendTime, Cron: req.Cron, Timezone: req.Timezone}
+	c.JSON(http.StatusOK, gin.H{
+		"nextRuns": services.Preview(template, time.Now(), req.Count),
+	})
+}
+
+// DeleteScheduleTemplate handles POST /api/schedule-templates/delete
+func (h *ScheduleTemplateHandler) DeleteScheduleTemplate(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		ID     string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.templateService.DeleteTemplate(req.UserID, req.ID); err != nil {
+		scheduleTemplateError(c, err, "Failed to delete schedule template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": t(c, "Schedule template deleted successfully"),
+	})
+}
+
+// GetNextScheduledShower handles GET /api/schedule-templates/next?userId=&after=
+func (h *ScheduleTemplateHandler) GetNextScheduledShower(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+	after, err := parseTimeQuery(c, "after")
+	if err != nil {
+		validationError(c, "after", "date", err.Error())
+		return
+	}
+	from := time.Now()
+	if after != nil {
+		from = *after
+	}
+
+	shower, err := h.templateService.NextShower(userID, from)
+	if err != nil {
+		scheduleTemplateError(c, err, "Failed to retrieve schedule templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shower": shower,
+	})
+}
+
+// SnoozeScheduledHeating handles POST /api/schedule/snooze, holding heating for the next
+// scheduled shower (of templateId, if given) back by minutes
+func (h *ScheduleTemplateHandler) SnoozeScheduledHeating(c *gin.Context) {
+	var req struct {
+		UserID     string `json:"userId" binding:"required"`
+		TemplateID string `json:"templateId"`
+		Minutes    int    `json:"minutes" binding:"required,min=1,max=720"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	now := time.Now()
+	shower, err := h.templateService.NextTemplateShower(req.UserID, req.TemplateID, now)
+	if err != nil {
+		scheduleTemplateError(c, err, "Failed to retrieve schedule templates")
+		return
+	}
+	if err := h.templateService.Snooze(req.UserID, shower, req.Minutes, now); err != nil {
+		scheduleTemplateError(c, err, "Failed to snooze heating")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"shower":  shower,
+	})
+}
+
+// SkipScheduledHeating handles POST /api/schedule/skip, cancelling heating for the next
+// scheduled shower (of templateId, if given)
+func (h *ScheduleTemplateHandler) SkipScheduledHeating(c *gin.Context) {
+	var req struct {
+		UserID     string `json:"userId" binding:"required"`
+		TemplateID string `json:"templateId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	shower, err := h.templateService.NextTemplateShower(req.UserID, req.TemplateID, time.Now())
+	if err != nil {
+		scheduleTemplateError(c, err, "Failed to retrieve schedule templates")
+		return
+	}
+	if err := h.templateService.Skip(req.UserID, shower); err != nil {
+		scheduleTemplateError(c, err, "Failed to skip heating")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"skipped": shower,
+	})
+}
+
+// CreateScheduleActionLinks handles POST /api/schedule/action-links, returning signed snooze and
+// skip links for the next scheduled shower to put in a notification. The links act on that
+// shower only and expire when its water is due.
+func (h *ScheduleTemplateHandler) CreateScheduleActionLinks(c *gin.Context) {
+	var req struct {
+		UserID        string `json:"userId" binding:"required"`
+		TemplateID    string `json:"templateId"`
+		SnoozeMinutes int    `json:"snoozeMinutes" binding:"omitempty,min=1,max=720"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.SnoozeMinutes == 0 {
+		req.SnoozeMinutes = defaultSnoozeMinutes
+	}
+
+	shower, err := h.templateService.NextTemplateShower(req.UserID, req.TemplateID, time.Now())
+	if err != nil {
+		scheduleTemplateError(c, err, "Failed to retrieve schedule templates")
+		return
+	}
+
+	target := shower.TemplateID + "/" + shower.Date
+	ttl := time.Until(shower.ReadyBy)
+	snoozeToken, expiresAt := h.shareService.Sign(services.ShareKindSnooze, req.UserID, target+"/"+strconv.Itoa(req.SnoozeMinutes), ttl)
+	skipToken, _ := h.shareService.Sign(services.ShareKindSkip, req.UserID, target, ttl)
+	snoozePath := "/api/schedule/actions/" + snoozeToken
+	skipPath := "/api/schedule/actions/" + skipToken
+
+	c.JSON(http.StatusOK, gin.H{
+		"shower":    shower,
+		"snooze":    gin.H{"url": requestOrigin(c) + snoozePath, "path": snoozePath, "minutes": req.SnoozeMinutes},
+		"skip":      gin.H{"url": requestOrigin(c) + skipPath, "path": skipPath},
+		"expiresAt": expiresAt,
+	})
+}
+
+// ApplyScheduleAction handles GET /api/schedule/actions/:token, snoozing or skipping the shower
+// an action link was made for. It is a GET so the link works when tapped in a notification.
+func (h *ScheduleTemplateHandler) ApplyScheduleAction(c *gin.Context) {
+	grant, err := h.shareService.Verify(c.Param("token"))
+	if err != nil {
+		shareLinkError(c, err)
+		return
+	}
+	parts := strings.Split(grant.Target, "/")
+	if len(parts) < 2 || (grant.Kind != services.ShareKindSnooze && grant.Kind != services.ShareKindSkip) {
+		shareLinkError(c, services.ErrShareLinkInvalid)
+		return
+	}
+
+	shower, err := h.templateService.ShowerOn(grant.UserID, parts[0], parts[1])
+	if err != nil {
+		scheduleTemplateError(c, err, "Failed to retrieve schedule templates")
+		return
+	}
+
+	if grant.Kind == services.ShareKindSkip {
+		if err := h.templateService.Skip(grant.UserID, shower); err != nil {
+			scheduleTemplateError(c, err, "Failed to skip heating")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": t(c, "Heating skipped"),
+			"skipped": shower,
+		})
+		return
+	}
+
+	minutes, err := strconv.Atoi(parts[len(parts)-1])
+	if len(parts) != 3 || err != nil {
+		shareLinkError(c, services.ErrShareLinkInvalid)
+		return
+	}
+	if err := h.templateService.Snooze(grant.UserID, shower, minutes, time.Now()); err != nil {
+		scheduleTemplateError(c, err, "Failed to snooze heating")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": tf(c, "Heating snoozed until %s", shower.SnoozedUntil.Local().Format("15:04")),
+		"shower":  shower,
+	})
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupHandler handles the first-run setup wizard
+type SetupHandler struct {
+	setup      *services.SetupService
+	adminToken string
+}
+
+// NewSetupHandler creates a new setup handler instance. Once the admin step is done the other
+// steps need its token, or the configured admin token.
+func NewSetupHandler(setup *services.SetupService, adminToken string) *SetupHandler {
+	return &SetupHandler{
+		setup:      setup,
+		adminToken: adminToken,
+	}
+}
+
+// GetSetupStatus handles GET /api/setup/status
+func (h *SetupHandler) GetSetupStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"setup": h.setup.Status()})
+}
+
+// CreateSetupAdmin handles POST /api/setup/admin
+func (h *SetupHandler) CreateSetupAdmin(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required,max=64"`
+		Name   string `json:"name"`
+		Email  string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	token, err := h.setup.CreateAdmin(req.UserID, req.Name, req.Email)
+	if err != nil {
+		h.setupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":    true,
+		"userId":     req.UserID,
+		"adminToken": token, // shown only this once
+		"setup":      h.setup.Status(),
+	})
+}
+
+// ChooseSetupDatabase handles POST /api/setup/database
+func (h *SetupHandler) ChooseSetupDatabase(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	var req struct {
+		Backend string `json:"backend" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.setup.ChooseDatabase(req.Backend); err != nil {
+		if errors.Is(err, services.ErrUnsupportedDatabase) {
+			validationError(c, "backend", "oneof", tf(c, "Database backend must be one of %s in this build", strings.Join(services.DatabaseBackends, ", ")))
+			return
+		}
+		h.setupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "setup": h.setup.Status()})
+}
+
+// SetSetupLocation handles POST /api/setup/location; without coordinates the weather provider is skipped
+func (h *SetupHandler) SetSetupLocation(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	var req struct {
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.setup.SetLocation(req.Latitude, req.Longitude); err != nil {
+		if errors.Is(err, services.ErrInvalidLocation) {
+			validationError(c, "latitude", "range", "Latitude must be between -90 and 90 and longitude between -180 and 180, or both left out")
+			return
+		}
+		h.setupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "setup": h.setup.Status()})
+}
+
+// CreateSetupDevice handles POST /api/setup/device; the device belongs to the admin unless userId says otherwise
+func (h *SetupHandler) CreateSetupDevice(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	var req struct {
+		UserID   string `json:"userId"`
+		DeviceID string `json:"deviceId" binding:"required"`
+		Preset   string `json:"preset" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = h.setup.AdminUserID()
+	}
+
+	profile, err := h.setup.CreateDevice(req.UserID, req.DeviceID, req.Preset)
+	if errors.Is(err, services.ErrUnknownBoilerPreset) {
+		validationError(c, "preset", "oneof", tf(c, "Unknown boiler preset %q", req.Preset))
+		return
+	}
+	if err != nil {
+		h.setupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "profile": profile, "setup": h.setup.Status()})
+}
+
+// CompleteSetup handles POST /api/setup/complete, opening the other routes
+func (h *SetupHandler) CompleteSetup(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	if err := h.setup.Finish(); err != nil {
+		h.setupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "setup": h.setup.Status()})
+}
+
+// authorize requires the token of the admin step, or the configured admin token, once the
+// admin exists; before that the steps fail on their own for want of an admin
+func (h *SetupHandler) authorize(c *gin.Context) bool {
+	if !h.setup.HasAdmin() {
+		return true
+	}
+	provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if _, ok := h.setup.AdminByToken(provided); ok {
+		return true
+	}
+	if h.adminToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(h.adminToken)) == 1 {
+		return true
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error": t(c, "Invalid admin credentials"),
+	})
+	return false
+}
+
+// setupError writes the response for an error from a setup step
+func (h *SetupHandler) setupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSetupComplete):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "Setup is already complete")})
+	case errors.Is(err, services.ErrSetupAdminExists):
+		c.JSON(http.StatusConflict, gin.H{"error": t(c, "The setup admin was already created")})
+	case errors.Is(err, services.ErrSetupStepMissing):
+		next := h.setup.Status().Next
+		c.JSON(http.StatusConflict, gin.H{
+			"error": tf(c, "Finish the %s setup step first", next),
+			"next":  next,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to save setup") + ": " + err.Error()})
+	}
+}
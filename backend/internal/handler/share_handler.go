@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareHandler handles signed read-only links to a user's stats or exports
+type ShareHandler struct {
+	shareService  *services.ShareService
+	statsService  *services.StatsService
+	recordService *services.RecordService
+	exportService *services.ExportService
+}
+
+// NewShareHandler creates a new share handler instance
+func NewShareHandler(shareService *services.ShareService, statsService *services.StatsService, recordService *services.RecordService, exportService *services.ExportService) *ShareHandler {
+	return &ShareHandler{
+		shareService:  shareService,
+		statsService:  statsService,
+		recordService: recordService,
+		exportService: exportService,
+	}
+}
+
+// CreateShareLink handles POST /api/share-links
+func (h *ShareHandler) CreateShareLink(c *gin.Context) {
+	var req struct {
+		UserID         string `json:"userId" binding:"required"`
+		Kind           string `json:"kind" binding:"required"`
+		ExportJobID    string `json:"exportJobId"`
+		ExpiresInHours int    `json:"expiresInHours"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	switch req.Kind {
+	case services.ShareKindStats:
+		req.ExportJobID = ""
+	case services.ShareKindExport:
+		if req.ExportJobID == "" {
+			validationError(c, "exportJobId", "required", "Export job ID is required for export links")
+			return
+		}
+		// Only completed exports can be shared
+		if _, err := h.exportService.DownloadPath(req.UserID, req.ExportJobID); err != nil {
+			exportDownloadError(c, err)
+			return
+		}
+	default:
+		validationError(c, "kind", "oneof", "Kind must be 'stats' or 'export'")
+		return
+	}
+
+	if req.ExpiresInHours < 0 {
+		validationError(c, "expiresInHours", "min", "Expiry must not be negative")
+		return
+	}
+
+	token, expiresAt := h.shareService.Sign(req.Kind, req.UserID, req.ExportJobID, time.Duration(req.ExpiresInHours)*time.Hour)
+	path := "/api/shared/" + token
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":       requestOrigin(c) + path,
+		"path":      path,
+		"expiresAt": expiresAt,
+	})
+}
+
+// GetShared handles GET /api/shared/:token
+func (h *ShareHandler) GetShared(c *gin.Context) {
+	grant, err := h.shareService.Verify(c.Param("token"))
+	if err != nil {
+		shareLinkError(c, err)
+		return
+	}
+
+	switch grant.Kind {
+	case services.ShareKindExport:
+		path, err := h.exportService.DownloadPath(grant.UserID, grant.Target)
+		if err != nil {
+			exportDownloadError(c, err)
+			return
+		}
+		c.FileAttachment(path, "heating_history_"+grant.Target+".csv")
+	case services.ShareKindStats:
+		report, err := h.statsService.ChangePoints(grant.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to load stats") + ": " + err.Error()})
+			return
+		}
+		records, err := h.recordService.GetRecordsByUser(grant.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": t(c, "Failed to retrieve history") + ": " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"userId":       grant.UserID,
+			"changePoints": report,
+			"history":      records,
+			"expiresAt":    time.Unix(grant.ExpiresAt, 0),
+		})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Link not found")})
+	}
+}
+
+// shareLinkError writes the response for a token that didn't verify
+func shareLinkError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrShareLinkExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": t(c, "This link has expired")})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Link not found")})
+}
+
+// requestOrigin returns the scheme and host the client used to reach the server
+func requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SolarHandler handles HTTP requests for the house's solar production
+type SolarHandler struct {
+	solarService *services.SolarService
+}
+
+// NewSolarHandler creates a new solar handler instance
+func NewSolarHandler(solarService *services.SolarService) *SolarHandler {
+	return &SolarHandler{
+		solarService: solarService,
+	}
+}
+
+// SetSolarForecast handles POST /api/solar/forecast, used by whatever polls the PV forecast service
+func (h *SolarHandler) SetSolarForecast(c *gin.Context) {
+	var req struct {
+		Points []services.SolarPoint `json:"points" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	for _, point := range req.Points {
+		if !point.End.After(point.Start) || point.Watts < 0 {
+			validationError(c, "points", "range", "Each forecast point needs an end after its start and non-negative watts")
+			return
+		}
+	}
+
+	h.solarService.SetForecast(req.Points)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// ReportSolarProduction handles POST /api/solar/production, used by inverter and MQTT bridges
+func (h *SolarHandler) ReportSolarProduction(c *gin.Context) {
+	var req struct {
+		Watts *float64  `json:"watts" binding:"required"`
+		At    time.Time `json:"at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if *req.Watts < 0 {
+		validationError(c, "watts", "min", "Production can't be negative")
+		return
+	}
+
+	h.solarService.ReportProduction(*req.Watts, req.At)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
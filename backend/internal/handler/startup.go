@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// StartupGate lets the server listen before the database is up. Until Open is called it
+// answers /healthz with 200, so the process counts as alive, and everything else, /readyz
+// included, with 503, so no traffic is routed to it yet.
+type StartupGate struct {
+	handler atomic.Value // http.Handler once open
+}
+
+// NewStartupGate creates a closed gate
+func NewStartupGate() *StartupGate {
+	return &StartupGate{}
+}
+
+// Open routes every request to h from now on
+func (g *StartupGate) Open(h http.Handler) {
+	g.handler.Store(h)
+}
+
+// ServeHTTP implements http.Handler
+func (g *StartupGate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h, ok := g.handler.Load().(http.Handler); ok {
+		h.ServeHTTP(w, r)
+		return
+	}
+	if r.URL.Path == "/healthz" {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Retry-After", "5")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(`{"status":"starting"}`))
+}
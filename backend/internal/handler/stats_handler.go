@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsHandler handles HTTP requests for user statistics
+type StatsHandler struct {
+	statsService *services.StatsService
+}
+
+// NewStatsHandler creates a new stats handler instance
+func NewStatsHandler(statsService *services.StatsService) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
+
+// GetChangePoints handles GET /api/stats/change-points?userId=
+func (h *StatsHandler) GetChangePoints(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	report, err := h.statsService.ChangePoints(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to detect change points") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetSummary handles GET /api/stats/summary?userId=
+func (h *StatsHandler) GetSummary(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+	h.writeSummary(c, userID)
+}
+
+// GetHeatmap handles GET /api/stats/heatmap?userId=
+func (h *StatsHandler) GetHeatmap(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	heatmap, err := h.statsService.Heatmap(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to build heatmap") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmap)
+}
+
+// GetDistribution handles GET /api/stats/distribution?userId=&duration=&temperature=, the
+// heating times and ratings of the records near that context, the user's and everyone else's
+func (h *StatsHandler) GetDistribution(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+	duration, err := strconv.ParseFloat(c.Query("duration"), 64)
+	if err != nil || !validShowerDuration(c, duration) {
+		durationRangeError(c, "duration")
+		return
+	}
+	temperature, err := strconv.ParseFloat(c.Query("temperature"), 64)
+	if err != nil || !validTemperature(c, temperature) {
+		temperatureRangeError(c, "temperature")
+		return
+	}
+
+	distribution, err := h.statsService.Distribution(userID, duration, temperature)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to build distribution") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, distribution)
+}
+
+// GetReportOptions handles GET /api/stats/reports/options, the metrics and groupings a custom
+// report can use
+func (h *StatsHandler) GetReportOptions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"metrics": services.ReportMetrics(),
+		"groupBy": services.ReportGroups(),
+	})
+}
+
+// RunReport handles POST /api/stats/reports, a custom report over the user's records built from
+// the metrics, groupings and filters in the body
+func (h *StatsHandler) RunReport(c *gin.Context) {
+	var req services.ReportQuery
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	report, err := h.statsService.Report(req)
+	if err != nil {
+		reportError(c, err, "Failed to run report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetOverview handles GET /api/admin/overview, the summary over every user's records
+func (h *StatsHandler) GetOverview(c *gin.Context) {
+	h.writeSummary(c, "")
+}
+
+func (h *StatsHandler) writeSummary(c *gin.Context, userID string) {
+	summary, err := h.statsService.Summary(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to summarise records") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncHandler handles HTTP requests for syncing between instances
+type SyncHandler struct {
+	syncService *services.SyncService
+}
+
+// NewSyncHandler creates a new sync handler instance
+func NewSyncHandler(syncService *services.SyncService) *SyncHandler {
+	return &SyncHandler{
+		syncService: syncService,
+	}
+}
+
+// GetInstance handles GET /api/instance
+func (h *SyncHandler) GetInstance(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"instanceId": h.syncService.InstanceID(),
+	})
+}
+
+// ExportChanges handles GET /api/sync/export?userId=&since=
+func (h *SyncHandler) ExportChanges(c *gin.Context) {
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		validationError(c, "since", "date", err.Error())
+		return
+	}
+	if since == nil {
+		since = &time.Time{}
+	}
+
+	bundle, err := h.syncService.Export(c.Query("userId"), *since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to export changes") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportChanges handles POST /api/sync/import?dryRun=
+func (h *SyncHandler) ImportChanges(c *gin.Context) {
+	var bundle services.SyncBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		bindError(c, err)
+		return
+	}
+	if bundle.InstanceID == "" {
+		validationError(c, "instanceId", "required", "Instance ID is required")
+		return
+	}
+
+	if isDryRun(c) {
+		preview, err := h.syncService.PreviewImport(&bundle)
+		if err != nil {
+			importError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"dryRun": true, "counts": preview.Counts, "sampleIds": preview.SampleIDs})
+		return
+	}
+
+	result, err := h.syncService.Import(&bundle)
+	if err != nil {
+		importError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"result":  result,
+	})
+}
+
+// importError writes the response for a bundle that couldn't be imported
+func importError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrSyncSameInstance) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": t(c, "Cannot import changes exported by this instance"),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": t(c, "Failed to import changes") + ": " + err.Error(),
+	})
+}
+
+// RunSync handles POST /api/sync/run
+func (h *SyncHandler) RunSync(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	result, err := h.syncService.Run(c.Request.Context(), req.UserID)
+	if err != nil {
+		if errors.Is(err, services.ErrSyncNoPeer) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": t(c, "No sync peer is configured"),
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": t(c, "Sync with peer failed") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"result":  result,
+	})
+}
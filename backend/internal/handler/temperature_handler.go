@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TemperatureHandler handles HTTP requests for temperature sources
+type TemperatureHandler struct {
+	temperatureService *services.TemperatureService
+}
+
+// NewTemperatureHandler creates a new temperature handler instance
+func NewTemperatureHandler(temperatureService *services.TemperatureService) *TemperatureHandler {
+	return &TemperatureHandler{
+		temperatureService: temperatureService,
+	}
+}
+
+// GetTemperature handles GET /api/temperature?userId=
+func (h *TemperatureHandler) GetTemperature(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	reading, err := h.temperatureService.Resolve(c.Request.Context(), services.TemperatureQuery{UserID: userID})
+	if err != nil {
+		if errors.Is(err, services.ErrNoTemperature) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": t(c, "No temperature source has a reading"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to determine temperature") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"temperature": reading,
+	})
+}
+
+// ReportTemperatureReading handles POST /api/temperature/readings, used by MQTT sensor bridges
+func (h *TemperatureHandler) ReportTemperatureReading(c *gin.Context) {
+	var req struct {
+		UserID      string    `json:"userId"` // empty for a sensor shared by the whole house
+		Temperature *float64  `json:"temperature" binding:"required"`
+		At          time.Time `json:"at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if !validTemperature(c, *req.Temperature) {
+		temperatureRangeError(c, "temperature")
+		return
+	}
+
+	h.temperatureService.ReportSensorReading(req.UserID, *req.Temperature, req.At)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// GetTemperatureSources handles GET /api/users/me/temperature-sources?userId=
+func (h *TemperatureHandler) GetTemperatureSources(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	sources, err := h.temperatureService.Sources(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load temperature sources") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sources":   sources,
+		"available": h.temperatureService.Available(),
+	})
+}
+
+// SetTemperatureSources handles POST /api/users/me/temperature-sources
+func (h *TemperatureHandler) SetTemperatureSources(c *gin.Context) {
+	var req struct {
+		UserID  string   `json:"userId" binding:"required"`
+		Sources []string `json:"sources"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.temperatureService.SetSources(req.UserID, req.Sources); err != nil {
+		if errors.Is(err, services.ErrUnknownTemperatureSource) {
+			validationError(c, "sources", "oneof", err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to save temperature sources") + ": " + err.Error(),
+		})
+		return
+	}
+
+	sources, err := h.temperatureService.Sources(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load temperature sources") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"sources": sources,
+	})
+}
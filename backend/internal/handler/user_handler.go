@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler handles HTTP requests a user makes about their own account
+type UserHandler struct {
+	userService    *services.UserService
+	contextService *services.ContextService
+	regions        *services.RegionDirectory
+	targets        *services.SatisfactionTargetDirectory
+	buckets        *services.BucketTreeService
+}
+
+// NewUserHandler creates a new user handler instance
+func NewUserHandler(userService *services.UserService, contextService *services.ContextService, regions *services.RegionDirectory, targets *services.SatisfactionTargetDirectory, buckets *services.BucketTreeService) *UserHandler {
+	return &UserHandler{
+		userService:    userService,
+		contextService: contextService,
+		regions:        regions,
+		targets:        targets,
+		buckets:        buckets,
+	}
+}
+
+// ResetMyModel handles POST /api/users/me/model/reset
+func (h *UserHandler) ResetMyModel(c *gin.Context) {
+	var req struct {
+		UserID string     `json:"userId" binding:"required"`
+		From   *time.Time `json:"from"`
+		To     *time.Time `json:"to"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if req.From != nil && req.To != nil && req.From.After(*req.To) {
+		validationError(c, "from", "range", "The start of the date range must not be after its end")
+		return
+	}
+
+	excluded, err := h.userService.ResetModel(req.UserID, req.From, req.To)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to reset model") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"message":         tf(c, "Excluded %d records from training", excluded),
+		"excludedRecords": excluded,
+	})
+}
+
+// GetMyStepCap handles GET /api/users/me/step-cap?userId=
+func (h *UserHandler) GetMyStepCap(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	stepCap, err := h.userService.StepCap(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load step cap") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stepCap": stepCap, // null while the default applies
+	})
+}
+
+// SetMyStepCap handles POST /api/users/me/step-cap
+func (h *UserHandler) SetMyStepCap(c *gin.Context) {
+	var req struct {
+		UserID  string   `json:"userId" binding:"required"`
+		StepCap *float64 `json:"stepCap"` // null restores the default
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.userService.SetStepCap(req.UserID, req.StepCap); err != nil {
+		if errors.Is(err, services.ErrInvalidStepCap) {
+			validationError(c, "stepCap", "range", "Step cap must be greater than 0 and at most 1")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to save step cap") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"stepCap": req.StepCap,
+	})
+}
+
+// GetMyRounding handles GET /api/users/me/rounding?userId=
+func (h *UserHandler) GetMyRounding(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	rounding, err := h.userService.RoundingPolicy(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load rounding preferences") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rounding": rounding, // fields left out use the deployment default
+	})
+}
+
+// SetMyRounding handles POST /api/users/me/rounding
+func (h *UserHandler) SetMyRounding(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		services.RoundingPolicy
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.userService.SetRoundingPolicy(req.UserID, req.RoundingPolicy); err != nil {
+		if errors.Is(err, services.ErrInvalidRounding) {
+			validationError(c, "rounding", "oneof", "Rounding mode must be smart, nearest or ceil, step between 0.1 and 10 minutes and hysteresis between 0 and 0.5")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to save rounding preferences") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"rounding": req.RoundingPolicy,
+	})
+}
+
+// GetMyRegion handles GET /api/users/me/region?userId=
+func (h *UserHandler) GetMyRegion(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"region":   h.regions.Region(userID), // empty while unknown
+		"declared": h.regions.Declared(userID) != "",
+		"regions":  services.Regions,
+	})
+}
+
+// SetMyRegion handles POST /api/users/me/region
+func (h *UserHandler) SetMyRegion(c *gin.Context) {
+	var req struct {
+		UserID string `json:"userId" binding:"required"`
+		Region string `json:"region"` // empty restores the region of the weather location
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.regions.SetRegion(req.UserID, req.Region); err != nil {
+		if errors.Is(err, services.ErrInvalidRegion) {
+			validationError(c, "region", "oneof", "Region must be tropical, subtropical, temperate, cold or polar")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to save region") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"region":   h.regions.Region(req.UserID),
+		"declared": req.Region != "",
+	})
+}
+
+// GetMyUsage handles GET /api/users/me/usage?userId=
+func (h *UserHandler) GetMyUsage(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	usage, err := h.userService.Usage(userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load usage") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"usage": usage})
+}
+
+// GetMySatisfactionTarget handles GET /api/users/me/satisfaction-target?userId=
+func (h *UserHandler) GetMySatisfactionTarget(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"satisfactionTarget": h.targets.Get(userID),
+	})
+}
+
+// SetMySatisfactionTarget handles POST /api/users/me/satisfaction-target, for users whose
+// perfect shower isn't a 50
+func (h *UserHandler) SetMySatisfactionTarget(c *gin.Context) {
+	var req struct {
+		UserID string   `json:"userId" binding:"required"`
+		Target *float64 `json:"target"` // null goes back to learning it from the user's ratings
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.targets.SetTarget(req.UserID, req.Target); err != nil {
+		if errors.Is(err, services.ErrInvalidSatisfactionTarget) {
+			validationError(c, "target", "range", "Target satisfaction must be between 30 and 70")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to save target satisfaction") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":            true,
+		"satisfactionTarget": h.targets.Get(req.UserID),
+	})
+}
+
+// GetMySatisfactionCalibration handles GET /api/users/me/satisfaction-calibration?userId=, how
+// the user's ratings spread compared with everyone's, and the scale that maps one onto the other
+func (h *UserHandler) GetMySatisfactionCalibration(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calibration": h.targets.Calibration(userID),
+	})
+}
+
+// GetMyContextBuckets handles GET /api/users/me/context-buckets?userId=, the buckets the user's
+// history is split into and that size the kernels of their predictions
+func (h *UserHandler) GetMyContextBuckets(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	tree, err := h.buckets.Tree(userID)
+	if errors.Is(err, services.ErrContextBucketsDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": t(c, "Context buckets are not enabled")})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to load context buckets") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"buckets": tree,
+	})
+}
+
+// GetMyPredictionProfile handles GET /api/users/me/prediction-profile?userId=, the instance's
+// global settings with the user's own overrides
+func (h *UserHandler) GetMyPredictionProfile(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	settings, err := h.userService.PredictionSettings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to export prediction profile") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.PredictionProfile{
+		Version:    services.PredictionProfileVersion,
+		ExportedAt: time.Now().UTC(),
+		Global:     config.PredictionSettings(),
+		Users:      settings,
+	})
+}
+
+// ImportMyPredictionProfile handles POST /api/users/me/prediction-profile?dryRun=, adopting the
+// overrides of one user of the profile: the only one, or the one named by from. A profile
+// without users restores the defaults. Global settings can't be changed by a user; the
+// response lists the ones that differ.
+func (h *UserHandler) ImportMyPredictionProfile(c *gin.Context) {
+	var req struct {
+		UserID  string                     `json:"userId" binding:"required"`
+		From    string                     `json:"from"`
+		Profile services.PredictionProfile `json:"profile"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if predictionProfileError(c, req.Profile.Validate()) {
+		return
+	}
+
+	adopted := services.UserPredictionSettings{UserID: req.UserID}
+	switch {
+	case req.From != "":
+		found := false
+		for _, user := range req.Profile.Users {
+			if user.UserID == req.From {
+				adopted, found = user, true
+			}
+		}
+		if !found {
+			validationError(c, "from", "oneof", "The profile has no settings for that user")
+			return
+		}
+	case len(req.Profile.Users) == 1:
+		adopted = req.Profile.Users[0]
+	case len(req.Profile.Users) > 1:
+		validationError(c, "from", "required", "The profile has several users; choose one with from")
+		return
+	}
+	adopted.UserID = req.UserID
+	if isDryRun(c) {
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun":        true,
+			"settings":      adopted,
+			"globalChanges": req.Profile.GlobalChanges(config.PredictionSettings()),
+		})
+		return
+	}
+
+	if err := h.userService.ImportPredictionSettings([]services.UserPredictionSettings{adopted}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to import prediction profile") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"settings":      adopted,
+		"globalChanges": req.Profile.GlobalChanges(config.PredictionSettings()),
+	})
+}
+
+// predictionProfileError writes the response for an invalid profile, reporting whether it did
+func predictionProfileError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrInvalidStepCap):
+		validationError(c, "stepCap", "range", "Step cap must be greater than 0 and at most 1")
+	case errors.Is(err, services.ErrInvalidRounding):
+		validationError(c, "rounding", "oneof", "Rounding mode must be smart, nearest or ceil, step between 0.1 and 10 minutes and hysteresis between 0 and 0.5")
+	default:
+		validationError(c, "profile", "format", err.Error())
+	}
+	return true
+}
+
+// ListMyContexts handles GET /api/users/me/contexts?userId=
+func (h *UserHandler) ListMyContexts(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID == "" {
+		validationError(c, "userId", "required", "UserID is required")
+		return
+	}
+
+	contexts, err := h.contextService.ListContexts(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": t(c, "Failed to retrieve contexts") + ": " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contexts": contexts,
+	})
+}
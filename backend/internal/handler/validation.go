@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"heat-logger/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Input ranges checked by the handlers; GET /api/meta/limits reports them to clients
+const (
+	minShowerDuration   = 1 // minutes
+	maxShowerDuration   = 60
+	minTemperature      = -50 // °C
+	maxTemperature      = 50
+	minInletTemperature = 0 // °C
+	maxInletTemperature = 40
+	maxSequenceShowers  = 10 // showers in one sequence plan
+)
+
+// limitsKey is the context key holding the input ranges in force
+const limitsKey = "limits"
+
+// inputLimits are the shower duration and temperature ranges in force: the built-in ones,
+// narrowed by the operator's rules
+type inputLimits struct {
+	Duration    limitRange
+	Temperature limitRange
+}
+
+// builtinLimits apply when the operator has set no rules
+var builtinLimits = inputLimits{
+	Duration:    limitRange{Min: minShowerDuration, Max: maxShowerDuration, Unit: "min"},
+	Temperature: limitRange{Min: minTemperature, Max: maxTemperature, Unit: "°C"},
+}
+
+// ValidationRules enforces the operator's extra record rules in the handlers. A rule can only
+// narrow a built-in range; a bound outside it is clamped to it.
+func ValidationRules(cfg config.RecordsConfig) gin.HandlerFunc {
+	limits := inputLimits{
+		Duration:    builtinLimits.Duration.narrow(cfg.MinDuration, cfg.MaxDuration),
+		Temperature: builtinLimits.Temperature.narrow(cfg.MinTemperature, cfg.MaxTemperature),
+	}
+	return func(c *gin.Context) {
+		c.Set(limitsKey, limits)
+		c.Next()
+	}
+}
+
+// narrow returns the range with the given bounds, where set, clamped into it
+func (r limitRange) narrow(min, max *float64) limitRange {
+	if min != nil {
+		r.Min = math.Min(math.Max(*min, r.Min), r.Max)
+	}
+	if max != nil {
+		r.Max = math.Max(math.Min(*max, r.Max), r.Min)
+	}
+	return r
+}
+
+// contains reports whether a value falls in the range
+func (r limitRange) contains(value float64) bool {
+	return value >= r.Min && value <= r.Max
+}
+
+// limitsOf returns the input ranges in force for the request
+func limitsOf(c *gin.Context) inputLimits {
+	if limits, ok := c.Get(limitsKey); ok {
+		return limits.(inputLimits)
+	}
+	return builtinLimits
+}
+
+// validShowerDuration reports whether a shower duration is within the accepted range
+func validShowerDuration(c *gin.Context, minutes float64) bool {
+	return limitsOf(c).Duration.contains(minutes)
+}
+
+// validTemperature reports whether an ambient temperature is within the accepted range
+func validTemperature(c *gin.Context, celsius float64) bool {
+	return limitsOf(c).Temperature.contains(celsius)
+}
+
+// durationRangeError writes a 400 for a shower duration outside the accepted range
+func durationRangeError(c *gin.Context, field string) {
+	rangeError(c, field, "Shower duration must be between %g and %g minutes", limitsOf(c).Duration)
+}
+
+// temperatureRangeError writes a 400 for an ambient temperature outside the accepted range
+func temperatureRangeError(c *gin.Context, field string) {
+	rangeError(c, field, "Temperature must be between %g and %g degrees Celsius", limitsOf(c).Temperature)
+}
+
+// rangeError writes a 400 for a field outside a range, naming the range's bounds
+func rangeError(c *gin.Context, field, format string, r limitRange) {
+	message := tf(c, format, r.Min, r.Max)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   message,
+		"details": []FieldError{{Field: field, Rule: "range", Message: message}},
+	})
+}
+
+// validInletTemperature reports whether a cold-water inlet reading is plausible
+func validInletTemperature(celsius float64) bool {
+	return celsius >= minInletTemperature && celsius <= maxInletTemperature
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. "userId" or "items[0].name"
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report JSON field names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindError writes a 400 for a failed bind, listing every offending field, or a 413 when the
+// body was cut off at the size limit
+func bindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		bodyTooLarge(c, tooLarge.Limit)
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   t(c, "Invalid request data"),
+		"details": fieldErrors(c, err),
+	})
+}
+
+// bodyTooLarge writes a 413 for a request body over its limit of limit bytes
+func bodyTooLarge(c *gin.Context, limit int64) {
+	message := tf(c, "Request body must not exceed %d KiB", limit/1024)
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":   message,
+		"details": []FieldError{{Rule: "max_size", Message: message}},
+	})
+}
+
+// validationError writes a 400 for a single field that failed a handler-level check
+func validationError(c *gin.Context, field, rule, message string) {
+	message = t(c, message)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   message,
+		"details": []FieldError{{Field: field, Rule: rule, Message: message}},
+	})
+}
+
+// fieldErrors translates binding and validator errors into field-level entries
+func fieldErrors(c *gin.Context, err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		out := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			field := fieldPath(fe.Namespace())
+			out = append(out, FieldError{
+				Field:   field,
+				Rule:    fe.Tag(),
+				Message: ruleMessage(c, field, fe),
+			})
+		}
+		return out
+	case errors.As(err, &typeErr):
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: tf(c, "%s must be a %s", typeErr.Field, jsonTypeName(typeErr.Type)),
+		}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Rule: "json", Message: t(c, "Request body must be valid JSON")}}
+	default:
+		return []FieldError{{Rule: "invalid", Message: err.Error()}}
+	}
+}
+
+// fieldPath drops the root struct name from a validator namespace
+func fieldPath(namespace string) string {
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		return rest
+	}
+	return namespace
+}
+
+// ruleMessage renders a readable message for a validator failure
+func ruleMessage(c *gin.Context, field string, fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return tf(c, "%s is required", field)
+	case "min", "gte":
+		return tf(c, "%s must be at least %s", field, fe.Param())
+	case "max", "lte":
+		return tf(c, "%s must be at most %s", field, fe.Param())
+	case "oneof":
+		return tf(c, "%s must be one of: %s", field, fe.Param())
+	default:
+		return tf(c, "%s is invalid", field)
+	}
+}
+
+func jsonTypeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	default:
+		return "object"
+	}
+}
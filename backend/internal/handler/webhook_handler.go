@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"heat-logger/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler handles events pushed by third-party services
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler instance
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// ReceiveWebhook handles POST /api/webhooks/:source. The request is signed with the webhook
+// secret in X-Webhook-Signature, or carries it as a bearer token; events no rule matches are
+// accepted and ignored so the sender doesn't retry them.
+func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		bindError(c, err)
+		return
+	}
+	bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !h.webhookService.Authorized(body, c.GetHeader("X-Webhook-Signature"), bearer) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": t(c, "Invalid webhook signature"),
+		})
+		return
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	results, err := h.webhookService.Handle(c.Request.Context(), c.Param("source"), payload, time.Now())
+	if err != nil {
+		var payloadErr *services.WebhookPayloadError
+		if errors.As(err, &payloadErr) {
+			validationError(c, payloadErr.Path, "number", tf(c, "Rule %q has no usable number at %s", payloadErr.Rule, payloadErr.Path))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   t(c, "Failed to apply webhook") + ": " + err.Error(),
+			"applied": results,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"applied": results,
+	})
+}
@@ -0,0 +1,208 @@
+// Package hooks lets a deployment add its own business rules at fixed points, e.g. capping
+// heating during grid peaks, without forking handler code.
+//
+// Hooks are compiled in: each lives in its own file and registers a factory under a name in
+// init, like notification channels do. HOOKS lists the names a deployment enables, in the order
+// they run, and HOOKS_SETTINGS hands them their settings. A hook implements any of the point
+// interfaces below.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+)
+
+// ErrUnknownHook is returned for a hook name no hook registered
+var ErrUnknownHook = errors.New("unknown hook")
+
+// PredictionHook runs before a prediction is returned, to the API and to the schedules and chat
+// commands alike. It may change the response, which is the hook's own copy; an error leaves the
+// prediction as the hooks before it made it.
+type PredictionHook interface {
+	BeforePredictionReturned(ctx context.Context, req services.PredictionRequest, resp *services.PredictionResponse) error
+}
+
+// FeedbackHook runs after a feedback record is stored, or once the user accepted a record
+// awaiting review. Records excluded from training don't reach it.
+type FeedbackHook interface {
+	AfterFeedbackStored(record models.DailyRecord)
+}
+
+// Settings are a hook's settings from HOOKS_SETTINGS, without the hook's name in front
+type Settings map[string]float64
+
+// Get returns a setting, or fallback when it isn't set
+func (s Settings) Get(key string, fallback float64) float64 {
+	if value, ok := s[key]; ok {
+		return value
+	}
+	return fallback
+}
+
+// Factory builds a hook, which implements PredictionHook, FeedbackHook or both, from its
+// settings. It returns an error for settings it can't run with.
+type Factory func(settings Settings) (interface{}, error)
+
+var (
+	factoriesMu sync.Mutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a hook available under name. It panics when the name is taken, as HOOKS
+// couldn't tell the two apart.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, taken := factories[name]; taken {
+		panic("hooks: hook " + name + " registered twice")
+	}
+	factories[name] = factory
+}
+
+// Available lists the registered hooks in alphabetical order
+func Available() []string {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type namedPredictionHook struct {
+	name string
+	hook PredictionHook
+}
+
+type namedFeedbackHook struct {
+	name string
+	hook FeedbackHook
+}
+
+// Registry holds the hooks a deployment enabled, in the order they run
+type Registry struct {
+	names       []string
+	predictions []namedPredictionHook
+	feedback    []namedFeedbackHook
+}
+
+// New builds the hooks cfg enables. Hooks that are unknown, or can't be built with their
+// settings, are left out and reported in the error; the others still run.
+func New(cfg config.HooksConfig) (*Registry, error) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	r := &Registry{}
+	var errs []error
+	for _, name := range cfg.Enabled {
+		if name == "" {
+			continue
+		}
+		factory, ok := factories[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w %q", ErrUnknownHook, name))
+			continue
+		}
+		hook, err := factory(settingsFor(cfg.Settings, name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hook %s: %w", name, err))
+			continue
+		}
+		r.Add(name, hook)
+	}
+	return r, errors.Join(errs...)
+}
+
+// settingsFor picks the settings of one hook out of everyone's
+func settingsFor(all map[string]float64, name string) Settings {
+	settings := Settings{}
+	for key, value := range all {
+		if rest, ok := strings.CutPrefix(key, name+"."); ok {
+			settings[rest] = value
+		}
+	}
+	return settings
+}
+
+// Add appends hook to those that run, under name
+func (r *Registry) Add(name string, hook interface{}) {
+	r.names = append(r.names, name)
+	if h, ok := hook.(PredictionHook); ok {
+		r.predictions = append(r.predictions, namedPredictionHook{name, h})
+	}
+	if h, ok := hook.(FeedbackHook); ok {
+		r.feedback = append(r.feedback, namedFeedbackHook{name, h})
+	}
+}
+
+// Names lists the enabled hooks in the order they run
+func (r *Registry) Names() []string {
+	return r.names
+}
+
+// Predictor wraps inner so the prediction hooks run on its answers; without any, inner is
+// returned as it is
+func (r *Registry) Predictor(inner services.Predictor) services.Predictor {
+	if len(r.predictions) == 0 {
+		return inner
+	}
+	return &hookPredictor{inner: inner, hooks: r.predictions}
+}
+
+// FeedbackStored runs the feedback hooks on a stored record, for RecordService.OnRecordCreated
+func (r *Registry) FeedbackStored(record models.DailyRecord) {
+	for _, h := range r.feedback {
+		h.hook.AfterFeedbackStored(record)
+	}
+}
+
+// hookPredictor runs the prediction hooks on the answers of the predictor it wraps
+type hookPredictor struct {
+	inner services.Predictor
+	hooks []namedPredictionHook
+}
+
+// Predict runs the wrapped predictor, then every hook in order on a copy of its answer. The
+// hooks that changed the heating time are listed in the explanation. A failing hook is logged
+// and skipped: a deployment's rule must not take predictions down.
+func (p *hookPredictor) Predict(ctx context.Context, req services.PredictionRequest) (*services.PredictionResponse, error) {
+	resp, err := p.inner.Predict(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	// Copy: cached predictions are shared
+	hooked := *resp
+	var changedBy []string
+	for _, h := range p.hooks {
+		attempt := hooked
+		if err := h.hook.BeforePredictionReturned(ctx, req, &attempt); err != nil {
+			log.Printf("Prediction hook %s failed for user %s: %v", h.name, req.UserID, err)
+			continue
+		}
+		if attempt.HeatingTime != hooked.HeatingTime {
+			changedBy = append(changedBy, h.name)
+			attempt.RawHeatingTime = attempt.HeatingTime
+		}
+		hooked = attempt
+	}
+	if len(changedBy) > 0 {
+		var expl services.PredictionExplanation
+		if hooked.Explanation != nil {
+			expl = *hooked.Explanation
+		}
+		expl.Hooks = append(append([]string(nil), expl.Hooks...), changedBy...)
+		hooked.Explanation = &expl
+	}
+	return &hooked, nil
+}
@@ -0,0 +1,114 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedPredictor answers every request with the same response
+type fixedPredictor struct {
+	resp *services.PredictionResponse
+}
+
+func (p fixedPredictor) Predict(context.Context, services.PredictionRequest) (*services.PredictionResponse, error) {
+	return p.resp, nil
+}
+
+// addMinutes adds its "minutes" setting to every prediction and remembers the feedback it saw
+type addMinutes struct {
+	minutes  float64
+	feedback []string
+}
+
+func (h *addMinutes) BeforePredictionReturned(_ context.Context, _ services.PredictionRequest, resp *services.PredictionResponse) error {
+	resp.HeatingTime += h.minutes
+	return nil
+}
+
+func (h *addMinutes) AfterFeedbackStored(record models.DailyRecord) {
+	h.feedback = append(h.feedback, record.ID)
+}
+
+// failing changes the prediction, then fails
+type failing struct{}
+
+func (failing) BeforePredictionReturned(_ context.Context, _ services.PredictionRequest, resp *services.PredictionResponse) error {
+	resp.HeatingTime = 0
+	return errors.New("rule service unreachable")
+}
+
+var added *addMinutes
+
+func init() {
+	Register("test_add", func(settings Settings) (interface{}, error) {
+		added = &addMinutes{minutes: settings.Get("minutes", 1)}
+		return added, nil
+	})
+	Register("test_failing", func(Settings) (interface{}, error) {
+		return failing{}, nil
+	})
+}
+
+func TestNew_BuildsEnabledHooksInOrder(t *testing.T) {
+	registry, err := New(config.HooksConfig{
+		Enabled:  []string{"test_failing", "test_add", "missing", "peak_cap"},
+		Settings: map[string]float64{"test_add.minutes": 5, "peak_cap.from": 30},
+	})
+	assert.ErrorIs(t, err, ErrUnknownHook)
+	assert.ErrorContains(t, err, "peak_cap.minutes must be positive")
+	assert.Equal(t, []string{"test_failing", "test_add"}, registry.Names(), "the hooks that could be built still run")
+	assert.Contains(t, Available(), "peak_cap")
+
+	cached := &services.PredictionResponse{HeatingTime: 20, RawHeatingTime: 19.6}
+	predictor := registry.Predictor(fixedPredictor{resp: cached})
+	resp, err := predictor.Predict(context.Background(), services.PredictionRequest{UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, resp.HeatingTime, "the failing hook's change is dropped")
+	assert.Equal(t, 25.0, resp.RawHeatingTime)
+	require.NotNil(t, resp.Explanation)
+	assert.Equal(t, []string{"test_add"}, resp.Explanation.Hooks)
+	assert.Equal(t, 20.0, cached.HeatingTime, "the wrapped predictor's answer isn't changed")
+	assert.Nil(t, cached.Explanation)
+
+	registry.FeedbackStored(models.DailyRecord{ID: "r1"})
+	assert.Equal(t, []string{"r1"}, added.feedback)
+}
+
+func TestRegistry_PredictorWithoutHooks(t *testing.T) {
+	registry, err := New(config.HooksConfig{})
+	require.NoError(t, err)
+	inner := fixedPredictor{resp: &services.PredictionResponse{HeatingTime: 20}}
+	assert.Equal(t, services.Predictor(inner), registry.Predictor(inner))
+}
+
+func TestPeakCap_CapsDuringThePeak(t *testing.T) {
+	peak, err := NewPeakCap(Settings{"minutes": 15, "from": 22, "to": 6.5})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		at   string
+		want float64
+	}{
+		{"21:59", 30},
+		{"22:00", 15},
+		{"03:00", 15},
+		{"06:29", 15},
+		{"06:30", 30},
+	} {
+		at, err := time.Parse("15:04", tc.at)
+		require.NoError(t, err)
+		peak.now = func() time.Time { return at }
+		resp := &services.PredictionResponse{HeatingTime: 30}
+		require.NoError(t, peak.BeforePredictionReturned(context.Background(), services.PredictionRequest{}, resp))
+		assert.Equal(t, tc.want, resp.HeatingTime, tc.at)
+	}
+}
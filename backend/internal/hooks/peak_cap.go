@@ -0,0 +1,59 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"heat-logger/internal/services"
+)
+
+func init() {
+	Register("peak_cap", func(settings Settings) (interface{}, error) {
+		return NewPeakCap(settings)
+	})
+}
+
+// PeakCap caps heating during the grid's daily peak, for tariffs or utilities that penalise
+// load then. Its settings are minutes, the cap, and from and to, the hours of the day the peak
+// starts and ends in server time (17 and 21 by default); a peak may run past midnight.
+type PeakCap struct {
+	minutes  float64
+	from, to float64
+	now      func() time.Time
+}
+
+// NewPeakCap creates the peak cap hook from its settings
+func NewPeakCap(settings Settings) (*PeakCap, error) {
+	c := &PeakCap{
+		minutes: settings.Get("minutes", 0),
+		from:    settings.Get("from", 17),
+		to:      settings.Get("to", 21),
+		now:     time.Now,
+	}
+	if c.minutes <= 0 {
+		return nil, errors.New("peak_cap.minutes must be positive")
+	}
+	if c.from >= 24 || c.to > 24 || c.from == c.to {
+		return nil, errors.New("peak_cap.from and peak_cap.to must be different hours of the day")
+	}
+	return c, nil
+}
+
+// BeforePredictionReturned implements PredictionHook
+func (c *PeakCap) BeforePredictionReturned(_ context.Context, _ services.PredictionRequest, resp *services.PredictionResponse) error {
+	if c.inPeak(c.now()) {
+		resp.HeatingTime = math.Min(resp.HeatingTime, c.minutes)
+	}
+	return nil
+}
+
+// inPeak reports whether t falls in the peak hours
+func (c *PeakCap) inPeak(t time.Time) bool {
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	if c.from < c.to {
+		return hour >= c.from && hour < c.to
+	}
+	return hour >= c.from || hour < c.to
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Annotation kinds for household events
+const (
+	AnnotationBoilerServiced      = "boiler_serviced"
+	AnnotationShowerheadInstalled = "showerhead_installed"
+	AnnotationThermostatReplaced  = "thermostat_replaced"
+	AnnotationBoilerReplaced      = "boiler_replaced"
+	AnnotationOther               = "other"
+	AnnotationDemandResponse      = "demand_response" // a grid demand-response event capped or shifted heating
+)
+
+// Annotation is a dated household event that may explain shifts in heating needs
+type Annotation struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID          string    `json:"userId" gorm:"not null;index"`
+	Date            time.Time `json:"date" gorm:"not null;index"`
+	Kind            string    `json:"kind" gorm:"not null"`
+	Note            string    `json:"note,omitempty"`
+	HardwareChanged bool      `json:"hardwareChanged" gorm:"not null;default:false"` // older records lose most of their weight
+
+	// Set for events that last a while, e.g. a demand-response event: showers from Date to Until
+	// aren't learned from
+	Until   *time.Time `json:"until,omitempty"`
+	EventID string     `json:"eventId,omitempty" gorm:"index"` // the demand-response event that added it
+
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an annotation
+func (a *Annotation) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the Annotation model
+func (Annotation) TableName() string {
+	return "annotations"
+}
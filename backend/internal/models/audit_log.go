@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditLog records a privileged action for later review
+type AuditLog struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Actor     string    `json:"actor" gorm:"not null"`
+	Action    string    `json:"action" gorm:"not null;index"`
+	Target    string    `json:"target" gorm:"index"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an entry
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the AuditLog model
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Boiler calibration steps
+const (
+	CalibrationHeating   = "heating"   // the boiler is heating for the chosen time
+	CalibrationShowering = "showering" // the shower is running until the water goes cold
+)
+
+// How heating for a device is expressed
+const (
+	OutputMinutes     = "minutes"     // a heating time; the default
+	OutputTemperature = "temperature" // a target tank temperature as well, for thermostat-controlled boilers
+)
+
+// BoilerProfile holds what is known about one of a user's water heaters. The measured values
+// come from the calibration wizard and replace the configured defaults for that device.
+type BoilerProfile struct {
+	UserID   string `json:"userId" gorm:"primaryKey"`
+	DeviceID string `json:"deviceId" gorm:"primaryKey"`
+
+	TankSize     *float64   `json:"tankSize,omitempty"`    // litres of hot water the tank delivers
+	FlowRate     *float64   `json:"flowRate,omitempty"`    // litres of hot water a shower draws per minute
+	HeaterPower  *float64   `json:"heaterPower,omitempty"` // kW
+	Resolution   *float64   `json:"resolution,omitempty"`  // minutes the heater's timer can be set in, e.g. 0.5
+	OutputMode   string     `json:"outputMode,omitempty"`  // minutes or temperature; empty means minutes
+	SafetyFloor  *float64   `json:"safetyFloor,omitempty"` // minutes heating never drops below in cold weather; nil uses the configured floor
+	CalibratedAt *time.Time `json:"calibratedAt,omitempty"`
+
+	// Set from an onboarding preset; nil keeps the configured prediction settings
+	Preset        string   `json:"preset,omitempty"`
+	MinMinutes    *float64 `json:"minMinutes,omitempty"`
+	MaxMinutes    *float64 `json:"maxMinutes,omitempty"`
+	SigmaDuration *float64 `json:"sigmaDuration,omitempty"`
+	SigmaTemp     *float64 `json:"sigmaTemp,omitempty"`
+
+	// Weekly legionella cycle, in the server's time zone; no day means none
+	SanitationDay     string  `json:"sanitationDay,omitempty"`     // monday to sunday
+	SanitationTime    string  `json:"sanitationTime,omitempty"`    // HH:MM the cycle starts
+	SanitationMinutes float64 `json:"sanitationMinutes,omitempty"` // heating time; the configured one when 0
+
+	// The calibration in progress, if any
+	CalibrationStep            string     `json:"calibrationStep,omitempty"`
+	CalibrationHeatingMinutes  float64    `json:"calibrationHeatingMinutes,omitempty"`
+	CalibrationShowerStartedAt *time.Time `json:"calibrationShowerStartedAt,omitempty"`
+
+	EfficiencyNotifiedAt *time.Time `json:"efficiencyNotifiedAt,omitempty"` // when the user was last told the heater is degrading
+
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the BoilerProfile model
+func (BoilerProfile) TableName() string {
+	return "boiler_profiles"
+}
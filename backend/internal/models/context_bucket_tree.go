@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ContextBucketTree is a user's (duration, temperature) context buckets, split finer where their
+// feedback is dense
+type ContextBucketTree struct {
+	UserID    string          `json:"userId" gorm:"primaryKey"`
+	Buckets   json.RawMessage `json:"buckets" gorm:"type:text;not null"` // the root buckets with their splits
+	Records   int             `json:"records"`                           // records counted into the tree
+	UpdatedAt time.Time       `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the ContextBucketTree model
+func (ContextBucketTree) TableName() string {
+	return "context_bucket_trees"
+}
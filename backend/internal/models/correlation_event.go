@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Kinds of correlation event besides the device event types
+const (
+	CorrelationKindSchedule = "schedule" // a heating schedule was recommended
+)
+
+// CorrelationEvent is one step of a heating run that followed a prediction, traced by the
+// prediction's correlation ID: the schedule recommended for it, or a device event such as the
+// smart plug confirming it switched on
+type CorrelationEvent struct {
+	ID            string     `json:"id" gorm:"primaryKey;type:varchar(36)" doc:"Unique event identifier"`
+	CorrelationID string     `json:"correlationId" gorm:"not null;index" doc:"Correlation ID of the prediction the run followed"`
+	Kind          string     `json:"kind" doc:"schedule, or the device event type"`
+	UserID        string     `json:"userId,omitempty" doc:"User the schedule was recommended to"`
+	DeviceID      string     `json:"deviceId,omitempty" doc:"Water heater the event concerns"`
+	HeatingTime   *float64   `json:"heatingTime,omitempty" unit:"min" doc:"Heating time scheduled or started"`
+	Temperature   *float64   `json:"temperature,omitempty" unit:"°C" doc:"Water temperature a sensor reported"`
+	State         string     `json:"state,omitempty" doc:"Device state after the event"`
+	Start         *time.Time `json:"start,omitempty" doc:"When the recommended run starts"`
+	ReadyAt       *time.Time `json:"readyAt,omitempty" doc:"When the water is planned or predicted to be ready"`
+	At            time.Time  `json:"at" gorm:"index" doc:"When the event happened"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an event
+func (e *CorrelationEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the CorrelationEvent model
+func (CorrelationEvent) TableName() string {
+	return "correlation_events"
+}
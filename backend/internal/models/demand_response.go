@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// What a demand-response event does to heating
+const (
+	DemandResponseCap   = "cap"   // each heating run is held to MaxMinutes
+	DemandResponseShift = "shift" // heating waits until the event ends
+)
+
+// DemandResponseEvent is a utility's request to use less power for a while. Heating returns to
+// normal at EndsAt on its own; EndedAt is set once users were told it has.
+type DemandResponseEvent struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ExternalID string     `json:"externalId,omitempty" gorm:"index"` // the utility's ID, so a repeated signal updates the event
+	Action     string     `json:"action" gorm:"not null"`
+	MaxMinutes float64    `json:"maxMinutes,omitempty"` // the cap, for DemandResponseCap
+	Reason     string     `json:"reason,omitempty"`
+	StartsAt   time.Time  `json:"startsAt" gorm:"not null;index"`
+	EndsAt     time.Time  `json:"endsAt" gorm:"not null;index"`
+	EndedAt    *time.Time `json:"endedAt,omitempty"` // when heating resumed and users were told
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an event
+func (e *DemandResponseEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the DemandResponseEvent model
+func (DemandResponseEvent) TableName() string {
+	return "demand_response_events"
+}
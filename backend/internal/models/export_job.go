@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Export job lifecycle states
+const (
+	ExportJobPending   = "pending"
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+)
+
+// ExportJob tracks an asynchronous history export for a user
+type ExportJob struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID      string     `json:"userId" gorm:"not null;index"`
+	Status      string     `json:"status" gorm:"not null;default:'pending'"`
+	FilePath    string     `json:"-"`
+	RecordCount int        `json:"recordCount"`
+	Error       string     `json:"error,omitempty"`
+	Locale      string     `json:"locale,omitempty"`   // how dates and numbers are written, e.g. de-DE
+	Timezone    string     `json:"timezone,omitempty"` // IANA zone dates are written in; empty for server time
+	CreatedAt   time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt" gorm:"index"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a job
+func (j *ExportJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the ExportJob model
+func (ExportJob) TableName() string {
+	return "export_jobs"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Feedback prompt states
+const (
+	FeedbackPromptPending   = "pending"
+	FeedbackPromptAnswered  = "answered"
+	FeedbackPromptDismissed = "dismissed"
+)
+
+// FeedbackPrompt is a reminder to give feedback for a scheduled shower that passed without any
+type FeedbackPrompt struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID     string     `json:"userId" gorm:"not null;index"`
+	TemplateID string     `json:"templateId" gorm:"not null;uniqueIndex:idx_feedback_prompts_template_date"`
+	Name       string     `json:"name"` // the template's name when the prompt was made
+	DeviceID   string     `json:"deviceId,omitempty"`
+	Date       string     `json:"date" gorm:"not null;uniqueIndex:idx_feedback_prompts_template_date"` // YYYY-MM-DD of the shower
+	ShowerAt   time.Time  `json:"showerAt"`
+	Status     string     `json:"status" gorm:"not null;default:'pending';index"`
+	NotifyAt   time.Time  `json:"notifyAt" gorm:"index"` // when the reminder is due, outside quiet hours
+	NotifiedAt *time.Time `json:"notifiedAt,omitempty"`  // when it was sent, or suppressed by the daily cap
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a prompt
+func (p *FeedbackPrompt) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the FeedbackPrompt model
+func (FeedbackPrompt) TableName() string {
+	return "feedback_prompts"
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// HealthProbe is a single-row table the database health monitor writes to when
+// checking whether the database has become writable again
+type HealthProbe struct {
+	ID        int       `gorm:"primaryKey;autoIncrement:false"`
+	CheckedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for the HealthProbe model
+func (HealthProbe) TableName() string {
+	return "health_probes"
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Roles a user can hold in a household. A household is the user account its member profiles,
+// devices and schedules belong to; the account itself is always its owner.
+const (
+	HouseholdRoleOwner  = "owner"  // can invite others, and remove them
+	HouseholdRoleMember = "member" // shares the household's devices and schedules
+)
+
+// HouseholdRoles lists the roles an invite can assign
+var HouseholdRoles = []string{HouseholdRoleOwner, HouseholdRoleMember}
+
+// HouseholdMembership places a user account in another account's household. Accounts without
+// one are a household of their own.
+type HouseholdMembership struct {
+	UserID      string    `json:"userId" gorm:"primaryKey;type:varchar(64)"`
+	HouseholdID string    `json:"householdId" gorm:"not null;index"` // the household account's user ID
+	Role        string    `json:"role" gorm:"not null"`
+	InviteID    string    `json:"inviteId,omitempty"`
+	JoinedAt    time.Time `json:"joinedAt" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the HouseholdMembership model
+func (HouseholdMembership) TableName() string {
+	return "household_memberships"
+}
+
+// HouseholdInvite lets whoever holds its token join a household once. Only a hash of the token
+// is stored.
+type HouseholdInvite struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	HouseholdID string     `json:"householdId" gorm:"not null;index"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex"` // sha256 of the token
+	Email       string     `json:"email,omitempty"`               // only a user with this email may accept; anyone when empty
+	Role        string     `json:"role" gorm:"not null"`
+	CreatedBy   string     `json:"createdBy" gorm:"not null"`
+	ExpiresAt   time.Time  `json:"expiresAt" gorm:"not null"`
+	AcceptedBy  string     `json:"acceptedBy,omitempty"`
+	AcceptedAt  *time.Time `json:"acceptedAt,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an invite
+func (i *HouseholdInvite) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Pending reports whether the invite can still be accepted at the given time
+func (i HouseholdInvite) Pending(at time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && at.Before(i.ExpiresAt)
+}
+
+// TableName specifies the table name for the HouseholdInvite model
+func (HouseholdInvite) TableName() string {
+	return "household_invites"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImportMapping is a confirmed mapping from the columns of a user's CSV files to record fields,
+// kept so later files laid out the same way import without the wizard
+type ImportMapping struct {
+	ID        string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string          `json:"userId" gorm:"not null;uniqueIndex:idx_import_mapping_name"`
+	Name      string          `json:"name" gorm:"not null;uniqueIndex:idx_import_mapping_name"`
+	Mapping   json.RawMessage `json:"mapping" gorm:"type:text;not null"` // separator, date format and column per field with its unit
+	CreatedAt time.Time       `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time       `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a mapping
+func (m *ImportMapping) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the ImportMapping model
+func (ImportMapping) TableName() string {
+	return "import_mappings"
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Instance identifies this deployment. The table holds a single row created on first start.
+type Instance struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the Instance model
+func (Instance) TableName() string {
+	return "instance"
+}
+
+// SyncState remembers how far records have been exchanged with a peer instance. Each side's
+// watermark is taken from the clock of the instance that exported the data.
+type SyncState struct {
+	Peer         string    `json:"peer" gorm:"primaryKey"`
+	UserID       string    `json:"userId" gorm:"primaryKey"` // empty when every user is synced
+	LastPulledAt time.Time `json:"lastPulledAt"`
+	LastPushedAt time.Time `json:"lastPushedAt"`
+	UpdatedAt    time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the SyncState model
+func (SyncState) TableName() string {
+	return "sync_states"
+}
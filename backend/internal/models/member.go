@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Member is a named household profile under a user account. Members have no login of
+// their own; they only attribute feedback and personalise predictions.
+type Member struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)" doc:"Unique member identifier"`
+	UserID    string    `json:"userId" gorm:"not null;uniqueIndex:idx_members_user_name" doc:"Household account the member belongs to"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_members_user_name" doc:"Name of the member, unique per household"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime" doc:"When the member was added"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime" doc:"When the member was last changed"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a member
+func (m *Member) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the Member model
+func (Member) TableName() string {
+	return "members"
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification outcomes
+const (
+	NotificationSent       = "sent"
+	NotificationSuppressed = "suppressed"
+	NotificationFailed     = "failed"
+)
+
+// Notification kinds, which routes pick notifications by
+const (
+	NotificationFeedbackPrompt   = "feedback_prompt"
+	NotificationBoilerEfficiency = "boiler_efficiency"
+	NotificationHouseholdInvite  = "household_invite"
+	NotificationHeatingStarted   = "heating_started"
+	NotificationDemandResponse   = "demand_response"
+)
+
+// NotificationKinds lists every notification kind
+var NotificationKinds = []string{
+	NotificationFeedbackPrompt,
+	NotificationBoilerEfficiency,
+	NotificationHouseholdInvite,
+	NotificationHeatingStarted,
+	NotificationDemandResponse,
+}
+
+// OptInNotification reports whether notifications of kind only go out over routes that name
+// the kind, as they come too often to send to everyone
+func OptInNotification(kind string) bool {
+	return kind == NotificationHeatingStarted
+}
+
+// Reasons a notification was suppressed
+const (
+	SuppressedQuietHours = "quiet_hours"
+	SuppressedDailyLimit = "daily_limit"
+	SuppressedNoChannel  = "no_channel" // none of the user's routes takes the kind, and there is no default webhook
+)
+
+// Notification is the log entry for one notification the server tried to send a user
+type Notification struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `json:"userId" gorm:"not null;index"`
+	Kind      string    `json:"kind" gorm:"not null"` // e.g. feedback_prompt
+	Message   string    `json:"message"`
+	Status    string    `json:"status" gorm:"not null;index"`
+	Reason    string    `json:"reason,omitempty"`   // why it was suppressed or failed
+	Channels  string    `json:"channels,omitempty"` // comma-separated channels it was delivered over
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a log entry
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the Notification model
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationRoute sends a user's notifications of some kinds over a channel to a target,
+// e.g. feedback prompts to a Telegram chat
+type NotificationRoute struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `json:"userId" gorm:"not null;index"`
+	Channel   string    `json:"channel" gorm:"not null"` // a registered notify channel, e.g. telegram
+	Target    string    `json:"target" gorm:"not null"`  // where the channel delivers, e.g. a chat ID
+	Token     string    `json:"-"`                       // credential for the target, e.g. an ntfy access token; never returned
+	Kinds     string    `json:"kinds,omitempty"`         // comma-separated notification kinds; empty for all but the opt-in ones
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a route
+func (r *NotificationRoute) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the NotificationRoute model
+func (NotificationRoute) TableName() string {
+	return "notification_routes"
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Operation lifecycle states
+const (
+	OperationRunning   = "running"
+	OperationCompleted = "completed"
+	OperationFailed    = "failed"
+	OperationCanceled  = "canceled"
+)
+
+// Kinds of long-running admin operations
+const (
+	OperationRescore = "rescore" // replays feedback through the predictor; Target is the re-score job
+	OperationBackup  = "backup"  // copies the database; Target is the backup file
+	OperationCompact = "compact" // rebuilds the database file to reclaim the space of deleted rows
+	OperationArchive = "archive" // excludes records past the influence horizon from training
+)
+
+// Operation tracks a long-running admin task that runs in the background
+type Operation struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Kind        string     `json:"kind" gorm:"not null;index"`
+	RequestedBy string     `json:"requestedBy" gorm:"not null"`
+	Status      string     `json:"status" gorm:"not null;default:'running';index"`
+	Progress    float64    `json:"progress"`         // percent done
+	Target      string     `json:"target,omitempty"` // what the operation works on or produced
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt" gorm:"autoCreateTime;index"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an operation
+func (o *Operation) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the Operation model
+func (Operation) TableName() string {
+	return "operations"
+}
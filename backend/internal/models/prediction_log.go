@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PredictionLog records one heating time served by /api/calculate, with the feedback that
+// followed it once the user gives some
+type PredictionLog struct {
+	ID                 string    `json:"id" gorm:"primaryKey;type:varchar(36)" doc:"Unique log entry identifier"`
+	UserID             string    `json:"userId" gorm:"not null;index" doc:"User the prediction was served to"`
+	MemberID           string    `json:"memberId,omitempty" doc:"Household member the prediction was for"`
+	DeviceID           string    `json:"deviceId,omitempty" doc:"Water heater the prediction was for"`
+	Duration           float64   `json:"duration" unit:"min" range:"1-60" doc:"Requested shower duration"`
+	Temperature        float64   `json:"temperature" unit:"°C" range:"-50-50" doc:"Outdoor temperature the prediction used"`
+	TemperatureSource  string    `json:"temperatureSource,omitempty" enum:"temperatureSource" doc:"Where the outdoor temperature came from"`
+	InletTemperature   *float64  `json:"inletTemperature,omitempty" unit:"°C" range:"0-40" doc:"Cold-water inlet temperature the prediction used"`
+	ModelVersion       string    `json:"modelVersion" doc:"Predictor version that served the prediction"`
+	ModelRevision      int       `json:"modelRevision,omitempty" doc:"Behaviour revision of that predictor version; 0 for predictions logged before revisions were tracked"`
+	HeatingTime        float64   `json:"heatingTime" unit:"min" doc:"Predicted heating time"`
+	Stale              bool      `json:"stale,omitempty" doc:"Served from the cache after the predictor timed out"`
+	LargeAdjustment    bool      `json:"largeAdjustment,omitempty" doc:"The client allowed this prediction past the step cap"`
+	Held               bool      `json:"held,omitempty" gorm:"index" doc:"The model's answer changed too much since the last one without new feedback, so the last one was served"`
+	ModelHeatingTime   *float64  `json:"modelHeatingTime,omitempty" unit:"min" doc:"What the model answered when the last value was held"`
+	GuardedHeatingTime *float64  `json:"guardedHeatingTime,omitempty" unit:"min" doc:"Heating time the output guard passed on, before demand-response caps, deployment hooks and the safety floor; later predictions are compared with it"`
+	Seed               int64     `json:"seed,omitempty" doc:"Seed of the prediction's random generator, to replay its randomised features"`
+	RecordID           string    `json:"recordId,omitempty" gorm:"index" doc:"Feedback record given for this prediction"`
+	CorrelationID      string    `json:"correlationId,omitempty" gorm:"index" doc:"Correlation ID the request came with or was given, tying the prediction to the heating run that followed"`
+	CreatedAt          time.Time `json:"createdAt" gorm:"autoCreateTime;index" doc:"When the prediction was served"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a log entry
+func (p *PredictionLog) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the PredictionLog model
+func (PredictionLog) TableName() string {
+	return "prediction_logs"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Preset is a named shower profile a user can predict and give feedback against
+type Preset struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(36)" doc:"Unique preset identifier"`
+	UserID      string    `json:"userId" gorm:"not null;uniqueIndex:idx_presets_user_name" doc:"Owner of the preset"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex:idx_presets_user_name" doc:"Name of the preset, unique per user"`
+	Duration    float64   `json:"duration" gorm:"not null" unit:"min" range:"1-60" doc:"Typical shower duration"`
+	Temperature *float64  `json:"temperature,omitempty" unit:"°C" range:"-50-50" doc:"Typical outdoor temperature, used when none is given"`
+	Usage       string    `json:"usage,omitempty" doc:"Free-text note on when the preset is used"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime" doc:"When the preset was created"`
+	UpdatedAt   time.Time `json:"updatedAt" gorm:"autoUpdateTime" doc:"When the preset was last changed"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a preset
+func (p *Preset) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the Preset model
+func (Preset) TableName() string {
+	return "presets"
+}
@@ -7,27 +7,146 @@ import (
 	"gorm.io/gorm"
 )
 
+// Record sources describe how a record entered the system
+const (
+	RecordSourceManual        = "manual"         // entered through the UI
+	RecordSourceCSVImport     = "csv_import"     // imported from a CSV file
+	RecordSourceSensor        = "sensor"         // reported by a boiler or shower sensor
+	RecordSourceHomeAssistant = "home_assistant" // imported from Home Assistant
+	RecordSourceSimulator     = "simulator"      // generated by the simulator
+)
+
+// RecordSources lists every valid record source
+var RecordSources = []string{
+	RecordSourceManual,
+	RecordSourceCSVImport,
+	RecordSourceSensor,
+	RecordSourceHomeAssistant,
+	RecordSourceSimulator,
+}
+
+// IsValidRecordSource reports whether source is one of RecordSources
+func IsValidRecordSource(source string) bool {
+	for _, s := range RecordSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsReview reports whether records from source are automated guesses the user has to
+// confirm before they're trusted for training
+func NeedsReview(source string) bool {
+	return source == RecordSourceSensor || source == RecordSourceHomeAssistant
+}
+
+// Review statuses track whether an automatically created record has been checked by the user
+const (
+	ReviewStatusPending   = "pending"   // waiting for the user, excluded from training
+	ReviewStatusConfirmed = "confirmed" // accepted as recorded
+	ReviewStatusCorrected = "corrected" // accepted after the user fixed some values
+	ReviewStatusInferred  = "inferred"  // not reviewed, but the satisfaction was inferred from how the user reacted; trained on at a lower weight
+)
+
+// AwaitingReview reports whether a record with the review status still waits for the user
+func AwaitingReview(status string) bool {
+	return status == ReviewStatusPending || status == ReviewStatusInferred
+}
+
+// The satisfaction scale: 50 means the water was perfect, lower means it was too cold and
+// higher too hot
+const (
+	SatisfactionMin     = 1
+	SatisfactionMax     = 100
+	SatisfactionPerfect = 50
+)
+
+// Temperature sources describe where a record's average temperature came from
+const (
+	TemperatureSourceManual        = "manual"         // typed in by the user
+	TemperatureSourceWeather       = "weather"        // current outdoor temperature from a weather API
+	TemperatureSourceMQTT          = "mqtt"           // latest reading published by an MQTT sensor
+	TemperatureSourceHomeAssistant = "home_assistant" // state of a Home Assistant temperature entity
+)
+
+// TemperatureSources lists every valid temperature source, in the default priority order
+var TemperatureSources = []string{
+	TemperatureSourceManual,
+	TemperatureSourceHomeAssistant,
+	TemperatureSourceMQTT,
+	TemperatureSourceWeather,
+}
+
+// IsValidTemperatureSource reports whether source is one of TemperatureSources
+func IsValidTemperatureSource(source string) bool {
+	for _, s := range TemperatureSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
 // DailyRecord represents a daily heating record with user feedback
 type DailyRecord struct {
-	ID                 string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
-	UserID             string    `json:"userId" gorm:"not null;default:'global';index"`
-	Date               time.Time `json:"date" gorm:"not null"`
-	ShowerDuration     float64   `json:"showerDuration" gorm:"not null"`
-	AverageTemperature float64   `json:"averageTemperature" gorm:"not null"`
-	HeatingTime        float64   `json:"heatingTime" gorm:"not null"`
-	Satisfaction       float64   `json:"satisfaction" gorm:"not null"`
-	CreatedAt          time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt          time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
-}
-
-// BeforeCreate is a GORM hook that generates a UUID before creating a record
+	ID                   string    `json:"id" gorm:"primaryKey;type:varchar(36)" doc:"Unique record identifier"`
+	UserID               string    `json:"userId" gorm:"not null;default:'global';index" doc:"Owner of the record"`
+	Date                 time.Time `json:"date" gorm:"not null" doc:"When the shower happened"`
+	ShowerDuration       float64   `json:"showerDuration" gorm:"not null" unit:"min" range:">0" doc:"How long the shower lasted"`
+	AverageTemperature   float64   `json:"averageTemperature" gorm:"not null" unit:"°C" range:"-50-50" doc:"Outdoor temperature around the shower"`
+	InletTemperature     *float64  `json:"inletTemperature,omitempty" unit:"°C" range:"0-40" doc:"Measured cold-water inlet temperature"`
+	HeatingTime          float64   `json:"heatingTime" gorm:"not null" unit:"min" range:">0" doc:"How long the water was heated"`
+	Satisfaction         float64   `json:"satisfaction" gorm:"not null" range:"1-100" doc:"How the water felt: 50 is perfect, lower was too cold, higher too hot"`
+	FeedbackConfidence   float64   `json:"feedbackConfidence" gorm:"not null;default:1" range:"0-1" doc:"How sure the satisfaction is: 1 for a rating, less for feedback inferred from the user's behaviour"`
+	ExcludedFromTraining bool      `json:"excludedFromTraining" gorm:"not null;default:false;index" doc:"Kept in history but hidden from the predictors"`
+	PresetID             string    `json:"presetId,omitempty" gorm:"index" doc:"Preset the shower was logged with"`
+	MemberID             string    `json:"memberId,omitempty" gorm:"index" doc:"Household member the feedback belongs to"`
+	DeviceID             string    `json:"deviceId,omitempty" gorm:"index" doc:"Water heater the shower used"`
+	Source               string    `json:"source" gorm:"not null;default:'manual';index" enum:"recordSource" doc:"How the record was created"`
+	TemperatureSource    string    `json:"temperatureSource" gorm:"not null;default:'manual'" enum:"temperatureSource" doc:"Where the outdoor temperature came from"`
+	ReviewStatus         string    `json:"reviewStatus,omitempty" gorm:"index" enum:"reviewStatus" doc:"Review state of records that needed the user's confirmation"`
+	ModelVersion         string    `json:"modelVersion,omitempty" gorm:"index" doc:"Predictor version serving the user when the feedback was given"`
+	ModelRevision        int       `json:"modelRevision,omitempty" doc:"Behaviour revision of that predictor version; 0 for records stored before revisions were tracked"`
+	CorrelationID        string    `json:"correlationId,omitempty" gorm:"index" doc:"Correlation ID the feedback was sent with, tying it to the prediction served under the same ID"`
+	CreatedAt            time.Time `json:"createdAt" gorm:"autoCreateTime" doc:"When the record was stored"`
+	UpdatedAt            time.Time `json:"updatedAt" gorm:"autoUpdateTime" doc:"When the record was last changed"`
+
+	Checksum         string `json:"-"` // HMAC of the signed fields, see ComputeChecksum
+	ChecksumMismatch bool   `json:"checksumMismatch,omitempty" gorm:"-" doc:"The record was changed outside the API since it was stored"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID and signs the record before creating it
 func (r *DailyRecord) BeforeCreate(tx *gorm.DB) error {
 	if r.ID == "" {
 		r.ID = uuid.New().String()
 	}
+	if r.Source == "" {
+		r.Source = RecordSourceManual
+	}
+	if r.TemperatureSource == "" {
+		r.TemperatureSource = TemperatureSourceManual
+	}
+	// Automated records wait for the user before the predictors learn from them
+	if r.ReviewStatus == "" && NeedsReview(r.Source) {
+		r.ReviewStatus = ReviewStatusPending
+	}
+	if r.ReviewStatus == ReviewStatusPending {
+		r.ExcludedFromTraining = true
+	}
+	r.sign()
 	return nil
 }
 
+// Confidence returns the record's feedback confidence; records built without one, such as
+// those saved before it was tracked, are fully trusted
+func (r DailyRecord) Confidence() float64 {
+	if r.FeedbackConfidence <= 0 {
+		return 1
+	}
+	return r.FeedbackConfidence
+}
+
 // TableName specifies the table name for the DailyRecord model
 func (DailyRecord) TableName() string {
 	return "daily_records"
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecordRevision keeps a record's values from before an edit, so edits to training data can
+// be reviewed and undone
+type RecordRevision struct {
+	ID       string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	RecordID string `json:"recordId" gorm:"not null;index"`
+	Editor   string `json:"editor"` // who made the edit that replaced these values
+
+	// The record's values before the edit
+	Date               time.Time `json:"date"`
+	ShowerDuration     float64   `json:"showerDuration"`
+	AverageTemperature float64   `json:"averageTemperature"`
+	InletTemperature   *float64  `json:"inletTemperature,omitempty"`
+	HeatingTime        float64   `json:"heatingTime"`
+	Satisfaction       float64   `json:"satisfaction"`
+	PresetID           string    `json:"presetId,omitempty"`
+	MemberID           string    `json:"memberId,omitempty"`
+	DeviceID           string    `json:"deviceId,omitempty"`
+	Source             string    `json:"source"`
+	TemperatureSource  string    `json:"temperatureSource"`
+
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index"` // when the edit was made
+}
+
+// NewRecordRevision captures a record's current values before editor changes it
+func NewRecordRevision(record DailyRecord, editor string) RecordRevision {
+	return RecordRevision{
+		RecordID:           record.ID,
+		Editor:             editor,
+		Date:               record.Date,
+		ShowerDuration:     record.ShowerDuration,
+		AverageTemperature: record.AverageTemperature,
+		InletTemperature:   record.InletTemperature,
+		HeatingTime:        record.HeatingTime,
+		Satisfaction:       record.Satisfaction,
+		PresetID:           record.PresetID,
+		MemberID:           record.MemberID,
+		DeviceID:           record.DeviceID,
+		Source:             record.Source,
+		TemperatureSource:  record.TemperatureSource,
+	}
+}
+
+// ApplyTo restores the revision's values onto a record
+func (r RecordRevision) ApplyTo(record *DailyRecord) {
+	record.Date = r.Date
+	record.ShowerDuration = r.ShowerDuration
+	record.AverageTemperature = r.AverageTemperature
+	record.InletTemperature = r.InletTemperature
+	record.HeatingTime = r.HeatingTime
+	record.Satisfaction = r.Satisfaction
+	record.PresetID = r.PresetID
+	record.MemberID = r.MemberID
+	record.DeviceID = r.DeviceID
+	record.Source = r.Source
+	record.TemperatureSource = r.TemperatureSource
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a revision
+func (r *RecordRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the RecordRevision model
+func (RecordRevision) TableName() string {
+	return "record_revisions"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RecordTombstone remembers a deleted record for a retention window, so clients syncing their
+// history incrementally and other instances learn to drop it too
+type RecordTombstone struct {
+	RecordID  string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `json:"userId" gorm:"not null;index:idx_record_tombstones_user_deleted"`
+	DeletedAt time.Time `json:"deletedAt" gorm:"not null;index:idx_record_tombstones_user_deleted"`
+	Actor     string    `json:"actor,omitempty"` // who deleted the record
+}
+
+// TableName specifies the table name for the RecordTombstone model
+func (RecordTombstone) TableName() string {
+	return "record_tombstones"
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SavedReport is a custom report query a user kept to run again or subscribe to
+type SavedReport struct {
+	ID        string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string          `json:"userId" gorm:"not null;index"`
+	Name      string          `json:"name" gorm:"not null"`
+	Query     json.RawMessage `json:"query" gorm:"type:text;not null"` // the report builder's metrics, groupings and filters
+	CreatedAt time.Time       `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time       `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a saved report
+func (r *SavedReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the SavedReport model
+func (SavedReport) TableName() string {
+	return "saved_reports"
+}
+
+// Channels a subscribed report is delivered through
+const (
+	ReportChannelEmail   = "email"
+	ReportChannelWebhook = "webhook"
+)
+
+// Formats a subscribed report is delivered in
+const (
+	ReportFormatCSV = "csv"
+	ReportFormatPDF = "pdf"
+)
+
+// ReportSubscription delivers a saved report whenever its cron schedule fires
+type ReportSubscription struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string     `json:"userId" gorm:"not null;index"`
+	ReportID  string     `json:"reportId" gorm:"not null;index"`
+	Cron      string     `json:"cron" gorm:"not null"`            // five-field cron expression
+	Timezone  string     `json:"timezone,omitempty"`              // IANA zone the schedule runs in; empty for server time
+	Channel   string     `json:"channel" gorm:"not null"`         // email or webhook
+	Target    string     `json:"target" gorm:"not null"`          // email address or webhook URL
+	Format    string     `json:"format" gorm:"not null"`          // csv or pdf
+	NextRunAt time.Time  `json:"nextRunAt" gorm:"not null;index"` // when the report is next delivered
+	LastRunAt *time.Time `json:"lastRunAt,omitempty"`
+	LastError string     `json:"lastError,omitempty"` // why the last delivery failed; empty after a success
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a subscription
+func (s *ReportSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the ReportSubscription model
+func (ReportSubscription) TableName() string {
+	return "report_subscriptions"
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Re-score job lifecycle states
+const (
+	RescoreJobPending   = "pending"
+	RescoreJobRunning   = "running"
+	RescoreJobCompleted = "completed"
+	RescoreJobFailed    = "failed"
+	RescoreJobCanceled  = "canceled"
+)
+
+// RescoreJob replays recent feedback through the current predictor and reports how its
+// predictions compare with the heating times that were actually used
+type RescoreJob struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	RequestedBy  string     `json:"requestedBy" gorm:"not null"`
+	Predictor    string     `json:"predictor" gorm:"not null"`
+	Since        time.Time  `json:"since" gorm:"not null"`
+	Status       string     `json:"status" gorm:"not null;default:'pending';index"`
+	OperationID  string     `json:"operationId,omitempty" gorm:"index"` // the operation running the job, for progress and cancellation
+	RecordCount  int        `json:"recordCount"`
+	Scored       int        `json:"scored"`
+	Improved     int        `json:"improved"`               // records the new predictor lands closer to the ideal time on
+	Worsened     int        `json:"worsened"`               // records it lands further from the ideal time on
+	BaselineMAE  *float64   `json:"baselineMae,omitempty"`  // mean absolute error of the heating times used, in minutes
+	CandidateMAE *float64   `json:"candidateMae,omitempty"` // mean absolute error of the replayed predictions, in minutes
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime;index"`
+	CompletedAt  *time.Time `json:"completedAt,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a job
+func (j *RescoreJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the RescoreJob model
+func (RescoreJob) TableName() string {
+	return "rescore_jobs"
+}
+
+// PredictionAudit stores what a predictor would have recommended for a past record
+type PredictionAudit struct {
+	ID                   string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	JobID                string    `json:"jobId" gorm:"not null;index"`
+	RecordID             string    `json:"recordId" gorm:"not null;index"`
+	UserID               string    `json:"userId" gorm:"not null"`
+	Predictor            string    `json:"predictor" gorm:"not null"`
+	UsedHeatingTime      float64   `json:"usedHeatingTime"`      // what the user actually heated for
+	PredictedHeatingTime float64   `json:"predictedHeatingTime"` // what the predictor recommends given the history at the time
+	TargetHeatingTime    float64   `json:"targetHeatingTime"`    // ideal time implied by the record's satisfaction
+	CreatedAt            time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an entry
+func (a *PredictionAudit) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the PredictionAudit model
+func (PredictionAudit) TableName() string {
+	return "prediction_audits"
+}
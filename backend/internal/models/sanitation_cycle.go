@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SanitationCycle is one run of a device's legionella cycle, the tank heated well past comfort
+// to kill bacteria. Kept apart from shower records so the comfort model never learns from it,
+// and used to leave out showers taken in the scalding water after it.
+type SanitationCycle struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	DeviceID  string    `json:"deviceId" gorm:"not null;index:idx_sanitation_cycles_device_started"`
+	StartedAt time.Time `json:"startedAt" gorm:"not null;index:idx_sanitation_cycles_device_started"`
+	EndsAt    time.Time `json:"endsAt" gorm:"not null"`
+	Minutes   float64   `json:"minutes"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a cycle
+func (c *SanitationCycle) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the SanitationCycle model
+func (SanitationCycle) TableName() string {
+	return "sanitation_cycles"
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScheduleOverride records a user snoozing or skipping one scheduled heating run. Skips are
+// kept so feedback from a day the user chose not to heat isn't learned from.
+type ScheduleOverride struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID       string     `json:"userId" gorm:"not null;index"`
+	TemplateID   string     `json:"templateId" gorm:"not null;uniqueIndex:idx_schedule_overrides_template_date"`
+	Date         string     `json:"date" gorm:"not null;uniqueIndex:idx_schedule_overrides_template_date"` // YYYY-MM-DD of the shower
+	Skipped      bool       `json:"skipped" gorm:"not null;default:false"`
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty"` // heating doesn't start before this
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an override
+func (o *ScheduleOverride) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the ScheduleOverride model
+func (ScheduleOverride) TableName() string {
+	return "schedule_overrides"
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"heat-logger/pkg/cron"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScheduleTemplate is a recurring shower time, e.g. weekdays at 06:45 and weekends at 09:30,
+// that the heating scheduler plans for without a one-off entry per day
+type ScheduleTemplate struct {
+	ID          string              `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID      string              `json:"userId" gorm:"not null;uniqueIndex:idx_schedule_templates_user_name"`
+	Name        string              `json:"name" gorm:"not null;uniqueIndex:idx_schedule_templates_user_name"`
+	DeviceID    string              `json:"deviceId,omitempty"`
+	WeekdayTime string              `json:"weekdayTime,omitempty"` // HH:MM the water is needed Monday to Friday; empty for none
+	WeekendTime string              `json:"weekendTime,omitempty"` // HH:MM on Saturday and Sunday
+	Cron        string              `json:"cron,omitempty"`        // e.g. "0 6 * * MON-FRI", instead of the weekday and weekend times
+	Timezone    string              `json:"timezone,omitempty"`    // IANA name the times are in; the server's when empty
+	Exceptions  []ScheduleException `json:"exceptions" gorm:"foreignKey:TemplateID"`
+	CreatedAt   time.Time           `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time           `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// ScheduleException overrides a template on one date
+type ScheduleException struct {
+	ID         string `json:"-" gorm:"primaryKey;type:varchar(36)"`
+	TemplateID string `json:"-" gorm:"not null;index"`
+	Date       string `json:"date" gorm:"not null"` // YYYY-MM-DD
+	Time       string `json:"time,omitempty"`       // HH:MM instead of the usual time; empty skips the day
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating a template
+func (t *ScheduleTemplate) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that generates a UUID before creating an exception
+func (e *ScheduleException) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Location returns the template's time zone, or fallback when it has none or it is unknown
+func (t ScheduleTemplate) Location(fallback *time.Location) *time.Location {
+	if t.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// ReadyAt returns when the water is needed on day's date, in the template's time zone,
+// reporting false when the template has no shower that day. A cron template's first time of
+// the day counts.
+func (t ScheduleTemplate) ReadyAt(day time.Time) (time.Time, bool) {
+	loc := t.Location(day.Location())
+	date := day.Format("2006-01-02")
+	for _, exception := range t.Exceptions {
+		if exception.Date == date {
+			return readyAtClock(date, exception.Time, loc)
+		}
+	}
+
+	if t.Cron != "" {
+		schedule, err := cron.Parse(t.Cron)
+		if err != nil {
+			return time.Time{}, false
+		}
+		midnight, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return time.Time{}, false
+		}
+		at := schedule.Next(midnight.Add(-time.Second))
+		if at.IsZero() || at.Format("2006-01-02") != date {
+			return time.Time{}, false
+		}
+		return at, true
+	}
+
+	clock := t.WeekdayTime
+	if weekday := day.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		clock = t.WeekendTime
+	}
+	return readyAtClock(date, clock, loc)
+}
+
+// readyAtClock returns the HH:MM clock time on date, reporting false for an empty clock
+func readyAtClock(date, clock string, loc *time.Location) (time.Time, bool) {
+	if clock == "" {
+		return time.Time{}, false
+	}
+	at, err := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+// TableName specifies the table name for the ScheduleTemplate model
+func (ScheduleTemplate) TableName() string {
+	return "schedule_templates"
+}
+
+// TableName specifies the table name for the ScheduleException model
+func (ScheduleException) TableName() string {
+	return "schedule_exceptions"
+}
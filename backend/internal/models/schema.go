@@ -0,0 +1,91 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldSchema describes one JSON field of a model that is exported or imported. It is built
+// from the struct's json tag and its doc, unit, range and enum tags.
+type FieldSchema struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"` // string, number, integer, boolean or timestamp
+	Nullable bool     `json:"nullable,omitempty"`
+	Unit     string   `json:"unit,omitempty"`
+	Range    string   `json:"range,omitempty"`  // e.g. "1-100" or ">0"
+	Values   []string `json:"values,omitempty"` // the allowed values of an enumerated string
+	Meaning  string   `json:"meaning"`
+}
+
+// schemaEnums holds the allowed values named by enum tags
+var schemaEnums = map[string][]string{
+	"recordSource":      RecordSources,
+	"temperatureSource": TemperatureSources,
+	"reviewStatus":      {ReviewStatusPending, ReviewStatusConfirmed, ReviewStatusCorrected, ReviewStatusInferred},
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DescribeFields lists the JSON fields of the struct v in declaration order. Fields of embedded
+// structs are listed in place and fields tagged json:"-" are left out.
+func DescribeFields(v interface{}) []FieldSchema {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var fields []FieldSchema
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, DescribeFields(reflect.New(field.Type).Interface())...)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := FieldSchema{
+			Name:    name,
+			Unit:    field.Tag.Get("unit"),
+			Range:   field.Tag.Get("range"),
+			Values:  schemaEnums[field.Tag.Get("enum")],
+			Meaning: field.Tag.Get("doc"),
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			schema.Nullable = true
+			fieldType = fieldType.Elem()
+		}
+		schema.Type = schemaType(fieldType)
+		fields = append(fields, schema)
+	}
+	return fields
+}
+
+// schemaType names the JSON type a Go type is encoded as
+func schemaType(t reflect.Type) string {
+	if t == timeType {
+		return "timestamp"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "string"
+	}
+}
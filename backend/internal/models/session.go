@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is a browser login. Only a hash of the session token is stored.
+type Session struct {
+	ID        string    `json:"-" gorm:"primaryKey;type:varchar(64)"` // sha256 of the token
+	UserID    string    `json:"userId" gorm:"not null;index"`
+	Role      string    `json:"role" gorm:"not null"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"not null;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the Session model
+func (Session) TableName() string {
+	return "sessions"
+}
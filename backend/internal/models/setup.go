@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Setup tracks the first-run setup of a fresh install. The table holds a single row.
+type Setup struct {
+	ID             int        `json:"-" gorm:"primaryKey"`
+	AdminUserID    string     `json:"adminUserId,omitempty"`
+	AdminTokenHash string     `json:"-"` // sha256 of the admin token handed out by the admin step
+	AdminAt        *time.Time `json:"adminAt,omitempty"`
+
+	DatabaseBackend string     `json:"databaseBackend,omitempty"`
+	DatabaseAt      *time.Time `json:"databaseAt,omitempty"`
+
+	// Latitude and Longitude locate the house for the weather provider; nil when it was skipped
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+	LocationAt *time.Time `json:"locationAt,omitempty"`
+
+	DeviceUserID string     `json:"deviceUserId,omitempty"`
+	DeviceID     string     `json:"deviceId,omitempty"`
+	DeviceAt     *time.Time `json:"deviceAt,omitempty"`
+
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// AutoCompleted is set when the install already had data the first time the wizard ran
+	AutoCompleted bool `json:"autoCompleted,omitempty"`
+}
+
+// TableName specifies the table name for the Setup model
+func (Setup) TableName() string {
+	return "setup"
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Roles a user can hold. Roles are assigned from identity-provider groups at login.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// User holds account-level state for a user ID seen in daily records
+type User struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(64)"`
+	Role         string     `json:"role" gorm:"not null;default:'user'"`
+	Email        string     `json:"email,omitempty"`
+	Name         string     `json:"name,omitempty"`
+	Disabled     bool       `json:"disabled" gorm:"not null;default:false"`
+	DisabledAt   *time.Time `json:"disabledAt,omitempty"`
+	ModelResetAt *time.Time `json:"modelResetAt,omitempty"`
+	// TemperatureSources is the user's comma-separated temperature source priority; empty uses the default
+	TemperatureSources string `json:"temperatureSources,omitempty"`
+	// QuietHours is the user's HH:MM-HH:MM window to hold notifications in, or "off"; empty uses the default
+	QuietHours string `json:"quietHours,omitempty"`
+	// NotificationDailyLimit caps the notifications sent to the user per day (0 for no cap); nil uses the default
+	NotificationDailyLimit *int `json:"notificationDailyLimit,omitempty"`
+	// StepCapFraction limits how far one v2 prediction may move from the last similar record; nil uses the default
+	StepCapFraction *float64 `json:"stepCapFraction,omitempty"`
+	// RoundingMode, RoundingStep and RoundingHysteresis are how the user's heating times are rounded; empty or zero uses the default
+	RoundingMode       string  `json:"roundingMode,omitempty"`
+	RoundingStep       float64 `json:"roundingStep,omitempty"`
+	RoundingHysteresis float64 `json:"roundingHysteresis,omitempty"`
+	// Region is the climate region the user declared; empty uses the one derived from the weather location
+	Region string `json:"region,omitempty"`
+	// TargetSatisfaction is the rating the user gives water that was just right; nil learns it from their ratings
+	TargetSatisfaction *float64 `json:"targetSatisfaction,omitempty"`
+	// LastSyncedAt is when the user's client last ran a delta sync
+	LastSyncedAt *time.Time `json:"lastSyncedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the User model
+func (User) TableName() string {
+	return "users"
+}
@@ -0,0 +1,150 @@
+// Package notify delivers notifications to users over pluggable channels.
+//
+// Each channel lives in its own file and registers a factory in init; the factory returns nil
+// when the deployment hasn't configured the channel. Adding a channel is adding a file, with no
+// changes to the services that send notifications or to how users route them.
+package notify
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+// ErrInvalidTarget is returned by channels for a route target they can't deliver to
+var ErrInvalidTarget = errors.New("invalid notification target")
+
+// Message is one notification for a user
+type Message struct {
+	UserID string
+	Kind   string // e.g. feedback_prompt
+	Text   string
+	Data   map[string]interface{} // details such as promptId or link
+}
+
+// Target is where a route delivers
+type Target struct {
+	Address string // e.g. an email address, chat ID or topic
+	Token   string // credential for the address, e.g. an ntfy access token; empty for none
+}
+
+// Capabilities describe what a channel can deliver and what its route targets are
+type Capabilities struct {
+	Target    string `json:"target"`              // what a route's target address is, e.g. "email address"
+	Token     bool   `json:"token,omitempty"`     // routes may carry a token the channel authenticates with
+	Links     bool   `json:"links"`               // the link in a message's data can be opened from the notification
+	Markdown  bool   `json:"markdown"`            // the text is rendered as Markdown
+	MaxLength int    `json:"maxLength,omitempty"` // longest text delivered in full; 0 for no limit
+	PublicKey string `json:"publicKey,omitempty"` // key clients need to create a target, e.g. web push's VAPID key
+}
+
+// Channel delivers notifications to targets, e.g. email addresses or chat IDs
+type Channel interface {
+	// Send delivers msg to a target, returning ErrInvalidTarget for an address it can't use
+	Send(ctx context.Context, to Target, msg Message) error
+	Capabilities() Capabilities
+}
+
+// TargetValidator is implemented by channels that can check a route's target address before
+// it is saved
+type TargetValidator interface {
+	ValidateTarget(address string) error
+}
+
+// CommandHandler answers a command a user sent from a target address, e.g. "!heat 12 7" from a
+// chat, returning the reply; empty for messages that aren't commands
+type CommandHandler func(ctx context.Context, from, text string) string
+
+// commandMaxAge is how old a message may be for a bot to still answer it, so commands sent
+// while the server was down aren't acted on hours later
+const commandMaxAge = 5 * time.Minute
+
+// Listener is implemented by channels whose bot takes commands. Listen receives messages sent
+// to the bot and replies to each with what handle returns, until ctx is done or the
+// connection fails.
+type Listener interface {
+	Listen(ctx context.Context, handle CommandHandler) error
+}
+
+// Factory builds a channel from the deployment's configuration, or returns nil when the
+// channel isn't configured
+type Factory func(cfg config.NotificationConfig) Channel
+
+var (
+	factoriesMu sync.Mutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a channel available under name. It panics when the name is taken, as two
+// channels can't share routes.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, taken := factories[name]; taken {
+		panic("notify: channel " + name + " registered twice")
+	}
+	factories[name] = factory
+}
+
+// Registry holds the channels a deployment has configured
+type Registry struct {
+	channels map[string]Channel
+}
+
+// NewRegistry builds every registered channel the configuration enables
+func NewRegistry(cfg config.NotificationConfig) *Registry {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	r := &Registry{channels: map[string]Channel{}}
+	for name, factory := range factories {
+		if channel := factory(cfg); channel != nil {
+			r.channels[name] = channel
+		}
+	}
+	return r
+}
+
+// Add makes channel available under name, replacing any channel of that name
+func (r *Registry) Add(name string, channel Channel) {
+	r.channels[name] = channel
+}
+
+// Get returns the channel configured under name
+func (r *Registry) Get(name string) (Channel, bool) {
+	channel, ok := r.channels[name]
+	return channel, ok
+}
+
+// Names lists the configured channels in alphabetical order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.channels))
+	for name := range r.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Listeners returns the configured channels that take commands, by name
+func (r *Registry) Listeners() map[string]Listener {
+	listeners := map[string]Listener{}
+	for name, channel := range r.channels {
+		if listener, ok := channel.(Listener); ok {
+			listeners[name] = listener
+		}
+	}
+	return listeners
+}
+
+// Truncate shortens text to the channel's maximum length, marking the cut with an ellipsis
+func Truncate(text string, maxLength int) string {
+	runes := []rune(text)
+	if maxLength <= 0 || len(runes) <= maxLength {
+		return text
+	}
+	return string(runes[:maxLength-1]) + "…"
+}
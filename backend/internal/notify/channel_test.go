@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegistry_BuildsConfiguredChannels(t *testing.T) {
+	registry := NewRegistry(config.NotificationConfig{})
+	assert.Equal(t, []string{"ntfy", "webhook"}, registry.Names(), "only ntfy and the webhook need no configuration")
+
+	registry = NewRegistry(config.NotificationConfig{
+		Email:    config.SMTPConfig{Host: "mail.example.com", Port: 587, From: "heat@example.com"},
+		Telegram: config.TelegramConfig{BotToken: "token"},
+	})
+	assert.Equal(t, []string{"email", "ntfy", "telegram", "webhook"}, registry.Names())
+}
+
+func TestTelegram_Send(t *testing.T) {
+	var got map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["chat_id"] == "404" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	telegram := NewTelegram(config.TelegramConfig{BotToken: "123:abc", APIURL: server.URL})
+	msg := Message{UserID: "user1", Kind: "feedback_prompt", Text: "How was your Morning shower?", Data: map[string]interface{}{"link": "https://heat.example/feedback"}}
+	require.NoError(t, telegram.Send(context.Background(), Target{Address: "42"}, msg))
+	assert.Equal(t, "/bot123:abc/sendMessage", path)
+	assert.Equal(t, "How was your Morning shower?\nhttps://heat.example/feedback", got["text"])
+
+	assert.ErrorContains(t, telegram.Send(context.Background(), Target{Address: "404"}, msg), "chat not found")
+	assert.ErrorIs(t, telegram.Send(context.Background(), Target{Address: "not a chat"}, msg), ErrInvalidTarget)
+}
+
+func TestTelegram_ListenAnswersCommands(t *testing.T) {
+	answered := make(chan map[string]interface{}, 1)
+	polled := make(chan string, 1) // the offset of the poll after the first
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot123:abc/getUpdates":
+			if offset := r.URL.Query().Get("offset"); offset != "0" {
+				polled <- offset
+				<-r.Context().Done()
+				return
+			}
+			fmt.Fprintf(w, `{"ok":true,"result":[
+				{"update_id":7,"message":{"date":%d,"text":"/heat 12","chat":{"id":42}}},
+				{"update_id":8,"message":{"date":%d,"text":"/heat 99","chat":{"id":42}}}]}`, time.Now().Unix(), time.Now().Add(-time.Hour).Unix())
+		case "/bot123:abc/sendMessage":
+			var got map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&got)
+			answered <- got
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		}
+	}))
+	defer server.Close()
+
+	telegram := NewTelegram(config.TelegramConfig{BotToken: "123:abc", APIURL: server.URL})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- telegram.Listen(ctx, func(_ context.Context, from, text string) string {
+			return from + " asked " + text
+		})
+	}()
+
+	got := <-answered
+	assert.Equal(t, "42", got["chat_id"])
+	assert.Equal(t, "42 asked /heat 12", got["text"], "the hour old command is skipped")
+	assert.Equal(t, "9", <-polled)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestMatrix_SendAndListen(t *testing.T) {
+	var joined []string
+	sent := make(chan map[string]interface{}, 2)
+	waiting := make(chan struct{}, 1) // the bot is waiting for messages after answering
+	syncs := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer syt_token", r.Header.Get("Authorization"))
+		path := strings.TrimPrefix(r.URL.EscapedPath(), "/_matrix/client/v3")
+		switch {
+		case path == "/account/whoami":
+			w.Write([]byte(`{"user_id":"@heat:example.org"}`))
+		case path == "/sync":
+			syncs++
+			now := time.Now().UnixMilli()
+			switch syncs {
+			case 1:
+				// History from before the bot started, and an invite
+				fmt.Fprintf(w, `{"next_batch":"s1","rooms":{
+					"join":{"!home:example.org":{"timeline":{"events":[{"type":"m.room.message","sender":"@alice:example.org","origin_server_ts":%d,"content":{"msgtype":"m.text","body":"!heat 10"}}]}}},
+					"invite":{"!new:example.org":{}}}}`, now)
+			case 2:
+				assert.Equal(t, "s1", r.URL.Query().Get("since"))
+				fmt.Fprintf(w, `{"next_batch":"s2","rooms":{"join":{"!home:example.org":{"timeline":{"events":[
+					{"type":"m.room.message","sender":"@heat:example.org","origin_server_ts":%d,"content":{"msgtype":"m.text","body":"!heat 11"}},
+					{"type":"m.room.message","sender":"@alice:example.org","origin_server_ts":%d,"content":{"msgtype":"m.text","body":"!heat 12 7"}}]}}}}}`, now, now)
+			default:
+				waiting <- struct{}{}
+				<-r.Context().Done()
+			}
+		case strings.HasPrefix(path, "/join/"):
+			joined = append(joined, strings.TrimPrefix(path, "/join/"))
+			w.Write([]byte(`{}`))
+		case strings.HasPrefix(path, "/rooms/%21home:example.org/send/m.room.message/"):
+			assert.Equal(t, http.MethodPut, r.Method)
+			var got map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&got)
+			sent <- got
+			w.Write([]byte(`{"event_id":"$1"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errcode":"M_FORBIDDEN","error":"not in room"}`))
+		}
+	}))
+	defer server.Close()
+
+	matrix := NewMatrix(config.MatrixConfig{HomeserverURL: server.URL, AccessToken: "syt_token"})
+	require.NoError(t, matrix.Send(context.Background(), Target{Address: "!home:example.org"}, Message{Text: "Heating started"}))
+	assert.Equal(t, map[string]interface{}{"msgtype": "m.text", "body": "Heating started"}, <-sent)
+	assert.ErrorIs(t, matrix.Send(context.Background(), Target{Address: "!elsewhere:example.org"}, Message{Text: "Hi"}), ErrInvalidTarget)
+	assert.ErrorIs(t, matrix.Send(context.Background(), Target{Address: "#alias:example.org"}, Message{Text: "Hi"}), ErrInvalidTarget)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- matrix.Listen(ctx, func(_ context.Context, from, text string) string {
+			return from + " asked " + text
+		})
+	}()
+	got := <-sent
+	assert.Equal(t, "!home:example.org asked !heat 12 7", got["body"], "only new messages from others are answered")
+	<-waiting
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, []string{"%21new:example.org"}, joined)
+}
+
+func TestWebPush_SendsEncryptedSignedPayload(t *testing.T) {
+	vapid, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	push, err := NewWebPush(config.WebPushConfig{
+		PrivateKey: base64.RawURLEncoding.EncodeToString(vapid.Bytes()),
+		Subject:    "mailto:admin@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(vapid.PublicKey().Bytes()), push.Capabilities().PublicKey)
+
+	// The browser's side of the subscription
+	browser, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	auth := make([]byte, 16)
+	rand.Read(auth)
+
+	var body []byte
+	var authorization, encoding string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		authorization, encoding = r.Header.Get("Authorization"), r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	push.client = server.Client()
+
+	subscription, _ := json.Marshal(map[string]interface{}{
+		"endpoint": server.URL + "/push/abc",
+		"keys": map[string]string{
+			"p256dh": base64.RawURLEncoding.EncodeToString(browser.PublicKey().Bytes()),
+			"auth":   base64.RawURLEncoding.EncodeToString(auth),
+		},
+	})
+	require.NoError(t, push.ValidateTarget(string(subscription)))
+	require.NoError(t, push.Send(context.Background(), Target{Address: string(subscription)}, Message{Kind: "feedback_prompt", Text: "How was your shower?"}))
+	assert.Equal(t, "aes128gcm", encoding)
+
+	// The VAPID token is signed by the channel's key for the push service's origin
+	token := strings.TrimPrefix(strings.Split(authorization, ",")[0], "vapid t=")
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.Contains(t, string(claims), `"aud":"`+server.URL+`"`)
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(&push.key.PublicKey, digest[:], r, s))
+
+	// and the browser can decrypt the payload
+	salt, keyLength := body[:16], int(body[20])
+	serverKey, err := ecdh.P256().NewPublicKey(body[21 : 21+keyLength])
+	require.NoError(t, err)
+	secret, err := browser.ECDH(serverKey)
+	require.NoError(t, err)
+	keyInfo := append([]byte("WebPush: info\x00"), browser.PublicKey().Bytes()...)
+	ikm := hkdf(auth, secret, append(keyInfo, serverKey.Bytes()...), 32)
+	block, _ := aes.NewCipher(hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16))
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12), body[21+keyLength:], nil)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	assert.JSONEq(t, `{"type":"feedback_prompt","message":"How was your shower?","link":""}`, string(plaintext[:len(plaintext)-1]))
+}
+
+func TestNtfy_Send(t *testing.T) {
+	type publish struct {
+		path, authorization string
+		body                map[string]interface{}
+	}
+	published := make(chan publish, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		published <- publish{r.URL.Path, r.Header.Get("Authorization"), body}
+	}))
+	defer server.Close()
+
+	ntfy := NewNtfy(config.NtfyConfig{URL: server.URL})
+	msg := Message{Kind: "heating_started", Text: "Your water heater started heating", Data: map[string]interface{}{"link": "https://heat.example"}}
+	require.NoError(t, ntfy.Send(context.Background(), Target{Address: "heat-alice", Token: "tk_secret"}, msg))
+	got := <-published
+	assert.Equal(t, "/", got.path)
+	assert.Equal(t, "Bearer tk_secret", got.authorization)
+	assert.Equal(t, "heat-alice", got.body["topic"])
+	assert.Equal(t, "https://heat.example", got.body["click"])
+	assert.Equal(t, []interface{}{"fire"}, got.body["tags"])
+
+	// A topic on another server, behind a reverse proxy path and basic auth
+	require.NoError(t, ntfy.Send(context.Background(), Target{Address: server.URL + "/ntfy/heat-bob", Token: "bob:pw"}, msg))
+	got = <-published
+	assert.Equal(t, "/ntfy/", got.path)
+	assert.Equal(t, "heat-bob", got.body["topic"])
+	assert.True(t, strings.HasPrefix(got.authorization, "Basic "))
+
+	for _, address := range []string{"has spaces", "ftp://ntfy.example/topic", "https://ntfy.example/", "https://user:pw@ntfy.example/topic"} {
+		assert.ErrorIs(t, ntfy.ValidateTarget(address), ErrInvalidTarget, address)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "shö…", Truncate("shörter", 4))
+	assert.Equal(t, "anything", Truncate("anything", 0))
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"heat-logger/internal/config"
+)
+
+func init() {
+	Register("email", func(cfg config.NotificationConfig) Channel {
+		if cfg.Email.Host == "" {
+			return nil
+		}
+		return NewEmail(cfg.Email)
+	})
+}
+
+// Email sends notifications as plain-text mail
+type Email struct {
+	cfg config.SMTPConfig
+}
+
+// NewEmail creates an email channel sending through the mail server in cfg
+func NewEmail(cfg config.SMTPConfig) *Email {
+	return &Email{cfg: cfg}
+}
+
+// Capabilities implements Channel
+func (e *Email) Capabilities() Capabilities {
+	return Capabilities{Target: "email address", Links: true}
+}
+
+// ValidateTarget implements TargetValidator
+func (e *Email) ValidateTarget(target string) error {
+	address, err := mail.ParseAddress(target)
+	if err != nil || address.Address != target {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// Send implements Channel. The first line of the text is the subject.
+func (e *Email) Send(ctx context.Context, to Target, msg Message) error {
+	if err := e.ValidateTarget(to.Address); err != nil {
+		return err
+	}
+	subject, _, _ := strings.Cut(msg.Text, "\n")
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&body, "To: %s\r\n", to.Address)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", Truncate(subject, 78)))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.textWithLink(), "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+	addr := e.cfg.Host + ":" + strconv.Itoa(e.cfg.Port)
+	return smtp.SendMail(addr, auth, e.cfg.From, []string{to.Address}, body.Bytes())
+}
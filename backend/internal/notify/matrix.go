@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+func init() {
+	Register("matrix", func(cfg config.NotificationConfig) Channel {
+		if cfg.Matrix.AccessToken == "" {
+			return nil
+		}
+		return NewMatrix(cfg.Matrix)
+	})
+}
+
+const (
+	// matrixMaxLength keeps a message well inside the 64 KiB a Matrix event may take
+	matrixMaxLength = 16000
+	// matrixPollTimeout is how long a /sync request waits for new events
+	matrixPollTimeout = 30 * time.Second
+)
+
+// matrixRoom matches a room ID such as !abc123:example.org
+var matrixRoom = regexp.MustCompile(`^![^:\s]+:\S+$`)
+
+// matrixSyncFilter limits /sync to the messages sent in rooms, leaving out presence, account
+// data and state the bot has no use for
+const matrixSyncFilter = `{"presence":{"types":[]},"account_data":{"types":[]},"room":{"timeline":{"types":["m.room.message"]},"state":{"types":[]},"ephemeral":{"types":[]},"account_data":{"types":[]}}}`
+
+// Matrix posts notifications to Matrix rooms as a bot account. Users invite the bot to a room,
+// which it joins, and route notifications to the room's ID.
+type Matrix struct {
+	cfg    config.MatrixConfig
+	client *http.Client
+	poller *http.Client // for /sync, which holds the request open
+	txn    atomic.Int64 // counts messages sent, numbering their transaction IDs
+	start  int64        // when the channel was created, so transaction IDs differ across restarts
+}
+
+// NewMatrix creates a Matrix channel for the account in cfg
+func NewMatrix(cfg config.MatrixConfig) *Matrix {
+	return &Matrix{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		poller: &http.Client{Timeout: matrixPollTimeout + 10*time.Second},
+		start:  time.Now().UnixNano(),
+	}
+}
+
+// Capabilities implements Channel
+func (m *Matrix) Capabilities() Capabilities {
+	return Capabilities{Target: "room ID", Links: true, MaxLength: matrixMaxLength}
+}
+
+// ValidateTarget implements TargetValidator
+func (m *Matrix) ValidateTarget(target string) error {
+	if !matrixRoom.MatchString(target) {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// Send implements Channel
+func (m *Matrix) Send(ctx context.Context, to Target, msg Message) error {
+	if err := m.ValidateTarget(to.Address); err != nil {
+		return err
+	}
+	txn := fmt.Sprintf("heat-%d-%d", m.start, m.txn.Add(1))
+	body := map[string]interface{}{
+		"msgtype": "m.text",
+		"body":    Truncate(msg.textWithLink(), matrixMaxLength),
+	}
+	err := m.call(ctx, m.client, http.MethodPut, "/rooms/"+url.PathEscape(to.Address)+"/send/m.room.message/"+txn, nil, body, nil)
+	var matrixErr *matrixError
+	if errors.As(err, &matrixErr) && matrixErr.status == http.StatusForbidden {
+		// The bot isn't in the room
+		return fmt.Errorf("%w: %s", ErrInvalidTarget, matrixErr.Message)
+	}
+	return err
+}
+
+// matrixSync is the part of a /sync response the bot reads
+type matrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []matrixEvent `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+// matrixEvent is a message in a room
+type matrixEvent struct {
+	Type           string `json:"type"`
+	Sender         string `json:"sender"`
+	OriginServerTS int64  `json:"origin_server_ts"`
+	Content        struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	} `json:"content"`
+}
+
+// Listen implements Listener, long polling /sync for messages in the rooms the bot is in. The
+// bot joins the rooms it is invited to. Messages from before Listen started are skipped.
+func (m *Matrix) Listen(ctx context.Context, handle CommandHandler) error {
+	var whoami struct {
+		UserID string `json:"user_id"`
+	}
+	if err := m.call(ctx, m.client, http.MethodGet, "/account/whoami", nil, nil, &whoami); err != nil {
+		return err
+	}
+
+	since := ""
+	for {
+		query := url.Values{"filter": {matrixSyncFilter}}
+		if since != "" {
+			query.Set("since", since)
+			query.Set("timeout", strconv.Itoa(int(matrixPollTimeout.Milliseconds())))
+		}
+		var sync matrixSync
+		if err := m.call(ctx, m.poller, http.MethodGet, "/sync", query, nil, &sync); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		for room := range sync.Rooms.Invite {
+			if err := m.call(ctx, m.client, http.MethodPost, "/join/"+url.PathEscape(room), nil, map[string]interface{}{}, nil); err != nil {
+				log.Printf("Failed to join Matrix room %s: %v", room, err)
+			}
+		}
+		// The first sync returns the rooms' recent history, which has been answered before
+		if since != "" {
+			for room, joined := range sync.Rooms.Join {
+				for _, event := range joined.Timeline.Events {
+					if event.Type != "m.room.message" || event.Sender == whoami.UserID || event.Content.MsgType != "m.text" ||
+						time.Since(time.UnixMilli(event.OriginServerTS)) > commandMaxAge {
+						continue
+					}
+					reply := handle(ctx, room, event.Content.Body)
+					if reply == "" {
+						continue
+					}
+					if err := m.Send(ctx, Target{Address: room}, Message{Text: reply}); err != nil {
+						log.Printf("Failed to answer Matrix room %s: %v", room, err)
+					}
+				}
+			}
+		}
+		since = sync.NextBatch
+	}
+}
+
+// matrixError is an error the homeserver returned
+type matrixError struct {
+	status  int
+	Code    string `json:"errcode"`
+	Message string `json:"error"`
+}
+
+func (e *matrixError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("matrix returned status %d", e.status)
+	}
+	return fmt.Sprintf("matrix: %s: %s", e.Code, e.Message)
+}
+
+// call sends a client-server API request for path under /_matrix/client/v3, encoding body as
+// JSON unless it is nil and decoding the response into out unless that is nil
+func (m *Matrix) call(ctx context.Context, client *http.Client, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	u := m.cfg.HomeserverURL + "/_matrix/client/v3" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.cfg.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		e := &matrixError{status: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(e)
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
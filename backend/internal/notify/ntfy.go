@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+func init() {
+	// Topics on ntfy.sh need no account, so the channel works out of the box
+	Register("ntfy", func(cfg config.NotificationConfig) Channel {
+		return NewNtfy(cfg.Ntfy)
+	})
+}
+
+// ntfyMaxLength is the longest message ntfy servers accept by default
+const ntfyMaxLength = 4096
+
+// ntfyTopic matches the topic names ntfy allows
+var ntfyTopic = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+// ntfyTags are the emoji tags shown with each kind of notification
+var ntfyTags = map[string][]string{
+	models.NotificationFeedbackPrompt:   {"shower"},
+	models.NotificationHeatingStarted:   {"fire"},
+	models.NotificationBoilerEfficiency: {"warning"},
+	models.NotificationHouseholdInvite:  {"house"},
+}
+
+// Ntfy publishes notifications to ntfy topics. A route's target is a topic on the configured
+// server, e.g. heat-alice, or the URL of a topic on another server, e.g.
+// https://ntfy.example.com/heat-alice. Its token, if any, is an access token, or user:password
+// for servers using basic auth.
+type Ntfy struct {
+	cfg    config.NtfyConfig
+	client *http.Client
+}
+
+// NewNtfy creates an ntfy channel publishing to the server in cfg by default
+func NewNtfy(cfg config.NtfyConfig) *Ntfy {
+	return &Ntfy{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Capabilities implements Channel
+func (n *Ntfy) Capabilities() Capabilities {
+	return Capabilities{Target: "topic or topic URL", Token: true, Links: true, MaxLength: ntfyMaxLength}
+}
+
+// ValidateTarget implements TargetValidator
+func (n *Ntfy) ValidateTarget(address string) error {
+	_, _, err := n.topic(address)
+	return err
+}
+
+// topic splits a target address into the server to publish to and the topic
+func (n *Ntfy) topic(address string) (string, string, error) {
+	if ntfyTopic.MatchString(address) {
+		return n.cfg.URL, address, nil
+	}
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return "", "", ErrInvalidTarget
+	}
+	server, topic := splitTopicURL(u)
+	if !ntfyTopic.MatchString(topic) {
+		return "", "", ErrInvalidTarget
+	}
+	return u.Scheme + "://" + u.Host + server, topic, nil
+}
+
+// splitTopicURL splits a topic URL's path into the server's base path and the topic
+func splitTopicURL(u *url.URL) (string, string) {
+	trimmed := strings.Trim(u.Path, "/")
+	if i := strings.LastIndex(trimmed, "/"); i >= 0 {
+		return "/" + trimmed[:i], trimmed[i+1:]
+	}
+	return "", trimmed
+}
+
+// Send implements Channel, publishing as JSON to the server's root
+func (n *Ntfy) Send(ctx context.Context, to Target, msg Message) error {
+	server, topic, err := n.topic(to.Address)
+	if err != nil {
+		return err
+	}
+	body := map[string]interface{}{
+		"topic":   topic,
+		"title":   "Heat Logger",
+		"message": Truncate(msg.Text, ntfyMaxLength),
+	}
+	if tags, ok := ntfyTags[msg.Kind]; ok {
+		body["tags"] = tags
+	}
+	if link := msg.link(); link != "" {
+		body["click"] = link
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if user, password, basic := strings.Cut(to.Token, ":"); basic {
+		req.SetBasicAuth(user, password)
+	} else if to.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+to.Token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var result struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&result) == nil && result.Error != "" {
+			return fmt.Errorf("ntfy: %s", result.Error)
+		}
+		return fmt.Errorf("ntfy returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+func init() {
+	Register("telegram", func(cfg config.NotificationConfig) Channel {
+		if cfg.Telegram.BotToken == "" {
+			return nil
+		}
+		return NewTelegram(cfg.Telegram)
+	})
+}
+
+const (
+	// telegramMaxLength is the longest message the Bot API accepts
+	telegramMaxLength = 4096
+	// telegramPollTimeout is how long a getUpdates request waits for a message
+	telegramPollTimeout = 30 * time.Second
+)
+
+// telegramChat matches a numeric chat ID or a public @channel name
+var telegramChat = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+
+// Telegram sends notifications to Telegram chats through a bot. Users start a chat with the
+// bot and route notifications to the chat's ID.
+type Telegram struct {
+	cfg    config.TelegramConfig
+	client *http.Client
+	poller *http.Client // for getUpdates, which holds the request open
+}
+
+// NewTelegram creates a Telegram channel for the bot in cfg
+func NewTelegram(cfg config.TelegramConfig) *Telegram {
+	return &Telegram{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		poller: &http.Client{Timeout: telegramPollTimeout + 10*time.Second},
+	}
+}
+
+// Capabilities implements Channel
+func (t *Telegram) Capabilities() Capabilities {
+	return Capabilities{Target: "chat ID", Links: true, MaxLength: telegramMaxLength}
+}
+
+// ValidateTarget implements TargetValidator
+func (t *Telegram) ValidateTarget(target string) error {
+	if !telegramChat.MatchString(target) {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// Send implements Channel
+func (t *Telegram) Send(ctx context.Context, to Target, msg Message) error {
+	if err := t.ValidateTarget(to.Address); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"chat_id": to.Address,
+		"text":    Truncate(msg.textWithLink(), telegramMaxLength),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.APIURL+"/bot"+t.cfg.BotToken+"/sendMessage", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return t.call(t.client, req, nil)
+}
+
+// telegramUpdate is a message sent to the bot, from getUpdates
+type telegramUpdate struct {
+	ID      int `json:"update_id"`
+	Message *struct {
+		Date int64  `json:"date"`
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// Listen implements Listener, long polling the Bot API for messages. Commands may be written
+// /heat as Telegram clients suggest, or !heat as on Matrix.
+func (t *Telegram) Listen(ctx context.Context, handle CommandHandler) error {
+	offset := 0
+	for {
+		query := url.Values{
+			"timeout":         {strconv.Itoa(int(telegramPollTimeout.Seconds()))},
+			"offset":          {strconv.Itoa(offset)},
+			"allowed_updates": {`["message"]`},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.cfg.APIURL+"/bot"+t.cfg.BotToken+"/getUpdates?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		var updates []telegramUpdate
+		if err := t.call(t.poller, req, &updates); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		for _, update := range updates {
+			offset = update.ID + 1
+			m := update.Message
+			if m == nil || m.Text == "" || time.Since(time.Unix(m.Date, 0)) > commandMaxAge {
+				continue
+			}
+			chat := strconv.FormatInt(m.Chat.ID, 10)
+			reply := handle(ctx, chat, m.Text)
+			if reply == "" {
+				continue
+			}
+			if err := t.Send(ctx, Target{Address: chat}, Message{Text: reply}); err != nil {
+				log.Printf("Failed to answer Telegram chat %s: %v", chat, err)
+			}
+		}
+	}
+}
+
+// call sends a Bot API request, decoding the result into out unless it is nil
+func (t *Telegram) call(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || !result.OK {
+		if result.Description != "" {
+			return fmt.Errorf("telegram: %s", result.Description)
+		}
+		return fmt.Errorf("telegram returned status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(result.Result, out)
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+func init() {
+	// Users may route notifications to their own bridge, so the channel is always available
+	Register("webhook", func(config.NotificationConfig) Channel {
+		return NewWebhook()
+	})
+}
+
+// Webhook POSTs notifications as JSON (type, userId, message and the message's data) to a
+// URL, for a bridge such as Home Assistant or Node-RED to deliver
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook creates a webhook channel
+func NewWebhook() *Webhook {
+	return &Webhook{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Capabilities implements Channel
+func (w *Webhook) Capabilities() Capabilities {
+	return Capabilities{Target: "http(s) URL", Links: true}
+}
+
+// ValidateTarget implements TargetValidator
+func (w *Webhook) ValidateTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// Send implements Channel
+func (w *Webhook) Send(ctx context.Context, to Target, msg Message) error {
+	if err := w.ValidateTarget(to.Address); err != nil {
+		return err
+	}
+	body := map[string]interface{}{}
+	for key, value := range msg.Data {
+		body[key] = value
+	}
+	body["type"] = msg.Kind
+	body["userId"] = msg.UserID
+	body["message"] = msg.Text
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, to.Address, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// link returns the message's link, if its data has one
+func (m Message) link() string {
+	link, _ := m.Data["link"].(string)
+	return link
+}
+
+// textWithLink is the message's text followed by its link, for channels that only carry text
+func (m Message) textWithLink() string {
+	if link := m.link(); link != "" {
+		return m.Text + "\n" + link
+	}
+	return m.Text
+}
@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+func init() {
+	Register("webpush", func(cfg config.NotificationConfig) Channel {
+		if cfg.WebPush.PrivateKey == "" {
+			return nil
+		}
+		channel, err := NewWebPush(cfg.WebPush)
+		if err != nil {
+			log.Printf("Warning: web push notifications disabled: %v", err)
+			return nil
+		}
+		return channel
+	})
+}
+
+const (
+	// webPushMaxLength keeps the encrypted payload well inside the 4 KiB push services accept
+	webPushMaxLength = 1000
+	webPushTTL       = 24 * time.Hour
+)
+
+// WebPush sends notifications to browsers that subscribed with the PWA's service worker. A
+// route's target is the PushSubscription JSON the browser returns, encrypted to as RFC 8291
+// describes and authenticated to the push service with VAPID (RFC 8292).
+type WebPush struct {
+	cfg       config.WebPushConfig
+	key       *ecdsa.PrivateKey
+	publicKey string // base64url uncompressed public key, applicationServerKey for subscribing
+	client    *http.Client
+}
+
+// NewWebPush creates a web push channel signing with the VAPID key in cfg
+func NewWebPush(cfg config.WebPushConfig) (*WebPush, error) {
+	d, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cfg.PrivateKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key must be base64url: %w", err)
+	}
+	private, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key: %w", err)
+	}
+	public := private.PublicKey().Bytes()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &WebPush{
+		cfg:       cfg,
+		key:       key,
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Capabilities implements Channel
+func (w *WebPush) Capabilities() Capabilities {
+	return Capabilities{Target: "PushSubscription JSON", Links: true, MaxLength: webPushMaxLength, PublicKey: w.publicKey}
+}
+
+// pushSubscription is the JSON form of a browser's PushSubscription
+type pushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// parseSubscription decodes a target into the subscription's endpoint and keys
+func parseSubscription(target string) (*pushSubscription, *ecdh.PublicKey, []byte, error) {
+	var sub pushSubscription
+	if err := json.Unmarshal([]byte(target), &sub); err != nil {
+		return nil, nil, nil, ErrInvalidTarget
+	}
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, nil, nil, ErrInvalidTarget
+	}
+	rawKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(sub.Keys.P256dh, "="))
+	if err != nil {
+		return nil, nil, nil, ErrInvalidTarget
+	}
+	userKey, err := ecdh.P256().NewPublicKey(rawKey)
+	if err != nil {
+		return nil, nil, nil, ErrInvalidTarget
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(sub.Keys.Auth, "="))
+	if err != nil || len(auth) != 16 {
+		return nil, nil, nil, ErrInvalidTarget
+	}
+	return &sub, userKey, auth, nil
+}
+
+// ValidateTarget implements TargetValidator
+func (w *WebPush) ValidateTarget(target string) error {
+	_, _, _, err := parseSubscription(target)
+	return err
+}
+
+// Send implements Channel. The service worker receives JSON with the message's type, text and
+// link.
+func (w *WebPush) Send(ctx context.Context, to Target, msg Message) error {
+	sub, userKey, auth, err := parseSubscription(to.Address)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":    msg.Kind,
+		"message": Truncate(msg.Text, webPushMaxLength),
+		"link":    msg.link(),
+	})
+	if err != nil {
+		return err
+	}
+	body, err := encryptPush(payload, userKey, auth)
+	if err != nil {
+		return err
+	}
+	token, err := w.vapidToken(sub.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", fmt.Sprint(int(webPushTTL.Seconds())))
+	req.Header.Set("Authorization", "vapid t="+token+", k="+w.publicKey)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// The browser unsubscribed
+		return ErrInvalidTarget
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("push service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// vapidToken signs the ES256 JWT that identifies this server to the endpoint's push service
+func (w *WebPush) vapidToken(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": w.cfg.Subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, w.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// encryptPush encrypts payload for the subscriber's key and auth secret as a single
+// aes128gcm record (RFC 8188, RFC 8291)
+func encryptPush(payload []byte, userKey *ecdh.PublicKey, auth []byte) ([]byte, error) {
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := serverKey.ECDH(userKey)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	keyInfo := append([]byte("WebPush: info\x00"), userKey.Bytes()...)
+	keyInfo = append(keyInfo, serverPublic...)
+	ikm := hkdf(auth, secret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// A single record, so it ends with the last-record delimiter and no padding
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, 21+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, 4096)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// hkdf derives length (at most 32) bytes with HKDF-SHA256
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
@@ -1,9 +1,16 @@
 package router
 
 import (
+	"io/fs"
+	"log"
+
 	"heat-logger/internal/config"
+	"heat-logger/internal/events"
 	"heat-logger/internal/handler"
+	"heat-logger/internal/hooks"
+	"heat-logger/internal/models"
 	"heat-logger/internal/services"
+	"heat-logger/pkg/logfile"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -18,42 +25,666 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	corsConfig.AllowMethods = cfg.CORS.AllowedMethods
 	corsConfig.AllowHeaders = cfg.CORS.AllowedHeaders
 	corsConfig.AllowCredentials = true
+	corsConfig.ExposeHeaders = []string{handler.CorrelationHeader}
 
 	r.Use(cors.New(corsConfig))
+	r.Use(handler.Correlation())
+	r.Use(handler.Localize())
+	r.Use(handler.LimitRequestBody(cfg.Server.MaxBodyKB, cfg.Server.BodyLimitsKB))
+	r.Use(handler.RequireJSON())
+	r.Use(handler.ValidationRules(cfg.Records))
 
 	// Initialize services
 	recordService := services.NewRecordService()
-	useV2 := cfg.Prediction.Version != "v1"
+	dbHealth := services.NewDBHealthMonitor(cfg.Database.Health)
+	dbHealth.Start()
+	recordService.SetHealthMonitor(dbHealth)
+	recordService.SetOneRecordPerDay(cfg.Records.OnePerDay)
+	recordService.SetTombstoneRetention(cfg.Records.TombstoneRetention)
+	recordService.SetFeedbackConfidence(cfg.Prediction.Confidence)
+	models.SetRecordChecksumKey(cfg.Records.ChecksumKey)
+	annotationService := services.NewAnnotationService()
+	presetService := services.NewPresetService()
+	memberService := services.NewMemberService()
+	predictorVersion := "v2"
+	if cfg.Prediction.Version == "v1" {
+		predictorVersion = "v1"
+	}
+
+	userService := services.NewUserService()
+	if predictorVersion == "v2" {
+		userService.SetMinPersonalRecords(cfg.Prediction.V2.MinPersonalRecords)
+	}
+	// Users who don't declare a climate region share the one of the instance's weather location
+	var instanceRegion string
+	if latitude := cfg.Temperature.Weather.Latitude; latitude != nil {
+		instanceRegion = services.RegionForLatitude(*latitude)
+	}
+	regions := services.NewRegionDirectory(instanceRegion)
+	regions.Start()
+	satisfactionTargets := services.NewSatisfactionTargetDirectory()
+	satisfactionTargets.SetCalibrationApplied(cfg.Prediction.Calibration)
+	satisfactionTargets.Start()
+	// A merge moves declared regions, targets and ratings to another user
+	userService.OnUsersMerged(func(into, from string) {
+		if err := regions.Refresh(); err != nil {
+			log.Printf("Failed to reload regions after merging %s into %s: %v", from, into, err)
+		}
+		if err := satisfactionTargets.Refresh(); err != nil {
+			log.Printf("Failed to reload satisfaction targets after merging %s into %s: %v", from, into, err)
+		}
+	})
+	// Bench mode stops the clock so predictions and schedules come out the same every run
+	clock := services.SystemClock
+	if cfg.App.FrozenTime != nil {
+		clock = services.NewFrozenClock(*cfg.App.FrozenTime)
+	}
+	random := services.NewRandomSource(cfg.Prediction.Seed)
+	if cfg.Prediction.Seed == 0 {
+		log.Printf("Prediction randomness seeded with %d; set PREDICTION_SEED to reproduce this run", random.Seed())
+	}
+	changePointConfig := services.DefaultChangePointConfig()
+	// Data-driven kernel widths: each user's history is bucketed, finer where they have more of it
+	buckets := services.NewBucketTreeService(cfg.Prediction.V2.BucketSplit)
+	if buckets.Enabled() {
+		recordService.OnRecordTrained(buckets.Observe)
+		userService.OnModelReset(buckets.Forget)
+		userService.OnUsersMerged(func(into, from string) {
+			buckets.Forget(into)
+			buckets.Forget(from)
+		})
+	}
+	residualHeat := services.NewResidualHeatModel(cfg.Device)
+	boilerService := services.NewBoilerService(cfg.Device, services.NewInletModel(cfg.Prediction.Inlet))
+	boilerService.SetClock(clock)
+	minMinutes, maxMinutes := cfg.Prediction.HeatingBounds()
+	boilerService.SetSafetyFloorDefaults(cfg.Prediction.SafetyFloor.Minutes, maxMinutes)
+
+	// newPredictor builds a predictor version over a record source and clock; the re-score job
+	// uses it to replay history through the same model that serves requests
+	newPredictor := func(version string, records services.RecordServiceInterface, clock services.Clock) services.Predictor {
+		if version == "v2" {
+			v2 := services.NewPredictionServiceV2(records, services.NewPredictionConfigV2(cfg.Prediction.V2))
+			v2.SetAnnotationLookup(annotationService)
+			v2.SetStepCapLookup(userService)
+			v2.SetRoundingLookup(userService)
+			v2.SetRegionLookup(regions)
+			v2.SetSatisfactionTargets(satisfactionTargets)
+			if cfg.Prediction.Calibration {
+				v2.SetRatingCalibration(satisfactionTargets)
+			}
+			v2.SetSourceWeights(cfg.Prediction.SourceWeights)
+			v2.SetInletModel(services.NewInletModel(cfg.Prediction.Inlet))
+			v2.SetResidualHeatModel(residualHeat)
+			v2.SetResolutionLookup(boilerService)
+			v2.SetDeviceTuningLookup(boilerService)
+			v2.SetHeatingBounds(minMinutes, maxMinutes)
+			v2.SetClock(clock)
+			v2.SetRandomSource(random)
+			return v2
+		}
+		v1 := services.NewPredictionService(records) // v1 implements Predictor via shim
+		v1.SetAnnotationLookup(annotationService)
+		v1.SetSourceWeights(cfg.Prediction.SourceWeights)
+		v1.SetResidualHeatModel(residualHeat)
+		v1.SetSatisfactionTargets(satisfactionTargets)
+		if cfg.Prediction.Calibration {
+			v1.SetRatingCalibration(satisfactionTargets)
+		}
+		v1.SetResolutionLookup(boilerService)
+		v1.SetHeatingBounds(minMinutes, maxMinutes)
+		v1.SetClock(clock)
+		return v1
+	}
+
+	// Only predictors that serve requests report anchor diagnostics, not re-scoring replays
+	anchorDiagnostics := services.NewAnchorDiagnostics(cfg.Prediction.V2.AnchorDiagnostics)
+	// v2 weighs only the records near each request, kept in memory as feedback changes
+	var candidates *services.CandidateIndex
+	if cfg.Prediction.IndexMemoryMB > 0 {
+		candidates = services.NewCandidateIndex(recordService, cfg.Prediction.IndexMemoryMB)
+		recordService.OnRecordTrained(candidates.Observe)
+		recordService.OnRecordUpdated(candidates.Observe)
+		recordService.OnRecordDeleted(candidates.Remove)
+		userService.OnModelReset(candidates.Forget)
+		userService.OnUsersMerged(func(into, from string) {
+			candidates.Forget(into)
+			candidates.Forget(from)
+		})
+	}
+	var predictionRecords services.RecordServiceInterface = recordService
+	if cfg.Prediction.Warmup.Enabled() {
+		recordSets := services.NewRecordSetCache(recordService, cfg.Prediction.Warmup.MemoryMB)
+		if candidates != nil {
+			recordSets.SetCandidateIndex(candidates)
+		}
+		go recordSets.WarmUp(cfg.Prediction.Warmup.Users)
+		predictionRecords = recordSets
+	}
+	var contributors *services.ContributorReliabilityService
+	if cfg.Prediction.Reliability.Enabled() {
+		contributors = services.NewContributorReliabilityService(cfg.Prediction.Reliability)
+		recordService.SetContributorFilter(contributors)
+		contributors.Start()
+	}
+	// The candidate index keeps the global pool current itself
+	var globalRecords *services.GlobalSnapshot
+	if cfg.Prediction.GlobalRefresh > 0 && cfg.Prediction.IndexMemoryMB == 0 {
+		globalRecords = services.NewGlobalSnapshot(recordService, cfg.Prediction.GlobalRefresh)
+		globalRecords.Start()
+	}
+	predictor := newPredictor(predictorVersion, predictionRecords, clock)
+	if v2, ok := predictor.(*services.PredictionServiceV2); ok {
+		changePointConfig = v2.ChangePointConfig()
+		v2.SetAnchorDiagnostics(anchorDiagnostics)
+		v2.SetGlobalSnapshot(globalRecords)
+		if candidates != nil {
+			v2.SetCandidateIndex(candidates)
+		}
+		if contributors != nil {
+			v2.SetContributorWeights(contributors)
+		}
+		// Not for re-scoring: today's buckets already know about the records being replayed
+		if buckets.Enabled() {
+			v2.SetLocalityLookup(buckets)
+		}
+	}
 
-	var predictor services.Predictor
-	if useV2 {
-		predictor = services.NewPredictionServiceV2(recordService, nil)
-	} else {
-		predictor = services.NewPredictionService(recordService) // v1 implements Predictor via shim
+	var canary *services.CanaryPredictor
+	if canaryCfg := cfg.Prediction.Canary; canaryCfg.Enabled() && canaryCfg.Version != predictorVersion {
+		canaryPredictor := newPredictor(canaryCfg.Version, predictionRecords, clock)
+		if v2, ok := canaryPredictor.(*services.PredictionServiceV2); ok {
+			v2.SetAnchorDiagnostics(anchorDiagnostics)
+			v2.SetGlobalSnapshot(globalRecords)
+			if candidates != nil {
+				v2.SetCandidateIndex(candidates)
+			}
+			if contributors != nil {
+				v2.SetContributorWeights(contributors)
+			}
+			if buckets.Enabled() {
+				v2.SetLocalityLookup(buckets)
+			}
+		}
+		canary = services.NewCanaryPredictor(predictor, predictorVersion, canaryPredictor, canaryCfg.Version, canaryCfg)
+		recordService.OnRecordTrained(canary.ObserveFeedback)
+		predictor = canary
+	}
+	versionOf := func(userID string) string {
+		if canary != nil {
+			return canary.VersionFor(userID)
+		}
+		return predictorVersion
+	}
+	predictionLog := services.NewPredictionLogService(versionOf)
+	recordService.SetModelVersionLookup(versionOf)
+	recordService.OnRecordCreated(predictionLog.LinkFeedback)
+	auditService := services.NewAuditService()
+	if logs := cfg.Database.Logs; logs.Store == config.LogStoreJSONL {
+		rotation := logfile.OptionsFrom(cfg.Logging.Rotation)
+		predictionLog.SetArchive(services.NewLogArchive(logs.Path, "predictions", rotation))
+		auditService.SetArchive(services.NewLogArchive(logs.Path, "audit", rotation))
+		services.StartLogRetention(logs.Retention, predictionLog, auditService)
+	}
+	if cfg.Prediction.Workers > 0 {
+		pool := services.NewPoolPredictor(predictor, cfg.Prediction.Workers, cfg.Prediction.Queue)
+		pool.SetHeatingBounds(minMinutes, maxMinutes)
+		userService.OnModelReset(pool.ForgetUser)
+		predictor = pool
+	}
+	if cfg.Prediction.Timeout > 0 {
+		timeoutPredictor := services.NewTimeoutPredictor(predictor, cfg.Prediction.Timeout)
+		userService.OnModelReset(timeoutPredictor.ForgetUser)
+		predictor = timeoutPredictor
+	}
+	if cfg.Prediction.Guard.Enabled() {
+		guard := services.NewGuardPredictor(predictor, cfg.Prediction.Guard, predictionLog, recordService)
+		guard.SetClock(clock)
+		userService.OnModelReset(guard.ForgetUser)
+		predictor = guard
+	}
+	// The deployment's own rules, which may change what is served but not below the safety floor
+	deploymentHooks, err := hooks.New(cfg.Hooks)
+	if err != nil {
+		log.Printf("Warning: Some hooks are not enabled: %v", err)
+	}
+	predictor = deploymentHooks.Predictor(predictor)
+	recordService.OnRecordCreated(deploymentHooks.FeedbackStored)
+	// The utility's demand-response events cap heating while they last; showers they affected
+	// aren't learned from
+	notificationService := services.NewNotificationService(cfg.Notifications)
+	demandResponse := services.NewDemandResponseService(cfg.Grid, notificationService)
+	demandResponse.SetClock(clock)
+	if err := demandResponse.Load(); err != nil {
+		log.Printf("Warning: Failed to load demand-response events: %v", err)
+	}
+	demandResponse.Start()
+	recordService.SetDemandResponseLookup(annotationService)
+	predictor = services.NewDemandResponsePredictor(predictor, demandResponse)
+	// Last, so nothing served in cold weather gets under the floor
+	predictor = services.NewSafetyFloorPredictor(predictor, boilerService, cfg.Prediction.SafetyFloor.Below)
+	sloService := services.NewSLOService(cfg.SLO)
+	sloService.SetClock(clock)
+	predictor = services.NewSLOPredictor(predictor, sloService)
+
+	statsService := services.NewStatsService(recordService, changePointConfig)
+	statsService.SetAggregates(recordService)
+	statsService.SetNeighborhood(2*cfg.Prediction.V2.SigmaDuration, 2*cfg.Prediction.V2.SigmaTemp)
+	reportService := services.NewReportService(recordService, cfg.Reports)
+	reportService.Start()
+	contextService := services.NewContextService(recordService, predictor)
+	exportService := services.NewExportService(recordService, cfg.Export)
+	exportService.Start()
+	sessionService := services.NewSessionService(cfg.OIDC.SessionTTL)
+	shareService := services.NewShareService(cfg.Share.Secret, cfg.Share.MaxTTL)
+	operationService := services.NewOperationService()
+	operationService.Start()
+	maintenanceService := services.NewMaintenanceService(operationService, userService, cfg.Database.BackupDir, cfg.Records)
+	maintenanceService.Start()
+	rescoreService := services.NewRescoreService(operationService, func(records services.RecordServiceInterface, clock services.Clock) services.Predictor {
+		return newPredictor(predictorVersion, records, clock)
+	}, predictorVersion)
+	rescoreService.SetClock(clock)
+	rescoreService.Start()
+	instanceID, err := services.ResolveInstanceID(cfg.Sync.InstanceID)
+	if err != nil {
+		log.Printf("Warning: Failed to resolve instance ID: %v", err)
+	}
+	syncService := services.NewSyncService(cfg.Sync, instanceID)
+	if candidates != nil {
+		syncService.OnImported(candidates.Reset)
+	}
+	syncService.Start()
+	deviceService := services.NewDeviceStateService(cfg.Device)
+	deviceService.SetClock(clock)
+	deviceService.OnManualBoost(services.NewSatisfactionInferenceService(cfg.Inference, recordService).ManualBoost)
+	temperatureService := services.NewTemperatureService(cfg.Temperature)
+	setupService := services.NewSetupService(cfg.Setup, cfg.Temperature.Weather, boilerService, temperatureService)
+	setupService.SetClock(clock)
+	if err := setupService.Load(); err != nil {
+		log.Printf("Warning: Failed to load setup state: %v", err)
+	}
+	showerPlanService := services.NewShowerPlanService(predictor)
+	priceService := services.NewPriceService(cfg.Prices)
+	solarService := services.NewSolarService(cfg.Solar)
+	heatingScheduler := services.NewHeatingScheduler(priceService, solarService, boilerService, residualHeat)
+	heatingScheduler.SetClock(clock)
+	heatingScheduler.SetDemandResponse(demandResponse)
+	scheduleTemplateService := services.NewScheduleTemplateService()
+	householdService := services.NewHouseholdService(cfg.Households.InviteTTL)
+	scheduleTemplateService.SetHouseholdLookup(householdService)
+	boilerService.SetHouseholdLookup(householdService)
+	recordService.SetSkipLookup(scheduleTemplateService)
+	// Legionella cycles are logged apart from showers, and showers right after them aren't learned from
+	sanitationService := services.NewSanitationService(cfg.Sanitation, boilerService)
+	sanitationService.SetClock(clock)
+	deviceService.OnSanitationStarted(sanitationService.Record)
+	recordService.SetSanitationLookup(sanitationService)
+	usageService := services.NewDeviceUsageService(boilerService, priceService)
+	usageService.SetClock(clock)
+	householdService.SetNotifications(notificationService)
+	deviceService.OnHeatingStarted(services.NewHeatingNoticeService(notificationService).HeatingStarted)
+	feedbackPromptService := services.NewFeedbackPromptService(scheduleTemplateService, notificationService, cfg.Feedback)
+	feedbackPromptService.Start()
+	efficiencyService := services.NewBoilerEfficiencyService(notificationService, services.NewInletModel(cfg.Prediction.Inlet), cfg.Efficiency)
+	efficiencyService.SetBoilerService(boilerService)
+	efficiencyService.SetHouseholdLookup(householdService)
+	efficiencyService.Start()
+	if cfg.Notifications.Commands {
+		services.NewChatCommandService(notificationService, predictor, temperatureService, predictionLog, recordService, userService, cfg.Records).Start()
+	}
+
+	// A prediction's correlation ID follows it through the schedule and the devices' run
+	correlationService := services.NewCorrelationService(predictionLog)
+	deviceService.OnCorrelated(correlationService.RecordDeviceEvent)
+	if cfg.Trace.Retention > 0 {
+		services.StartLogRetention(cfg.Trace.Retention, correlationService)
 	}
 
+	// Live events for dashboards and heater controllers
+	eventHub := events.NewHub(cfg.Events.ClientBuffer, cfg.Events.MaxClients)
+	deviceService.OnApplied(func(deviceID string, status services.DeviceStatus) {
+		eventHub.Publish(events.Event{Type: events.TypeDeviceState, DeviceID: deviceID, Data: status})
+	})
+	recordService.OnRecordCreated(func(record models.DailyRecord) {
+		eventHub.Publish(events.Event{Type: events.TypeRecordCreated, UserID: record.UserID, DeviceID: record.DeviceID, Data: record})
+	})
+
 	// Initialize handlers
-	recordHandler := handler.NewRecordHandler(recordService, predictor)
+	recordHandler := handler.NewRecordHandler(recordService, userService, presetService, memberService, predictor, temperatureService, showerPlanService, boilerService, predictionLog)
+	exportHandler := handler.NewExportHandler(exportService)
+	adminHandler := handler.NewAdminHandler(userService, auditService, rescoreService, recordService, operationService, maintenanceService, canary, maxMinutes)
+	userHandler := handler.NewUserHandler(userService, contextService, regions, satisfactionTargets, buckets)
+	annotationHandler := handler.NewAnnotationHandler(annotationService)
+	statsHandler := handler.NewStatsHandler(statsService)
+	publicStatsService := services.NewPublicStatsService(cfg.PublicStats, recordService)
+	publicStatsService.SetClock(clock)
+	publicStatsHandler := handler.NewPublicStatsHandler(publicStatsService, int(cfg.PublicStats.Refresh.Seconds()))
+	configHandler := handler.NewConfigHandler(cfg)
+	reportHandler := handler.NewReportHandler(reportService)
+	quickFeedbackHandler := handler.NewQuickFeedbackHandler(predictionLog, satisfactionTargets, recordHandler)
+	importHandler := handler.NewImportHandler(services.NewCSVImportService(), recordHandler)
+	presetHandler := handler.NewPresetHandler(presetService)
+	memberHandler := handler.NewMemberHandler(memberService)
+	householdHandler := handler.NewHouseholdHandler(householdService)
+	syncHandler := handler.NewSyncHandler(syncService)
+	deviceHandler := handler.NewDeviceHandler(deviceService, boilerService, efficiencyService, sanitationService, usageService)
+	eventsHandler := handler.NewEventsHandler(eventHub, cfg.Events)
+	temperatureHandler := handler.NewTemperatureHandler(temperatureService)
+	metaHandler := handler.NewMetaHandler(cfg.Prediction, satisfactionTargets)
+	forecastHandler := handler.NewForecastHandler(services.NewForecastService(scheduleTemplateService, predictor, temperatureService, priceService, boilerService, recordService))
+	priceHandler := handler.NewPriceHandler(priceService, heatingScheduler, scheduleTemplateService, correlationService)
+	scheduleTemplateHandler := handler.NewScheduleTemplateHandler(scheduleTemplateService, shareService, services.NewScheduleConflictService(scheduleTemplateService, showerPlanService, temperatureService, boilerService, recordService))
+	solarHandler := handler.NewSolarHandler(solarService)
+	feedbackPromptHandler := handler.NewFeedbackPromptHandler(feedbackPromptService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	predictionLogHandler := handler.NewPredictionLogHandler(predictionLog)
+	diagnosticsHandler := handler.NewDiagnosticsHandler(anchorDiagnostics, contributors, sloService)
+	showerDetection := services.NewShowerDetectionService(cfg.Showers, recordService, temperatureService, predictionLog)
+	humidityHandler := handler.NewHumidityHandler(showerDetection)
+	webhookHandler := handler.NewWebhookHandler(services.NewWebhookService(cfg.Webhook, deviceService, temperatureService, recordService, showerDetection))
+	shareHandler := handler.NewShareHandler(shareService, statsService, recordService, exportService)
+	demandResponseHandler := handler.NewDemandResponseHandler(demandResponse)
+	correlationHandler := handler.NewCorrelationHandler(correlationService)
+	graphqlHandler := handler.NewGraphQLHandler(recordService, statsService, scheduleTemplateService, deviceService)
+	setupHandler := handler.NewSetupHandler(setupService, cfg.Admin.Token)
+	// First-run setup wizard; the other API routes wait until it is finished
+	setup := r.Group("/api/setup", handler.RejectWritesWhenReadOnly(dbHealth))
+	{
+		setup.GET("/status", setupHandler.GetSetupStatus)
+		setup.POST("/admin", setupHandler.CreateSetupAdmin)
+		setup.POST("/database", setupHandler.ChooseSetupDatabase)
+		setup.POST("/location", setupHandler.SetSetupLocation)
+		setup.POST("/device", setupHandler.CreateSetupDevice)
+		setup.POST("/complete", setupHandler.CompleteSetup)
+	}
+
 	// API routes
-	api := r.Group("/api")
+	api := r.Group("/api", handler.RejectWritesWhenReadOnly(dbHealth), handler.RequireSetup(setupService))
 	{
 		// Heating time calculation
 		api.POST("/calculate", recordHandler.CalculateHeatingTime)
+		api.POST("/calculate/sequence", recordHandler.CalculateShowerSequence)
 
 		// Feedback submission
 		api.POST("/feedback", recordHandler.SubmitFeedback)
+		api.POST("/feedback/batch", recordHandler.SubmitFeedbackBatch)
+		api.POST("/feedback/quick", quickFeedbackHandler.SubmitQuickFeedback)
+		api.GET("/feedback/pending", feedbackPromptHandler.GetPendingFeedback)
+		api.POST("/feedback/pending/:id/dismiss", feedbackPromptHandler.DismissPendingFeedback)
+		api.PUT("/records/:date", recordHandler.UpsertDayRecord)
+
+		// Delta sync for offline-first clients
+		api.POST("/sync", recordHandler.Sync)
 
 		// History management
 		api.GET("/history", recordHandler.GetHistory)
+		api.GET("/history/changes", recordHandler.GetHistoryChanges)
 		api.POST("/history/delete", recordHandler.DeleteRecord)
+		api.GET("/history/pending", recordHandler.GetPendingRecords)
+		api.POST("/history/:id/review", recordHandler.ReviewRecord)
+		api.GET("/history/:id/revisions", recordHandler.GetRecordRevisions)
+		api.POST("/history/:id/revert", recordHandler.RevertRecord)
 		api.POST("/history/deleteall", recordHandler.DeleteAllRecords)
 		api.GET("/history/export", recordHandler.ExportHistory)
+		api.GET("/predictions/export", predictionLogHandler.ExportPredictionLog)
+
+		// Asynchronous exports
+		api.POST("/history/export-jobs", exportHandler.CreateExportJob)
+		api.GET("/history/export-jobs", exportHandler.ListExportJobs)
+		api.GET("/history/export-jobs/:id", exportHandler.GetExportJob)
+		api.GET("/history/export-jobs/:id/download", exportHandler.DownloadExportJob)
+
+		// CSV import with proposed column mappings
+		api.POST("/imports/csv/propose", importHandler.ProposeCSVMapping)
+		api.POST("/imports/csv", importHandler.ImportCSV)
+		api.GET("/imports/csv/mappings", importHandler.ListCSVMappings)
+		api.POST("/imports/csv/mappings/delete", importHandler.DeleteCSVMapping)
+
+		// Named shower presets
+		api.POST("/presets", presetHandler.CreatePreset)
+		api.GET("/presets", presetHandler.ListPresets)
+		api.POST("/presets/update", presetHandler.UpdatePreset)
+		api.POST("/presets/delete", presetHandler.DeletePreset)
+
+		// Household member profiles
+		api.POST("/members", memberHandler.CreateMember)
+		api.GET("/members", memberHandler.ListMembers)
+		api.POST("/members/rename", memberHandler.RenameMember)
+		api.POST("/members/delete", memberHandler.DeleteMember)
+
+		// Households other accounts join through invites
+		api.GET("/households/mine", householdHandler.GetMyHousehold)
+		api.POST("/households/invites", householdHandler.CreateHouseholdInvite)
+		api.GET("/households/invites", householdHandler.ListHouseholdInvites)
+		api.POST("/households/invites/revoke", householdHandler.RevokeHouseholdInvite)
+		api.GET("/households/invites/:token", householdHandler.GetHouseholdInvite)
+		api.POST("/households/invites/:token/accept", householdHandler.AcceptHouseholdInvite)
+		api.POST("/households/leave", householdHandler.LeaveHousehold)
+
+		// Household event annotations
+		api.POST("/annotations", annotationHandler.CreateAnnotation)
+		api.GET("/annotations", annotationHandler.ListAnnotations)
+		api.POST("/annotations/delete", annotationHandler.DeleteAnnotation)
+
+		// Live water heater state
+		api.GET("/devices/:id/state", deviceHandler.GetDeviceState)
+		api.GET("/events", eventsHandler.StreamEvents)
+		api.GET("/events/ws", eventsHandler.StreamEventsWebSocket)
+		api.GET("/devices/:id/remaining", deviceHandler.GetDeviceRemaining)
+		api.POST("/devices/:id/events", deviceHandler.ReportDeviceEvent)
+		api.GET("/devices/:id/profile", deviceHandler.GetBoilerProfile)
+		api.GET("/devices/:id/efficiency", deviceHandler.GetDeviceEfficiency)
+		api.POST("/devices/:id/resolution", deviceHandler.SetDeviceResolution)
+		api.POST("/devices/:id/output", deviceHandler.SetDeviceOutputMode)
+		api.POST("/devices/:id/safety-floor", deviceHandler.SetDeviceSafetyFloor)
+		api.GET("/devices/:id/sanitation", deviceHandler.GetDeviceSanitation)
+		api.POST("/devices/:id/sanitation", deviceHandler.SetDeviceSanitation)
+		api.GET("/devices/:id/usage.csv", deviceHandler.ExportDeviceUsage)
+		api.POST("/devices/:id/preset", deviceHandler.ApplyBoilerPreset)
+		api.GET("/boiler-presets", deviceHandler.ListBoilerPresets)
+		api.POST("/devices/:id/calibration/start", deviceHandler.StartBoilerCalibration)
+		api.POST("/devices/:id/calibration/shower", deviceHandler.StartCalibrationShower)
+		api.POST("/devices/:id/calibration/cold", deviceHandler.FinishBoilerCalibration)
+
+		// Temperature sources
+		api.GET("/temperature", temperatureHandler.GetTemperature)
+		api.POST("/temperature/readings", temperatureHandler.ReportTemperatureReading)
+
+		// Bathroom humidity, checked for showers
+		api.POST("/humidity/readings", humidityHandler.ReportHumidityReading)
+
+		// Spot electricity prices, solar production and cheapest heating times
+		api.GET("/prices", priceHandler.GetEnergyPrices)
+		api.POST("/solar/forecast", solarHandler.SetSolarForecast)
+		api.POST("/solar/production", solarHandler.ReportSolarProduction)
+		api.POST("/schedule/recommend", priceHandler.RecommendHeatingSchedule)
+
+		// Recurring shower schedules
+		api.POST("/schedule-templates", scheduleTemplateHandler.CreateScheduleTemplate)
+		api.GET("/schedule-templates", scheduleTemplateHandler.ListScheduleTemplates)
+		api.POST("/schedule-templates/update", scheduleTemplateHandler.UpdateScheduleTemplate)
+		api.POST("/schedule-templates/delete", scheduleTemplateHandler.DeleteScheduleTemplate)
+		api.POST("/schedule-templates/preview", scheduleTemplateHandler.PreviewScheduleTemplate)
+		api.GET("/schedule-templates/next", scheduleTemplateHandler.GetNextScheduledShower)
+		api.POST("/schedule/snooze", scheduleTemplateHandler.SnoozeScheduledHeating)
+		api.POST("/schedule/skip", scheduleTemplateHandler.SkipScheduledHeating)
+		api.POST("/schedule/action-links", scheduleTemplateHandler.CreateScheduleActionLinks)
+		api.GET("/schedule/actions/:token", scheduleTemplateHandler.ApplyScheduleAction)
+
+		// Read-only GraphQL over history, stats, schedules and device state
+		api.POST("/graphql", graphqlHandler.ExecuteGraphQL)
+
+		// Heating forecast for budgeting
+		api.GET("/forecast/week", forecastHandler.GetWeekForecast)
+
+		// Statistics
+		api.GET("/stats/change-points", statsHandler.GetChangePoints)
+		api.GET("/stats/summary", statsHandler.GetSummary)
+		api.GET("/stats/heatmap", statsHandler.GetHeatmap)
+		api.GET("/stats/devices/compare", deviceHandler.CompareDeviceEfficiency)
+		api.GET("/stats/distribution", statsHandler.GetDistribution)
+		api.GET("/stats/reports/options", statsHandler.GetReportOptions)
+		api.POST("/stats/reports", statsHandler.RunReport)
+		api.POST("/stats/reports/saved", reportHandler.SaveReport)
+		api.GET("/stats/reports/saved", reportHandler.ListSavedReports)
+		api.GET("/stats/reports/saved/:id", reportHandler.RunSavedReport)
+		api.POST("/stats/reports/saved/delete", reportHandler.DeleteSavedReport)
+
+		// Scheduled delivery of saved reports
+		api.POST("/report-subscriptions", reportHandler.CreateReportSubscription)
+		api.GET("/report-subscriptions", reportHandler.ListReportSubscriptions)
+		api.POST("/report-subscriptions/update", reportHandler.UpdateReportSubscription)
+		api.POST("/report-subscriptions/delete", reportHandler.DeleteReportSubscription)
+
+		// Signed read-only share links
+		api.POST("/share-links", shareHandler.CreateShareLink)
+		api.GET("/shared/:token", shareHandler.GetShared)
+
+		// Anonymized instance-wide figures anyone may see, when the operator opts in
+		if cfg.PublicStats.Enabled {
+			api.GET("/public/stats", publicStatsHandler.GetPublicStats)
+		}
+
+		// Self-service account actions
+		api.POST("/users/me/model/reset", userHandler.ResetMyModel)
+		api.GET("/users/me/contexts", userHandler.ListMyContexts)
+		api.GET("/users/me/step-cap", userHandler.GetMyStepCap)
+		api.POST("/users/me/step-cap", userHandler.SetMyStepCap)
+		api.GET("/users/me/rounding", userHandler.GetMyRounding)
+		api.POST("/users/me/rounding", userHandler.SetMyRounding)
+		api.GET("/users/me/usage", userHandler.GetMyUsage)
+		api.GET("/users/me/region", userHandler.GetMyRegion)
+		api.POST("/users/me/region", userHandler.SetMyRegion)
+		api.GET("/users/me/satisfaction-target", userHandler.GetMySatisfactionTarget)
+		api.POST("/users/me/satisfaction-target", userHandler.SetMySatisfactionTarget)
+		api.GET("/users/me/satisfaction-calibration", userHandler.GetMySatisfactionCalibration)
+		api.GET("/users/me/context-buckets", userHandler.GetMyContextBuckets)
+		api.GET("/users/me/prediction-profile", userHandler.GetMyPredictionProfile)
+		api.POST("/users/me/prediction-profile", userHandler.ImportMyPredictionProfile)
+		api.GET("/users/me/temperature-sources", temperatureHandler.GetTemperatureSources)
+		api.POST("/users/me/temperature-sources", temperatureHandler.SetTemperatureSources)
+		api.GET("/users/me/notification-settings", notificationHandler.GetNotificationSettings)
+		api.POST("/users/me/notification-settings", notificationHandler.SetNotificationSettings)
+		api.GET("/users/me/notifications", notificationHandler.GetNotificationLog)
+		api.GET("/users/me/notification-routes", notificationHandler.ListNotificationRoutes)
+		api.POST("/users/me/notification-routes", notificationHandler.CreateNotificationRoute)
+		api.POST("/users/me/notification-routes/delete", notificationHandler.DeleteNotificationRoute)
+		api.GET("/notification-channels", notificationHandler.ListNotificationChannels)
+
+		// OpenID Connect login
+		if cfg.OIDC.Enabled() {
+			oidcService := services.NewOIDCService(cfg.OIDC)
+			authHandler := handler.NewAuthHandler(oidcService, sessionService, userService, cfg.OIDC)
+			api.GET("/auth/oidc/login", authHandler.Login)
+			api.GET("/auth/oidc/callback", authHandler.Callback)
+			api.GET("/auth/me", authHandler.Me)
+			api.POST("/auth/logout", authHandler.Logout)
+		}
 
 		// Health check
 		api.GET("/health", func(c *gin.Context) {
 			c.String(200, "OK")
 		})
+		api.GET("/instance", syncHandler.GetInstance)
+
+		// How the server interprets client input
+		api.GET("/meta/satisfaction-scale", metaHandler.GetSatisfactionScale)
+		api.GET("/meta/limits", metaHandler.GetValidationLimits)
+		api.GET("/meta/adjustment-curve", metaHandler.GetAdjustmentCurve)
+		api.GET("/meta/schema", metaHandler.GetSchema)
+		api.GET("/meta/changelog", metaHandler.GetChangelog)
+
+		// Instance-to-instance sync, authenticated with the shared sync token
+		if cfg.Sync.Enabled() {
+			sync := api.Group("/sync", handler.SyncAuth(cfg.Sync.Token))
+			sync.GET("/export", syncHandler.ExportChanges)
+			sync.POST("/import", syncHandler.ImportChanges)
+			sync.POST("/run", syncHandler.RunSync)
+		}
+
+		// Demand-response signals from the utility, authenticated with their own token
+		api.GET("/grid/demand-response", demandResponseHandler.ListDemandResponseEvents)
+		if cfg.Grid.Enabled() {
+			grid := api.Group("/grid/demand-response", handler.DemandResponseAuth(cfg.Grid.Token))
+			grid.POST("", demandResponseHandler.ReceiveDemandResponseSignal)
+			grid.POST("/:id/end", demandResponseHandler.EndDemandResponseEvent)
+		}
+
+		// Events pushed by third-party services, signed with the webhook secret
+		if cfg.Webhook.Enabled() {
+			api.POST("/webhooks/:source", webhookHandler.ReceiveWebhook)
+		}
+
+		api.GET("/health/db", func(c *gin.Context) {
+			c.JSON(200, dbHealth.Status())
+		})
+	}
+
+	// Liveness and readiness probes; before the router exists the startup gate answers them
+	r.GET("/healthz", func(c *gin.Context) {
+		c.String(200, "OK")
+	})
+	r.GET("/readyz", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ready"})
+	})
+
+	// Prometheus metrics
+	if cfg.Metrics.Enabled {
+		r.GET("/metrics", handler.Metrics(sloService))
+	}
+
+	// Admin routes
+	admin := r.Group("/api/admin", handler.AdminAuth(cfg.Admin.Token, sessionService, setupService), handler.RejectWritesWhenReadOnly(dbHealth))
+	{
+		admin.GET("/users", adminHandler.ListUsers)
+		admin.GET("/users/:id", adminHandler.GetUser)
+		admin.POST("/users/:id/disable", adminHandler.DisableUser)
+		admin.POST("/users/:id/enable", adminHandler.EnableUser)
+		admin.POST("/users/:id/model/reset", adminHandler.ResetUserModel)
+		admin.POST("/users/:id/impersonate", adminHandler.ImpersonateUser)
+		admin.POST("/users/:id/merge", adminHandler.MergeUser)
+		admin.GET("/audit", adminHandler.ListAuditLog)
+		admin.POST("/rescore-jobs", adminHandler.CreateRescoreJob)
+		admin.GET("/rescore-jobs", adminHandler.ListRescoreJobs)
+		admin.GET("/rescore-jobs/:id", adminHandler.GetRescoreJob)
+		admin.GET("/rescore-jobs/:id/results", adminHandler.ListRescoreResults)
+		admin.GET("/operations", adminHandler.ListOperations)
+		admin.GET("/operations/:id", adminHandler.GetOperation)
+		admin.POST("/operations/:id/cancel", adminHandler.CancelOperation)
+		admin.POST("/backups", adminHandler.CreateBackup)
+		admin.POST("/compact", adminHandler.CompactDatabase)
+		admin.POST("/records/archive", adminHandler.ArchiveRecords)
+		admin.GET("/diagnostics/anchors", diagnosticsHandler.GetAnchorDiagnostics)
+		admin.GET("/diagnostics/contributors", diagnosticsHandler.GetContributorReliability)
+		admin.GET("/slo", diagnosticsHandler.GetSLO)
+		admin.GET("/predictions/held", predictionLogHandler.ListHeldPredictions)
+		admin.GET("/trace", correlationHandler.GetTrace)
+		admin.GET("/overview", statsHandler.GetOverview)
+		admin.GET("/config", configHandler.GetEffectiveConfig)
+		admin.GET("/prediction-profile", adminHandler.ExportPredictionProfile)
+		admin.POST("/prediction-profile", adminHandler.ImportPredictionProfile)
+		admin.GET("/integrity", adminHandler.CheckIntegrity)
+		admin.POST("/integrity/fix", adminHandler.FixIntegrity)
+		admin.POST("/integrity/exclude", adminHandler.ExcludeIntegrity)
+		admin.POST("/integrity/sign", adminHandler.SignIntegrity)
+		admin.GET("/data-quality", adminHandler.GetDataQuality)
+
+		if canary != nil {
+			admin.GET("/canary", adminHandler.GetCanary)
+			admin.POST("/canary", adminHandler.UpdateCanary)
+		}
 	}
 
 	return r
 }
+
+// ServeFrontend serves the web app's built files on every path the API leaves free
+func ServeFrontend(r *gin.Engine, files fs.FS) error {
+	frontend, err := handler.NewFrontendHandler(files)
+	if err != nil {
+		return err
+	}
+	r.NoRoute(frontend.ServeFrontend)
+	return nil
+}
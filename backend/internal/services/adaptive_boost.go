@@ -0,0 +1,134 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"heat-logger/internal/models"
+)
+
+const (
+	adaptiveBoostMin        = 0.25 // never let global data fully drown out the user's own history
+	adaptiveBoostMax        = 8.0  // ...nor the other way round
+	adaptiveBoostEpsilon    = 0.02 // error floor so a perfect source doesn't get infinite weight
+	adaptiveBoostMinSamples = 3    // evaluations needed before trusting the measured errors
+)
+
+// PredictionExplanation describes how a v2 prediction was put together
+type PredictionExplanation struct {
+	UserWeight   float64  `json:"userWeight"`            // share of neighbor weight from the user's own records
+	GlobalWeight float64  `json:"globalWeight"`          // share of neighbor weight from other users' records
+	UserBoost    float64  `json:"userBoost"`             // multiplier applied to the user's records
+	BoostSource  string   `json:"boostSource"`           // "adaptive" or "fixed"
+	UserError    *float64 `json:"userError,omitempty"`   // recent mean relative error of user-only estimates
+	GlobalError  *float64 `json:"globalError,omitempty"` // recent mean relative error of global-only estimates
+	Neighbors    int      `json:"neighbors"`
+	Confidence   float64  `json:"confidence"`   // 0..1, how many and how consistent the neighbors are
+	Contributors int      `json:"contributors"` // distinct other users among the neighbors
+
+	// AnonymityFallback is set when other users' records would have led the estimate but came
+	// from too few of them, so the device's physics baseline was served instead
+	AnonymityFallback bool `json:"anonymityFallback,omitempty"`
+
+	// PersonalRecordsHeldBack counts the user's records left out because there are fewer of
+	// them than MinPersonalRecords
+	PersonalRecordsHeldBack int `json:"personalRecordsHeldBack,omitempty"`
+
+	Region               string `json:"region,omitempty"`               // the user's climate region, when known
+	CrossRegionNeighbors int    `json:"crossRegionNeighbors,omitempty"` // neighbors from other users in another region
+
+	Anchors    int     `json:"anchors"`              // near-perfect records among the neighbors
+	AnchorPull float64 `json:"anchorPull,omitempty"` // how far the estimate was blended toward the anchors, 0-1
+
+	Exploration *ExplorationInfo `json:"exploration,omitempty"` // set while the context is still being explored
+
+	ResidualHeatMinutes float64 `json:"residualHeatMinutes,omitempty"` // heating still stored from the last session, subtracted
+
+	StepCapBypassed bool `json:"stepCapBypassed,omitempty"` // a large adjustment was allowed past the step cap
+
+	Locality *BucketNode `json:"locality,omitempty"` // the context bucket the kernels were sized by
+
+	SafetyFloor *SafetyFloorInfo `json:"safetyFloor,omitempty"` // set when cold weather raised the prediction to the device's floor
+
+	Hooks []string `json:"hooks,omitempty"` // deployment hooks that changed the heating time, in the order they ran
+}
+
+// sourceErrors replays the user's most recent records in this context and measures how well
+// user-only and global-only data (as known at the time) would have predicted each outcome.
+func (s *PredictionServiceV2) sourceErrors(req PredictionRequest, userRecords, globalRecords []models.DailyRecord) (userErr, globalErr float64, ok bool) {
+	var evals []models.DailyRecord
+	for _, r := range userRecords {
+		if math.Abs(r.ShowerDuration-req.Duration) > 2*s.cfg.SigmaDuration ||
+			math.Abs(r.AverageTemperature-req.Temperature) > 2*s.cfg.SigmaTemp {
+			continue
+		}
+		evals = append(evals, r)
+	}
+	sort.Slice(evals, func(i, j int) bool { return evals[i].Date.After(evals[j].Date) })
+	if len(evals) > s.cfg.AdaptiveBoostWindow {
+		evals = evals[:s.cfg.AdaptiveBoostWindow]
+	}
+
+	var userSum, globalSum float64
+	samples := 0
+	for _, e := range evals {
+		truth := impliedTarget(e)
+		if truth <= 0 {
+			continue
+		}
+		userEst, okUser := s.sourceEstimate(userRecords, e)
+		globalEst, okGlobal := s.sourceEstimate(globalRecords, e)
+		if !okUser || !okGlobal {
+			continue
+		}
+		userSum += math.Abs(userEst-truth) / truth
+		globalSum += math.Abs(globalEst-truth) / truth
+		samples++
+	}
+
+	if samples < adaptiveBoostMinSamples {
+		return 0, 0, false
+	}
+	return userSum / float64(samples), globalSum / float64(samples), true
+}
+
+// sourceEstimate predicts the implied target for target using only records dated before it
+func (s *PredictionServiceV2) sourceEstimate(records []models.DailyRecord, target models.DailyRecord) (float64, bool) {
+	var sum, totalW float64
+	for _, r := range records {
+		if !r.Date.Before(target.Date) {
+			continue
+		}
+		w := gaussian(target.ShowerDuration-r.ShowerDuration, s.cfg.SigmaDuration) *
+			gaussian(target.AverageTemperature-r.AverageTemperature, s.cfg.SigmaTemp) *
+			expHalfLife(target.Date.Sub(r.Date).Hours()/24.0, s.cfg.RecencyHalfLifeDays)
+		sum += impliedTarget(r) * w
+		totalW += w
+	}
+	if totalW < 1e-9 {
+		return 0, false
+	}
+	return sum / totalW, true
+}
+
+// userBoostFor weights user vs global data inversely to their recent error, falling back
+// to the configured fixed boost when there isn't enough history to measure either source.
+func (s *PredictionServiceV2) userBoostFor(req PredictionRequest, userRecords, globalRecords []models.DailyRecord, expl *PredictionExplanation) float64 {
+	expl.UserBoost = s.cfg.UserBoost
+	expl.BoostSource = "fixed"
+	if s.cfg.FixedUserBoost {
+		return s.cfg.UserBoost
+	}
+
+	userErr, globalErr, ok := s.sourceErrors(req, userRecords, globalRecords)
+	if !ok {
+		return s.cfg.UserBoost
+	}
+
+	boost := clamp((globalErr+adaptiveBoostEpsilon)/(userErr+adaptiveBoostEpsilon), adaptiveBoostMin, adaptiveBoostMax)
+	expl.UserBoost = boost
+	expl.BoostSource = "adaptive"
+	expl.UserError = &userErr
+	expl.GlobalError = &globalErr
+	return boost
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// AnchorPolicy is how v2 treats near-perfect feedback
+type AnchorPolicy struct {
+	Epsilon float64 `json:"epsilon"` // satisfaction distance from 50 that counts as an anchor
+	Boost   float64 `json:"boost"`   // weight multiplier for anchors
+	Blend   float64 `json:"blend"`   // how far anchors may pull the estimate, 0-1
+}
+
+// anchorSample is how much anchors contributed to one prediction
+type anchorSample struct {
+	at      time.Time
+	anchors int
+	pull    float64
+}
+
+// AnchorSummary reports how often anchors fired over recent predictions
+type AnchorSummary struct {
+	Predictions int        `json:"predictions"`
+	Fired       int        `json:"fired"` // predictions with at least one anchor among the neighbors
+	FireRate    float64    `json:"fireRate"`
+	MeanAnchors float64    `json:"meanAnchors"`     // anchors per prediction
+	MeanPull    float64    `json:"meanPull"`        // average blend toward the anchor estimate
+	Since       *time.Time `json:"since,omitempty"` // time of the oldest prediction covered
+
+	Policy AnchorPolicy `json:"policy"`
+}
+
+// AnchorDiagnostics keeps the anchor contribution of the most recent v2 predictions in memory
+type AnchorDiagnostics struct {
+	mu      sync.Mutex
+	policy  AnchorPolicy
+	samples []anchorSample // ring buffer
+	next    int
+	full    bool
+}
+
+// NewAnchorDiagnostics keeps the last size predictions
+func NewAnchorDiagnostics(size int) *AnchorDiagnostics {
+	if size < 1 {
+		size = 1
+	}
+	return &AnchorDiagnostics{samples: make([]anchorSample, size)}
+}
+
+// Observe records one prediction. It does nothing on a nil receiver, so predictors built for
+// re-scoring don't need diagnostics.
+func (d *AnchorDiagnostics) Observe(anchors int, pull float64) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.samples[d.next] = anchorSample{at: time.Now(), anchors: anchors, pull: pull}
+	d.next = (d.next + 1) % len(d.samples)
+	if d.next == 0 {
+		d.full = true
+	}
+}
+
+// setPolicy records the anchor settings of the predictor reporting here
+func (d *AnchorDiagnostics) setPolicy(policy AnchorPolicy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policy = policy
+}
+
+// Summary describes the last n predictions; n below 1 or beyond what is kept covers all of them
+func (d *AnchorDiagnostics) Summary(n int) AnchorSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	kept := d.next
+	if d.full {
+		kept = len(d.samples)
+	}
+	if n < 1 || n > kept {
+		n = kept
+	}
+
+	summary := AnchorSummary{Policy: d.policy}
+	var anchors int
+	var pull float64
+	for i := 1; i <= n; i++ {
+		sample := d.samples[(d.next-i+len(d.samples))%len(d.samples)]
+		summary.Predictions++
+		if sample.anchors > 0 {
+			summary.Fired++
+		}
+		anchors += sample.anchors
+		pull += sample.pull
+		at := sample.at
+		summary.Since = &at
+	}
+	if summary.Predictions > 0 {
+		total := float64(summary.Predictions)
+		summary.FireRate = float64(summary.Fired) / total
+		summary.MeanAnchors = float64(anchors) / total
+		summary.MeanPull = pull / total
+	}
+	return summary
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnchorDiagnostics_Summary(t *testing.T) {
+	diagnostics := NewAnchorDiagnostics(3)
+	assert.Equal(t, 0, diagnostics.Summary(0).Predictions)
+
+	diagnostics.Observe(0, 0)
+	diagnostics.Observe(4, 0.2)
+	diagnostics.Observe(2, 0.1)
+	diagnostics.Observe(0, 0) // pushes out the first prediction
+
+	all := diagnostics.Summary(0)
+	assert.Equal(t, 3, all.Predictions)
+	assert.Equal(t, 2, all.Fired)
+	assert.InDelta(t, 2.0/3, all.FireRate, 1e-9)
+	assert.InDelta(t, 2.0, all.MeanAnchors, 1e-9)
+	assert.InDelta(t, 0.1, all.MeanPull, 1e-9)
+
+	last := diagnostics.Summary(2)
+	assert.Equal(t, 2, last.Predictions)
+	assert.Equal(t, 1, last.Fired)
+}
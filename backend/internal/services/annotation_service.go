@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrAnnotationNotFound is returned when an annotation does not exist for the user
+var ErrAnnotationNotFound = errors.New("annotation not found")
+
+// annotationKinds lists the accepted annotation kinds and whether they imply new hardware by default
+var annotationKinds = map[string]bool{
+	models.AnnotationBoilerServiced:      false,
+	models.AnnotationShowerheadInstalled: true,
+	models.AnnotationThermostatReplaced:  true,
+	models.AnnotationBoilerReplaced:      true,
+	models.AnnotationOther:               false,
+}
+
+// AnnotationLookup reports household events that change how history should be weighted
+type AnnotationLookup interface {
+	LatestHardwareChange(userID string) (*time.Time, error)
+}
+
+// AnnotationService handles household event annotations
+type AnnotationService struct {
+	db *gorm.DB
+}
+
+// NewAnnotationService creates a new annotation service instance
+func NewAnnotationService() *AnnotationService {
+	return &AnnotationService{
+		db: database.GetDB(),
+	}
+}
+
+// IsValidAnnotationKind reports whether kind is a known annotation kind
+func IsValidAnnotationKind(kind string) bool {
+	_, ok := annotationKinds[kind]
+	return ok
+}
+
+// ImpliesHardwareChange reports whether an annotation kind implies changed hardware by default
+func ImpliesHardwareChange(kind string) bool {
+	return annotationKinds[kind]
+}
+
+// CreateAnnotation stores a new annotation
+func (s *AnnotationService) CreateAnnotation(annotation *models.Annotation) error {
+	if annotation.Date.IsZero() {
+		annotation.Date = time.Now()
+	}
+	return s.db.Create(annotation).Error
+}
+
+// ListAnnotations returns the user's annotations in date order, optionally bounded by from/to
+func (s *AnnotationService) ListAnnotations(userID string, from, to *time.Time) ([]models.Annotation, error) {
+	var annotations []models.Annotation
+	query := s.db.Where("user_id = ?", userID)
+	if from != nil {
+		query = query.Where("date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("date <= ?", *to)
+	}
+	err := query.Order("date ASC").Find(&annotations).Error
+	return annotations, err
+}
+
+// DeleteAnnotation deletes one of the user's annotations
+func (s *AnnotationService) DeleteAnnotation(userID, id string) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Annotation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAnnotationNotFound
+	}
+	return nil
+}
+
+// LatestHardwareChange returns the date of the user's most recent hardware-changing annotation, if any
+func (s *AnnotationService) LatestHardwareChange(userID string) (*time.Time, error) {
+	var annotation models.Annotation
+	result := s.db.Where("user_id = ? AND hardware_changed = ?", userID, true).
+		Order("date DESC").Limit(1).Find(&annotation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &annotation.Date, nil
+}
+
+// InterruptedAt reports whether an annotation that lasts a while, such as a demand-response
+// event, covers a shower the user took at at
+func (s *AnnotationService) InterruptedAt(userID string, at time.Time) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Annotation{}).
+		Where("user_id = ? AND until IS NOT NULL AND date <= ? AND until >= ?", userID, at, at).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"math"
+
+	"heat-logger/internal/models"
+)
+
+// Other users' showers reach a prediction only through this layer: their records are
+// quantized as they enter the global pool, and a prediction they lead must draw on enough
+// of them (see otherContributors).
+
+// Quantizer rounds the context of other users' records to the middle of fixed-width bands,
+// so an exact shower length or temperature reading can't be matched back to a household.
+// A zero width leaves that value exact.
+type Quantizer struct {
+	DurationWidth    float64 // minutes
+	TemperatureWidth float64 // °C
+}
+
+// Enabled reports whether the quantizer changes anything
+func (q Quantizer) Enabled() bool {
+	return q.DurationWidth > 0 || q.TemperatureWidth > 0
+}
+
+// Quantize returns the records with their shower duration and outdoor temperature moved to
+// the middle of their band. The records passed in are left untouched, as they may be shared
+// with a cache.
+func (q Quantizer) Quantize(records []models.DailyRecord) []models.DailyRecord {
+	if !q.Enabled() || len(records) == 0 {
+		return records
+	}
+	out := append([]models.DailyRecord(nil), records...)
+	for i := range out {
+		out[i].ShowerDuration = quantize(out[i].ShowerDuration, q.DurationWidth)
+		out[i].AverageTemperature = quantize(out[i].AverageTemperature, q.TemperatureWidth)
+	}
+	return out
+}
+
+// quantize returns the middle of value's band, bands starting at multiples of width
+func quantize(value, width float64) float64 {
+	if width <= 0 {
+		return value
+	}
+	return math.Floor(value/width)*width + width/2
+}
+
+// otherContributors returns how many users other than userID the records came from, and the
+// share of the records' weight they carry
+func otherContributors(recs []recWrap, userID string) (int, float64) {
+	users := make(map[string]bool)
+	var others, total float64
+	for _, r := range recs {
+		total += r.weight
+		if r.rec.UserID != userID {
+			users[r.rec.UserID] = true
+			others += r.weight
+		}
+	}
+	if total == 0 {
+		return len(users), 0
+	}
+	return len(users), others / total
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// AuditService records privileged actions
+type AuditService struct {
+	db      *gorm.DB
+	archive *LogArchive
+}
+
+// NewAuditService creates a new audit service instance
+func NewAuditService() *AuditService {
+	return &AuditService{
+		db: database.GetLogDB(),
+	}
+}
+
+// SetArchive also appends every audit entry to archive
+func (s *AuditService) SetArchive(archive *LogArchive) {
+	s.archive = archive
+}
+
+// Record stores an audit entry
+func (s *AuditService) Record(actor, action, target, details string) error {
+	entry := &models.AuditLog{
+		Actor:   actor,
+		Action:  action,
+		Target:  target,
+		Details: details,
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		return err
+	}
+	if s.archive != nil {
+		if err := s.archive.Append(entry); err != nil {
+			log.Printf("Failed to archive audit entry %s: %v", entry.ID, err)
+		}
+	}
+	return nil
+}
+
+// Prune deletes the audit entries recorded before before
+func (s *AuditService) Prune(before time.Time) (int64, error) {
+	result := s.db.Where("created_at < ?", before).Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+// List returns the most recent audit entries, newest first
+func (s *AuditService) List(limit int) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	err := s.db.Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
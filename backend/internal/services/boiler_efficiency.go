@@ -0,0 +1,379 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// efficiencyCheckInterval is how often every device's trend is checked
+	efficiencyCheckInterval = 6 * time.Hour
+	// efficiencySetpoint is the temperature a tank is assumed to be heated to, °C. Records are
+	// scaled by how far their inlet water is below it, so colder mains water in winter doesn't
+	// read as a degrading heater.
+	efficiencySetpoint = 60.0
+	// minTrendSpan is the shortest stretch of history a trend is fitted over
+	minTrendSpan = 21 * 24 * time.Hour
+)
+
+// EfficiencyWeek is the mean required heating of one week's records, starting on Monday
+type EfficiencyWeek struct {
+	WeekStart string  `json:"weekStart"` // YYYY-MM-DD
+	Records   int     `json:"records"`
+	Level     float64 `json:"level"` // required heating relative to the context baseline
+}
+
+// EfficiencyTrend is a straight line fitted through the levels of a device's records
+type EfficiencyTrend struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	LevelStart    float64   `json:"levelStart"`    // fitted level at From
+	LevelEnd      float64   `json:"levelEnd"`      // fitted level at To
+	RisePercent   float64   `json:"risePercent"`   // change from LevelStart to LevelEnd
+	WeeklyPercent float64   `json:"weeklyPercent"` // change per week, relative to LevelStart
+	Score         float64   `json:"score"`         // t-score of the slope
+}
+
+// EfficiencyReport says whether a water heater needs more heating over time for the same
+// showers, with the weekly levels behind the verdict
+type EfficiencyReport struct {
+	UserID    string           `json:"userId"`
+	DeviceID  string           `json:"deviceId"`
+	Records   int              `json:"records"`
+	Weekly    []EfficiencyWeek `json:"weekly"`
+	Trend     *EfficiencyTrend `json:"trend,omitempty"` // nil with too few records or too short a history
+	Degrading bool             `json:"degrading"`
+}
+
+// efficiencyLevel is the heating a record implies was needed, relative to the baseline for its
+// context and scaled to the average inlet temperature
+func efficiencyLevel(r models.DailyRecord, inlet InletModel) float64 {
+	rise := math.Max(efficiencySetpoint-inlet.ForRecord(r), 5)
+	return impliedTarget(r) / contextBaseline(r.ShowerDuration, r.AverageTemperature) *
+		(efficiencySetpoint - inlet.Mean) / rise
+}
+
+// DetectEfficiencyTrend fits a least-squares line through the efficiency levels of one device's
+// records, which may be in any order. The device is degrading when the line rises by at least
+// MinRise with a t-score of at least MinScore.
+func DetectEfficiencyTrend(records []models.DailyRecord, inlet InletModel, cfg config.EfficiencyConfig) (*EfficiencyTrend, bool) {
+	if len(records) < cfg.MinRecords || len(records) < 3 {
+		return nil, false
+	}
+	sorted := make([]models.DailyRecord, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	from, to := sorted[0].Date, sorted[len(sorted)-1].Date
+	if to.Sub(from) < minTrendSpan {
+		return nil, false
+	}
+
+	week := 7 * 24 * time.Hour
+	n := float64(len(sorted))
+	xs := make([]float64, len(sorted))
+	ys := make([]float64, len(sorted))
+	var meanX, meanY float64
+	for i, r := range sorted {
+		xs[i] = float64(r.Date.Sub(from)) / float64(week)
+		ys[i] = efficiencyLevel(r, inlet)
+		meanX += xs[i] / n
+		meanY += ys[i] / n
+	}
+	var sxx, sxy float64
+	for i := range xs {
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+		sxy += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+	var residuals float64
+	for i := range xs {
+		r := ys[i] - (intercept + slope*xs[i])
+		residuals += r * r
+	}
+	// Floor the variance so perfectly consistent records don't produce infinite scores
+	stderr := math.Sqrt(math.Max(residuals/(n-2), 1e-4) / sxx)
+
+	span := float64(to.Sub(from)) / float64(week)
+	trend := &EfficiencyTrend{
+		From:       from,
+		To:         to,
+		LevelStart: roundTo(intercept, 3),
+		LevelEnd:   roundTo(intercept+slope*span, 3),
+		Score:      roundTo(slope/stderr, 2),
+	}
+	if intercept <= 0 {
+		return trend, false
+	}
+	rise := slope * span / intercept
+	trend.RisePercent = roundTo(rise*100, 1)
+	trend.WeeklyPercent = roundTo(slope/intercept*100, 2)
+	return trend, rise >= cfg.MinRise && slope/stderr >= cfg.MinScore
+}
+
+// efficiencyWeeks averages the records' levels per week, oldest first
+func efficiencyWeeks(records []models.DailyRecord, inlet InletModel) []EfficiencyWeek {
+	sums := map[string]*EfficiencyWeek{}
+	for _, r := range records {
+		day := startOfDay(r.Date)
+		start := day.AddDate(0, 0, -(int(day.Weekday())+6)%7).Format("2006-01-02")
+		week, ok := sums[start]
+		if !ok {
+			week = &EfficiencyWeek{WeekStart: start}
+			sums[start] = week
+		}
+		week.Records++
+		week.Level += efficiencyLevel(r, inlet)
+	}
+	weeks := make([]EfficiencyWeek, 0, len(sums))
+	for _, week := range sums {
+		week.Level = roundTo(week.Level/float64(week.Records), 3)
+		weeks = append(weeks, *week)
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].WeekStart < weeks[j].WeekStart })
+	return weeks
+}
+
+// BoilerEfficiencyService watches each device's history for a heater that needs more and more
+// heating for the same showers, from scale buildup or a failing element, and tells the user
+type BoilerEfficiencyService struct {
+	db            *gorm.DB
+	notifications *NotificationService
+	inlet         InletModel
+	cfg           config.EfficiencyConfig
+	boilers       *BoilerService
+	households    HouseholdLookup
+}
+
+// NewBoilerEfficiencyService creates a new boiler efficiency service instance
+func NewBoilerEfficiencyService(notifications *NotificationService, inlet InletModel, cfg config.EfficiencyConfig) *BoilerEfficiencyService {
+	return &BoilerEfficiencyService{
+		db:            database.GetDB(),
+		notifications: notifications,
+		inlet:         inlet,
+		cfg:           cfg,
+	}
+}
+
+// SetBoilerService enables comparing devices by the water they heat, from their calibrated
+// tank, flow rate and heater power
+func (s *BoilerEfficiencyService) SetBoilerService(boilers *BoilerService) {
+	s.boilers = boilers
+}
+
+// SetHouseholdLookup compares the devices of the user's whole household, with everyone's records
+func (s *BoilerEfficiencyService) SetHouseholdLookup(households HouseholdLookup) {
+	s.households = households
+}
+
+// Start launches the worker that checks every device and notifies about degrading ones
+func (s *BoilerEfficiencyService) Start() {
+	if !s.cfg.Enabled() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(efficiencyCheckInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.NotifyDegrading(time.Now()); err != nil {
+				log.Printf("Failed to check boiler efficiency: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Report fits the trend over the device's training records in the configured window
+func (s *BoilerEfficiencyService) Report(userID, deviceID string, now time.Time) (*EfficiencyReport, error) {
+	var records []models.DailyRecord
+	err := s.window(now).Where("user_id = ? AND device_id = ?", userID, deviceID).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	report := &EfficiencyReport{
+		UserID:   userID,
+		DeviceID: deviceID,
+		Records:  len(records),
+		Weekly:   efficiencyWeeks(records, s.inlet),
+	}
+	report.Trend, report.Degrading = DetectEfficiencyTrend(records, s.inlet, s.cfg)
+	return report, nil
+}
+
+// NotifyDegrading checks every device with records in the window and notifies the owners of
+// degrading ones that haven't been told recently. A notification held back by quiet hours or
+// the daily cap is tried again on the next check.
+func (s *BoilerEfficiencyService) NotifyDegrading(now time.Time) error {
+	if !s.notifications.Enabled() {
+		return nil
+	}
+	var devices []struct {
+		UserID   string
+		DeviceID string
+	}
+	if err := s.window(now).Distinct("user_id", "device_id").Scan(&devices).Error; err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		var profile models.BoilerProfile
+		err := s.db.Where("user_id = ? AND device_id = ?", device.UserID, device.DeviceID).Limit(1).Find(&profile).Error
+		if err != nil {
+			return err
+		}
+		if profile.EfficiencyNotifiedAt != nil && now.Sub(*profile.EfficiencyNotifiedAt) < s.cfg.Renotify {
+			continue
+		}
+		report, err := s.Report(device.UserID, device.DeviceID, now)
+		if err != nil {
+			return err
+		}
+		if !report.Degrading {
+			continue
+		}
+
+		name := device.DeviceID
+		if name == "" {
+			name = "water heater"
+		}
+		message := fmt.Sprintf("Your %s needs %.0f%% more heating for the same showers than %d weeks ago. Scale buildup or a failing element may be the cause.",
+			name, report.Trend.RisePercent, s.cfg.Weeks)
+		entry, err := s.notifications.Notify(device.UserID, models.NotificationBoilerEfficiency, message, map[string]interface{}{
+			"deviceId": device.DeviceID,
+			"trend":    report.Trend,
+			"weekly":   report.Weekly,
+		}, now)
+		if err != nil {
+			log.Printf("Failed to send boiler efficiency notification for %s/%s: %v", device.UserID, device.DeviceID, err)
+			continue
+		}
+		if entry.Status != models.NotificationSent {
+			continue
+		}
+		err = s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "device_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"efficiency_notified_at", "updated_at"}),
+		}).Create(&models.BoilerProfile{UserID: device.UserID, DeviceID: device.DeviceID, EfficiencyNotifiedAt: &now}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// window selects the training records of the configured number of weeks before now
+func (s *BoilerEfficiencyService) window(now time.Time) *gorm.DB {
+	return s.db.Model(&models.DailyRecord{}).
+		Where("excluded_from_training = ? AND date >= ?", false, now.AddDate(0, 0, -7*s.cfg.Weeks))
+}
+
+// DeviceEfficiency is how much heating a device needs for the water its showers use
+type DeviceEfficiency struct {
+	DeviceID string `json:"deviceId"` // empty for records without a device
+	Records  int    `json:"records"`
+	// Heating needed per litre of hot water per degree it is raised from the inlet temperature,
+	// the satisfaction-adjusted heating time of each record over its litres and rise
+	MinutesPerLitreDegree float64 `json:"minutesPerLitreDegree"`
+	Relative              float64 `json:"relative"`    // MinutesPerLitreDegree over the best device's; 1 for the best
+	HeaterPower           float64 `json:"heaterPower"` // kW
+	// Share of the electricity drawn that ends up in the shower water. Heat kept in the tank
+	// from earlier can take it above 1.
+	Efficiency float64 `json:"efficiency"`
+}
+
+// DeviceComparison compares a household's devices, most efficient first
+type DeviceComparison struct {
+	UserID  string             `json:"userId"`
+	Since   *time.Time         `json:"since,omitempty"` // nil when all history is compared
+	Devices []DeviceEfficiency `json:"devices"`
+}
+
+// Compare normalises the heating the user's devices needed by the litres their showers drew and
+// the degrees the water was raised, so boilers of different sizes and in different places can be
+// compared. Records in the configured window are used, or all of them when detection is off;
+// devices whose flow rate is unknown are left out.
+func (s *BoilerEfficiencyService) Compare(userID string, now time.Time) (*DeviceComparison, error) {
+	userIDs := []string{userID}
+	if s.households != nil {
+		household, err := s.households.HouseholdUserIDs(userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(household) > 0 {
+			userIDs = household
+		}
+	}
+
+	comparison := &DeviceComparison{UserID: userID, Devices: []DeviceEfficiency{}}
+	if s.boilers == nil {
+		return comparison, nil
+	}
+	query := s.db.Model(&models.DailyRecord{}).Where("excluded_from_training = ?", false)
+	if s.cfg.Weeks > 0 {
+		query = s.window(now)
+		since := now.AddDate(0, 0, -7*s.cfg.Weeks)
+		comparison.Since = &since
+	}
+	var records []models.DailyRecord
+	if err := query.Where("user_id IN ?", userIDs).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	byDevice := map[string][]models.DailyRecord{}
+	for _, r := range records {
+		byDevice[r.DeviceID] = append(byDevice[r.DeviceID], r)
+	}
+
+	for deviceID, records := range byDevice {
+		tank, power := s.boilers.Tank(userID, deviceID), s.boilers.HeaterPower(userID, deviceID)
+		if tank.FlowRate <= 0 {
+			continue
+		}
+		var minutes, litreDegrees float64
+		for _, r := range records {
+			litres := r.ShowerDuration * tank.FlowRate
+			if tank.Size > 0 {
+				litres = math.Min(litres, tank.Size)
+			}
+			minutes += impliedTarget(r)
+			litreDegrees += litres * math.Max(efficiencySetpoint-s.inlet.ForRecord(r), 5)
+		}
+		if litreDegrees <= 0 {
+			continue
+		}
+		device := DeviceEfficiency{
+			DeviceID:              deviceID,
+			Records:               len(records),
+			MinutesPerLitreDegree: minutes / litreDegrees,
+			HeaterPower:           power,
+		}
+		// kWh the heater drew against kWh the water took
+		if drawn := minutes / 60 * power; drawn > 0 {
+			device.Efficiency = roundTo(litreDegrees*waterHeatCapacity/3600/drawn, 3)
+		}
+		comparison.Devices = append(comparison.Devices, device)
+	}
+
+	sort.Slice(comparison.Devices, func(i, j int) bool {
+		a, b := comparison.Devices[i], comparison.Devices[j]
+		if a.MinutesPerLitreDegree != b.MinutesPerLitreDegree {
+			return a.MinutesPerLitreDegree < b.MinutesPerLitreDegree
+		}
+		return a.DeviceID < b.DeviceID
+	})
+	for i := range comparison.Devices {
+		device := &comparison.Devices[i]
+		device.Relative = roundTo(device.MinutesPerLitreDegree/comparison.Devices[0].MinutesPerLitreDegree, 3)
+		device.MinutesPerLitreDegree = roundTo(device.MinutesPerLitreDegree, 6)
+	}
+	return comparison, nil
+}
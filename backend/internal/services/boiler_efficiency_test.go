@@ -0,0 +1,146 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func efficiencyConfig() config.EfficiencyConfig {
+	return config.EfficiencyConfig{Weeks: 12, MinRecords: 10, MinRise: 0.15, MinScore: 3}
+}
+
+// efficiencyRecords returns one record every other day over eight weeks, all with the same
+// context and inlet temperature, whose heating time follows heating(day)
+func efficiencyRecords(heating func(day int) float64) []models.DailyRecord {
+	start := time.Date(2025, 3, 3, 7, 0, 0, 0, time.UTC)
+	inlet := 15.0
+	var records []models.DailyRecord
+	for day := 0; day < 56; day += 2 {
+		records = append(records, models.DailyRecord{
+			Date:               start.AddDate(0, 0, day),
+			ShowerDuration:     10,
+			AverageTemperature: 15,
+			InletTemperature:   &inlet,
+			HeatingTime:        heating(day) + float64(day%3) - 1, // small day-to-day noise
+			Satisfaction:       50,
+		})
+	}
+	return records
+}
+
+func TestDetectEfficiencyTrend_RisingHeating(t *testing.T) {
+	records := efficiencyRecords(func(day int) float64 { return 20 + float64(day)*0.1 })
+
+	trend, degrading := DetectEfficiencyTrend(records, DefaultInletModel(), efficiencyConfig())
+
+	require.NotNil(t, trend)
+	assert.True(t, degrading)
+	assert.InDelta(t, 27, trend.RisePercent, 3)
+	assert.Greater(t, trend.Score, 3.0)
+	assert.Equal(t, records[0].Date, trend.From)
+}
+
+func TestDetectEfficiencyTrend_StableHeating(t *testing.T) {
+	records := efficiencyRecords(func(int) float64 { return 20 })
+
+	trend, degrading := DetectEfficiencyTrend(records, DefaultInletModel(), efficiencyConfig())
+
+	require.NotNil(t, trend)
+	assert.False(t, degrading)
+	assert.Less(t, trend.RisePercent, 5.0)
+}
+
+func TestDetectEfficiencyTrend_ColderInletIsNotDegradation(t *testing.T) {
+	// The heater's efficiency is constant; only the mains water gets colder, so each record
+	// needs heating in proportion to how far the inlet is below the setpoint
+	records := efficiencyRecords(func(int) float64 { return 0 })
+	for i := range records {
+		inlet := 20 - float64(i)*0.4
+		records[i].InletTemperature = &inlet
+		records[i].HeatingTime = 20 * (efficiencySetpoint - inlet) / (efficiencySetpoint - 20)
+	}
+
+	trend, degrading := DetectEfficiencyTrend(records, DefaultInletModel(), efficiencyConfig())
+
+	require.NotNil(t, trend)
+	assert.False(t, degrading)
+	assert.InDelta(t, 0, trend.RisePercent, 1)
+}
+
+func TestDetectEfficiencyTrend_NeedsEnoughHistory(t *testing.T) {
+	records := efficiencyRecords(func(day int) float64 { return 20 + float64(day)*0.1 })
+
+	trend, degrading := DetectEfficiencyTrend(records[:8], DefaultInletModel(), efficiencyConfig())
+	assert.Nil(t, trend, "fewer records than MinRecords")
+	assert.False(t, degrading)
+
+	trend, _ = DetectEfficiencyTrend(records[:10], DefaultInletModel(), efficiencyConfig())
+	assert.Nil(t, trend, "only 18 days of history")
+}
+
+func TestEfficiencyWeeks(t *testing.T) {
+	records := efficiencyRecords(func(int) float64 { return 20 })
+
+	weeks := efficiencyWeeks(records, DefaultInletModel())
+
+	require.Len(t, weeks, 8)
+	assert.Equal(t, "2025-03-03", weeks[0].WeekStart) // a Monday
+	assert.Equal(t, 4, weeks[0].Records)
+	assert.Equal(t, "2025-04-21", weeks[7].WeekStart)
+}
+
+// fixedHousehold puts every user in the same household
+type fixedHousehold []string
+
+func (h fixedHousehold) HouseholdUserIDs(string) ([]string, error) {
+	return h, nil
+}
+
+func TestBoilerEfficiencyService_Compare(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "compare.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	boilers := NewBoilerService(config.DeviceConfig{HeaterPower: 2, TankSize: 120, FlowRate: 3}, DefaultInletModel())
+	service := NewBoilerEfficiencyService(nil, DefaultInletModel(), efficiencyConfig())
+	service.SetBoilerService(boilers)
+	service.SetHouseholdLookup(fixedHousehold{"user1", "user2"})
+
+	// The upstairs boiler needs 80 minutes where the main one needs 60 for the same showers
+	// (30 litres raised 45 degrees), and one of the household's other members showers upstairs
+	now := time.Now()
+	inlet := 15.0
+	for i, r := range []struct {
+		userID, deviceID string
+		heating          float64
+	}{
+		{"user1", "main", 60}, {"user1", "main", 60}, {"user1", "upstairs", 80}, {"user2", "upstairs", 80}, {"user3", "upstairs", 200},
+	} {
+		require.NoError(t, database.GetDB().Create(&models.DailyRecord{
+			UserID: r.userID, DeviceID: r.deviceID, Date: now.AddDate(0, 0, -i-1), InletTemperature: &inlet,
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: r.heating, Satisfaction: 50,
+		}).Error)
+	}
+
+	comparison, err := service.Compare("user1", now)
+	require.NoError(t, err)
+	require.Len(t, comparison.Devices, 2)
+	main, upstairs := comparison.Devices[0], comparison.Devices[1]
+	assert.Equal(t, "main", main.DeviceID)
+	assert.Equal(t, 2, main.Records)
+	assert.InDelta(t, 60.0/(30*45), main.MinutesPerLitreDegree, 1e-6)
+	assert.Equal(t, 1.0, main.Relative)
+	assert.InDelta(t, 30*45*waterHeatCapacity/3600/2, main.Efficiency, 0.001)
+	assert.Equal(t, "upstairs", upstairs.DeviceID)
+	assert.Equal(t, 2, upstairs.Records, "user3 isn't in the household")
+	assert.InDelta(t, 1.333, upstairs.Relative, 0.001)
+	assert.Less(t, upstairs.Efficiency, main.Efficiency)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"math"
+
+	"heat-logger/internal/models"
+)
+
+// ErrUnknownBoilerPreset is returned for a preset key that isn't in BoilerPresets
+var ErrUnknownBoilerPreset = errors.New("unknown boiler preset")
+
+// BoilerPreset is a starting point for a common kind of water heater, so a new user picks what
+// they have instead of tuning the predictor. Nil fields keep the configured values.
+type BoilerPreset struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	TankSize    *float64 `json:"tankSize,omitempty"`    // litres
+	FlowRate    *float64 `json:"flowRate,omitempty"`    // litres of hot water per minute
+	HeaterPower *float64 `json:"heaterPower,omitempty"` // kW
+	Resolution  *float64 `json:"resolution,omitempty"`  // minutes
+
+	MinMinutes    float64 `json:"minMinutes"`
+	MaxMinutes    float64 `json:"maxMinutes"`
+	SigmaDuration float64 `json:"sigmaDuration"` // minutes; wider means shower length matters less
+	SigmaTemp     float64 `json:"sigmaTemp"`     // °C; wider means the weather matters less
+}
+
+func ptr(v float64) *float64 { return &v }
+
+// BoilerPresets are the heater types offered during onboarding
+var BoilerPresets = []BoilerPreset{
+	{
+		Key:         "electric-tank-150",
+		Name:        "150 L electric tank",
+		Description: "A storage tank heated by an electric element on a timer",
+		TankSize:    ptr(150), FlowRate: ptr(8), HeaterPower: ptr(2.5),
+		MinMinutes: 10, MaxMinutes: 120, SigmaDuration: 4, SigmaTemp: 3,
+	},
+	{
+		Key:         "solar-electric",
+		Name:        "Solar + electric backup",
+		Description: "A solar collector tank with an electric element for cloudy days; the weather decides most of the backup heating",
+		TankSize:    ptr(150), FlowRate: ptr(8), HeaterPower: ptr(2.5),
+		MinMinutes: 5, MaxMinutes: 90, SigmaDuration: 4, SigmaTemp: 2,
+	},
+	{
+		Key:         "instant-gas",
+		Name:        "Instant gas",
+		Description: "A tankless gas heater that only needs a short warm-up before the water runs hot",
+		FlowRate:    ptr(10), Resolution: ptr(0.5),
+		MinMinutes: 1, MaxMinutes: 10, SigmaDuration: 8, SigmaTemp: 6,
+	},
+}
+
+// FindBoilerPreset returns the preset with the key
+func FindBoilerPreset(key string) (BoilerPreset, bool) {
+	for _, preset := range BoilerPresets {
+		if preset.Key == key {
+			return preset, true
+		}
+	}
+	return BoilerPreset{}, false
+}
+
+// DeviceTuning is how predictions for one device differ from the configured ones; zero fields
+// keep the configured value
+type DeviceTuning struct {
+	MinMinutes    float64
+	MaxMinutes    float64
+	SigmaDuration float64
+	SigmaTemp     float64
+	ColdStart     float64 // heating time worked out from the tank and heater, used before there is any history
+}
+
+// DeviceTuningLookup reports the tuning for the device of a prediction request
+type DeviceTuningLookup interface {
+	Tuning(req PredictionRequest) DeviceTuning
+}
+
+// ApplyPreset sets up the device from a preset: the prediction bounds and sigmas, and the tank,
+// flow and heater values unless calibration measured them
+func (s *BoilerService) ApplyPreset(userID, deviceID, key string) (*models.BoilerProfile, error) {
+	preset, ok := FindBoilerPreset(key)
+	if !ok {
+		return nil, ErrUnknownBoilerPreset
+	}
+	profile, err := s.profileOrNew(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile.Preset = preset.Key
+	profile.MinMinutes = ptr(preset.MinMinutes)
+	profile.MaxMinutes = ptr(preset.MaxMinutes)
+	profile.SigmaDuration = ptr(preset.SigmaDuration)
+	profile.SigmaTemp = ptr(preset.SigmaTemp)
+	if preset.Resolution != nil {
+		profile.Resolution = preset.Resolution
+	}
+	if profile.CalibratedAt == nil {
+		profile.TankSize = preset.TankSize
+		profile.FlowRate = preset.FlowRate
+		profile.HeaterPower = preset.HeaterPower
+	}
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Tuning returns the request's device tuning. Its cold start is the time the heater takes to
+// warm the hot water the shower draws (at most the tank) from the inlet temperature.
+func (s *BoilerService) Tuning(req PredictionRequest) DeviceTuning {
+	if req.DeviceID == "" {
+		return DeviceTuning{}
+	}
+	profile, err := s.SharedProfile(req.UserID, req.DeviceID)
+	if err != nil {
+		return DeviceTuning{}
+	}
+
+	var tuning DeviceTuning
+	for _, field := range []struct {
+		dst *float64
+		src *float64
+	}{
+		{&tuning.MinMinutes, profile.MinMinutes},
+		{&tuning.MaxMinutes, profile.MaxMinutes},
+		{&tuning.SigmaDuration, profile.SigmaDuration},
+		{&tuning.SigmaTemp, profile.SigmaTemp},
+	} {
+		if field.src != nil {
+			*field.dst = *field.src
+		}
+	}
+
+	tank := s.Tank(req.UserID, req.DeviceID)
+	power := s.HeaterPower(req.UserID, req.DeviceID)
+	if profile.TankSize != nil && tank.FlowRate > 0 && power > 0 {
+		litres := math.Min(req.Duration*tank.FlowRate, tank.Size)
+		rise := math.Max(s.hotWater-s.inlet.ForRequest(req, s.now()), 0)
+		tuning.ColdStart = roundTo(litres*waterHeatCapacity*rise/(power*60), 1)
+	}
+	return tuning
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBoilerProfileNotFound is returned when a device has no profile yet
+	ErrBoilerProfileNotFound = errors.New("boiler profile not found")
+	// ErrCalibrationStep is returned when a calibration step is reported out of order
+	ErrCalibrationStep = errors.New("calibration is not at this step")
+	// ErrInvalidResolution is returned for a timer resolution outside 0.1-10 minutes
+	ErrInvalidResolution = errors.New("resolution must be between 0.1 and 10 minutes")
+	// ErrInvalidOutputMode is returned for an output mode other than minutes or temperature
+	ErrInvalidOutputMode = errors.New("output mode must be minutes or temperature")
+)
+
+// waterHeatCapacity is the energy needed to warm one litre of water by one degree, in kJ
+const waterHeatCapacity = 4.186
+
+// maxTargetTemperature is the highest tank temperature a boiler thermostat is set to, °C
+const maxTargetTemperature = 75
+
+// BoilerService stores boiler profiles and runs the calibration wizard: heat for a chosen
+// time, shower until the water goes cold, and work out the tank size and heater power from
+// how long the hot water lasted.
+type BoilerService struct {
+	db          *gorm.DB
+	defaults    TankCapacity
+	power       float64            // configured heater power, kW
+	resolutions map[string]float64 // configured timer resolutions by device, minutes
+	hotWater    float64            // °C the shower water has to reach to count as hot
+	inlet       InletModel
+	households  HouseholdLookup
+	now         func() time.Time
+
+	safetyFloor    float64 // minutes, for devices without their own
+	maxSafetyFloor float64 // the highest floor a device may set, minutes
+}
+
+// NewBoilerService creates a new boiler service instance
+func NewBoilerService(cfg config.DeviceConfig, inlet InletModel) *BoilerService {
+	return &BoilerService{
+		db:          database.GetDB(),
+		defaults:    NewTankCapacity(cfg),
+		power:       cfg.HeaterPower,
+		resolutions: cfg.Resolutions,
+		hotWater:    cfg.ReadyTemperature,
+		inlet:       inlet,
+		now:         time.Now,
+	}
+}
+
+// SetClock sets the clock calibration showers are timed and inlet temperatures estimated by
+func (s *BoilerService) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// CalibrationResult is what the last step of the wizard reports
+type CalibrationResult struct {
+	ShowerMinutes    float64  // how long the hot water lasted; measured from the shower step when 0
+	FlowRate         *float64 // measured hot water draw, litres per minute; the configured rate when nil
+	InletTemperature *float64 // measured cold-water temperature, °C; estimated when nil
+}
+
+// GetProfile returns the user's profile for a device
+func (s *BoilerService) GetProfile(userID, deviceID string) (*models.BoilerProfile, error) {
+	var profile models.BoilerProfile
+	err := s.db.Where("user_id = ? AND device_id = ?", userID, deviceID).First(&profile).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBoilerProfileNotFound
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SetHouseholdLookup has users share the profiles of the devices in their household, so a
+// device calibrated by one of them is calibrated for everyone
+func (s *BoilerService) SetHouseholdLookup(households HouseholdLookup) {
+	s.households = households
+}
+
+// SharedProfile returns the user's profile for a device, or when they have none, the profile
+// of the first account in their household that has one
+func (s *BoilerService) SharedProfile(userID, deviceID string) (*models.BoilerProfile, error) {
+	profile, err := s.GetProfile(userID, deviceID)
+	if !errors.Is(err, ErrBoilerProfileNotFound) || s.households == nil {
+		return profile, err
+	}
+	userIDs, err := s.households.HouseholdUserIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range userIDs {
+		if id == userID {
+			continue
+		}
+		if profile, err := s.GetProfile(id, deviceID); !errors.Is(err, ErrBoilerProfileNotFound) {
+			return profile, err
+		}
+	}
+	return nil, ErrBoilerProfileNotFound
+}
+
+// Tank returns the device's calibrated capacity, falling back to the configured defaults
+func (s *BoilerService) Tank(userID, deviceID string) TankCapacity {
+	tank := s.defaults
+	if deviceID == "" {
+		return tank
+	}
+	profile, err := s.SharedProfile(userID, deviceID)
+	if err != nil {
+		return tank
+	}
+	if profile.TankSize != nil {
+		tank.Size = *profile.TankSize
+	}
+	if profile.FlowRate != nil {
+		tank.FlowRate = *profile.FlowRate
+	}
+	return tank
+}
+
+// HeaterPower returns the device's calibrated heater power in kW, falling back to the configured default
+func (s *BoilerService) HeaterPower(userID, deviceID string) float64 {
+	if deviceID == "" {
+		return s.power
+	}
+	profile, err := s.SharedProfile(userID, deviceID)
+	if err != nil || profile.HeaterPower == nil {
+		return s.power
+	}
+	return *profile.HeaterPower
+}
+
+// Resolution returns the step in minutes the device's timer can be set in, or 0 when it only
+// takes whole minutes or isn't known
+func (s *BoilerService) Resolution(userID, deviceID string) float64 {
+	if deviceID == "" {
+		return 0
+	}
+	if profile, err := s.SharedProfile(userID, deviceID); err == nil && profile.Resolution != nil {
+		return *profile.Resolution
+	}
+	return s.resolutions[deviceID]
+}
+
+// SetResolution stores the device's timer resolution; nil restores the configured one
+func (s *BoilerService) SetResolution(userID, deviceID string, resolution *float64) (*models.BoilerProfile, error) {
+	if resolution != nil && (*resolution < 0.1 || *resolution > 10) {
+		return nil, ErrInvalidResolution
+	}
+	profile, err := s.profileOrNew(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	profile.Resolution = resolution
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// SetOutputMode chooses whether heating for the device is also expressed as a target temperature
+func (s *BoilerService) SetOutputMode(userID, deviceID, mode string) (*models.BoilerProfile, error) {
+	switch mode {
+	case "", models.OutputMinutes:
+		mode = ""
+	case models.OutputTemperature:
+	default:
+		return nil, ErrInvalidOutputMode
+	}
+	profile, err := s.profileOrNew(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	profile.OutputMode = mode
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// TargetTemperature maps a heating time for the request's device to the tank temperature the
+// heater reaches in that time, rounded up to a whole degree. It reports false when the device
+// isn't set to temperature output or its tank size and heater power aren't known.
+func (s *BoilerService) TargetTemperature(req PredictionRequest, minutes float64) (float64, bool) {
+	if req.DeviceID == "" {
+		return 0, false
+	}
+	profile, err := s.SharedProfile(req.UserID, req.DeviceID)
+	if err != nil || profile.OutputMode != models.OutputTemperature {
+		return 0, false
+	}
+	tank := s.Tank(req.UserID, req.DeviceID)
+	power := s.HeaterPower(req.UserID, req.DeviceID)
+	if tank.Size <= 0 || power <= 0 {
+		return 0, false
+	}
+
+	// The heater warms the whole tank from the inlet temperature
+	rise := minutes * 60 * power / (tank.Size * waterHeatCapacity)
+	target := math.Ceil(s.inlet.ForRequest(req, s.now()) + rise)
+	return math.Min(target, maxTargetTemperature), true
+}
+
+// StartCalibration begins the wizard: the user heats the boiler for heatingMinutes from cold.
+// Starting again abandons a calibration in progress.
+func (s *BoilerService) StartCalibration(userID, deviceID string, heatingMinutes float64) (*models.BoilerProfile, error) {
+	profile, err := s.profileOrNew(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	profile.CalibrationStep = models.CalibrationHeating
+	profile.CalibrationHeatingMinutes = heatingMinutes
+	profile.CalibrationShowerStartedAt = nil
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// StartCalibrationShower records that heating finished and the shower is running
+func (s *BoilerService) StartCalibrationShower(userID, deviceID string) (*models.BoilerProfile, error) {
+	profile, err := s.GetProfile(userID, deviceID)
+	if errors.Is(err, ErrBoilerProfileNotFound) {
+		return nil, ErrCalibrationStep
+	}
+	if err != nil {
+		return nil, err
+	}
+	if profile.CalibrationStep != models.CalibrationHeating {
+		return nil, ErrCalibrationStep
+	}
+	now := s.now()
+	profile.CalibrationStep = models.CalibrationShowering
+	profile.CalibrationShowerStartedAt = &now
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// FinishCalibration records that the water went cold and stores the measured tank size
+// and heater power on the profile
+func (s *BoilerService) FinishCalibration(userID, deviceID string, result CalibrationResult) (*models.BoilerProfile, error) {
+	profile, err := s.GetProfile(userID, deviceID)
+	if errors.Is(err, ErrBoilerProfileNotFound) {
+		return nil, ErrCalibrationStep
+	}
+	if err != nil {
+		return nil, err
+	}
+	if profile.CalibrationStep != models.CalibrationShowering {
+		return nil, ErrCalibrationStep
+	}
+
+	now := s.now()
+	showerMinutes := result.ShowerMinutes
+	if showerMinutes <= 0 {
+		showerMinutes = now.Sub(*profile.CalibrationShowerStartedAt).Minutes()
+	}
+	flowRate := s.defaults.FlowRate
+	if result.FlowRate != nil {
+		flowRate = *result.FlowRate
+	}
+	inlet := s.inlet.Estimate(now)
+	if result.InletTemperature != nil {
+		inlet = *result.InletTemperature
+	}
+
+	// Everything the boiler heated came out as hot water before it ran cold
+	tankSize := roundTo(showerMinutes*flowRate, 1)
+	energy := tankSize * waterHeatCapacity * math.Max(s.hotWater-inlet, 1) // kJ
+	heaterPower := roundTo(energy/(profile.CalibrationHeatingMinutes*60), 2)
+
+	profile.TankSize = &tankSize
+	profile.FlowRate = &flowRate
+	profile.HeaterPower = &heaterPower
+	profile.CalibratedAt = &now
+	profile.CalibrationStep = ""
+	profile.CalibrationHeatingMinutes = 0
+	profile.CalibrationShowerStartedAt = nil
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// profileOrNew returns the stored profile or an empty one for the device
+func (s *BoilerService) profileOrNew(userID, deviceID string) (*models.BoilerProfile, error) {
+	profile, err := s.GetProfile(userID, deviceID)
+	if errors.Is(err, ErrBoilerProfileNotFound) {
+		return &models.BoilerProfile{UserID: userID, DeviceID: deviceID}, nil
+	}
+	return profile, err
+}
+
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(v*scale) / scale
+}
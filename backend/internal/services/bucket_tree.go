@@ -0,0 +1,391 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Context bucket trees
+const (
+	bucketTreeRootWidth = 16.0 // minutes and °C covered by a root bucket
+	bucketTreeMinWidth  = 4.0  // buckets aren't split any finer
+	bucketTreeSigmas    = 4.0  // a bucket is this many kernel widths across
+)
+
+// ErrContextBucketsDisabled is returned when no bucket split threshold is configured
+var ErrContextBucketsDisabled = errors.New("context buckets are disabled")
+
+// LocalityLookup finds the context bucket a request falls in, sized by how much feedback the
+// user has given around it
+type LocalityLookup interface {
+	Locality(userID string, duration, temperature float64) (BucketNode, bool)
+}
+
+// BucketNode is a context bucket covering [min, max) of both dimensions. Once split, its four
+// quarters hold its records between them.
+type BucketNode struct {
+	DurationMin    float64       `json:"durationMin"`
+	DurationMax    float64       `json:"durationMax"`
+	TemperatureMin float64       `json:"temperatureMin"`
+	TemperatureMax float64       `json:"temperatureMax"`
+	Records        int           `json:"records"`
+	Children       []*BucketNode `json:"children,omitempty"`
+}
+
+// SigmaDuration is the duration kernel width that fits the bucket, minutes
+func (n BucketNode) SigmaDuration() float64 {
+	return (n.DurationMax - n.DurationMin) / bucketTreeSigmas
+}
+
+// SigmaTemperature is the temperature kernel width that fits the bucket, °C
+func (n BucketNode) SigmaTemperature() float64 {
+	return (n.TemperatureMax - n.TemperatureMin) / bucketTreeSigmas
+}
+
+// contains reports whether the context falls in the bucket
+func (n *BucketNode) contains(duration, temperature float64) bool {
+	return duration >= n.DurationMin && duration < n.DurationMax &&
+		temperature >= n.TemperatureMin && temperature < n.TemperatureMax
+}
+
+// leaf returns the smallest bucket under n holding the context
+func (n *BucketNode) leaf(duration, temperature float64) *BucketNode {
+	for _, child := range n.Children {
+		if child.contains(duration, temperature) {
+			return child.leaf(duration, temperature)
+		}
+	}
+	return n
+}
+
+// build counts points into the bucket and quarters it, recursively, while a bucket holds at
+// least split points and is wider than the finest buckets
+func (n *BucketNode) build(points []contextPoint, split int) {
+	n.Records = len(points)
+	n.Children = nil
+	if split <= 0 || len(points) < split || n.DurationMax-n.DurationMin <= bucketTreeMinWidth {
+		return
+	}
+	midD := (n.DurationMin + n.DurationMax) / 2
+	midT := (n.TemperatureMin + n.TemperatureMax) / 2
+	for _, t := range [][2]float64{{n.TemperatureMin, midT}, {midT, n.TemperatureMax}} {
+		for _, d := range [][2]float64{{n.DurationMin, midD}, {midD, n.DurationMax}} {
+			child := &BucketNode{DurationMin: d[0], DurationMax: d[1], TemperatureMin: t[0], TemperatureMax: t[1]}
+			var inside []contextPoint
+			for _, p := range points {
+				if child.contains(p.ShowerDuration, p.AverageTemperature) {
+					inside = append(inside, p)
+				}
+			}
+			child.build(inside, split)
+			n.Children = append(n.Children, child)
+		}
+	}
+}
+
+// clone copies the bucket and its splits
+func (n *BucketNode) clone() *BucketNode {
+	out := *n
+	out.Children = nil
+	for _, child := range n.Children {
+		out.Children = append(out.Children, child.clone())
+	}
+	return &out
+}
+
+// contextPoint is where a record sits in context space
+type contextPoint struct {
+	ShowerDuration     float64
+	AverageTemperature float64
+}
+
+// bucketTree is one user's root buckets, the ones they have records in
+type bucketTree struct {
+	Roots   []*BucketNode
+	Records int
+}
+
+// clone copies the tree, so it can be changed while the published one is still read
+func (t *bucketTree) clone() *bucketTree {
+	out := &bucketTree{Records: t.Records}
+	for _, root := range t.Roots {
+		out.Roots = append(out.Roots, root.clone())
+	}
+	return out
+}
+
+// root returns the root bucket holding the context, creating it when create is set
+func (t *bucketTree) root(duration, temperature float64, create bool) *BucketNode {
+	for _, root := range t.Roots {
+		if root.contains(duration, temperature) {
+			return root
+		}
+	}
+	if !create {
+		return nil
+	}
+	d := math.Floor(duration/bucketTreeRootWidth) * bucketTreeRootWidth
+	tmp := math.Floor(temperature/bucketTreeRootWidth) * bucketTreeRootWidth
+	root := &BucketNode{DurationMin: d, DurationMax: d + bucketTreeRootWidth, TemperatureMin: tmp, TemperatureMax: tmp + bucketTreeRootWidth}
+	t.Roots = append(t.Roots, root)
+	sort.Slice(t.Roots, func(i, j int) bool {
+		if t.Roots[i].TemperatureMin != t.Roots[j].TemperatureMin {
+			return t.Roots[i].TemperatureMin < t.Roots[j].TemperatureMin
+		}
+		return t.Roots[i].DurationMin < t.Roots[j].DurationMin
+	})
+	return root
+}
+
+// ContextBucketTree is a user's context buckets as reported by the API
+type ContextBucketTree struct {
+	UserID  string        `json:"userId"`
+	Records int           `json:"records"`
+	Leaves  int           `json:"leaves"`  // buckets that aren't split
+	Split   int           `json:"split"`   // records that make a bucket split
+	Buckets []*BucketNode `json:"buckets"` // the root buckets the user has records in
+}
+
+// BucketTreeService keeps each user's context bucket tree. Users start with coarse 16-minute
+// by 16 °C buckets; a bucket collecting split training records is quartered, down to 4 by 4,
+// so buckets are small where the user has a lot of feedback and wide where they have little.
+// v2 sizes its kernels by the bucket a request falls in and only looks at records near it.
+// Trees are persisted and refined as feedback arrives, without re-reading the user's history.
+type BucketTreeService struct {
+	db    *gorm.DB
+	split int
+
+	// mu guards the maps only and is never held across the database. Published trees aren't
+	// changed, only replaced, so predictions read them without waiting on anyone's writes.
+	mu    sync.Mutex
+	trees map[string]*bucketTree
+	users map[string]*sync.Mutex // serialises loading and updating each user's tree
+}
+
+// NewBucketTreeService creates a bucket tree service splitting buckets at split records; 0
+// disables it
+func NewBucketTreeService(split int) *BucketTreeService {
+	return &BucketTreeService{
+		db:    database.GetDB(),
+		split: split,
+		trees: make(map[string]*bucketTree),
+		users: make(map[string]*sync.Mutex),
+	}
+}
+
+// Enabled reports whether buckets are split, and so whether predictors should use them
+func (s *BucketTreeService) Enabled() bool {
+	return s.split > 0
+}
+
+// Tree returns the user's buckets
+func (s *BucketTreeService) Tree(userID string) (*ContextBucketTree, error) {
+	if s.split <= 0 {
+		return nil, ErrContextBucketsDisabled
+	}
+	tree, err := s.load(userID)
+	if err != nil {
+		return nil, err
+	}
+	out := &ContextBucketTree{UserID: userID, Records: tree.Records, Split: s.split, Buckets: []*BucketNode{}}
+	var count func(n *BucketNode)
+	count = func(n *BucketNode) {
+		if len(n.Children) == 0 {
+			out.Leaves++
+		}
+		for _, child := range n.Children {
+			count(child)
+		}
+	}
+	for _, root := range tree.Roots {
+		count(root)
+		out.Buckets = append(out.Buckets, root.clone())
+	}
+	return out, nil
+}
+
+// Locality returns the smallest bucket holding the context, without its splits. It reports
+// false when the user has no records in the context's root bucket, or the tree can't be loaded.
+func (s *BucketTreeService) Locality(userID string, duration, temperature float64) (BucketNode, bool) {
+	tree, err := s.load(userID)
+	if err != nil {
+		log.Printf("Failed to load context buckets for %s: %v", userID, err)
+		return BucketNode{}, false
+	}
+	root := tree.root(duration, temperature, false)
+	if root == nil {
+		return BucketNode{}, false
+	}
+	leaf := *root.leaf(duration, temperature)
+	leaf.Children = nil
+	return leaf, true
+}
+
+// Observe counts a new training record into its user's tree, splitting its bucket once it
+// holds enough records. Failures are only logged; the tree is rebuilt if it goes missing.
+func (s *BucketTreeService) Observe(record models.DailyRecord) {
+	if record.ExcludedFromTraining {
+		return
+	}
+	lock := s.userLock(record.UserID)
+	lock.Lock()
+	defer lock.Unlock()
+	published, built, err := s.loadLocked(record.UserID)
+	if err != nil {
+		log.Printf("Failed to load context buckets for %s: %v", record.UserID, err)
+		return
+	}
+	if built {
+		return // built from the history, which already has the record
+	}
+
+	tree := published.clone()
+	tree.Records++
+	node := tree.root(record.ShowerDuration, record.AverageTemperature, true)
+	node.Records++
+	for len(node.Children) > 0 {
+		node = node.leaf(record.ShowerDuration, record.AverageTemperature)
+		node.Records++
+	}
+	if node.Records >= s.split && node.DurationMax-node.DurationMin > bucketTreeMinWidth {
+		var points []contextPoint
+		err := s.db.Model(&models.DailyRecord{}).Select("shower_duration", "average_temperature").
+			Where("user_id = ? AND excluded_from_training = ?", record.UserID, false).
+			Where("shower_duration >= ? AND shower_duration < ?", node.DurationMin, node.DurationMax).
+			Where("average_temperature >= ? AND average_temperature < ?", node.TemperatureMin, node.TemperatureMax).
+			Find(&points).Error
+		if err != nil {
+			log.Printf("Failed to split context bucket for %s: %v", record.UserID, err)
+			return
+		}
+		node.build(points, s.split)
+	}
+	s.publish(record.UserID, tree)
+	if err := s.save(record.UserID, tree); err != nil {
+		log.Printf("Failed to save context buckets for %s: %v", record.UserID, err)
+	}
+}
+
+// Forget drops the user's tree, e.g. after their model was reset; it is rebuilt from their
+// history when next needed
+func (s *BucketTreeService) Forget(userID string) {
+	lock := s.userLock(userID)
+	lock.Lock()
+	defer lock.Unlock()
+	s.publish(userID, nil)
+	if err := s.db.Where("user_id = ?", userID).Delete(&models.ContextBucketTree{}).Error; err != nil {
+		log.Printf("Failed to drop context buckets for %s: %v", userID, err)
+	}
+}
+
+// userLock returns the lock serialising the loads and updates of the user's tree
+func (s *BucketTreeService) userLock(userID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.users[userID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.users[userID] = lock
+	}
+	return lock
+}
+
+// cached returns the user's published tree, which must not be changed
+func (s *BucketTreeService) cached(userID string) (*bucketTree, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tree, ok := s.trees[userID]
+	return tree, ok
+}
+
+// publish replaces the user's tree in memory; nil drops it
+func (s *BucketTreeService) publish(userID string, tree *bucketTree) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tree == nil {
+		delete(s.trees, userID)
+		return
+	}
+	s.trees[userID] = tree
+}
+
+// load returns the user's published tree, loading it under the user's lock when it isn't in
+// memory yet
+func (s *BucketTreeService) load(userID string) (*bucketTree, error) {
+	if tree, ok := s.cached(userID); ok {
+		return tree, nil
+	}
+	lock := s.userLock(userID)
+	lock.Lock()
+	defer lock.Unlock()
+	tree, _, err := s.loadLocked(userID)
+	return tree, err
+}
+
+// loadLocked returns the user's tree from memory, the database, or built from their history, in
+// which case built is set. The caller holds the user's lock.
+func (s *BucketTreeService) loadLocked(userID string) (tree *bucketTree, built bool, err error) {
+	if tree, ok := s.cached(userID); ok {
+		return tree, false, nil
+	}
+
+	var stored models.ContextBucketTree
+	err = s.db.Where("user_id = ?", userID).Limit(1).Find(&stored).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if stored.UserID != "" {
+		tree = &bucketTree{Records: stored.Records}
+		if err := json.Unmarshal(stored.Buckets, &tree.Roots); err != nil {
+			return nil, false, err
+		}
+		s.publish(userID, tree)
+		return tree, false, nil
+	}
+
+	var points []contextPoint
+	err = s.db.Model(&models.DailyRecord{}).Select("shower_duration", "average_temperature").
+		Where("user_id = ? AND excluded_from_training = ?", userID, false).
+		Find(&points).Error
+	if err != nil {
+		return nil, false, err
+	}
+	tree = &bucketTree{Records: len(points)}
+	byRoot := map[*BucketNode][]contextPoint{}
+	for _, p := range points {
+		root := tree.root(p.ShowerDuration, p.AverageTemperature, true)
+		byRoot[root] = append(byRoot[root], p)
+	}
+	for root, rootPoints := range byRoot {
+		root.build(rootPoints, s.split)
+	}
+	s.publish(userID, tree)
+	if len(points) > 0 {
+		if err := s.save(userID, tree); err != nil {
+			return nil, false, err
+		}
+	}
+	return tree, true, nil
+}
+
+// save persists the user's tree
+func (s *BucketTreeService) save(userID string, tree *bucketTree) error {
+	buckets, err := json.Marshal(tree.Roots)
+	if err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"buckets", "records", "updated_at"}),
+	}).Create(&models.ContextBucketTree{UserID: userID, Buckets: buckets, Records: tree.Records}).Error
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestBucketNode_Build(t *testing.T) {
+	root := &BucketNode{DurationMin: 0, DurationMax: 16, TemperatureMin: 0, TemperatureMax: 16}
+	var points []contextPoint
+	for i := 0; i < 8; i++ {
+		points = append(points, contextPoint{ShowerDuration: 1 + float64(i%2), AverageTemperature: 1})
+	}
+	points = append(points, contextPoint{ShowerDuration: 12, AverageTemperature: 12})
+
+	root.build(points, 4)
+	assert.Equal(t, 9, root.Records)
+	require.Len(t, root.Children, 4)
+
+	// The crowded corner is split down to the finest buckets, the rest stays coarse
+	leaf := root.leaf(1, 1)
+	assert.Equal(t, 4.0, leaf.DurationMax-leaf.DurationMin)
+	assert.Equal(t, 8, leaf.Records)
+	assert.Equal(t, 1.0, leaf.SigmaDuration())
+	far := root.leaf(12, 12)
+	assert.Equal(t, 8.0, far.DurationMax-far.DurationMin)
+	assert.Equal(t, 1, far.Records)
+	assert.Equal(t, 2.0, far.SigmaTemperature())
+}
+
+func TestBucketTreeService(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "buckets.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	add := func(duration, temperature float64) {
+		record := &models.DailyRecord{
+			UserID: "user1", Date: time.Now(),
+			ShowerDuration: duration, AverageTemperature: temperature, HeatingTime: 20, Satisfaction: 50,
+		}
+		require.NoError(t, records.CreateRecord(record))
+	}
+	for i := 0; i < 3; i++ {
+		add(10, 12)
+	}
+
+	buckets := NewBucketTreeService(4)
+	records.OnRecordTrained(buckets.Observe)
+
+	// Built from the history on first use
+	leaf, ok := buckets.Locality("user1", 10, 12)
+	require.True(t, ok)
+	assert.Equal(t, 3, leaf.Records)
+	assert.Equal(t, 4.0, leaf.SigmaDuration())
+	_, ok = buckets.Locality("user1", 40, 12)
+	assert.False(t, ok, "no records in that root bucket")
+
+	// The fourth record splits the bucket, and the quarter holding all four again
+	add(11, 13)
+	leaf, ok = buckets.Locality("user1", 10, 12)
+	require.True(t, ok)
+	assert.Equal(t, BucketNode{DurationMin: 8, DurationMax: 12, TemperatureMin: 12, TemperatureMax: 16, Records: 4}, leaf)
+
+	// Persisted: a new service picks up the split tree without rebuilding it
+	tree, err := NewBucketTreeService(4).Tree("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, tree.Records)
+	assert.Equal(t, 7, tree.Leaves)
+	require.Len(t, tree.Buckets, 1)
+	assert.Len(t, tree.Buckets[0].Children, 4)
+
+	// A tree being updated is still read, and other users don't wait for it
+	lock := buckets.userLock("user1")
+	lock.Lock()
+	_, ok = buckets.Locality("user1", 10, 12)
+	assert.True(t, ok)
+	_, ok = buckets.Locality("user2", 10, 12)
+	assert.False(t, ok)
+	lock.Unlock()
+
+	buckets.Forget("user1")
+	tree, err = buckets.Tree("user1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, tree.Records)
+
+	_, err = NewBucketTreeService(0).Tree("user1")
+	assert.ErrorIs(t, err, ErrContextBucketsDisabled)
+}
+
+type fixedLocality BucketNode
+
+func (l fixedLocality) Locality(string, float64, float64) (BucketNode, bool) {
+	return BucketNode(l), true
+}
+
+func TestPredictionServiceV2_LocalitySizesKernels(t *testing.T) {
+	service := NewPredictionServiceV2(&MockRecordService{}, nil)
+	service.SetLocalityLookup(fixedLocality{DurationMin: 8, DurationMax: 12, TemperatureMin: 8, TemperatureMax: 16, Records: 40})
+
+	cfg, _, bucket := service.configFor(PredictionRequest{UserID: "user1", Duration: 10, Temperature: 10})
+	require.NotNil(t, bucket)
+	assert.Equal(t, 1.0, cfg.SigmaDuration)
+	assert.Equal(t, 2.0, cfg.SigmaTemp)
+
+	// Far records are dropped only while MinK remain
+	var all []recWrap
+	for i := 0; i < 10; i++ {
+		all = append(all, recWrap{rec: models.DailyRecord{ShowerDuration: 10, AverageTemperature: 10}})
+	}
+	all = append(all, recWrap{rec: models.DailyRecord{ShowerDuration: 30, AverageTemperature: 10}})
+	req := PredictionRequest{Duration: 10, Temperature: 10}
+	assert.Len(t, withinKernels(all, req, cfg), 10)
+	cfg.MinK = 11
+	assert.Len(t, withinKernels(all, req, cfg), 11)
+}
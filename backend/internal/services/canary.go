@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// canaryServedTTL is how long a served prediction waits for the feedback that scores it
+const canaryServedTTL = 24 * time.Hour
+
+// CanaryStatus describes the current canary split and how both versions are performing
+type CanaryStatus struct {
+	Incumbent        string     `json:"incumbent"`
+	Canary           string     `json:"canary"`
+	Percent          int        `json:"percent"`
+	Margin           float64    `json:"margin"`
+	IncumbentError   *float64   `json:"incumbentError,omitempty"` // rolling mean relative error
+	CanaryError      *float64   `json:"canaryError,omitempty"`
+	IncumbentSamples int        `json:"incumbentSamples"`
+	CanarySamples    int        `json:"canarySamples"`
+	RolledBack       bool       `json:"rolledBack"`
+	RollbackReason   string     `json:"rollbackReason,omitempty"`
+	RolledBackAt     *time.Time `json:"rolledBackAt,omitempty"`
+}
+
+type servedPrediction struct {
+	canary      bool
+	heatingTime float64
+	at          time.Time
+}
+
+// rollingError keeps the most recent relative errors of one predictor version
+type rollingError struct {
+	samples []float64
+	next    int
+	full    bool
+}
+
+func (r *rollingError) add(value float64, window int) {
+	if len(r.samples) != window {
+		r.samples = make([]float64, window)
+		r.next, r.full = 0, false
+	}
+	r.samples[r.next] = value
+	r.next = (r.next + 1) % window
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *rollingError) count() int {
+	if r.full {
+		return len(r.samples)
+	}
+	return r.next
+}
+
+func (r *rollingError) mean() (float64, bool) {
+	n := r.count()
+	if n == 0 {
+		return 0, false
+	}
+	sum := 0.0
+	for _, v := range r.samples[:n] {
+		sum += v
+	}
+	return sum / float64(n), true
+}
+
+// CanaryPredictor serves a new predictor version to a fixed share of users and rolls it back
+// automatically when its rolling error on real feedback falls behind the incumbent's.
+// Users are assigned by a hash of their ID, so each user consistently sees one version.
+type CanaryPredictor struct {
+	incumbent        Predictor
+	canary           Predictor
+	incumbentVersion string
+	canaryVersion    string
+	cfg              config.CanaryConfig
+
+	mu             sync.Mutex
+	percent        int
+	margin         float64
+	served         map[string]servedPrediction
+	incumbentErr   rollingError
+	canaryErr      rollingError
+	rolledBack     bool
+	rollbackReason string
+	rolledBackAt   *time.Time
+}
+
+// NewCanaryPredictor creates a canary split between two predictor versions
+func NewCanaryPredictor(incumbent Predictor, incumbentVersion string, canary Predictor, canaryVersion string, cfg config.CanaryConfig) *CanaryPredictor {
+	if cfg.Window <= 0 {
+		cfg.Window = 50
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 1
+	}
+	return &CanaryPredictor{
+		incumbent:        incumbent,
+		canary:           canary,
+		incumbentVersion: incumbentVersion,
+		canaryVersion:    canaryVersion,
+		cfg:              cfg,
+		percent:          cfg.Percent,
+		margin:           cfg.Margin,
+		served:           make(map[string]servedPrediction),
+	}
+}
+
+// Predict routes the request to the canary or the incumbent and remembers which one answered
+func (p *CanaryPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	useCanary := p.inCanary(req.UserID)
+	predictor := p.incumbent
+	if useCanary {
+		predictor = p.canary
+	}
+
+	resp, err := predictor.Predict(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	if len(p.served) > 1024 {
+		for key, s := range p.served {
+			if now.Sub(s.at) > canaryServedTTL {
+				delete(p.served, key)
+			}
+		}
+	}
+	p.served[canaryKey(req.UserID, req.MemberID)] = servedPrediction{canary: useCanary, heatingTime: resp.HeatingTime, at: now}
+	p.mu.Unlock()
+	return resp, nil
+}
+
+// ObserveFeedback scores the prediction last served to the record's user against the outcome
+// and rolls the canary back if it is doing measurably worse than the incumbent
+func (p *CanaryPredictor) ObserveFeedback(record models.DailyRecord) {
+	truth := impliedTarget(record)
+	if truth <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := canaryKey(record.UserID, record.MemberID)
+	served, ok := p.served[key]
+	if !ok || time.Since(served.at) > canaryServedTTL {
+		return
+	}
+	delete(p.served, key)
+
+	relErr := math.Abs(served.heatingTime-truth) / truth
+	if served.canary {
+		p.canaryErr.add(relErr, p.cfg.Window)
+	} else {
+		p.incumbentErr.add(relErr, p.cfg.Window)
+	}
+	p.checkRollback()
+}
+
+// checkRollback must be called with the lock held
+func (p *CanaryPredictor) checkRollback() {
+	if p.rolledBack || p.percent == 0 {
+		return
+	}
+	if p.canaryErr.count() < p.cfg.MinSamples || p.incumbentErr.count() < p.cfg.MinSamples {
+		return
+	}
+	canaryMean, _ := p.canaryErr.mean()
+	incumbentMean, _ := p.incumbentErr.mean()
+	if canaryMean <= incumbentMean+p.margin {
+		return
+	}
+
+	now := time.Now()
+	p.rolledBack = true
+	p.rolledBackAt = &now
+	p.rollbackReason = "canary error exceeded incumbent by more than the margin"
+	log.Printf("Canary %s rolled back from %d%%: rolling error %.3f vs incumbent %s %.3f (margin %.3f)",
+		p.canaryVersion, p.percent, canaryMean, p.incumbentVersion, incumbentMean, p.margin)
+	p.percent = 0
+}
+
+// Configure sets the canary share and rollback margin. It clears any previous rollback and
+// starts both rolling errors afresh so old samples can't immediately trigger another one.
+func (p *CanaryPredictor) Configure(percent int, margin float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.percent = percent
+	p.margin = margin
+	p.rolledBack = false
+	p.rollbackReason = ""
+	p.rolledBackAt = nil
+	p.incumbentErr = rollingError{}
+	p.canaryErr = rollingError{}
+}
+
+// Status returns the current split and rolling errors
+func (p *CanaryPredictor) Status() CanaryStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := CanaryStatus{
+		Incumbent:        p.incumbentVersion,
+		Canary:           p.canaryVersion,
+		Percent:          p.percent,
+		Margin:           p.margin,
+		IncumbentSamples: p.incumbentErr.count(),
+		CanarySamples:    p.canaryErr.count(),
+		RolledBack:       p.rolledBack,
+		RollbackReason:   p.rollbackReason,
+		RolledBackAt:     p.rolledBackAt,
+	}
+	if mean, ok := p.incumbentErr.mean(); ok {
+		status.IncumbentError = &mean
+	}
+	if mean, ok := p.canaryErr.mean(); ok {
+		status.CanaryError = &mean
+	}
+	return status
+}
+
+// inCanary reports whether the user falls inside the canary share
+// VersionFor returns the predictor version the user is currently served
+func (p *CanaryPredictor) VersionFor(userID string) string {
+	if p.inCanary(userID) {
+		return p.canaryVersion
+	}
+	return p.incumbentVersion
+}
+
+func (p *CanaryPredictor) inCanary(userID string) bool {
+	p.mu.Lock()
+	percent := p.percent
+	p.mu.Unlock()
+	if percent <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return int(h.Sum32()%100) < percent
+}
+
+func canaryKey(userID, memberID string) string {
+	return userID + "|" + memberID
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedPredictor float64
+
+func (p fixedPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	return &PredictionResponse{HeatingTime: float64(p)}, nil
+}
+
+func TestCanaryPredictor_RollsBackWhenCanaryIsWorse(t *testing.T) {
+	// The incumbent is spot on, the canary heats twice as long as needed
+	canary := NewCanaryPredictor(fixedPredictor(20), "v1", fixedPredictor(40), "v2", config.CanaryConfig{
+		Percent: 50, Margin: 0.1, Window: 20, MinSamples: 5,
+	})
+
+	for i := 0; i < 100; i++ {
+		userID := fmt.Sprintf("user%d", i)
+		resp, err := canary.Predict(context.Background(), PredictionRequest{UserID: userID, Duration: 10, Temperature: 15})
+		assert.NoError(t, err)
+		canary.ObserveFeedback(models.DailyRecord{
+			UserID: userID, ShowerDuration: 10, AverageTemperature: 15, HeatingTime: resp.HeatingTime,
+			Satisfaction: map[float64]float64{20: 50, 40: 90}[resp.HeatingTime],
+		})
+	}
+
+	status := canary.Status()
+	assert.True(t, status.RolledBack)
+	assert.Equal(t, 0, status.Percent)
+
+	// After rollback everyone is served by the incumbent
+	resp, err := canary.Predict(context.Background(), PredictionRequest{UserID: "user-after", Duration: 10, Temperature: 15})
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, resp.HeatingTime)
+
+	canary.Configure(10, 0.2)
+	status = canary.Status()
+	assert.False(t, status.RolledBack)
+	assert.Equal(t, 10, status.Percent)
+	assert.Equal(t, 0, status.CanarySamples)
+}
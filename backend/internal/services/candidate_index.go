@@ -0,0 +1,427 @@
+package services
+
+import (
+	"container/list"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"heat-logger/internal/models"
+)
+
+// memoryBudget is memory, in bytes, that in-memory prediction state may take
+type memoryBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// newMemoryBudget creates a budget of memoryMB
+func newMemoryBudget(memoryMB float64) *memoryBudget {
+	return &memoryBudget{limit: int64(memoryMB * 1024 * 1024)}
+}
+
+// reserve takes size bytes when they fit, reporting whether they did
+func (b *memoryBudget) reserve(size int64) bool {
+	for {
+		used := b.used.Load()
+		if used+size > b.limit {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+size) {
+			return true
+		}
+	}
+}
+
+// release returns size bytes. Held state that grows takes its growth with a negative size,
+// fitting or not, and is trimmed afterwards.
+func (b *memoryBudget) release(size int64) {
+	b.used.Add(-size)
+}
+
+// full reports whether the budget is used up
+func (b *memoryBudget) full() bool {
+	return b.used.Load() >= b.limit
+}
+
+// over reports whether more than the budget is in use
+func (b *memoryBudget) over() bool {
+	return b.used.Load() > b.limit
+}
+
+// candidateCell is one (duration, temperature) band of the candidate index, as wide as a
+// context bucket
+type candidateCell struct {
+	duration    int
+	temperature int
+}
+
+// candidateCellOf returns the cell holding the context
+func candidateCellOf(duration, temperature float64) candidateCell {
+	return candidateCell{
+		duration:    int(math.Floor(duration / bucketDurationWidth)),
+		temperature: int(math.Floor(temperature / bucketTemperatureWidth)),
+	}
+}
+
+// candidateSet is the newest limit training records of one user, or of everyone, by cell
+type candidateSet struct {
+	userID string // empty for the global pool
+	limit  int
+	full   bool                                   // loaded at its limit, so older records may be missing
+	cells  map[candidateCell][]models.DailyRecord // newest first
+	byID   map[string]candidateCell
+	size   int64
+}
+
+// newCandidateSet indexes records, newest first as loaded
+func newCandidateSet(userID string, limit int, records []models.DailyRecord) *candidateSet {
+	set := &candidateSet{
+		userID: userID,
+		limit:  limit,
+		full:   len(records) >= limit,
+		cells:  make(map[candidateCell][]models.DailyRecord),
+		byID:   make(map[string]candidateCell, len(records)),
+		size:   int64(unsafe.Sizeof(candidateSet{})),
+	}
+	for _, record := range records {
+		set.insert(record)
+	}
+	return set
+}
+
+// insert adds a record to its cell, keeping the cell newest first
+func (s *candidateSet) insert(record models.DailyRecord) {
+	cell := candidateCellOf(record.ShowerDuration, record.AverageTemperature)
+	records := s.cells[cell]
+	i := sort.Search(len(records), func(i int) bool { return records[i].Date.Before(record.Date) })
+	records = append(records, models.DailyRecord{})
+	copy(records[i+1:], records[i:])
+	records[i] = record
+	s.cells[cell] = records
+	s.byID[record.ID] = cell
+	s.size += candidateSize(record)
+}
+
+// remove drops the record with the ID, reporting whether the set held it
+func (s *candidateSet) remove(id string) bool {
+	cell, ok := s.byID[id]
+	if !ok {
+		return false
+	}
+	records := s.cells[cell]
+	for i := range records {
+		if records[i].ID == id {
+			s.size -= candidateSize(records[i])
+			records = append(records[:i], records[i+1:]...)
+			break
+		}
+	}
+	if len(records) == 0 {
+		delete(s.cells, cell)
+	} else {
+		s.cells[cell] = records
+	}
+	delete(s.byID, id)
+	return true
+}
+
+// oldest returns the oldest record held
+func (s *candidateSet) oldest() (models.DailyRecord, bool) {
+	var oldest models.DailyRecord
+	found := false
+	for _, records := range s.cells {
+		if last := records[len(records)-1]; !found || last.Date.Before(oldest.Date) {
+			oldest, found = last, true
+		}
+	}
+	return oldest, found
+}
+
+// apply brings the set up to date with a record as stored now, kept being false when it was
+// deleted or excluded from training. It reports false when the set can no longer tell which
+// records are the newest, because one it held is gone and the next older one was never loaded.
+func (s *candidateSet) apply(record models.DailyRecord, kept bool) bool {
+	removed := s.remove(record.ID)
+	if !kept {
+		return !(removed && s.full)
+	}
+	if oldest, ok := s.oldest(); s.full && ok && !record.Date.After(oldest.Date) {
+		// Older than every record held: whether it is among the newest depends on those not loaded
+		return !removed
+	}
+	s.insert(record)
+	if len(s.byID) > s.limit {
+		oldest, _ := s.oldest()
+		s.remove(oldest.ID)
+		s.full = true
+	}
+	return true
+}
+
+// near appends the records that keep accepts from the cells within reach of the context, cell
+// by cell. A negative reach takes every cell.
+func (s *candidateSet) near(out []models.DailyRecord, duration, temperature, durationReach, temperatureReach float64, keep func(models.DailyRecord) bool) []models.DailyRecord {
+	add := func(records []models.DailyRecord) {
+		for _, record := range records {
+			if keep == nil || keep(record) {
+				out = append(out, record)
+			}
+		}
+	}
+	if durationReach < 0 || temperatureReach < 0 {
+		cells := make([]candidateCell, 0, len(s.cells))
+		for cell := range s.cells {
+			cells = append(cells, cell)
+		}
+		// In a fixed order, so the same records always come out the same
+		sort.Slice(cells, func(i, j int) bool {
+			if cells[i].duration != cells[j].duration {
+				return cells[i].duration < cells[j].duration
+			}
+			return cells[i].temperature < cells[j].temperature
+		})
+		for _, cell := range cells {
+			add(s.cells[cell])
+		}
+		return out
+	}
+	from := candidateCellOf(duration-durationReach, temperature-temperatureReach)
+	to := candidateCellOf(duration+durationReach, temperature+temperatureReach)
+	for d := from.duration; d <= to.duration; d++ {
+		for t := from.temperature; t <= to.temperature; t++ {
+			add(s.cells[candidateCell{duration: d, temperature: t}])
+		}
+	}
+	return out
+}
+
+// CandidateIndex keeps the records the v2 predictor weighs in memory, for each user and for the
+// global pool, by (duration, temperature) cell, so a prediction reads only the cells within
+// its kernels' reach instead of loading and weighting every record. Sets are loaded on first
+// use and kept current from record hooks: Observe for created and edited records, Remove for
+// deleted ones, Forget and Reset for changes made in bulk. User sets are dropped least
+// recently used first to stay within the memory budget.
+type CandidateIndex struct {
+	records *RecordService
+	budget  *memoryBudget
+
+	mu         sync.Mutex
+	generation uint64 // bumped by every change, so sets loaded meanwhile aren't kept
+	global     *candidateSet
+	users      map[string]*list.Element
+	order      *list.List // user sets, most recently used first
+	builds     int        // sets loaded from the database
+}
+
+// NewCandidateIndex creates a candidate index over the record service, taking at most memoryMB
+func NewCandidateIndex(records *RecordService, memoryMB float64) *CandidateIndex {
+	return &CandidateIndex{
+		records: records,
+		budget:  newMemoryBudget(memoryMB),
+		users:   make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Near returns the user's records and everyone else's from the cells within reach of the given
+// context, durationReach minutes and temperatureReach °C either way; a negative reach means
+// any distance. Whole cells are read, so some records lie a little beyond reach. Other users
+// excluded as unreliable contributors are left out.
+func (x *CandidateIndex) Near(userID string, duration, temperature, durationReach, temperatureReach float64) (user, global []models.DailyRecord, err error) {
+	userSet, globalSet, err := x.sets(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var excluded map[string]bool
+	if x.records.contributors != nil {
+		if ids := x.records.contributors.Excluded(); len(ids) > 0 {
+			excluded = make(map[string]bool, len(ids))
+			for _, id := range ids {
+				excluded[id] = true
+			}
+		}
+	}
+	others := func(record models.DailyRecord) bool {
+		return record.UserID != userID && !excluded[record.UserID]
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	user = userSet.near(nil, duration, temperature, durationReach, temperatureReach, nil)
+	global = globalSet.near(nil, duration, temperature, durationReach, temperatureReach, others)
+	return user, global, nil
+}
+
+// WarmUp loads the user's set and the global pool's, unless they are held already
+func (x *CandidateIndex) WarmUp(userID string) error {
+	_, _, err := x.sets(userID)
+	return err
+}
+
+// sets returns the user's set and the global pool's, loading those that aren't held. A set
+// loaded while records changed, or that doesn't fit the budget, serves this call only.
+func (x *CandidateIndex) sets(userID string) (user, global *candidateSet, err error) {
+	x.mu.Lock()
+	if element, ok := x.users[userID]; ok {
+		x.order.MoveToFront(element)
+		user = element.Value.(*candidateSet)
+	}
+	global = x.global
+	generation := x.generation
+	x.mu.Unlock()
+	if user != nil && global != nil {
+		return user, global, nil
+	}
+
+	loadedUser, loadedGlobal := user == nil, global == nil
+	if loadedUser {
+		records, err := x.records.candidateRecords(userID, warmupUserRecords)
+		if err != nil {
+			return nil, nil, err
+		}
+		user = newCandidateSet(userID, warmupUserRecords, records)
+	}
+	if loadedGlobal {
+		records, err := x.records.candidateRecords("", globalSnapshotSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		global = newCandidateSet("", globalSnapshotSize, records)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if loadedUser {
+		x.builds++
+	}
+	if loadedGlobal {
+		x.builds++
+	}
+	if generation != x.generation {
+		return user, global, nil
+	}
+	if loadedGlobal && x.global == nil && x.fit(global.size) {
+		x.global = global
+	}
+	if _, ok := x.users[userID]; loadedUser && !ok && x.fit(user.size) {
+		x.users[userID] = x.order.PushFront(user)
+	}
+	return user, global, nil
+}
+
+// fit reserves size bytes, dropping the least recently used user sets until they fit. It
+// reports false when they don't fit even without them.
+func (x *CandidateIndex) fit(size int64) bool {
+	for !x.budget.reserve(size) {
+		back := x.order.Back()
+		if back == nil {
+			return false
+		}
+		x.dropUser(back)
+	}
+	return true
+}
+
+// dropUser drops one user set
+func (x *CandidateIndex) dropUser(element *list.Element) {
+	set := x.order.Remove(element).(*candidateSet)
+	delete(x.users, set.userID)
+	x.budget.release(set.size)
+}
+
+// dropGlobal drops the global pool's set, to be loaded again when next used
+func (x *CandidateIndex) dropGlobal() {
+	if x.global != nil {
+		x.budget.release(x.global.size)
+		x.global = nil
+	}
+}
+
+// Observe brings the index up to date with a record that was created or edited, for
+// RecordService.OnRecordTrained and OnRecordUpdated
+func (x *CandidateIndex) Observe(record models.DailyRecord) {
+	x.apply(candidateOf(record), !record.ExcludedFromTraining)
+}
+
+// Remove takes a deleted record out of the index, for RecordService.OnRecordDeleted
+func (x *CandidateIndex) Remove(record models.DailyRecord) {
+	x.apply(candidateOf(record), false)
+}
+
+// apply passes a change on to the sets holding the record's user and the global pool, dropping
+// those that can't follow it
+func (x *CandidateIndex) apply(record models.DailyRecord, kept bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.generation++
+	if element, ok := x.users[record.UserID]; ok {
+		set := element.Value.(*candidateSet)
+		before := set.size
+		ok := set.apply(record, kept)
+		x.budget.release(before - set.size)
+		if !ok {
+			x.dropUser(element)
+		}
+	}
+	if x.global != nil {
+		before := x.global.size
+		ok := x.global.apply(record, kept)
+		x.budget.release(before - x.global.size)
+		if !ok {
+			x.dropGlobal()
+		}
+	}
+	for x.budget.over() && x.order.Len() > 0 {
+		x.dropUser(x.order.Back())
+	}
+}
+
+// Forget drops the user's set and the global pool's, which are loaded again when next used, for
+// changes to the user's records made in bulk such as a model reset or a merge
+func (x *CandidateIndex) Forget(userID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.generation++
+	if element, ok := x.users[userID]; ok {
+		x.dropUser(element)
+	}
+	x.dropGlobal()
+}
+
+// Reset drops every set, for records changed outside RecordService such as by a sync import
+func (x *CandidateIndex) Reset() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.generation++
+	for x.order.Len() > 0 {
+		x.dropUser(x.order.Back())
+	}
+	x.dropGlobal()
+}
+
+// candidateOf returns the fields of a record the index keeps: those the predictors read, and
+// the ID to follow it by
+func candidateOf(record models.DailyRecord) models.DailyRecord {
+	return models.DailyRecord{
+		ID:                 record.ID,
+		UserID:             record.UserID,
+		MemberID:           record.MemberID,
+		DeviceID:           record.DeviceID,
+		Date:               record.Date,
+		ShowerDuration:     record.ShowerDuration,
+		AverageTemperature: record.AverageTemperature,
+		InletTemperature:   record.InletTemperature,
+		HeatingTime:        record.HeatingTime,
+		Satisfaction:       record.Satisfaction,
+		Source:             record.Source,
+		FeedbackConfidence: record.FeedbackConfidence,
+	}
+}
+
+// candidateSize estimates the memory an indexed record takes
+func candidateSize(record models.DailyRecord) int64 {
+	return recordSize(record) + int64(len(record.ID))
+}
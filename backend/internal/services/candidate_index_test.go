@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+// newCandidateIndexDB opens an empty database for an index test
+func newCandidateIndexDB(t *testing.T) *RecordService {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "index.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	return NewRecordService()
+}
+
+// nearIDs returns the IDs of the user's and everyone else's records near the context, sorted
+func nearIDs(t *testing.T, index *CandidateIndex, userID string, duration, temperature float64) (user, global []string) {
+	userRecords, globalRecords, err := index.Near(userID, duration, temperature, 6, 6)
+	require.NoError(t, err)
+	ids := func(records []models.DailyRecord) []string {
+		out := []string{}
+		for _, record := range records {
+			out = append(out, record.ID)
+		}
+		sort.Strings(out)
+		return out
+	}
+	return ids(userRecords), ids(globalRecords)
+}
+
+func TestCandidateIndex_FollowsCreateEditAndDelete(t *testing.T) {
+	records := newCandidateIndexDB(t)
+	for i := 0; i < 12; i++ {
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: fmt.Sprintf("user%d", i%2), DeviceID: "boiler", Date: time.Now().AddDate(0, 0, -i),
+			ShowerDuration: float64(8 + i%3*20), AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50,
+		}))
+	}
+	index := NewCandidateIndex(records, 1)
+	records.OnRecordTrained(index.Observe)
+	records.OnRecordUpdated(index.Observe)
+	records.OnRecordDeleted(index.Remove)
+
+	// matchesFresh checks the index against one loaded from the database now
+	matchesFresh := func(duration float64) {
+		t.Helper()
+		user, global := nearIDs(t, index, "user0", duration, 15)
+		freshUser, freshGlobal := nearIDs(t, NewCandidateIndex(records, 1), "user0", duration, 15)
+		assert.Equal(t, freshUser, user)
+		assert.Equal(t, freshGlobal, global)
+	}
+	user, global := nearIDs(t, index, "user0", 8, 15)
+	assert.Len(t, user, 2, "only user0's 8-minute showers are near; the 28 and 48-minute ones are out of reach")
+	assert.Len(t, global, 2)
+	matchesFresh(8)
+
+	// Created
+	created := &models.DailyRecord{UserID: "user0", DeviceID: "other", Date: time.Now(), ShowerDuration: 9, AverageTemperature: 15, HeatingTime: 25, Satisfaction: 50}
+	require.NoError(t, records.CreateRecord(created))
+	user, _ = nearIDs(t, index, "user0", 8, 15)
+	assert.Contains(t, user, created.ID)
+	matchesFresh(8)
+
+	// Edited into another cell
+	edited := &models.DailyRecord{UserID: "user0", DeviceID: "other", ShowerDuration: 48, AverageTemperature: 15, HeatingTime: 40, Satisfaction: 50}
+	_, err := records.UpsertDayRecord(edited, created.Date)
+	require.NoError(t, err)
+	user, _ = nearIDs(t, index, "user0", 8, 15)
+	assert.NotContains(t, user, created.ID)
+	user, _ = nearIDs(t, index, "user0", 48, 15)
+	assert.Contains(t, user, created.ID)
+	matchesFresh(8)
+	matchesFresh(48)
+
+	// Deleted, from the user's set and from everyone else's view of the global pool
+	require.NoError(t, records.DeleteRecord(created.ID, ""))
+	user, _ = nearIDs(t, index, "user0", 48, 15)
+	assert.NotContains(t, user, created.ID)
+	_, global = nearIDs(t, index, "user1", 48, 15)
+	assert.NotContains(t, global, created.ID)
+	matchesFresh(48)
+
+	require.NoError(t, records.DeleteAllRecords())
+	user, global = nearIDs(t, index, "user0", 8, 15)
+	assert.Empty(t, user)
+	assert.Empty(t, global)
+}
+
+func TestCandidateSet_KeepsNewestWithinLimit(t *testing.T) {
+	now := time.Now()
+	record := func(id string, daysAgo int) models.DailyRecord {
+		return models.DailyRecord{ID: id, Date: now.AddDate(0, 0, -daysAgo), ShowerDuration: 10, AverageTemperature: 15}
+	}
+	set := newCandidateSet("", 3, []models.DailyRecord{record("a", 1), record("b", 2), record("c", 3)})
+	require.True(t, set.full)
+
+	// A newer record pushes out the oldest
+	assert.True(t, set.apply(record("d", 0), true))
+	oldest, _ := set.oldest()
+	assert.Equal(t, "b", oldest.ID)
+	assert.Len(t, set.byID, 3)
+
+	// An older one than any held can't be placed, but changes nothing held either
+	assert.True(t, set.apply(record("e", 9), true))
+	assert.NotContains(t, set.byID, "e")
+
+	// Losing a record leaves a gap only the database can fill
+	assert.False(t, set.apply(record("a", 1), false))
+}
+
+func TestPredictionServiceV2_CandidateIndexMatchesFullScan(t *testing.T) {
+	records := newCandidateIndexDB(t)
+	now := time.Now()
+	for i := 0; i < 40; i++ {
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: fmt.Sprintf("user%d", i%4), Date: now.Add(-time.Duration(i) * 6 * time.Hour),
+			ShowerDuration: float64(8 + i%5), AverageTemperature: float64(12 + i%4),
+			HeatingTime: float64(18 + i%7), Satisfaction: float64(40 + i%20),
+		}))
+	}
+	cfg := &PredictionConfigV2{DisableExploration: true, FixedUserBoost: true}
+	req := PredictionRequest{UserID: "user0", Duration: 10, Temperature: 14}
+
+	scan := NewPredictionServiceV2(records, cfg)
+	want, err := scan.Predict(context.Background(), req)
+	require.NoError(t, err)
+
+	indexed := NewPredictionServiceV2(records, cfg)
+	indexed.SetCandidateIndex(NewCandidateIndex(records, 1))
+	got, err := indexed.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.InDelta(t, want.RawHeatingTime, got.RawHeatingTime, 1e-9, "every record is within reach, so the index weighs the same ones")
+	assert.Equal(t, want.Explanation.Neighbors, got.Explanation.Neighbors)
+}
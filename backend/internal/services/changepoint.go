@@ -0,0 +1,132 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// ChangePointConfig controls regime-change detection over a user's history
+type ChangePointConfig struct {
+	MinSegment int     // minimum records on each side of a change point
+	MinShift   float64 // minimum relative shift in required heating (0.2 => 20%)
+	MinScore   float64 // minimum two-sample t-score for the shift to count
+}
+
+// DefaultChangePointConfig returns the detection settings used by the predictors
+func DefaultChangePointConfig() ChangePointConfig {
+	return ChangePointConfig{
+		MinSegment: 6,
+		MinShift:   0.2,
+		MinScore:   3.0,
+	}
+}
+
+// ChangePoint is a detected persistent shift in how much heating a user needs.
+// Levels are required heating relative to the context baseline (1.0 = baseline).
+type ChangePoint struct {
+	Date         time.Time `json:"date"`
+	LevelBefore  float64   `json:"levelBefore"`
+	LevelAfter   float64   `json:"levelAfter"`
+	ShiftPercent float64   `json:"shiftPercent"`
+	Score        float64   `json:"score"`
+}
+
+// contextBaseline is a rough heating time for a context, used to compare records across contexts
+func contextBaseline(duration, temperature float64) float64 {
+	return math.Max(5.0, 12.0+0.4*duration-0.15*temperature)
+}
+
+// DetectChangePoints finds regime changes with binary segmentation over each record's
+// implied target time normalised by the context baseline. Records may be in any order;
+// change points are returned oldest first.
+func DetectChangePoints(records []models.DailyRecord, cfg ChangePointConfig) []ChangePoint {
+	if cfg.MinSegment < 2 || len(records) < 2*cfg.MinSegment {
+		return nil
+	}
+
+	sorted := make([]models.DailyRecord, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	levels := make([]float64, len(sorted))
+	for i, r := range sorted {
+		levels[i] = impliedTarget(r) / contextBaseline(r.ShowerDuration, r.AverageTemperature)
+	}
+
+	var points []ChangePoint
+	var segment func(lo, hi int)
+	segment = func(lo, hi int) {
+		split, cp, ok := bestSplit(levels[lo:hi], cfg)
+		if !ok {
+			return
+		}
+		cp.Date = sorted[lo+split].Date
+		points = append(points, cp)
+		segment(lo, lo+split)
+		segment(lo+split, hi)
+	}
+	segment(0, len(levels))
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+	return points
+}
+
+// bestSplit finds the split index maximising the two-sample t-score within xs
+func bestSplit(xs []float64, cfg ChangePointConfig) (int, ChangePoint, bool) {
+	n := len(xs)
+	if n < 2*cfg.MinSegment {
+		return 0, ChangePoint{}, false
+	}
+
+	prefix := make([]float64, n+1)
+	prefixSq := make([]float64, n+1)
+	for i, x := range xs {
+		prefix[i+1] = prefix[i] + x
+		prefixSq[i+1] = prefixSq[i] + x*x
+	}
+
+	bestIdx := 0
+	var best ChangePoint
+	for k := cfg.MinSegment; k <= n-cfg.MinSegment; k++ {
+		n1, n2 := float64(k), float64(n-k)
+		mean1 := prefix[k] / n1
+		mean2 := (prefix[n] - prefix[k]) / n2
+		ss1 := prefixSq[k] - n1*mean1*mean1
+		ss2 := (prefixSq[n] - prefixSq[k]) - n2*mean2*mean2
+		pooled := (ss1 + ss2) / (n1 + n2 - 2)
+		// Floor the variance so perfectly consistent segments don't produce infinite scores
+		stderr := math.Sqrt(math.Max(pooled, 1e-4) * (1/n1 + 1/n2))
+		score := math.Abs(mean2-mean1) / stderr
+		if score > best.Score {
+			bestIdx = k
+			best = ChangePoint{
+				LevelBefore:  mean1,
+				LevelAfter:   mean2,
+				ShiftPercent: (mean2 - mean1) / mean1 * 100,
+				Score:        score,
+			}
+		}
+	}
+
+	if best.Score < cfg.MinScore || math.Abs(best.ShiftPercent)/100 < cfg.MinShift {
+		return 0, ChangePoint{}, false
+	}
+	return bestIdx, best, true
+}
+
+// truncateAtChangePoint drops records older than the latest detected change point
+func truncateAtChangePoint(records []models.DailyRecord, cfg ChangePointConfig) []models.DailyRecord {
+	return recordsSince(records, latestChangePoint(records, cfg))
+}
+
+// latestChangePoint returns when the latest detected change point happened, nil when there is none
+func latestChangePoint(records []models.DailyRecord, cfg ChangePointConfig) *time.Time {
+	points := DetectChangePoints(records, cfg)
+	if len(points) == 0 {
+		return nil
+	}
+	return &points[len(points)-1].Date
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func regimeRecords(start time.Time, count int, heatingTime float64) []models.DailyRecord {
+	records := make([]models.DailyRecord, count)
+	for i := range records {
+		records[i] = models.DailyRecord{
+			Date:               start.AddDate(0, 0, i),
+			ShowerDuration:     10,
+			AverageTemperature: 15,
+			HeatingTime:        heatingTime + float64(i%3) - 1, // small day-to-day noise
+			Satisfaction:       50,
+		}
+	}
+	return records
+}
+
+func TestDetectChangePoints_FindsPersistentShift(t *testing.T) {
+	start := time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC)
+	records := append(regimeRecords(start, 10, 20), regimeRecords(start.AddDate(0, 0, 10), 10, 32)...)
+
+	points := DetectChangePoints(records, DefaultChangePointConfig())
+
+	assert.Len(t, points, 1)
+	assert.Equal(t, start.AddDate(0, 0, 10), points[0].Date)
+	assert.Greater(t, points[0].ShiftPercent, 40.0)
+
+	truncated := truncateAtChangePoint(records, DefaultChangePointConfig())
+	assert.Len(t, truncated, 10)
+}
+
+func TestDetectChangePoints_StableHistory(t *testing.T) {
+	start := time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC)
+	records := regimeRecords(start, 20, 20)
+
+	assert.Empty(t, DetectChangePoints(records, DefaultChangePointConfig()))
+	assert.Len(t, truncateAtChangePoint(records, DefaultChangePointConfig()), 20)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// chatReconnectDelay is how long a bot waits before listening again after losing its connection
+const chatReconnectDelay = 30 * time.Second
+
+// Built-in input ranges for chat commands, the same the API accepts
+const (
+	chatMinDuration    = 1 // minutes
+	chatMaxDuration    = 60
+	chatMinTemperature = -50 // °C
+	chatMaxTemperature = 50
+)
+
+const chatHelp = "Commands:\n" +
+	"!heat MINUTES [°C] - how long to heat for a shower, e.g. !heat 12 7; without a temperature your temperature sources are used\n" +
+	"!rate 1-100 - rate your last shower, 50 being perfect, e.g. !rate 60"
+
+// ChatCommandService answers the commands users send the notification bots, e.g. "!heat 12 7"
+// for a prediction and "!rate 60" for feedback on it. Each chat bot passes its messages here,
+// so the commands behave the same whichever chat they come from. A chat is linked to the user
+// with a notification route to it.
+type ChatCommandService struct {
+	notifications *NotificationService
+	predictor     Predictor
+	temperatures  *TemperatureService
+	predictionLog *PredictionLogService
+	records       *RecordService
+	users         *UserService
+	cfg           config.RecordsConfig
+	now           func() time.Time
+}
+
+// NewChatCommandService creates a chat command service. cfg's validation rules narrow the
+// ranges commands accept, as they do the API's.
+func NewChatCommandService(notifications *NotificationService, predictor Predictor, temperatures *TemperatureService, predictionLog *PredictionLogService, records *RecordService, users *UserService, cfg config.RecordsConfig) *ChatCommandService {
+	return &ChatCommandService{
+		notifications: notifications,
+		predictor:     predictor,
+		temperatures:  temperatures,
+		predictionLog: predictionLog,
+		records:       records,
+		users:         users,
+		cfg:           cfg,
+		now:           time.Now,
+	}
+}
+
+// Start makes every configured bot listen for commands, reconnecting when a connection fails
+func (s *ChatCommandService) Start() {
+	for name, listener := range s.notifications.Listeners() {
+		go func() {
+			handle := func(ctx context.Context, from, text string) string {
+				return s.Handle(ctx, name, from, text)
+			}
+			for {
+				err := listener.Listen(context.Background(), handle)
+				log.Printf("Stopped listening for %s commands, retrying in %s: %v", name, chatReconnectDelay, err)
+				time.Sleep(chatReconnectDelay)
+			}
+		}()
+	}
+}
+
+// Handle answers a message sent from a target of channel, returning the reply. Messages that
+// aren't commands get no reply. Commands start with ! or /, and may name the bot as Telegram
+// clients do, e.g. /heat@HeatLoggerBot 12.
+func (s *ChatCommandService) Handle(ctx context.Context, channel, from, text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || (fields[0][0] != '!' && fields[0][0] != '/') {
+		return ""
+	}
+	command, _, _ := strings.Cut(strings.ToLower(fields[0][1:]), "@")
+	args := fields[1:]
+	switch command {
+	case "heat", "rate":
+	case "help", "start":
+		return chatHelp
+	default:
+		// Probably meant for another bot in the room
+		return ""
+	}
+
+	userID, err := s.notifications.RouteOwner(channel, from)
+	if errors.Is(err, ErrNotificationRouteNotFound) {
+		return fmt.Sprintf("This chat isn't linked to Heat Logger yet. Add a %s notification route with the target %s to use commands here.", channel, from)
+	}
+	if err != nil {
+		return s.failed(command, err)
+	}
+	disabled, err := s.users.IsDisabled(userID)
+	if err != nil {
+		return s.failed(command, err)
+	}
+	if disabled {
+		return "This account has been disabled"
+	}
+
+	if command == "heat" {
+		return s.heat(ctx, userID, args)
+	}
+	return s.rate(userID, args)
+}
+
+// heat answers !heat MINUTES [°C] with a prediction, logged so !rate can answer it
+func (s *ChatCommandService) heat(ctx context.Context, userID string, args []string) string {
+	if len(args) < 1 || len(args) > 2 {
+		return "Usage: !heat MINUTES [°C], e.g. !heat 12 7"
+	}
+	duration, ok := parseChatNumber(args[0])
+	if min, max := s.durationRange(); !ok || duration < min || duration > max {
+		return fmt.Sprintf("Shower duration must be between %g and %g minutes", min, max)
+	}
+	var manual *float64
+	if len(args) == 2 {
+		temperature, ok := parseChatNumber(args[1])
+		if !ok {
+			return "Usage: !heat MINUTES [°C], e.g. !heat 12 7"
+		}
+		manual = &temperature
+	}
+	temperature, err := s.temperatures.Resolve(ctx, TemperatureQuery{UserID: userID, Manual: manual})
+	if errors.Is(err, ErrNoTemperature) {
+		return "Temperature is required, e.g. !heat 12 7"
+	}
+	if err != nil {
+		return s.failed("heat", err)
+	}
+	if min, max := s.temperatureRange(); temperature.Value < min || temperature.Value > max {
+		return fmt.Sprintf("Temperature must be between %g and %g degrees Celsius", min, max)
+	}
+
+	req := PredictionRequest{UserID: userID, Duration: duration, Temperature: temperature.Value}
+	prediction, err := s.predictor.Predict(ctx, req)
+	if errors.Is(err, ErrPredictionTimeout) {
+		return "Prediction took too long, please try again"
+	}
+	if err != nil {
+		return s.failed("heat", err)
+	}
+	// Copy before logging: cached predictions are shared
+	response := *prediction
+	response.Temperature = temperature
+	s.predictionLog.Record(req, &response)
+	return fmt.Sprintf("Heat for %g minutes for a %g minute shower at %g°C. Afterwards, rate it with !rate 1-100 (50 is perfect).",
+		math.Round(response.HeatingTime*10)/10, duration, temperature.Value)
+}
+
+// rate answers !rate SATISFACTION with feedback on the last prediction the user was served
+func (s *ChatCommandService) rate(userID string, args []string) string {
+	satisfaction, ok := 0.0, len(args) == 1
+	if ok {
+		satisfaction, ok = parseChatNumber(args[0])
+	}
+	if !ok || satisfaction < models.SatisfactionMin || satisfaction > models.SatisfactionMax {
+		return "Usage: !rate 1-100, 50 being perfect"
+	}
+
+	prediction, err := s.predictionLog.LatestUnanswered(userID, s.now())
+	if errors.Is(err, ErrPredictionNotFound) {
+		return "There's no shower from the last day to rate; ask for a heating time with !heat first"
+	}
+	if err != nil {
+		return s.failed("rate", err)
+	}
+	record := PredictionFeedbackRecord(*prediction, math.Round(satisfaction))
+
+	// The prediction is claimed first so the record isn't linked to another one once stored
+	if err := s.predictionLog.Claim(prediction.ID, record.ID); err != nil {
+		if errors.Is(err, ErrPredictionAnswered) {
+			return "That shower already has feedback"
+		}
+		return s.failed("rate", err)
+	}
+	err = s.records.CreateRecord(record)
+	switch {
+	case err == nil, errors.Is(err, ErrDuplicateRecord), errors.Is(err, ErrWriteBuffered):
+	case errors.Is(err, ErrRecordIDConflict):
+		return "That shower already has feedback"
+	default:
+		s.predictionLog.Release(prediction.ID, record.ID)
+		return s.failed("rate", err)
+	}
+	return fmt.Sprintf("Thanks! Rated %g for your %g minute shower, heated for %g minutes.",
+		record.Satisfaction, record.ShowerDuration, math.Round(record.HeatingTime*10)/10)
+}
+
+// failed logs an error running a command and returns the reply for it
+func (s *ChatCommandService) failed(command string, err error) string {
+	log.Printf("Failed to run chat command %s: %v", command, err)
+	return "Something went wrong, please try again later"
+}
+
+// durationRange returns the shower durations commands accept, narrowed by the operator's rules
+func (s *ChatCommandService) durationRange() (float64, float64) {
+	return narrowRange(chatMinDuration, chatMaxDuration, s.cfg.MinDuration, s.cfg.MaxDuration)
+}
+
+// temperatureRange returns the temperatures commands accept, narrowed by the operator's rules
+func (s *ChatCommandService) temperatureRange() (float64, float64) {
+	return narrowRange(chatMinTemperature, chatMaxTemperature, s.cfg.MinTemperature, s.cfg.MaxTemperature)
+}
+
+// narrowRange clamps the set bounds into a built-in range
+func narrowRange(min, max float64, narrowMin, narrowMax *float64) (float64, float64) {
+	lo, hi := min, max
+	if narrowMin != nil {
+		lo = math.Min(math.Max(*narrowMin, min), max)
+	}
+	if narrowMax != nil {
+		hi = math.Max(math.Min(*narrowMax, max), lo)
+	}
+	return lo, hi
+}
+
+// parseChatNumber parses a number as typed in a chat, allowing a decimal comma
+func parseChatNumber(s string) (float64, bool) {
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "°C"), "°")
+	value, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return value, err == nil && !math.IsNaN(value) && !math.IsInf(value, 0)
+}
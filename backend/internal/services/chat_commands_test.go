@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestChatCommandService_HeatThenRate(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "chat.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+
+	notifications := NewNotificationService(config.NotificationConfig{QuietHours: "off"})
+	notifications.channels.Add("chat", &recordingChannel{})
+	predictionLog := NewPredictionLogService(func(string) string { return "v2" })
+	records := NewRecordService()
+	maxDuration := 30.0
+	commands := NewChatCommandService(notifications, fixedPredictor(23), NewTemperatureService(config.TemperatureConfig{}),
+		predictionLog, records, NewUserService(), config.RecordsConfig{MaxDuration: &maxDuration})
+	ctx := context.Background()
+
+	assert.Empty(t, commands.Handle(ctx, "chat", "room1", "good morning"), "chatter gets no reply")
+	assert.Empty(t, commands.Handle(ctx, "chat", "room1", "!weather"), "nor do other bots' commands")
+	assert.Contains(t, commands.Handle(ctx, "chat", "room1", "/help"), "!heat MINUTES")
+	assert.Contains(t, commands.Handle(ctx, "chat", "room1", "!heat 12 7"), "route with the target room1")
+
+	require.NoError(t, notifications.AddRoute(&models.NotificationRoute{UserID: "user1", Channel: "chat", Target: "room1"}))
+	assert.Contains(t, commands.Handle(ctx, "chat", "room1", "!rate 60"), "no shower from the last day")
+	assert.Equal(t, "Shower duration must be between 1 and 30 minutes", commands.Handle(ctx, "chat", "room1", "!heat 45 7"))
+	assert.Contains(t, commands.Handle(ctx, "chat", "room1", "/heat@HeatLoggerBot 12 7,5"), "Heat for 23 minutes for a 12 minute shower at 7.5°C")
+
+	assert.Contains(t, commands.Handle(ctx, "chat", "room1", "!rate 60"), "Rated 60 for your 12 minute shower, heated for 23 minutes")
+	assert.Contains(t, commands.Handle(ctx, "chat", "room1", "!rate 70"), "no shower from the last day", "the prediction is answered")
+
+	stored, err := records.GetRecordsByUser("user1")
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, 12.0, stored[0].ShowerDuration)
+	assert.Equal(t, 7.5, stored[0].AverageTemperature)
+	assert.Equal(t, 60.0, stored[0].Satisfaction)
+}
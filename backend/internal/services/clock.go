@@ -0,0 +1,52 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells services what time it is. Predictions weight history by its age and the
+// scheduler plans from now, so backtests and benchmarks swap in a FrozenClock to get the same
+// answer every run.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// FrozenClock is a clock that only moves when told to
+type FrozenClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFrozenClock creates a clock stopped at now
+func NewFrozenClock(now time.Time) *FrozenClock {
+	return &FrozenClock{now: now}
+}
+
+// Now returns the time the clock is stopped at
+func (c *FrozenClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set stops the clock at now
+func (c *FrozenClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d
+func (c *FrozenClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
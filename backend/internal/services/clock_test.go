@@ -0,0 +1,21 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrozenClock_OnlyMovesWhenTold(t *testing.T) {
+	at := time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC)
+	clock := NewFrozenClock(at)
+	assert.Equal(t, at, clock.Now())
+	assert.Equal(t, at, clock.Now())
+
+	clock.Advance(90 * time.Minute)
+	assert.Equal(t, at.Add(90*time.Minute), clock.Now())
+
+	clock.Set(at)
+	assert.Equal(t, at, clock.Now())
+}
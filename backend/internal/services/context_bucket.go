@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"heat-logger/internal/models"
+)
+
+// Context bucket widths used to group (duration, temperature) contexts
+const (
+	bucketDurationWidth    = 5.0 // minutes
+	bucketTemperatureWidth = 5.0 // °C
+)
+
+// ContextBucket is a coarse (duration, temperature) range that records and requests fall into
+type ContextBucket struct {
+	Key            string  `json:"key"`
+	DurationMin    float64 `json:"durationMin"`
+	DurationMax    float64 `json:"durationMax"`
+	TemperatureMin float64 `json:"temperatureMin"`
+	TemperatureMax float64 `json:"temperatureMax"`
+}
+
+// BucketFor returns the bucket containing the given context
+func BucketFor(duration, temperature float64) ContextBucket {
+	d := math.Floor(duration / bucketDurationWidth)
+	t := math.Floor(temperature / bucketTemperatureWidth)
+	return ContextBucket{
+		Key:            fmt.Sprintf("d%d|t%d", int(d), int(t)),
+		DurationMin:    d * bucketDurationWidth,
+		DurationMax:    (d + 1) * bucketDurationWidth,
+		TemperatureMin: t * bucketTemperatureWidth,
+		TemperatureMax: (t + 1) * bucketTemperatureWidth,
+	}
+}
+
+// countInBucket counts records falling into the bucket
+func countInBucket(records []models.DailyRecord, bucket ContextBucket) int {
+	count := 0
+	for _, r := range records {
+		if BucketFor(r.ShowerDuration, r.AverageTemperature).Key == bucket.Key {
+			count++
+		}
+	}
+	return count
+}
+
+// contextConfidence scores 0..1 how settled a bucket is: more records and more consistent
+// implied targets both raise it.
+func contextConfidence(records []models.DailyRecord) float64 {
+	n := float64(len(records))
+	if n == 0 {
+		return 0
+	}
+	var sum, sumSq float64
+	for _, r := range records {
+		t := impliedTarget(r)
+		sum += t
+		sumSq += t * t
+	}
+	mean := sum / n
+	if mean <= 0 {
+		return 0
+	}
+	variance := math.Max(0, sumSq/n-mean*mean)
+	cv := math.Sqrt(variance) / mean
+	return (1 - math.Exp(-n/5)) / (1 + cv)
+}
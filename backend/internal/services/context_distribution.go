@@ -0,0 +1,166 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"heat-logger/internal/models"
+)
+
+const (
+	// distributionBinWidth is the width of a heating time histogram bin, minutes
+	distributionBinWidth = 5.0
+	// distributionMinContributors is how many other users the global histogram must draw on
+	// before it is shown, so no single household's showers can be read back from it
+	distributionMinContributors = 3
+	// distributionPerfectBand is how far from 50 a rating still counts as perfect
+	distributionPerfectBand = 3.0
+)
+
+// ContextDistribution is what the records near a context looked like: the heating times used
+// and how the showers turned out, for the user and for everyone else
+type ContextDistribution struct {
+	UserID      string               `json:"userId"`
+	Duration    float64              `json:"duration"`
+	Temperature float64              `json:"temperature"`
+	Window      DistributionWindow   `json:"window"`
+	User        NeighborDistribution `json:"user"`
+	Global      NeighborDistribution `json:"global"`
+}
+
+// DistributionWindow is how far from the requested context a record may be to count as a neighbor
+type DistributionWindow struct {
+	Duration    float64 `json:"duration"`    // ± minutes
+	Temperature float64 `json:"temperature"` // ± °C
+}
+
+// NeighborDistribution is a histogram of neighbors' heating times. Withheld global histograms
+// only report how many records they would have shown.
+type NeighborDistribution struct {
+	Records     int            `json:"records"`
+	Withheld    bool           `json:"withheld,omitempty"` // too few contributors to show
+	HeatingTime []HistogramBin `json:"heatingTime"`
+	Percentiles *Percentiles   `json:"percentiles,omitempty"` // of the heating times; nil without records
+}
+
+// HistogramBin counts the neighbors whose heating time fell in [Min, Max) and how they rated it
+type HistogramBin struct {
+	Min          float64 `json:"min"`
+	Max          float64 `json:"max"`
+	Records      int     `json:"records"`
+	Satisfaction float64 `json:"satisfaction"` // mean 1-100, 50 is perfect
+	TooCold      int     `json:"tooCold"`
+	Perfect      int     `json:"perfect"`
+	TooHot       int     `json:"tooHot"`
+}
+
+// SetNeighborhood sets how far from a context records count as its neighbors; values that
+// aren't positive keep the default
+func (s *StatsService) SetNeighborhood(duration, temperature float64) {
+	if duration > 0 {
+		s.neighborhood.Duration = duration
+	}
+	if temperature > 0 {
+		s.neighborhood.Temperature = temperature
+	}
+}
+
+// Distribution histograms the heating times and ratings of the training records near the
+// context, the user's apart from everyone else's
+func (s *StatsService) Distribution(userID string, duration, temperature float64) (*ContextDistribution, error) {
+	userRecords, err := s.recordService.GetRecordsForPredictionByUser(userID, 400)
+	if err != nil {
+		return nil, err
+	}
+	globalRecords, err := s.recordService.GetGlobalRecordsForPrediction(userID, 1200)
+	if err != nil {
+		return nil, err
+	}
+
+	window := s.neighborhood
+	near := func(records []models.DailyRecord) []models.DailyRecord {
+		var out []models.DailyRecord
+		for _, r := range records {
+			if math.Abs(r.ShowerDuration-duration) <= window.Duration && math.Abs(r.AverageTemperature-temperature) <= window.Temperature {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+
+	global := near(globalRecords)
+	contributors := map[string]bool{}
+	for _, r := range global {
+		contributors[r.UserID] = true
+	}
+	result := &ContextDistribution{
+		UserID:      userID,
+		Duration:    duration,
+		Temperature: temperature,
+		Window:      window,
+		User:        histogram(near(userRecords)),
+	}
+	if len(global) > 0 && len(contributors) < distributionMinContributors {
+		result.Global = NeighborDistribution{Records: len(global), Withheld: true, HeatingTime: []HistogramBin{}}
+	} else {
+		result.Global = histogram(global)
+	}
+	return result, nil
+}
+
+// histogram bins records by heating time; bins between the shortest and longest are kept even
+// when empty so the histogram can be drawn as is
+func histogram(records []models.DailyRecord) NeighborDistribution {
+	dist := NeighborDistribution{Records: len(records), HeatingTime: []HistogramBin{}}
+	if len(records) == 0 {
+		return dist
+	}
+
+	times := make([]float64, len(records))
+	for i, r := range records {
+		times[i] = r.HeatingTime
+	}
+	sort.Float64s(times)
+	dist.Percentiles = &Percentiles{
+		P10: roundTo(percentileOf(times, 0.1), 2),
+		P50: roundTo(percentileOf(times, 0.5), 2),
+		P90: roundTo(percentileOf(times, 0.9), 2),
+	}
+
+	first := math.Floor(times[0] / distributionBinWidth)
+	last := math.Floor(times[len(times)-1] / distributionBinWidth)
+	bins := make([]HistogramBin, int(last-first)+1)
+	sums := make([]float64, len(bins))
+	for i := range bins {
+		lo := (first + float64(i)) * distributionBinWidth
+		bins[i] = HistogramBin{Min: lo, Max: lo + distributionBinWidth}
+	}
+	for _, r := range records {
+		i := int(math.Floor(r.HeatingTime/distributionBinWidth) - first)
+		bins[i].Records++
+		sums[i] += r.Satisfaction
+		switch {
+		case r.Satisfaction < 50-distributionPerfectBand:
+			bins[i].TooCold++
+		case r.Satisfaction > 50+distributionPerfectBand:
+			bins[i].TooHot++
+		default:
+			bins[i].Perfect++
+		}
+	}
+	for i := range bins {
+		if bins[i].Records > 0 {
+			bins[i].Satisfaction = roundTo(sums[i]/float64(bins[i].Records), 2)
+		}
+	}
+	dist.HeatingTime = bins
+	return dist
+}
+
+// percentileOf interpolates the p-th percentile, 0-1, of sorted values
+func percentileOf(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// ContextService summarises the context buckets a user's model has learned
+type ContextService struct {
+	recordService RecordServiceInterface
+	predictor     Predictor
+}
+
+// NewContextService creates a new context service instance
+func NewContextService(recordService RecordServiceInterface, predictor Predictor) *ContextService {
+	return &ContextService{
+		recordService: recordService,
+		predictor:     predictor,
+	}
+}
+
+// LearnedContext describes one context bucket the user has history in
+type LearnedContext struct {
+	ContextBucket
+	RecordCount            int       `json:"recordCount"`
+	TypicalDuration        float64   `json:"typicalDuration"`
+	TypicalTemperature     float64   `json:"typicalTemperature"`
+	LastUsedAt             time.Time `json:"lastUsedAt"`
+	RecommendedHeatingTime float64   `json:"recommendedHeatingTime"`
+	Confidence             float64   `json:"confidence"` // 0..1
+}
+
+// ListContexts returns the user's learned context buckets, most used first
+func (s *ContextService) ListContexts(ctx context.Context, userID string) ([]LearnedContext, error) {
+	records, err := s.recordService.GetRecordsForPredictionByUser(userID, 400)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string][]models.DailyRecord)
+	buckets := make(map[string]ContextBucket)
+	for _, r := range records {
+		bucket := BucketFor(r.ShowerDuration, r.AverageTemperature)
+		grouped[bucket.Key] = append(grouped[bucket.Key], r)
+		buckets[bucket.Key] = bucket
+	}
+
+	contexts := make([]LearnedContext, 0, len(grouped))
+	for key, bucketRecords := range grouped {
+		learned := LearnedContext{
+			ContextBucket: buckets[key],
+			RecordCount:   len(bucketRecords),
+			Confidence:    math.Round(contextConfidence(bucketRecords)*100) / 100,
+		}
+		for _, r := range bucketRecords {
+			learned.TypicalDuration += r.ShowerDuration
+			learned.TypicalTemperature += r.AverageTemperature
+			if r.Date.After(learned.LastUsedAt) {
+				learned.LastUsedAt = r.Date
+			}
+		}
+		learned.TypicalDuration = math.Round(learned.TypicalDuration/float64(len(bucketRecords))*10) / 10
+		learned.TypicalTemperature = math.Round(learned.TypicalTemperature/float64(len(bucketRecords))*10) / 10
+
+		// A recommendation is the model's answer, not a probe: no feedback comes back on it
+		prediction, err := s.predictor.Predict(ctx, PredictionRequest{
+			UserID:      userID,
+			Duration:    learned.TypicalDuration,
+			Temperature: learned.TypicalTemperature,
+
+			withoutExploration: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		learned.RecommendedHeatingTime = prediction.HeatingTime
+
+		contexts = append(contexts, learned)
+	}
+
+	sort.Slice(contexts, func(i, j int) bool {
+		if contexts[i].RecordCount != contexts[j].RecordCount {
+			return contexts[i].RecordCount > contexts[j].RecordCount
+		}
+		return contexts[i].Key < contexts[j].Key
+	})
+	return contexts, nil
+}
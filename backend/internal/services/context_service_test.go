@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextService_RecommendsWithoutExploring(t *testing.T) {
+	// One shower in the context, so the next one there is still being explored
+	userRecords := []models.DailyRecord{{
+		UserID: "user1", Date: time.Now().AddDate(0, 0, -1),
+		ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50,
+	}}
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	explored, err := NewPredictionServiceV2(mockRecordService, nil).Predict(context.Background(), req)
+	require.NoError(t, err)
+	require.NotNil(t, explored.Explanation.Exploration)
+	plain, err := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true}).Predict(context.Background(), req)
+	require.NoError(t, err)
+	require.NotEqual(t, plain.HeatingTime, explored.HeatingTime)
+
+	contexts, err := NewContextService(mockRecordService, NewPredictionServiceV2(mockRecordService, nil)).ListContexts(context.Background(), "user1")
+	require.NoError(t, err)
+	require.Len(t, contexts, 1)
+	assert.Equal(t, plain.HeatingTime, contexts[0].RecommendedHeatingTime)
+}
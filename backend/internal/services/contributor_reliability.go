@@ -0,0 +1,196 @@
+package services
+
+import (
+	"log"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// reliabilityLookback is how far back a user's feedback counts towards their score
+	reliabilityLookback = 180 * 24 * time.Hour
+	// reliabilityRefreshInterval is how often scores are recomputed
+	reliabilityRefreshInterval = 15 * time.Minute
+	// reliabilityUserRecords caps the records scored per user, latest first
+	reliabilityUserRecords = 400
+)
+
+// Two showers are comparable when they were this close in length and weather, and the heating
+// differed by enough that the user should have felt it
+const (
+	comparableDuration = 3.0 // minutes
+	comparableTemp     = 3.0 // °C
+	comparableHeating  = 2.0 // minutes
+	comparableRating   = 5.0 // satisfaction points; smaller differences are noise
+)
+
+// ContributorFilter lists the users whose records are left out of other users' predictions
+type ContributorFilter interface {
+	Excluded() []string
+}
+
+// ContributorWeightLookup reports how much a user's records count in other users' predictions
+type ContributorWeightLookup interface {
+	ContributorWeight(userID string) float64
+}
+
+// ContributorReliability is how consistently a user rated their showers
+type ContributorReliability struct {
+	UserID string  `json:"userId"`
+	Score  float64 `json:"score"`  // share of comparable pairs of showers rated consistently, 0-1
+	Pairs  int     `json:"pairs"`  // comparable pairs of showers
+	Weight float64 `json:"weight"` // what their records count for in others' predictions; 0 excludes them
+}
+
+// ContributorReliabilityService scores how consistent each user's feedback is, so users who
+// click ratings at random don't skew everyone else's predictions. Of two comparable showers,
+// the one heated longer should not have been rated colder; a user's score is the share of
+// their comparable pairs where that holds. Random ratings score about 0.5. Scores are
+// recomputed in the background and only affect the global pool: a user's own predictions
+// always learn from all of their feedback.
+type ContributorReliabilityService struct {
+	db      *gorm.DB
+	cfg     config.ReliabilityConfig
+	current atomic.Pointer[map[string]ContributorReliability]
+}
+
+// NewContributorReliabilityService creates a new contributor reliability service instance
+func NewContributorReliabilityService(cfg config.ReliabilityConfig) *ContributorReliabilityService {
+	return &ContributorReliabilityService{
+		db:  database.GetDB(),
+		cfg: cfg,
+	}
+}
+
+// Start scores contributors now and keeps rescoring them in the background
+func (s *ContributorReliabilityService) Start() {
+	go func() {
+		ticker := time.NewTicker(reliabilityRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.Refresh(time.Now()); err != nil {
+				log.Printf("Failed to score contributor reliability: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Refresh rescores every user with feedback since the lookback
+func (s *ContributorReliabilityService) Refresh(now time.Time) error {
+	var records []models.DailyRecord
+	err := s.db.Select("user_id", "date", "shower_duration", "average_temperature", "heating_time", "satisfaction").
+		Where("date >= ? AND excluded_from_training = ? AND review_status NOT IN ?",
+			now.Add(-reliabilityLookback), false, []string{models.ReviewStatusPending, models.ReviewStatusInferred}).
+		Order("date DESC").Find(&records).Error
+	if err != nil {
+		return err
+	}
+
+	byUser := make(map[string][]models.DailyRecord)
+	for _, record := range records {
+		if len(byUser[record.UserID]) < reliabilityUserRecords {
+			byUser[record.UserID] = append(byUser[record.UserID], record)
+		}
+	}
+	scores := make(map[string]ContributorReliability, len(byUser))
+	for userID, userRecords := range byUser {
+		score, pairs := feedbackConsistency(userRecords)
+		scores[userID] = ContributorReliability{
+			UserID: userID,
+			Score:  roundTo(score, 3),
+			Pairs:  pairs,
+			Weight: roundTo(s.weight(score, pairs), 3),
+		}
+	}
+	s.current.Store(&scores)
+	return nil
+}
+
+// weight maps a score to what the user's records count for: nothing at or below the exclude
+// threshold, rising linearly to full weight at the full threshold. Users with too few
+// comparable showers to tell count fully.
+func (s *ContributorReliabilityService) weight(score float64, pairs int) float64 {
+	if pairs < s.cfg.MinPairs {
+		return 1
+	}
+	return math.Max(0, math.Min(1, (score-s.cfg.Exclude)/(s.cfg.Full-s.cfg.Exclude)))
+}
+
+// List returns every scored user, least reliable first
+func (s *ContributorReliabilityService) List() []ContributorReliability {
+	list := []ContributorReliability{}
+	if scores := s.current.Load(); scores != nil {
+		for _, reliability := range *scores {
+			list = append(list, reliability)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Weight != list[j].Weight {
+			return list[i].Weight < list[j].Weight
+		}
+		return list[i].UserID < list[j].UserID
+	})
+	return list
+}
+
+// ContributorWeight returns what the user's records count for in others' predictions, 1 until
+// they are scored
+func (s *ContributorReliabilityService) ContributorWeight(userID string) float64 {
+	if scores := s.current.Load(); scores != nil {
+		if reliability, ok := (*scores)[userID]; ok {
+			return reliability.Weight
+		}
+	}
+	return 1
+}
+
+// Excluded returns the users whose records are left out of others' predictions
+func (s *ContributorReliabilityService) Excluded() []string {
+	var excluded []string
+	if scores := s.current.Load(); scores != nil {
+		for userID, reliability := range *scores {
+			if reliability.Weight == 0 {
+				excluded = append(excluded, userID)
+			}
+		}
+	}
+	sort.Strings(excluded)
+	return excluded
+}
+
+// feedbackConsistency returns the share of comparable pairs of records whose ratings agree
+// with their heating times, and the number of comparable pairs
+func feedbackConsistency(records []models.DailyRecord) (float64, int) {
+	consistent, pairs := 0, 0
+	for i := range records {
+		for j := i + 1; j < len(records); j++ {
+			a, b := records[i], records[j]
+			if math.Abs(a.ShowerDuration-b.ShowerDuration) > comparableDuration ||
+				math.Abs(a.AverageTemperature-b.AverageTemperature) > comparableTemp {
+				continue
+			}
+			heating := a.HeatingTime - b.HeatingTime
+			rating := a.Satisfaction - b.Satisfaction
+			if math.Abs(heating) < comparableHeating || math.Abs(rating) < comparableRating {
+				continue
+			}
+			pairs++
+			if (heating > 0) == (rating > 0) {
+				consistent++
+			}
+		}
+	}
+	if pairs == 0 {
+		return 0, 0
+	}
+	return float64(consistent) / float64(pairs), pairs
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestContributorReliability(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "reliability.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+
+	// "careful" rates longer heating warmer; "clicker" rates at random
+	random := rand.New(rand.NewSource(1))
+	now := time.Now()
+	for i := 0; i < 40; i++ {
+		heating := float64(10 + i%8*3)
+		for _, record := range []models.DailyRecord{
+			{UserID: "careful", HeatingTime: heating, Satisfaction: 20 + heating*1.5},
+			{UserID: "clicker", HeatingTime: heating, Satisfaction: float64(random.Intn(100) + 1)},
+			{UserID: "newcomer", HeatingTime: heating, Satisfaction: float64(random.Intn(100) + 1)},
+		} {
+			if record.UserID == "newcomer" && i >= 4 {
+				continue
+			}
+			record.Date = now.AddDate(0, 0, -i)
+			record.ShowerDuration = 10
+			record.AverageTemperature = 15
+			require.NoError(t, records.CreateRecord(&record))
+		}
+	}
+
+	reliability := NewContributorReliabilityService(config.ReliabilityConfig{MinPairs: 20, Exclude: 0.6, Full: 0.8})
+	assert.Equal(t, 1.0, reliability.ContributorWeight("clicker"), "unscored users count fully")
+	require.NoError(t, reliability.Refresh(now))
+
+	list := reliability.List()
+	require.Len(t, list, 3)
+	assert.Equal(t, "clicker", list[0].UserID)
+	assert.Zero(t, list[0].Weight)
+	assert.Equal(t, 1.0, reliability.ContributorWeight("careful"))
+	assert.Equal(t, 1.0, reliability.ContributorWeight("newcomer"), "too few comparable showers to tell")
+	assert.Equal(t, []string{"clicker"}, reliability.Excluded())
+
+	// The clicker's records are left out of everyone else's global pool
+	records.SetContributorFilter(reliability)
+	global, err := records.GetGlobalRecordsForPrediction("careful", 1000)
+	require.NoError(t, err)
+	require.NotEmpty(t, global)
+	for _, record := range global {
+		assert.NotEqual(t, "clicker", record.UserID)
+	}
+}
+
+func TestFeedbackConsistency(t *testing.T) {
+	record := func(duration, heating, satisfaction float64) models.DailyRecord {
+		return models.DailyRecord{ShowerDuration: duration, AverageTemperature: 15, HeatingTime: heating, Satisfaction: satisfaction}
+	}
+	score, pairs := feedbackConsistency([]models.DailyRecord{
+		record(10, 10, 30),
+		record(10, 20, 50),
+		record(11, 30, 40), // colder than a shower heated less
+		record(25, 5, 90),  // not comparable: much longer shower
+		record(10, 21, 52), // heated about the same as the second
+	})
+	assert.Equal(t, 5, pairs)
+	assert.InDelta(t, 0.6, score, 1e-9)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrTraceNotFound is returned when nothing was recorded under a correlation ID, or the feedback
+// record asked about answers no correlated prediction
+var ErrTraceNotFound = errors.New("trace not found")
+
+// CorrelationTrace is everything recorded under one correlation ID: the prediction served by
+// /api/calculate, the schedule recommended for it, the smart plug and sensor events of the run
+// and the feedback the user gave afterwards
+type CorrelationTrace struct {
+	CorrelationID string                    `json:"correlationId"`
+	Predictions   []models.PredictionLog    `json:"predictions"`
+	Events        []models.CorrelationEvent `json:"events"`   // oldest first
+	Feedback      []models.DailyRecord      `json:"feedback"` // the records the predictions were answered with
+}
+
+// CorrelationService records the steps of a heating run under the correlation ID of the
+// prediction it heats for, so a bad shower can be followed back to what was planned and what
+// the devices reported. Predictions and their feedback are kept in the prediction log; the
+// steps between them are kept here.
+type CorrelationService struct {
+	db          *gorm.DB
+	predictions *PredictionLogService
+}
+
+// NewCorrelationService creates a new correlation service instance
+func NewCorrelationService(predictions *PredictionLogService) *CorrelationService {
+	return &CorrelationService{
+		db:          database.GetDB(),
+		predictions: predictions,
+	}
+}
+
+// Prune deletes events older than before
+func (s *CorrelationService) Prune(before time.Time) (int64, error) {
+	result := s.db.Where("at < ?", before).Delete(&models.CorrelationEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// RecordSchedule records the schedule recommended for a correlated prediction. Failures are
+// only logged: the user still gets their schedule.
+func (s *CorrelationService) RecordSchedule(correlationID string, req ScheduleRequest, schedule *HeatingSchedule) {
+	start, readyBy, minutes := schedule.Start, req.ReadyBy, req.Minutes
+	s.record(&models.CorrelationEvent{
+		CorrelationID: correlationID,
+		Kind:          models.CorrelationKindSchedule,
+		UserID:        req.UserID,
+		DeviceID:      req.DeviceID,
+		HeatingTime:   &minutes,
+		Start:         &start,
+		ReadyAt:       &readyBy,
+		At:            time.Now(),
+	})
+}
+
+// RecordDeviceEvent records an event of a correlated heating run; it is registered with
+// DeviceStateService.OnCorrelated
+func (s *CorrelationService) RecordDeviceEvent(deviceID string, event DeviceEvent, status DeviceStatus) {
+	entry := &models.CorrelationEvent{
+		CorrelationID: event.CorrelationID,
+		Kind:          event.Type,
+		DeviceID:      deviceID,
+		Temperature:   event.Temperature,
+		State:         string(status.State),
+		ReadyAt:       status.ReadyAt,
+		At:            event.At,
+	}
+	if event.HeatingTime > 0 {
+		minutes := event.HeatingTime
+		entry.HeatingTime = &minutes
+	}
+	s.record(entry)
+}
+
+func (s *CorrelationService) record(entry *models.CorrelationEvent) {
+	if err := s.db.Create(entry).Error; err != nil {
+		log.Printf("Failed to record %s event for correlation %s: %v", entry.Kind, entry.CorrelationID, err)
+	}
+}
+
+// Trace returns everything recorded under a correlation ID
+func (s *CorrelationService) Trace(correlationID string) (*CorrelationTrace, error) {
+	predictions, err := s.predictions.Correlated(correlationID)
+	if err != nil {
+		return nil, err
+	}
+	var events []models.CorrelationEvent
+	if err := s.db.Where("correlation_id = ?", correlationID).Order("at ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	if len(predictions) == 0 && len(events) == 0 {
+		return nil, ErrTraceNotFound
+	}
+
+	// The prediction log may be kept apart from the records
+	var recordIDs []string
+	for _, prediction := range predictions {
+		if prediction.RecordID != "" {
+			recordIDs = append(recordIDs, prediction.RecordID)
+		}
+	}
+	records, err := recordsByID(s.db, recordIDs)
+	if err != nil {
+		return nil, err
+	}
+	trace := &CorrelationTrace{
+		CorrelationID: correlationID,
+		Predictions:   predictions,
+		Events:        events,
+		Feedback:      []models.DailyRecord{},
+	}
+	for _, id := range recordIDs {
+		if record, ok := records[id]; ok {
+			trace.Feedback = append(trace.Feedback, record)
+		}
+	}
+	return trace, nil
+}
+
+// TraceRecord returns the trace of the prediction a feedback record answers
+func (s *CorrelationService) TraceRecord(recordID string) (*CorrelationTrace, error) {
+	correlationID, err := s.predictions.CorrelationOf(recordID)
+	if err != nil {
+		return nil, err
+	}
+	if correlationID == "" {
+		return nil, ErrTraceNotFound
+	}
+	return s.Trace(correlationID)
+}
@@ -0,0 +1,719 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrImportMappingNotFound is returned when a saved import mapping does not exist for the user
+	ErrImportMappingNotFound = errors.New("import mapping not found")
+	// ErrImportMappingFields is returned when a required field is unmapped, a field is unknown or
+	// two fields share a column
+	ErrImportMappingFields = errors.New("date, showerDuration, averageTemperature, heatingTime and satisfaction must each be mapped to a different column")
+	// ErrImportMappingUnit is returned for a unit the field can't be converted from
+	ErrImportMappingUnit = errors.New("unsupported unit")
+	// ErrImportMappingFormat is returned for an unknown separator, date format or time zone
+	ErrImportMappingFormat = errors.New("unknown separator, date format or time zone")
+	// ErrImportNoHeader is returned for a file without a header row
+	ErrImportNoHeader = errors.New("the file has no header row")
+	// ErrImportColumnMissing is returned when the file lacks a column the mapping reads
+	ErrImportColumnMissing = errors.New("the file lacks a column the mapping reads")
+	// ErrImportTooManyRows is returned for a file with more rows than one import takes
+	ErrImportTooManyRows = errors.New("too many rows")
+)
+
+// Record fields a CSV column can be mapped to
+const (
+	ImportFieldDate               = "date"
+	ImportFieldShowerDuration     = "showerDuration"
+	ImportFieldAverageTemperature = "averageTemperature"
+	ImportFieldHeatingTime        = "heatingTime"
+	ImportFieldSatisfaction       = "satisfaction"
+	ImportFieldInletTemperature   = "inletTemperature"
+	ImportFieldDeviceID           = "deviceId"
+)
+
+// Units import values can be converted from
+const (
+	UnitCelsius    = "C"
+	UnitFahrenheit = "F"
+	UnitKelvin     = "K"
+	UnitMinutes    = "min"
+	UnitSeconds    = "s"
+	UnitHours      = "h"
+)
+
+// Reasons a proposed mapping is worth a second look
+const (
+	ImportNoteUnitFromHeader     = "unit_from_header"     // the unit was read from the column's header
+	ImportNoteUnitFromValues     = "unit_from_values"     // the unit was guessed from the range of the values
+	ImportNoteAmbiguousDateOrder = "ambiguous_date_order" // every date reads both day-first and month-first
+)
+
+// Limits of the import flow
+const (
+	csvImportMaxRows      = 5000
+	csvProposalSampleRows = 50
+	csvProposalPreview    = 5
+)
+
+// importFieldKind is how an import field's values are read
+type importFieldKind int
+
+const (
+	importText importFieldKind = iota
+	importDate
+	importNumber
+	importDuration
+	importTemperature
+)
+
+// importField describes a record field a column can be mapped to, with the header names that
+// suggest it, normalized as by normalizeHeader
+type importField struct {
+	name     string
+	kind     importFieldKind
+	required bool
+	synonyms []string
+}
+
+var importFields = []importField{
+	{ImportFieldDate, importDate, true, []string{"date", "day", "datetime", "timestamp", "time", "when", "תאריך"}},
+	{ImportFieldShowerDuration, importDuration, true, []string{"showerduration", "duration", "shower", "showerlength", "showertime", "showerminutes", "length", "מקלחת"}},
+	{ImportFieldAverageTemperature, importTemperature, true, []string{"averagetemperature", "temperature", "temp", "outsidetemp", "outsidetemperature", "outdoortemp", "outdoortemperature", "airtemp", "weather", "טמפרטורה"}},
+	{ImportFieldHeatingTime, importDuration, true, []string{"heatingtime", "heating", "heatingminutes", "heattime", "heatup", "boiler", "boilertime", "heatertime", "dudtime", "דוד", "חימום"}},
+	{ImportFieldSatisfaction, importNumber, true, []string{"satisfaction", "rating", "feedback", "score", "comfort", "feeling", "שביעותרצון", "דירוג"}},
+	{ImportFieldInletTemperature, importTemperature, false, []string{"inlettemperature", "inlet", "inlettemp", "watertemp", "watertemperature", "coldwater", "coldwatertemp"}},
+	{ImportFieldDeviceID, importText, false, []string{"deviceid", "device", "heater", "boilerid", "heaterid"}},
+}
+
+// importDateFormats are the date layouts an import can read, by the name clients use for them,
+// in the order a proposal tries them
+var importDateFormats = []struct{ name, layout string }{
+	{"ISO 8601", time.RFC3339},
+	{"YYYY-MM-DD HH:mm:ss", "2006-1-2 15:04:05"},
+	{"YYYY-MM-DD HH:mm", "2006-1-2 15:04"},
+	{"YYYY-MM-DD", "2006-1-2"},
+	{"YYYY/MM/DD", "2006/1/2"},
+	{"DD/MM/YYYY HH:mm", "2/1/2006 15:04"},
+	{"DD/MM/YYYY", "2/1/2006"},
+	{"MM/DD/YYYY HH:mm", "1/2/2006 15:04"},
+	{"MM/DD/YYYY", "1/2/2006"},
+	{"DD.MM.YYYY HH:mm", "2.1.2006 15:04"},
+	{"DD.MM.YYYY", "2.1.2006"},
+}
+
+// importSeparators are the column separators an import can read
+var importSeparators = []string{",", ";", "\t", "|"}
+
+// ImportDateFormats lists the date formats an import mapping can name
+func ImportDateFormats() []string {
+	names := make([]string, len(importDateFormats))
+	for i, format := range importDateFormats {
+		names[i] = format.name
+	}
+	return names
+}
+
+// CSVMapping says how to read a CSV file into records
+type CSVMapping struct {
+	Separator  string               `json:"separator,omitempty"` // one of , ; tab or |; "," when empty
+	DateFormat string               `json:"dateFormat"`          // see ImportDateFormats
+	Timezone   string               `json:"timezone,omitempty"`  // IANA zone of dates written without one; server time when empty
+	Columns    map[string]CSVColumn `json:"columns"`             // by record field
+}
+
+// CSVColumn is the column a record field is read from
+type CSVColumn struct {
+	Column string `json:"column"`         // header of the column, matched ignoring case
+	Unit   string `json:"unit,omitempty"` // C, F or K for temperatures and min, s or h for durations; C or min when empty
+}
+
+// CSVMappingNote flags a proposed field mapping the user should check
+type CSVMappingNote struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"` // unit_from_header, unit_from_values or ambiguous_date_order
+}
+
+// CSVImportProposal is the server's guess at how to read a file, for the user to confirm
+type CSVImportProposal struct {
+	Mapping  CSVMapping       `json:"mapping"`
+	Headers  []string         `json:"headers"`
+	Missing  []string         `json:"missing"`  // required fields no column was found for
+	Unmapped []string         `json:"unmapped"` // columns that won't be imported
+	Notes    []CSVMappingNote `json:"notes"`
+	Preview  []CSVImportRow   `json:"preview"` // the first rows as they would import; empty while fields are missing
+}
+
+// CSVImportRow is one data row of a file read through a mapping: its record, or the problem
+// that kept it from being read
+type CSVImportRow struct {
+	Line    int                 `json:"line"`
+	Record  *models.DailyRecord `json:"record,omitempty"`
+	Field   string              `json:"field,omitempty"`
+	Problem string              `json:"problem,omitempty"` // untranslated
+}
+
+// Problems reading a value, untranslated
+const (
+	importProblemMissing = "Value is missing"
+	importProblemNumber  = "Value is not a number"
+	importProblemDate    = "Value doesn't match the date format"
+)
+
+// importRecordNamespace derives stable record IDs from imported rows, so importing the same
+// file again reports its rows as duplicates instead of storing them twice
+var importRecordNamespace = uuid.MustParse("6f1c3c38-5e4b-4a8e-9d4c-2b6f0c9e7a11")
+
+// CSVImportService reads CSV files laid out however the user's old spreadsheet was, through
+// mappings it proposes from a sample and the user confirms, and keeps confirmed mappings for
+// the next file
+type CSVImportService struct {
+	db *gorm.DB
+}
+
+// NewCSVImportService creates a new CSV import service instance
+func NewCSVImportService() *CSVImportService {
+	return &CSVImportService{
+		db: database.GetDB(),
+	}
+}
+
+// Validate checks the mapping names known fields, units, formats and distinct columns
+func (m CSVMapping) Validate() error {
+	if m.Separator != "" && !slices.Contains(importSeparators, m.Separator) {
+		return ErrImportMappingFormat
+	}
+	if _, ok := dateLayout(m.DateFormat); !ok {
+		return ErrImportMappingFormat
+	}
+	if m.Timezone != "" {
+		if _, err := time.LoadLocation(m.Timezone); err != nil {
+			return ErrImportMappingFormat
+		}
+	}
+
+	columns := make(map[string]bool)
+	for name, column := range m.Columns {
+		field, ok := importFieldNamed(name)
+		header := strings.ToLower(strings.TrimSpace(column.Column))
+		if !ok || header == "" || columns[header] {
+			return ErrImportMappingFields
+		}
+		columns[header] = true
+		if !validImportUnit(field.kind, column.Unit) {
+			return ErrImportMappingUnit
+		}
+	}
+	for _, field := range importFields {
+		if _, ok := m.Columns[field.name]; field.required && !ok {
+			return ErrImportMappingFields
+		}
+	}
+	return nil
+}
+
+func importFieldNamed(name string) (importField, bool) {
+	for _, field := range importFields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	return importField{}, false
+}
+
+func validImportUnit(kind importFieldKind, unit string) bool {
+	switch kind {
+	case importTemperature:
+		return unit == "" || unit == UnitCelsius || unit == UnitFahrenheit || unit == UnitKelvin
+	case importDuration:
+		return unit == "" || unit == UnitMinutes || unit == UnitSeconds || unit == UnitHours
+	default:
+		return unit == ""
+	}
+}
+
+func dateLayout(name string) (string, bool) {
+	for _, format := range importDateFormats {
+		if format.name == name {
+			return format.layout, true
+		}
+	}
+	return "", false
+}
+
+// convertImportValue converts a value in the unit to minutes or °C
+func convertImportValue(value float64, unit string) float64 {
+	switch unit {
+	case UnitFahrenheit:
+		return (value - 32) * 5 / 9
+	case UnitKelvin:
+		return value - 273.15
+	case UnitSeconds:
+		return value / 60
+	case UnitHours:
+		return value * 60
+	}
+	return value
+}
+
+// parseImportNumber reads a number written with a decimal point or, as many locales do, a
+// decimal comma
+func parseImportNumber(s string) (float64, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if strings.Contains(s, ",") && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	return value, err == nil
+}
+
+// newCSVReader reads CSV text with the separator, skipping a byte order mark
+func newCSVReader(text, separator string) *csv.Reader {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(text, "\ufeff")))
+	if separator != "" {
+		reader.Comma = []rune(separator)[0]
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+// Parse reads the user's records from CSV text through the mapping. Rows that can't be read
+// carry the problem instead of a record; blank rows are skipped.
+func (s *CSVImportService) Parse(userID, text string, mapping CSVMapping) ([]CSVImportRow, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	return parseCSVRows(userID, newCSVReader(text, mapping.Separator), mapping, csvImportMaxRows)
+}
+
+func parseCSVRows(userID string, reader *csv.Reader, mapping CSVMapping, maxRows int) ([]CSVImportRow, error) {
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrImportNoHeader
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	columns := make(map[string]int, len(mapping.Columns))
+	for field, column := range mapping.Columns {
+		i, ok := index[strings.ToLower(strings.TrimSpace(column.Column))]
+		if !ok {
+			return nil, ErrImportColumnMissing
+		}
+		columns[field] = i
+	}
+	layout, _ := dateLayout(mapping.DateFormat)
+	loc := time.Local
+	if mapping.Timezone != "" {
+		loc, _ = time.LoadLocation(mapping.Timezone)
+	}
+
+	rows := []CSVImportRow{}
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(strings.Join(cells, "")) == "" {
+			continue
+		}
+		if len(rows) == maxRows {
+			if maxRows == csvImportMaxRows {
+				return nil, ErrImportTooManyRows
+			}
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, readCSVRow(userID, line, cells, columns, mapping, layout, loc))
+	}
+	return rows, nil
+}
+
+// readCSVRow converts one row's cells into a record
+func readCSVRow(userID string, line int, cells []string, columns map[string]int, mapping CSVMapping, layout string, loc *time.Location) CSVImportRow {
+	record := &models.DailyRecord{
+		UserID:            userID,
+		Source:            models.RecordSourceCSVImport,
+		TemperatureSource: models.TemperatureSourceManual,
+	}
+	for _, field := range importFields {
+		i, ok := columns[field.name]
+		if !ok {
+			continue
+		}
+		value := ""
+		if i < len(cells) {
+			value = strings.TrimSpace(cells[i])
+		}
+		if value == "" {
+			if field.required {
+				return CSVImportRow{Line: line, Field: field.name, Problem: importProblemMissing}
+			}
+			continue
+		}
+
+		switch field.kind {
+		case importText:
+			record.DeviceID = value
+		case importDate:
+			date, err := time.ParseInLocation(layout, value, loc)
+			if err != nil {
+				return CSVImportRow{Line: line, Field: field.name, Problem: importProblemDate}
+			}
+			record.Date = date
+		default:
+			number, ok := parseImportNumber(value)
+			if !ok {
+				return CSVImportRow{Line: line, Field: field.name, Problem: importProblemNumber}
+			}
+			number = roundTo(convertImportValue(number, mapping.Columns[field.name].Unit), 2)
+			switch field.name {
+			case ImportFieldShowerDuration:
+				record.ShowerDuration = number
+			case ImportFieldAverageTemperature:
+				record.AverageTemperature = number
+			case ImportFieldHeatingTime:
+				record.HeatingTime = number
+			case ImportFieldSatisfaction:
+				record.Satisfaction = number
+			case ImportFieldInletTemperature:
+				record.InletTemperature = &number
+			}
+		}
+	}
+	record.ID = uuid.NewSHA1(importRecordNamespace, []byte(userID+"\n"+strings.Join(cells, "\x1f"))).String()
+	return CSVImportRow{Line: line, Record: record}
+}
+
+// Propose guesses a mapping for a file from a sample of it: the header and some rows. Fields
+// are matched to columns by their headers, units are read from the headers or guessed from the
+// values, and the date format is the first that reads every sampled date.
+func (s *CSVImportService) Propose(userID, sample string) (*CSVImportProposal, error) {
+	separator := sniffSeparator(sample)
+	reader := newCSVReader(sample, separator)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrImportNoHeader
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for len(rows) < csvProposalSampleRows {
+		cells, err := reader.Read()
+		if err != nil {
+			// A sample may well end mid-row
+			break
+		}
+		rows = append(rows, cells)
+	}
+	column := func(i int) []string {
+		var values []string
+		for _, cells := range rows {
+			if i < len(cells) && strings.TrimSpace(cells[i]) != "" {
+				values = append(values, strings.TrimSpace(cells[i]))
+			}
+		}
+		return values
+	}
+
+	proposal := &CSVImportProposal{
+		Mapping:  CSVMapping{Columns: map[string]CSVColumn{}},
+		Headers:  header,
+		Missing:  []string{},
+		Unmapped: []string{},
+		Notes:    []CSVMappingNote{},
+		Preview:  []CSVImportRow{},
+	}
+	if separator != "," {
+		proposal.Mapping.Separator = separator
+	}
+	matched := matchImportColumns(header)
+	for _, field := range importFields {
+		i, ok := matched[field.name]
+		if !ok {
+			if field.required {
+				proposal.Missing = append(proposal.Missing, field.name)
+			}
+			continue
+		}
+		mapped := CSVColumn{Column: strings.TrimSpace(header[i])}
+		unit, reason := proposeUnit(field, header[i], column(i))
+		if unit != "" {
+			mapped.Unit = unit
+			proposal.Notes = append(proposal.Notes, CSVMappingNote{Field: field.name, Reason: reason})
+		}
+		proposal.Mapping.Columns[field.name] = mapped
+	}
+	for i, name := range header {
+		if !slices.Contains(mapValues(matched), i) {
+			proposal.Unmapped = append(proposal.Unmapped, name)
+		}
+	}
+
+	if i, ok := matched[ImportFieldDate]; ok {
+		// Fahrenheit suggests a US spreadsheet, where months come first
+		monthFirst := proposal.Mapping.Columns[ImportFieldAverageTemperature].Unit == UnitFahrenheit
+		format, ambiguous := proposeDateFormat(column(i), monthFirst)
+		proposal.Mapping.DateFormat = format
+		if ambiguous {
+			proposal.Notes = append(proposal.Notes, CSVMappingNote{Field: ImportFieldDate, Reason: ImportNoteAmbiguousDateOrder})
+		}
+	}
+
+	if len(proposal.Missing) == 0 && proposal.Mapping.Validate() == nil {
+		preview, err := parseCSVRows(userID, newCSVReader(sample, separator), proposal.Mapping, csvProposalPreview)
+		if err == nil {
+			proposal.Preview = preview
+		}
+	}
+	return proposal, nil
+}
+
+func mapValues(m map[string]int) []int {
+	values := make([]int, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// sniffSeparator picks the separator used most in the first line
+func sniffSeparator(sample string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(sample, "\ufeff"), "\n")
+	best, count := ",", 0
+	for _, separator := range importSeparators {
+		if n := strings.Count(first, separator); n > count {
+			best, count = separator, n
+		}
+	}
+	return best
+}
+
+var unitInHeader = regexp.MustCompile(`[(\[][^)\]]*[)\]]`)
+
+// normalizeHeader lowercases a header and drops units in brackets and everything but letters
+// and digits, so "Outside Temp (°F)" matches "outsidetemp"
+func normalizeHeader(header string) string {
+	header = unitInHeader.ReplaceAllString(strings.ToLower(header), "")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, header)
+}
+
+// matchImportColumns maps fields to the index of the header that suggests them best: an exact
+// synonym first, else the header containing the longest synonym. Each column goes to one field.
+func matchImportColumns(header []string) map[string]int {
+	type candidate struct {
+		field, column, score int
+	}
+	var candidates []candidate
+	for c, name := range header {
+		normalized := normalizeHeader(name)
+		if normalized == "" {
+			continue
+		}
+		for f, field := range importFields {
+			score := 0
+			for _, synonym := range field.synonyms {
+				switch {
+				case normalized == synonym:
+					score = max(score, 1000)
+				case strings.Contains(normalized, synonym):
+					score = max(score, len(synonym))
+				}
+			}
+			if score > 0 {
+				candidates = append(candidates, candidate{f, c, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	matched := make(map[string]int)
+	used := make(map[int]bool)
+	for _, candidate := range candidates {
+		name := importFields[candidate.field].name
+		if _, ok := matched[name]; ok || used[candidate.column] {
+			continue
+		}
+		matched[name] = candidate.column
+		used[candidate.column] = true
+	}
+	return matched
+}
+
+// proposeUnit reads a temperature or duration unit from the header, else guesses it from the
+// values: outdoor temperatures above 50 are Fahrenheit, and showers or heating lasting hours
+// are in seconds. Celsius and minutes, the defaults, are left empty.
+func proposeUnit(field importField, header string, values []string) (string, string) {
+	h := strings.ToLower(header)
+	hinted := func(hints ...string) bool {
+		for _, hint := range hints {
+			if strings.Contains(h, hint) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var numbers []float64
+	for _, value := range values {
+		if number, ok := parseImportNumber(value); ok {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Float64s(numbers)
+	median := 0.0
+	if len(numbers) > 0 {
+		median = numbers[len(numbers)/2]
+	}
+
+	switch field.kind {
+	case importTemperature:
+		switch {
+		case hinted("°f", "(f)", "[f]", "fahrenheit"):
+			return UnitFahrenheit, ImportNoteUnitFromHeader
+		case hinted("(k)", "[k]", "kelvin"):
+			return UnitKelvin, ImportNoteUnitFromHeader
+		case hinted("°c", "(c)", "[c]", "celsius"):
+			return "", ""
+		case median > 200:
+			return UnitKelvin, ImportNoteUnitFromValues
+		case len(numbers) > 0 && numbers[len(numbers)-1] > 50:
+			return UnitFahrenheit, ImportNoteUnitFromValues
+		}
+	case importDuration:
+		switch {
+		case hinted("(s)", "[s]", "sec"):
+			return UnitSeconds, ImportNoteUnitFromHeader
+		case hinted("(h)", "[h]", "hour", "hrs"):
+			return UnitHours, ImportNoteUnitFromHeader
+		case hinted("(min)", "[min]", "minute", "mins"):
+			return "", ""
+		case median > 240:
+			return UnitSeconds, ImportNoteUnitFromValues
+		}
+	}
+	return "", ""
+}
+
+// proposeDateFormat returns the first format that reads every value, preferring month-first
+// layouts when asked. It reports whether the values read both day-first and month-first.
+func proposeDateFormat(values []string, monthFirst bool) (string, bool) {
+	parses := func(layout string) bool {
+		for _, value := range values {
+			if _, err := time.Parse(layout, value); err != nil {
+				return false
+			}
+		}
+		return len(values) > 0
+	}
+	var fits []string
+	for _, format := range importDateFormats {
+		if parses(format.layout) {
+			fits = append(fits, format.name)
+		}
+	}
+	if len(fits) == 0 {
+		return "", false
+	}
+
+	dayFirst := slices.ContainsFunc(fits, func(name string) bool { return strings.HasPrefix(name, "DD") })
+	monthFirstFits := slices.ContainsFunc(fits, func(name string) bool { return strings.HasPrefix(name, "MM") })
+	if monthFirst {
+		for _, name := range fits {
+			if strings.HasPrefix(name, "MM") {
+				return name, dayFirst
+			}
+		}
+	}
+	return fits[0], dayFirst && monthFirstFits
+}
+
+// SaveMapping keeps the mapping under the name, replacing the user's mapping of that name
+func (s *CSVImportService) SaveMapping(userID, name string, mapping CSVMapping) (*models.ImportMapping, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	definition, err := json.Marshal(mapping)
+	if err != nil {
+		return nil, err
+	}
+	saved := &models.ImportMapping{UserID: userID, Name: name, Mapping: definition}
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mapping", "updated_at"}),
+	}).Create(saved).Error
+	if err != nil {
+		return nil, err
+	}
+	// On a conflict the stored row keeps its ID
+	if err := s.db.Where("user_id = ? AND name = ?", userID, name).First(saved).Error; err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// ListMappings returns the user's saved mappings by name
+func (s *CSVImportService) ListMappings(userID string) ([]models.ImportMapping, error) {
+	mappings := []models.ImportMapping{}
+	err := s.db.Where("user_id = ?", userID).Order("name ASC").Find(&mappings).Error
+	return mappings, err
+}
+
+// Mapping returns one of the user's saved mappings
+func (s *CSVImportService) Mapping(userID, id string) (CSVMapping, error) {
+	var saved models.ImportMapping
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&saved).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return CSVMapping{}, ErrImportMappingNotFound
+	}
+	if err != nil {
+		return CSVMapping{}, err
+	}
+	var mapping CSVMapping
+	err = json.Unmarshal(saved.Mapping, &mapping)
+	return mapping, err
+}
+
+// DeleteMapping removes one of the user's saved mappings
+func (s *CSVImportService) DeleteMapping(userID, id string) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ImportMapping{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrImportMappingNotFound
+	}
+	return nil
+}
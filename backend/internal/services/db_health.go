@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrWriteBuffered is returned when a record was queued in memory because the database is read-only
+	ErrWriteBuffered = errors.New("database is read-only, write buffered")
+	// ErrDatabaseReadOnly is returned when the database is read-only and the write buffer is full
+	ErrDatabaseReadOnly = errors.New("database is read-only")
+)
+
+// DBHealthStatus describes the current state of the database health monitor
+type DBHealthStatus struct {
+	ReadOnly            bool       `json:"readOnly"`
+	ReadOnlySince       *time.Time `json:"readOnlySince,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	BufferedWrites      int        `json:"bufferedWrites"`
+	BufferCapacity      int        `json:"bufferCapacity"`
+}
+
+// DBHealthMonitor watches for persistent storage failures (disk full, I/O errors, a
+// read-only file). After enough consecutive failures it switches the app to read-only
+// mode and buffers feedback in memory, then probes the database until writes succeed
+// again and flushes the buffer.
+type DBHealthMonitor struct {
+	db    *gorm.DB
+	cfg   config.DatabaseHealthConfig
+	probe func() error
+
+	onFlushed []func(record models.DailyRecord)
+
+	mu            sync.Mutex
+	failures      int
+	readOnly      bool
+	readOnlySince time.Time
+	buffer        []models.DailyRecord
+}
+
+// NewDBHealthMonitor creates a new database health monitor
+func NewDBHealthMonitor(cfg config.DatabaseHealthConfig) *DBHealthMonitor {
+	m := &DBHealthMonitor{
+		db:  database.GetDB(),
+		cfg: cfg,
+	}
+	m.probe = m.writeProbe
+	return m
+}
+
+// Start launches the recovery prober
+func (m *DBHealthMonitor) Start() {
+	interval := m.cfg.ProbeInterval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			m.tryRecover()
+		}
+	}()
+}
+
+// ReadOnly reports whether writes are currently being refused
+func (m *DBHealthMonitor) ReadOnly() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.readOnly
+}
+
+// Status returns a snapshot of the monitor state
+func (m *DBHealthMonitor) Status() DBHealthStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := DBHealthStatus{
+		ReadOnly:            m.readOnly,
+		ConsecutiveFailures: m.failures,
+		BufferedWrites:      len(m.buffer),
+		BufferCapacity:      m.cfg.BufferSize,
+	}
+	if m.readOnly {
+		since := m.readOnlySince
+		status.ReadOnlySince = &since
+	}
+	return status
+}
+
+// Observe records the outcome of a write and reports whether the app is now read-only
+// because of a storage failure
+func (m *DBHealthMonitor) Observe(err error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		m.failures = 0
+		return false
+	}
+	if !isStorageFailure(err) {
+		return false
+	}
+
+	m.failures++
+	if !m.readOnly && m.failures >= m.cfg.FailureThreshold {
+		m.readOnly = true
+		m.readOnlySince = time.Now()
+		log.Printf("Database writes failing persistently (%v); switching to read-only mode", err)
+	}
+	return m.readOnly
+}
+
+// Buffer queues a record to be written once the database recovers
+func (m *DBHealthMonitor) Buffer(record models.DailyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if record.ID != "" {
+		// A client retrying a record it named itself is already queued
+		for _, queued := range m.buffer {
+			if queued.ID == record.ID {
+				return ErrWriteBuffered
+			}
+		}
+	}
+	if len(m.buffer) >= m.cfg.BufferSize {
+		return ErrDatabaseReadOnly
+	}
+	m.buffer = append(m.buffer, record)
+	return ErrWriteBuffered
+}
+
+// OnRecordFlushed registers a callback run for each buffered record once it is written
+func (m *DBHealthMonitor) OnRecordFlushed(fn func(record models.DailyRecord)) {
+	m.onFlushed = append(m.onFlushed, fn)
+}
+
+// tryRecover probes a read-only database and, once it accepts writes, flushes the buffer in order
+func (m *DBHealthMonitor) tryRecover() {
+	for _, record := range m.flush() {
+		for _, fn := range m.onFlushed {
+			fn(record)
+		}
+	}
+}
+
+// flush writes the buffer once the database accepts writes again, returning the records stored.
+// The writes happen outside the lock so Observe and Buffer aren't held up; records buffered
+// meanwhile queue behind them and are flushed in the same pass.
+func (m *DBHealthMonitor) flush() (flushed []models.DailyRecord) {
+	if !m.ReadOnly() {
+		return nil
+	}
+	if err := m.probe(); err != nil {
+		return nil
+	}
+
+	for {
+		m.mu.Lock()
+		if len(m.buffer) == 0 {
+			m.readOnly = false
+			m.failures = 0
+			m.buffer = nil
+			log.Printf("Database writable again after %s; leaving read-only mode", time.Since(m.readOnlySince).Round(time.Second))
+			m.mu.Unlock()
+			return flushed
+		}
+		// Queued records stay in the buffer until written, so retries of them are still recognised
+		pending := append([]models.DailyRecord(nil), m.buffer...)
+		m.mu.Unlock()
+
+		written := 0
+		for i := range pending {
+			if err := m.db.Create(&pending[i]).Error; err != nil {
+				if isStorageFailure(err) {
+					log.Printf("Database still failing while flushing buffered writes: %v", err)
+					m.dequeue(written)
+					return flushed
+				}
+				// e.g. a client-named record that was stored before the outage
+				log.Printf("Dropping buffered record %s: %v", pending[i].ID, err)
+			} else {
+				flushed = append(flushed, pending[i])
+			}
+			written++
+		}
+		m.dequeue(written)
+	}
+}
+
+// dequeue removes the first n buffered records, which have been dealt with
+func (m *DBHealthMonitor) dequeue(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.buffer = m.buffer[n:]
+}
+
+func (m *DBHealthMonitor) writeProbe() error {
+	return m.db.Save(&models.HealthProbe{ID: 1, CheckedAt: time.Now()}).Error
+}
+
+// isStorageFailure reports whether err means the database file itself can't be written,
+// as opposed to a problem with the data being written. A busy or locked database is only
+// contention between writers and clears up by itself, so it doesn't count.
+func isStorageFailure(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrFull, sqlite3.ErrIoErr, sqlite3.ErrReadonly, sqlite3.ErrCantOpen, sqlite3.ErrCorrupt, sqlite3.ErrNotADB:
+		return true
+	}
+	return false
+}
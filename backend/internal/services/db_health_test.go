@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestDBHealthMonitor_SwitchesToReadOnlyAfterPersistentFailures(t *testing.T) {
+	monitor := NewDBHealthMonitor(config.DatabaseHealthConfig{FailureThreshold: 3, BufferSize: 1})
+	diskFull := sqlite3.Error{Code: sqlite3.ErrFull}
+
+	// Constraint violations are the caller's problem, not a storage failure
+	assert.False(t, monitor.Observe(sqlite3.Error{Code: sqlite3.ErrConstraint}))
+	assert.False(t, monitor.Observe(errors.New("boom")))
+	// Nor is contention between writers, however long it lasts
+	for i := 0; i < 5; i++ {
+		assert.False(t, monitor.Observe(sqlite3.Error{Code: sqlite3.ErrBusy}))
+		assert.False(t, monitor.Observe(sqlite3.Error{Code: sqlite3.ErrLocked}))
+	}
+
+	assert.False(t, monitor.Observe(diskFull))
+	assert.False(t, monitor.Observe(diskFull))
+	assert.True(t, monitor.Observe(diskFull))
+	assert.True(t, monitor.ReadOnly())
+
+	assert.ErrorIs(t, monitor.Buffer(models.DailyRecord{UserID: "user1"}), ErrWriteBuffered)
+	assert.ErrorIs(t, monitor.Buffer(models.DailyRecord{UserID: "user2"}), ErrDatabaseReadOnly)
+	assert.Equal(t, 1, monitor.Status().BufferedWrites)
+}
+
+func TestDBHealthMonitor_StaysReadOnlyWhileProbeFails(t *testing.T) {
+	monitor := NewDBHealthMonitor(config.DatabaseHealthConfig{FailureThreshold: 1, BufferSize: 10})
+	monitor.probe = func() error { return sqlite3.Error{Code: sqlite3.ErrIoErr} }
+
+	monitor.Observe(sqlite3.Error{Code: sqlite3.ErrIoErr})
+	monitor.tryRecover()
+	assert.True(t, monitor.ReadOnly())
+
+	// A successful write elsewhere doesn't lift read-only mode; only the prober does
+	monitor.Observe(nil)
+	assert.True(t, monitor.ReadOnly())
+}
+
+func TestDBHealthMonitor_FlushedRecordsReachPredictions(t *testing.T) {
+	records := newCandidateIndexDB(t)
+	monitor := NewDBHealthMonitor(config.DatabaseHealthConfig{FailureThreshold: 1, BufferSize: 10})
+	records.SetHealthMonitor(monitor)
+	index := NewCandidateIndex(records, 1)
+	records.OnRecordTrained(index.Observe)
+	for i := 0; i < 6; i++ {
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: "user1", Date: time.Now().AddDate(0, 0, -i-1),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50,
+		}))
+	}
+	predictor := NewPredictionServiceV2(records, &PredictionConfigV2{DisableExploration: true})
+	predictor.SetCandidateIndex(index)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+	before, err := predictor.Predict(context.Background(), req)
+	require.NoError(t, err)
+
+	// Buffered while read-only, written once the database recovers
+	monitor.Observe(sqlite3.Error{Code: sqlite3.ErrIoErr})
+	flushed := &models.DailyRecord{UserID: "user1", Date: time.Now(), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 40, Satisfaction: 50}
+	require.ErrorIs(t, records.CreateRecord(flushed), ErrWriteBuffered)
+	monitor.tryRecover()
+	require.False(t, monitor.ReadOnly())
+
+	after, err := predictor.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, before.Explanation.Neighbors+1, after.Explanation.Neighbors, "the flushed shower is weighed")
+	fresh := NewPredictionServiceV2(records, &PredictionConfigV2{DisableExploration: true})
+	fresh.SetCandidateIndex(NewCandidateIndex(records, 1))
+	want, err := fresh.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.InDelta(t, want.RawHeatingTime, after.RawHeatingTime, 1e-9)
+}
+
+func TestDBHealthMonitor_BuffersWhileFlushing(t *testing.T) {
+	newCandidateIndexDB(t)
+	monitor := NewDBHealthMonitor(config.DatabaseHealthConfig{FailureThreshold: 1, BufferSize: 10})
+	monitor.Observe(sqlite3.Error{Code: sqlite3.ErrIoErr})
+	require.ErrorIs(t, monitor.Buffer(models.DailyRecord{UserID: "user1", Date: time.Now(), ShowerDuration: 10, HeatingTime: 20, Satisfaction: 50}), ErrWriteBuffered)
+
+	// Feedback arriving while the buffer is written isn't held up, and is written in the same pass
+	arrived := false
+	require.NoError(t, monitor.db.Callback().Create().Before("gorm:create").Register("test:buffer_meanwhile", func(*gorm.DB) {
+		if arrived {
+			return
+		}
+		arrived = true
+		done := make(chan error)
+		go func() {
+			done <- monitor.Buffer(models.DailyRecord{UserID: "user2", Date: time.Now(), ShowerDuration: 10, HeatingTime: 20, Satisfaction: 50})
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, ErrWriteBuffered)
+		case <-time.After(time.Second):
+			t.Error("buffering waited for the flush")
+		}
+	}))
+	flushed := monitor.flush()
+	assert.Len(t, flushed, 2)
+	assert.False(t, monitor.ReadOnly())
+	assert.Zero(t, monitor.Status().BufferedWrites)
+}
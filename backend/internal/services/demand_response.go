@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// demandResponseResumeInterval is how often ended events are looked for, to tell users heating
+// is back to normal
+const demandResponseResumeInterval = time.Minute
+
+var (
+	// ErrInvalidDemandResponse is returned for a signal with an unknown action, a cap of no
+	// minutes or an end that isn't after its start
+	ErrInvalidDemandResponse = errors.New("invalid demand-response signal")
+	// ErrDemandResponseTooLong is returned for an event longer than DEMAND_RESPONSE_MAX_DURATION
+	ErrDemandResponseTooLong = errors.New("demand-response event is too long")
+	// ErrDemandResponseNotFound is returned when an event doesn't exist
+	ErrDemandResponseNotFound = errors.New("demand-response event not found")
+	// ErrDemandResponseEnded is returned when ending an event that already ended
+	ErrDemandResponseEnded = errors.New("demand-response event has already ended")
+	// ErrHeatingCurtailed is returned by the scheduler when demand-response events leave no time
+	// to heat before the water is due
+	ErrHeatingCurtailed = errors.New("demand-response events leave no time to heat")
+)
+
+// DemandResponseSignal is a utility's request to cap or shift heating for a while
+type DemandResponseSignal struct {
+	ExternalID string    // the utility's ID for the event; a signal repeating it updates the event
+	Action     string    // models.DemandResponseCap or models.DemandResponseShift
+	MaxMinutes float64   // longest heating run while a cap lasts
+	Reason     string    // shown to users, e.g. "evening peak"
+	Start      time.Time // zero for now
+	End        time.Time
+}
+
+// DemandResponseNotice tells a client that a demand-response event changed the prediction
+type DemandResponseNotice struct {
+	EventID          string    `json:"eventId"`
+	Action           string    `json:"action"`
+	Reason           string    `json:"reason,omitempty"`
+	Until            time.Time `json:"until"`
+	MaxMinutes       float64   `json:"maxMinutes,omitempty"`
+	ModelHeatingTime float64   `json:"modelHeatingTime,omitempty"` // what the predictor answered, when the cap lowered it
+}
+
+// DemandResponseLookup reports whether a shower the user took at a time falls in an event, such
+// as a demand-response event, that heating wasn't normal during
+type DemandResponseLookup interface {
+	InterruptedAt(userID string, at time.Time) (bool, error)
+}
+
+// DemandResponseService takes demand-response signals from the utility, usually through an MQTT
+// or webhook bridge. While an event lasts predictions are capped and schedules avoid it; every
+// user is told when it starts and when heating is back to normal, and an annotation keeps the
+// showers it affected, up to the recovery time after it, out of training.
+type DemandResponseService struct {
+	db            *gorm.DB
+	cfg           config.DemandResponseConfig
+	notifications *NotificationService
+	now           func() time.Time
+
+	mu     sync.RWMutex
+	events []models.DemandResponseEvent // events that haven't ended, by start
+}
+
+// NewDemandResponseService creates a new demand-response service instance; Load reads the
+// events that haven't ended
+func NewDemandResponseService(cfg config.DemandResponseConfig, notifications *NotificationService) *DemandResponseService {
+	return &DemandResponseService{
+		db:            database.GetDB(),
+		cfg:           cfg,
+		notifications: notifications,
+		now:           time.Now,
+	}
+}
+
+// SetClock sets the clock events are started and ended by
+func (s *DemandResponseService) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// Load reads the events that haven't ended
+func (s *DemandResponseService) Load() error {
+	var events []models.DemandResponseEvent
+	if err := s.db.Where("ended_at IS NULL").Order("starts_at ASC").Find(&events).Error; err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.events = events
+	s.mu.Unlock()
+	return nil
+}
+
+// Start tells users in the background when events end
+func (s *DemandResponseService) Start() {
+	go func() {
+		ticker := time.NewTicker(demandResponseResumeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := s.ResumeDue(); err != nil {
+				log.Printf("Failed to resume heating after demand-response events: %v", err)
+			}
+		}
+	}()
+}
+
+// Signal starts an event, or updates the one the signal's external ID names. Users are told
+// about new events in the background.
+func (s *DemandResponseService) Signal(signal DemandResponseSignal) (*models.DemandResponseEvent, bool, error) {
+	now := s.now()
+	if signal.Start.IsZero() {
+		signal.Start = now
+	}
+	switch {
+	case signal.Action != models.DemandResponseCap && signal.Action != models.DemandResponseShift:
+		return nil, false, fmt.Errorf("%w: action must be %s or %s", ErrInvalidDemandResponse, models.DemandResponseCap, models.DemandResponseShift)
+	case signal.Action == models.DemandResponseCap && signal.MaxMinutes <= 0:
+		return nil, false, fmt.Errorf("%w: a cap needs maxMinutes", ErrInvalidDemandResponse)
+	case !signal.End.After(signal.Start) || !signal.End.After(now):
+		return nil, false, fmt.Errorf("%w: the end must be after the start and in the future", ErrInvalidDemandResponse)
+	case signal.End.Sub(signal.Start) > s.cfg.MaxDuration:
+		return nil, false, fmt.Errorf("%w: at most %s", ErrDemandResponseTooLong, s.cfg.MaxDuration)
+	}
+
+	event := models.DemandResponseEvent{}
+	created := true
+	if signal.ExternalID != "" {
+		result := s.db.Where("external_id = ? AND ended_at IS NULL", signal.ExternalID).Limit(1).Find(&event)
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		created = result.RowsAffected == 0
+	}
+	event.ExternalID = signal.ExternalID
+	event.Action = signal.Action
+	event.MaxMinutes = 0
+	if signal.Action == models.DemandResponseCap {
+		event.MaxMinutes = signal.MaxMinutes
+	}
+	event.Reason = signal.Reason
+	event.StartsAt = signal.Start
+	event.EndsAt = signal.End
+
+	users, err := s.users()
+	if err != nil {
+		return nil, false, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&event).Error; err != nil {
+			return err
+		}
+		if !created {
+			return rescheduleEventAnnotations(tx, event.ID, event.StartsAt, event.EndsAt.Add(s.cfg.Recovery))
+		}
+		return annotateEvent(tx, users, event, s.describe(event), event.EndsAt.Add(s.cfg.Recovery))
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.Load(); err != nil {
+		return nil, false, err
+	}
+	if created {
+		go s.notify(users, s.describe(event), event)
+	}
+	return &event, created, nil
+}
+
+// End ends an event now, ahead of its scheduled end, and tells users heating is back to normal
+func (s *DemandResponseService) End(id string) (*models.DemandResponseEvent, error) {
+	var event models.DemandResponseEvent
+	result := s.db.Where("id = ?", id).Limit(1).Find(&event)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDemandResponseNotFound
+	}
+	if event.EndedAt != nil {
+		return nil, ErrDemandResponseEnded
+	}
+	now := s.now()
+	if now.Before(event.EndsAt) {
+		event.EndsAt = now
+		if now.Before(event.StartsAt) {
+			event.StartsAt = now
+		}
+	}
+	if err := s.resume(&event, now); err != nil {
+		return nil, err
+	}
+	return &event, s.Load()
+}
+
+// ResumeDue tells users heating is back to normal after every event that ended by now,
+// returning how many ended
+func (s *DemandResponseService) ResumeDue() (int, error) {
+	now := s.now()
+	var due []models.DemandResponseEvent
+	if err := s.db.Where("ended_at IS NULL AND ends_at <= ?", now).Find(&due).Error; err != nil {
+		return 0, err
+	}
+	for i := range due {
+		if err := s.resume(&due[i], now); err != nil {
+			return i, err
+		}
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+	return len(due), s.Load()
+}
+
+// resume marks an event ended at now, moves its annotations to its real end and tells users
+func (s *DemandResponseService) resume(event *models.DemandResponseEvent, now time.Time) error {
+	event.EndedAt = &now
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(event).Error; err != nil {
+			return err
+		}
+		return rescheduleEventAnnotations(tx, event.ID, event.StartsAt, event.EndsAt.Add(s.cfg.Recovery))
+	})
+	if err != nil {
+		return err
+	}
+	var users []string
+	if err := s.db.Model(&models.Annotation{}).Where("event_id = ?", event.ID).Distinct().Pluck("user_id", &users).Error; err != nil {
+		return err
+	}
+	go s.notify(users, "Heating is back to normal after the demand-response event", *event)
+	return nil
+}
+
+// Upcoming returns the events that haven't ended, by start
+func (s *DemandResponseService) Upcoming() []models.DemandResponseEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.DemandResponseEvent{}, s.events...)
+}
+
+// Between returns the events overlapping from to to that haven't ended, by start
+func (s *DemandResponseService) Between(from, to time.Time) []models.DemandResponseEvent {
+	var events []models.DemandResponseEvent
+	for _, event := range s.Upcoming() {
+		if event.StartsAt.Before(to) && event.EndsAt.After(from) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Notice returns how the events in effect at at change a prediction of heatingTime minutes:
+// the lowest cap, or else the shift that ends last. It returns nil when none is in effect.
+func (s *DemandResponseService) Notice(at time.Time, heatingTime float64) *DemandResponseNotice {
+	var notice *DemandResponseNotice
+	for _, event := range s.Between(at, at.Add(time.Nanosecond)) {
+		switch {
+		case event.Action == models.DemandResponseCap && heatingTime > event.MaxMinutes &&
+			(notice == nil || notice.MaxMinutes == 0 || event.MaxMinutes < notice.MaxMinutes):
+			notice = &DemandResponseNotice{EventID: event.ID, Action: event.Action, Reason: event.Reason, Until: event.EndsAt, MaxMinutes: event.MaxMinutes, ModelHeatingTime: heatingTime}
+		case event.Action == models.DemandResponseShift && (notice == nil || (notice.MaxMinutes == 0 && event.EndsAt.After(notice.Until))):
+			notice = &DemandResponseNotice{EventID: event.ID, Action: event.Action, Reason: event.Reason, Until: event.EndsAt}
+		}
+	}
+	return notice
+}
+
+// blocking returns the events a heating run of minutes can't overlap: every shift, and caps
+// lower than the run
+func (s *DemandResponseService) blocking(from, to time.Time, minutes float64) []models.DemandResponseEvent {
+	var blocking []models.DemandResponseEvent
+	for _, event := range s.Between(from, to) {
+		if event.Action == models.DemandResponseShift || minutes > event.MaxMinutes {
+			blocking = append(blocking, event)
+		}
+	}
+	return blocking
+}
+
+// users lists everyone an event affects: every user that isn't disabled, and owners of records
+// without a user row
+func (s *DemandResponseService) users() ([]string, error) {
+	var active, recorded, disabled []string
+	if err := s.db.Model(&models.User{}).Where("disabled = ?", false).Pluck("id", &active).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.User{}).Where("disabled = ?", true).Pluck("id", &disabled).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.DailyRecord{}).Where("user_id <> ?", "global").Distinct().Pluck("user_id", &recorded).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, userID := range disabled {
+		seen[userID] = true
+	}
+	var users []string
+	for _, userID := range append(active, recorded...) {
+		if !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// annotateEvent adds a demand_response annotation to each user's history, covering the event
+// and its recovery up to until
+func annotateEvent(tx *gorm.DB, userIDs []string, event models.DemandResponseEvent, note string, until time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	annotations := make([]models.Annotation, len(userIDs))
+	for i, userID := range userIDs {
+		annotations[i] = models.Annotation{
+			UserID:  userID,
+			Date:    event.StartsAt,
+			Until:   &until,
+			Kind:    models.AnnotationDemandResponse,
+			Note:    note,
+			EventID: event.ID,
+		}
+	}
+	return tx.Create(&annotations).Error
+}
+
+// rescheduleEventAnnotations moves the annotations of an event to cover from to until
+func rescheduleEventAnnotations(tx *gorm.DB, eventID string, from, until time.Time) error {
+	return tx.Model(&models.Annotation{}).Where("event_id = ?", eventID).
+		Updates(map[string]interface{}{"date": from, "until": until}).Error
+}
+
+// describe is the notice users get when an event starts
+func (s *DemandResponseService) describe(event models.DemandResponseEvent) string {
+	window := fmt.Sprintf("%s to %s", event.StartsAt.Local().Format("15:04"), event.EndsAt.Local().Format("15:04"))
+	message := fmt.Sprintf("Your utility asked to save power from %s: heating waits until it ends", window)
+	if event.Action == models.DemandResponseCap {
+		message = fmt.Sprintf("Your utility asked to save power from %s: heating is held to %g minutes", window, math.Round(event.MaxMinutes*10)/10)
+	}
+	if event.Reason != "" {
+		message += " (" + event.Reason + ")"
+	}
+	return message
+}
+
+// notify sends users a demand_response notification about event
+func (s *DemandResponseService) notify(users []string, message string, event models.DemandResponseEvent) {
+	if s.notifications == nil || !s.notifications.Enabled() {
+		return
+	}
+	data := map[string]interface{}{
+		"eventId":  event.ID,
+		"action":   event.Action,
+		"startsAt": event.StartsAt,
+		"endsAt":   event.EndsAt,
+		"ended":    event.EndedAt != nil,
+	}
+	for _, userID := range users {
+		if _, err := s.notifications.Notify(userID, models.NotificationDemandResponse, message, data, s.now()); err != nil {
+			log.Printf("Failed to notify %s of demand-response event %s: %v", userID, event.ID, err)
+		}
+	}
+}
+
+// DemandResponsePredictor caps predictions while a demand-response event asks for it, and tells
+// clients when an event holds heating back
+type DemandResponsePredictor struct {
+	inner  Predictor
+	events *DemandResponseService
+}
+
+// NewDemandResponsePredictor wraps inner with the demand-response events of events
+func NewDemandResponsePredictor(inner Predictor, events *DemandResponseService) *DemandResponsePredictor {
+	return &DemandResponsePredictor{inner: inner, events: events}
+}
+
+// Predict runs the wrapped predictor and applies the event in effect now, if any
+func (p *DemandResponsePredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	resp, err := p.inner.Predict(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	notice := p.events.Notice(p.events.now(), resp.HeatingTime)
+	if notice == nil {
+		return resp, nil
+	}
+
+	// Copy: cached predictions are shared
+	curtailed := *resp
+	curtailed.DemandResponse = notice
+	if notice.MaxMinutes > 0 {
+		curtailed.HeatingTime = notice.MaxMinutes
+		curtailed.RawHeatingTime = math.Min(resp.RawHeatingTime, notice.MaxMinutes)
+	}
+	return &curtailed, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestDemandResponseService(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "demand.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	records.SetDemandResponseLookup(NewAnnotationService())
+	// Records are only accepted from the past, so the events happened two days ago
+	base := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	clock := NewFrozenClock(base)
+	service := NewDemandResponseService(config.DemandResponseConfig{Recovery: time.Hour, MaxDuration: 6 * time.Hour}, nil)
+	service.SetClock(clock)
+	require.NoError(t, service.Load())
+
+	shower := func(at time.Time) *models.DailyRecord {
+		record := &models.DailyRecord{
+			UserID: "user1", Date: at,
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 90,
+		}
+		require.NoError(t, records.CreateRecord(record))
+		return record
+	}
+	assert.False(t, shower(base.Add(-time.Hour)).ExcludedFromTraining)
+
+	_, _, err := service.Signal(DemandResponseSignal{Action: models.DemandResponseCap, End: base.Add(time.Hour)})
+	assert.ErrorIs(t, err, ErrInvalidDemandResponse, "a cap needs its minutes")
+	_, _, err = service.Signal(DemandResponseSignal{Action: models.DemandResponseShift, End: base.Add(7 * time.Hour)})
+	assert.ErrorIs(t, err, ErrDemandResponseTooLong)
+
+	event, created, err := service.Signal(DemandResponseSignal{
+		ExternalID: "peak-1", Action: models.DemandResponseCap, MaxMinutes: 10, Reason: "evening peak",
+		Start: base.Add(time.Hour), End: base.Add(3 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Len(t, service.Upcoming(), 1)
+
+	// Predictions are only capped while the event lasts, and only when they run longer
+	predictor := NewDemandResponsePredictor(fixedPredictor(25), service)
+	resp, err := predictor.Predict(context.Background(), PredictionRequest{UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, resp.HeatingTime)
+	assert.Nil(t, resp.DemandResponse)
+	clock.Set(base.Add(90 * time.Minute))
+	resp, err = predictor.Predict(context.Background(), PredictionRequest{UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, resp.HeatingTime)
+	require.NotNil(t, resp.DemandResponse)
+	assert.Equal(t, event.ID, resp.DemandResponse.EventID)
+	assert.Equal(t, 25.0, resp.DemandResponse.ModelHeatingTime)
+	resp, err = NewDemandResponsePredictor(fixedPredictor(8), service).Predict(context.Background(), PredictionRequest{UserID: "user1"})
+	require.NoError(t, err)
+	assert.Nil(t, resp.DemandResponse)
+
+	// Repeating the utility's ID extends the event and its annotation
+	extended, created, err := service.Signal(DemandResponseSignal{
+		ExternalID: "peak-1", Action: models.DemandResponseCap, MaxMinutes: 10,
+		Start: base.Add(time.Hour), End: base.Add(4 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, event.ID, extended.ID)
+	var annotations []models.Annotation
+	require.NoError(t, database.GetDB().Where("event_id = ?", event.ID).Find(&annotations).Error)
+	require.Len(t, annotations, 1)
+	assert.Equal(t, "user1", annotations[0].UserID)
+	require.NotNil(t, annotations[0].Until)
+	assert.True(t, base.Add(5*time.Hour).Equal(*annotations[0].Until), "the event and the recovery after it")
+
+	// Ending it early moves the annotation's end; showers until then aren't learned from
+	clock.Set(base.Add(2 * time.Hour))
+	ended, err := service.End(event.ID)
+	require.NoError(t, err)
+	require.NotNil(t, ended.EndedAt)
+	assert.Empty(t, service.Upcoming())
+	assert.True(t, shower(base.Add(150*time.Minute)).ExcludedFromTraining)
+	assert.False(t, shower(base.Add(3*time.Hour+time.Minute)).ExcludedFromTraining)
+	_, err = service.End(event.ID)
+	assert.ErrorIs(t, err, ErrDemandResponseEnded)
+	_, err = service.End("missing")
+	assert.ErrorIs(t, err, ErrDemandResponseNotFound)
+
+	// Events that ran their course end on their own
+	_, _, err = service.Signal(DemandResponseSignal{Action: models.DemandResponseShift, End: base.Add(150 * time.Minute)})
+	require.NoError(t, err)
+	clock.Set(base.Add(3 * time.Hour))
+	resumed, err := service.ResumeDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+	assert.Empty(t, service.Upcoming())
+}
+
+func TestHeatingScheduler_AvoidsDemandResponseEvents(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "demand.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	day := time.Date(2026, 6, 5, 0, 0, 0, 0, time.UTC)
+	solar := NewSolarService(config.SolarConfig{BaseLoad: 300})
+	solar.SetForecast([]SolarPoint{{Start: day.Add(10 * time.Hour), End: day.Add(14 * time.Hour), Watts: 3000}})
+	scheduler := NewHeatingScheduler(
+		NewPriceService(config.PricesConfig{}),
+		solar,
+		NewBoilerService(config.DeviceConfig{HeaterPower: 2}, InletModel{}),
+		ResidualHeatModel{HalfLife: 8 * time.Hour},
+	)
+	events := NewDemandResponseService(config.DemandResponseConfig{MaxDuration: 12 * time.Hour}, nil)
+	events.SetClock(NewFrozenClock(day))
+	scheduler.SetDemandResponse(events)
+
+	// The utility shifts heating out of the last two hours of the surplus
+	_, _, err := events.Signal(DemandResponseSignal{Action: models.DemandResponseShift, Start: day.Add(12 * time.Hour), End: day.Add(14 * time.Hour)})
+	require.NoError(t, err)
+	schedule, err := scheduler.Recommend(context.Background(), ScheduleRequest{Minutes: 60, Earliest: day.Add(6 * time.Hour), ReadyBy: day.Add(19 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, day.Add(11*time.Hour), schedule.Start, "the last run on solar that is over before the event")
+	assert.Len(t, schedule.DemandResponse, 1)
+
+	// A cap longer than the run doesn't hold it back
+	_, _, err = events.Signal(DemandResponseSignal{Action: models.DemandResponseCap, MaxMinutes: 90, Start: day.Add(6 * time.Hour), End: day.Add(12 * time.Hour)})
+	require.NoError(t, err)
+	schedule, err = scheduler.Recommend(context.Background(), ScheduleRequest{Minutes: 60, Earliest: day.Add(6 * time.Hour), ReadyBy: day.Add(19 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, day.Add(11*time.Hour), schedule.Start)
+
+	// One shorter than the run leaves nothing before the shower
+	_, _, err = events.Signal(DemandResponseSignal{Action: models.DemandResponseCap, MaxMinutes: 20, Start: day.Add(6 * time.Hour), End: day.Add(12 * time.Hour)})
+	require.NoError(t, err)
+	_, err = scheduler.Recommend(context.Background(), ScheduleRequest{Minutes: 60, Earliest: day.Add(6 * time.Hour), ReadyBy: day.Add(13 * time.Hour)})
+	assert.ErrorIs(t, err, ErrHeatingCurtailed)
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+// ErrUnknownDeviceEvent is returned for an event type the state machine doesn't handle
+var ErrUnknownDeviceEvent = errors.New("unknown device event")
+
+// DeviceState is where a water heater is in its heating cycle
+type DeviceState string
+
+const (
+	DeviceIdle    DeviceState = "idle"
+	DeviceHeating DeviceState = "heating"
+	DeviceReady   DeviceState = "ready"
+	DeviceCooling DeviceState = "cooling"
+)
+
+// Device event types, by the integration that reports them
+const (
+	DeviceEventScheduleStart   = "schedule_start"   // the scheduler started a heating run of HeatingTime minutes
+	DeviceEventSanitationStart = "sanitation_start" // the scheduler started a legionella cycle of HeatingTime minutes
+	DeviceEventPlugOn          = "plug_on"          // the smart plug confirmed it switched on
+	DeviceEventPlugOff         = "plug_off"         // the smart plug confirmed it switched off
+	DeviceEventBoost           = "boost"            // the user switched the heater on by hand, e.g. with a thermostat's boost button
+	DeviceEventTemperature     = "temperature"      // a sensor reported the water temperature
+)
+
+// DeviceEvent is one input to the device state machine
+type DeviceEvent struct {
+	Type        string    `json:"type" binding:"required"`
+	HeatingTime float64   `json:"heatingTime"`           // minutes, for schedule_start
+	Temperature *float64  `json:"temperature,omitempty"` // °C, for temperature
+	At          time.Time `json:"at"`                    // when it happened; now when zero
+
+	// CorrelationID is the correlation ID of the prediction a scheduled run heats for. Events
+	// without one belong to the run under way, if any.
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// DeviceStatus is a snapshot of one device, including the countdown while it heats
+type DeviceStatus struct {
+	DeviceID         string      `json:"deviceId"`
+	State            DeviceState `json:"state"`
+	Since            *time.Time  `json:"since,omitempty"`
+	ReadyAt          *time.Time  `json:"readyAt,omitempty"`
+	RemainingSeconds *float64    `json:"remainingSeconds,omitempty"`
+	PlugOn           bool        `json:"plugOn"`
+	Temperature      *float64    `json:"temperature,omitempty"`
+	TemperatureAt    *time.Time  `json:"temperatureAt,omitempty"`
+	CorrelationID    string      `json:"correlationId,omitempty"` // of the run under way or cooling down
+}
+
+// Bases of a remaining-time estimate
+const (
+	RemainingFromSensor   = "sensor"   // extrapolated from the water temperature rising since heating started
+	RemainingFromSchedule = "schedule" // the heating time the scheduler planned
+)
+
+// DeviceRemaining is how long until a device's water is predicted ready
+type DeviceRemaining struct {
+	DeviceID         string      `json:"deviceId"`
+	State            DeviceState `json:"state"`
+	Active           bool        `json:"active"` // heating or ready
+	RemainingMinutes *float64    `json:"remainingMinutes,omitempty"`
+	ReadyAt          *time.Time  `json:"readyAt,omitempty"`
+	Basis            string      `json:"basis,omitempty"`
+}
+
+// temperatureReading is one sensor reading
+type temperatureReading struct {
+	value float64
+	at    time.Time
+}
+
+type deviceRecord struct {
+	state         DeviceState
+	since         time.Time
+	readyAt       *time.Time
+	plugOn        bool
+	temperature   *float64
+	temperatureAt *time.Time
+	heatingFrom   *temperatureReading // water temperature when the current heating run started
+	correlationID string              // of the current run, until the device goes idle
+}
+
+// DeviceStateService tracks each water heater through idle → heating → ready → cooling → idle.
+// State is kept in memory: it describes the next few minutes, not history. Time-based
+// transitions (the planned heating time elapsing, the cool-down ending) are applied
+// whenever a device is read or receives an event.
+type DeviceStateService struct {
+	cfg config.DeviceConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	devices map[string]*deviceRecord
+
+	onBoost      []func(deviceID string, at time.Time)
+	onHeating    []func(deviceID string, status DeviceStatus)
+	onApplied    []func(deviceID string, status DeviceStatus)
+	onSanitation []func(deviceID string, at time.Time, minutes float64)
+	onCorrelated []func(deviceID string, event DeviceEvent, status DeviceStatus)
+}
+
+// NewDeviceStateService creates a new device state service instance
+func NewDeviceStateService(cfg config.DeviceConfig) *DeviceStateService {
+	return &DeviceStateService{
+		cfg:     cfg,
+		now:     time.Now,
+		devices: make(map[string]*deviceRecord),
+	}
+}
+
+// SetClock sets the clock heating progress and cool-down are measured against
+func (s *DeviceStateService) SetClock(clock Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = clock.Now
+}
+
+// State returns the current state of a device. Devices that never reported are idle.
+func (s *DeviceStateService) State(deviceID string) DeviceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	device, ok := s.devices[deviceID]
+	if !ok {
+		return DeviceStatus{DeviceID: deviceID, State: DeviceIdle}
+	}
+	now := s.now()
+	s.advance(device, now)
+	return device.status(deviceID, now)
+}
+
+// Remaining estimates when the water will be ready. While heating, once the temperature has
+// risen since the run started the estimate extrapolates that rise to the ready temperature,
+// so it tracks the sensor; before that it falls back to the scheduled heating time.
+func (s *DeviceStateService) Remaining(deviceID string) DeviceRemaining {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	device, ok := s.devices[deviceID]
+	if !ok {
+		return DeviceRemaining{DeviceID: deviceID, State: DeviceIdle}
+	}
+	now := s.now()
+	s.advance(device, now)
+
+	remaining := DeviceRemaining{DeviceID: deviceID, State: device.state}
+	switch device.state {
+	case DeviceReady:
+		zero := 0.0
+		remaining.Active = true
+		remaining.RemainingMinutes = &zero
+	case DeviceHeating:
+		remaining.Active = true
+		readyAt, basis := s.predictReady(device)
+		if readyAt == nil {
+			break
+		}
+		minutes := math.Max(readyAt.Sub(now).Minutes(), 0)
+		remaining.RemainingMinutes = &minutes
+		remaining.ReadyAt = readyAt
+		remaining.Basis = basis
+	}
+	return remaining
+}
+
+// predictReady must be called with the lock held
+func (s *DeviceStateService) predictReady(device *deviceRecord) (*time.Time, string) {
+	if from := device.heatingFrom; from != nil && device.temperature != nil && device.temperatureAt.After(from.at) {
+		rise := *device.temperature - from.value
+		if rise > 0 {
+			perMinute := rise / device.temperatureAt.Sub(from.at).Minutes()
+			left := (s.cfg.ReadyTemperature - *device.temperature) / perMinute
+			readyAt := device.temperatureAt.Add(time.Duration(left * float64(time.Minute)))
+			return &readyAt, RemainingFromSensor
+		}
+	}
+	if device.readyAt != nil {
+		readyAt := *device.readyAt
+		return &readyAt, RemainingFromSchedule
+	}
+	return nil, ""
+}
+
+// OnManualBoost registers a callback run when the user starts heating by hand: a boost event,
+// or the plug switching on while no scheduled run is under way
+func (s *DeviceStateService) OnManualBoost(fn func(deviceID string, at time.Time)) {
+	s.onBoost = append(s.onBoost, fn)
+}
+
+// OnHeatingStarted registers a callback run when a device starts heating for a shower, whether
+// scheduled or by hand. Legionella cycles don't count: nobody is waiting for that water.
+func (s *DeviceStateService) OnHeatingStarted(fn func(deviceID string, status DeviceStatus)) {
+	s.onHeating = append(s.onHeating, fn)
+}
+
+// OnSanitationStarted registers a callback run when the scheduler starts a legionella cycle
+func (s *DeviceStateService) OnSanitationStarted(fn func(deviceID string, at time.Time, minutes float64)) {
+	s.onSanitation = append(s.onSanitation, fn)
+}
+
+// OnApplied registers a callback run with a device's status after every event applied to it
+func (s *DeviceStateService) OnApplied(fn func(deviceID string, status DeviceStatus)) {
+	s.onApplied = append(s.onApplied, fn)
+}
+
+// OnCorrelated registers a callback run after each event that belongs to a correlated run, with
+// the event's CorrelationID filled in from the run when it came without one
+func (s *DeviceStateService) OnCorrelated(fn func(deviceID string, event DeviceEvent, status DeviceStatus)) {
+	s.onCorrelated = append(s.onCorrelated, fn)
+}
+
+// Apply feeds an event into a device's state machine and returns the resulting state.
+// Events that don't apply to the current state, such as a repeated plug confirmation,
+// leave it unchanged.
+func (s *DeviceStateService) Apply(deviceID string, event DeviceEvent) (DeviceStatus, error) {
+	status, at, boostedAt, started, err := s.apply(deviceID, &event)
+	if boostedAt != nil {
+		for _, fn := range s.onBoost {
+			fn(deviceID, *boostedAt)
+		}
+	}
+	if err == nil && event.Type == DeviceEventSanitationStart {
+		for _, fn := range s.onSanitation {
+			fn(deviceID, at, event.HeatingTime)
+		}
+	} else if started {
+		for _, fn := range s.onHeating {
+			fn(deviceID, status)
+		}
+	}
+	if err == nil {
+		for _, fn := range s.onApplied {
+			fn(deviceID, status)
+		}
+	}
+	if err == nil && event.CorrelationID != "" {
+		event.At = at
+		for _, fn := range s.onCorrelated {
+			fn(deviceID, event, status)
+		}
+	}
+	return status, err
+}
+
+// apply runs Apply under the lock, also returning when the event happened, when a manual boost
+// started heating and whether the device started heating at all. An event without a correlation
+// ID is given the one of the run it belongs to.
+func (s *DeviceStateService) apply(deviceID string, event *DeviceEvent) (DeviceStatus, time.Time, *time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	at := event.At
+	if at.IsZero() || at.After(now) {
+		at = now
+	}
+
+	device := s.device(deviceID, now)
+	s.advance(device, now)
+	wasHeating := device.state == DeviceHeating
+	runID := device.correlationID
+
+	var boostedAt *time.Time
+	switch event.Type {
+	case DeviceEventScheduleStart, DeviceEventSanitationStart:
+		readyAt := at.Add(time.Duration(event.HeatingTime * float64(time.Minute)))
+		if device.state != DeviceHeating {
+			device.enter(DeviceHeating, at)
+		}
+		device.readyAt = &readyAt
+		device.correlationID = event.CorrelationID
+		runID = event.CorrelationID
+	case DeviceEventPlugOn, DeviceEventBoost:
+		device.plugOn = true
+		// The scheduler announces its runs, so one starting unannounced was started by hand.
+		// A boost also heats water that is already ready.
+		if device.state == DeviceIdle || device.state == DeviceCooling ||
+			(event.Type == DeviceEventBoost && device.state == DeviceReady) {
+			device.enter(DeviceHeating, at)
+			device.correlationID = event.CorrelationID
+			runID = event.CorrelationID
+			boostedAt = &at
+		}
+	case DeviceEventPlugOff:
+		device.plugOn = false
+		if device.state == DeviceHeating || device.state == DeviceReady {
+			device.enter(DeviceCooling, at)
+		}
+	case DeviceEventTemperature:
+		if event.Temperature == nil {
+			break
+		}
+		temperature := *event.Temperature
+		device.temperature = &temperature
+		device.temperatureAt = &at
+		if device.state == DeviceHeating && device.heatingFrom == nil {
+			device.heatingFrom = &temperatureReading{value: temperature, at: at}
+		}
+		if device.state == DeviceHeating && temperature >= s.cfg.ReadyTemperature {
+			device.enter(DeviceReady, at)
+		}
+	default:
+		return DeviceStatus{}, at, nil, false, ErrUnknownDeviceEvent
+	}
+	if device.correlationID == "" && device.state != DeviceIdle {
+		device.correlationID = event.CorrelationID
+	}
+	if event.CorrelationID == "" {
+		event.CorrelationID = runID
+	}
+
+	// An event dated in the past may already have run its course
+	s.advance(device, now)
+	return device.status(deviceID, now), at, boostedAt, !wasHeating && device.state == DeviceHeating, nil
+}
+
+// device must be called with the lock held
+func (s *DeviceStateService) device(deviceID string, now time.Time) *deviceRecord {
+	device, ok := s.devices[deviceID]
+	if !ok {
+		device = &deviceRecord{state: DeviceIdle, since: now}
+		s.devices[deviceID] = device
+	}
+	return device
+}
+
+// advance applies transitions that happen with time alone; it must be called with the lock held
+func (s *DeviceStateService) advance(device *deviceRecord, now time.Time) {
+	if device.state == DeviceHeating && device.readyAt != nil && !now.Before(*device.readyAt) {
+		device.enter(DeviceReady, *device.readyAt)
+	}
+	if device.state == DeviceCooling && now.Sub(device.since) >= s.cfg.CoolDown {
+		device.enter(DeviceIdle, device.since.Add(s.cfg.CoolDown))
+	}
+}
+
+func (d *deviceRecord) enter(state DeviceState, at time.Time) {
+	d.state = state
+	d.since = at
+	d.heatingFrom = nil
+	if state == DeviceIdle {
+		d.correlationID = ""
+	}
+	if state != DeviceHeating {
+		d.readyAt = nil
+		return
+	}
+	// The last reading before the run is the best guess at the starting temperature
+	if d.temperature != nil {
+		d.heatingFrom = &temperatureReading{value: *d.temperature, at: at}
+	}
+}
+
+func (d *deviceRecord) status(deviceID string, now time.Time) DeviceStatus {
+	since := d.since
+	status := DeviceStatus{
+		DeviceID:      deviceID,
+		State:         d.state,
+		Since:         &since,
+		PlugOn:        d.plugOn,
+		Temperature:   d.temperature,
+		TemperatureAt: d.temperatureAt,
+		CorrelationID: d.correlationID,
+	}
+	if d.readyAt != nil {
+		readyAt := *d.readyAt
+		remaining := readyAt.Sub(now).Seconds()
+		status.ReadyAt = &readyAt
+		status.RemainingSeconds = &remaining
+	}
+	return status
+}
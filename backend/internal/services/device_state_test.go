@@ -0,0 +1,199 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceStateService_HeatingCycle(t *testing.T) {
+	now := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	service := NewDeviceStateService(config.DeviceConfig{ReadyTemperature: 45, CoolDown: 30 * time.Minute})
+	service.now = func() time.Time { return now }
+	var started []string
+	service.OnHeatingStarted(func(deviceID string, _ DeviceStatus) { started = append(started, deviceID) })
+
+	assert.Equal(t, DeviceIdle, service.State("boiler").State)
+
+	status, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventScheduleStart, HeatingTime: 20})
+	require.NoError(t, err)
+	assert.Equal(t, DeviceHeating, status.State)
+	require.NotNil(t, status.RemainingSeconds)
+	assert.Equal(t, 1200.0, *status.RemainingSeconds)
+
+	// The plug confirming doesn't restart the countdown
+	now = now.Add(5 * time.Minute)
+	status, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOn})
+	require.NoError(t, err)
+	assert.True(t, status.PlugOn)
+	assert.Equal(t, 900.0, *status.RemainingSeconds)
+	assert.Equal(t, []string{"boiler"}, started, "heating started once")
+
+	// A hot enough reading makes the water ready before the planned time
+	hot := 47.5
+	now = now.Add(10 * time.Minute)
+	status, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventTemperature, Temperature: &hot})
+	require.NoError(t, err)
+	assert.Equal(t, DeviceReady, status.State)
+	assert.Nil(t, status.RemainingSeconds)
+
+	status, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOff})
+	require.NoError(t, err)
+	assert.Equal(t, DeviceCooling, status.State)
+
+	now = now.Add(31 * time.Minute)
+	assert.Equal(t, DeviceIdle, service.State("boiler").State)
+
+	_, err = service.Apply("boiler", DeviceEvent{Type: "explode"})
+	assert.ErrorIs(t, err, ErrUnknownDeviceEvent)
+}
+
+func TestDeviceStateService_CorrelatedRun(t *testing.T) {
+	now := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	service := NewDeviceStateService(config.DeviceConfig{ReadyTemperature: 45, CoolDown: 30 * time.Minute})
+	service.now = func() time.Time { return now }
+	var traced []string
+	service.OnCorrelated(func(_ string, event DeviceEvent, _ DeviceStatus) {
+		traced = append(traced, event.Type+":"+event.CorrelationID)
+	})
+
+	// Events outside a correlated run aren't traced
+	_, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOn})
+	require.NoError(t, err)
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOff})
+	require.NoError(t, err)
+	assert.Empty(t, traced)
+
+	// A new run doesn't inherit the ID of the one cooling down
+	status, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventScheduleStart, HeatingTime: 20, CorrelationID: "run-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", status.CorrelationID)
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOff})
+	require.NoError(t, err)
+	status, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventBoost})
+	require.NoError(t, err)
+	assert.Empty(t, status.CorrelationID)
+	assert.Equal(t, []string{"schedule_start:run-1", "plug_off:run-1"}, traced)
+
+	// The ID is forgotten once the device goes idle
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventScheduleStart, HeatingTime: 20, CorrelationID: "run-2"})
+	require.NoError(t, err)
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOff})
+	require.NoError(t, err)
+	now = now.Add(31 * time.Minute)
+	assert.Empty(t, service.State("boiler").CorrelationID)
+	assert.Equal(t, []string{"schedule_start:run-1", "plug_off:run-1", "schedule_start:run-2", "plug_off:run-2"}, traced)
+}
+
+func TestDeviceStateService_ReadyWhenPlannedTimeElapses(t *testing.T) {
+	now := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	service := NewDeviceStateService(config.DeviceConfig{ReadyTemperature: 45, CoolDown: 30 * time.Minute})
+	service.now = func() time.Time { return now }
+
+	_, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventScheduleStart, HeatingTime: 15})
+	require.NoError(t, err)
+
+	now = now.Add(16 * time.Minute)
+	status := service.State("boiler")
+	assert.Equal(t, DeviceReady, status.State)
+	assert.Equal(t, now.Add(-time.Minute), *status.Since)
+}
+
+func TestDeviceStateService_RemainingFollowsSensor(t *testing.T) {
+	now := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	service := NewDeviceStateService(config.DeviceConfig{ReadyTemperature: 45, CoolDown: 30 * time.Minute})
+	service.now = func() time.Time { return now }
+	reading := func(value float64) {
+		_, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventTemperature, Temperature: &value})
+		require.NoError(t, err)
+	}
+
+	assert.False(t, service.Remaining("boiler").Active)
+
+	reading(25)
+	_, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventScheduleStart, HeatingTime: 30})
+	require.NoError(t, err)
+
+	remaining := service.Remaining("boiler")
+	assert.True(t, remaining.Active)
+	assert.Equal(t, RemainingFromSchedule, remaining.Basis)
+	assert.Equal(t, 30.0, *remaining.RemainingMinutes)
+
+	// 25 -> 30 °C in 5 minutes leaves 15 °C, i.e. 15 more minutes
+	now = now.Add(5 * time.Minute)
+	reading(30)
+	remaining = service.Remaining("boiler")
+	assert.Equal(t, RemainingFromSensor, remaining.Basis)
+	assert.InDelta(t, 15.0, *remaining.RemainingMinutes, 1e-9)
+
+	// The water heats faster than that, so the estimate comes down
+	now = now.Add(5 * time.Minute)
+	reading(40)
+	assert.InDelta(t, 3.33, *service.Remaining("boiler").RemainingMinutes, 0.01)
+
+	reading(45)
+	remaining = service.Remaining("boiler")
+	assert.Equal(t, DeviceReady, remaining.State)
+	assert.Equal(t, 0.0, *remaining.RemainingMinutes)
+}
+
+func TestDeviceStateService_ManualBoost(t *testing.T) {
+	now := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	service := NewDeviceStateService(config.DeviceConfig{ReadyTemperature: 45, CoolDown: 30 * time.Minute})
+	service.now = func() time.Time { return now }
+	var boosts []time.Time
+	service.OnManualBoost(func(deviceID string, at time.Time) {
+		assert.Equal(t, "boiler", deviceID)
+		boosts = append(boosts, at)
+	})
+
+	// A scheduled run confirmed by the plug is not a boost
+	_, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventScheduleStart, HeatingTime: 20})
+	require.NoError(t, err)
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOn})
+	require.NoError(t, err)
+	assert.Empty(t, boosts)
+
+	// Boosting water that is already ready is
+	now = now.Add(25 * time.Minute)
+	status, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventBoost})
+	require.NoError(t, err)
+	assert.Equal(t, DeviceHeating, status.State)
+	assert.Equal(t, []time.Time{now}, boosts)
+
+	// So is the plug switching on while nothing is scheduled
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOff})
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOn})
+	require.NoError(t, err)
+	assert.Len(t, boosts, 2)
+}
+
+func TestDeviceStateService_SanitationCycle(t *testing.T) {
+	now := time.Date(2026, 1, 4, 3, 0, 0, 0, time.UTC)
+	service := NewDeviceStateService(config.DeviceConfig{ReadyTemperature: 45, CoolDown: 30 * time.Minute})
+	service.now = func() time.Time { return now }
+	var started, boosted []string
+	var cycles []float64
+	service.OnHeatingStarted(func(deviceID string, _ DeviceStatus) { started = append(started, deviceID) })
+	service.OnManualBoost(func(deviceID string, _ time.Time) { boosted = append(boosted, deviceID) })
+	service.OnSanitationStarted(func(deviceID string, at time.Time, minutes float64) {
+		assert.Equal(t, now, at)
+		cycles = append(cycles, minutes)
+	})
+
+	status, err := service.Apply("boiler", DeviceEvent{Type: DeviceEventSanitationStart, HeatingTime: 90})
+	require.NoError(t, err)
+	assert.Equal(t, DeviceHeating, status.State)
+	_, err = service.Apply("boiler", DeviceEvent{Type: DeviceEventPlugOn})
+	require.NoError(t, err)
+
+	assert.Equal(t, []float64{90}, cycles)
+	assert.Empty(t, started, "nobody is told about a cycle")
+	assert.Empty(t, boosted, "the plug switching on was announced")
+}
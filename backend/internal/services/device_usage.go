@@ -0,0 +1,218 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidUsageMonth is returned for a usage month that isn't YYYY-MM
+	ErrInvalidUsageMonth = errors.New("month must be in YYYY-MM format")
+	// ErrInvalidUsageRange is returned for a usage range that ends before it starts or spans
+	// more than maxUsageMonths
+	ErrInvalidUsageRange = errors.New("usage range must run forward and span at most 24 months")
+)
+
+// maxUsageMonths is the longest range a usage export covers
+const maxUsageMonths = 24
+
+// UsageDay is one day of a device's heating
+type UsageDay struct {
+	Date              time.Time `json:"date"` // midnight, in the export's time zone
+	Showers           int       `json:"showers"`
+	HeatingMinutes    float64   `json:"heatingMinutes"` // showers and sanitation cycles
+	SanitationMinutes float64   `json:"sanitationMinutes"`
+	EnergyKWh         float64   `json:"energyKwh"`
+	Cost              *float64  `json:"cost,omitempty"` // at the flat rate; nil without one
+}
+
+// DeviceUsage is a device's heating per day over whole months
+type DeviceUsage struct {
+	DeviceID    string     `json:"deviceId"`
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"` // exclusive
+	HeaterPower float64    `json:"heaterPower"`
+	Currency    string     `json:"currency,omitempty"`
+	Days        []UsageDay `json:"days"`
+	Total       UsageDay   `json:"total"`
+}
+
+// DeviceUsageService totals what a water heater used per day, for tenants on a shared meter
+// who claim the heater's electricity back from a landlord or flatmates. Heating minutes are the
+// logged heating times of every shower on the device, whoever took it, plus its legionella
+// cycles; simulated showers never ran the heater and are left out. Energy is the minutes at the
+// device's heater power and cost is at the configured flat rate: reimbursement is settled at
+// the tariff on the bill, not at spot prices.
+type DeviceUsageService struct {
+	db      *gorm.DB
+	boilers *BoilerService
+	prices  *PriceService
+	now     func() time.Time
+}
+
+// NewDeviceUsageService creates a new device usage service instance
+func NewDeviceUsageService(boilers *BoilerService, prices *PriceService) *DeviceUsageService {
+	return &DeviceUsageService{
+		db:      database.GetDB(),
+		boilers: boilers,
+		prices:  prices,
+		now:     time.Now,
+	}
+}
+
+// SetClock sets the clock the current month and the last day listed are worked out from
+func (s *DeviceUsageService) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// Usage totals the device's heating per day from the start of the from month to the end of the
+// to month, both YYYY-MM in loc; an empty from is the to month and an empty to the current
+// month. Days after today aren't listed.
+func (s *DeviceUsageService) Usage(userID, deviceID, from, to string, loc *time.Location) (*DeviceUsage, error) {
+	now := s.now().In(loc)
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	if to != "" {
+		month, err := time.ParseInLocation("2006-01", to, loc)
+		if err != nil {
+			return nil, ErrInvalidUsageMonth
+		}
+		end = month
+	}
+	start := end
+	if from != "" {
+		month, err := time.ParseInLocation("2006-01", from, loc)
+		if err != nil {
+			return nil, ErrInvalidUsageMonth
+		}
+		start = month
+	}
+	end = end.AddDate(0, 1, 0)
+	if !start.Before(end) || start.AddDate(0, maxUsageMonths, 0).Before(end) {
+		return nil, ErrInvalidUsageRange
+	}
+
+	usage := &DeviceUsage{
+		DeviceID:    deviceID,
+		From:        start,
+		To:          end,
+		HeaterPower: s.boilers.HeaterPower(userID, deviceID),
+		Currency:    s.prices.Currency(),
+		Days:        []UsageDay{},
+	}
+	index := make(map[string]int)
+	for day := start; day.Before(end) && !day.After(now); day = day.AddDate(0, 0, 1) {
+		index[day.Format("2006-01-02")] = len(usage.Days)
+		usage.Days = append(usage.Days, UsageDay{Date: day})
+	}
+	dayOf := func(t time.Time) (*UsageDay, bool) {
+		i, ok := index[t.In(loc).Format("2006-01-02")]
+		if !ok {
+			return nil, false
+		}
+		return &usage.Days[i], true
+	}
+
+	// Stored times can be in any zone, so the query is a day wider and days are matched here
+	var records []models.DailyRecord
+	err := s.db.Where("device_id = ? AND source <> ? AND date >= ? AND date < ?",
+		deviceID, models.RecordSourceSimulator, start.AddDate(0, 0, -1), end.AddDate(0, 0, 1)).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if day, ok := dayOf(record.Date); ok {
+			day.Showers++
+			day.HeatingMinutes += record.HeatingTime
+		}
+	}
+
+	var cycles []models.SanitationCycle
+	err = s.db.Where("device_id = ? AND started_at >= ? AND started_at < ?",
+		deviceID, start.UTC().AddDate(0, 0, -1), end.UTC().AddDate(0, 0, 1)).
+		Find(&cycles).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, cycle := range cycles {
+		if day, ok := dayOf(cycle.StartedAt); ok {
+			day.SanitationMinutes += cycle.Minutes
+			day.HeatingMinutes += cycle.Minutes
+		}
+	}
+
+	flatRate, hasFlatRate := s.prices.FlatRate()
+	usage.Total = UsageDay{Date: start}
+	if hasFlatRate {
+		usage.Total.Cost = new(float64)
+	}
+	for i := range usage.Days {
+		day := &usage.Days[i]
+		day.HeatingMinutes = roundTo(day.HeatingMinutes, 2)
+		day.SanitationMinutes = roundTo(day.SanitationMinutes, 2)
+		day.EnergyKWh = roundTo(usage.HeaterPower*day.HeatingMinutes/60, 3)
+		if hasFlatRate {
+			cost := roundTo(day.EnergyKWh*flatRate, 4)
+			day.Cost = &cost
+			*usage.Total.Cost = roundTo(*usage.Total.Cost+cost, 4)
+		}
+		usage.Total.Showers += day.Showers
+		usage.Total.HeatingMinutes = roundTo(usage.Total.HeatingMinutes+day.HeatingMinutes, 2)
+		usage.Total.SanitationMinutes = roundTo(usage.Total.SanitationMinutes+day.SanitationMinutes, 2)
+		usage.Total.EnergyKWh = roundTo(usage.Total.EnergyKWh+day.EnergyKWh, 3)
+	}
+	return usage, nil
+}
+
+// usageCSVDateLayout is how usage days are written when no locale is chosen
+const usageCSVDateLayout = "2006-01-02"
+
+// WriteDeviceUsageCSV writes usage as CSV, one row per day and a closing total row, with dates
+// and numbers in the given format
+func WriteDeviceUsageCSV(w io.Writer, usage *DeviceUsage, format ExportFormat) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = format.Separator()
+
+	cost := "Cost"
+	if usage.Currency != "" {
+		cost += " (" + usage.Currency + ")"
+	}
+	header := []string{"Date", "Showers", "Heating Minutes", "Sanitation Minutes", "Energy (kWh)", cost}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	row := func(date string, day UsageDay) []string {
+		costText := ""
+		if day.Cost != nil {
+			costText = format.Number(*day.Cost, 2)
+		}
+		return []string{
+			date,
+			strconv.Itoa(day.Showers),
+			format.Number(day.HeatingMinutes, 1),
+			format.Number(day.SanitationMinutes, 1),
+			format.Number(day.EnergyKWh, 2),
+			costText,
+		}
+	}
+	for _, day := range usage.Days {
+		if err := writer.Write(row(format.Date(day.Date, usageCSVDateLayout), day)); err != nil {
+			return err
+		}
+	}
+	if err := writer.Write(row("Total", usage.Total)); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
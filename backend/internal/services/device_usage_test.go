@@ -0,0 +1,78 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestDeviceUsageService(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "usage.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	db := database.GetDB()
+
+	boilers := NewBoilerService(config.DeviceConfig{HeaterPower: 3}, InletModel{})
+	usage := NewDeviceUsageService(boilers, NewPriceService(config.PricesConfig{Currency: "EUR", FlatRate: 0.2}))
+	usage.SetClock(NewFrozenClock(time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)))
+
+	for _, record := range []models.DailyRecord{
+		{UserID: "user1", DeviceID: "boiler", Date: time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC), HeatingTime: 20},
+		{UserID: "user2", DeviceID: "boiler", Date: time.Date(2026, 3, 2, 22, 30, 0, 0, time.UTC), HeatingTime: 40},
+		{UserID: "user1", DeviceID: "boiler", Date: time.Date(2026, 2, 28, 7, 0, 0, 0, time.UTC), HeatingTime: 30},
+		{UserID: "user1", DeviceID: "boiler", Date: time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC), HeatingTime: 99, Source: models.RecordSourceSimulator},
+		{UserID: "user1", DeviceID: "other", Date: time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC), HeatingTime: 99},
+	} {
+		record := record
+		record.ShowerDuration, record.AverageTemperature, record.Satisfaction = 10, 15, 50
+		require.NoError(t, db.Create(&record).Error)
+	}
+	require.NoError(t, db.Create(&models.SanitationCycle{DeviceID: "boiler", StartedAt: time.Date(2026, 3, 8, 3, 0, 0, 0, time.UTC), Minutes: 90}).Error)
+
+	march, err := usage.Usage("user1", "boiler", "", "", time.UTC)
+	require.NoError(t, err)
+	require.Len(t, march.Days, 10, "the current month up to today")
+	assert.Equal(t, 2, march.Days[1].Showers, "every household member's showers, but not simulated ones")
+	assert.Equal(t, 60.0, march.Days[1].HeatingMinutes)
+	assert.Equal(t, 3.0, march.Days[1].EnergyKWh)
+	require.NotNil(t, march.Days[1].Cost)
+	assert.Equal(t, 0.6, *march.Days[1].Cost)
+	assert.Equal(t, 0, march.Days[2].Showers)
+	assert.Equal(t, 90.0, march.Days[7].SanitationMinutes)
+	assert.Equal(t, 150.0, march.Total.HeatingMinutes)
+	assert.Equal(t, 7.5, march.Total.EnergyKWh)
+
+	// Jerusalem is two hours ahead in winter, which moves the evening shower to 3 March
+	both, err := usage.Usage("user1", "boiler", "2026-02", "2026-03", time.FixedZone("IST", 2*60*60))
+	require.NoError(t, err)
+	assert.Len(t, both.Days, 28+10)
+	assert.Equal(t, 1, both.Days[27].Showers)
+	assert.Equal(t, 1, both.Days[28+1].Showers)
+	assert.Equal(t, 1, both.Days[28+2].Showers)
+
+	_, err = usage.Usage("user1", "boiler", "March", "", time.UTC)
+	assert.ErrorIs(t, err, ErrInvalidUsageMonth)
+	_, err = usage.Usage("user1", "boiler", "2026-03", "2026-02", time.UTC)
+	assert.ErrorIs(t, err, ErrInvalidUsageRange)
+	_, err = usage.Usage("user1", "boiler", "2023-01", "2026-02", time.UTC)
+	assert.ErrorIs(t, err, ErrInvalidUsageRange)
+
+	format, err := NewExportFormat("de", "")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, WriteDeviceUsageCSV(&out, march, format))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 12)
+	assert.Equal(t, "Date;Showers;Heating Minutes;Sanitation Minutes;Energy (kWh);Cost (EUR)", lines[0])
+	assert.Equal(t, "02.03.2026;2;60,0;0,0;3,00;0,60", lines[2])
+	assert.Equal(t, "Total;2;150,0;90,0;7,50;1,50", lines[11])
+}
@@ -0,0 +1,18 @@
+package services
+
+// dryRunSampleSize is how many IDs a dry run lists of the rows it would affect
+const dryRunSampleSize = 10
+
+// DryRunResult is what a destructive request would affect, reported instead of carrying it
+// out so a filter or file can be checked first
+type DryRunResult struct {
+	Counts    map[string]int64 `json:"counts"`
+	SampleIDs []string         `json:"sampleIds"`
+}
+
+// sample adds an ID to the sample unless it is full
+func (r *DryRunResult) sample(id string) {
+	if len(r.SampleIDs) < dryRunSampleSize {
+		r.SampleIDs = append(r.SampleIDs, id)
+	}
+}
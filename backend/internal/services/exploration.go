@@ -0,0 +1,42 @@
+package services
+
+import (
+	"math/rand"
+
+	"heat-logger/internal/models"
+)
+
+// defaultExplorationMultipliers is the probe schedule for the first sessions in a new context:
+// start on the warm side, then try slightly less and slightly more to find the edges quickly.
+var defaultExplorationMultipliers = []float64{1.10, 0.95, 1.20}
+
+// ExplorationInfo reports an exploration adjustment applied to a prediction
+type ExplorationInfo struct {
+	Bucket     string  `json:"bucket"`
+	Session    int     `json:"session"` // 0-based index of this session within the bucket's exploration budget
+	Multiplier float64 `json:"multiplier"`
+}
+
+// explorationFor returns the multiplier for the user's next session in the request's context bucket,
+// or nil once the bucket has used up its exploration budget, or for a request whose answer is
+// only shown and so can't teach anything. With a generator and a configured jitter, the
+// multiplier is varied at random around the schedule.
+func (s *PredictionServiceV2) explorationFor(req PredictionRequest, userRecords []models.DailyRecord, rng *rand.Rand) *ExplorationInfo {
+	if s.cfg.DisableExploration || req.withoutExploration || len(s.cfg.ExplorationMultipliers) == 0 {
+		return nil
+	}
+	bucket := BucketFor(req.Duration, req.Temperature)
+	session := countInBucket(userRecords, bucket)
+	if session >= len(s.cfg.ExplorationMultipliers) {
+		return nil
+	}
+	multiplier := s.cfg.ExplorationMultipliers[session]
+	if rng != nil && s.cfg.ExplorationJitter > 0 {
+		multiplier *= 1 + s.cfg.ExplorationJitter*(2*rng.Float64()-1)
+	}
+	return &ExplorationInfo{
+		Bucket:     bucket.Key,
+		Session:    session,
+		Multiplier: multiplier,
+	}
+}
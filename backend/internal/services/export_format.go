@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupportedLocale is returned for an export locale without a known format
+	ErrUnsupportedLocale = errors.New("unsupported export locale")
+	// ErrUnknownTimezone is returned for an export time zone that isn't an IANA name
+	ErrUnknownTimezone = errors.New("unknown time zone")
+)
+
+// exportLocale is how one locale writes dates and numbers
+type exportLocale struct {
+	layout       string
+	decimalComma bool
+}
+
+// exportLocales are the locales exports can be written in, by language tag. A tag that isn't
+// listed falls back to its language, e.g. de-AT to de.
+var exportLocales = map[string]exportLocale{
+	"en":    {layout: "2006-01-02 15:04:05"},
+	"en-US": {layout: "01/02/2006 3:04:05 PM"},
+	"en-GB": {layout: "02/01/2006 15:04:05"},
+	"he":    {layout: "02/01/2006 15:04:05"},
+	"de":    {layout: "02.01.2006 15:04:05", decimalComma: true},
+	"fr":    {layout: "02/01/2006 15:04:05", decimalComma: true},
+	"es":    {layout: "02/01/2006 15:04:05", decimalComma: true},
+	"it":    {layout: "02/01/2006 15:04:05", decimalComma: true},
+	"nl":    {layout: "02-01-2006 15:04:05", decimalComma: true},
+	"ru":    {layout: "02.01.2006 15:04:05", decimalComma: true},
+}
+
+// ExportFormat is how an export writes dates and numbers. The zero value keeps each export's
+// own layout and the server's time zone, with decimal points.
+type ExportFormat struct {
+	Locale   string `json:"locale,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+
+	layout       string
+	location     *time.Location
+	decimalComma bool
+}
+
+// NewExportFormat resolves a locale such as de-DE and an IANA time zone; either may be empty
+func NewExportFormat(locale, timezone string) (ExportFormat, error) {
+	format := ExportFormat{Locale: locale, Timezone: timezone}
+	if locale != "" {
+		tag := strings.ReplaceAll(locale, "_", "-")
+		known, ok := exportLocales[tag]
+		if !ok {
+			language, _, _ := strings.Cut(tag, "-")
+			known, ok = exportLocales[strings.ToLower(language)]
+		}
+		if !ok {
+			return ExportFormat{}, ErrUnsupportedLocale
+		}
+		format.layout = known.layout
+		format.decimalComma = known.decimalComma
+	}
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return ExportFormat{}, ErrUnknownTimezone
+		}
+		format.location = location
+	}
+	return format, nil
+}
+
+// Time writes t in the format's locale and time zone, or in fallback, the export's own layout,
+// when no locale was chosen
+func (f ExportFormat) Time(t time.Time, fallback string) string {
+	if f.location != nil {
+		t = t.In(f.location)
+	}
+	if f.layout == "" {
+		return t.Format(fallback)
+	}
+	return t.Format(f.layout)
+}
+
+// Date writes the day of t in the format's locale, without the time of day, or in fallback
+// when no locale was chosen
+func (f ExportFormat) Date(t time.Time, fallback string) string {
+	if f.location != nil {
+		t = t.In(f.location)
+	}
+	if f.layout == "" {
+		return t.Format(fallback)
+	}
+	layout, _, _ := strings.Cut(f.layout, " ")
+	return t.Format(layout)
+}
+
+// Location is the format's time zone, the server's when none was chosen
+func (f ExportFormat) Location() *time.Location {
+	if f.location == nil {
+		return time.Local
+	}
+	return f.location
+}
+
+// Number writes v with the locale's decimal separator
+func (f ExportFormat) Number(v float64, decimals int) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if f.decimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// Separator is the CSV field separator: a semicolon where the comma is the decimal separator,
+// as spreadsheet programs in those locales expect
+func (f ExportFormat) Separator() rune {
+	if f.decimalComma {
+		return ';'
+	}
+	return ','
+}
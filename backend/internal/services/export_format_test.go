@@ -0,0 +1,48 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExportFormat(t *testing.T) {
+	format, err := NewExportFormat("de-AT", "Europe/Vienna")
+	require.NoError(t, err, "an unlisted region falls back to its language")
+	assert.Equal(t, "12,5", format.Number(12.5, 1))
+	assert.Equal(t, ';', format.Separator())
+
+	format, err = NewExportFormat("en_US", "")
+	require.NoError(t, err)
+	assert.Equal(t, "03/10/2026 7:05:00 AM", format.Time(time.Date(2026, 3, 10, 7, 5, 0, 0, time.UTC), time.RFC3339))
+
+	_, err = NewExportFormat("tlh", "")
+	assert.ErrorIs(t, err, ErrUnsupportedLocale)
+	_, err = NewExportFormat("", "Mars/Olympus")
+	assert.ErrorIs(t, err, ErrUnknownTimezone)
+}
+
+func TestWriteRecordsCSV_Format(t *testing.T) {
+	records := []models.DailyRecord{{
+		UserID: "user1", Date: time.Date(2026, 3, 10, 23, 30, 0, 0, time.UTC),
+		ShowerDuration: 10, AverageTemperature: 15.5, HeatingTime: 22.5, Satisfaction: 50, Source: "manual",
+	}}
+
+	var out strings.Builder
+	require.NoError(t, WriteRecordsCSV(&out, records, ExportFormat{}))
+	assert.Equal(t, "user1,2026-03-10 23:30:00,10.0,15.5,22.5,50.0,manual,,,", strings.Split(out.String(), "\n")[1])
+
+	format, err := NewExportFormat("de-DE", "Europe/Berlin")
+	require.NoError(t, err)
+	out.Reset()
+	require.NoError(t, WriteRecordsCSV(&out, records, format))
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, strings.Join(recordCSVHeader, ";"), lines[0])
+	// Berlin is an hour ahead of UTC in March, which moves the shower to the next day
+	assert.Equal(t, "user1;11.03.2026 00:30:00;10,0;15,5;22,5;50,0;manual;;;", lines[1])
+}
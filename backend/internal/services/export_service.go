@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrExportThrottled is returned when a user requests exports faster than the configured throttle
+	ErrExportThrottled = errors.New("export requested too soon after the previous one")
+	// ErrExportJobNotFound is returned when a job does not exist or belongs to another user
+	ErrExportJobNotFound = errors.New("export job not found")
+	// ErrExportNotReady is returned when downloading a job that has not completed
+	ErrExportNotReady = errors.New("export job is not ready")
+	// ErrExportExpired is returned when downloading a job past its expiry
+	ErrExportExpired = errors.New("export job has expired")
+)
+
+// ExportService runs history exports in the background so large exports don't block request workers
+type ExportService struct {
+	db            *gorm.DB
+	recordService *RecordService
+	cfg           config.ExportConfig
+	queue         chan string
+}
+
+// NewExportService creates a new export service instance
+func NewExportService(recordService *RecordService, cfg config.ExportConfig) *ExportService {
+	return &ExportService{
+		db:            database.GetDB(),
+		recordService: recordService,
+		cfg:           cfg,
+		queue:         make(chan string, 64),
+	}
+}
+
+// Start launches the export worker and the expiry sweeper
+func (s *ExportService) Start() {
+	// Jobs left in flight by a previous process will never finish
+	s.db.Model(&models.ExportJob{}).
+		Where("status IN ?", []string{models.ExportJobPending, models.ExportJobRunning}).
+		Updates(map[string]interface{}{"status": models.ExportJobFailed, "error": "interrupted by server restart"})
+
+	go s.worker()
+	go s.sweeper()
+}
+
+// CreateJob queues a new export for the user in the given format, enforcing the per-user throttle
+func (s *ExportService) CreateJob(userID string, format ExportFormat) (*models.ExportJob, error) {
+	var latest models.ExportJob
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").First(&latest).Error
+	if err == nil && time.Since(latest.CreatedAt) < s.cfg.Throttle {
+		return nil, ErrExportThrottled
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	job := &models.ExportJob{
+		UserID:    userID,
+		Status:    models.ExportJobPending,
+		Locale:    format.Locale,
+		Timezone:  format.Timezone,
+		ExpiresAt: time.Now().Add(s.cfg.JobTTL),
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- job.ID:
+	default:
+		s.finish(job.ID, 0, "", errors.New("export queue is full"))
+		return nil, errors.New("export queue is full, try again later")
+	}
+	return job, nil
+}
+
+// ListJobs returns the user's unexpired export jobs, newest first
+func (s *ExportService) ListJobs(userID string) ([]models.ExportJob, error) {
+	var jobs []models.ExportJob
+	err := s.db.Where("user_id = ? AND expires_at > ?", userID, time.Now()).Order("created_at DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+// GetJob retrieves a job owned by the user
+func (s *ExportService) GetJob(userID, id string) (*models.ExportJob, error) {
+	var job models.ExportJob
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DownloadPath returns the file path for a completed, unexpired job
+func (s *ExportService) DownloadPath(userID, id string) (string, error) {
+	job, err := s.GetJob(userID, id)
+	if err != nil {
+		return "", err
+	}
+	if time.Now().After(job.ExpiresAt) {
+		return "", ErrExportExpired
+	}
+	if job.Status != models.ExportJobCompleted {
+		return "", ErrExportNotReady
+	}
+	return job.FilePath, nil
+}
+
+func (s *ExportService) worker() {
+	for id := range s.queue {
+		s.run(id)
+	}
+}
+
+// run writes the CSV for a single job and records the outcome
+func (s *ExportService) run(id string) {
+	var job models.ExportJob
+	if err := s.db.Where("id = ?", id).First(&job).Error; err != nil {
+		log.Printf("Export job %s could not be loaded: %v", id, err)
+		return
+	}
+	s.db.Model(&job).Update("status", models.ExportJobRunning)
+
+	format, err := NewExportFormat(job.Locale, job.Timezone)
+	if err != nil {
+		s.finish(id, 0, "", err)
+		return
+	}
+	records, err := s.recordService.GetRecordsByUser(job.UserID)
+	if err != nil {
+		s.finish(id, 0, "", err)
+		return
+	}
+
+	if err := os.MkdirAll(s.cfg.Dir, 0o755); err != nil {
+		s.finish(id, 0, "", err)
+		return
+	}
+	path := filepath.Join(s.cfg.Dir, fmt.Sprintf("export_%s.csv", job.ID))
+	file, err := os.Create(path)
+	if err != nil {
+		s.finish(id, 0, "", err)
+		return
+	}
+	err = WriteRecordsCSV(file, records, format)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		s.finish(id, 0, "", err)
+		return
+	}
+
+	s.finish(id, len(records), path, nil)
+}
+
+func (s *ExportService) finish(id string, count int, path string, runErr error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"completed_at": &now,
+		"record_count": count,
+		"file_path":    path,
+		"status":       models.ExportJobCompleted,
+	}
+	if runErr != nil {
+		updates["status"] = models.ExportJobFailed
+		updates["error"] = runErr.Error()
+		log.Printf("Export job %s failed: %v", id, runErr)
+	}
+	s.db.Model(&models.ExportJob{}).Where("id = ?", id).Updates(updates)
+}
+
+// sweeper periodically removes expired jobs and their files
+func (s *ExportService) sweeper() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		s.purgeExpired()
+		<-ticker.C
+	}
+}
+
+func (s *ExportService) purgeExpired() {
+	var expired []models.ExportJob
+	if err := s.db.Where("expires_at <= ?", time.Now()).Find(&expired).Error; err != nil {
+		log.Printf("Failed to list expired export jobs: %v", err)
+		return
+	}
+	for _, job := range expired {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove export file %s: %v", job.FilePath, err)
+				continue
+			}
+		}
+		s.db.Delete(&job)
+	}
+}
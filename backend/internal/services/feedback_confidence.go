@@ -0,0 +1,44 @@
+package services
+
+import "heat-logger/internal/models"
+
+// FeedbackInferred is the FeedbackConfidence entry for satisfaction inferred from how the user
+// reacted rather than rated
+const FeedbackInferred = "inferred"
+
+// FeedbackConfidence is how sure the satisfaction of a record is, by the source of the
+// feedback: a record source for ratings, or FeedbackInferred. Sources without an entry are
+// fully trusted.
+type FeedbackConfidence map[string]float64
+
+// DefaultFeedbackConfidence trusts ratings fully and inferred feedback half as much
+func DefaultFeedbackConfidence() FeedbackConfidence {
+	return FeedbackConfidence{
+		FeedbackInferred: 0.5,
+	}
+}
+
+// For returns the confidence in the record's satisfaction. Records saved before sources were
+// tracked have no source and count as manual.
+func (c FeedbackConfidence) For(record models.DailyRecord) float64 {
+	source := record.Source
+	switch {
+	case record.ReviewStatus == models.ReviewStatusInferred:
+		source = FeedbackInferred
+	case source == "":
+		source = models.RecordSourceManual
+	}
+	if confidence, ok := c[source]; ok {
+		return confidence
+	}
+	return 1.0
+}
+
+// withDefaults returns the default confidences overridden by c
+func (c FeedbackConfidence) withDefaults() FeedbackConfidence {
+	merged := DefaultFeedbackConfidence()
+	for source, confidence := range c {
+		merged[source] = confidence
+	}
+	return merged
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrFeedbackPromptNotFound is returned when a prompt does not exist or belongs to another user
+var ErrFeedbackPromptNotFound = errors.New("feedback prompt not found")
+
+const (
+	// feedbackPromptInterval is how often passed showers are checked and due reminders sent
+	feedbackPromptInterval = time.Minute
+	// feedbackPromptRetry is how long a reminder the webhook failed to take waits before another try
+	feedbackPromptRetry = 15 * time.Minute
+)
+
+// FeedbackPromptService reminds users to give feedback after a scheduled shower. The model only
+// learns from feedback, so a shower that passes without any is queued as a prompt once the
+// configured delay is over; reminders due in the user's quiet hours wait until they end.
+type FeedbackPromptService struct {
+	db            *gorm.DB
+	templates     *ScheduleTemplateService
+	notifications *NotificationService
+	cfg           config.FeedbackConfig
+}
+
+// NewFeedbackPromptService creates a new feedback prompt service instance
+func NewFeedbackPromptService(templates *ScheduleTemplateService, notifications *NotificationService, cfg config.FeedbackConfig) *FeedbackPromptService {
+	return &FeedbackPromptService{
+		db:            database.GetDB(),
+		templates:     templates,
+		notifications: notifications,
+		cfg:           cfg,
+	}
+}
+
+// Start launches the worker that queues prompts and sends due reminders
+func (s *FeedbackPromptService) Start() {
+	go func() {
+		ticker := time.NewTicker(feedbackPromptInterval)
+		defer ticker.Stop()
+		for {
+			now := time.Now()
+			if err := s.Enqueue("", now); err != nil {
+				log.Printf("Failed to queue feedback prompts: %v", err)
+			}
+			s.notifyDue(now)
+			<-ticker.C
+		}
+	}()
+}
+
+// Enqueue queues a prompt for each of the user's showers (every scheduled user's when userID is
+// empty) that is at least the prompt delay in the past, within the lookback, and has no feedback
+func (s *FeedbackPromptService) Enqueue(userID string, now time.Time) error {
+	userIDs := []string{userID}
+	if userID == "" {
+		var err error
+		if userIDs, err = s.templates.ScheduledUsers(); err != nil {
+			return err
+		}
+	}
+
+	for _, userID := range userIDs {
+		showers, err := s.templates.ShowersBetween(userID, now.Add(-s.cfg.Lookback), now.Add(-s.cfg.PromptDelay))
+		if err != nil {
+			return err
+		}
+		for _, shower := range showers {
+			answered, err := s.hasFeedback(userID, shower.ReadyBy)
+			if err != nil {
+				return err
+			}
+			if answered {
+				continue
+			}
+			notifyAt, err := s.notifications.NextAllowed(userID, shower.ReadyBy.Add(s.cfg.PromptDelay))
+			if err != nil {
+				return err
+			}
+			prompt := &models.FeedbackPrompt{
+				UserID:     userID,
+				TemplateID: shower.TemplateID,
+				Name:       shower.Name,
+				DeviceID:   shower.DeviceID,
+				Date:       shower.Date,
+				ShowerAt:   shower.ReadyBy,
+				Status:     models.FeedbackPromptPending,
+				NotifyAt:   notifyAt,
+			}
+			if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(prompt).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Pending returns the user's showers still waiting for feedback, newest first. Prompts for days
+// that got feedback in the meantime are closed.
+func (s *FeedbackPromptService) Pending(userID string, now time.Time) ([]models.FeedbackPrompt, error) {
+	if err := s.Enqueue(userID, now); err != nil {
+		return nil, err
+	}
+	var prompts []models.FeedbackPrompt
+	err := s.db.Where("user_id = ? AND status = ?", userID, models.FeedbackPromptPending).
+		Order("shower_at DESC").Find(&prompts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]models.FeedbackPrompt, 0, len(prompts))
+	for _, prompt := range prompts {
+		open, err := s.stillOpen(&prompt)
+		if err != nil {
+			return nil, err
+		}
+		if open {
+			pending = append(pending, prompt)
+		}
+	}
+	return pending, nil
+}
+
+// Dismiss stops prompting for one shower, e.g. because nobody showered
+func (s *FeedbackPromptService) Dismiss(userID, id string) error {
+	result := s.db.Model(&models.FeedbackPrompt{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, models.FeedbackPromptPending).
+		Update("status", models.FeedbackPromptDismissed)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFeedbackPromptNotFound
+	}
+	return nil
+}
+
+// notifyDue sends reminders that are due and haven't been sent. A reminder held back by quiet
+// hours is moved to when they end; one over the daily cap is given up on, and stays in the
+// pending list.
+func (s *FeedbackPromptService) notifyDue(now time.Time) {
+	if !s.notifications.Enabled() {
+		return
+	}
+	var due []models.FeedbackPrompt
+	err := s.db.Where("status = ? AND notified_at IS NULL AND notify_at <= ?", models.FeedbackPromptPending, now).
+		Order("notify_at ASC").Find(&due).Error
+	if err != nil {
+		log.Printf("Failed to list due feedback prompts: %v", err)
+		return
+	}
+	for _, prompt := range due {
+		if open, err := s.stillOpen(&prompt); err != nil || !open {
+			continue
+		}
+		entry, err := s.notifications.Notify(prompt.UserID, models.NotificationFeedbackPrompt, fmt.Sprintf("How was your %s shower?", prompt.Name), map[string]interface{}{
+			"promptId":   prompt.ID,
+			"templateId": prompt.TemplateID,
+			"deviceId":   prompt.DeviceID,
+			"date":       prompt.Date,
+			"showerAt":   prompt.ShowerAt,
+		}, now)
+		switch {
+		case err != nil:
+			log.Printf("Failed to send feedback reminder %s: %v", prompt.ID, err)
+			s.db.Model(&prompt).Update("notify_at", now.Add(feedbackPromptRetry))
+		case entry.Reason == models.SuppressedQuietHours:
+			if wake, err := s.notifications.NextAllowed(prompt.UserID, now); err == nil {
+				s.db.Model(&prompt).Update("notify_at", wake)
+			}
+		default:
+			s.db.Model(&prompt).Update("notified_at", now)
+		}
+	}
+}
+
+// stillOpen closes a prompt whose day got feedback, reporting whether it is still waiting
+func (s *FeedbackPromptService) stillOpen(prompt *models.FeedbackPrompt) (bool, error) {
+	answered, err := s.hasFeedback(prompt.UserID, prompt.ShowerAt)
+	if err != nil || !answered {
+		return !answered, err
+	}
+	prompt.Status = models.FeedbackPromptAnswered
+	return false, s.db.Model(prompt).Update("status", models.FeedbackPromptAnswered).Error
+}
+
+// hasFeedback reports whether the user has a record on at's date
+func (s *FeedbackPromptService) hasFeedback(userID string, at time.Time) (bool, error) {
+	day := startOfDay(at.Local())
+	var count int64
+	err := s.db.Model(&models.DailyRecord{}).
+		Where("user_id = ? AND date >= ? AND date < ?", userID, day, day.AddDate(0, 0, 1)).
+		Count(&count).Error
+	return count > 0, err
+}
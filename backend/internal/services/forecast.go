@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrNoSchedule is returned when the user has no schedule templates to forecast from
+	ErrNoSchedule = errors.New("no shower schedule to forecast")
+)
+
+// forecastDays is how many days a week forecast covers, today included
+const forecastDays = 7
+
+// defaultShowerDuration is the shower length, in minutes, assumed for users without feedback
+const defaultShowerDuration = 10
+
+// typicalDurationRecords is how many of the user's latest records their typical shower length
+// is taken from
+const typicalDurationRecords = 30
+
+// Ways a forecast shower's electricity is costed
+const (
+	ForecastPricingSpot = "spot" // day-ahead prices cover the heating run
+	ForecastPricingFlat = "flat" // the configured flat rate, as spot prices aren't published yet
+)
+
+// WeekForecastRequest asks for a week's heating forecast
+type WeekForecastRequest struct {
+	UserID   string
+	MemberID string
+	Duration float64   // shower length in minutes; the user's typical shower when 0
+	From     time.Time // the forecast starts here and runs to the end of the seventh day
+}
+
+// ForecastShower is one scheduled shower with the heating the predictor expects it to need
+type ForecastShower struct {
+	ScheduledShower
+	Temperature    *TemperatureReading `json:"temperature"` // the forecast for the hour the water is due, or the current reading without a forecast
+	HeatingMinutes float64             `json:"heatingMinutes"`
+	EnergyKWh      float64             `json:"energyKwh"`
+	Cost           *float64            `json:"cost,omitempty"`    // unknown without spot prices or a flat rate
+	Pricing        string              `json:"pricing,omitempty"` // spot or flat
+}
+
+// ForecastDay totals one day's forecast showers. Its cost is left out when any of its showers
+// couldn't be costed.
+type ForecastDay struct {
+	Date           string           `json:"date"` // YYYY-MM-DD
+	Showers        []ForecastShower `json:"showers"`
+	HeatingMinutes float64          `json:"heatingMinutes"`
+	EnergyKWh      float64          `json:"energyKwh"`
+	Cost           *float64         `json:"cost,omitempty"`
+}
+
+// WeekForecast is the heating expected over the next seven days, for budgeting
+type WeekForecast struct {
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Duration       float64       `json:"duration"` // shower length forecast, in minutes
+	Currency       string        `json:"currency,omitempty"`
+	Days           []ForecastDay `json:"days"`
+	HeatingMinutes float64       `json:"heatingMinutes"`
+	EnergyKWh      float64       `json:"energyKwh"`
+	Cost           *float64      `json:"cost,omitempty"`
+}
+
+// ForecastService forecasts heating for the coming week by running each shower in the user's
+// schedule through the predictor at the forecast outdoor temperature, and prices the energy at
+// day-ahead spot prices where they are published and a flat rate after that
+type ForecastService struct {
+	templates    *ScheduleTemplateService
+	predictor    Predictor
+	temperatures *TemperatureService
+	prices       *PriceService
+	boilers      *BoilerService
+	records      *RecordService
+}
+
+// NewForecastService creates a forecast service
+func NewForecastService(templates *ScheduleTemplateService, predictor Predictor, temperatures *TemperatureService, prices *PriceService, boilers *BoilerService, records *RecordService) *ForecastService {
+	return &ForecastService{
+		templates:    templates,
+		predictor:    predictor,
+		temperatures: temperatures,
+		prices:       prices,
+		boilers:      boilers,
+		records:      records,
+	}
+}
+
+// Week forecasts the user's scheduled showers from req.From to the end of the seventh day.
+// Days without a scheduled shower are included with nothing to heat.
+func (s *ForecastService) Week(ctx context.Context, req WeekForecastRequest) (*WeekForecast, error) {
+	templates, err := s.templates.ListTemplates(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, ErrNoSchedule
+	}
+
+	duration := req.Duration
+	if duration == 0 {
+		if duration, err = typicalDuration(s.records, req.UserID); err != nil {
+			return nil, err
+		}
+	}
+	from := req.From
+	to := startOfDay(from).AddDate(0, 0, forecastDays)
+	showers, err := s.templates.ShowersBetween(req.UserID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := s.prices.pricesBetween(ctx, from, to)
+	if err != nil && !errors.Is(err, ErrPricesDisabled) {
+		// The forecast is still useful at the flat rate
+		log.Printf("Forecast prices unavailable: %v", err)
+	}
+	flatRate, hasFlatRate := s.prices.FlatRate()
+
+	forecast := &WeekForecast{From: from, To: to, Duration: duration, Currency: s.prices.Currency(), Days: make([]ForecastDay, forecastDays)}
+	index := make(map[string]int, forecastDays)
+	for i := range forecast.Days {
+		date := startOfDay(from).AddDate(0, 0, i).Format("2006-01-02")
+		forecast.Days[i] = ForecastDay{Date: date, Showers: []ForecastShower{}, Cost: new(float64)}
+		index[date] = i
+	}
+
+	var current *TemperatureReading
+	for _, shower := range showers {
+		day, ok := index[shower.Date]
+		if !ok {
+			// A template in another time zone can put a shower on a date outside the week
+			continue
+		}
+		temperature, err := s.temperatures.ForecastAt(ctx, shower.ReadyBy)
+		if err != nil {
+			if current == nil {
+				if current, err = s.temperatures.Resolve(ctx, TemperatureQuery{UserID: req.UserID}); err != nil {
+					return nil, err
+				}
+			}
+			temperature = current
+		}
+
+		prediction, err := s.predictor.Predict(ctx, PredictionRequest{
+			UserID:      req.UserID,
+			MemberID:    req.MemberID,
+			DeviceID:    shower.DeviceID,
+			Duration:    duration,
+			Temperature: temperature.Value,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		power := s.boilers.HeaterPower(req.UserID, shower.DeviceID)
+		item := ForecastShower{
+			ScheduledShower: shower,
+			Temperature:     temperature,
+			HeatingMinutes:  prediction.HeatingTime,
+			EnergyKWh:       roundTo(power*prediction.HeatingTime/60, 3),
+		}
+		start := shower.ReadyBy.Add(-time.Duration(prediction.HeatingTime * float64(time.Minute)))
+		if cost, ok := spotCost(prices, power, start, shower.ReadyBy); ok {
+			item.Cost, item.Pricing = &cost, ForecastPricingSpot
+		} else if hasFlatRate {
+			cost := roundTo(item.EnergyKWh*flatRate, 4)
+			item.Cost, item.Pricing = &cost, ForecastPricingFlat
+		}
+		forecast.Days[day].add(item)
+	}
+
+	forecast.Cost = new(float64)
+	for i := range forecast.Days {
+		day := &forecast.Days[i]
+		forecast.HeatingMinutes = roundTo(forecast.HeatingMinutes+day.HeatingMinutes, 2)
+		forecast.EnergyKWh = roundTo(forecast.EnergyKWh+day.EnergyKWh, 3)
+		if day.Cost == nil {
+			forecast.Cost = nil
+		} else if forecast.Cost != nil {
+			*forecast.Cost = roundTo(*forecast.Cost+*day.Cost, 4)
+		}
+	}
+	return forecast, nil
+}
+
+// add counts a shower into the day's totals
+func (d *ForecastDay) add(shower ForecastShower) {
+	d.Showers = append(d.Showers, shower)
+	d.HeatingMinutes = roundTo(d.HeatingMinutes+shower.HeatingMinutes, 2)
+	d.EnergyKWh = roundTo(d.EnergyKWh+shower.EnergyKWh, 3)
+	if shower.Cost == nil {
+		d.Cost = nil
+	} else if d.Cost != nil {
+		*d.Cost = roundTo(*d.Cost+*shower.Cost, 4)
+	}
+}
+
+// typicalDuration is the median length of the user's latest showers
+func typicalDuration(records *RecordService, userID string) (float64, error) {
+	latest, err := records.GetRecordsForPredictionByUser(userID, typicalDurationRecords)
+	if err != nil {
+		return 0, err
+	}
+	if len(latest) == 0 {
+		return defaultShowerDuration, nil
+	}
+	durations := make([]float64, len(latest))
+	for i, record := range latest {
+		durations[i] = record.ShowerDuration
+	}
+	sort.Float64s(durations)
+	median := durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		median = (durations[len(durations)/2-1] + median) / 2
+	}
+	return roundTo(median, 1), nil
+}
+
+// spotCost prices power kW drawn from start to end at spot prices, reporting false unless the
+// prices cover all of it
+func spotCost(prices []EnergyPrice, power float64, start, end time.Time) (float64, bool) {
+	var covered time.Duration
+	cost := 0.0
+	for _, price := range prices {
+		from, until := maxTime(start, price.Start), minTime(end, price.End)
+		if until.After(from) {
+			covered += until.Sub(from)
+			cost += power * until.Sub(from).Hours() * price.Price
+		}
+	}
+	if covered < end.Sub(start) {
+		return 0, false
+	}
+	return roundTo(cost, 4), true
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+// coldPredictor heats 40 minutes less a minute per degree outside
+type coldPredictor struct{}
+
+func (coldPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	return &PredictionResponse{HeatingTime: 40 - req.Temperature}, nil
+}
+
+func TestForecastService_Week(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "forecast.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	// Each day is a degree warmer than the one before
+	weather := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "temperature_2m", r.URL.Query().Get("hourly"))
+		var body struct {
+			Hourly struct {
+				Time        []int64   `json:"time"`
+				Temperature []float64 `json:"temperature_2m"`
+			} `json:"hourly"`
+		}
+		for h := 0; h < 24*weatherForecastDays; h++ {
+			body.Hourly.Time = append(body.Hourly.Time, day.Add(time.Duration(h)*time.Hour).Unix())
+			body.Hourly.Temperature = append(body.Hourly.Temperature, float64(10+h/24))
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer weather.Close()
+	latitude, longitude := 32.1, 34.8
+	temperatures := NewTemperatureService(config.TemperatureConfig{Weather: config.WeatherConfig{URL: weather.URL, Latitude: &latitude, Longitude: &longitude}})
+
+	// Spot prices are only published for the first day: 100 EUR/MWh all day
+	spot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") != strconv.FormatInt(day.UnixMilli(), 10) {
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		w.Write([]byte(fmt.Sprintf(`{"data": [{"start_timestamp": %d, "end_timestamp": %d, "marketprice": 100}]}`,
+			day.UnixMilli(), day.AddDate(0, 0, 1).UnixMilli())))
+	}))
+	defer spot.Close()
+	prices := NewPriceService(config.PricesConfig{Provider: "awattar", URL: spot.URL, FlatRate: 0.3})
+
+	templates := NewScheduleTemplateService()
+	forecasts := NewForecastService(templates, coldPredictor{}, temperatures, prices,
+		NewBoilerService(config.DeviceConfig{HeaterPower: 2}, InletModel{}), NewRecordService())
+
+	_, err := forecasts.Week(context.Background(), WeekForecastRequest{UserID: "user1", From: day})
+	assert.ErrorIs(t, err, ErrNoSchedule)
+
+	// Weekday mornings only
+	require.NoError(t, templates.CreateTemplate(&models.ScheduleTemplate{UserID: "user1", Name: "Mornings", WeekdayTime: "07:00", Timezone: "UTC"}))
+	forecast, err := forecasts.Week(context.Background(), WeekForecastRequest{UserID: "user1", From: day.Add(5 * time.Hour)})
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(defaultShowerDuration), forecast.Duration)
+	assert.Equal(t, "EUR", forecast.Currency)
+	require.Len(t, forecast.Days, 7)
+	monday := forecast.Days[0]
+	assert.Equal(t, "2026-01-05", monday.Date)
+	require.Len(t, monday.Showers, 1)
+	assert.Equal(t, 10.0, monday.Showers[0].Temperature.Value)
+	assert.Equal(t, 30.0, monday.HeatingMinutes)
+	assert.Equal(t, 1.0, monday.EnergyKWh)
+	assert.Equal(t, ForecastPricingSpot, monday.Showers[0].Pricing)
+	require.NotNil(t, monday.Cost)
+	assert.InDelta(t, 0.1, *monday.Cost, 0.0001)
+
+	tuesday := forecast.Days[1]
+	assert.Equal(t, 29.0, tuesday.HeatingMinutes)
+	assert.Equal(t, ForecastPricingFlat, tuesday.Showers[0].Pricing)
+	assert.InDelta(t, 0.967*0.3, *tuesday.Cost, 0.0001)
+
+	// No showers at the weekend
+	assert.Equal(t, "2026-01-10", forecast.Days[5].Date)
+	assert.Empty(t, forecast.Days[5].Showers)
+	assert.Equal(t, 0.0, forecast.Days[5].EnergyKWh)
+
+	assert.Equal(t, 30.0+29+28+27+26, forecast.HeatingMinutes)
+	require.NotNil(t, forecast.Cost)
+	assert.InDelta(t, 0.1+(29+28+27+26)/30.0*0.3, *forecast.Cost, 0.001)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// globalSnapshotSize is how many of the latest records a snapshot holds: the 1200 a v2
+// prediction reads, with as many again to spare for the requesting user's own, which are left out
+const globalSnapshotSize = 2400
+
+// globalRecordSet is one loaded snapshot
+type globalRecordSet struct {
+	records  []models.DailyRecord // newest first
+	loadedAt time.Time
+}
+
+// GlobalSnapshot holds everyone's latest records in memory for the v2 predictor's global
+// pool, reloading them every interval instead of on every prediction. The pool changes slowly:
+// a new record reaches other users' predictions by the next refresh at the latest. A refresh
+// swaps the whole set at once, so predictions never see a half-loaded one.
+type GlobalSnapshot struct {
+	records  RecordServiceInterface
+	interval time.Duration
+	current  atomic.Pointer[globalRecordSet]
+}
+
+// NewGlobalSnapshot creates a snapshot of the records, refreshed every interval once started
+func NewGlobalSnapshot(records RecordServiceInterface, interval time.Duration) *GlobalSnapshot {
+	return &GlobalSnapshot{records: records, interval: interval}
+}
+
+// Start loads the snapshot and keeps refreshing it in the background. A failed refresh keeps
+// the previous set.
+func (g *GlobalSnapshot) Start() {
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			if err := g.Refresh(); err != nil {
+				log.Printf("Failed to refresh global prediction records: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Refresh reloads the snapshot now
+func (g *GlobalSnapshot) Refresh() error {
+	records, err := g.records.GetGlobalRecordsForPrediction("", globalSnapshotSize)
+	if err != nil {
+		return err
+	}
+	g.current.Store(&globalRecordSet{records: records, loadedAt: time.Now()})
+	return nil
+}
+
+// LoadedAt returns when the snapshot was last refreshed, zero before it first loaded
+func (g *GlobalSnapshot) LoadedAt() time.Time {
+	if set := g.current.Load(); set != nil {
+		return set.loadedAt
+	}
+	return time.Time{}
+}
+
+// Records returns the latest limit records of everyone but the user, newest first. It reports
+// false before the snapshot has loaded, and when the user's own records leave fewer than limit
+// of a full snapshot, in which case the caller should read the database.
+func (g *GlobalSnapshot) Records(excludeUserID string, limit int) ([]models.DailyRecord, bool) {
+	set := g.current.Load()
+	if set == nil {
+		return nil, false
+	}
+	records := make([]models.DailyRecord, 0, min(limit, len(set.records)))
+	for _, record := range set.records {
+		if len(records) == limit {
+			return records, true
+		}
+		if excludeUserID == "" || record.UserID != excludeUserID {
+			records = append(records, record)
+		}
+	}
+	// Running out of a set that wasn't full means there are no more records
+	return records, len(records) == limit || len(set.records) < globalSnapshotSize
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// poolRecords serves a fixed list of records, newest first, counting global reads
+type poolRecords struct {
+	records []models.DailyRecord
+	reads   int
+}
+
+func (p *poolRecords) GetRecordsForPredictionByUser(userID string, limit int) ([]models.DailyRecord, error) {
+	return nil, nil
+}
+
+func (p *poolRecords) GetGlobalRecordsForPrediction(excludeUserID string, limit int) ([]models.DailyRecord, error) {
+	p.reads++
+	var records []models.DailyRecord
+	for _, record := range p.records {
+		if len(records) < limit && record.UserID != excludeUserID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (p *poolRecords) GetRecordsForPrediction(limit int) ([]models.DailyRecord, error) {
+	return p.records[:min(limit, len(p.records))], nil
+}
+
+func TestGlobalSnapshot(t *testing.T) {
+	pool := &poolRecords{}
+	for i := 0; i < 10; i++ {
+		pool.records = append(pool.records, models.DailyRecord{ID: fmt.Sprint(i), UserID: fmt.Sprintf("user%d", i%2)})
+	}
+	snapshot := NewGlobalSnapshot(pool, 0)
+
+	_, ok := snapshot.Records("user0", 3)
+	assert.False(t, ok, "nothing is served before the first load")
+
+	want, _ := pool.GetGlobalRecordsForPrediction("user0", 3)
+	pool.reads = 0
+	require.NoError(t, snapshot.Refresh())
+	records, ok := snapshot.Records("user0", 3)
+	require.True(t, ok)
+	assert.Equal(t, want, records)
+	assert.False(t, snapshot.LoadedAt().IsZero())
+
+	// New records show up with the next refresh
+	pool.records = append([]models.DailyRecord{{ID: "new", UserID: "user1"}}, pool.records...)
+	records, _ = snapshot.Records("user0", 3)
+	assert.Equal(t, "1", records[0].ID)
+	require.NoError(t, snapshot.Refresh())
+	records, _ = snapshot.Records("user0", 3)
+	assert.Equal(t, "new", records[0].ID)
+	assert.Equal(t, 2, pool.reads)
+
+	// A snapshot that isn't full holds every record, so fewer than asked for is the answer
+	records, ok = snapshot.Records("user0", 100)
+	assert.True(t, ok)
+	assert.Len(t, records, 6)
+}
+
+func TestGlobalSnapshot_FallsBackWhenTheUserFillsIt(t *testing.T) {
+	pool := &poolRecords{}
+	for i := 0; i < globalSnapshotSize+10; i++ {
+		userID := "busy"
+		if i%10 == 0 {
+			userID = "other"
+		}
+		pool.records = append(pool.records, models.DailyRecord{ID: fmt.Sprint(i), UserID: userID})
+	}
+	snapshot := NewGlobalSnapshot(pool, 0)
+	require.NoError(t, snapshot.Refresh())
+
+	_, ok := snapshot.Records("busy", 1200)
+	assert.False(t, ok, "the busy user's records crowd the others out of the snapshot")
+	records, ok := snapshot.Records("other", 1200)
+	assert.True(t, ok)
+	assert.Len(t, records, 1200)
+}
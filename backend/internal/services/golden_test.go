@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/internal/testsupport"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// goldenReplayShowers is how many of each dataset's last showers are predicted from the history
+// before them
+const goldenReplayShowers = 40
+
+// neededHeating is the heating time a golden shower needed, read back from how its heating time
+// felt: the datasets rate a shower 120 points hotter per 100% of extra heating
+func neededHeating(record models.DailyRecord) float64 {
+	return record.HeatingTime / (1 + (record.Satisfaction-models.SatisfactionPerfect)/120)
+}
+
+// replayGolden predicts each of the dataset's last showers from the history before it, returning
+// the mean relative error against the heating the showers needed
+func replayGolden(t *testing.T, name string, predictor func(records RecordServiceInterface) Predictor) float64 {
+	dataset := testsupport.MustLoad(name, time.Now())
+	showers := dataset.Records[len(dataset.Records)-goldenReplayShowers:]
+
+	var totalError float64
+	for _, shower := range showers {
+		history := dataset.Before(shower.Date)
+		prediction, err := predictor(history).Predict(context.Background(), PredictionRequest{
+			UserID:           shower.UserID,
+			MemberID:         shower.MemberID,
+			DeviceID:         shower.DeviceID,
+			Duration:         shower.ShowerDuration,
+			Temperature:      shower.AverageTemperature,
+			InletTemperature: shower.InletTemperature,
+		})
+		require.NoError(t, err)
+		require.False(t, math.IsNaN(prediction.HeatingTime))
+		needed := neededHeating(shower)
+		totalError += math.Abs(prediction.HeatingTime-needed) / needed
+	}
+	return totalError / goldenReplayShowers
+}
+
+// goldenErrorCeilings are the mean relative errors each predictor stays under on each dataset,
+// a little above what they reach today. Tighten them when a change improves a predictor.
+var goldenErrorCeilings = map[string]map[string]float64{
+	testsupport.SingleUserWinter:    {"v1": 0.18, "v2": 0.20},
+	testsupport.HouseholdTwoSeasons: {"v1": 0.24, "v2": 0.38},
+	testsupport.MultiUserYear:       {"v1": 0.29, "v2": 0.55},
+}
+
+func TestGoldenDatasets_Replay(t *testing.T) {
+	predictors := map[string]func(records RecordServiceInterface) Predictor{
+		"v1": func(records RecordServiceInterface) Predictor { return NewPredictionService(records) },
+		"v2": func(records RecordServiceInterface) Predictor {
+			return NewPredictionServiceV2(records, &PredictionConfigV2{DisableExploration: true})
+		},
+	}
+	for _, name := range testsupport.Datasets {
+		for version, predictor := range predictors {
+			t.Run(name+"/"+version, func(t *testing.T) {
+				meanError := replayGolden(t, name, predictor)
+				t.Logf("mean relative error %.1f%%", meanError*100)
+				assert.Less(t, meanError, goldenErrorCeilings[name][version])
+			})
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// HeatingNoticeService tells a water heater's users when it starts heating, so they know when
+// to expect hot water. The notifications are opt-in: they only go over routes naming
+// heating_started.
+type HeatingNoticeService struct {
+	db            *gorm.DB
+	notifications *NotificationService
+	now           func() time.Time
+}
+
+// NewHeatingNoticeService creates a new heating notice service instance
+func NewHeatingNoticeService(notifications *NotificationService) *HeatingNoticeService {
+	return &HeatingNoticeService{
+		db:            database.GetDB(),
+		notifications: notifications,
+		now:           time.Now,
+	}
+}
+
+// HeatingStarted notifies the device's users in the background; register it with
+// DeviceStateService.OnHeatingStarted
+func (s *HeatingNoticeService) HeatingStarted(deviceID string, status DeviceStatus) {
+	go func() {
+		if err := s.Notify(deviceID, status); err != nil {
+			log.Printf("Failed to send heating notifications for device %s: %v", deviceID, err)
+		}
+	}()
+}
+
+// Notify sends a heating_started notification to everyone using the device: users with a
+// boiler profile for it or records on it
+func (s *HeatingNoticeService) Notify(deviceID string, status DeviceStatus) error {
+	if !s.notifications.Enabled() {
+		return nil
+	}
+	users, err := s.deviceUsers(deviceID)
+	if err != nil {
+		return err
+	}
+
+	message := "Your water heater started heating"
+	if status.ReadyAt != nil {
+		message += fmt.Sprintf(" and should be ready around %s", status.ReadyAt.Local().Format("15:04"))
+	}
+	data := map[string]interface{}{
+		"deviceId": deviceID,
+		"readyAt":  status.ReadyAt,
+	}
+	for _, userID := range users {
+		if _, err := s.notifications.Notify(userID, models.NotificationHeatingStarted, message, data, s.now()); err != nil {
+			log.Printf("Failed to notify %s that device %s started heating: %v", userID, deviceID, err)
+		}
+	}
+	return nil
+}
+
+// deviceUsers lists the users of a device
+func (s *HeatingNoticeService) deviceUsers(deviceID string) ([]string, error) {
+	var profiled, recorded []string
+	if err := s.db.Model(&models.BoilerProfile{}).Where("device_id = ?", deviceID).Distinct().Pluck("user_id", &profiled).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.DailyRecord{}).Where("device_id = ?", deviceID).Distinct().Pluck("user_id", &recorded).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var users []string
+	for _, userID := range append(profiled, recorded...) {
+		if !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
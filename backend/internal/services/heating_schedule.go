@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// ErrNoScheduleData is returned when neither spot prices nor a solar forecast cover the time
+// before the deadline, so every start time is as good as any other
+var ErrNoScheduleData = errors.New("no energy prices or solar forecast to schedule by")
+
+// ScheduleRequest asks when to heat
+type ScheduleRequest struct {
+	UserID   string
+	DeviceID string  // picks the calibrated heater power and insulation, if known
+	Minutes  float64 // heating time
+	Earliest time.Time
+	ReadyBy  time.Time
+}
+
+// HeatingSlot is a possible heating run, what its electricity costs and how much of its heat
+// is left when the shower starts
+type HeatingSlot struct {
+	Start        time.Time     `json:"start"`
+	End          time.Time     `json:"end"`
+	AveragePrice float64       `json:"averagePrice,omitempty"` // spot price per kWh, weighted by time spent in each price interval
+	Currency     string        `json:"currency,omitempty"`
+	Prices       []EnergyPrice `json:"prices,omitempty"` // the price intervals the run overlaps
+	EnergyKWh    float64       `json:"energyKwh"`
+	SolarShare   float64       `json:"solarShare"`     // share of the energy covered by forecast solar surplus
+	Cost         *float64      `json:"cost,omitempty"` // grid energy at spot price plus solar energy at the feed-in price; unknown without prices
+	HeatRetained float64       `json:"heatRetained"`   // share of the heat still in the tank at readyBy
+	WaitMinutes  float64       `json:"waitMinutes"`    // between the end of heating and readyBy
+	TopUpMinutes float64       `json:"topUpMinutes"`   // heating needed right before readyBy to make up for the heat lost waiting
+
+	gridKWh  float64
+	retained float64
+}
+
+// HeatingSchedule is the recommended run, with heating right before the deadline for comparison
+type HeatingSchedule struct {
+	HeatingSlot
+	Latest *HeatingSlot `json:"latest,omitempty"` // omitted when the recommendation is already the latest start
+
+	DemandResponse []models.DemandResponseEvent `json:"demandResponse,omitempty"` // events the run was kept out of
+}
+
+// HeatingScheduler picks when to heat from spot prices and forecast solar surplus. Heating
+// early can be cheaper but the tank cools while it waits, and the lost heat has to be made up
+// right before the shower, so each run is charged for that top-up at the latest run's price.
+type HeatingScheduler struct {
+	prices   *PriceService
+	solar    *SolarService
+	boilers  *BoilerService
+	residual ResidualHeatModel
+	clock    Clock
+	events   *DemandResponseService
+}
+
+// NewHeatingScheduler creates a heating scheduler
+func NewHeatingScheduler(prices *PriceService, solar *SolarService, boilers *BoilerService, residual ResidualHeatModel) *HeatingScheduler {
+	return &HeatingScheduler{
+		prices:   prices,
+		solar:    solar,
+		boilers:  boilers,
+		residual: residual,
+		clock:    SystemClock,
+	}
+}
+
+// SetClock sets the clock runs are planned from
+func (s *HeatingScheduler) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// SetDemandResponse keeps runs out of the demand-response events that shift heating, or cap it
+// below the run's length
+func (s *HeatingScheduler) SetDemandResponse(events *DemandResponseService) {
+	s.events = events
+}
+
+// Now returns the scheduler's current time, the earliest start when none is asked for
+func (s *HeatingScheduler) Now() time.Time {
+	return s.clock.Now()
+}
+
+// Recommend finds the best start between req.Earliest and the latest start that still has the
+// water ready by req.ReadyBy. Ties go to the later start. Runs never overlap a demand-response
+// event that holds them back; when every run would, ErrHeatingCurtailed is returned.
+func (s *HeatingScheduler) Recommend(ctx context.Context, req ScheduleRequest) (*HeatingSchedule, error) {
+	length := time.Duration(req.Minutes * float64(time.Minute))
+	latest := req.ReadyBy.Add(-length)
+	if latest.Before(req.Earliest) {
+		latest = req.Earliest
+	}
+
+	prices, err := s.prices.pricesBetween(ctx, req.Earliest, req.ReadyBy)
+	if err != nil && !errors.Is(err, ErrPricesDisabled) {
+		return nil, err
+	}
+	hasSolar := s.solar.Covers(req.Earliest, latest.Add(length))
+	if len(prices) == 0 && !hasSolar {
+		if errors.Is(err, ErrPricesDisabled) {
+			return nil, ErrNoScheduleData
+		}
+		return nil, ErrNoPrices
+	}
+
+	// Costs only change where a price or the solar forecast does, so the best run starts or
+	// ends on such a boundary, or sits at the edge of the allowed range
+	var boundaries []time.Time
+	for _, price := range prices {
+		boundaries = append(boundaries, price.Start, price.End)
+	}
+	boundaries = append(boundaries, s.solar.boundaries(req.Earliest, latest.Add(length))...)
+	var blocked []models.DemandResponseEvent
+	if s.events != nil {
+		blocked = s.events.blocking(req.Earliest, req.ReadyBy, req.Minutes)
+	}
+	curtailed := func(start time.Time) bool {
+		for _, event := range blocked {
+			if event.StartsAt.Before(start.Add(length)) && event.EndsAt.After(start) {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidates := []time.Time{req.Earliest, latest}
+	// Right after an event, or finishing right as one starts
+	for _, event := range blocked {
+		for _, start := range []time.Time{event.EndsAt, event.StartsAt.Add(-length)} {
+			if !start.Before(req.Earliest) && !start.After(latest) {
+				candidates = append(candidates, start)
+			}
+		}
+	}
+	for _, boundary := range boundaries {
+		for _, start := range []time.Time{boundary, boundary.Add(-length)} {
+			if !start.Before(req.Earliest) && !start.After(latest) {
+				candidates = append(candidates, start)
+			}
+		}
+	}
+
+	power := s.boilers.HeaterPower(req.UserID, req.DeviceID)
+	var slots []*HeatingSlot
+	for _, start := range candidates {
+		if curtailed(start) {
+			continue
+		}
+		if slot, ok := s.evaluate(req, prices, boundaries, power, start, start.Add(length)); ok {
+			slots = append(slots, slot)
+		}
+	}
+	if len(slots) == 0 {
+		if len(blocked) > 0 {
+			return nil, ErrHeatingCurtailed
+		}
+		return nil, ErrNoPrices
+	}
+	latestSlot, latestOK := s.evaluate(req, prices, boundaries, power, latest, latest.Add(length))
+	latestOK = latestOK && !curtailed(latest)
+
+	// Heat lost while waiting is bought again at the deadline; without prices the score is
+	// the energy drawn from the grid
+	score := func(slot *HeatingSlot) float64 {
+		lost := slot.EnergyKWh * (1 - slot.retained)
+		if slot.Cost == nil {
+			return slot.gridKWh + lost
+		}
+		topUpPrice := slot.AveragePrice
+		if latestOK {
+			topUpPrice = latestSlot.AveragePrice
+		}
+		return *slot.Cost + lost*topUpPrice
+	}
+
+	best := slots[0]
+	bestScore := score(best)
+	for _, slot := range slots[1:] {
+		if slotScore := score(slot); slotScore < bestScore || (slotScore == bestScore && slot.Start.After(best.Start)) {
+			best, bestScore = slot, slotScore
+		}
+	}
+
+	schedule := &HeatingSchedule{HeatingSlot: *best, DemandResponse: blocked}
+	if latestOK && !best.Start.Equal(latest) {
+		schedule.Latest = latestSlot
+	}
+	return schedule, nil
+}
+
+// evaluate costs a run from start to end with the heater drawing power kW. A run that isn't
+// fully priced while prices are in use isn't a candidate.
+func (s *HeatingScheduler) evaluate(req ScheduleRequest, prices []EnergyPrice, boundaries []time.Time, power float64, start, end time.Time) (*HeatingSlot, bool) {
+	cuts := []time.Time{start, end}
+	for _, boundary := range boundaries {
+		if boundary.After(start) && boundary.Before(end) {
+			cuts = append(cuts, boundary)
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].Before(cuts[j]) })
+
+	slot := &HeatingSlot{Start: start, End: end}
+	var gridKWh, solarKWh, cost, weightedPrice float64
+	for i := 1; i < len(cuts); i++ {
+		hours := cuts[i].Sub(cuts[i-1]).Hours()
+		if hours <= 0 {
+			continue
+		}
+		mid := cuts[i-1].Add(cuts[i].Sub(cuts[i-1]) / 2)
+		solarKW := math.Min(s.solar.SurplusAt(mid)/1000, power)
+		gridKWh += (power - solarKW) * hours
+		solarKWh += solarKW * hours
+		if len(prices) > 0 {
+			price, ok := priceAt(prices, mid)
+			if !ok {
+				return nil, false
+			}
+			weightedPrice += price * hours
+			cost += (power-solarKW)*hours*price + solarKW*hours*s.solar.FeedInPrice()
+		}
+	}
+
+	slot.EnergyKWh = roundTo(gridKWh+solarKWh, 3)
+	if gridKWh+solarKWh > 0 {
+		slot.SolarShare = roundTo(solarKWh/(gridKWh+solarKWh), 3)
+	}
+	wait := req.ReadyBy.Sub(end)
+	if wait < 0 {
+		wait = 0
+	}
+	slot.WaitMinutes = roundTo(wait.Minutes(), 1)
+	slot.retained = s.residual.Retained(req.DeviceID, wait)
+	slot.HeatRetained = roundTo(slot.retained, 3)
+	slot.TopUpMinutes = roundTo(req.Minutes*(1-slot.retained), 1)
+	slot.gridKWh = gridKWh
+
+	if len(prices) > 0 {
+		for _, price := range prices {
+			if price.Start.Before(end) && price.End.After(start) {
+				slot.Prices = append(slot.Prices, price)
+			}
+		}
+		slot.AveragePrice = roundTo(weightedPrice/end.Sub(start).Hours(), 5)
+		slot.Currency = s.prices.Currency()
+		rounded := roundTo(cost, 4)
+		slot.Cost = &rounded
+	}
+	return slot, true
+}
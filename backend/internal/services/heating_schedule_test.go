@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeatingScheduler_CheapestPrices(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	// 02:00-04:00 is the cheapest stretch before 07:00
+	requests := 0
+	server := newAwattarServer(t, day, []float64{120, 90, 40, 50, 80, 150, 200, 210}, &requests)
+	scheduler := NewHeatingScheduler(
+		NewPriceService(config.PricesConfig{Provider: "awattar", URL: server.URL}),
+		NewSolarService(config.SolarConfig{}),
+		NewBoilerService(config.DeviceConfig{HeaterPower: 2}, InletModel{}),
+		ResidualHeatModel{},
+	)
+
+	schedule, err := scheduler.Recommend(context.Background(), ScheduleRequest{Minutes: 90, Earliest: day, ReadyBy: day.Add(7 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, day.Add(2*time.Hour), schedule.Start.UTC())
+	assert.Equal(t, day.Add(3*time.Hour+30*time.Minute), schedule.End.UTC())
+	assert.InDelta(t, (0.040*60+0.050*30)/90, schedule.AveragePrice, 0.0001)
+	assert.Equal(t, "EUR", schedule.Currency)
+	assert.Len(t, schedule.Prices, 2)
+	require.NotNil(t, schedule.Cost)
+	assert.InDelta(t, 2*(0.040+0.050/2), *schedule.Cost, 0.0001)
+
+	// The alternative is heating right before the deadline
+	require.NotNil(t, schedule.Latest)
+	assert.Equal(t, day.Add(5*time.Hour+30*time.Minute), schedule.Latest.Start.UTC())
+
+	// Hours without prices can't be scheduled
+	_, err = scheduler.Recommend(context.Background(), ScheduleRequest{Minutes: 60, Earliest: day.Add(8 * time.Hour), ReadyBy: day.Add(10 * time.Hour)})
+	assert.ErrorIs(t, err, ErrNoPrices)
+}
+
+func TestHeatingScheduler_SolarSurplus(t *testing.T) {
+	day := time.Date(2026, 6, 5, 0, 0, 0, 0, time.UTC)
+	solar := NewSolarService(config.SolarConfig{BaseLoad: 300})
+	solar.SetForecast([]SolarPoint{{Start: day.Add(10 * time.Hour), End: day.Add(14 * time.Hour), Watts: 3000}})
+	scheduler := NewHeatingScheduler(
+		NewPriceService(config.PricesConfig{}),
+		solar,
+		NewBoilerService(config.DeviceConfig{HeaterPower: 2}, InletModel{}),
+		ResidualHeatModel{HalfLife: 8 * time.Hour},
+	)
+
+	// Heating on solar as late as the surplus lasts beats the grid at the deadline, even after
+	// five hours of cooling
+	schedule, err := scheduler.Recommend(context.Background(), ScheduleRequest{Minutes: 60, Earliest: day.Add(6 * time.Hour), ReadyBy: day.Add(19 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, day.Add(13*time.Hour), schedule.Start)
+	assert.Equal(t, 1.0, schedule.SolarShare)
+	assert.Nil(t, schedule.Cost)
+	assert.Equal(t, 300.0, schedule.WaitMinutes)
+	assert.InDelta(t, 0.648, schedule.HeatRetained, 0.001)
+	assert.InDelta(t, 21.1, schedule.TopUpMinutes, 0.1)
+	require.NotNil(t, schedule.Latest)
+	assert.Equal(t, day.Add(18*time.Hour), schedule.Latest.Start)
+	assert.Equal(t, 0.0, schedule.Latest.SolarShare)
+
+	// After the surplus there is nothing to choose by
+	_, err = scheduler.Recommend(context.Background(), ScheduleRequest{Minutes: 60, Earliest: day.Add(15 * time.Hour), ReadyBy: day.Add(19 * time.Hour)})
+	assert.ErrorIs(t, err, ErrNoScheduleData)
+}
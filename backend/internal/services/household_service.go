@@ -0,0 +1,394 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrHouseholdInviteNotFound is returned when an invite token or ID is unknown
+	ErrHouseholdInviteNotFound = errors.New("household invite not found")
+	// ErrHouseholdInviteExpired is returned when accepting an invite past its expiry
+	ErrHouseholdInviteExpired = errors.New("household invite has expired")
+	// ErrHouseholdInviteUsed is returned when accepting an invite that was accepted or revoked
+	ErrHouseholdInviteUsed = errors.New("household invite is no longer valid")
+	// ErrHouseholdInviteEmail is returned when an invite for one email address is accepted by a user with another
+	ErrHouseholdInviteEmail = errors.New("household invite is for another email address")
+	// ErrHouseholdRole is returned for a role that isn't owner or member
+	ErrHouseholdRole = errors.New("role must be owner or member")
+	// ErrHouseholdNotOwner is returned when someone other than an owner manages the household
+	ErrHouseholdNotOwner = errors.New("only household owners can do this")
+	// ErrHouseholdJoined is returned when joining a household the user already belongs to one
+	ErrHouseholdJoined = errors.New("user already belongs to a household")
+	// ErrHouseholdHasMembers is returned when a household others have joined tries to join another
+	ErrHouseholdHasMembers = errors.New("a household others have joined can't join another")
+	// ErrHouseholdOwnAccount is returned when leaving the household the user's own account is
+	ErrHouseholdOwnAccount = errors.New("users can't leave their own household")
+)
+
+// HouseholdLookup finds the accounts whose devices and schedules a user shares
+type HouseholdLookup interface {
+	HouseholdUserIDs(userID string) ([]string, error)
+}
+
+// HouseholdUser is one account in a household
+type HouseholdUser struct {
+	UserID   string     `json:"userId"`
+	Name     string     `json:"name,omitempty"`
+	Email    string     `json:"email,omitempty"`
+	Role     string     `json:"role"`
+	JoinedAt *time.Time `json:"joinedAt,omitempty"` // nil for the household account itself
+}
+
+// Household is a household account with everyone who joined it and the devices they share
+type Household struct {
+	ID      string          `json:"id"`
+	Role    string          `json:"role"` // the requesting user's
+	Users   []HouseholdUser `json:"users"`
+	Devices []string        `json:"devices"`
+}
+
+// HouseholdService lets other accounts join a household through single-use invites. Everyone
+// in a household sees its schedules and predicts with its devices' calibration. A household is
+// the account the others joined, whose owner role can't be taken away.
+type HouseholdService struct {
+	db            *gorm.DB
+	inviteTTL     time.Duration
+	notifications *NotificationService
+}
+
+// NewHouseholdService creates a household service whose invites last at most inviteTTL
+func NewHouseholdService(inviteTTL time.Duration) *HouseholdService {
+	return &HouseholdService{
+		db:        database.GetDB(),
+		inviteTTL: inviteTTL,
+	}
+}
+
+// SetNotifications has invites sent to the invited user when their email address is known
+func (s *HouseholdService) SetNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// Membership returns the household the user is in and their role in it. A user who hasn't
+// joined one owns the household of their own account.
+func (s *HouseholdService) Membership(userID string) (householdID, role string, err error) {
+	var membership models.HouseholdMembership
+	err = s.db.Where("user_id = ?", userID).Limit(1).Find(&membership).Error
+	if err != nil {
+		return "", "", err
+	}
+	if membership.HouseholdID == "" {
+		return userID, models.HouseholdRoleOwner, nil
+	}
+	return membership.HouseholdID, membership.Role, nil
+}
+
+// HouseholdUserIDs returns the household account of the user's household first, then everyone
+// who joined it in the order they joined
+func (s *HouseholdService) HouseholdUserIDs(userID string) ([]string, error) {
+	householdID, _, err := s.Membership(userID)
+	if err != nil {
+		return nil, err
+	}
+	var joined []string
+	err = s.db.Model(&models.HouseholdMembership{}).
+		Where("household_id = ?", householdID).Order("joined_at ASC").
+		Pluck("user_id", &joined).Error
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{householdID}, joined...), nil
+}
+
+// Household returns the user's household
+func (s *HouseholdService) Household(userID string) (*Household, error) {
+	householdID, role, err := s.Membership(userID)
+	if err != nil {
+		return nil, err
+	}
+	var memberships []models.HouseholdMembership
+	err = s.db.Where("household_id = ?", householdID).Order("joined_at ASC").Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []string{householdID}
+	for _, membership := range memberships {
+		userIDs = append(userIDs, membership.UserID)
+	}
+	var accounts []models.User
+	if err := s.db.Where("id IN ?", userIDs).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.User, len(accounts))
+	for _, account := range accounts {
+		byID[account.ID] = account
+	}
+
+	household := &Household{ID: householdID, Role: role}
+	household.Users = append(household.Users, HouseholdUser{
+		UserID: householdID,
+		Name:   byID[householdID].Name,
+		Email:  byID[householdID].Email,
+		Role:   models.HouseholdRoleOwner,
+	})
+	for _, membership := range memberships {
+		joinedAt := membership.JoinedAt
+		household.Users = append(household.Users, HouseholdUser{
+			UserID:   membership.UserID,
+			Name:     byID[membership.UserID].Name,
+			Email:    byID[membership.UserID].Email,
+			Role:     membership.Role,
+			JoinedAt: &joinedAt,
+		})
+	}
+	if household.Devices, err = s.devices(userIDs); err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+// devices returns the IDs of the devices the users set up, schedule or log showers on, sorted
+func (s *HouseholdService) devices(userIDs []string) ([]string, error) {
+	devices := []string{}
+	for _, model := range []interface{}{&models.BoilerProfile{}, &models.ScheduleTemplate{}, &models.DailyRecord{}} {
+		var ids []string
+		err := s.db.Model(model).Distinct("device_id").
+			Where("user_id IN ? AND device_id <> ''", userIDs).
+			Pluck("device_id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if !slices.Contains(devices, id) {
+				devices = append(devices, id)
+			}
+		}
+	}
+	sort.Strings(devices)
+	return devices, nil
+}
+
+// CreateInvite creates an invite to the user's household that assigns role to whoever accepts
+// it, optionally only a user with the email address. The invite expires after ttl, capped at
+// the configured maximum, which is also the default. The token is returned only here.
+func (s *HouseholdService) CreateInvite(userID, email, role string, ttl time.Duration) (string, *models.HouseholdInvite, error) {
+	if role == "" {
+		role = models.HouseholdRoleMember
+	}
+	if !slices.Contains(models.HouseholdRoles, role) {
+		return "", nil, ErrHouseholdRole
+	}
+	householdID, callerRole, err := s.Membership(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if callerRole != models.HouseholdRoleOwner {
+		return "", nil, ErrHouseholdNotOwner
+	}
+	if ttl <= 0 || ttl > s.inviteTTL {
+		ttl = s.inviteTTL
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(raw)
+	invite := &models.HouseholdInvite{
+		HouseholdID: householdID,
+		TokenHash:   hashSessionToken(token),
+		Email:       strings.TrimSpace(email),
+		Role:        role,
+		CreatedBy:   userID,
+		ExpiresAt:   time.Now().Add(ttl).Truncate(time.Second),
+	}
+	if err := s.db.Create(invite).Error; err != nil {
+		return "", nil, err
+	}
+	return token, invite, nil
+}
+
+// NotifyInvitee sends the invite to the user with its email address, reporting whether it
+// went out. Invites without an email, or to an address no user has, aren't sent.
+func (s *HouseholdService) NotifyInvitee(invite *models.HouseholdInvite, link string) (bool, error) {
+	if s.notifications == nil || !s.notifications.Enabled() || invite.Email == "" {
+		return false, nil
+	}
+	var invitee models.User
+	err := s.db.Where("LOWER(email) = ?", strings.ToLower(invite.Email)).Limit(1).Find(&invitee).Error
+	if err != nil || invitee.ID == "" {
+		return false, err
+	}
+	entry, err := s.notifications.Notify(invitee.ID, models.NotificationHouseholdInvite,
+		fmt.Sprintf("You were invited to join %s's household", s.displayName(invite.CreatedBy)),
+		map[string]interface{}{
+			"inviteId":    invite.ID,
+			"householdId": invite.HouseholdID,
+			"role":        invite.Role,
+			"link":        link,
+			"expiresAt":   invite.ExpiresAt,
+		}, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return entry.Status == models.NotificationSent, nil
+}
+
+// displayName returns the user's name, or their ID when they have none
+func (s *HouseholdService) displayName(userID string) string {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).Limit(1).Find(&user).Error; err == nil && user.Name != "" {
+		return user.Name
+	}
+	return userID
+}
+
+// ListInvites returns the invites to the user's household that can still be accepted, newest
+// first. Only owners see them.
+func (s *HouseholdService) ListInvites(userID string) ([]models.HouseholdInvite, error) {
+	householdID, role, err := s.Membership(userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.HouseholdRoleOwner {
+		return nil, ErrHouseholdNotOwner
+	}
+	var invites []models.HouseholdInvite
+	err = s.db.Where("household_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", householdID, time.Now()).
+		Order("created_at DESC").Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite stops an invite to the user's household from being accepted
+func (s *HouseholdService) RevokeInvite(userID, id string) (*models.HouseholdInvite, error) {
+	householdID, role, err := s.Membership(userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.HouseholdRoleOwner {
+		return nil, ErrHouseholdNotOwner
+	}
+	var invite models.HouseholdInvite
+	err = s.db.Where("id = ? AND household_id = ?", id, householdID).First(&invite).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHouseholdInviteNotFound
+		}
+		return nil, err
+	}
+	if invite.AcceptedAt != nil || invite.RevokedAt != nil {
+		return nil, ErrHouseholdInviteUsed
+	}
+	now := time.Now()
+	invite.RevokedAt = &now
+	if err := s.db.Model(&invite).Update("revoked_at", &now).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// Invite returns the invite for a token while it can still be accepted
+func (s *HouseholdService) Invite(token string) (*models.HouseholdInvite, error) {
+	var invite models.HouseholdInvite
+	err := s.db.Where("token_hash = ?", hashSessionToken(token)).First(&invite).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHouseholdInviteNotFound
+		}
+		return nil, err
+	}
+	switch {
+	case invite.AcceptedAt != nil || invite.RevokedAt != nil:
+		return nil, ErrHouseholdInviteUsed
+	case !invite.Pending(time.Now()):
+		return nil, ErrHouseholdInviteExpired
+	}
+	return &invite, nil
+}
+
+// AcceptInvite adds the user to the invite's household with the invite's role. From then on
+// they share its devices and schedules. A user can be in one household at a time, and a
+// household others joined can't join another.
+func (s *HouseholdService) AcceptInvite(userID, token string) (*models.HouseholdMembership, error) {
+	invite, err := s.Invite(token)
+	if err != nil {
+		return nil, err
+	}
+	if invite.Email != "" {
+		var user models.User
+		if err := s.db.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+			return nil, err
+		}
+		if user.Email != "" && !strings.EqualFold(user.Email, invite.Email) {
+			return nil, ErrHouseholdInviteEmail
+		}
+	}
+
+	membership := &models.HouseholdMembership{
+		UserID:      userID,
+		HouseholdID: invite.HouseholdID,
+		Role:        invite.Role,
+		InviteID:    invite.ID,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if userID == invite.HouseholdID {
+			return ErrHouseholdJoined
+		}
+		var count int64
+		if err := tx.Model(&models.HouseholdMembership{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrHouseholdJoined
+		}
+		if err := tx.Model(&models.HouseholdMembership{}).Where("household_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrHouseholdHasMembers
+		}
+
+		// Claim the invite first, so two users racing for it can't both join
+		now := time.Now()
+		claimed := tx.Model(&models.HouseholdInvite{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invite.ID).
+			Updates(map[string]interface{}{"accepted_by": userID, "accepted_at": &now})
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			return ErrHouseholdInviteUsed
+		}
+		return tx.Create(membership).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// Leave takes the user out of the household they joined. Their own account's devices and
+// schedules stay with them.
+func (s *HouseholdService) Leave(userID string) error {
+	result := s.db.Where("user_id = ?", userID).Delete(&models.HouseholdMembership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrHouseholdOwnAccount
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// InletModel estimates the cold-water inlet temperature when it isn't measured. Mains water
+// follows the ground rather than the air: a yearly sine wave around Mean that peaks on PeakDay,
+// well after the warmest air, and barely moves from one day to the next.
+type InletModel struct {
+	Mean      float64 // yearly average inlet temperature, °C
+	Amplitude float64 // swing between the average and the seasonal extremes, °C
+	PeakDay   int     // day of the year the inlet is warmest
+}
+
+// DefaultInletModel returns a model for a temperate northern-hemisphere climate
+func DefaultInletModel() InletModel {
+	return InletModel{Mean: 18, Amplitude: 5, PeakDay: 220}
+}
+
+// NewInletModel builds a model from configuration, using the defaults when it is unset
+func NewInletModel(cfg config.InletConfig) InletModel {
+	if cfg.Mean == 0 && cfg.Amplitude == 0 {
+		return DefaultInletModel()
+	}
+	model := InletModel{Mean: cfg.Mean, Amplitude: cfg.Amplitude, PeakDay: cfg.PeakDay}
+	if model.PeakDay <= 0 {
+		model.PeakDay = DefaultInletModel().PeakDay
+	}
+	return model
+}
+
+// Estimate returns the seasonal inlet temperature on the given day
+func (m InletModel) Estimate(at time.Time) float64 {
+	phase := 2 * math.Pi * float64(at.YearDay()-m.PeakDay) / 365.25
+	return m.Mean + m.Amplitude*math.Cos(phase)
+}
+
+// ForRecord returns the record's measured inlet temperature, or the estimate for its date
+func (m InletModel) ForRecord(r models.DailyRecord) float64 {
+	if r.InletTemperature != nil {
+		return *r.InletTemperature
+	}
+	return m.Estimate(r.Date)
+}
+
+// ForRequest returns the request's measured inlet temperature, or today's estimate
+func (m InletModel) ForRequest(req PredictionRequest, now time.Time) float64 {
+	if req.InletTemperature != nil {
+		return *req.InletTemperature
+	}
+	return m.Estimate(now)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"time"
+
+	"heat-logger/pkg/logfile"
+)
+
+// logRetentionInterval is how often entries past the retention are pruned from the database
+const logRetentionInterval = time.Hour
+
+// LogArchive appends log entries to a JSONL file, one JSON object per line. The file rotates
+// as LOG_* configures: rotated files are named after the time they were rotated, e.g.
+// predictions-20260316-071500.jsonl.gz, and are never written again.
+type LogArchive struct {
+	file *logfile.File
+}
+
+// NewLogArchive creates an archive writing dir/name.jsonl
+func NewLogArchive(dir, name string, rotation logfile.Options) *LogArchive {
+	return &LogArchive{file: logfile.New(filepath.Join(dir, name+".jsonl"), rotation)}
+}
+
+// Path is the file entries are currently appended to
+func (a *LogArchive) Path() string {
+	return a.file.Path()
+}
+
+// Append writes entry as one line, so it never straddles a rotation
+func (a *LogArchive) Append(entry interface{}) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the current file
+func (a *LogArchive) Close() error {
+	return a.file.Close()
+}
+
+// LogPruner deletes log entries older than a time from the database
+type LogPruner interface {
+	Prune(before time.Time) (int64, error)
+}
+
+// StartLogRetention prunes entries older than retention from the database every hour. It is
+// used when logs are archived to JSONL, so the database keeps only what queries need, and for
+// correlation traces, which are only looked into while a run is fresh.
+func StartLogRetention(retention time.Duration, pruners ...LogPruner) {
+	go func() {
+		ticker := time.NewTicker(logRetentionInterval)
+		defer ticker.Stop()
+		for {
+			for _, pruner := range pruners {
+				if _, err := pruner.Prune(time.Now().Add(-retention)); err != nil {
+					log.Printf("Failed to prune logs: %v", err)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
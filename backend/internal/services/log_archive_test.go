@@ -0,0 +1,60 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"heat-logger/pkg/logfile"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogArchive_RotatesAtSize(t *testing.T) {
+	dir := t.TempDir()
+	archive := NewLogArchive(dir, "predictions", logfile.Options{MaxBytes: 100})
+	entry := map[string]string{"id": "0123456789012345678901234567890123456789"} // 48 bytes a line
+
+	require.NoError(t, archive.Append(entry))
+	require.NoError(t, archive.Append(entry))
+	rotated, err := filepath.Glob(filepath.Join(dir, "predictions-*.jsonl"))
+	require.NoError(t, err)
+	assert.Empty(t, rotated, "two lines fit")
+
+	require.NoError(t, archive.Append(entry))
+	rotated, err = filepath.Glob(filepath.Join(dir, "predictions-*.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, rotated, 1, "the third line starts a new file")
+	assert.Equal(t, 2, countLines(t, rotated[0]))
+	assert.Equal(t, 1, countLines(t, archive.Path()))
+	require.NoError(t, archive.Close())
+
+	// After a restart the size is picked up from the file
+	archive = NewLogArchive(dir, "predictions", logfile.Options{MaxBytes: 100})
+	require.NoError(t, archive.Append(entry))
+	require.NoError(t, archive.Append(entry))
+	require.NoError(t, archive.Close())
+	rotated, err = filepath.Glob(filepath.Join(dir, "predictions-*.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, rotated, 2, "rotations within a second keep both files")
+	assert.Equal(t, 1, countLines(t, archive.Path()))
+}
+
+// countLines counts the JSON lines of a file, failing on one that doesn't parse
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		lines++
+	}
+	return lines
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrArchivingDisabled is returned when records are archived without an influence horizon
+var ErrArchivingDisabled = errors.New("record archiving is not configured")
+
+// archiveInterval is how often the influence horizon is swept
+const archiveInterval = 24 * time.Hour
+
+// MaintenanceService backs up and compacts the database and archives old records, each as an
+// operation
+type MaintenanceService struct {
+	db         *gorm.DB
+	operations *OperationService
+	users      *UserService // clears the learning state of users whose records were archived
+	backupDir  string
+	records    config.RecordsConfig
+}
+
+// NewMaintenanceService creates a new maintenance service writing backups to backupDir
+func NewMaintenanceService(operations *OperationService, users *UserService, backupDir string, records config.RecordsConfig) *MaintenanceService {
+	return &MaintenanceService{
+		db:         database.GetDB(),
+		operations: operations,
+		users:      users,
+		backupDir:  backupDir,
+		records:    records,
+	}
+}
+
+// Start sweeps the influence horizon daily, when one is configured
+func (s *MaintenanceService) Start() {
+	if s.records.InfluenceDays <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(archiveInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.Archive("scheduler"); err != nil && !errors.Is(err, ErrOperationActive) {
+				log.Printf("Failed to start record archiving: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Backup starts copying the database to a new file in the backup directory. The copy is taken
+// in one transaction, so it is consistent while the server keeps writing.
+func (s *MaintenanceService) Backup(requestedBy string) (*models.Operation, error) {
+	return s.operations.Run(models.OperationBackup, requestedBy, "", func(ctx context.Context, progress func(float64)) (string, error) {
+		if err := os.MkdirAll(s.backupDir, 0o755); err != nil {
+			return "", fmt.Errorf("creating backup directory: %w", err)
+		}
+		path := filepath.Join(s.backupDir, "heat-logger-"+time.Now().UTC().Format("20060102-150405")+".db")
+		progress(5)
+		if err := s.db.WithContext(ctx).Exec("VACUUM INTO ?", path).Error; err != nil {
+			os.Remove(path) // a partial copy is no backup
+			return "", err
+		}
+		return path, nil
+	})
+}
+
+// Compact starts rebuilding the database file, returning the space of deleted rows to the
+// file system, and refreshing the statistics the query planner uses
+func (s *MaintenanceService) Compact(requestedBy string) (*models.Operation, error) {
+	return s.operations.Run(models.OperationCompact, requestedBy, "", func(ctx context.Context, progress func(float64)) (string, error) {
+		if err := s.db.WithContext(ctx).Exec("VACUUM").Error; err != nil {
+			return "", err
+		}
+		progress(90)
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return "", s.db.WithContext(ctx).Exec("ANALYZE").Error
+	})
+}
+
+// Archive starts excluding records older than the influence horizon from training, so the
+// predictors learn from a bounded, recent history however long the server has been logging.
+// Each user's newest records are kept so a user back after a long break still has a history.
+// Archived records stay in the history and exports.
+func (s *MaintenanceService) Archive(requestedBy string) (*models.Operation, error) {
+	if s.records.InfluenceDays <= 0 {
+		return nil, ErrArchivingDisabled
+	}
+	return s.operations.Run(models.OperationArchive, requestedBy, "", func(ctx context.Context, progress func(float64)) (string, error) {
+		candidates, err := s.archiveCandidates(time.Now())
+		if err != nil {
+			return "", err
+		}
+		var archived int
+		done := 0
+		for userID, ids := range candidates {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			if err := s.db.WithContext(ctx).Model(&models.DailyRecord{}).Where("id IN ?", ids).Update("excluded_from_training", true).Error; err != nil {
+				return "", err
+			}
+			s.users.resetLearning(userID)
+			archived += len(ids)
+			done++
+			progress(float64(done) * 100 / float64(len(candidates)))
+		}
+		return fmt.Sprintf("%d records", archived), nil
+	})
+}
+
+// PreviewArchive reports what Archive would exclude from training now
+func (s *MaintenanceService) PreviewArchive() (*DryRunResult, error) {
+	if s.records.InfluenceDays <= 0 {
+		return nil, ErrArchivingDisabled
+	}
+	candidates, err := s.archiveCandidates(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	preview := &DryRunResult{Counts: map[string]int64{"records": 0, "users": int64(len(candidates))}, SampleIDs: []string{}}
+	for _, ids := range candidates {
+		preview.Counts["records"] += int64(len(ids))
+		for _, id := range ids {
+			preview.sample(id)
+		}
+	}
+	return preview, nil
+}
+
+// archiveCandidates returns, by user, the training records dated before the influence horizon
+// that aren't among the user's newest
+func (s *MaintenanceService) archiveCandidates(now time.Time) (map[string][]string, error) {
+	cutoff := now.AddDate(0, 0, -s.records.InfluenceDays)
+	var userIDs []string
+	err := s.db.Model(&models.DailyRecord{}).
+		Where("excluded_from_training = ? AND date < ?", false, cutoff).
+		Distinct().Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string][]string)
+	for _, userID := range userIDs {
+		older := s.db.Model(&models.DailyRecord{}).Select("id").
+			Where("user_id = ? AND excluded_from_training = ?", userID, false).
+			Order("date DESC").Offset(s.records.InfluenceKeep)
+		var ids []string
+		err := s.db.Model(&models.DailyRecord{}).
+			Where("id IN (?) AND date < ?", older, cutoff).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			candidates[userID] = ids
+		}
+	}
+	return candidates, nil
+}
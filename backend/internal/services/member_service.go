@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMemberNotFound is returned when a member does not exist under the user
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrMemberNameTaken is returned when the user already has a member with the same name
+	ErrMemberNameTaken = errors.New("a member with this name already exists")
+)
+
+// MemberService handles household member profiles
+type MemberService struct {
+	db *gorm.DB
+}
+
+// NewMemberService creates a new member service instance
+func NewMemberService() *MemberService {
+	return &MemberService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateMember stores a new member profile
+func (s *MemberService) CreateMember(member *models.Member) error {
+	if err := s.ensureNameFree(member.UserID, member.Name, ""); err != nil {
+		return err
+	}
+	return s.db.Create(member).Error
+}
+
+// ListMembers returns the user's members ordered by name
+func (s *MemberService) ListMembers(userID string) ([]models.Member, error) {
+	var members []models.Member
+	err := s.db.Where("user_id = ?", userID).Order("name ASC").Find(&members).Error
+	return members, err
+}
+
+// GetMember retrieves one of the user's members
+func (s *MemberService) GetMember(userID, id string) (*models.Member, error) {
+	var member models.Member
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RenameMember changes a member's display name
+func (s *MemberService) RenameMember(userID, id, name string) (*models.Member, error) {
+	member, err := s.GetMember(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureNameFree(userID, name, id); err != nil {
+		return nil, err
+	}
+	member.Name = name
+	if err := s.db.Save(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// DeleteMember deletes one of the user's members. Their records stay with the account
+// and keep the member ID for reference.
+func (s *MemberService) DeleteMember(userID, id string) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Member{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+func (s *MemberService) ensureNameFree(userID, name, exceptID string) error {
+	var count int64
+	query := s.db.Model(&models.Member{}).Where("user_id = ? AND LOWER(name) = ?", userID, strings.ToLower(name))
+	if exceptID != "" {
+		query = query.Where("id != ?", exceptID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrMemberNameTaken
+	}
+	return nil
+}
+
+// splitByMember separates the member's own records from the rest of the household's.
+// Predictors treat the member's records as personal history and the household's as
+// extra neighbours alongside global data.
+func splitByMember(records []models.DailyRecord, memberID string) (own, household []models.DailyRecord) {
+	for _, r := range records {
+		if r.MemberID == memberID {
+			own = append(own, r)
+		} else {
+			household = append(household, r)
+		}
+	}
+	return own, household
+}
@@ -0,0 +1,14 @@
+package services
+
+// modelRevisions numbers the behaviour of each predictor version. Bump a version's revision with
+// every change to how it learns from feedback or answers, so analysis can tell apart data
+// produced by different behaviour of the same version.
+var modelRevisions = map[string]int{
+	"v1": 1,
+	"v2": 1,
+}
+
+// ModelRevision returns the current behaviour revision of a predictor version, 0 when unknown
+func ModelRevision(version string) int {
+	return modelRevisions[version]
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/internal/notify"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotificationsDisabled is returned when no notification webhook is configured and no
+	// user has routed notifications anywhere
+	ErrNotificationsDisabled = errors.New("notifications are not configured")
+	// ErrInvalidQuietHours is returned for quiet hours that aren't an HH:MM-HH:MM window or "off"
+	ErrInvalidQuietHours = errors.New("quiet hours must look like 22:00-07:00 or be off")
+	// ErrUnknownNotificationChannel is returned for a route over a channel that isn't configured
+	ErrUnknownNotificationChannel = errors.New("unknown notification channel")
+	// ErrUnknownNotificationKind is returned for a route naming a kind no notification has
+	ErrUnknownNotificationKind = errors.New("unknown notification kind")
+	// ErrNotificationRouteNotFound is returned when a route does not exist for the user
+	ErrNotificationRouteNotFound = errors.New("notification route not found")
+)
+
+// notificationSendTimeout bounds delivery over each channel
+const notificationSendTimeout = 15 * time.Second
+
+// defaultNotificationChannel delivers to NOTIFICATION_WEBHOOK_URL for users without routes
+const defaultNotificationChannel = "webhook"
+
+// NotificationSettings are the limits applied to a user's notifications
+type NotificationSettings struct {
+	QuietHours string `json:"quietHours"` // HH:MM-HH:MM or "off"
+	DailyLimit int    `json:"dailyLimit"` // 0 for no cap
+	Custom     bool   `json:"custom"`     // false when the deployment defaults apply
+}
+
+// NotificationService sends notifications over the channels users route them to, e.g. email
+// or Telegram, or to the configured webhook for users without routes, which a bridge delivers
+// to the user's phone. Each user's quiet hours and daily cap are enforced here; notifications
+// that are held back are logged as suppressed alongside the ones that went out.
+type NotificationService struct {
+	db       *gorm.DB
+	cfg      config.NotificationConfig
+	channels *notify.Registry
+}
+
+// NewNotificationService creates a new notification service instance sending over the
+// registered channels cfg configures
+func NewNotificationService(cfg config.NotificationConfig) *NotificationService {
+	return &NotificationService{
+		db:       database.GetDB(),
+		cfg:      cfg,
+		channels: notify.NewRegistry(cfg),
+	}
+}
+
+// Enabled reports whether notifications are sent anywhere
+func (s *NotificationService) Enabled() bool {
+	if s.cfg.Enabled() {
+		return true
+	}
+	var routes int64
+	return s.db.Model(&models.NotificationRoute{}).Limit(1).Count(&routes).Error == nil && routes > 0
+}
+
+// Channels returns the capabilities of every configured channel, by name
+func (s *NotificationService) Channels() map[string]notify.Capabilities {
+	channels := map[string]notify.Capabilities{}
+	for _, name := range s.channels.Names() {
+		channel, _ := s.channels.Get(name)
+		channels[name] = channel.Capabilities()
+	}
+	return channels
+}
+
+// Routes returns the user's notification routes, oldest first
+func (s *NotificationService) Routes(userID string) ([]models.NotificationRoute, error) {
+	var routes []models.NotificationRoute
+	err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&routes).Error
+	return routes, err
+}
+
+// AddRoute stores a route after checking its channel, target and kinds
+func (s *NotificationService) AddRoute(route *models.NotificationRoute) error {
+	channel, ok := s.channels.Get(route.Channel)
+	if !ok {
+		return ErrUnknownNotificationChannel
+	}
+	if validator, ok := channel.(notify.TargetValidator); ok {
+		if err := validator.ValidateTarget(route.Target); err != nil {
+			return err
+		}
+	}
+	var kinds []string
+	for _, kind := range strings.Split(route.Kinds, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !isNotificationKind(kind) {
+			return ErrUnknownNotificationKind
+		}
+		kinds = append(kinds, kind)
+	}
+	route.Kinds = strings.Join(kinds, ",")
+	return s.db.Create(route).Error
+}
+
+// DeleteRoute deletes one of the user's routes
+func (s *NotificationService) DeleteRoute(userID, id string) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.NotificationRoute{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationRouteNotFound
+	}
+	return nil
+}
+
+// RouteOwner returns the user with a route to the channel's target, so a message to a bot from
+// a chat can be told whose it is. When several users route to it, e.g. a household's group
+// chat, the oldest route's user owns it.
+func (s *NotificationService) RouteOwner(channel, target string) (string, error) {
+	var route models.NotificationRoute
+	err := s.db.Where("channel = ? AND target = ?", channel, target).Order("created_at ASC").Limit(1).Find(&route).Error
+	if err != nil {
+		return "", err
+	}
+	if route.ID == "" {
+		return "", ErrNotificationRouteNotFound
+	}
+	return route.UserID, nil
+}
+
+// Listeners returns the configured channels whose bots take commands, by name
+func (s *NotificationService) Listeners() map[string]notify.Listener {
+	return s.channels.Listeners()
+}
+
+func isNotificationKind(kind string) bool {
+	for _, k := range models.NotificationKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// routeTakes reports whether a route's kinds include kind
+func routeTakes(route models.NotificationRoute, kind string) bool {
+	if route.Kinds == "" {
+		return !models.OptInNotification(kind)
+	}
+	for _, k := range strings.Split(route.Kinds, ",") {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// routesFor returns the routes a notification of kind goes out over: the user's routes that
+// take it, or the default webhook when the user has no routes at all
+func (s *NotificationService) routesFor(userID, kind string) ([]models.NotificationRoute, error) {
+	routes, err := s.Routes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		if s.cfg.WebhookURL == "" || models.OptInNotification(kind) {
+			return nil, nil
+		}
+		return []models.NotificationRoute{{Channel: defaultNotificationChannel, Target: s.cfg.WebhookURL}}, nil
+	}
+	var matching []models.NotificationRoute
+	for _, route := range routes {
+		if routeTakes(route, kind) {
+			matching = append(matching, route)
+		}
+	}
+	return matching, nil
+}
+
+// Settings returns the user's quiet hours and daily cap, falling back to the defaults
+func (s *NotificationService) Settings(userID string) (*NotificationSettings, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	settings := &NotificationSettings{QuietHours: s.cfg.QuietHours, DailyLimit: s.cfg.DailyLimit}
+	if user.QuietHours != "" {
+		settings.QuietHours = user.QuietHours
+		settings.Custom = true
+	}
+	if user.NotificationDailyLimit != nil {
+		settings.DailyLimit = *user.NotificationDailyLimit
+		settings.Custom = true
+	}
+	return settings, nil
+}
+
+// SetSettings stores the user's quiet hours and daily cap. Empty quiet hours or a nil limit
+// restore the default.
+func (s *NotificationService) SetSettings(userID, quietHours string, dailyLimit *int) error {
+	if quietHours != "" && !config.ValidQuietHours(quietHours) {
+		return ErrInvalidQuietHours
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quiet_hours", "notification_daily_limit", "updated_at"}),
+	}).Create(&models.User{ID: userID, QuietHours: quietHours, NotificationDailyLimit: dailyLimit}).Error
+}
+
+// NextAllowed returns at, or the end of the user's quiet hours when at falls inside them
+func (s *NotificationService) NextAllowed(userID string, at time.Time) (time.Time, error) {
+	settings, err := s.Settings(userID)
+	if err != nil {
+		return at, err
+	}
+	if until, quiet := quietUntil(settings.QuietHours, at); quiet {
+		return until, nil
+	}
+	return at, nil
+}
+
+// Notify sends a notification unless the user's quiet hours or daily cap hold it back, and logs
+// the outcome. A suppressed notification is not an error; check the returned entry's status.
+func (s *NotificationService) Notify(userID, kind, message string, data map[string]interface{}, now time.Time) (*models.Notification, error) {
+	if !s.Enabled() {
+		return nil, ErrNotificationsDisabled
+	}
+	settings, err := s.Settings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := s.routesFor(userID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.Notification{UserID: userID, Kind: kind, Message: message, Status: models.NotificationSent}
+	if len(routes) == 0 {
+		entry.Status, entry.Reason = models.NotificationSuppressed, models.SuppressedNoChannel
+	} else if _, quiet := quietUntil(settings.QuietHours, now); quiet {
+		entry.Status, entry.Reason = models.NotificationSuppressed, models.SuppressedQuietHours
+	} else if settings.DailyLimit > 0 {
+		var sent int64
+		err := s.db.Model(&models.Notification{}).
+			Where("user_id = ? AND status = ? AND created_at >= ?", userID, models.NotificationSent, startOfDay(now.Local())).
+			Count(&sent).Error
+		if err != nil {
+			return nil, err
+		}
+		if sent >= int64(settings.DailyLimit) {
+			entry.Status, entry.Reason = models.NotificationSuppressed, models.SuppressedDailyLimit
+		}
+	}
+
+	var sendErr error
+	if entry.Status == models.NotificationSent {
+		sendErr = s.send(entry, routes, notify.Message{UserID: userID, Kind: kind, Text: message, Data: data})
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		return nil, err
+	}
+	return entry, sendErr
+}
+
+// Log returns the user's most recent notifications, newest first, optionally of one status
+func (s *NotificationService) Log(userID, status string, limit int) ([]models.Notification, error) {
+	query := s.db.Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var entries []models.Notification
+	err := query.Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// send delivers a notification over each route, recording on the entry which channels it
+// went out over. It fails only when no route delivered it.
+func (s *NotificationService) send(entry *models.Notification, routes []models.NotificationRoute, msg notify.Message) error {
+	var delivered, failures []string
+	var lastErr error
+	for _, route := range routes {
+		err := ErrUnknownNotificationChannel
+		if channel, ok := s.channels.Get(route.Channel); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+			err = channel.Send(ctx, notify.Target{Address: route.Target, Token: route.Token}, msg)
+			cancel()
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", route.Channel, err))
+			lastErr = err
+			continue
+		}
+		delivered = append(delivered, route.Channel)
+	}
+
+	entry.Channels = strings.Join(delivered, ",")
+	entry.Reason = strings.Join(failures, "; ")
+	if len(delivered) == 0 {
+		entry.Status = models.NotificationFailed
+		return lastErr
+	}
+	return nil
+}
+
+// quietUntil reports whether at falls in the HH:MM-HH:MM quiet hours window, and when it ends
+func quietUntil(quietHours string, at time.Time) (time.Time, bool) {
+	startClock, endClock, ok := strings.Cut(quietHours, "-")
+	if !ok || startClock == endClock {
+		return at, false
+	}
+	clock := func(hhmm string) (int, bool) {
+		parsed, err := time.Parse("15:04", hhmm)
+		return parsed.Hour()*60 + parsed.Minute(), err == nil
+	}
+	start, startOK := clock(startClock)
+	end, endOK := clock(endClock)
+	if !startOK || !endOK {
+		return at, false
+	}
+
+	at = at.Local()
+	minute := at.Hour()*60 + at.Minute()
+	quiet := minute >= start && minute < end
+	if start > end {
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return at, false
+	}
+	until := time.Date(at.Year(), at.Month(), at.Day(), end/60, end%60, 0, 0, at.Location())
+	if !until.After(at) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/internal/notify"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestQuietUntil(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.Local)
+	}
+	quietAt := func(quietHours string, now time.Time) *time.Time {
+		if until, quiet := quietUntil(quietHours, now); quiet {
+			return &until
+		}
+		return nil
+	}
+	wake := at(11, 7, 0)
+
+	assert.Nil(t, quietAt("22:00-07:00", at(10, 21, 59)))
+	assert.Equal(t, &wake, quietAt("22:00-07:00", at(10, 22, 0)), "held until the morning")
+	assert.Equal(t, &wake, quietAt("22:00-07:00", at(11, 2, 0)), "after midnight waits for the same morning")
+	assert.Nil(t, quietAt("22:00-07:00", at(11, 7, 0)))
+
+	afternoon := at(10, 15, 0)
+	assert.Equal(t, &afternoon, quietAt("13:00-15:00", at(10, 14, 10)))
+	assert.Nil(t, quietAt("13:00-15:00", at(10, 12, 0)))
+
+	assert.Nil(t, quietAt("off", at(11, 2, 0)))
+	assert.Nil(t, quietAt("", at(11, 2, 0)))
+}
+
+// recordingChannel is a notify.Channel that keeps what it was asked to send
+type recordingChannel struct {
+	sent []string
+	err  error
+}
+
+func (c *recordingChannel) Send(_ context.Context, to notify.Target, msg notify.Message) error {
+	c.sent = append(c.sent, to.Address+": "+msg.Text)
+	return c.err
+}
+
+func (c *recordingChannel) Capabilities() notify.Capabilities {
+	return notify.Capabilities{Target: "name"}
+}
+
+func TestNotificationService_RoutesByKind(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "notifications.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+
+	notifications := NewNotificationService(config.NotificationConfig{QuietHours: "off", WebhookURL: "http://bridge.invalid"})
+	chat, pager := &recordingChannel{}, &recordingChannel{err: errors.New("pager offline")}
+	notifications.channels.Add("chat", chat)
+	notifications.channels.Add("pager", pager)
+	notifications.channels.Add(defaultNotificationChannel, &recordingChannel{})
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	require.NoError(t, notifications.AddRoute(&models.NotificationRoute{UserID: "user1", Channel: "chat", Target: "alice"}))
+	require.NoError(t, notifications.AddRoute(&models.NotificationRoute{UserID: "user1", Channel: "pager", Target: "p1", Kinds: " boiler_efficiency "}))
+	assert.ErrorIs(t, notifications.AddRoute(&models.NotificationRoute{UserID: "user1", Channel: "fax", Target: "1"}), ErrUnknownNotificationChannel)
+	assert.ErrorIs(t, notifications.AddRoute(&models.NotificationRoute{UserID: "user1", Channel: "chat", Target: "alice", Kinds: "gossip"}), ErrUnknownNotificationKind)
+
+	entry, err := notifications.Notify("user1", models.NotificationFeedbackPrompt, "How was it?", nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationSent, entry.Status)
+	assert.Equal(t, "chat", entry.Channels)
+	assert.Equal(t, []string{"alice: How was it?"}, chat.sent)
+	assert.Empty(t, pager.sent, "the pager only takes boiler efficiency notifications")
+
+	entry, err = notifications.Notify("user1", models.NotificationBoilerEfficiency, "Descale", nil, now)
+	require.NoError(t, err, "one channel delivering is enough")
+	assert.Equal(t, models.NotificationSent, entry.Status)
+	assert.Equal(t, "chat", entry.Channels)
+	assert.Equal(t, "pager: pager offline", entry.Reason)
+
+	// Users without routes get the deployment's webhook
+	entry, err = notifications.Notify("user2", models.NotificationFeedbackPrompt, "How was it?", nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, defaultNotificationChannel, entry.Channels)
+
+	// Heating notices only go over routes that ask for them
+	entry, err = notifications.Notify("user1", models.NotificationHeatingStarted, "Heating", nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.SuppressedNoChannel, entry.Reason)
+	entry, err = notifications.Notify("user2", models.NotificationHeatingStarted, "Heating", nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.SuppressedNoChannel, entry.Reason, "nor to the default webhook")
+	require.NoError(t, notifications.AddRoute(&models.NotificationRoute{UserID: "user1", Channel: "chat", Target: "alice-phone", Kinds: "heating_started"}))
+	entry, err = notifications.Notify("user1", models.NotificationHeatingStarted, "Heating", nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationSent, entry.Status)
+	assert.Contains(t, chat.sent, "alice-phone: Heating")
+
+	routes, err := notifications.Routes("user1")
+	require.NoError(t, err)
+	require.Len(t, routes, 3)
+	require.NoError(t, notifications.DeleteRoute("user1", routes[0].ID))
+	assert.ErrorIs(t, notifications.DeleteRoute("user1", routes[0].ID), ErrNotificationRouteNotFound)
+
+	entry, err = notifications.Notify("user1", models.NotificationFeedbackPrompt, "How was it?", nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationSuppressed, entry.Status)
+	assert.Equal(t, models.SuppressedNoChannel, entry.Reason)
+}
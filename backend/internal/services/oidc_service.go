@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+var (
+	// ErrOIDCAccessDenied is returned when the identity is valid but not in an allowed group
+	ErrOIDCAccessDenied = errors.New("user is not in an allowed group")
+	// ErrInvalidIDToken is returned when an ID token fails verification
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// OIDCIdentity is the verified result of an OpenID Connect login
+type OIDCIdentity struct {
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+	Role    string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// OIDCService implements the authorization code flow against a configured provider.
+// Only RS256-signed ID tokens are accepted, which covers Authelia, Keycloak and most others.
+type OIDCService struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+// NewOIDCService creates a new OIDC service instance. Provider metadata is fetched lazily.
+func NewOIDCService(cfg config.OIDCConfig) *OIDCService {
+	return &OIDCService{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthCodeURL returns the provider login URL for the given state and nonce
+func (s *OIDCService) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	disc, err := s.provider(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {s.cfg.ClientID},
+		"redirect_uri":  {s.cfg.RedirectURL},
+		"scope":         {strings.Join(s.cfg.Scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	return disc.AuthorizationEndpoint + "?" + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified identity
+func (s *OIDCService) Exchange(ctx context.Context, code, nonce string) (*OIDCIdentity, error) {
+	disc, err := s.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {s.cfg.RedirectURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, disc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+
+	claims, err := s.verifyIDToken(ctx, tokens.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.identityFromClaims(claims)
+}
+
+// provider returns the cached discovery document, fetching it on first use
+func (s *OIDCService) provider(ctx context.Context) (*oidcDiscovery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.discovery != nil {
+		return s.discovery, nil
+	}
+
+	var disc oidcDiscovery
+	if err := s.getJSON(ctx, s.cfg.IssuerURL+"/.well-known/openid-configuration", &disc); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != s.cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc discovery returned issuer %q, expected %q", disc.Issuer, s.cfg.IssuerURL)
+	}
+	s.discovery = &disc
+	return s.discovery, nil
+}
+
+// signingKey returns the provider key with the given ID, refreshing the key set once if it is unknown
+func (s *OIDCService) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	disc, err := s.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.getJSON(ctx, disc.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc key fetch failed: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		key, err := jwk.rsaPublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	s.keys = keys
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+	}
+	return key, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (s *OIDCService) verifyIDToken(ctx context.Context, token, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidIDToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidIDToken, header.Alg)
+	}
+
+	key, err := s.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidIDToken)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidIDToken)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != s.cfg.IssuerURL {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, iss)
+	}
+	if !containsString(claimStrings(claims["aud"]), s.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: token not issued for this client", ErrInvalidIDToken)
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().After(time.Unix(int64(exp), 0).Add(time.Minute)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidIDToken)
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// identityFromClaims maps verified claims to an identity and applies group-to-role mapping
+func (s *OIDCService) identityFromClaims(claims map[string]interface{}) (*OIDCIdentity, error) {
+	identity := &OIDCIdentity{Groups: claimStrings(claims[s.cfg.GroupsClaim])}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	if identity.Name == "" {
+		identity.Name, _ = claims["preferred_username"].(string)
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	role, err := s.roleFor(identity.Groups)
+	if err != nil {
+		return nil, err
+	}
+	identity.Role = role
+	return identity, nil
+}
+
+// roleFor returns admin for members of an admin group, user for allowed users, and denies the rest
+func (s *OIDCService) roleFor(groups []string) (string, error) {
+	for _, g := range groups {
+		if containsString(s.cfg.AdminGroups, g) {
+			return models.RoleAdmin, nil
+		}
+	}
+	if len(s.cfg.UserGroups) == 0 {
+		return models.RoleUser, nil
+	}
+	for _, g := range groups {
+		if containsString(s.cfg.UserGroups, g) {
+			return models.RoleUser, nil
+		}
+	}
+	return "", ErrOIDCAccessDenied
+}
+
+func (s *OIDCService) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func decodeJWTPart(part string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: bad encoding", ErrInvalidIDToken)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%w: bad json", ErrInvalidIDToken)
+	}
+	return nil
+}
+
+// claimStrings reads a claim that may be a single string or a list of strings
+func claimStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if strings.TrimSpace(item) == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProvider serves discovery and a single RSA key, returning the service and a token signer
+func newTestProvider(t *testing.T, cfg config.OIDCConfig) (*OIDCService, func(claims map[string]interface{}) string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	cfg.IssuerURL = server.URL
+	cfg.ClientID = "heat-logger"
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	sign := func(claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	return NewOIDCService(cfg), sign
+}
+
+func TestOIDCService_VerifiesTokenAndMapsGroups(t *testing.T) {
+	service, sign := newTestProvider(t, config.OIDCConfig{AdminGroups: []string{"heat-admins"}})
+
+	claims := map[string]interface{}{
+		"iss":    service.cfg.IssuerURL,
+		"aud":    "heat-logger",
+		"sub":    "alice",
+		"exp":    float64(time.Now().Add(time.Hour).Unix()),
+		"nonce":  "n1",
+		"groups": []string{"family", "heat-admins"},
+	}
+
+	verified, err := service.verifyIDToken(context.Background(), sign(claims), "n1")
+	require.NoError(t, err)
+	identity, err := service.identityFromClaims(verified)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Subject)
+	assert.Equal(t, models.RoleAdmin, identity.Role)
+
+	// Wrong nonce and wrong audience are both rejected
+	_, err = service.verifyIDToken(context.Background(), sign(claims), "other")
+	assert.True(t, errors.Is(err, ErrInvalidIDToken))
+	claims["aud"] = "someone-else"
+	_, err = service.verifyIDToken(context.Background(), sign(claims), "n1")
+	assert.True(t, errors.Is(err, ErrInvalidIDToken))
+}
+
+func TestOIDCService_RoleFor(t *testing.T) {
+	service := NewOIDCService(config.OIDCConfig{AdminGroups: []string{"admins"}, UserGroups: []string{"family"}})
+
+	role, err := service.roleFor([]string{"family"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+
+	role, err = service.roleFor([]string{"admins"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, role)
+
+	_, err = service.roleFor([]string{"guests"})
+	assert.ErrorIs(t, err, ErrOIDCAccessDenied)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOperationNotFound is returned when an operation does not exist
+	ErrOperationNotFound = errors.New("operation not found")
+	// ErrOperationActive is returned when an operation is started while another of its kind is still running
+	ErrOperationActive = errors.New("an operation of that kind is already in progress")
+	// ErrOperationFinished is returned when canceling an operation that is no longer running
+	ErrOperationFinished = errors.New("operation has already finished")
+)
+
+// OperationRun does the work of an operation. It reports how far it got through progress, in
+// percent, and returns ctx.Err() once ctx is canceled. The string it returns, when not empty,
+// replaces the operation's target, e.g. with the file it wrote.
+type OperationRun func(ctx context.Context, progress func(percent float64)) (string, error)
+
+// OperationService runs long-running admin tasks (re-scores, backups, compaction) in the
+// background and tracks them in one place, so each can be followed and canceled the same way.
+// Operations are stored so they can be listed after the fact; the ones running live in this
+// process, which is where they are canceled.
+type OperationService struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// NewOperationService creates a new operation service instance
+func NewOperationService() *OperationService {
+	return &OperationService{
+		db:      database.GetDB(),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// Start marks operations left running by a previous process as failed; they will never finish
+func (s *OperationService) Start() {
+	now := time.Now()
+	s.db.Model(&models.Operation{}).
+		Where("status = ?", models.OperationRunning).
+		Updates(map[string]interface{}{"status": models.OperationFailed, "error": "interrupted by server restart", "completed_at": &now})
+}
+
+// Run starts an operation of the kind in the background. Only one operation of each kind runs
+// at a time; starting another returns ErrOperationActive.
+func (s *OperationService) Run(kind, requestedBy, target string, run OperationRun) (*models.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var active int64
+	err := s.db.Model(&models.Operation{}).
+		Where("kind = ? AND status = ?", kind, models.OperationRunning).
+		Count(&active).Error
+	if err != nil {
+		return nil, err
+	}
+	if active > 0 {
+		return nil, ErrOperationActive
+	}
+
+	operation := &models.Operation{
+		Kind:        kind,
+		RequestedBy: requestedBy,
+		Status:      models.OperationRunning,
+		Target:      target,
+	}
+	if err := s.db.Create(operation).Error; err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancels[operation.ID] = cancel
+	go s.execute(ctx, operation.ID, run)
+	return operation, nil
+}
+
+// execute runs an operation and stores how it ended
+func (s *OperationService) execute(ctx context.Context, id string, run OperationRun) {
+	defer func() {
+		s.mu.Lock()
+		s.cancels[id]()
+		delete(s.cancels, id)
+		s.mu.Unlock()
+	}()
+
+	// Progress is stored in whole percents so a long loop doesn't write on every step
+	stored := 0.0
+	progress := func(percent float64) {
+		percent = math.Floor(math.Max(0, math.Min(percent, 100)))
+		if percent <= stored {
+			return
+		}
+		stored = percent
+		s.db.Model(&models.Operation{}).Where("id = ?", id).Update("progress", percent)
+	}
+
+	target, err := run(ctx, progress)
+	now := time.Now()
+	updates := map[string]interface{}{"status": models.OperationCompleted, "progress": 100.0, "completed_at": &now}
+	switch {
+	case err != nil && ctx.Err() != nil:
+		updates = map[string]interface{}{"status": models.OperationCanceled, "completed_at": &now}
+	case err != nil:
+		log.Printf("Operation %s failed: %v", id, err)
+		updates = map[string]interface{}{"status": models.OperationFailed, "error": err.Error(), "completed_at": &now}
+	}
+	if target != "" {
+		updates["target"] = target
+	}
+	s.db.Model(&models.Operation{}).Where("id = ?", id).Updates(updates)
+}
+
+// List returns the most recent operations, newest first, only of the kind unless it is empty
+func (s *OperationService) List(kind string, limit int) ([]models.Operation, error) {
+	query := s.db.Order("created_at DESC").Limit(limit)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var operations []models.Operation
+	err := query.Find(&operations).Error
+	return operations, err
+}
+
+// Get retrieves an operation and its progress
+func (s *OperationService) Get(id string) (*models.Operation, error) {
+	var operation models.Operation
+	err := s.db.Where("id = ?", id).First(&operation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOperationNotFound
+		}
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// Cancel asks a running operation to stop. It ends as canceled once it notices, which for a
+// step that can't be interrupted is when that step is done.
+func (s *OperationService) Cancel(id string) (*models.Operation, error) {
+	operation, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	cancel, ok := s.cancels[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrOperationFinished
+	}
+	cancel()
+	return operation, nil
+}
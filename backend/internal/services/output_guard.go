@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// ServedPredictions is the log of predictions served, which the guard compares new ones against
+type ServedPredictions interface {
+	LastServed(req PredictionRequest, since time.Time) (*models.PredictionLog, error)
+}
+
+// FeedbackLookup reports whether a user gave feedback since a given time
+type FeedbackLookup interface {
+	HasFeedbackSince(userID string, since time.Time) (bool, error)
+}
+
+// GuardPredictor is a last line of defence against model bugs reaching the boiler. When the
+// answer for a context moves more than the configured share from the last one served for it,
+// with no feedback since that could explain the move, it logs an anomaly and serves the last
+// value instead, marked held so the prediction log flags it for review. Demand-response caps,
+// deployment hooks and the safety floor adjust answers after the guard, so it compares what
+// it passed on rather than what was finally served: a capped answer mustn't hold the next one.
+type GuardPredictor struct {
+	inner    Predictor
+	cfg      config.GuardConfig
+	served   ServedPredictions
+	feedback FeedbackLookup
+	now      func() time.Time
+
+	mu      sync.Mutex
+	resetAt map[string]time.Time // users whose model was reset, when
+}
+
+// NewGuardPredictor wraps inner with the rate-of-change guard
+func NewGuardPredictor(inner Predictor, cfg config.GuardConfig, served ServedPredictions, feedback FeedbackLookup) *GuardPredictor {
+	return &GuardPredictor{
+		inner:    inner,
+		cfg:      cfg,
+		served:   served,
+		feedback: feedback,
+		now:      time.Now,
+		resetAt:  make(map[string]time.Time),
+	}
+}
+
+// Predict runs the wrapped predictor and holds its answer when it moved too far. Stale and
+// shed answers are fallbacks rather than model output, and clients allowing a large
+// adjustment have asked for the jump, so those pass unchecked. When the log can't be read
+// the answer is served as is: the guard must not take predictions down with it.
+func (p *GuardPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	resp, err := p.inner.Predict(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Stale || resp.Shed || req.AllowLargeAdjustment {
+		return guarded(resp), nil
+	}
+
+	since := p.now().Add(-p.cfg.Window)
+	p.mu.Lock()
+	if reset, ok := p.resetAt[req.UserID]; ok && reset.After(since) {
+		since = reset
+	}
+	p.mu.Unlock()
+
+	last, err := p.served.LastServed(req, since)
+	if err != nil {
+		log.Printf("Prediction guard could not read the last prediction for %s: %v", req.UserID, err)
+		return guarded(resp), nil
+	}
+	if last == nil {
+		return guarded(resp), nil
+	}
+	// Entries logged before the guarded value was recorded only have what was served
+	previous := last.HeatingTime
+	if last.GuardedHeatingTime != nil {
+		previous = *last.GuardedHeatingTime
+	}
+	if previous <= 0 {
+		return guarded(resp), nil
+	}
+	change := math.Abs(resp.HeatingTime-previous) / previous * 100
+	if change <= p.cfg.MaxChange {
+		return guarded(resp), nil
+	}
+	if explained, err := p.feedback.HasFeedbackSince(req.UserID, last.CreatedAt); err != nil || explained {
+		return guarded(resp), nil
+	}
+
+	log.Printf("Prediction anomaly for user %s: %.1f minutes is %.0f%% from the %.1f passed on at %s without feedback since; serving %.1f",
+		req.UserID, resp.HeatingTime, change, previous, last.CreatedAt.Format(time.RFC3339), previous)
+	// Copy: cached predictions are shared
+	held := *resp
+	model := resp.HeatingTime
+	held.HeatingTime = previous
+	held.RawHeatingTime = previous
+	held.Held = true
+	held.ModelHeatingTime = &model
+	held.GuardedHeatingTime = previous
+	return &held, nil
+}
+
+// guarded returns a copy of resp recording it as what the guard passed on; cached predictions
+// are shared, so resp itself is left alone
+func guarded(resp *PredictionResponse) *PredictionResponse {
+	out := *resp
+	out.GuardedHeatingTime = resp.HeatingTime
+	return &out
+}
+
+// SetClock sets the clock the comparison window and model resets are measured by
+func (p *GuardPredictor) SetClock(clock Clock) {
+	p.now = clock.Now
+}
+
+// ForgetUser stops comparing the user's predictions with those served before their model was reset
+func (p *GuardPredictor) ForgetUser(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resetAt[userID] = p.now()
+}
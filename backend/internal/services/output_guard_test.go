@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+// servedLog is a prediction log holding at most one served prediction
+type servedLog struct{ last *models.PredictionLog }
+
+func (l *servedLog) LastServed(req PredictionRequest, since time.Time) (*models.PredictionLog, error) {
+	if l.last == nil || l.last.CreatedAt.Before(since) {
+		return nil, nil
+	}
+	return l.last, nil
+}
+
+// feedbackAt reports feedback given at a fixed time
+type feedbackAt struct{ at *time.Time }
+
+func (f feedbackAt) HasFeedbackSince(userID string, since time.Time) (bool, error) {
+	return f.at != nil && f.at.After(since), nil
+}
+
+func TestGuardPredictor_HoldsUnexplainedJumps(t *testing.T) {
+	now := time.Date(2026, 2, 3, 7, 0, 0, 0, time.UTC)
+	served := &servedLog{last: &models.PredictionLog{HeatingTime: 20, CreatedAt: now.Add(-24 * time.Hour)}}
+	var feedback feedbackAt
+	newGuard := func(model float64) *GuardPredictor {
+		guard := NewGuardPredictor(fixedPredictor(model), config.GuardConfig{MaxChange: 50, Window: 48 * time.Hour}, served, &feedback)
+		guard.now = func() time.Time { return now }
+		return guard
+	}
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	// A day later the model asks for three times as long with nothing learnt since
+	resp, err := newGuard(60).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, resp.Held)
+	assert.Equal(t, 20.0, resp.HeatingTime)
+	require.NotNil(t, resp.ModelHeatingTime)
+	assert.Equal(t, 60.0, *resp.ModelHeatingTime)
+
+	// Moves within the limit pass
+	resp, err = newGuard(28).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.False(t, resp.Held)
+	assert.Equal(t, 28.0, resp.HeatingTime)
+
+	// So does a jump the user asked for
+	allowed := req
+	allowed.AllowLargeAdjustment = true
+	resp, err = newGuard(60).Predict(context.Background(), allowed)
+	require.NoError(t, err)
+	assert.False(t, resp.Held)
+
+	// Feedback since the last prediction explains the jump
+	given := now.Add(-time.Hour)
+	feedback.at = &given
+	resp, err = newGuard(60).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.False(t, resp.Held)
+	assert.Equal(t, 60.0, resp.HeatingTime)
+}
+
+func TestGuardPredictor_ForgetsValuesBeforeModelReset(t *testing.T) {
+	now := time.Date(2026, 2, 3, 7, 0, 0, 0, time.UTC)
+	served := &servedLog{last: &models.PredictionLog{HeatingTime: 20, CreatedAt: now.Add(-time.Hour)}}
+	guard := NewGuardPredictor(fixedPredictor(60), config.GuardConfig{MaxChange: 50, Window: 48 * time.Hour}, served, feedbackAt{})
+	guard.now = func() time.Time { return now }
+
+	guard.ForgetUser("user1")
+	resp, err := guard.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15})
+	require.NoError(t, err)
+	assert.False(t, resp.Held)
+}
+
+func TestGuardPredictor_IgnoresDemandResponseCaps(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "guard.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	predictionLog := NewPredictionLogService(func(string) string { return "v2" })
+	now := time.Now()
+	clock := NewFrozenClock(now)
+	events := NewDemandResponseService(config.DemandResponseConfig{MaxDuration: 6 * time.Hour}, nil)
+	events.SetClock(clock)
+	guard := NewGuardPredictor(fixedPredictor(30), config.GuardConfig{MaxChange: 50, Window: 48 * time.Hour}, predictionLog, NewRecordService())
+	predictor := NewDemandResponsePredictor(guard, events)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	// The utility caps heating at 12 minutes, and that is what gets served and logged
+	_, _, err := events.Signal(DemandResponseSignal{Action: models.DemandResponseCap, MaxMinutes: 12, Start: now.Add(-time.Minute), End: now.Add(time.Hour)})
+	require.NoError(t, err)
+	resp, err := predictor.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, resp.HeatingTime)
+	predictionLog.Record(req, resp)
+
+	// Once the event is over the model's answer comes back without being held at the cap
+	clock.Set(now.Add(2 * time.Hour))
+	resp, err = predictor.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.False(t, resp.Held)
+	assert.Equal(t, 30.0, resp.HeatingTime)
+}
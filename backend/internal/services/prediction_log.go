@@ -0,0 +1,386 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPredictionNotFound is returned when a logged prediction does not exist or was served to
+	// another user
+	ErrPredictionNotFound = errors.New("prediction not found")
+	// ErrPredictionAnswered is returned when a prediction already has feedback of its own
+	ErrPredictionAnswered = errors.New("prediction already has feedback")
+)
+
+// predictionFeedbackWindow is how long before a feedback record's date the prediction it
+// answers may have been served
+const predictionFeedbackWindow = 24 * time.Hour
+
+// PredictionLogEntry is a served prediction with the outcome the user reported
+type PredictionLogEntry struct {
+	models.PredictionLog
+	Satisfaction      *float64 `json:"satisfaction,omitempty" range:"1-100" doc:"Satisfaction reported in the linked feedback"`
+	ActualHeatingTime *float64 `json:"actualHeatingTime,omitempty" unit:"min" doc:"What the user heated for in the end, from the linked feedback"`
+}
+
+// PredictionLogFilter narrows an export; zero fields don't filter
+type PredictionLogFilter struct {
+	UserID string
+	From   *time.Time
+	To     *time.Time
+}
+
+// PredictionLogService keeps a log of served predictions so model behaviour can be analysed
+// against the feedback that followed
+type PredictionLogService struct {
+	db        *gorm.DB // the log store
+	records   *gorm.DB // the operational database, for the feedback of exports
+	versionOf func(userID string) string
+	archive   *LogArchive
+}
+
+// NewPredictionLogService creates a prediction log; versionOf names the predictor version a
+// user is served
+func NewPredictionLogService(versionOf func(userID string) string) *PredictionLogService {
+	return &PredictionLogService{
+		db:        database.GetLogDB(),
+		records:   database.GetDB(),
+		versionOf: versionOf,
+	}
+}
+
+// SetArchive also appends every prediction logged to archive, as it was served
+func (s *PredictionLogService) SetArchive(archive *LogArchive) {
+	s.archive = archive
+}
+
+// Prune deletes the predictions logged before before
+func (s *PredictionLogService) Prune(before time.Time) (int64, error) {
+	result := s.db.Where("created_at < ?", before).Delete(&models.PredictionLog{})
+	return result.RowsAffected, result.Error
+}
+
+// VersionFor names the predictor version serving the user
+func (s *PredictionLogService) VersionFor(userID string) string {
+	return s.versionOf(userID)
+}
+
+// Record logs a prediction and sets its ID on the response, so feedback can name the prediction
+// it answers. Failures are only logged: the user still gets their answer.
+func (s *PredictionLogService) Record(req PredictionRequest, resp *PredictionResponse) {
+	version := s.versionOf(req.UserID)
+	entry := &models.PredictionLog{
+		UserID:           req.UserID,
+		MemberID:         req.MemberID,
+		DeviceID:         req.DeviceID,
+		Duration:         req.Duration,
+		Temperature:      req.Temperature,
+		InletTemperature: req.InletTemperature,
+		ModelVersion:     version,
+		ModelRevision:    ModelRevision(version),
+		HeatingTime:      resp.HeatingTime,
+		Stale:            resp.Stale,
+		LargeAdjustment:  req.AllowLargeAdjustment,
+		Held:             resp.Held,
+		ModelHeatingTime: resp.ModelHeatingTime,
+		Seed:             resp.Seed,
+		CorrelationID:    resp.CorrelationID,
+	}
+	if resp.Temperature != nil {
+		entry.TemperatureSource = resp.Temperature.Source
+	}
+	if resp.GuardedHeatingTime > 0 {
+		guarded := resp.GuardedHeatingTime
+		entry.GuardedHeatingTime = &guarded
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		log.Printf("Failed to log prediction for %s: %v", req.UserID, err)
+		return
+	}
+	resp.PredictionID = entry.ID
+	if s.archive != nil {
+		if err := s.archive.Append(entry); err != nil {
+			log.Printf("Failed to archive prediction %s: %v", entry.ID, err)
+		}
+	}
+}
+
+// Get returns a prediction served to the user
+func (s *PredictionLogService) Get(userID, id string) (*models.PredictionLog, error) {
+	var entry models.PredictionLog
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPredictionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Claim links the prediction to the feedback record about to be stored for it. Claiming it again
+// for the same record succeeds, so a retried request goes through; a prediction answered by
+// another record returns ErrPredictionAnswered.
+func (s *PredictionLogService) Claim(id, recordID string) error {
+	result := s.db.Model(&models.PredictionLog{}).
+		Where("id = ? AND record_id IN ?", id, []string{"", recordID}).
+		Update("record_id", recordID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPredictionAnswered
+	}
+	return nil
+}
+
+// Release undoes a claim whose record couldn't be stored
+func (s *PredictionLogService) Release(id, recordID string) {
+	err := s.db.Model(&models.PredictionLog{}).Where("id = ? AND record_id = ?", id, recordID).Update("record_id", "").Error
+	if err != nil {
+		log.Printf("Failed to release prediction %s: %v", id, err)
+	}
+}
+
+// LinkFeedback attaches a feedback record to the prediction it answers: the unanswered one
+// served under the record's correlation ID, or without one the latest unanswered prediction
+// served to the same user and member in the day before it. Records that claimed their
+// prediction are left alone, and a prediction another record answered meanwhile is not taken
+// over.
+func (s *PredictionLogService) LinkFeedback(record models.DailyRecord) {
+	var claimed int64
+	if err := s.db.Model(&models.PredictionLog{}).Where("record_id = ?", record.ID).Count(&claimed).Error; err != nil {
+		log.Printf("Failed to find prediction for record %s: %v", record.ID, err)
+		return
+	}
+	if claimed > 0 {
+		return
+	}
+	query := s.db.Where("user_id = ? AND record_id = ?", record.UserID, "")
+	if record.CorrelationID != "" {
+		query = query.Where("correlation_id = ?", record.CorrelationID)
+	} else {
+		query = query.Where("member_id = ? AND created_at BETWEEN ? AND ?",
+			record.MemberID, record.Date.Add(-predictionFeedbackWindow), record.Date)
+	}
+	var entry models.PredictionLog
+	if err := query.Order("created_at DESC").Limit(1).Find(&entry).Error; err != nil {
+		log.Printf("Failed to find prediction for record %s: %v", record.ID, err)
+		return
+	}
+	if entry.ID == "" {
+		return
+	}
+	err := s.db.Model(&models.PredictionLog{}).Where("id = ? AND record_id = ?", entry.ID, "").
+		Update("record_id", record.ID).Error
+	if err != nil {
+		log.Printf("Failed to link record %s to prediction %s: %v", record.ID, entry.ID, err)
+	}
+}
+
+// LatestHeatingTime returns the heating time last served to the user for the device in the day
+// before the given time, ok false when there was none
+func (s *PredictionLogService) LatestHeatingTime(userID, deviceID string, before time.Time) (float64, bool, error) {
+	var entry models.PredictionLog
+	err := s.db.Where("user_id = ? AND device_id = ? AND created_at BETWEEN ? AND ?",
+		userID, deviceID, before.Add(-predictionFeedbackWindow), before).
+		Order("created_at DESC").Limit(1).Find(&entry).Error
+	if err != nil {
+		return 0, false, err
+	}
+	return entry.HeatingTime, entry.ID != "", nil
+}
+
+// LatestUnanswered returns the last prediction served to the user in the day before the given
+// time that has no feedback yet, ErrPredictionNotFound when there is none
+func (s *PredictionLogService) LatestUnanswered(userID string, before time.Time) (*models.PredictionLog, error) {
+	var entries []models.PredictionLog
+	err := s.db.Where("user_id = ? AND record_id = ? AND created_at BETWEEN ? AND ?",
+		userID, "", before.Add(-predictionFeedbackWindow), before).
+		Order("created_at DESC").Limit(1).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrPredictionNotFound
+	}
+	return &entries[0], nil
+}
+
+// guardContextTolerance is how far a logged prediction's duration (minutes) and temperature
+// (°C) may be from a request's for the two to count as the same context
+const guardContextTolerance = 0.5
+
+// LastServed returns the latest prediction served since the given time for the request's
+// user, member and device and a duration and temperature within guardContextTolerance of it,
+// or nil when there was none
+func (s *PredictionLogService) LastServed(req PredictionRequest, since time.Time) (*models.PredictionLog, error) {
+	var entries []models.PredictionLog
+	err := s.db.Where("user_id = ? AND member_id = ? AND device_id = ? AND created_at >= ? AND duration BETWEEN ? AND ? AND temperature BETWEEN ? AND ?",
+		req.UserID, req.MemberID, req.DeviceID, since,
+		req.Duration-guardContextTolerance, req.Duration+guardContextTolerance,
+		req.Temperature-guardContextTolerance, req.Temperature+guardContextTolerance).
+		Order("created_at DESC").Limit(1).Find(&entries).Error
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// Correlated returns the predictions served under a correlation ID, oldest first
+func (s *PredictionLogService) Correlated(correlationID string) ([]models.PredictionLog, error) {
+	var entries []models.PredictionLog
+	err := s.db.Where("correlation_id = ?", correlationID).Order("created_at ASC").Find(&entries).Error
+	return entries, err
+}
+
+// CorrelationOf returns the correlation ID of the prediction a feedback record answers, empty
+// when it answers none or the prediction had none
+func (s *PredictionLogService) CorrelationOf(recordID string) (string, error) {
+	var entries []models.PredictionLog
+	if err := s.db.Where("record_id = ?", recordID).Limit(1).Find(&entries).Error; err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	return entries[0].CorrelationID, nil
+}
+
+// ListHeld returns the most recent predictions the rate-of-change guard held, newest first
+func (s *PredictionLogService) ListHeld(limit int) ([]models.PredictionLog, error) {
+	var entries []models.PredictionLog
+	err := s.db.Where("held = ?", true).Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// Export returns logged predictions with their feedback, oldest first. The feedback is looked
+// up separately, since the log may be kept apart from the records.
+func (s *PredictionLogService) Export(filter PredictionLogFilter) ([]PredictionLogEntry, error) {
+	query := s.db.Model(&models.PredictionLog{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	var logged []models.PredictionLog
+	if err := query.Order("created_at ASC").Find(&logged).Error; err != nil {
+		return nil, err
+	}
+
+	var recordIDs []string
+	for _, entry := range logged {
+		if entry.RecordID != "" {
+			recordIDs = append(recordIDs, entry.RecordID)
+		}
+	}
+	feedback, err := recordsByID(s.records, recordIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]PredictionLogEntry, len(logged))
+	for i, entry := range logged {
+		entries[i].PredictionLog = entry
+		if record, ok := feedback[entry.RecordID]; ok {
+			satisfaction, heating := record.Satisfaction, record.HeatingTime
+			entries[i].Satisfaction, entries[i].ActualHeatingTime = &satisfaction, &heating
+		}
+	}
+	return entries, nil
+}
+
+// recordIDBatch is how many records recordsByID asks for per query, under SQLite's variable limit
+const recordIDBatch = 500
+
+// recordsByID loads the records with the given IDs, by ID
+func recordsByID(db *gorm.DB, ids []string) (map[string]models.DailyRecord, error) {
+	records := make(map[string]models.DailyRecord, len(ids))
+	for start := 0; start < len(ids); start += recordIDBatch {
+		end := start + recordIDBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var batch []models.DailyRecord
+		if err := db.Where("id IN ?", ids[start:end]).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range batch {
+			records[record.ID] = record
+		}
+	}
+	return records, nil
+}
+
+// seedText is a logged seed, empty for predictions that drew no randomness
+func seedText(seed int64) string {
+	if seed == 0 {
+		return ""
+	}
+	return strconv.FormatInt(seed, 10)
+}
+
+// predictionLogCSVHeader is the column layout of prediction log exports
+var predictionLogCSVHeader = []string{"ID", "Time", "User ID", "Member ID", "Device ID", "Shower Duration", "Temperature", "Temperature Source", "Inlet Temperature", "Model Version", "Model Revision", "Predicted Heating Time", "Stale", "Large Adjustment", "Held", "Model Heating Time", "Record ID", "Actual Heating Time", "Satisfaction", "Seed"}
+
+// WritePredictionLogCSV writes prediction log entries as CSV, including the header row, with
+// times and numbers in the given format. Missing values are left empty.
+func WritePredictionLogCSV(w io.Writer, entries []PredictionLogEntry, format ExportFormat) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = format.Separator()
+
+	if err := writer.Write(predictionLogCSVHeader); err != nil {
+		return err
+	}
+
+	optional := func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return format.Number(*value, 1)
+	}
+	for _, entry := range entries {
+		row := []string{
+			entry.ID,
+			format.Time(entry.CreatedAt, time.RFC3339),
+			entry.UserID,
+			entry.MemberID,
+			entry.DeviceID,
+			format.Number(entry.Duration, 1),
+			format.Number(entry.Temperature, 1),
+			entry.TemperatureSource,
+			optional(entry.InletTemperature),
+			entry.ModelVersion,
+			modelRevisionText(entry.ModelRevision),
+			format.Number(entry.HeatingTime, 1),
+			strconv.FormatBool(entry.Stale),
+			strconv.FormatBool(entry.LargeAdjustment),
+			strconv.FormatBool(entry.Held),
+			optional(entry.ModelHeatingTime),
+			entry.RecordID,
+			optional(entry.ActualHeatingTime),
+			optional(entry.Satisfaction),
+			seedText(entry.Seed),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
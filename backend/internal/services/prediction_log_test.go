@@ -0,0 +1,35 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePredictionLogCSV(t *testing.T) {
+	satisfaction, actual := 80.0, 25.0
+	entries := []PredictionLogEntry{
+		{
+			PredictionLog: models.PredictionLog{
+				ID: "p1", UserID: "user1", Duration: 10, Temperature: 15, TemperatureSource: "manual",
+				ModelVersion: "v2", ModelRevision: 1, HeatingTime: 22.5, RecordID: "r1", Seed: 42,
+				CreatedAt: time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC),
+			},
+			Satisfaction: &satisfaction, ActualHeatingTime: &actual,
+		},
+		{PredictionLog: models.PredictionLog{ID: "p2", UserID: "user2", Duration: 8, ModelVersion: "v1", HeatingTime: 18, Stale: true}},
+	}
+
+	var out strings.Builder
+	require.NoError(t, WritePredictionLogCSV(&out, entries, ExportFormat{}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, strings.Join(predictionLogCSVHeader, ","), lines[0])
+	assert.Equal(t, "p1,2026-03-10T07:00:00Z,user1,,,10.0,15.0,manual,,v2,1,22.5,false,false,false,,r1,25.0,80.0,42", lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], ",v1,,18.0,true,false,false,,,,,"), "unanswered predictions leave the feedback and seed columns empty")
+}
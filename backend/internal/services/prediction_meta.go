@@ -0,0 +1,55 @@
+package services
+
+import (
+	"math"
+	"time"
+)
+
+// Reasons a prediction wasn't the model's own estimate, in PredictionMeta.Fallback
+const (
+	FallbackShed      = "shed"      // the server was too busy to run the predictor
+	FallbackStale     = "stale"     // the predictor timed out and the last known prediction was served
+	FallbackHeld      = "held"      // the answer moved too far without new feedback and the last one was served
+	FallbackBaseline  = "baseline"  // there was no history to learn from
+	FallbackAnonymity = "anonymity" // other users' records would have led but came from too few of them
+)
+
+// PredictionMeta is a short account of how a prediction was served, for clients that monitor
+// predictions without reading the whole explanation
+type PredictionMeta struct {
+	ModelVersion  string  `json:"modelVersion"`
+	ModelRevision int     `json:"modelRevision"`
+	Neighbors     int     `json:"neighbors"`          // records the estimate drew on; 0 for v1
+	Confidence    float64 `json:"confidence"`         // 0..1, how many and how consistent those records are; 0 for v1
+	Fallback      string  `json:"fallback,omitempty"` // why the model's own estimate wasn't served, if it wasn't
+	CacheHit      bool    `json:"cacheHit"`           // served from the last known predictions
+	ComputeMillis float64 `json:"computeMs"`          // time spent predicting
+}
+
+// NewPredictionMeta describes resp, served by version after elapsed. It reads the explanation,
+// so it has to be built before that is dropped from the response.
+func NewPredictionMeta(resp *PredictionResponse, version string, elapsed time.Duration) *PredictionMeta {
+	meta := &PredictionMeta{
+		ModelVersion:  version,
+		ModelRevision: ModelRevision(version),
+		CacheHit:      resp.Stale,
+		ComputeMillis: math.Round(float64(elapsed.Microseconds())/10) / 100,
+	}
+	if expl := resp.Explanation; expl != nil {
+		meta.Neighbors = expl.Neighbors
+		meta.Confidence = expl.Confidence
+	}
+	switch {
+	case resp.Shed:
+		meta.Fallback = FallbackShed
+	case resp.Stale:
+		meta.Fallback = FallbackStale
+	case resp.Held:
+		meta.Fallback = FallbackHeld
+	case resp.Explanation != nil && resp.Explanation.AnonymityFallback:
+		meta.Fallback = FallbackAnonymity
+	case resp.Baseline:
+		meta.Fallback = FallbackBaseline
+	}
+	return meta
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPredictionMeta(t *testing.T) {
+	resp := &PredictionResponse{HeatingTime: 20, Explanation: &PredictionExplanation{Neighbors: 12, Confidence: 0.74}}
+	meta := NewPredictionMeta(resp, "v2", 1234*time.Microsecond)
+	assert.Equal(t, &PredictionMeta{ModelVersion: "v2", ModelRevision: 1, Neighbors: 12, Confidence: 0.74, ComputeMillis: 1.23}, meta)
+
+	for _, tc := range []struct {
+		resp     PredictionResponse
+		fallback string
+		cacheHit bool
+	}{
+		{PredictionResponse{Shed: true, Stale: true}, FallbackShed, true},
+		{PredictionResponse{Stale: true}, FallbackStale, true},
+		{PredictionResponse{Held: true}, FallbackHeld, false},
+		{PredictionResponse{Baseline: true, Explanation: &PredictionExplanation{AnonymityFallback: true}}, FallbackAnonymity, false},
+		{PredictionResponse{Baseline: true}, FallbackBaseline, false},
+	} {
+		meta := NewPredictionMeta(&tc.resp, "v2", 0)
+		assert.Equal(t, tc.fallback, meta.Fallback)
+		assert.Equal(t, tc.cacheHit, meta.CacheHit, tc.fallback)
+	}
+}
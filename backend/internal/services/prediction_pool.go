@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// PoolPredictor bounds how many predictions run at once. Each prediction sorts over the
+// user's and the global history, so on small hosts such as a Raspberry Pi unbounded
+// parallelism slows every request down. Requests beyond the workers wait in a queue; when
+// the queue is full too the request is shed and answered from the last known prediction for
+// its context, or a conservative default, marked Shed.
+type PoolPredictor struct {
+	inner     Predictor
+	slots     chan struct{} // one token per running prediction
+	queue     int
+	lastKnown *lastKnownCache
+
+	mu      sync.Mutex
+	waiting int
+
+	minMinutes float64
+	maxMinutes float64
+}
+
+// NewPoolPredictor runs at most workers predictions of inner at once, with up to queue more waiting
+func NewPoolPredictor(inner Predictor, workers, queue int) *PoolPredictor {
+	if workers < 1 {
+		workers = 1
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	return &PoolPredictor{
+		inner:      inner,
+		slots:      make(chan struct{}, workers),
+		queue:      queue,
+		lastKnown:  newLastKnownCache(),
+		minMinutes: 5,
+		maxMinutes: 120,
+	}
+}
+
+// SetHeatingBounds sets the range the default answer for shed requests is clamped to
+func (p *PoolPredictor) SetHeatingBounds(minMinutes, maxMinutes float64) {
+	p.minMinutes = minMinutes
+	p.maxMinutes = maxMinutes
+}
+
+// Predict runs the wrapped predictor once a worker is free, or sheds the request when the
+// queue is full
+func (p *PoolPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		if !p.enqueue() {
+			return p.shed(req), nil
+		}
+		select {
+		case p.slots <- struct{}{}:
+			p.dequeue()
+		case <-ctx.Done():
+			p.dequeue()
+			return nil, ctx.Err()
+		}
+	}
+	defer func() { <-p.slots }()
+
+	resp, err := p.inner.Predict(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p.lastKnown.remember(req, resp)
+	return resp, nil
+}
+
+// ForgetUser drops every cached prediction for the user
+func (p *PoolPredictor) ForgetUser(userID string) {
+	p.lastKnown.forgetUser(userID)
+}
+
+// enqueue takes a place in the queue, reporting false when it is full
+func (p *PoolPredictor) enqueue() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.waiting >= p.queue {
+		return false
+	}
+	p.waiting++
+	return true
+}
+
+func (p *PoolPredictor) dequeue() {
+	p.mu.Lock()
+	p.waiting--
+	p.mu.Unlock()
+}
+
+// shed answers without running the predictor
+func (p *PoolPredictor) shed(req PredictionRequest) *PredictionResponse {
+	resp, ok := p.lastKnown.lookup(req)
+	if !ok {
+		// The same conservative default the predictors use without any data
+		resp = &PredictionResponse{HeatingTime: clamp(30.0, p.minMinutes, p.maxMinutes)}
+	}
+	resp.Shed = true
+	resp.Explanation = nil
+	return resp
+}
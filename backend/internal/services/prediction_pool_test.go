@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedPredictor answers with a fixed value once its gate is opened
+type gatedPredictor struct {
+	gate    chan struct{}
+	started chan struct{}
+	answer  float64
+}
+
+func newGatedPredictor(answer float64) *gatedPredictor {
+	return &gatedPredictor{gate: make(chan struct{}), started: make(chan struct{}, 10), answer: answer}
+}
+
+func (p *gatedPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	p.started <- struct{}{}
+	select {
+	case <-p.gate:
+		return &PredictionResponse{HeatingTime: p.answer}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestPoolPredictor_ShedsWhenSaturated(t *testing.T) {
+	inner := newGatedPredictor(17)
+	pool := NewPoolPredictor(inner, 1, 0)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 20}
+
+	// Occupy the only worker
+	done := make(chan *PredictionResponse)
+	go func() {
+		resp, _ := pool.Predict(context.Background(), req)
+		done <- resp
+	}()
+	<-inner.started
+
+	// Nothing cached yet: the default answer
+	resp, err := pool.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, resp.Shed)
+	assert.Equal(t, 30.0, resp.HeatingTime)
+
+	close(inner.gate)
+	first := <-done
+	assert.False(t, first.Shed)
+	assert.Equal(t, 17.0, first.HeatingTime)
+
+	// Once cached, a shed request gets the last known value for its context
+	inner.gate = make(chan struct{})
+	go func() {
+		resp, _ := pool.Predict(context.Background(), req)
+		done <- resp
+	}()
+	<-inner.started
+	resp, err = pool.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, resp.Shed)
+	assert.True(t, resp.Stale)
+	assert.Equal(t, 17.0, resp.HeatingTime)
+	close(inner.gate)
+	<-done
+}
+
+func TestPoolPredictor_QueuedRequestWaitsForWorker(t *testing.T) {
+	inner := newGatedPredictor(17)
+	pool := NewPoolPredictor(inner, 1, 1)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 20}
+
+	done := make(chan *PredictionResponse, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, _ := pool.Predict(context.Background(), req)
+			done <- resp
+		}()
+	}
+	<-inner.started
+
+	// A third request finds the worker busy and the queue full
+	time.Sleep(20 * time.Millisecond)
+	resp, err := pool.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, resp.Shed)
+
+	close(inner.gate)
+	for i := 0; i < 2; i++ {
+		resp := <-done
+		assert.False(t, resp.Shed, "queued requests are predicted, not shed")
+		assert.Equal(t, 17.0, resp.HeatingTime)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PredictionProfileVersion is the format of exported prediction profiles
+const PredictionProfileVersion = 1
+
+// ErrInvalidPredictionProfile is returned for a profile of another version or with unknown global settings
+var ErrInvalidPredictionProfile = errors.New("invalid prediction profile")
+
+// PredictionProfile is the tuned prediction configuration of an instance, to be imported on
+// another one. Global holds the prediction environment variables set on the exporting instance;
+// they take effect on a restart with PREDICTION_PROFILE pointing at the file. Users holds the
+// per-user overrides, which are applied on import.
+type PredictionProfile struct {
+	Version    int                      `json:"version"`
+	ExportedAt time.Time                `json:"exportedAt"`
+	Global     map[string]string        `json:"global"`
+	Users      []UserPredictionSettings `json:"users"`
+}
+
+// UserPredictionSettings are one user's overrides of the global prediction settings
+type UserPredictionSettings struct {
+	UserID   string          `json:"userId"`
+	StepCap  *float64        `json:"stepCap,omitempty"`
+	Rounding *RoundingPolicy `json:"rounding,omitempty"`
+}
+
+// Validate checks the profile's version, global settings and user overrides
+func (p *PredictionProfile) Validate() error {
+	if p.Version != PredictionProfileVersion {
+		return fmt.Errorf("%w: version must be %d", ErrInvalidPredictionProfile, PredictionProfileVersion)
+	}
+	if unknown := config.UnknownProfileSettings(p.Global); len(unknown) > 0 {
+		return fmt.Errorf("%w: %v are not prediction settings", ErrInvalidPredictionProfile, unknown)
+	}
+	for _, user := range p.Users {
+		if user.UserID == "" {
+			return fmt.Errorf("%w: every user needs a userId", ErrInvalidPredictionProfile)
+		}
+		if user.StepCap != nil && (*user.StepCap <= 0 || *user.StepCap > 1) {
+			return ErrInvalidStepCap
+		}
+		if user.Rounding != nil {
+			if err := user.Rounding.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SettingChange is a global setting whose value differs between a profile and this instance
+type SettingChange struct {
+	Key     string `json:"key"`
+	Current string `json:"current"` // empty while the default applies
+	Profile string `json:"profile"`
+}
+
+// GlobalChanges lists the profile's global settings that differ from current, sorted by key
+func (p *PredictionProfile) GlobalChanges(current map[string]string) []SettingChange {
+	changes := []SettingChange{}
+	for _, key := range config.ProfileSettings {
+		if value, ok := p.Global[key]; ok && value != current[key] {
+			changes = append(changes, SettingChange{Key: key, Current: current[key], Profile: value})
+		}
+	}
+	return changes
+}
+
+// settingsOf returns the user's overrides, ok false when they have none
+func settingsOf(user models.User) (UserPredictionSettings, bool) {
+	settings := UserPredictionSettings{UserID: user.ID, StepCap: user.StepCapFraction}
+	if user.RoundingMode != "" || user.RoundingStep != 0 || user.RoundingHysteresis != 0 {
+		settings.Rounding = &RoundingPolicy{Mode: user.RoundingMode, Step: user.RoundingStep, Hysteresis: user.RoundingHysteresis}
+	}
+	return settings, settings.StepCap != nil || settings.Rounding != nil
+}
+
+// PredictionSettings returns the overrides of every user who has any, or only of userID when
+// it isn't empty
+func (s *UserService) PredictionSettings(userID string) ([]UserPredictionSettings, error) {
+	query := s.db.Order("id ASC")
+	if userID != "" {
+		query = query.Where("id = ?", userID)
+	}
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	settings := []UserPredictionSettings{}
+	for _, user := range users {
+		if userSettings, ok := settingsOf(user); ok {
+			settings = append(settings, userSettings)
+		}
+	}
+	return settings, nil
+}
+
+// ImportPredictionSettings replaces the overrides of each listed user in one transaction;
+// fields a user's entry leaves out restore the defaults
+func (s *UserService) ImportPredictionSettings(settings []UserPredictionSettings) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range settings {
+			user := models.User{ID: entry.UserID, StepCapFraction: entry.StepCap}
+			if entry.Rounding != nil {
+				user.RoundingMode = entry.Rounding.Mode
+				user.RoundingStep = entry.Rounding.Step
+				user.RoundingHysteresis = entry.Rounding.Hysteresis
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"step_cap_fraction", "rounding_mode", "rounding_step", "rounding_hysteresis", "updated_at"}),
+			}).Create(&user).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PreviewPredictionSettings reports what ImportPredictionSettings would change: the users whose
+// overrides would be replaced, and how many of them don't exist yet and would be created
+func (s *UserService) PreviewPredictionSettings(settings []UserPredictionSettings) (*DryRunResult, error) {
+	ids := make([]string, 0, len(settings))
+	preview := &DryRunResult{SampleIDs: []string{}}
+	for _, entry := range settings {
+		ids = append(ids, entry.UserID)
+		preview.sample(entry.UserID)
+	}
+	var existing int64
+	if err := s.db.Model(&models.User{}).Where("id IN ?", ids).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	preview.Counts = map[string]int64{"users": int64(len(settings)), "created": int64(len(settings)) - existing}
+	return preview, nil
+}
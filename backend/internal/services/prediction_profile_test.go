@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredictionProfile_Validate(t *testing.T) {
+	stepCap := 0.5
+	profile := PredictionProfile{
+		Version: PredictionProfileVersion,
+		Global:  map[string]string{"PREDICTION_V2_K": "12"},
+		Users:   []UserPredictionSettings{{UserID: "user1", StepCap: &stepCap, Rounding: &RoundingPolicy{Mode: RoundingCeil}}},
+	}
+	assert.NoError(t, profile.Validate())
+
+	withSecret := profile
+	withSecret.Global = map[string]string{"PREDICTION_V2_K": "12", "ADMIN_TOKEN": "x"}
+	assert.True(t, errors.Is(withSecret.Validate(), ErrInvalidPredictionProfile), "only prediction settings travel in a profile")
+
+	future := profile
+	future.Version = PredictionProfileVersion + 1
+	assert.True(t, errors.Is(future.Validate(), ErrInvalidPredictionProfile))
+
+	tooHigh := 1.5
+	badCap := profile
+	badCap.Users = []UserPredictionSettings{{UserID: "user1", StepCap: &tooHigh}}
+	assert.ErrorIs(t, badCap.Validate(), ErrInvalidStepCap)
+
+	badRounding := profile
+	badRounding.Users = []UserPredictionSettings{{UserID: "user1", Rounding: &RoundingPolicy{Mode: "floor"}}}
+	assert.ErrorIs(t, badRounding.Validate(), ErrInvalidRounding)
+}
+
+func TestPredictionProfile_GlobalChanges(t *testing.T) {
+	profile := PredictionProfile{Global: map[string]string{
+		"PREDICTION_V2_K":        "12",
+		"PREDICTION_V2_STEP_CAP": "0.3",
+		"PREDICTOR_VERSION":      "v2",
+	}}
+	current := map[string]string{"PREDICTION_V2_K": "12", "PREDICTION_V2_STEP_CAP": "0.5"}
+
+	assert.Equal(t, []SettingChange{
+		{Key: "PREDICTOR_VERSION", Current: "", Profile: "v2"},
+		{Key: "PREDICTION_V2_STEP_CAP", Current: "0.5", Profile: "0.3"},
+	}, profile.GlobalChanges(current))
+}
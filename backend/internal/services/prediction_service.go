@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"math"
 	"time"
 
@@ -14,15 +15,28 @@ type RecordServiceInterface interface {
 	GetRecordsForPrediction(limit int) ([]models.DailyRecord, error)
 }
 
+// hardwareChangeDecay is the weight v1 keeps for user records that predate a hardware change
+const hardwareChangeDecay = 0.05
+
 // PredictionService handles ML prediction logic
 type PredictionService struct {
 	recordService RecordServiceInterface
+	annotations   AnnotationLookup
+	sourceWeights SourceWeights
+	residualHeat  ResidualHeatModel
+	resolutions   ResolutionLookup
+	targets       SatisfactionTargetLookup
+	calibration   RatingCalibrationLookup
+	clock         Clock
+	minMinutes    float64 // 5-120 minutes when unset
+	maxMinutes    float64
 }
 
 // NewPredictionService creates a new prediction service instance
-func NewPredictionService(recordService *RecordService) *PredictionService {
+func NewPredictionService(recordService RecordServiceInterface) *PredictionService {
 	return &PredictionService{
 		recordService: recordService,
+		sourceWeights: DefaultSourceWeights(),
 	}
 }
 
@@ -31,11 +45,45 @@ type PredictionRequest struct {
 	UserID      string  `json:"userId" binding:"required"`
 	Duration    float64 `json:"duration" binding:"required,min=1,max=60"`
 	Temperature float64 `json:"temperature" binding:"required,min=-50,max=50"`
+	MemberID    string  `json:"memberId,omitempty"` // personalise for a household member of the account
+	DeviceID    string  `json:"deviceId,omitempty"` // the water heater, for its insulation
+
+	InletTemperature *float64 `json:"inletTemperature,omitempty"` // measured cold-water inlet, °C; estimated when nil
+
+	AllowLargeAdjustment bool `json:"allowLargeAdjustment,omitempty"` // skip v2's step cap for this one prediction
+
+	hardwareChangedAt  *time.Time // resolved by the predictor, not supplied by clients
+	withoutExploration bool       // for answers only shown, with no feedback collected on them
 }
 
 // PredictionResponse represents the prediction output
 type PredictionResponse struct {
-	HeatingTime float64 `json:"heatingTime"`
+	HeatingTime    float64 `json:"heatingTime"`
+	HeatingSeconds int     `json:"heatingSeconds,omitempty"` // the heating time in seconds, set by the API
+	RawHeatingTime float64 `json:"rawHeatingTime,omitempty"` // before rounding, for clients that round themselves
+	Stale          bool    `json:"stale,omitempty"`          // true when served from the last-known cache after a timeout
+	Shed           bool    `json:"shed,omitempty"`           // true when the server was too busy to run the predictor
+	Held           bool    `json:"held,omitempty"`           // true when the answer moved too far from the last one served without new feedback, which was served instead
+	PredictionID   string  `json:"predictionId,omitempty"`   // the logged prediction, set by the API for quick feedback
+	CorrelationID  string  `json:"correlationId,omitempty"`  // ties the prediction to the schedule and device events that follow it, set by the API
+	Seed           int64   `json:"-"`                        // what the prediction's random generator was seeded with, for the log
+	Baseline       bool    `json:"-"`                        // true when the predictor had no history to learn from and served its baseline
+
+	GuardedHeatingTime float64 `json:"-"` // what the output guard passed on, before the adjustments wrapped around it, for the log
+
+	ModelHeatingTime *float64 `json:"modelHeatingTime,omitempty"` // what the model answered, when Held
+
+	TargetTemperature *float64 `json:"targetTemperature,omitempty"` // °C, set by the API for devices in temperature output mode
+
+	Temperature *TemperatureReading `json:"temperature,omitempty"` // the temperature used and its source, set by the API
+
+	DemandResponse *DemandResponseNotice `json:"demandResponse,omitempty"` // set while a grid demand-response event caps or holds back heating
+
+	Warning     string   `json:"warning,omitempty"`     // set by the API when one tank can't supply the shower
+	MaxDuration *float64 `json:"maxDuration,omitempty"` // the longest shower one tank supplies, with the warning
+
+	Explanation *PredictionExplanation `json:"explanation,omitempty"` // only returned when the client asks for it
+	Meta        *PredictionMeta        `json:"meta,omitempty"`        // only returned when the client asks for it
 }
 
 // SimilarRecord represents a record with similarity score
@@ -45,8 +93,71 @@ type SimilarRecord struct {
 	Weight     float64
 }
 
+// SetAnnotationLookup enables down-weighting of user history that predates a hardware change
+func (s *PredictionService) SetAnnotationLookup(annotations AnnotationLookup) {
+	s.annotations = annotations
+}
+
+// SetSourceWeights overrides how much records from each source count, on top of the defaults
+func (s *PredictionService) SetSourceWeights(weights SourceWeights) {
+	s.sourceWeights = weights.withDefaults()
+}
+
+// SetResidualHeatModel enables subtracting heat left in the tank from the last session
+func (s *PredictionService) SetResidualHeatModel(model ResidualHeatModel) {
+	s.residualHeat = model
+}
+
+// SetResolutionLookup rounds predictions for a device to the steps its timer can be set in
+func (s *PredictionService) SetResolutionLookup(resolutions ResolutionLookup) {
+	s.resolutions = resolutions
+}
+
+// SetSatisfactionTargets rescales each user's ratings so the rating they give perfect water
+// reads as 50 for the adjustment math
+func (s *PredictionService) SetSatisfactionTargets(targets SatisfactionTargetLookup) {
+	s.targets = targets
+}
+
+// SetRatingCalibration stretches other users' ratings by their fitted scale before they enter
+// the global pool, so ratings from users who only move the slider a little count fully
+func (s *PredictionService) SetRatingCalibration(calibration RatingCalibrationLookup) {
+	s.calibration = calibration
+}
+
+// SetClock sets the clock records are aged against, e.g. a frozen one for backtests
+func (s *PredictionService) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// now reads the clock, the wall clock when none was set
+func (s *PredictionService) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// SetHeatingBounds overrides the range predictions are clamped to
+func (s *PredictionService) SetHeatingBounds(minMinutes, maxMinutes float64) {
+	s.minMinutes = minMinutes
+	s.maxMinutes = maxMinutes
+}
+
+// heatingBounds returns the range predictions are clamped to
+func (s *PredictionService) heatingBounds() (float64, float64) {
+	if s.maxMinutes <= 0 {
+		return 5, 120
+	}
+	return s.minMinutes, s.maxMinutes
+}
+
 // PredictHeatingTime calculates the optimal heating time using hybrid user/global model
 func (s *PredictionService) PredictHeatingTime(req *PredictionRequest) (*PredictionResponse, error) {
+	return s.predictHeatingTime(context.Background(), req)
+}
+
+func (s *PredictionService) predictHeatingTime(ctx context.Context, req *PredictionRequest) (*PredictionResponse, error) {
 	// Get user-specific records
 	userRecords, err := s.recordService.GetRecordsForPredictionByUser(req.UserID, 50)
 	if err != nil {
@@ -58,12 +169,44 @@ func (s *PredictionService) PredictHeatingTime(req *PredictionRequest) (*Predict
 	if err != nil {
 		return nil, err
 	}
+	userRecords = neutralizeRecords(userRecords, s.targets)
+	globalRecords = calibrateRecords(neutralizeRecords(globalRecords, s.targets), s.calibration)
+
+	// The tank is shared, so whoever showered last left the residual heat
+	residual := s.residualHeat.Minutes(req.DeviceID, userRecords, s.now())
+
+	// Personalise for a household member, see splitByMember
+	if req.MemberID != "" {
+		var household []models.DailyRecord
+		userRecords, household = splitByMember(userRecords, req.MemberID)
+		globalRecords = append(household, globalRecords...)
+	}
 
-	// Calculate hybrid prediction
-	heatingTime := s.getCombinedPrediction(req, userRecords, globalRecords)
+	// Only learn from the user's current regime
+	userRecords = truncateAtChangePoint(userRecords, DefaultChangePointConfig())
 
+	req.hardwareChangedAt, err = latestHardwareChange(s.annotations, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bail out early if the caller has already given up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Calculate hybrid prediction, less the heat still in the tank, within the heating bounds
+	minMinutes, maxMinutes := s.heatingBounds()
+	heatingTime := s.getCombinedPrediction(req, userRecords, globalRecords) - residual
+	heatingTime = math.Min(math.Max(heatingTime, minMinutes), maxMinutes)
+
+	// Round to whole minutes, or to the steps the device's timer takes
+	rounding := RoundingPolicy{Mode: RoundingNearest, Step: 1}
+	if s.resolutions != nil {
+		rounding = rounding.With(RoundingPolicy{Step: s.resolutions.Resolution(req.UserID, req.DeviceID)})
+	}
 	return &PredictionResponse{
-		HeatingTime: math.Round(heatingTime), // Round to whole minutes
+		HeatingTime: rounding.Round(heatingTime, nil),
 	}, nil
 }
 
@@ -77,8 +220,8 @@ func (s *PredictionService) predictWithDefaults(req *PredictionRequest) *Predict
 	heatingTime := baseTime + (req.Duration * durationFactor) + (req.Temperature * tempFactor)
 
 	// Ensure minimum heating time
-	if heatingTime < 5.0 {
-		heatingTime = 5.0
+	if minMinutes, _ := s.heatingBounds(); heatingTime < minMinutes {
+		heatingTime = minMinutes
 	}
 
 	return &PredictionResponse{
@@ -150,14 +293,8 @@ func (s *PredictionService) getCombinedPrediction(req *PredictionRequest, userRe
 	finalPrediction := (userPrediction * userWeight) + (globalPrediction * globalWeight)
 
 	// Ensure the prediction is within reasonable bounds
-	if finalPrediction < 5.0 {
-		return 5.0
-	}
-	if finalPrediction > 120.0 {
-		return 120.0
-	}
-
-	return finalPrediction
+	minMinutes, maxMinutes := s.heatingBounds()
+	return math.Min(math.Max(finalPrediction, minMinutes), maxMinutes)
 }
 
 // calculateUserWeight determines how much weight to give to user-specific data
@@ -276,13 +413,8 @@ func (s *PredictionService) calculatePrediction(req *PredictionRequest, records
 			finalPrediction = s.applySuccessAnchorLogic(finalPrediction, successAnchors)
 		}
 
-		if finalPrediction < 5.0 {
-			return 5.0
-		}
-		if finalPrediction > 120.0 {
-			return 120.0
-		}
-		return finalPrediction
+		minMinutes, maxMinutes := s.heatingBounds()
+		return math.Min(math.Max(finalPrediction, minMinutes), maxMinutes)
 	}
 
 	return s.predictWithDefaults(req).HeatingTime
@@ -422,7 +554,7 @@ type WeightedSuccessAnchor struct {
 // IMPROVEMENT: Find multiple weighted success anchors instead of just the last one
 func (s *PredictionService) findWeightedSuccessAnchors(records []models.DailyRecord) []WeightedSuccessAnchor {
 	var anchors []WeightedSuccessAnchor
-	now := time.Now()
+	now := s.now()
 
 	// Find all records with satisfaction > 55 (lowered threshold to include more hot feedback)
 	for i := len(records) - 1; i >= 0; i-- {
@@ -432,7 +564,7 @@ func (s *PredictionService) findWeightedSuccessAnchors(records []models.DailyRec
 			daysSince := now.Sub(record.Date).Hours() / 24.0
 			recencyWeight := math.Exp(-0.1 * daysSince)             // Decay over ~10 days
 			satisfactionWeight := (record.Satisfaction - 55) / 45.0 // 0-1 scale for 55-100
-			totalWeight := recencyWeight * (1.0 + satisfactionWeight)
+			totalWeight := recencyWeight * (1.0 + satisfactionWeight) * s.sourceWeights.For(record.Source) * record.Confidence()
 
 			anchors = append(anchors, WeightedSuccessAnchor{
 				Record: record,
@@ -596,7 +728,7 @@ func (s *PredictionService) countConsecutiveHotFeedback(records []models.DailyRe
 // findSimilarRecords finds records with similar temperature and duration
 func (s *PredictionService) findSimilarRecords(req *PredictionRequest, records []models.DailyRecord) []SimilarRecord {
 	var similarRecords []SimilarRecord
-	now := time.Now()
+	now := s.now()
 
 	for _, record := range records {
 		tempDiff := math.Abs(record.AverageTemperature - req.Temperature)
@@ -618,7 +750,12 @@ func (s *PredictionService) findSimilarRecords(req *PredictionRequest, records [
 		recencyWeight := math.Exp(-decayConstant * daysSince)
 
 		frequencyWeight := s.calculateFrequencyWeight(req, records, record)
-		totalWeight := overallSimilarity * recencyWeight * frequencyWeight
+		totalWeight := overallSimilarity * recencyWeight * frequencyWeight * s.sourceWeights.For(record.Source) * record.Confidence()
+
+		// The user's own history from before new hardware barely describes the current system
+		if req.hardwareChangedAt != nil && record.UserID == req.UserID && record.Date.Before(*req.hardwareChangedAt) {
+			totalWeight *= hardwareChangeDecay
+		}
 
 		similarRecords = append(similarRecords, SimilarRecord{
 			Record:     record,
@@ -690,6 +827,6 @@ func (s *PredictionService) calculatePerfectScoreDecay(perfectRecord models.Dail
 	return 1.0
 }
 
-func (s *PredictionService) Predict(req PredictionRequest) (*PredictionResponse, error) {
-	return s.PredictHeatingTime(&req)
+func (s *PredictionService) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	return s.predictHeatingTime(ctx, &req)
 }
@@ -429,3 +429,26 @@ func TestContextualLearningProgression(t *testing.T) {
 	// Verify mock expectations
 	mockRecordService.AssertExpectations(t)
 }
+
+func TestPredictionService_ResidualHeatStaysWithinBounds(t *testing.T) {
+	now := time.Now()
+	// The boiler ran for 40 minutes ten minutes ago, so the tank is still nearly full
+	userRecords := []models.DailyRecord{{
+		UserID: "user1", Date: now.Add(-10 * time.Minute),
+		ShowerDuration: 10, AverageTemperature: 20, HeatingTime: 40, Satisfaction: 50,
+	}}
+	globalRecords := []models.DailyRecord{{
+		UserID: "other_user", Date: now.AddDate(0, 0, -1),
+		ShowerDuration: 10, AverageTemperature: 20, HeatingTime: 8, Satisfaction: 50,
+	}}
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 50).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 200).Return(globalRecords, nil)
+	service := NewPredictionService(mockRecordService)
+	service.SetResidualHeatModel(ResidualHeatModel{HalfLife: 4 * time.Hour})
+	service.SetHeatingBounds(6, 120)
+
+	result, err := service.PredictHeatingTime(&PredictionRequest{UserID: "user1", Duration: 10, Temperature: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, 6.0, result.HeatingTime, "the minimum heating time, not zero")
+}
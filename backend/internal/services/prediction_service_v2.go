@@ -1,11 +1,15 @@
 package services
 
 import (
+	"container/heap"
+	"context"
 	"fmt"
 	"math"
+	"math/rand"
 	"sort"
 	"time"
 
+	"heat-logger/internal/config"
 	"heat-logger/internal/models"
 )
 
@@ -16,13 +20,13 @@ import (
 // blends user + global data with distance kernels, and uses symmetric "success anchors".
 //
 // Key ideas:
-//  - Distance weighting: Gaussian on duration (minutes) and temperature (°C).
+//  - Distance weighting: Gaussian on duration (minutes), temperature (°C) and inlet water temperature (°C).
 //  - Recency decay: half‑life in days.
 //  - Anchor boost: near‑perfect satisfaction (|s-50|<=ε) gets extra weight on both sides.
 //  - User vs global: explicit userBoost multiplier instead of hard fallbacks.
 //  - Frequency control: down‑weight repeated (duration,temp) cells so one context doesn't dominate.
 //  - Safety clamps: configurable % step cap vs last user record and absolute [min,max] bounds.
-//  - Risk policy: "NeverCold" prefers rounding up; otherwise round to nearest minute.
+//  - Risk policy: a rounding policy (smart, nearest or ceil, at a configurable step); "NeverCold" always rounds up.
 //
 // Integrate by constructing PredictionServiceV2 and calling Predict(req).
 // You can keep the old service side‑by‑side during rollout.
@@ -33,10 +37,40 @@ type recWrap struct {
 	weight  float64
 	anchor  bool
 	cellKey string
+
+	crossRegion bool // another user's record from a different climate region
+}
+
+// RoundingLookup reports a user's own rounding preferences; unset fields are zero
+type RoundingLookup interface {
+	RoundingPolicy(userID string) (RoundingPolicy, error)
+}
+
+// StepCapLookup reports a user's own step cap, nil when they use the default
+type StepCapLookup interface {
+	StepCap(userID string) (*float64, error)
 }
 
 type PredictionServiceV2 struct {
 	recordService RecordServiceInterface
+	globalRecords *GlobalSnapshot
+	candidates    *CandidateIndex
+	contributors  ContributorWeightLookup
+	regions       RegionLookup
+	targets       SatisfactionTargetLookup
+	calibration   RatingCalibrationLookup
+	annotations   AnnotationLookup
+	stepCaps      StepCapLookup
+	roundings     RoundingLookup
+	resolutions   ResolutionLookup
+	tunings       DeviceTuningLookup
+	locality      LocalityLookup
+	anchorLog     *AnchorDiagnostics
+	sourceWeights SourceWeights
+	inlet         InletModel
+	residualHeat  ResidualHeatModel
+	clock         Clock
+	random        *RandomSource
 	cfg           PredictionConfigV2
 }
 
@@ -44,6 +78,7 @@ type PredictionConfigV2 struct {
 	// Gaussian kernel sigmas
 	SigmaDuration float64 // minutes
 	SigmaTemp     float64 // °C
+	SigmaInlet    float64 // °C, inlet water temperature
 
 	// Neighborhood size
 	K    int // top‑K neighbors used for final estimate
@@ -58,7 +93,13 @@ type PredictionConfigV2 struct {
 	RecencyHalfLifeDays float64 // exponential half‑life for time decay
 
 	// Source balance
-	UserBoost float64 // multiplier applied to *user* records
+	UserBoost           float64   // multiplier applied to *user* records (the fallback when adaptive weighting lacks data)
+	FixedUserBoost      bool      // if true, always use UserBoost instead of weighting sources by recent error
+	AdaptiveBoostWindow int       // recent in-context user records replayed to measure each source's error
+	MinContributors     int       // distinct other users needed when their records carry most of the neighbor weight; 1 allows one
+	MinPersonalRecords  int       // user records needed before they are used at all; until then only global records and physics count
+	CrossRegionWeight   float64   // multiplier for other users' records from another climate region; 1 ignores regions
+	GlobalQuantizer     Quantizer // bands other users' records are rounded to as they enter the global pool
 
 	// Safety
 	StepCapFraction float64 // e.g., 0.35 => limit change vs last user record to ±35%
@@ -66,7 +107,53 @@ type PredictionConfigV2 struct {
 	MaxMinutes      float64
 
 	// Risk policy
-	NeverCold bool // if true, ceil at the end; else round to nearest
+	NeverCold bool           // if true, always ceil; shorthand for RoundingCeil
+	Rounding  RoundingPolicy // how the final estimate is rounded; users may override it
+
+	// Exploration in new context buckets
+	ExplorationMultipliers []float64 // probe schedule applied to the first sessions in a bucket
+	ExplorationJitter      float64   // e.g., 0.05 => each probe varies by up to ±5%; needs a random source
+	DisableExploration     bool
+
+	// Household events
+	HardwareChangeDecay float64 // weight multiplier for user records older than the latest hardware change
+
+	// Regime changes: user history before the latest detected change point is ignored
+	ChangePointMinSegment int
+	ChangePointMinShift   float64
+	ChangePointMinScore   float64
+}
+
+// NewPredictionConfigV2 converts the deployment's v2 settings.
+func NewPredictionConfigV2(cfg config.V2Config) *PredictionConfigV2 {
+	return &PredictionConfigV2{
+		SigmaDuration:          cfg.SigmaDuration,
+		SigmaTemp:              cfg.SigmaTemp,
+		SigmaInlet:             cfg.SigmaInlet,
+		K:                      cfg.K,
+		MinK:                   cfg.MinK,
+		AnchorEpsilon:          cfg.AnchorEpsilon,
+		AnchorBoost:            cfg.AnchorBoost,
+		AnchorBlend:            cfg.AnchorBlend,
+		RecencyHalfLifeDays:    cfg.RecencyHalfLifeDays,
+		UserBoost:              cfg.UserBoost,
+		FixedUserBoost:         cfg.FixedUserBoost,
+		AdaptiveBoostWindow:    cfg.AdaptiveBoostWindow,
+		StepCapFraction:        cfg.StepCapFraction,
+		NeverCold:              cfg.NeverCold,
+		Rounding:               RoundingPolicy{Mode: cfg.Rounding, Step: cfg.RoundingStep, Hysteresis: cfg.RoundingHysteresis},
+		ExplorationMultipliers: cfg.ExplorationMultipliers,
+		ExplorationJitter:      cfg.ExplorationJitter,
+		DisableExploration:     cfg.DisableExploration,
+		HardwareChangeDecay:    cfg.HardwareChangeDecay,
+		ChangePointMinSegment:  cfg.ChangePointMinSegment,
+		ChangePointMinShift:    cfg.ChangePointMinShift,
+		ChangePointMinScore:    cfg.ChangePointMinScore,
+		MinContributors:        cfg.MinContributors,
+		MinPersonalRecords:     cfg.MinPersonalRecords,
+		CrossRegionWeight:      cfg.CrossRegionWeight,
+		GlobalQuantizer:        Quantizer{DurationWidth: cfg.GlobalDurationBand, TemperatureWidth: cfg.GlobalTemperatureBand},
+	}
 }
 
 // NewPredictionServiceV2 with sensible defaults.
@@ -74,16 +161,30 @@ func NewPredictionServiceV2(recordService RecordServiceInterface, cfg *Predictio
 	defaultCfg := PredictionConfigV2{
 		SigmaDuration:       4.0,   // Std-dev for Gaussian weighting on shower duration (min) — smaller = more sensitive to duration similarity.
 		SigmaTemp:           3.0,   // Std-dev for Gaussian weighting on ambient temperature (°C) — smaller = more sensitive to temperature similarity.
+		SigmaInlet:          2.5,   // Std-dev for Gaussian weighting on cold-water inlet temperature (°C), measured or seasonally estimated.
 		K:                   25,    // Number of nearest neighbors (records) to consider from history (user + global).
 		MinK:                6,     // Minimum number of records required for a prediction — ensures stability when history is sparse.
 		RecencyHalfLifeDays: 5.0,   // Weight decay half-life in days — newer feedback counts more, halves in influence every N days.
+		AnchorEpsilon:       3.0,   // Satisfaction within ±3 of 50 counts as a “perfect” anchor.
+		AnchorBoost:         1.5,   // Extra weight for anchors — a perfect shower says more than a miss.
 		AnchorBlend:         0.35,  // Blend ratio between nearest-neighbor average and “perfect anchor” values — higher = perfects pull prediction more strongly.
 		UserBoost:           2,     // Multiplier for weights from the current user’s history — increases personalisation over global data.
+		AdaptiveBoostWindow: 10,    // Recent in-context records used to compare user vs global accuracy when adapting UserBoost.
+		MinContributors:     3,     // Distinct other users a prediction led by global records must draw on, so no single household's showers can be read back from it.
 		StepCapFraction:     0.35,  // Max fractional change (vs. previous prediction) allowed in one step — smooths large jumps.
 		MinMinutes:          5,     // Lower bound for predicted heating time (minutes) — safety/clamping.
 		MaxMinutes:          120,   // Upper bound for predicted heating time (minutes) — safety/clamping.
 		NeverCold:           false, // If true, bias rounding upward to avoid under-heating (“cold” risk).
+		HardwareChangeDecay: 0.05,  // Weight kept by user records that predate a hardware-change annotation.
+		CrossRegionWeight:   0.3,   // Weight kept by other users' records from another climate region — heating needs differ by climate.
+		// Probe schedule for a user's first sessions in a new context bucket — converges faster than leaning on global data.
+		ExplorationMultipliers: defaultExplorationMultipliers,
 	}
+	defaultCfg.Rounding = DefaultRoundingPolicy() // Whole minutes; up after cold feedback, down after a near miss on hot.
+	changePoints := DefaultChangePointConfig()
+	defaultCfg.ChangePointMinSegment = changePoints.MinSegment // Records needed on each side before a regime change is believed.
+	defaultCfg.ChangePointMinShift = changePoints.MinShift     // Relative shift in required heating that counts as a regime change.
+	defaultCfg.ChangePointMinScore = changePoints.MinScore     // Statistical strength (t-score) required for a regime change.
 
 	if cfg != nil {
 		// override defaults with provided values
@@ -93,6 +194,9 @@ func NewPredictionServiceV2(recordService RecordServiceInterface, cfg *Predictio
 		if cfg.SigmaTemp > 0 {
 			defaultCfg.SigmaTemp = cfg.SigmaTemp
 		}
+		if cfg.SigmaInlet > 0 {
+			defaultCfg.SigmaInlet = cfg.SigmaInlet
+		}
 		if cfg.K > 0 {
 			defaultCfg.K = cfg.K
 		}
@@ -114,6 +218,27 @@ func NewPredictionServiceV2(recordService RecordServiceInterface, cfg *Predictio
 		if cfg.UserBoost > 0 {
 			defaultCfg.UserBoost = cfg.UserBoost
 		}
+		defaultCfg.FixedUserBoost = cfg.FixedUserBoost
+		if len(cfg.ExplorationMultipliers) > 0 {
+			defaultCfg.ExplorationMultipliers = cfg.ExplorationMultipliers
+		}
+		if cfg.ExplorationJitter > 0 && cfg.ExplorationJitter < 1 {
+			defaultCfg.ExplorationJitter = cfg.ExplorationJitter
+		}
+		defaultCfg.DisableExploration = cfg.DisableExploration
+		if cfg.AdaptiveBoostWindow > 0 {
+			defaultCfg.AdaptiveBoostWindow = cfg.AdaptiveBoostWindow
+		}
+		if cfg.MinContributors > 0 {
+			defaultCfg.MinContributors = cfg.MinContributors
+		}
+		if cfg.MinPersonalRecords > 0 {
+			defaultCfg.MinPersonalRecords = cfg.MinPersonalRecords
+		}
+		if cfg.CrossRegionWeight > 0 && cfg.CrossRegionWeight <= 1 {
+			defaultCfg.CrossRegionWeight = cfg.CrossRegionWeight
+		}
+		defaultCfg.GlobalQuantizer = cfg.GlobalQuantizer
 		if cfg.StepCapFraction > 0 && cfg.StepCapFraction < 1 {
 			defaultCfg.StepCapFraction = cfg.StepCapFraction
 		}
@@ -124,42 +249,239 @@ func NewPredictionServiceV2(recordService RecordServiceInterface, cfg *Predictio
 			defaultCfg.MaxMinutes = cfg.MaxMinutes
 		}
 		defaultCfg.NeverCold = cfg.NeverCold
+		defaultCfg.Rounding = defaultCfg.Rounding.With(cfg.Rounding)
+		if cfg.HardwareChangeDecay > 0 && cfg.HardwareChangeDecay <= 1 {
+			defaultCfg.HardwareChangeDecay = cfg.HardwareChangeDecay
+		}
+		if cfg.ChangePointMinSegment > 0 {
+			defaultCfg.ChangePointMinSegment = cfg.ChangePointMinSegment
+		}
+		if cfg.ChangePointMinShift > 0 {
+			defaultCfg.ChangePointMinShift = cfg.ChangePointMinShift
+		}
+		if cfg.ChangePointMinScore > 0 {
+			defaultCfg.ChangePointMinScore = cfg.ChangePointMinScore
+		}
 	}
 	return &PredictionServiceV2{
 		recordService: recordService,
+		sourceWeights: DefaultSourceWeights(),
+		inlet:         DefaultInletModel(),
 		cfg:           defaultCfg,
 	}
 }
 
+// SetAnnotationLookup enables down-weighting of user history that predates a hardware change.
+func (s *PredictionServiceV2) SetAnnotationLookup(annotations AnnotationLookup) {
+	s.annotations = annotations
+}
+
+// SetStepCapLookup lets users widen or narrow the step cap for their own predictions.
+func (s *PredictionServiceV2) SetStepCapLookup(stepCaps StepCapLookup) {
+	s.stepCaps = stepCaps
+}
+
+// SetRoundingLookup lets users choose how their heating times are rounded.
+func (s *PredictionServiceV2) SetRoundingLookup(roundings RoundingLookup) {
+	s.roundings = roundings
+}
+
+// SetResolutionLookup rounds predictions for a device to the steps its timer can be set in.
+func (s *PredictionServiceV2) SetResolutionLookup(resolutions ResolutionLookup) {
+	s.resolutions = resolutions
+}
+
+// SetDeviceTuningLookup applies each device's own bounds, sigmas and cold-start estimate.
+func (s *PredictionServiceV2) SetDeviceTuningLookup(tunings DeviceTuningLookup) {
+	s.tunings = tunings
+}
+
+// SetLocalityLookup sizes the kernels by the user's context bucket around each request instead
+// of the fixed sigmas; a device's own sigmas still win.
+func (s *PredictionServiceV2) SetLocalityLookup(locality LocalityLookup) {
+	s.locality = locality
+}
+
+// SetGlobalSnapshot reads the global pool from the snapshot instead of the database.
+func (s *PredictionServiceV2) SetGlobalSnapshot(snapshot *GlobalSnapshot) {
+	s.globalRecords = snapshot
+}
+
+// SetCandidateIndex reads the records weighed for each prediction from the index, only those
+// within the kernels' reach, instead of weighing the user's latest records and loading the
+// global pool. The user's latest records are still read for their regime and last session.
+func (s *PredictionServiceV2) SetCandidateIndex(index *CandidateIndex) {
+	s.candidates = index
+}
+
+// SetContributorWeights down-weights global records of users whose feedback is inconsistent.
+func (s *PredictionServiceV2) SetContributorWeights(contributors ContributorWeightLookup) {
+	s.contributors = contributors
+}
+
+// SetRegionLookup prefers other users' records from the user's own climate region.
+func (s *PredictionServiceV2) SetRegionLookup(regions RegionLookup) {
+	s.regions = regions
+}
+
+// SetSatisfactionTargets rescales each user's ratings so the rating they give perfect water
+// reads as 50 for the implied targets and anchors.
+func (s *PredictionServiceV2) SetSatisfactionTargets(targets SatisfactionTargetLookup) {
+	s.targets = targets
+}
+
+// SetRatingCalibration stretches other users' ratings by their fitted scale before they enter
+// the global pool, so ratings from users who only move the slider a little count fully.
+func (s *PredictionServiceV2) SetRatingCalibration(calibration RatingCalibrationLookup) {
+	s.calibration = calibration
+}
+
+// SetAnchorDiagnostics records how much anchors contributed to each prediction.
+func (s *PredictionServiceV2) SetAnchorDiagnostics(diagnostics *AnchorDiagnostics) {
+	s.anchorLog = diagnostics
+	diagnostics.setPolicy(s.AnchorPolicy())
+}
+
+// AnchorPolicy returns the anchor settings in effect.
+func (s *PredictionServiceV2) AnchorPolicy() AnchorPolicy {
+	return AnchorPolicy{Epsilon: s.cfg.AnchorEpsilon, Boost: s.cfg.AnchorBoost, Blend: s.cfg.AnchorBlend}
+}
+
+// SetSourceWeights overrides how much records from each source count, on top of the defaults.
+func (s *PredictionServiceV2) SetSourceWeights(weights SourceWeights) {
+	s.sourceWeights = weights.withDefaults()
+}
+
+// SetInletModel sets how inlet water temperature is estimated for records and requests that didn't measure it.
+func (s *PredictionServiceV2) SetInletModel(model InletModel) {
+	s.inlet = model
+}
+
+// SetResidualHeatModel enables subtracting heat left in the tank from the last session.
+func (s *PredictionServiceV2) SetResidualHeatModel(model ResidualHeatModel) {
+	s.residualHeat = model
+}
+
+// SetClock sets the clock records are aged against, e.g. a frozen one for backtests.
+func (s *PredictionServiceV2) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// SetRandomSource enables the randomised features, e.g. exploration jitter, drawing from source.
+func (s *PredictionServiceV2) SetRandomSource(source *RandomSource) {
+	s.random = source
+}
+
+// now reads the clock, the wall clock when none was set.
+func (s *PredictionServiceV2) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// SetHeatingBounds overrides the range predictions are clamped to.
+func (s *PredictionServiceV2) SetHeatingBounds(minMinutes, maxMinutes float64) {
+	s.cfg.MinMinutes = minMinutes
+	s.cfg.MaxMinutes = maxMinutes
+}
+
+// ChangePointConfig returns the regime-change detection settings in effect.
+func (s *PredictionServiceV2) ChangePointConfig() ChangePointConfig {
+	return ChangePointConfig{
+		MinSegment: s.cfg.ChangePointMinSegment,
+		MinShift:   s.cfg.ChangePointMinShift,
+		MinScore:   s.cfg.ChangePointMinScore,
+	}
+}
+
 // Predict computes the recommended heating time using Gaussian‑kNN with anchors.
-func (s *PredictionServiceV2) Predict(req PredictionRequest) (*PredictionResponse, error) {
+func (s *PredictionServiceV2) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
 	// 1) Fetch data
 	userRecords, err := s.recordService.GetRecordsForPredictionByUser(req.UserID, 400)
 	if err != nil {
 		return nil, err
 	}
-	globalRecords, err := s.recordService.GetGlobalRecordsForPrediction(req.UserID, 1200)
+	cfg, coldStart, bucket := s.configFor(req)
+	// With an index, only the records near the request are weighed: the user's among them
+	// instead of all their latest, and everyone else's instead of the global pool
+	var nearUserRecords, globalRecords []models.DailyRecord
+	if s.candidates != nil {
+		nearUserRecords, globalRecords, err = s.nearCandidates(req, cfg)
+	} else {
+		globalRecords, err = s.globalPool(req.UserID, 1200)
+	}
+	if err != nil {
+		return nil, err
+	}
+	userRecords = neutralizeRecords(userRecords, s.targets)
+	nearUserRecords = neutralizeRecords(nearUserRecords, s.targets)
+	globalRecords = calibrateRecords(neutralizeRecords(globalRecords, s.targets), s.calibration)
+	// The tank is shared, so whoever showered last left the residual heat
+	residual := s.residualHeat.Minutes(req.DeviceID, userRecords, s.now())
+
+	// Personalise for a household member as v1 does, see splitByMember
+	if req.MemberID != "" {
+		var household []models.DailyRecord
+		userRecords, household = splitByMember(userRecords, req.MemberID)
+		if s.candidates != nil {
+			nearUserRecords, household = splitByMember(nearUserRecords, req.MemberID)
+		}
+		globalRecords = append(household, globalRecords...)
+	}
+	// Only learn from the user's current regime
+	regimeStart := latestChangePoint(userRecords, s.ChangePointConfig())
+	userRecords = recordsSince(userRecords, regimeStart)
+	// Exploration and the rounding bias follow every session of the regime, learned from or not
+	regimeRecords := userRecords
+	// A record or two is mostly noise; until there are enough, the user is predicted for like a
+	// newcomer, and the step cap has no record of theirs to hold the prediction to
+	var heldBack int
+	if len(userRecords) < s.cfg.MinPersonalRecords {
+		heldBack, userRecords = len(userRecords), nil
+	}
+	userCandidates := userRecords
+	if s.candidates != nil {
+		userCandidates = nil
+		if len(userRecords) > 0 {
+			userCandidates = recordsSince(nearUserRecords, regimeStart)
+		}
+	}
+
+	hardwareChangedAt, err := latestHardwareChange(s.annotations, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	capFrac, err := s.stepCapFor(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	rounding, err := s.roundingFor(req.UserID, req.DeviceID)
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// 2) Combine into a single slice with source flag
-	all := make([]recWrap, 0, len(userRecords)+len(globalRecords))
-	for _, r := range userRecords {
+	all := make([]recWrap, 0, len(userCandidates)+len(globalRecords))
+	for _, r := range userCandidates {
 		all = append(all, recWrap{rec: r, isUser: true})
 	}
 	for _, r := range globalRecords {
 		all = append(all, recWrap{rec: r, isUser: false})
 	}
 	if len(all) == 0 {
-		// No data at all — conservative default of 30 minutes
-		out := 30.0
-		if s.cfg.NeverCold {
-			out = math.Ceil(out)
-		} else {
-			out = math.Round(out)
+		// No data at all
+		var expl *PredictionExplanation
+		if heldBack > 0 {
+			expl = &PredictionExplanation{PersonalRecordsHeldBack: heldBack}
 		}
-		return &PredictionResponse{HeatingTime: clamp(out, s.cfg.MinMinutes, s.cfg.MaxMinutes)}, nil
+		return baselinePrediction(cfg, coldStart, rounding, expl), nil
+	}
+	if bucket != nil || s.candidates != nil {
+		all = withinKernels(all, req, cfg)
 	}
 
 	// 3) Precompute cell frequencies to avoid O(n²) scans
@@ -171,21 +493,34 @@ func (s *PredictionServiceV2) Predict(req PredictionRequest) (*PredictionRespons
 	}
 
 	// 4) Compute weights
-	now := time.Now().UTC()
+	var expl PredictionExplanation
+	expl.Locality = bucket
+	expl.PersonalRecordsHeldBack = heldBack
+	userBoost := s.userBoostFor(req, userRecords, globalRecords, &expl)
+	region := s.regionOf(req.UserID)
+	expl.Region = region
+	now := s.now().UTC()
+	var rng *rand.Rand
+	var seed int64
+	if s.random != nil {
+		rng, seed = s.random.ForPrediction(req, now)
+	}
+	reqInlet := s.inlet.ForRequest(req, now)
 	for i := range all {
 		r := &all[i]
-		// Gaussian distance on duration & temperature
-		wDur := gaussian(req.Duration-r.rec.ShowerDuration, s.cfg.SigmaDuration)
-		wTmp := gaussian(req.Temperature-r.rec.AverageTemperature, s.cfg.SigmaTemp)
-		w := wDur * wTmp
+		// Gaussian distance on duration, temperature and the cold water the heater starts from
+		wDur := gaussian(req.Duration-r.rec.ShowerDuration, cfg.SigmaDuration)
+		wTmp := gaussian(req.Temperature-r.rec.AverageTemperature, cfg.SigmaTemp)
+		wInlet := gaussian(reqInlet-s.inlet.ForRecord(r.rec), cfg.SigmaInlet)
+		w := wDur * wTmp * wInlet
 
 		// Recency decay
 		days := math.Abs(now.Sub(r.rec.Date).Hours()) / 24.0
-		w *= expHalfLife(days, s.cfg.RecencyHalfLifeDays)
+		w *= expHalfLife(days, cfg.RecencyHalfLifeDays)
 
 		// Anchor boost on BOTH sides near 50
-		if math.Abs(r.rec.Satisfaction-50.0) <= s.cfg.AnchorEpsilon {
-			w *= s.cfg.AnchorBoost
+		if math.Abs(r.rec.Satisfaction-50.0) <= cfg.AnchorEpsilon {
+			w *= cfg.AnchorBoost
 			r.anchor = true
 		}
 
@@ -197,24 +532,53 @@ func (s *PredictionServiceV2) Predict(req PredictionRequest) (*PredictionRespons
 			w *= 1.0 / math.Sqrt(float64(cnt))
 		}
 
+		// Provenance: simulated or imported records and inferred feedback may be trusted less than real feedback
+		w *= s.sourceWeights.For(r.rec.Source) * r.rec.Confidence()
+
 		// Source balance
 		if r.isUser {
-			w *= s.cfg.UserBoost
+			w *= userBoost
+			// History from before new hardware barely describes the current system
+			if hardwareChangedAt != nil && r.rec.Date.Before(*hardwareChangedAt) {
+				w *= cfg.HardwareChangeDecay
+			}
+		} else if r.rec.UserID != req.UserID {
+			// Other users count by how consistent their own feedback is
+			if s.contributors != nil {
+				w *= s.contributors.ContributorWeight(r.rec.UserID)
+			}
+			// and less when they live in another climate
+			if r.crossRegion = s.crossRegion(region, r.rec.UserID); r.crossRegion {
+				w *= cfg.CrossRegionWeight
+			}
 		}
 
 		r.weight = w
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// 5) Select top‑K by weight (keep at least MinK)
-	sort.Slice(all, func(i, j int) bool { return all[i].weight > all[j].weight })
-	k := s.cfg.K
-	if k < s.cfg.MinK {
-		k = s.cfg.MinK
+	k := cfg.K
+	if k < cfg.MinK {
+		k = cfg.MinK
 	}
-	if k > len(all) {
-		k = len(all)
+	top := topKByWeight(all, k)
+	expl.Neighbors = len(top)
+	expl.Confidence = neighborConfidence(top)
+	expl.UserWeight, expl.GlobalWeight = sourceShares(top)
+	expl.CrossRegionNeighbors = countCrossRegion(top)
+
+	// A prediction led by other users' records must draw on enough of them that no single
+	// household's showers can be read back from it
+	var othersWeight float64
+	expl.Contributors, othersWeight = otherContributors(top, req.UserID)
+	if othersWeight > 0.5 && expl.Contributors < cfg.MinContributors {
+		expl.AnonymityFallback = true
+		return baselinePrediction(cfg, coldStart, rounding, &expl), nil
 	}
-	top := all[:k]
 
 	// 6) Weighted estimate using implied targets (all) + anchor‑only estimate (if anchors exist)
 	estAll := weightedMeanTargets(top)
@@ -222,27 +586,162 @@ func (s *PredictionServiceV2) Predict(req PredictionRequest) (*PredictionRespons
 
 	// Blend toward anchors proportionally to their weight presence
 	if anchorWeightSum > 0 {
-		alpha := s.cfg.AnchorBlend * math.Min(1.0, anchorWeightSum/(sumWeights(top)+1e-9))
+		alpha := cfg.AnchorBlend * math.Min(1.0, anchorWeightSum/(sumWeights(top)+1e-9))
 		estAll = (1.0-alpha)*estAll + alpha*estAnchors
+		expl.AnchorPull = alpha
+	}
+	expl.Anchors = countAnchors(top)
+	s.anchorLog.Observe(expl.Anchors, expl.AnchorPull)
+
+	// Exploration: deliberately vary the first few sessions in a context the user hasn't tried
+	// yet. Probes come before the safety clamp, so they never step further than the user allows.
+	sessions := recordsSince(regimeRecords, hardwareChangedAt)
+	if exploration := s.explorationFor(req, sessions, rng); exploration != nil {
+		estAll *= exploration.Multiplier
+		expl.Exploration = exploration
 	}
 
-	// 7) Safety clamp vs last similar user record (context‑aware) to avoid big jumps
-	if last, ok := latestSimilarUserRecord(userRecords, req, s.cfg.SigmaDuration*2.0, s.cfg.SigmaTemp*2.0); ok {
-		capFrac := s.cfg.StepCapFraction
+	// 7) Safety clamp vs last similar user record (context‑aware) to avoid big jumps.
+	// Records from before a hardware change are not a meaningful reference point.
+	// A request may skip the clamp once, e.g. when a repair calls for a big correction.
+	currentUserRecords := recordsSince(userRecords, hardwareChangedAt)
+	if last, ok := latestSimilarUserRecord(currentUserRecords, req, cfg.SigmaDuration*2.0, cfg.SigmaTemp*2.0); ok {
 		minStep := last.HeatingTime * (1.0 - capFrac)
 		maxStep := last.HeatingTime * (1.0 + capFrac)
-		estAll = clamp(estAll, minStep, maxStep)
+		if capped := clamp(estAll, minStep, maxStep); !req.AllowLargeAdjustment {
+			estAll = capped
+		} else if capped != estAll {
+			expl.StepCapBypassed = true
+		}
 	}
 
-	// 8) Absolute bounds and smart rounding (avoid 48.0x → ceil → 49 loop when feedback is hot)
-	estAll = clamp(estAll, s.cfg.MinMinutes, s.cfg.MaxMinutes)
-	if lastSat, ok := lastUserFeedback(userRecords); ok {
-		estAll = smartRound(estAll, lastSat)
-	} else {
-		estAll = math.Round(estAll)
+	// Heat still in the tank from the last session doesn't need to be produced again
+	if residual > 0 {
+		estAll -= residual
+		expl.ResidualHeatMinutes = residual
+	}
+
+	// 8) Absolute bounds and rounding, biased by the last feedback under the smart policy
+	raw := clamp(estAll, cfg.MinMinutes, cfg.MaxMinutes)
+	var lastSat *float64
+	if sat, ok := lastUserFeedback(sessions); ok {
+		lastSat = &sat
+	}
+	estAll = keepOnGrid(rounding.Round(raw, lastSat), rounding.Step, cfg.MinMinutes, cfg.MaxMinutes)
+
+	return &PredictionResponse{HeatingTime: estAll, RawHeatingTime: raw, Seed: seed, Explanation: &expl}, nil
+}
+
+// baselinePrediction is the estimate without history to learn from: the device's physics when
+// its cold start is known, else a conservative 30 minutes
+func baselinePrediction(cfg PredictionConfigV2, coldStart float64, rounding RoundingPolicy, expl *PredictionExplanation) *PredictionResponse {
+	if coldStart <= 0 {
+		coldStart = 30.0
+	}
+	raw := clamp(coldStart, cfg.MinMinutes, cfg.MaxMinutes)
+	out := keepOnGrid(rounding.Round(raw, nil), rounding.Step, cfg.MinMinutes, cfg.MaxMinutes)
+	return &PredictionResponse{HeatingTime: out, RawHeatingTime: raw, Baseline: true, Explanation: expl}
+}
+
+// configFor returns the configuration with the request's context bucket and device tuning
+// applied, the device's cold-start heating time (0 when unknown) and the bucket, if any
+func (s *PredictionServiceV2) configFor(req PredictionRequest) (PredictionConfigV2, float64, *BucketNode) {
+	cfg := s.cfg
+	var bucket *BucketNode
+	if s.locality != nil {
+		if leaf, ok := s.locality.Locality(req.UserID, req.Duration, req.Temperature); ok {
+			bucket = &leaf
+			cfg.SigmaDuration = leaf.SigmaDuration()
+			cfg.SigmaTemp = leaf.SigmaTemperature()
+		}
+	}
+	if s.tunings == nil {
+		return cfg, 0, bucket
+	}
+	tuning := s.tunings.Tuning(req)
+	if tuning.MinMinutes > 0 {
+		cfg.MinMinutes = tuning.MinMinutes
+	}
+	if tuning.MaxMinutes > cfg.MinMinutes {
+		cfg.MaxMinutes = tuning.MaxMinutes
+	}
+	if tuning.SigmaDuration > 0 {
+		cfg.SigmaDuration = tuning.SigmaDuration
+	}
+	if tuning.SigmaTemp > 0 {
+		cfg.SigmaTemp = tuning.SigmaTemp
+	}
+	return cfg, tuning.ColdStart, bucket
+}
+
+// kernelReach is how many sigmas from the request a record may be before its weight is
+// negligible
+const kernelReach = 3.0
+
+// withinKernels drops records beyond the kernels' reach of the request, whose weight would be
+// negligible anyway, unless that leaves fewer than MinK
+func withinKernels(all []recWrap, req PredictionRequest, cfg PredictionConfigV2) []recWrap {
+	var near []recWrap
+	for _, r := range all {
+		if math.Abs(req.Duration-r.rec.ShowerDuration) <= kernelReach*cfg.SigmaDuration &&
+			math.Abs(req.Temperature-r.rec.AverageTemperature) <= kernelReach*cfg.SigmaTemp {
+			near = append(near, r)
+		}
+	}
+	if len(near) < cfg.MinK {
+		return all
+	}
+	return near
+}
+
+// nearCandidates reads the user's and everyone else's records within the kernels' reach of the
+// request from the candidate index, or all of them when fewer than MinK are that close. Other
+// users' records are quantized, which moves them by up to half a band, so they are looked for
+// that much further out.
+func (s *PredictionServiceV2) nearCandidates(req PredictionRequest, cfg PredictionConfigV2) (user, global []models.DailyRecord, err error) {
+	quantizer := s.cfg.GlobalQuantizer
+	durationReach := kernelReach*cfg.SigmaDuration + quantizer.DurationWidth/2
+	temperatureReach := kernelReach*cfg.SigmaTemp + quantizer.TemperatureWidth/2
+	user, global, err = s.candidates.Near(req.UserID, req.Duration, req.Temperature, durationReach, temperatureReach)
+	if err == nil && len(user)+len(global) < cfg.MinK {
+		user, global, err = s.candidates.Near(req.UserID, req.Duration, req.Temperature, -1, -1)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, quantizer.Quantize(global), nil
+}
+
+// stepCapFor returns the user's own step cap, or the configured one
+func (s *PredictionServiceV2) stepCapFor(userID string) (float64, error) {
+	if s.stepCaps == nil {
+		return s.cfg.StepCapFraction, nil
+	}
+	stepCap, err := s.stepCaps.StepCap(userID)
+	if err != nil || stepCap == nil {
+		return s.cfg.StepCapFraction, err
 	}
+	return *stepCap, nil
+}
 
-	return &PredictionResponse{HeatingTime: estAll}, nil
+// roundingFor returns the configured rounding policy with the user's preferences applied. A
+// device with a finer (or coarser) timer rounds to its own resolution.
+func (s *PredictionServiceV2) roundingFor(userID, deviceID string) (RoundingPolicy, error) {
+	policy := s.cfg.Rounding
+	if s.cfg.NeverCold {
+		policy.Mode = RoundingCeil
+	}
+	if s.roundings != nil {
+		own, err := s.roundings.RoundingPolicy(userID)
+		if err != nil {
+			return policy, err
+		}
+		policy = policy.With(own)
+	}
+	if s.resolutions != nil {
+		policy = policy.With(RoundingPolicy{Step: s.resolutions.Resolution(userID, deviceID)})
+	}
+	return policy, nil
 }
 
 // ------------- helpers --------------
@@ -273,6 +772,44 @@ func clamp(x, lo, hi float64) float64 {
 	return x
 }
 
+// topKByWeight returns the k heaviest candidates, heaviest first. A bounded min-heap keeps
+// this O(n log k): weights depend on the request, so candidates can't be kept presorted, but
+// only the k that are used need ordering.
+func topKByWeight(all []recWrap, k int) []recWrap {
+	if k > len(all) {
+		k = len(all)
+	}
+	if k <= 0 {
+		return nil
+	}
+	h := make(weightHeap, 0, k)
+	for _, r := range all {
+		if len(h) < k {
+			heap.Push(&h, r)
+		} else if r.weight > h[0].weight {
+			h[0] = r
+			heap.Fix(&h, 0)
+		}
+	}
+	top := []recWrap(h)
+	sort.Slice(top, func(i, j int) bool { return top[i].weight > top[j].weight })
+	return top
+}
+
+// weightHeap is a min-heap of candidates by weight
+type weightHeap []recWrap
+
+func (h weightHeap) Len() int            { return len(h) }
+func (h weightHeap) Less(i, j int) bool  { return h[i].weight < h[j].weight }
+func (h weightHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *weightHeap) Push(x interface{}) { *h = append(*h, x.(recWrap)) }
+func (h *weightHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
 func freqCellKey(r models.DailyRecord) string {
 	d := int(math.Round(r.ShowerDuration))
 	t := int(math.Round(r.AverageTemperature))
@@ -324,6 +861,52 @@ func weightedMeanAnchors(recs []recWrap) (mean float64, weightSum float64) {
 	return sum / totalW, totalW
 }
 
+// sourceShares splits the total neighbor weight into user and global fractions
+func sourceShares(recs []recWrap) (user, global float64) {
+	for _, r := range recs {
+		if r.isUser {
+			user += r.weight
+		} else {
+			global += r.weight
+		}
+	}
+	total := user + global
+	if total == 0 {
+		return 0, 0
+	}
+	return user / total, global / total
+}
+
+// neighborConfidence scores the neighbors like a context bucket, rounded to two decimals
+func neighborConfidence(recs []recWrap) float64 {
+	records := make([]models.DailyRecord, len(recs))
+	for i, r := range recs {
+		records[i] = r.rec
+	}
+	return roundTo(contextConfidence(records), 2)
+}
+
+// countCrossRegion counts the records from other users in a different climate region
+func countCrossRegion(recs []recWrap) int {
+	n := 0
+	for _, r := range recs {
+		if r.crossRegion {
+			n++
+		}
+	}
+	return n
+}
+
+func countAnchors(recs []recWrap) int {
+	n := 0
+	for _, r := range recs {
+		if r.anchor {
+			n++
+		}
+	}
+	return n
+}
+
 func sumWeights(recs []recWrap) float64 {
 	total := 0.0
 	for _, r := range recs {
@@ -335,36 +918,39 @@ func sumWeights(recs []recWrap) float64 {
 // --- v2 learning helpers: implied target and context-aware clamp ---
 
 // impliedTarget converts a historical record into an implied target time based on satisfaction feedback.
+func impliedTarget(r models.DailyRecord) float64 {
+	return r.HeatingTime * ImpliedMultiplier(r.Satisfaction)
+}
+
+// ImpliedMultiplier is what a heating time rated s is multiplied by to get the time the rating
+// implies was needed. GET /api/meta/adjustment-curve publishes it, so changing it changes the API.
 // - Satisfaction ~50 -> keep the same time
 // - Satisfaction >50 (too hot) -> reduce time with graduated percentages
 // - Satisfaction <50 (too cold) -> increase time proportionally to severity with mild overshoot
-func impliedTarget(r models.DailyRecord) float64 {
-	s := r.Satisfaction
-	h := r.HeatingTime
-
+func ImpliedMultiplier(s float64) float64 {
 	// Near-perfect: tiny/no change
 	if math.Abs(s-50.0) <= 1.0 {
-		return h
+		return 1
 	}
 
 	if s > 50.0 {
 		// Graduated reductions similar to v1 behavior
 		switch {
 		case s >= 85:
-			return h * 0.75
+			return 0.75
 		case s >= 80:
-			return h * 0.80
+			return 0.80
 		case s >= 75:
-			return h * 0.83
+			return 0.83
 		case s >= 65:
-			return h * 0.87
+			return 0.87
 		case s >= 60:
-			return h * 0.92
+			return 0.92
 		case s >= 55:
-			return h * 0.97
+			return 0.97
 		default:
 			// Slightly hot (50<s<55): small nudge
-			return h * 0.99
+			return 0.99
 		}
 	}
 
@@ -377,8 +963,7 @@ func impliedTarget(r models.DailyRecord) float64 {
 	basePercent := 0.12 + 0.28*coldSeverity
 	// Mild overshoot up to +10% extra when extremely cold
 	overshoot := 1.0 + 0.10*coldSeverity
-	factor := 1.0 + basePercent*overshoot
-	return h * factor
+	return 1.0 + basePercent*overshoot
 }
 
 // weightedMeanTargets computes weighted mean over implied targets instead of raw times
@@ -438,16 +1023,59 @@ func latestSimilarUserRecord(userRecs []models.DailyRecord, req PredictionReques
 	return latest, found
 }
 
-// smartRound: bias safe, but avoid sticking on the upper minute when user said "too hot".
-func smartRound(est float64, lastSat float64) float64 {
-	frac := est - math.Floor(est)
-	if lastSat > 50 && frac <= 0.25 { // recently hot -> allow snap-down if close
-		return math.Floor(est)
+// latestHardwareChange looks up the user's latest hardware change, tolerating a nil lookup
+func latestHardwareChange(annotations AnnotationLookup, userID string) (*time.Time, error) {
+	if annotations == nil {
+		return nil, nil
+	}
+	return annotations.LatestHardwareChange(userID)
+}
+
+// regionOf returns the user's climate region, empty without a lookup or when it is unknown
+func (s *PredictionServiceV2) regionOf(userID string) string {
+	if s.regions == nil {
+		return ""
+	}
+	return s.regions.Region(userID)
+}
+
+// crossRegion reports whether the other user is known to live in a different region than the
+// requesting user's; an unknown region on either side is never penalized
+func (s *PredictionServiceV2) crossRegion(region, otherUserID string) bool {
+	if region == "" {
+		return false
 	}
-	if lastSat < 50 { // recently cold -> keep bias-to-hot
-		return math.Ceil(est)
+	other := s.regionOf(otherUserID)
+	return other != "" && other != region
+}
+
+// globalPool returns the latest limit records of everyone but the user, from the snapshot
+// when there is one that can serve them, quantized so no household's showers enter it exactly
+func (s *PredictionServiceV2) globalPool(userID string, limit int) ([]models.DailyRecord, error) {
+	if s.globalRecords != nil {
+		if records, ok := s.globalRecords.Records(userID, limit); ok {
+			return s.cfg.GlobalQuantizer.Quantize(records), nil
+		}
+	}
+	records, err := s.recordService.GetGlobalRecordsForPrediction(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.cfg.GlobalQuantizer.Quantize(records), nil
+}
+
+// recordsSince returns the records dated at or after since (all records when since is nil)
+func recordsSince(recs []models.DailyRecord, since *time.Time) []models.DailyRecord {
+	if since == nil {
+		return recs
+	}
+	out := make([]models.DailyRecord, 0, len(recs))
+	for _, r := range recs {
+		if !r.Date.Before(*since) {
+			out = append(out, r)
+		}
 	}
-	return math.Round(est) // near-perfect recently -> unbiased
+	return out
 }
 
 // lastUserFeedback returns the most recent satisfaction for the user.
@@ -0,0 +1,665 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAnnotations returns a fixed hardware change date
+type stubAnnotations struct {
+	changedAt *time.Time
+}
+
+func (s stubAnnotations) LatestHardwareChange(userID string) (*time.Time, error) {
+	return s.changedAt, nil
+}
+
+func TestPredictionServiceV2_HardwareChangeDecaysOlderHistory(t *testing.T) {
+	now := time.Now()
+	changedAt := now.AddDate(0, 0, -3)
+
+	var userRecords []models.DailyRecord
+	for i := 0; i < 6; i++ {
+		// Before the new boiler: long heating times
+		userRecords = append(userRecords, models.DailyRecord{
+			UserID: "user1", Date: now.AddDate(0, 0, -5).Add(time.Duration(i) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 40, Satisfaction: 49,
+		})
+	}
+	for i := 0; i < 2; i++ {
+		// After the new boiler: much shorter heating is already perfect
+		userRecords = append(userRecords, models.DailyRecord{
+			UserID: "user1", Date: now.AddDate(0, 0, -1).Add(time.Duration(i) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 49,
+		})
+	}
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	// Exploration would vary the result for the few post-change records; keep it out of this test
+	cfg := &PredictionConfigV2{DisableExploration: true}
+
+	withoutAnnotations := NewPredictionServiceV2(mockRecordService, cfg)
+	before, err := withoutAnnotations.Predict(context.Background(), req)
+	assert.NoError(t, err)
+
+	withAnnotations := NewPredictionServiceV2(mockRecordService, cfg)
+	withAnnotations.SetAnnotationLookup(stubAnnotations{changedAt: &changedAt})
+	after, err := withAnnotations.Predict(context.Background(), req)
+	assert.NoError(t, err)
+
+	assert.Less(t, after.HeatingTime, before.HeatingTime)
+	assert.InDelta(t, 20.0, after.HeatingTime, 2.0)
+}
+
+// stubStepCaps returns a fixed per-user step cap
+type stubStepCaps struct {
+	stepCap *float64
+}
+
+func (s stubStepCaps) StepCap(userID string) (*float64, error) {
+	return s.stepCap, nil
+}
+
+func TestPredictionServiceV2_StepCap(t *testing.T) {
+	now := time.Now()
+
+	var userRecords []models.DailyRecord
+	for i := 0; i < 6; i++ {
+		// After a boiler repair the heating that used to be perfect is much longer
+		userRecords = append(userRecords, models.DailyRecord{
+			UserID: "user1", Date: now.AddDate(0, 0, -2).Add(time.Duration(i) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 40, Satisfaction: 49,
+		})
+	}
+	// The latest shower was heated far too short
+	userRecords = append(userRecords, models.DailyRecord{
+		UserID: "user1", Date: now.Add(-time.Hour),
+		ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 10,
+	})
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+	service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true})
+
+	capped, err := service.Predict(context.Background(), req)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, capped.HeatingTime, 27.0, "the default cap allows +35% over the last 20 minutes")
+	assert.False(t, capped.Explanation.StepCapBypassed)
+
+	narrow := 0.1
+	service.SetStepCapLookup(stubStepCaps{stepCap: &narrow})
+	own, err := service.Predict(context.Background(), req)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, own.HeatingTime, 22.0)
+
+	// Exploration probes stay within the user's cap too
+	exploring := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{ExplorationMultipliers: []float64{1.2, 1.2, 1.2, 1.2, 1.2, 1.2, 1.2, 1.2}})
+	exploring.SetStepCapLookup(stubStepCaps{stepCap: &narrow})
+	probed, err := exploring.Predict(context.Background(), req)
+	assert.NoError(t, err)
+	require.NotNil(t, probed.Explanation.Exploration)
+	assert.LessOrEqual(t, probed.HeatingTime, 22.0)
+
+	req.AllowLargeAdjustment = true
+	bypassed, err := service.Predict(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Greater(t, bypassed.HeatingTime, 27.0)
+	assert.True(t, bypassed.Explanation.StepCapBypassed)
+}
+
+func TestPredictionServiceV2_AnchorsFireByDefault(t *testing.T) {
+	now := time.Now()
+
+	var userRecords []models.DailyRecord
+	for i := 0; i < 8; i++ {
+		satisfaction := 51.0 // close enough to perfect to anchor
+		if i%2 == 1 {
+			satisfaction = 70
+		}
+		userRecords = append(userRecords, models.DailyRecord{
+			UserID: "user1", Date: now.Add(-time.Duration(i) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: satisfaction,
+		})
+	}
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+
+	// A zero blend in a partial config is taken literally, so set the deployment default
+	service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true, AnchorBlend: 0.35})
+	diagnostics := NewAnchorDiagnostics(10)
+	service.SetAnchorDiagnostics(diagnostics)
+
+	resp, err := service.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, resp.Explanation.Anchors)
+	assert.Greater(t, resp.Explanation.AnchorPull, 0.0)
+
+	summary := diagnostics.Summary(0)
+	assert.Equal(t, 1, summary.Fired)
+	assert.Equal(t, AnchorPolicy{Epsilon: 3, Boost: 1.5, Blend: 0.35}, summary.Policy)
+}
+
+func TestPredictionServiceV2_AdaptiveBoostFavoursAccurateSource(t *testing.T) {
+	now := time.Now()
+	var userRecords, globalRecords []models.DailyRecord
+	for i := 0; i < 8; i++ {
+		date := now.AddDate(0, 0, -8+i)
+		userRecords = append(userRecords, models.DailyRecord{
+			UserID: "user1", Date: date,
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 49,
+		})
+		globalRecords = append(globalRecords, models.DailyRecord{
+			UserID: "other", Date: date.Add(-time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 40, Satisfaction: 49,
+		})
+	}
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	fixed, err := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{FixedUserBoost: true}).Predict(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed", fixed.Explanation.BoostSource)
+
+	adaptive, err := NewPredictionServiceV2(mockRecordService, nil).Predict(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "adaptive", adaptive.Explanation.BoostSource)
+	assert.Greater(t, adaptive.Explanation.UserBoost, 2.0)
+	assert.Less(t, *adaptive.Explanation.UserError, *adaptive.Explanation.GlobalError)
+	assert.Greater(t, adaptive.Explanation.UserWeight, fixed.Explanation.UserWeight)
+	assert.LessOrEqual(t, adaptive.HeatingTime, fixed.HeatingTime)
+}
+
+func TestPredictionServiceV2_ExploresNewContextBucket(t *testing.T) {
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return([]models.DailyRecord{}, nil)
+	var globalRecords []models.DailyRecord
+	for _, userID := range []string{"other1", "other2", "other3"} {
+		globalRecords = append(globalRecords, models.DailyRecord{
+			UserID: userID, Date: time.Now().AddDate(0, 0, -1), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 49,
+		})
+	}
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+
+	resp, err := NewPredictionServiceV2(mockRecordService, nil).Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15})
+
+	assert.NoError(t, err)
+	require.NotNil(t, resp.Explanation.Exploration)
+	assert.Equal(t, 0, resp.Explanation.Exploration.Session)
+	assert.Equal(t, 22.0, resp.HeatingTime) // first probe is on the warm side
+}
+
+func TestPredictionServiceV2_ExplorationJitterIsReproducibleFromSeed(t *testing.T) {
+	at := time.Date(2025, 1, 15, 7, 0, 0, 0, time.UTC)
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return([]models.DailyRecord{}, nil)
+	var globalRecords []models.DailyRecord
+	for _, userID := range []string{"other1", "other2", "other3"} {
+		globalRecords = append(globalRecords, models.DailyRecord{
+			UserID: userID, Date: at.AddDate(0, 0, -1), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 49,
+		})
+	}
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	predict := func(seed int64) *PredictionResponse {
+		service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{ExplorationJitter: 0.2})
+		service.SetClock(NewFrozenClock(at))
+		service.SetRandomSource(NewRandomSource(seed))
+		resp, err := service.Predict(context.Background(), req)
+		require.NoError(t, err)
+		require.NotNil(t, resp.Explanation.Exploration)
+		return resp
+	}
+
+	first, again, other := predict(1), predict(1), predict(2)
+	assert.Equal(t, first.Explanation.Exploration.Multiplier, again.Explanation.Exploration.Multiplier)
+	assert.Equal(t, first.Seed, again.Seed)
+	assert.NotZero(t, first.Seed)
+	assert.NotEqual(t, first.Seed, other.Seed)
+	assert.NotEqual(t, first.Explanation.Exploration.Multiplier, other.Explanation.Exploration.Multiplier)
+	assert.InDelta(t, 1.10, first.Explanation.Exploration.Multiplier, 1.10*0.2)
+
+	// The logged seed alone reproduces the draw
+	rng := rand.New(rand.NewSource(first.Seed))
+	assert.InDelta(t, 1.10*(1+0.2*(2*rng.Float64()-1)), first.Explanation.Exploration.Multiplier, 1e-12)
+}
+
+func TestPredictionServiceV2_NeedsSeveralContributors(t *testing.T) {
+	now := time.Now()
+	var globalRecords []models.DailyRecord
+	for i := 0; i < 10; i++ {
+		userID := "neighbour"
+		if i%5 == 0 {
+			userID = "other"
+		}
+		globalRecords = append(globalRecords, models.DailyRecord{
+			UserID: userID, Date: now.Add(-time.Duration(i) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 18, Satisfaction: 49,
+		})
+	}
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return([]models.DailyRecord{}, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	// Two households' showers alone would give either of them away
+	resp, err := NewPredictionServiceV2(mockRecordService, nil).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, resp.Explanation.AnonymityFallback)
+	assert.Equal(t, 2, resp.Explanation.Contributors)
+	assert.Equal(t, 30.0, resp.HeatingTime)
+
+	resp, err = NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{MinContributors: 2}).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.False(t, resp.Explanation.AnonymityFallback)
+	assert.NotEqual(t, 30.0, resp.HeatingTime)
+}
+
+func TestPredictionServiceV2_MinPersonalRecords(t *testing.T) {
+	now := time.Now()
+	var globalRecords []models.DailyRecord
+	for i := 0; i < 9; i++ {
+		globalRecords = append(globalRecords, models.DailyRecord{
+			UserID: fmt.Sprintf("neighbour%d", i%3), Date: now.Add(-time.Duration(i) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50,
+		})
+	}
+	userRecords := []models.DailyRecord{
+		{UserID: "user1", Date: now.Add(-time.Hour), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 60, Satisfaction: 50},
+		{UserID: "user1", Date: now.Add(-2 * time.Hour), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 60, Satisfaction: 50},
+	}
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	// Two odd showers pull the prediction toward them straight away
+	resp, err := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true}).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Greater(t, resp.HeatingTime, 35.0)
+	assert.Zero(t, resp.Explanation.PersonalRecordsHeldBack)
+
+	// Until there are three, only everyone else's showers count
+	resp, err = NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true, MinPersonalRecords: 3}).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, resp.HeatingTime)
+	assert.Equal(t, 2, resp.Explanation.PersonalRecordsHeldBack)
+	assert.Zero(t, resp.Explanation.UserWeight)
+}
+
+func TestPredictionServiceV2_ExploresWhileRecordsAreHeldBack(t *testing.T) {
+	now := time.Now()
+	var globalRecords []models.DailyRecord
+	for _, userID := range []string{"other1", "other2", "other3"} {
+		globalRecords = append(globalRecords, models.DailyRecord{
+			UserID: userID, Date: now.AddDate(0, 0, -1), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 49,
+		})
+	}
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	// A newcomer's first sessions aren't learned from yet, but still walk the probe schedule
+	var userRecords []models.DailyRecord
+	for session, want := range []float64{22, 19, 24} {
+		mockRecordService := &MockRecordService{}
+		mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+		mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+		resp, err := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{MinPersonalRecords: 3}).Predict(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, session, resp.Explanation.PersonalRecordsHeldBack)
+		require.NotNil(t, resp.Explanation.Exploration, "session %d", session)
+		assert.Equal(t, session, resp.Explanation.Exploration.Session)
+		assert.Equal(t, want, resp.HeatingTime, "session %d", session)
+
+		userRecords = append([]models.DailyRecord{{
+			UserID: "user1", Date: now.Add(-time.Duration(3-session) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: resp.HeatingTime, Satisfaction: 50,
+		}}, userRecords...)
+	}
+
+	// Once the schedule is done the user's own records count
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+	resp, err := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{MinPersonalRecords: 3}).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Nil(t, resp.Explanation.Exploration)
+	assert.Zero(t, resp.Explanation.PersonalRecordsHeldBack)
+	assert.Greater(t, resp.Explanation.UserWeight, 0.0)
+}
+
+func TestPredictionServiceV2_QuantizesGlobalPool(t *testing.T) {
+	globalRecords := []models.DailyRecord{
+		{UserID: "other1", ShowerDuration: 10.5, AverageTemperature: 14.2, HeatingTime: 20},
+		{UserID: "other2", ShowerDuration: 13, AverageTemperature: -1.5, HeatingTime: 35},
+	}
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+	service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{
+		GlobalQuantizer: Quantizer{DurationWidth: 2, TemperatureWidth: 5},
+	})
+
+	pool, err := service.globalPool("user1", 1200)
+	require.NoError(t, err)
+	require.Len(t, pool, 2)
+	assert.Equal(t, 11.0, pool[0].ShowerDuration)
+	assert.Equal(t, 12.5, pool[0].AverageTemperature)
+	assert.Equal(t, 13.0, pool[1].ShowerDuration)
+	assert.Equal(t, -2.5, pool[1].AverageTemperature)
+	assert.Equal(t, 35.0, pool[1].HeatingTime, "what was learned is kept exact")
+	assert.Equal(t, 10.5, globalRecords[0].ShowerDuration, "the cached records are left alone")
+
+	// Zero widths keep the pool exact
+	pool, err = NewPredictionServiceV2(mockRecordService, nil).globalPool("user1", 1200)
+	require.NoError(t, err)
+	assert.Equal(t, globalRecords, pool)
+}
+
+type stubRegions map[string]string
+
+func (s stubRegions) Region(userID string) string {
+	return s[userID]
+}
+
+func TestPredictionServiceV2_PrefersSameRegion(t *testing.T) {
+	now := time.Now()
+	regions := stubRegions{"user1": RegionTemperate}
+	var globalRecords []models.DailyRecord
+	for i := 0; i < 12; i++ {
+		userID, heating := fmt.Sprintf("north%d", i%3), 15.0
+		if i%2 == 1 {
+			userID, heating = fmt.Sprintf("south%d", i%3), 30.0
+			regions[userID] = RegionTropical
+		} else {
+			regions[userID] = RegionTemperate
+		}
+		globalRecords = append(globalRecords, models.DailyRecord{
+			UserID: userID, Date: now.Add(-time.Duration(i) * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: heating, Satisfaction: 50,
+		})
+	}
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return([]models.DailyRecord{}, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return(globalRecords, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+	cfg := &PredictionConfigV2{DisableExploration: true, Rounding: RoundingPolicy{Mode: RoundingNearest, Step: 0.1}}
+
+	blind, err := NewPredictionServiceV2(mockRecordService, cfg).Predict(context.Background(), req)
+	require.NoError(t, err)
+
+	v2 := NewPredictionServiceV2(mockRecordService, cfg)
+	v2.SetRegionLookup(regions)
+	regional, err := v2.Predict(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, RegionTemperate, regional.Explanation.Region)
+	assert.Equal(t, 6, regional.Explanation.CrossRegionNeighbors)
+	assert.Less(t, regional.HeatingTime, blind.HeatingTime)
+	assert.Greater(t, regional.HeatingTime, 15.0) // other regions still count, just less
+
+	// Users of unknown region are never penalized
+	delete(regions, "user1")
+	unknown, err := v2.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, blind.HeatingTime, unknown.HeatingTime)
+}
+
+func TestPredictionServiceV2_MemberUsesOwnHistory(t *testing.T) {
+	now := time.Now()
+
+	var userRecords []models.DailyRecord
+	for i := 0; i < 8; i++ {
+		// Dad is happy with short heating, the kid needs much longer on the same shower
+		userRecords = append(userRecords,
+			models.DailyRecord{
+				UserID: "user1", MemberID: "dad", Date: now.Add(-time.Duration(i) * time.Hour),
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 15, Satisfaction: 49,
+			},
+			models.DailyRecord{
+				UserID: "user1", MemberID: "kid", Date: now.Add(-time.Duration(i)*time.Hour - time.Minute),
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 40, Satisfaction: 49,
+			},
+		)
+	}
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+
+	service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true})
+
+	dad, err := service.Predict(context.Background(), PredictionRequest{UserID: "user1", MemberID: "dad", Duration: 10, Temperature: 15})
+	assert.NoError(t, err)
+	kid, err := service.Predict(context.Background(), PredictionRequest{UserID: "user1", MemberID: "kid", Duration: 10, Temperature: 15})
+	assert.NoError(t, err)
+
+	assert.Less(t, dad.HeatingTime, kid.HeatingTime)
+}
+
+func TestPredictionServiceV2_SourceWeightsDiscountSimulatedRecords(t *testing.T) {
+	now := time.Now()
+
+	var userRecords []models.DailyRecord
+	for i := 0; i < 8; i++ {
+		userRecords = append(userRecords,
+			models.DailyRecord{
+				UserID: "user1", Source: models.RecordSourceManual, Date: now.Add(-time.Duration(i) * time.Hour),
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 15, Satisfaction: 49,
+			},
+			models.DailyRecord{
+				UserID: "user1", Source: models.RecordSourceSimulator, Date: now.Add(-time.Duration(i)*time.Hour - time.Minute),
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 49,
+			},
+		)
+	}
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	discounted := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true})
+	withDefaults, err := discounted.Predict(context.Background(), req)
+	assert.NoError(t, err)
+
+	trusted := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true})
+	trusted.SetSourceWeights(SourceWeights{models.RecordSourceSimulator: 1})
+	withTrust, err := trusted.Predict(context.Background(), req)
+	assert.NoError(t, err)
+
+	assert.Less(t, withDefaults.HeatingTime, withTrust.HeatingTime)
+}
+
+func TestPredictionServiceV2_InletTemperatureSeparatesSeasons(t *testing.T) {
+	now := time.Now()
+	cold, warm := 10.0, 25.0
+
+	var userRecords []models.DailyRecord
+	for i := 0; i < 8; i++ {
+		userRecords = append(userRecords,
+			models.DailyRecord{
+				UserID: "user1", Date: now.Add(-time.Duration(i) * time.Hour), InletTemperature: &cold,
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 30, Satisfaction: 49,
+			},
+			models.DailyRecord{
+				UserID: "user1", Date: now.Add(-time.Duration(i)*time.Hour - time.Minute), InletTemperature: &warm,
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 15, Satisfaction: 49,
+			},
+		)
+	}
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+
+	service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true})
+	coldWater, err := service.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15, InletTemperature: &cold})
+	assert.NoError(t, err)
+	warmWater, err := service.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15, InletTemperature: &warm})
+	assert.NoError(t, err)
+
+	assert.Greater(t, coldWater.HeatingTime, warmWater.HeatingTime)
+}
+
+func TestInletModel_PeaksLateInSummer(t *testing.T) {
+	model := DefaultInletModel()
+	peak := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, model.PeakDay-1)
+
+	assert.InDelta(t, model.Mean+model.Amplitude, model.Estimate(peak), 1e-9)
+	assert.Less(t, model.Estimate(peak.AddDate(0, -6, 0)), model.Mean-model.Amplitude+0.1)
+
+	measured := 12.0
+	assert.Equal(t, 12.0, model.ForRecord(models.DailyRecord{Date: peak, InletTemperature: &measured}))
+}
+
+func TestPredictionServiceV2_SubtractsResidualHeat(t *testing.T) {
+	now := time.Now()
+
+	var userRecords []models.DailyRecord
+	for i := 1; i <= 8; i++ {
+		userRecords = append(userRecords, models.DailyRecord{
+			UserID: "user1", Date: now.AddDate(0, 0, -i),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 30, Satisfaction: 49,
+		})
+	}
+	// The boiler ran for the last shower two hours ago
+	userRecords = append(userRecords, models.DailyRecord{
+		UserID: "user1", Date: now.Add(-2 * time.Hour),
+		ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 30, Satisfaction: 49,
+	})
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15, DeviceID: "boiler"}
+
+	cold, err := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true}).Predict(context.Background(), req)
+	assert.NoError(t, err)
+
+	service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true})
+	service.SetResidualHeatModel(ResidualHeatModel{
+		HalfLife:       time.Hour,
+		DeviceHalfLife: map[string]time.Duration{"boiler": 4 * time.Hour},
+	})
+	warm, err := service.Predict(context.Background(), req)
+	assert.NoError(t, err)
+
+	// Two hours into a four-hour half-life about 70% of 30 minutes is still stored
+	assert.InDelta(t, 21.2, warm.Explanation.ResidualHeatMinutes, 0.1)
+	assert.InDelta(t, cold.HeatingTime-21.2, warm.HeatingTime, 1.0)
+}
+
+func TestTopKByWeight_MatchesFullSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	all := make([]recWrap, 1600)
+	for i := range all {
+		all[i] = recWrap{weight: rng.Float64(), rec: models.DailyRecord{ID: fmt.Sprint(i)}}
+	}
+	sorted := append([]recWrap(nil), all...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].weight > sorted[j].weight })
+
+	top := topKByWeight(all, 25)
+	assert.Equal(t, sorted[:25], top)
+	assert.Len(t, topKByWeight(all[:10], 25), 10, "k beyond the candidates returns them all")
+	assert.Empty(t, topKByWeight(nil, 25))
+}
+
+// stubTargets maps users to their target satisfaction; others target 50
+type stubTargets map[string]float64
+
+func (s stubTargets) Target(userID string) float64 {
+	if target, ok := s[userID]; ok {
+		return target
+	}
+	return models.SatisfactionPerfect
+}
+
+func TestPredictionServiceV2_SatisfactionTarget(t *testing.T) {
+	now := time.Now()
+	// The user rates 60 whenever 20 minutes was just right
+	var userRecords []models.DailyRecord
+	for i := 0; i < 8; i++ {
+		userRecords = append(userRecords, models.DailyRecord{
+			UserID: "user1", Date: now.Add(-time.Duration(i) * 24 * time.Hour),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 60,
+		})
+	}
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+	cfg := &PredictionConfigV2{DisableExploration: true, Rounding: RoundingPolicy{Mode: RoundingNearest, Step: 0.1}}
+
+	// Read on the neutral scale, 60 means too hot
+	neutral, err := NewPredictionServiceV2(mockRecordService, cfg).Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Less(t, neutral.HeatingTime, 20.0)
+
+	v2 := NewPredictionServiceV2(mockRecordService, cfg)
+	v2.SetSatisfactionTargets(stubTargets{"user1": 60})
+	tuned, err := v2.Predict(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, tuned.HeatingTime)
+	assert.Equal(t, 60.0, userRecords[0].Satisfaction, "records shared with a cache are left alone")
+}
+
+func TestPredictionServiceV2_FrozenClockMakesPredictionsReproducible(t *testing.T) {
+	start := time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC)
+	cold, warm := 13.0, 23.0
+
+	// The same showers taken with cold and with warm inlet water
+	var userRecords []models.DailyRecord
+	for i := 0; i < 6; i++ {
+		userRecords = append(userRecords,
+			models.DailyRecord{
+				UserID: "user1", Date: start.AddDate(0, 0, i), InletTemperature: &cold,
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 30, Satisfaction: 50,
+			},
+			models.DailyRecord{
+				UserID: "user1", Date: start.AddDate(0, 0, i).Add(time.Hour), InletTemperature: &warm,
+				ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 15, Satisfaction: 50,
+			},
+		)
+	}
+
+	mockRecordService := &MockRecordService{}
+	mockRecordService.On("GetRecordsForPredictionByUser", "user1", 400).Return(userRecords, nil)
+	mockRecordService.On("GetGlobalRecordsForPrediction", "user1", 1200).Return([]models.DailyRecord{}, nil)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15}
+
+	predictAt := func(at time.Time) float64 {
+		service := NewPredictionServiceV2(mockRecordService, &PredictionConfigV2{DisableExploration: true})
+		service.SetClock(NewFrozenClock(at))
+		resp, err := service.Predict(context.Background(), req)
+		require.NoError(t, err)
+		return resp.HeatingTime
+	}
+
+	// The inlet estimate follows the season of the clock, not of the machine running the test
+	winter := time.Date(2025, 2, 1, 7, 0, 0, 0, time.UTC)
+	summer := time.Date(2025, 8, 8, 7, 0, 0, 0, time.UTC)
+	assert.Equal(t, predictAt(winter), predictAt(winter))
+	assert.Greater(t, predictAt(winter), predictAt(summer))
+}
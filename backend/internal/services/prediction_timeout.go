@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPredictionTimeout is returned when a prediction exceeds its deadline and no
+// last-known value exists for the requested context.
+var ErrPredictionTimeout = errors.New("prediction timed out")
+
+// TimeoutPredictor wraps a Predictor with a latency budget. Successful predictions are
+// remembered per user/context so a slow call can fall back to the last known value
+// (marked stale) instead of hanging the UI.
+type TimeoutPredictor struct {
+	inner     Predictor
+	timeout   time.Duration
+	lastKnown *lastKnownCache
+}
+
+// NewTimeoutPredictor creates a predictor that enforces the given deadline on inner.
+func NewTimeoutPredictor(inner Predictor, timeout time.Duration) *TimeoutPredictor {
+	return &TimeoutPredictor{
+		inner:     inner,
+		timeout:   timeout,
+		lastKnown: newLastKnownCache(),
+	}
+}
+
+type predictionResult struct {
+	resp *PredictionResponse
+	err  error
+}
+
+// Predict runs the wrapped predictor under a deadline derived from ctx.
+func (p *TimeoutPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	// Buffered so the worker never blocks if we stop listening after a timeout
+	done := make(chan predictionResult, 1)
+	go func() {
+		resp, err := p.inner.Predict(ctx, req)
+		done <- predictionResult{resp: resp, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			if errors.Is(res.err, context.DeadlineExceeded) {
+				return p.fallback(req)
+			}
+			return nil, res.err
+		}
+		p.lastKnown.remember(req, res.resp)
+		return res.resp, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return p.fallback(req)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// fallback returns the last known prediction for the request's context, marked stale
+func (p *TimeoutPredictor) fallback(req PredictionRequest) (*PredictionResponse, error) {
+	cached, ok := p.lastKnown.lookup(req)
+	if !ok {
+		return nil, ErrPredictionTimeout
+	}
+	return cached, nil
+}
+
+// ForgetUser drops every cached prediction for the user
+func (p *TimeoutPredictor) ForgetUser(userID string) {
+	p.lastKnown.forgetUser(userID)
+}
+
+// lastKnownCache remembers the latest successful prediction per user and context
+type lastKnownCache struct {
+	mu        sync.RWMutex
+	responses map[string]PredictionResponse
+}
+
+func newLastKnownCache() *lastKnownCache {
+	return &lastKnownCache{responses: make(map[string]PredictionResponse)}
+}
+
+// lookup returns a copy of the cached prediction for the request's context, marked stale
+func (c *lastKnownCache) lookup(req PredictionRequest) (*PredictionResponse, bool) {
+	c.mu.RLock()
+	cached, ok := c.responses[lastKnownKey(req)]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	cached.Stale = true
+	return &cached, true
+}
+
+// remember keeps a prediction as the context's last known one. Answers that skipped the step cap
+// are left out, so a timeout can't serve the large adjustment again without being asked.
+func (c *lastKnownCache) remember(req PredictionRequest, resp *PredictionResponse) {
+	if resp == nil || req.AllowLargeAdjustment {
+		return
+	}
+	c.mu.Lock()
+	c.responses[lastKnownKey(req)] = *resp
+	c.mu.Unlock()
+}
+
+func (c *lastKnownCache) forgetUser(userID string) {
+	prefix := userID + "|"
+	c.mu.Lock()
+	for key := range c.responses {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.responses, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// lastKnownKey identifies a context as user, member and device plus whole-minute duration and
+// whole-degree temperature
+func lastKnownKey(req PredictionRequest) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%d", req.UserID, req.MemberID, req.DeviceID, int(math.Round(req.Duration)), int(math.Round(req.Temperature)))
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowPredictor answers with a fixed value after a delay, honouring cancellation
+type slowPredictor struct {
+	delay  time.Duration
+	answer float64
+}
+
+func (p *slowPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	select {
+	case <-time.After(p.delay):
+		return &PredictionResponse{HeatingTime: p.answer}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestTimeoutPredictor_ServesLastKnownValueWhenSlow(t *testing.T) {
+	inner := &slowPredictor{answer: 17}
+	predictor := NewTimeoutPredictor(inner, 50*time.Millisecond)
+	req := PredictionRequest{UserID: "user1", Duration: 10, Temperature: 20}
+
+	// Fast call populates the cache
+	resp, err := predictor.Predict(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 17.0, resp.HeatingTime)
+	assert.False(t, resp.Stale)
+
+	// Slow call falls back to the cached value
+	inner.delay = time.Second
+	resp, err = predictor.Predict(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 17.0, resp.HeatingTime)
+	assert.True(t, resp.Stale)
+}
+
+func TestTimeoutPredictor_NoCachedValue(t *testing.T) {
+	predictor := NewTimeoutPredictor(&slowPredictor{delay: time.Second}, 20*time.Millisecond)
+
+	_, err := predictor.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 20})
+	assert.ErrorIs(t, err, ErrPredictionTimeout)
+}
+
+func TestTimeoutPredictor_LastKnownValuePerDevice(t *testing.T) {
+	inner := &slowPredictor{answer: 17}
+	predictor := NewTimeoutPredictor(inner, 50*time.Millisecond)
+	boiler := PredictionRequest{UserID: "user1", DeviceID: "boiler", Duration: 10, Temperature: 20}
+	_, err := predictor.Predict(context.Background(), boiler)
+	assert.NoError(t, err)
+
+	// An answer that skipped the step cap isn't remembered
+	inner.answer = 40
+	annex := PredictionRequest{UserID: "user1", DeviceID: "annex", Duration: 10, Temperature: 20, AllowLargeAdjustment: true}
+	_, err = predictor.Predict(context.Background(), annex)
+	assert.NoError(t, err)
+
+	inner.delay = time.Second
+	resp, err := predictor.Predict(context.Background(), boiler)
+	assert.NoError(t, err)
+	assert.Equal(t, 17.0, resp.HeatingTime)
+	annex.AllowLargeAdjustment = false
+	_, err = predictor.Predict(context.Background(), annex)
+	assert.ErrorIs(t, err, ErrPredictionTimeout, "the other heater's answer isn't served")
+}
@@ -1,9 +1,13 @@
 package services
 
+import "context"
+
 type Predictor interface {
-	Predict(PredictionRequest) (*PredictionResponse, error)
+	Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error)
 }
 
 // compile-time assertions
 var _ Predictor = (*PredictionService)(nil)
 var _ Predictor = (*PredictionServiceV2)(nil)
+var _ Predictor = (*TimeoutPredictor)(nil)
+var _ Predictor = (*SafetyFloorPredictor)(nil)
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPresetNotFound is returned when a preset does not exist for the user
+	ErrPresetNotFound = errors.New("preset not found")
+	// ErrPresetNameTaken is returned when the user already has a preset with the same name
+	ErrPresetNameTaken = errors.New("a preset with this name already exists")
+)
+
+// PresetService handles named shower profiles
+type PresetService struct {
+	db *gorm.DB
+}
+
+// NewPresetService creates a new preset service instance
+func NewPresetService() *PresetService {
+	return &PresetService{
+		db: database.GetDB(),
+	}
+}
+
+// CreatePreset stores a new preset
+func (s *PresetService) CreatePreset(preset *models.Preset) error {
+	if err := s.ensureNameFree(preset.UserID, preset.Name, ""); err != nil {
+		return err
+	}
+	return s.db.Create(preset).Error
+}
+
+// ListPresets returns the user's presets ordered by name
+func (s *PresetService) ListPresets(userID string) ([]models.Preset, error) {
+	var presets []models.Preset
+	err := s.db.Where("user_id = ?", userID).Order("name ASC").Find(&presets).Error
+	return presets, err
+}
+
+// GetPreset retrieves one of the user's presets
+func (s *PresetService) GetPreset(userID, id string) (*models.Preset, error) {
+	var preset models.Preset
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&preset).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPresetNotFound
+		}
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// UpdatePreset overwrites the editable fields of an existing preset
+func (s *PresetService) UpdatePreset(preset *models.Preset) error {
+	existing, err := s.GetPreset(preset.UserID, preset.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureNameFree(preset.UserID, preset.Name, preset.ID); err != nil {
+		return err
+	}
+
+	existing.Name = preset.Name
+	existing.Duration = preset.Duration
+	existing.Temperature = preset.Temperature
+	existing.Usage = preset.Usage
+	if err := s.db.Save(existing).Error; err != nil {
+		return err
+	}
+	*preset = *existing
+	return nil
+}
+
+// DeletePreset deletes one of the user's presets. Records keep their preset ID for reference.
+func (s *PresetService) DeletePreset(userID, id string) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Preset{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPresetNotFound
+	}
+	return nil
+}
+
+func (s *PresetService) ensureNameFree(userID, name, exceptID string) error {
+	var count int64
+	query := s.db.Model(&models.Preset{}).Where("user_id = ? AND LOWER(name) = ?", userID, strings.ToLower(name))
+	if exceptID != "" {
+		query = query.Where("id != ?", exceptID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPresetNameTaken
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+var (
+	// ErrPricesDisabled is returned when no price provider is configured
+	ErrPricesDisabled = errors.New("energy prices are not configured")
+	// ErrNoPrices is returned when the provider has no prices for the requested time
+	ErrNoPrices = errors.New("no energy prices for that time")
+)
+
+// EnergyPrice is the spot price of electricity over one interval, usually an hour
+type EnergyPrice struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Price float64   `json:"price"` // per kWh, in the provider's currency
+}
+
+// PriceProvider fetches the day-ahead prices for the day containing day
+type PriceProvider interface {
+	Name() string
+	Currency() string
+	DayPrices(ctx context.Context, day time.Time) ([]EnergyPrice, error)
+}
+
+// awattarPrices reads the aWATTar market data API (Germany and Austria)
+type awattarPrices struct {
+	url    string
+	client *http.Client
+}
+
+func (p *awattarPrices) Name() string     { return "awattar" }
+func (p *awattarPrices) Currency() string { return "EUR" }
+
+func (p *awattarPrices) DayPrices(ctx context.Context, day time.Time) ([]EnergyPrice, error) {
+	start := startOfDay(day)
+	params := url.Values{
+		"start": {strconv.FormatInt(start.UnixMilli(), 10)},
+		"end":   {strconv.FormatInt(start.AddDate(0, 0, 1).UnixMilli(), 10)},
+	}
+	var body struct {
+		Data []struct {
+			Start       int64   `json:"start_timestamp"`
+			End         int64   `json:"end_timestamp"`
+			MarketPrice float64 `json:"marketprice"` // EUR/MWh
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, p.client, p.url+"/v1/marketdata?"+params.Encode(), "", &body); err != nil {
+		return nil, err
+	}
+	prices := make([]EnergyPrice, 0, len(body.Data))
+	for _, entry := range body.Data {
+		prices = append(prices, EnergyPrice{
+			Start: time.UnixMilli(entry.Start),
+			End:   time.UnixMilli(entry.End),
+			Price: entry.MarketPrice / 1000,
+		})
+	}
+	return prices, nil
+}
+
+// nordpoolPrices reads the Nord Pool day-ahead prices for one delivery area
+type nordpoolPrices struct {
+	url      string
+	area     string
+	currency string
+	client   *http.Client
+}
+
+func (p *nordpoolPrices) Name() string     { return "nordpool" }
+func (p *nordpoolPrices) Currency() string { return p.currency }
+
+func (p *nordpoolPrices) DayPrices(ctx context.Context, day time.Time) ([]EnergyPrice, error) {
+	params := url.Values{
+		"date":         {day.Format("2006-01-02")},
+		"market":       {"DayAhead"},
+		"deliveryArea": {p.area},
+		"currency":     {p.currency},
+	}
+	var body struct {
+		Entries []struct {
+			Start   time.Time          `json:"deliveryStart"`
+			End     time.Time          `json:"deliveryEnd"`
+			PerArea map[string]float64 `json:"entryPerArea"` // currency/MWh
+		} `json:"multiAreaEntries"`
+	}
+	if err := getJSON(ctx, p.client, p.url+"/api/DayAheadPrices?"+params.Encode(), "", &body); err != nil {
+		return nil, err
+	}
+	prices := make([]EnergyPrice, 0, len(body.Entries))
+	for _, entry := range body.Entries {
+		price, ok := entry.PerArea[p.area]
+		if !ok {
+			continue
+		}
+		prices = append(prices, EnergyPrice{Start: entry.Start, End: entry.End, Price: price / 1000})
+	}
+	return prices, nil
+}
+
+// newPriceProvider builds the provider the configuration names, or nil when prices are disabled
+func newPriceProvider(cfg config.PricesConfig) PriceProvider {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch cfg.Provider {
+	case "awattar":
+		target := cfg.URL
+		if target == "" {
+			target = "https://api.awattar.de"
+		}
+		return &awattarPrices{url: target, client: client}
+	case "nordpool":
+		target := cfg.URL
+		if target == "" {
+			target = "https://dataportal-api.nordpoolgroup.com"
+		}
+		return &nordpoolPrices{url: target, area: cfg.Area, currency: cfg.Currency, client: client}
+	}
+	return nil
+}
+
+// priceCacheDays is how many days of prices are kept in memory
+const priceCacheDays = 7
+
+// PriceService serves hourly spot prices and picks the cheapest time to heat. Day-ahead prices
+// don't change once published, so each day is fetched once and cached.
+type PriceService struct {
+	provider PriceProvider
+	flatRate float64
+	currency string
+
+	mu    sync.Mutex
+	cache map[string][]EnergyPrice
+}
+
+// NewPriceService creates a price service for the configured provider
+func NewPriceService(cfg config.PricesConfig) *PriceService {
+	return &PriceService{
+		provider: newPriceProvider(cfg),
+		flatRate: cfg.FlatRate,
+		currency: cfg.Currency,
+		cache:    make(map[string][]EnergyPrice),
+	}
+}
+
+// Currency returns the currency prices are quoted in, or "" when there are neither spot prices
+// nor a flat rate
+func (s *PriceService) Currency() string {
+	if s.provider == nil {
+		if s.flatRate > 0 {
+			return s.currency
+		}
+		return ""
+	}
+	return s.provider.Currency()
+}
+
+// FlatRate returns the configured tariff per kWh for hours without spot prices, if there is one
+func (s *PriceService) FlatRate() (float64, bool) {
+	return s.flatRate, s.flatRate > 0
+}
+
+// DayPrices returns the prices for the day containing day, ordered by start time. A day the
+// provider hasn't published yet returns ErrNoPrices and is asked for again next time.
+func (s *PriceService) DayPrices(ctx context.Context, day time.Time) ([]EnergyPrice, error) {
+	if s.provider == nil {
+		return nil, ErrPricesDisabled
+	}
+	key := day.Format("2006-01-02")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prices, ok := s.cache[key]; ok {
+		return prices, nil
+	}
+
+	prices, err := s.provider.DayPrices(ctx, day)
+	if err != nil {
+		return nil, fmt.Errorf("%s prices: %w", s.provider.Name(), err)
+	}
+	if len(prices) == 0 {
+		return nil, ErrNoPrices
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Start.Before(prices[j].Start) })
+
+	s.cache[key] = prices
+	oldest := startOfDay(day).AddDate(0, 0, -priceCacheDays).Format("2006-01-02")
+	for cached := range s.cache {
+		if cached < oldest {
+			delete(s.cache, cached)
+		}
+	}
+	return prices, nil
+}
+
+// pricesBetween collects the prices of every day from from to to; days the provider has no
+// prices for are left out
+func (s *PriceService) pricesBetween(ctx context.Context, from, to time.Time) ([]EnergyPrice, error) {
+	var prices []EnergyPrice
+	for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		dayPrices, err := s.DayPrices(ctx, day)
+		if errors.Is(err, ErrNoPrices) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, dayPrices...)
+	}
+	return prices, nil
+}
+
+// priceAt returns the price in effect at t
+func priceAt(prices []EnergyPrice, t time.Time) (float64, bool) {
+	for _, price := range prices {
+		if !t.Before(price.Start) && t.Before(price.End) {
+			return price.Price, true
+		}
+	}
+	return 0, false
+}
+
+// startOfDay returns midnight at the start of t's day, in t's location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
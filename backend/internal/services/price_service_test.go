@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAwattarServer serves hourly EUR/MWh prices for day starting at midnight, counting requests
+func newAwattarServer(t *testing.T, day time.Time, hourly []float64, requests *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		assert.Equal(t, "/v1/marketdata", r.URL.Path)
+		entries := make([]string, len(hourly))
+		for i, price := range hourly {
+			start := day.Add(time.Duration(i) * time.Hour)
+			entries[i] = fmt.Sprintf(`{"start_timestamp": %d, "end_timestamp": %d, "marketprice": %g, "unit": "Eur/MWh"}`,
+				start.UnixMilli(), start.Add(time.Hour).UnixMilli(), price)
+		}
+		w.Write([]byte(`{"object": "list", "data": [` + strings.Join(entries, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPriceService_DayPrices(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	requests := 0
+	server := newAwattarServer(t, day, []float64{120, 90}, &requests)
+	service := NewPriceService(config.PricesConfig{Provider: "awattar", URL: server.URL})
+
+	prices, err := service.DayPrices(context.Background(), day)
+	require.NoError(t, err)
+	require.Len(t, prices, 2)
+	assert.Equal(t, 0.09, prices[1].Price)
+	assert.Equal(t, day.Add(time.Hour), prices[1].Start.UTC())
+
+	// The day's prices are fetched once
+	_, err = service.DayPrices(context.Background(), day.Add(12*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestPriceService_Disabled(t *testing.T) {
+	service := NewPriceService(config.PricesConfig{})
+
+	_, err := service.DayPrices(context.Background(), time.Now())
+	assert.ErrorIs(t, err, ErrPricesDisabled)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+// publicCountStep is what published counts are rounded down to a multiple of, so a single
+// new shower doesn't show
+const publicCountStep = 10
+
+// PublicAggregates computes the instance-wide figures public statistics are made of
+type PublicAggregates interface {
+	Count(q RecordQuery) (int64, error)
+	Users(q RecordQuery) (int64, error)
+	HeatingTimeByTemperature(q RecordQuery, width float64) ([]TemperatureBand, error)
+}
+
+// PublicStats are instance-wide figures safe to show without a login. Every figure draws on at
+// least MinGroupSize users; what doesn't is left out rather than shown as small.
+type PublicStats struct {
+	Showers          *int64            `json:"showers"`          // showers logged, rounded down to a multiple of 10; null with too few users
+	TemperatureBands []PublicStatsBand `json:"temperatureBands"` // coldest first
+	MinGroupSize     int               `json:"minGroupSize"`     // users every figure draws on at least
+	UpdatedAt        time.Time         `json:"updatedAt"`
+}
+
+// PublicStatsBand is the average heating time for one outdoor temperature band
+type PublicStatsBand struct {
+	Min                float64 `json:"min"` // °C, inclusive
+	Max                float64 `json:"max"` // °C, exclusive
+	Showers            int64   `json:"showers"`
+	AverageHeatingTime float64 `json:"averageHeatingTime"` // minutes
+}
+
+// PublicStatsService computes the public statistics and caches them for the refresh interval
+type PublicStatsService struct {
+	cfg        config.PublicStatsConfig
+	aggregates PublicAggregates
+	now        func() time.Time
+
+	mu     sync.Mutex
+	cached *PublicStats
+}
+
+// NewPublicStatsService creates a new public statistics service instance
+func NewPublicStatsService(cfg config.PublicStatsConfig, aggregates PublicAggregates) *PublicStatsService {
+	return &PublicStatsService{
+		cfg:        cfg,
+		aggregates: aggregates,
+		now:        time.Now,
+	}
+}
+
+// SetClock sets the clock the statistics are dated and refreshed by
+func (s *PublicStatsService) SetClock(clock Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = clock.Now
+}
+
+// Stats returns the public statistics, recomputed once the cached ones are older than the
+// refresh interval
+func (s *PublicStatsService) Stats() (*PublicStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.cached != nil && now.Sub(s.cached.UpdatedAt) < s.cfg.Refresh {
+		return s.cached, nil
+	}
+	stats, err := s.compute(now)
+	if err != nil {
+		return nil, err
+	}
+	s.cached = stats
+	return stats, nil
+}
+
+func (s *PublicStatsService) compute(now time.Time) (*PublicStats, error) {
+	stats := &PublicStats{TemperatureBands: []PublicStatsBand{}, MinGroupSize: s.cfg.MinUsers, UpdatedAt: now}
+	users, err := s.aggregates.Users(RecordQuery{})
+	if err != nil {
+		return nil, err
+	}
+	if users < int64(s.cfg.MinUsers) {
+		return stats, nil
+	}
+	showers, err := s.aggregates.Count(RecordQuery{})
+	if err != nil {
+		return nil, err
+	}
+	showers -= showers % publicCountStep
+	stats.Showers = &showers
+
+	// Showers the learner leaves out, such as those after a legionella cycle, would skew the
+	// averages
+	bands, err := s.aggregates.HeatingTimeByTemperature(RecordQuery{TrainingOnly: true}, s.cfg.BandWidth)
+	if err != nil {
+		return nil, err
+	}
+	for _, band := range bands {
+		if band.Users < int64(s.cfg.MinUsers) || band.Records < int64(s.cfg.MinRecords) {
+			continue
+		}
+		lo := float64(band.Band) * s.cfg.BandWidth
+		stats.TemperatureBands = append(stats.TemperatureBands, PublicStatsBand{
+			Min:                lo,
+			Max:                lo + s.cfg.BandWidth,
+			Showers:            band.Records - band.Records%publicCountStep,
+			AverageHeatingTime: roundTo(band.HeatingTime, 1),
+		})
+	}
+	return stats, nil
+}
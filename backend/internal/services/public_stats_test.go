@@ -0,0 +1,73 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestPublicStatsService(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "public.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	service := NewPublicStatsService(config.PublicStatsConfig{MinUsers: 3, MinRecords: 5, BandWidth: 5}, records)
+	clock := NewFrozenClock(time.Now())
+	service.SetClock(clock)
+
+	at := time.Now().Add(-time.Hour)
+	shower := func(userID string, temperature, heatingTime float64) {
+		at = at.Add(-time.Minute)
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: userID, Date: at,
+			ShowerDuration: 10, AverageTemperature: temperature, HeatingTime: heatingTime, Satisfaction: 50,
+		}))
+	}
+	for _, userID := range []string{"user1", "user2"} {
+		for i := 0; i < 4; i++ {
+			shower(userID, 12, 20)
+		}
+	}
+
+	// Two users are too few for anything
+	stats, err := service.Stats()
+	require.NoError(t, err)
+	assert.Nil(t, stats.Showers)
+	assert.Empty(t, stats.TemperatureBands)
+	assert.Equal(t, 3, stats.MinGroupSize)
+
+	for i := 0; i < 4; i++ {
+		shower("user3", 14.5, 26)
+	}
+	// One user's frosty mornings are theirs alone
+	for i := 0; i < 6; i++ {
+		shower("user1", -3, 35)
+	}
+	stats, err = service.Stats()
+	require.NoError(t, err)
+	require.NotNil(t, stats.Showers)
+	assert.Equal(t, int64(10), *stats.Showers, "18 showers, rounded down")
+	assert.Equal(t, []PublicStatsBand{{Min: 10, Max: 15, Showers: 10, AverageHeatingTime: 22}}, stats.TemperatureBands)
+
+	// Cached figures don't move until the refresh interval passes
+	service.cfg.Refresh = time.Hour
+	_, err = service.Stats()
+	require.NoError(t, err)
+	shower("user2", 12, 20)
+	cached, err := service.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 22.0, cached.TemperatureBands[0].AverageHeatingTime)
+	assert.Equal(t, clock.Now(), cached.UpdatedAt)
+	clock.Advance(2 * time.Hour)
+	fresh, err := service.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 21.8, fresh.TemperatureBands[0].AverageHeatingTime)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Quick feedback verdicts, for users who won't move a 0–100 slider after every shower
+const (
+	QuickVerdictPerfect = "perfect"
+	QuickVerdictTooCold = "too_cold"
+	QuickVerdictTooHot  = "too_hot"
+)
+
+// quickVerdictSatisfaction is the rating each verdict stands for on the neutral scale, where 50
+// is perfect. A verdict says which way the water was off but not by how much, so it lands
+// halfway to the end of the scale.
+var quickVerdictSatisfaction = map[string]float64{
+	QuickVerdictPerfect: models.SatisfactionPerfect,
+	QuickVerdictTooCold: 25,
+	QuickVerdictTooHot:  75,
+}
+
+// ErrUnknownVerdict is returned for a verdict other than perfect, too_cold or too_hot
+var ErrUnknownVerdict = errors.New("unknown quick feedback verdict")
+
+// quickFeedbackNamespace derives a quick feedback record's ID from its prediction, so a retried
+// request finds the record it stored the first time
+var quickFeedbackNamespace = uuid.MustParse("0b8f5d2e-7c41-4f6a-a3e9-5d1c8b2f7e64")
+
+// QuickFeedbackRecord builds the feedback record a verdict on a logged prediction stands for:
+// the shower the prediction was served for, heated for the time it predicted. The rating is put
+// on the user's own scale, so their target satisfaction rescales a perfect verdict back to 50.
+func QuickFeedbackRecord(prediction models.PredictionLog, verdict string, target float64) (*models.DailyRecord, error) {
+	satisfaction, ok := quickVerdictSatisfaction[verdict]
+	if !ok {
+		return nil, ErrUnknownVerdict
+	}
+	return PredictionFeedbackRecord(prediction, userSatisfaction(satisfaction, target)), nil
+}
+
+// PredictionFeedbackRecord builds the feedback record for a rating of a logged prediction, the
+// shower it was served for heated for the time it predicted. Its ID is derived from the
+// prediction, so a prediction takes one rating however it is given.
+func PredictionFeedbackRecord(prediction models.PredictionLog, satisfaction float64) *models.DailyRecord {
+	return &models.DailyRecord{
+		ID:                 uuid.NewSHA1(quickFeedbackNamespace, []byte(prediction.ID)).String(),
+		UserID:             prediction.UserID,
+		MemberID:           prediction.MemberID,
+		DeviceID:           prediction.DeviceID,
+		Date:               prediction.CreatedAt.Add(time.Duration(prediction.HeatingTime * float64(time.Minute))),
+		ShowerDuration:     prediction.Duration,
+		AverageTemperature: prediction.Temperature,
+		InletTemperature:   prediction.InletTemperature,
+		HeatingTime:        prediction.HeatingTime,
+		Satisfaction:       satisfaction,
+		Source:             models.RecordSourceManual,
+		TemperatureSource:  prediction.TemperatureSource,
+	}
+}
+
+// userSatisfaction undoes neutralizeSatisfaction, turning a rating on the neutral scale into
+// the one a user with the given target would have given
+func userSatisfaction(satisfaction, target float64) float64 {
+	switch {
+	case target == models.SatisfactionPerfect:
+		return satisfaction
+	case satisfaction < models.SatisfactionPerfect:
+		satisfaction = models.SatisfactionMin + (satisfaction-models.SatisfactionMin)*(target-models.SatisfactionMin)/(models.SatisfactionPerfect-models.SatisfactionMin)
+	default:
+		satisfaction = target + (satisfaction-models.SatisfactionPerfect)*(models.SatisfactionMax-target)/(models.SatisfactionMax-models.SatisfactionPerfect)
+	}
+	return math.Round(satisfaction)
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RandomSource hands out the randomness predictors use, e.g. to jitter exploration. Each
+// prediction gets its own generator, seeded from the source's seed, the request and the clock,
+// so a backtest replaying the same history with the same seed and a frozen clock varies every
+// prediction the same way, however the predictions interleave. The per-prediction seed is kept
+// in the prediction log.
+type RandomSource struct {
+	seed int64
+}
+
+// NewRandomSource creates a source from seed; 0 picks one from the current time
+func NewRandomSource(seed int64) *RandomSource {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &RandomSource{seed: seed}
+}
+
+// Seed returns the seed every prediction's generator derives from
+func (s *RandomSource) Seed() int64 {
+	return s.seed
+}
+
+// ForPrediction returns the generator for one prediction and the seed it was made from
+func (s *RandomSource) ForPrediction(req PredictionRequest, now time.Time) (*rand.Rand, int64) {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, n := range []uint64{uint64(s.seed), math.Float64bits(req.Duration), math.Float64bits(req.Temperature), uint64(now.UnixNano())} {
+		binary.LittleEndian.PutUint64(buf[:], n)
+		h.Write(buf[:])
+	}
+	for _, id := range []string{req.UserID, req.MemberID, req.DeviceID} {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+	seed := int64(h.Sum64())
+	return rand.New(rand.NewSource(seed)), seed
+}
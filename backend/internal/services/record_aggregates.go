@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RecordQuery narrows the records an aggregate covers; zero fields don't filter
+type RecordQuery struct {
+	UserID  string
+	Sources []string
+	From    *time.Time // inclusive
+
+	TrainingOnly bool // leave out records excluded from training
+}
+
+// WeeklyAverage summarises the records of one week, starting on Monday
+type WeeklyAverage struct {
+	WeekStart      string  `json:"weekStart"` // YYYY-MM-DD
+	Records        int64   `json:"records"`
+	HeatingTime    float64 `json:"heatingTime"`    // mean minutes
+	ShowerDuration float64 `json:"showerDuration"` // mean minutes
+	Satisfaction   float64 `json:"satisfaction"`   // mean 1-100
+}
+
+// BucketSatisfaction is the feedback of the records in one context bucket
+type BucketSatisfaction struct {
+	Duration     int     // bucket index, duration / bucketDurationWidth rounded down
+	Temperature  int     // bucket index, temperature / bucketTemperatureWidth rounded down
+	Records      int64   //
+	Satisfaction float64 // mean 1-100
+	Deviation    float64 // mean distance from a perfect 50
+}
+
+// TemperatureBand summarises the records whose outdoor temperature fell in one band
+type TemperatureBand struct {
+	Band        int     // band index, temperature / width rounded down
+	Records     int64   //
+	Users       int64   // distinct users the records are from
+	HeatingTime float64 // mean minutes
+}
+
+// scope applies the query's filters to the records table
+func (q RecordQuery) scope(db *gorm.DB) *gorm.DB {
+	db = db.Model(&models.DailyRecord{})
+	if q.UserID != "" {
+		db = db.Where("user_id = ?", q.UserID)
+	}
+	if len(q.Sources) > 0 {
+		db = db.Where("source IN ?", q.Sources)
+	}
+	if q.From != nil {
+		db = db.Where("date >= ?", *q.From)
+	}
+	if q.TrainingOnly {
+		db = db.Where("excluded_from_training = ?", false)
+	}
+	return db
+}
+
+// Count returns the number of matching records without loading them
+func (s *RecordService) Count(q RecordQuery) (int64, error) {
+	var count int64
+	err := q.scope(s.db).Count(&count).Error
+	return count, err
+}
+
+// Users returns the number of distinct users with matching records
+func (s *RecordService) Users(q RecordQuery) (int64, error) {
+	var count int64
+	err := q.scope(s.db).Distinct("user_id").Count(&count).Error
+	return count, err
+}
+
+// HeatingTimeByTemperature groups the matching records into outdoor temperature bands of the
+// given width in °C, coldest first. The grouping is done by the database.
+func (s *RecordService) HeatingTimeByTemperature(q RecordQuery, width float64) ([]TemperatureBand, error) {
+	bands := []TemperatureBand{}
+	// Offset so the cast rounds negative temperatures down too
+	err := q.scope(s.db).
+		Select(fmt.Sprintf("CAST(average_temperature / %g + 1000 AS INTEGER) - 1000 AS band, "+
+			"COUNT(*) AS records, COUNT(DISTINCT user_id) AS users, AVG(heating_time) AS heating_time", width)).
+		Group("band").
+		Order("band ASC").
+		Scan(&bands).Error
+	return bands, err
+}
+
+// AverageByWeek returns per-week means of the matching records, oldest week first. The
+// grouping is done by the database.
+func (s *RecordService) AverageByWeek(q RecordQuery) ([]WeeklyAverage, error) {
+	weeks := []WeeklyAverage{}
+	err := q.scope(s.db).
+		Select("date(date, 'weekday 0', '-6 days') AS week_start, COUNT(*) AS records, " +
+			"AVG(heating_time) AS heating_time, AVG(shower_duration) AS shower_duration, AVG(satisfaction) AS satisfaction").
+		Group("week_start").
+		Order("week_start ASC").
+		Scan(&weeks).Error
+	return weeks, err
+}
+
+// HeatingTimePercentiles returns the nearest-rank percentiles of the matching records'
+// heating times, one per fraction in ps (e.g. 0.5 for the median). The database orders the
+// values; only the ones asked for are read. With no records the result is nil.
+func (s *RecordService) HeatingTimePercentiles(q RecordQuery, ps ...float64) ([]float64, error) {
+	count, err := s.Count(q)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	values := make([]float64, len(ps))
+	for i, p := range ps {
+		rank := int64(math.Ceil(clamp(p, 0, 1) * float64(count)))
+		if rank < 1 {
+			rank = 1
+		}
+		err := q.scope(s.db).Select("heating_time").Order("heating_time ASC").
+			Offset(int(rank - 1)).Limit(1).Scan(&values[i]).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// SatisfactionByBucket groups the matching records into context buckets in the database. The
+// offsets keep the integer cast a floor for temperatures down to -1000 °C.
+func (s *RecordService) SatisfactionByBucket(q RecordQuery) ([]BucketSatisfaction, error) {
+	var buckets []BucketSatisfaction
+	err := q.scope(s.db).
+		Select(fmt.Sprintf("CAST(shower_duration / %[1]g AS INTEGER) AS duration, "+
+			"CAST((average_temperature + 1000) / %[2]g AS INTEGER) - CAST(1000 / %[2]g AS INTEGER) AS temperature, "+
+			"COUNT(*) AS records, AVG(satisfaction) AS satisfaction, AVG(ABS(satisfaction - %[3]d)) AS deviation",
+			bucketDurationWidth, bucketTemperatureWidth, models.SatisfactionPerfect)).
+		Group("duration, temperature").
+		Order("temperature ASC, duration ASC").
+		Scan(&buckets).Error
+	return buckets, err
+}
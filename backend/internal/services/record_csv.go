@@ -0,0 +1,55 @@
+package services
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"heat-logger/internal/models"
+)
+
+// recordCSVHeader is the column layout shared by synchronous and asynchronous exports
+var recordCSVHeader = []string{"User ID", "Date", "Shower Duration", "Average Temperature", "Heating Time", "Satisfaction", "Source", "Temperature Source", "Model Version", "Model Revision"}
+
+// recordCSVTimeLayout is how record dates are written when no locale is chosen
+const recordCSVTimeLayout = "2006-01-02 15:04:05"
+
+// WriteRecordsCSV writes records as CSV, including the header row, with dates and numbers in
+// the given format
+func WriteRecordsCSV(w io.Writer, records []models.DailyRecord, format ExportFormat) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = format.Separator()
+
+	if err := writer.Write(recordCSVHeader); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := []string{
+			record.UserID,
+			format.Time(record.Date, recordCSVTimeLayout),
+			format.Number(record.ShowerDuration, 1),
+			format.Number(record.AverageTemperature, 1),
+			format.Number(record.HeatingTime, 1),
+			format.Number(record.Satisfaction, 1),
+			record.Source,
+			record.TemperatureSource,
+			record.ModelVersion,
+			modelRevisionText(record.ModelRevision),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// modelRevisionText writes a model revision, leaving it empty when unknown
+func modelRevisionText(revision int) string {
+	if revision == 0 {
+		return ""
+	}
+	return strconv.Itoa(revision)
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// Integrity problems a stored record can have. Legacy imports bypassed the API's validation, so
+// their history holds values no shower can produce.
+const (
+	IntegrityDurationNotPositive = "duration_not_positive"     // shower duration of zero or below
+	IntegrityHeatingNotPositive  = "heating_not_positive"      // heating time of zero or below
+	IntegrityHeatingAboveMax     = "heating_above_max"         // heating time above the configured maximum
+	IntegritySatisfactionRange   = "satisfaction_out_of_range" // satisfaction off the 1-100 scale
+	IntegrityFutureDate          = "future_date"               // dated more than a day after the scan
+	IntegrityChecksumMismatch    = "checksum_mismatch"         // changed outside the API since it was signed
+)
+
+// integrityFutureSlack is how far ahead of the server a record may be dated before it counts
+// as in the future, leaving room for clients in time zones ahead of it
+const integrityFutureSlack = 24 * time.Hour
+
+// IntegrityIssue is one impossible value of a record. Fix is the value a bulk fix stores; it
+// is nil when there is no sensible replacement, e.g. for a future date or a zero duration.
+type IntegrityIssue struct {
+	RecordID             string    `json:"recordId"`
+	UserID               string    `json:"userId"`
+	Date                 time.Time `json:"date"`
+	Problem              string    `json:"problem"`
+	Field                string    `json:"field"`
+	Value                float64   `json:"value"`
+	Fix                  *float64  `json:"fix,omitempty"`
+	ExcludedFromTraining bool      `json:"excludedFromTraining"`
+}
+
+// IntegrityReport lists the impossible values found in the history
+type IntegrityReport struct {
+	ScannedAt      time.Time        `json:"scannedAt"`
+	MaxHeatingTime float64          `json:"maxHeatingTime"`
+	Records        int              `json:"records"`  // records with at least one issue
+	Unsigned       int              `json:"unsigned"` // records without a checksum while signing is enabled
+	Counts         map[string]int   `json:"counts"`   // issues by problem
+	Issues         []IntegrityIssue `json:"issues"`
+}
+
+// RecordIntegrityIssues returns the impossible values of a record: non-positive times,
+// satisfaction off the scale, heating above maxHeating minutes, dates after now and a checksum
+// that no longer matches
+func RecordIntegrityIssues(record models.DailyRecord, maxHeating float64, now time.Time) []IntegrityIssue {
+	var issues []IntegrityIssue
+	add := func(problem, field string, value float64, fix *float64) {
+		issues = append(issues, IntegrityIssue{
+			RecordID:             record.ID,
+			UserID:               record.UserID,
+			Date:                 record.Date,
+			Problem:              problem,
+			Field:                field,
+			Value:                value,
+			Fix:                  fix,
+			ExcludedFromTraining: record.ExcludedFromTraining,
+		})
+	}
+
+	if record.ShowerDuration <= 0 {
+		// A negative time is usually a sign flipped by the importer; zero carries nothing to recover
+		var fix *float64
+		if record.ShowerDuration < 0 {
+			fix = ptr(-record.ShowerDuration)
+		}
+		add(IntegrityDurationNotPositive, "showerDuration", record.ShowerDuration, fix)
+	}
+	switch {
+	case record.HeatingTime <= 0:
+		var fix *float64
+		if record.HeatingTime < 0 {
+			fix = ptr(math.Min(-record.HeatingTime, maxHeating))
+		}
+		add(IntegrityHeatingNotPositive, "heatingTime", record.HeatingTime, fix)
+	case record.HeatingTime > maxHeating:
+		add(IntegrityHeatingAboveMax, "heatingTime", record.HeatingTime, ptr(maxHeating))
+	}
+	if record.Satisfaction < models.SatisfactionMin || record.Satisfaction > models.SatisfactionMax {
+		fix := math.Min(math.Max(record.Satisfaction, models.SatisfactionMin), models.SatisfactionMax)
+		add(IntegritySatisfactionRange, "satisfaction", record.Satisfaction, &fix)
+	}
+	if record.Date.After(now.Add(integrityFutureSlack)) {
+		add(IntegrityFutureDate, "date", 0, nil) // the date is in Date
+	}
+	if record.ChecksumMismatch {
+		// Which value was changed is unknown; only the admin can tell whether to trust it
+		add(IntegrityChecksumMismatch, "checksum", 0, nil)
+	}
+	return issues
+}
+
+// flaggedRecords loads the records that may have integrity issues, limited to recordIDs unless
+// it is empty. The date condition is loose because stored dates keep the client's zone;
+// RecordIntegrityIssues makes the exact check. Checksums can only be verified on loaded records,
+// so every record is loaded while signing is enabled.
+func (s *RecordService) flaggedRecords(recordIDs []string, maxHeating float64, now time.Time) ([]models.DailyRecord, error) {
+	query := s.db
+	if !models.RecordChecksumsEnabled() {
+		query = query.Where("shower_duration <= 0 OR heating_time <= 0 OR heating_time > ? OR satisfaction < ? OR satisfaction > ? OR date > ?",
+			maxHeating, models.SatisfactionMin, models.SatisfactionMax, now.Add(integrityFutureSlack-14*time.Hour))
+	}
+	if len(recordIDs) > 0 {
+		query = query.Where("id IN ?", recordIDs)
+	}
+	var records []models.DailyRecord
+	err := query.Order("user_id ASC, date ASC").Find(&records).Error
+	return records, err
+}
+
+// ScanIntegrity reports every record with impossible values, by user and date
+func (s *RecordService) ScanIntegrity(maxHeating float64, now time.Time) (*IntegrityReport, error) {
+	records, err := s.flaggedRecords(nil, maxHeating, now)
+	if err != nil {
+		return nil, err
+	}
+	report := &IntegrityReport{
+		ScannedAt:      now,
+		MaxHeatingTime: maxHeating,
+		Counts:         map[string]int{},
+		Issues:         []IntegrityIssue{},
+	}
+	for _, record := range records {
+		if models.RecordChecksumsEnabled() && record.Checksum == "" {
+			report.Unsigned++
+		}
+		issues := RecordIntegrityIssues(record, maxHeating, now)
+		if len(issues) == 0 {
+			continue
+		}
+		report.Records++
+		for _, issue := range issues {
+			report.Counts[issue.Problem]++
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+	return report, nil
+}
+
+// FixIntegrity stores the fix of every issue of the flagged records, or of those in recordIDs
+// when it isn't empty. The replaced values are kept as a revision, so a fix can be reverted.
+// Records with an issue that has no fix are left alone and counted as skipped.
+func (s *RecordService) FixIntegrity(recordIDs []string, maxHeating float64, now time.Time, editor string) (int, int, error) {
+	records, err := s.flaggedRecords(recordIDs, maxHeating, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	fixed, skipped := 0, 0
+	for _, record := range records {
+		issues := RecordIntegrityIssues(record, maxHeating, now)
+		if len(issues) == 0 {
+			continue
+		}
+		updated := record
+		fixable := true
+		for _, issue := range issues {
+			if issue.Fix == nil {
+				fixable = false
+				break
+			}
+			switch issue.Field {
+			case "showerDuration":
+				updated.ShowerDuration = *issue.Fix
+			case "heatingTime":
+				updated.HeatingTime = *issue.Fix
+			case "satisfaction":
+				updated.Satisfaction = *issue.Fix
+			}
+		}
+		if !fixable {
+			skipped++
+			continue
+		}
+		if err := s.editRecord(record, &updated, editor); err != nil {
+			return fixed, skipped, err
+		}
+		fixed++
+	}
+	return fixed, skipped, nil
+}
+
+// ExcludeIntegrity keeps the flagged records, or those in recordIDs when it isn't empty, in
+// history but away from the predictors, returning how many were newly excluded
+func (s *RecordService) ExcludeIntegrity(recordIDs []string, maxHeating float64, now time.Time) (int64, error) {
+	records, err := s.flaggedRecords(recordIDs, maxHeating, now)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	var excluded []models.DailyRecord
+	for _, record := range records {
+		if !record.ExcludedFromTraining && len(RecordIntegrityIssues(record, maxHeating, now)) > 0 {
+			ids = append(ids, record.ID)
+			record.ExcludedFromTraining = true
+			excluded = append(excluded, record)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := s.db.Model(&models.DailyRecord{}).Where("id IN ?", ids).Update("excluded_from_training", true)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	recordsChanged(s.onUpdated, excluded...)
+	return result.RowsAffected, nil
+}
+
+// SignIntegrity accepts the current values of records that are unsigned or fail their checksum,
+// or only of those in recordIDs when it isn't empty, by signing them again. It returns how many
+// were signed; nothing is signed while signing is disabled.
+func (s *RecordService) SignIntegrity(recordIDs []string) (int, error) {
+	if !models.RecordChecksumsEnabled() {
+		return 0, nil
+	}
+	query := s.db
+	if len(recordIDs) > 0 {
+		query = query.Where("id IN ?", recordIDs)
+	}
+	var records []models.DailyRecord
+	if err := query.Find(&records).Error; err != nil {
+		return 0, err
+	}
+	signed := 0
+	for _, record := range records {
+		if record.Checksum != "" && !record.ChecksumMismatch {
+			continue
+		}
+		err := s.db.Model(&record).UpdateColumn("checksum", record.ComputeChecksum()).Error
+		if err != nil {
+			return signed, err
+		}
+		signed++
+	}
+	return signed, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordIntegrityIssues(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	valid := models.DailyRecord{ID: "r1", Date: now, ShowerDuration: 10, HeatingTime: 30, Satisfaction: 50}
+
+	assert.Empty(t, RecordIntegrityIssues(valid, 120, now))
+
+	record := valid
+	record.ShowerDuration = -8
+	record.HeatingTime = 400
+	record.Satisfaction = 0
+	issues := RecordIntegrityIssues(record, 120, now)
+	require.Len(t, issues, 3)
+	assert.Equal(t, IntegrityDurationNotPositive, issues[0].Problem)
+	assert.Equal(t, 8.0, *issues[0].Fix)
+	assert.Equal(t, IntegrityHeatingAboveMax, issues[1].Problem)
+	assert.Equal(t, 120.0, *issues[1].Fix)
+	assert.Equal(t, IntegritySatisfactionRange, issues[2].Problem)
+	assert.Equal(t, 1.0, *issues[2].Fix)
+
+	record = valid
+	record.HeatingTime = 0
+	record.Date = now.Add(48 * time.Hour)
+	issues = RecordIntegrityIssues(record, 120, now)
+	require.Len(t, issues, 2)
+	assert.Equal(t, IntegrityHeatingNotPositive, issues[0].Problem)
+	assert.Nil(t, issues[0].Fix, "a zero heating time can't be recovered")
+	assert.Equal(t, IntegrityFutureDate, issues[1].Problem)
+	assert.Nil(t, issues[1].Fix)
+}
+
+func TestRecordIntegrityIssues_AllowsClientsAheadOfTheServer(t *testing.T) {
+	now := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+	record := models.DailyRecord{Date: now.Add(10 * time.Hour), ShowerDuration: 10, HeatingTime: 30, Satisfaction: 50}
+
+	assert.Empty(t, RecordIntegrityIssues(record, 120, now))
+}
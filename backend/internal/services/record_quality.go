@@ -0,0 +1,89 @@
+package services
+
+import (
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// QualityShare is how many records have a property and their share of the whole history
+type QualityShare struct {
+	Records  int64   `json:"records"`
+	Fraction float64 `json:"fraction"` // 0-1; 0 for an empty history
+}
+
+// DataQualityReport summarises what the predictors learn from: how much of the history is
+// flagged, excluded, unconfirmed or guessed, and where it came from. A history that is mostly
+// pending, inferred or from one automated source trains a model that can't be trusted yet.
+type DataQualityReport struct {
+	ScannedAt           time.Time        `json:"scannedAt"`
+	Records             int64            `json:"records"`
+	Training            QualityShare     `json:"training"`            // not excluded from training
+	Flagged             QualityShare     `json:"flagged"`             // with impossible values, see ScanIntegrity
+	Excluded            QualityShare     `json:"excluded"`            // excluded by hand or as flagged, not waiting for review
+	PendingReview       QualityShare     `json:"pendingReview"`       // automated records the user hasn't confirmed
+	Inferred            QualityShare     `json:"inferred"`            // satisfaction guessed from the user's behaviour
+	MissingSatisfaction QualityShare     `json:"missingSatisfaction"` // stored without a rating, e.g. by legacy imports
+	Sources             map[string]int64 `json:"sources"`             // records by source
+}
+
+// DataQuality reports the share of the history in each state that decides whether the learner
+// can be trusted. Flagged records are found as ScanIntegrity finds them.
+func (s *RecordService) DataQuality(maxHeating float64, now time.Time) (*DataQualityReport, error) {
+	report := &DataQualityReport{ScannedAt: now, Sources: map[string]int64{}}
+	if err := s.db.Model(&models.DailyRecord{}).Count(&report.Records).Error; err != nil {
+		return nil, err
+	}
+
+	count := func(query string, args ...interface{}) (QualityShare, error) {
+		var share QualityShare
+		if err := s.db.Model(&models.DailyRecord{}).Where(query, args...).Count(&share.Records).Error; err != nil {
+			return share, err
+		}
+		share.Fraction = report.fraction(share.Records)
+		return share, nil
+	}
+	var err error
+	if report.Training, err = count("excluded_from_training = ?", false); err != nil {
+		return nil, err
+	}
+	if report.Excluded, err = count("excluded_from_training = ? AND (review_status IS NULL OR review_status <> ?)", true, models.ReviewStatusPending); err != nil {
+		return nil, err
+	}
+	if report.PendingReview, err = count("review_status = ?", models.ReviewStatusPending); err != nil {
+		return nil, err
+	}
+	if report.Inferred, err = count("review_status = ?", models.ReviewStatusInferred); err != nil {
+		return nil, err
+	}
+	if report.MissingSatisfaction, err = count("satisfaction = 0"); err != nil {
+		return nil, err
+	}
+
+	integrity, err := s.ScanIntegrity(maxHeating, now)
+	if err != nil {
+		return nil, err
+	}
+	report.Flagged = QualityShare{Records: int64(integrity.Records), Fraction: report.fraction(int64(integrity.Records))}
+
+	var sources []struct {
+		Source  string
+		Records int64
+	}
+	err = s.db.Model(&models.DailyRecord{}).Select("source, COUNT(*) AS records").Group("source").Scan(&sources).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		report.Sources[source.Source] = source.Records
+	}
+	return report, nil
+}
+
+// fraction is records' share of the history, rounded for display
+func (r *DataQualityReport) fraction(records int64) float64 {
+	if r.Records == 0 {
+		return 0
+	}
+	return roundTo(float64(records)/float64(r.Records), 4)
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// Custom reports are declarative: a client names metrics, groupings and filters from fixed
+// lists, and only expressions from those lists are put into the SQL. Values from filters are
+// always bound as parameters.
+
+// reportMetrics maps each metric a report can ask for to its SQL aggregate
+var reportMetrics = map[string]string{
+	"count":             "COUNT(*)",
+	"avgHeatingTime":    "AVG(heating_time)",
+	"minHeatingTime":    "MIN(heating_time)",
+	"maxHeatingTime":    "MAX(heating_time)",
+	"totalHeatingTime":  "SUM(heating_time)",
+	"avgShowerDuration": "AVG(shower_duration)",
+	"avgTemperature":    "AVG(average_temperature)",
+	"avgSatisfaction":   "AVG(satisfaction)",
+	"avgDeviation":      fmt.Sprintf("AVG(ABS(satisfaction - %d))", models.SatisfactionPerfect),
+}
+
+// reportGroups maps each grouping a report can use to the SQL of its group key. Context keys
+// match ContextBucket keys; the offset keeps the integer cast a floor for temperatures down to
+// -1000 °C, as in SatisfactionByBucket.
+var reportGroups = map[string]string{
+	"week":  "date(date, 'weekday 0', '-6 days')",
+	"month": "strftime('%Y-%m', date)",
+	"context": fmt.Sprintf("'d' || CAST(shower_duration / %[1]g AS INTEGER) || "+
+		"'|t' || (CAST((average_temperature + 1000) / %[2]g AS INTEGER) - CAST(1000 / %[2]g AS INTEGER))",
+		bucketDurationWidth, bucketTemperatureWidth),
+	"device": "device_id",
+}
+
+// Limits on a report's size
+const (
+	reportMaxMetrics = 8
+	reportMaxGroups  = 2
+	reportMaxRows    = 1000
+)
+
+var (
+	// ErrReportMetric is returned for a report without metrics, with too many or with an unknown one
+	ErrReportMetric = errors.New("unknown or too many report metrics")
+	// ErrReportGroup is returned for too many or unknown report groupings
+	ErrReportGroup = errors.New("unknown or too many report groupings")
+	// ErrReportFilter is returned for a report filter whose range is empty
+	ErrReportFilter = errors.New("report filter range is empty")
+)
+
+// ReportQuery is a custom report over one user's records
+type ReportQuery struct {
+	UserID  string        `json:"userId" binding:"required"`
+	Metrics []string      `json:"metrics"`           // see ReportMetrics
+	GroupBy []string      `json:"groupBy,omitempty"` // see ReportGroups; none for a single row over all matching records
+	Filters ReportFilters `json:"filters"`
+}
+
+// ReportFilters narrow the records a report covers; zero fields don't filter
+type ReportFilters struct {
+	From           *time.Time `json:"from,omitempty"` // inclusive
+	To             *time.Time `json:"to,omitempty"`   // exclusive
+	DeviceIDs      []string   `json:"deviceIds,omitempty"`
+	MemberIDs      []string   `json:"memberIds,omitempty"`
+	Sources        []string   `json:"sources,omitempty"`
+	MinDuration    *float64   `json:"minDuration,omitempty"` // minutes
+	MaxDuration    *float64   `json:"maxDuration,omitempty"`
+	MinTemperature *float64   `json:"minTemperature,omitempty"` // °C
+	MaxTemperature *float64   `json:"maxTemperature,omitempty"`
+	TrainingOnly   bool       `json:"trainingOnly,omitempty"` // leave out records excluded from training
+}
+
+// Report is the result of a ReportQuery: one row per group, ordered by the group keys
+type Report struct {
+	Metrics   []string    `json:"metrics"`
+	GroupBy   []string    `json:"groupBy"`
+	Rows      []ReportRow `json:"rows"`
+	Truncated bool        `json:"truncated,omitempty"` // more groups matched than a report returns
+}
+
+// ReportRow holds the metrics of one group
+type ReportRow struct {
+	Group  map[string]string   `json:"group,omitempty"` // group key per grouping; devices without an ID are ""
+	Values map[string]*float64 `json:"values"`          // null when no record had a value, e.g. the average of none
+}
+
+// ReportMetrics lists the metrics a report can ask for, sorted
+func ReportMetrics() []string {
+	return sortedKeys(reportMetrics)
+}
+
+// ReportGroups lists the groupings a report can use, sorted
+func ReportGroups() []string {
+	return sortedKeys(reportGroups)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Validate checks the query against the metrics and groupings reports support
+func (q ReportQuery) Validate() error {
+	if len(q.Metrics) == 0 || len(q.Metrics) > reportMaxMetrics {
+		return ErrReportMetric
+	}
+	for i, metric := range q.Metrics {
+		if _, ok := reportMetrics[metric]; !ok || slices.Contains(q.Metrics[:i], metric) {
+			return ErrReportMetric
+		}
+	}
+	if len(q.GroupBy) > reportMaxGroups {
+		return ErrReportGroup
+	}
+	for i, group := range q.GroupBy {
+		if _, ok := reportGroups[group]; !ok || slices.Contains(q.GroupBy[:i], group) {
+			return ErrReportGroup
+		}
+	}
+	f := q.Filters
+	if (f.From != nil && f.To != nil && !f.From.Before(*f.To)) ||
+		(f.MinDuration != nil && f.MaxDuration != nil && *f.MinDuration > *f.MaxDuration) ||
+		(f.MinTemperature != nil && f.MaxTemperature != nil && *f.MinTemperature > *f.MaxTemperature) {
+		return ErrReportFilter
+	}
+	return nil
+}
+
+// Report runs a validated custom report in the database
+func (s *RecordService) Report(q ReportQuery) (*Report, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Columns are aliased by position, so nothing the client sent is used as an identifier
+	columns := make([]string, 0, len(q.GroupBy)+len(q.Metrics))
+	groupAliases := make([]string, len(q.GroupBy))
+	for i, group := range q.GroupBy {
+		groupAliases[i] = fmt.Sprintf("g%d", i)
+		columns = append(columns, fmt.Sprintf("%s AS %s", reportGroups[group], groupAliases[i]))
+	}
+	for i, metric := range q.Metrics {
+		columns = append(columns, fmt.Sprintf("%s AS m%d", reportMetrics[metric], i))
+	}
+
+	db := RecordQuery{UserID: q.UserID, Sources: q.Filters.Sources, From: q.Filters.From, TrainingOnly: q.Filters.TrainingOnly}.
+		scope(s.db).Select(strings.Join(columns, ", "))
+	f := q.Filters
+	if f.To != nil {
+		db = db.Where("date < ?", *f.To)
+	}
+	if len(f.DeviceIDs) > 0 {
+		db = db.Where("device_id IN ?", f.DeviceIDs)
+	}
+	if len(f.MemberIDs) > 0 {
+		db = db.Where("member_id IN ?", f.MemberIDs)
+	}
+	if f.MinDuration != nil {
+		db = db.Where("shower_duration >= ?", *f.MinDuration)
+	}
+	if f.MaxDuration != nil {
+		db = db.Where("shower_duration <= ?", *f.MaxDuration)
+	}
+	if f.MinTemperature != nil {
+		db = db.Where("average_temperature >= ?", *f.MinTemperature)
+	}
+	if f.MaxTemperature != nil {
+		db = db.Where("average_temperature <= ?", *f.MaxTemperature)
+	}
+	if len(groupAliases) > 0 {
+		db = db.Group(strings.Join(groupAliases, ", ")).Order(strings.Join(groupAliases, ", "))
+	}
+
+	rows, err := db.Limit(reportMaxRows + 1).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &Report{Metrics: q.Metrics, GroupBy: q.GroupBy, Rows: []ReportRow{}}
+	if report.GroupBy == nil {
+		report.GroupBy = []string{}
+	}
+	for rows.Next() {
+		if len(report.Rows) == reportMaxRows {
+			report.Truncated = true
+			break
+		}
+		groups := make([]sql.NullString, len(q.GroupBy))
+		values := make([]sql.NullFloat64, len(q.Metrics))
+		dest := make([]any, 0, len(groups)+len(values))
+		for i := range groups {
+			dest = append(dest, &groups[i])
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := ReportRow{Values: make(map[string]*float64, len(values))}
+		if len(groups) > 0 {
+			row.Group = make(map[string]string, len(groups))
+			for i, group := range q.GroupBy {
+				row.Group[group] = groups[i].String
+			}
+		}
+		for i, metric := range q.Metrics {
+			if values[i].Valid {
+				value := roundTo(values[i].Float64, 3)
+				row.Values[metric] = &value
+			} else {
+				row.Values[metric] = nil
+			}
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	return report, rows.Err()
+}
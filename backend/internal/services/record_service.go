@@ -1,34 +1,565 @@
 package services
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"heat-logger/internal/models"
 	"heat-logger/pkg/database"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // RecordService handles business logic for daily records
 type RecordService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	health    *DBHealthMonitor
+	onCreated []func(record models.DailyRecord)
+	onTrained []func(record models.DailyRecord)
+	onUpdated []func(record models.DailyRecord)
+	onDeleted []func(record models.DailyRecord)
+	onePerDay bool
+	skips     SkipLookup
+	sanitized SanitationLookup
+	demand    DemandResponseLookup
+
+	contributors ContributorFilter
+
+	confidence   FeedbackConfidence
+	modelVersion func(userID string) string
+
+	tombstoneRetention time.Duration // 0 keeps tombstones forever
+}
+
+// SkipLookup reports whether the user skipped scheduled heating on a day
+type SkipLookup interface {
+	SkippedOn(userID string, day time.Time) (bool, error)
+}
+
+var (
+	// ErrDuplicateRecord is returned when a client-supplied record ID is already stored with
+	// the same content, i.e. the request is a retry. The stored record is copied into the argument.
+	ErrDuplicateRecord = errors.New("record already exists")
+	// ErrRecordIDConflict is returned when a client-supplied record ID is used by a different record
+	ErrRecordIDConflict = errors.New("record ID is already used by a different record")
+	// ErrRecordNotFound is returned when a record does not exist, or not for the user asking
+	ErrRecordNotFound = errors.New("record not found")
+	// ErrRevisionNotFound is returned when a record has no revision with the given ID
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrRecordNotPending is returned when reviewing a record that isn't waiting for review
+	ErrRecordNotPending = errors.New("record is not pending review")
+	// ErrSeveralRecordsOnDay is returned when a day to upsert already has more than one record
+	ErrSeveralRecordsOnDay = errors.New("more than one record exists on that day")
+)
+
+var (
+	// ErrInvalidSyncCursor is returned when a client sends a cursor this server didn't issue
+	ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+	// ErrSyncCursorExpired is returned for a cursor older than the tombstone retention window,
+	// since deletions after it may have been forgotten
+	ErrSyncCursorExpired = errors.New("sync cursor has expired")
+)
+
+// deleteAllActor is the actor of the tombstones DeleteAllRecords leaves
+const deleteAllActor = "delete_all"
+
+// SyncCursor marks a position in a user's change feed. Records are ordered by UpdatedAt and
+// then ID, so the position is exact even when several records share a timestamp. Without an
+// ID it is the start of UpdatedAt.
+type SyncCursor struct {
+	UpdatedAt time.Time
+	ID        string
+}
+
+// Encode returns the opaque form handed to clients
+func (c SyncCursor) Encode() string {
+	if c.ID == "" && c.UpdatedAt.IsZero() {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(c.UpdatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID))
+}
+
+// DecodeSyncCursor parses a cursor from Encode; an empty string starts from the beginning
+func DecodeSyncCursor(encoded string) (SyncCursor, error) {
+	if encoded == "" {
+		return SyncCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	stamp, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	updatedAt, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	return SyncCursor{UpdatedAt: updatedAt, ID: id}, nil
 }
 
 // NewRecordService creates a new record service instance
 func NewRecordService() *RecordService {
 	return &RecordService{
-		db: database.GetDB(),
+		db:         database.GetDB(),
+		confidence: DefaultFeedbackConfidence(),
 	}
 }
 
-// CreateRecord creates a new daily record
+// SetHealthMonitor enables buffering of new records while the database is read-only. Buffered
+// records run the OnRecordCreated and OnRecordTrained callbacks once they are written.
+func (s *RecordService) SetHealthMonitor(health *DBHealthMonitor) {
+	s.health = health
+	health.OnRecordFlushed(s.recordCreated)
+}
+
+// SetTombstoneRetention sets how long the tombstones of deleted records are kept; 0 keeps them
+// forever
+func (s *RecordService) SetTombstoneRetention(retention time.Duration) {
+	s.tombstoneRetention = retention
+}
+
+// SetOneRecordPerDay makes feedback for a day the user already has a record on (for the same
+// device) edit that record instead of adding another
+func (s *RecordService) SetOneRecordPerDay(enabled bool) {
+	s.onePerDay = enabled
+}
+
+// SetSkipLookup keeps records from days the user skipped scheduled heating out of training
+func (s *RecordService) SetSkipLookup(skips SkipLookup) {
+	s.skips = skips
+}
+
+// SetSanitationLookup keeps showers taken in water heated by a legionella cycle out of training
+func (s *RecordService) SetSanitationLookup(sanitized SanitationLookup) {
+	s.sanitized = sanitized
+}
+
+// SetDemandResponseLookup keeps showers taken during or just after a demand-response event out
+// of training
+func (s *RecordService) SetDemandResponseLookup(demand DemandResponseLookup) {
+	s.demand = demand
+}
+
+// SetContributorFilter leaves the records of users whose feedback is unreliable out of the
+// global records other users' predictions learn from
+func (s *RecordService) SetContributorFilter(contributors ContributorFilter) {
+	s.contributors = contributors
+}
+
+// SetFeedbackConfidence overrides how sure the satisfaction of records from each feedback
+// source is, on top of the defaults
+func (s *RecordService) SetFeedbackConfidence(confidence FeedbackConfidence) {
+	s.confidence = confidence.withDefaults()
+}
+
+// SetModelVersionLookup stamps new and edited records with the predictor version serving their
+// user, and its behaviour revision
+func (s *RecordService) SetModelVersionLookup(versionOf func(userID string) string) {
+	s.modelVersion = versionOf
+}
+
+// stampModel records which predictor version and revision served the heating time being rated
+func (s *RecordService) stampModel(record *models.DailyRecord) {
+	if s.modelVersion == nil {
+		return
+	}
+	record.ModelVersion = s.modelVersion(record.UserID)
+	record.ModelRevision = ModelRevision(record.ModelVersion)
+}
+
+// excludeUnrepresentative marks a record as excluded from training when its water wasn't heated
+// the usual way: on a day whose heating was skipped it was cold because nobody heated it, and
+// after a legionella cycle it was hot because of the cycle, not the heating time; during a
+// demand-response event the utility held heating back
+func (s *RecordService) excludeUnrepresentative(record *models.DailyRecord) {
+	if record.ExcludedFromTraining {
+		return
+	}
+	if s.skips != nil {
+		if skipped, err := s.skips.SkippedOn(record.UserID, record.Date); err == nil && skipped {
+			record.ExcludedFromTraining = true
+			return
+		}
+	}
+	if s.sanitized != nil {
+		if sanitized, err := s.sanitized.SanitizedAt(record.DeviceID, record.Date); err == nil && sanitized {
+			record.ExcludedFromTraining = true
+			return
+		}
+	}
+	if s.demand != nil {
+		if interrupted, err := s.demand.InterruptedAt(record.UserID, record.Date); err == nil && interrupted {
+			record.ExcludedFromTraining = true
+		}
+	}
+}
+
+// OneRecordPerDay reports whether feedback is upserted by day
+func (s *RecordService) OneRecordPerDay() bool {
+	return s.onePerDay
+}
+
+// OnRecordCreated registers a callback run after a record is saved, whether or not it is used
+// for training
+func (s *RecordService) OnRecordCreated(fn func(record models.DailyRecord)) {
+	s.onCreated = append(s.onCreated, fn)
+}
+
+// OnRecordTrained registers a callback run when a saved record starts being used for training:
+// when it is saved, or for records pending review once their satisfaction is inferred or the
+// user has accepted them. Records excluded from training never reach it.
+func (s *RecordService) OnRecordTrained(fn func(record models.DailyRecord)) {
+	s.onTrained = append(s.onTrained, fn)
+}
+
+// OnRecordUpdated registers a callback run after a record's values are edited, with the record
+// as stored now; it may have been excluded from training by the edit
+func (s *RecordService) OnRecordUpdated(fn func(record models.DailyRecord)) {
+	s.onUpdated = append(s.onUpdated, fn)
+}
+
+// OnRecordDeleted registers a callback run after a record is deleted, with its ID and UserID
+func (s *RecordService) OnRecordDeleted(fn func(record models.DailyRecord)) {
+	s.onDeleted = append(s.onDeleted, fn)
+}
+
+// recordsChanged runs the callbacks with each record
+func recordsChanged(callbacks []func(record models.DailyRecord), records ...models.DailyRecord) {
+	for _, record := range records {
+		for _, fn := range callbacks {
+			fn(record)
+		}
+	}
+}
+
+// CreateRecord creates a new daily record. While the database is read-only the record is
+// buffered and ErrWriteBuffered (or ErrDatabaseReadOnly when the buffer is full) is returned.
+// Records may carry a client-generated ID; re-sending one returns ErrDuplicateRecord, and
+// reusing it for different content returns ErrRecordIDConflict.
 func (s *RecordService) CreateRecord(record *models.DailyRecord) error {
 	if record.Date.IsZero() {
 		record.Date = time.Now()
 	}
+	s.excludeUnrepresentative(record)
+	record.FeedbackConfidence = s.confidence.For(*record)
+	s.stampModel(record)
+
+	if s.health != nil && s.health.ReadOnly() {
+		return s.health.Buffer(*record)
+	}
+	clientID := record.ID != ""
+	err := s.db.Create(record).Error
+	if s.health != nil && s.health.Observe(err) {
+		return s.health.Buffer(*record)
+	}
+	if err != nil {
+		if clientID {
+			if existing, lookupErr := s.GetRecordByID(record.ID); lookupErr == nil {
+				return resolveExistingRecord(existing, record)
+			}
+		}
+		return err
+	}
+
+	s.recordCreated(*record)
+	return nil
+}
+
+// recordCreated runs the OnRecordCreated callbacks, then the OnRecordTrained ones unless the
+// record isn't used for training
+func (s *RecordService) recordCreated(record models.DailyRecord) {
+	recordsChanged(s.onCreated, record)
+	s.recordTrained(record)
+}
+
+// recordTrained runs the OnRecordTrained callbacks unless the record isn't used for training
+func (s *RecordService) recordTrained(record models.DailyRecord) {
+	if trainedOn(record) {
+		recordsChanged(s.onTrained, record)
+	}
+}
+
+// trainedOn reports whether the predictors learn from a stored record: not while it is pending
+// review, nor once excluded from training. Inferred records are learned from, at the weight of
+// their feedback confidence, before the user has checked them.
+func trainedOn(record models.DailyRecord) bool {
+	return record.ReviewStatus != models.ReviewStatusPending && !record.ExcludedFromTraining
+}
+
+// resolveExistingRecord decides how a create that collided with a stored ID ends: a retry of the
+// same record is reported as a duplicate, anything else is a conflict and the stored copy wins
+func resolveExistingRecord(stored, uploaded *models.DailyRecord) error {
+	same := stored.UserID == uploaded.UserID &&
+		stored.Date.Equal(uploaded.Date) &&
+		stored.ShowerDuration == uploaded.ShowerDuration &&
+		stored.AverageTemperature == uploaded.AverageTemperature &&
+		sameOptionalFloat(stored.InletTemperature, uploaded.InletTemperature) &&
+		stored.HeatingTime == uploaded.HeatingTime &&
+		stored.Satisfaction == uploaded.Satisfaction &&
+		stored.MemberID == uploaded.MemberID &&
+		stored.DeviceID == uploaded.DeviceID &&
+		stored.PresetID == uploaded.PresetID
+	if !same {
+		return ErrRecordIDConflict
+	}
+	*uploaded = *stored
+	return ErrDuplicateRecord
+}
+
+func sameOptionalFloat(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// UpsertDayRecord stores the user's record for the device on the day containing day: a new
+// record, or the day's existing record updated with the new measurements, keeping its ID and,
+// unless record.Date is set, its time. It reports whether a record was created; a day with
+// several records returns ErrSeveralRecordsOnDay.
+func (s *RecordService) UpsertDayRecord(record *models.DailyRecord, day time.Time) (bool, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	var existing []models.DailyRecord
+	err := s.db.Where("user_id = ? AND device_id = ? AND date >= ? AND date < ?", record.UserID, record.DeviceID, start, start.AddDate(0, 0, 1)).
+		Limit(2).Find(&existing).Error
+	if err != nil {
+		return false, err
+	}
+	switch len(existing) {
+	case 0:
+		if record.Date.IsZero() {
+			record.Date = day
+		}
+		return true, s.CreateRecord(record)
+	case 1:
+	default:
+		return false, ErrSeveralRecordsOnDay
+	}
+
+	stored := existing[0]
+	record.ID = stored.ID
+	record.CreatedAt = stored.CreatedAt
+	record.ExcludedFromTraining = stored.ExcludedFromTraining
+	record.ReviewStatus = stored.ReviewStatus
+	if record.Date.IsZero() {
+		record.Date = stored.Date
+	}
+	s.excludeUnrepresentative(record)
+	record.FeedbackConfidence = s.confidence.For(*record)
+	s.stampModel(record)
+	return false, s.editRecord(stored, record, recordEditor(record))
+}
+
+// ListRevisions returns the values one of the user's records had before each edit, newest first
+func (s *RecordService) ListRevisions(userID, recordID string) ([]models.RecordRevision, error) {
+	if _, err := s.userRecord(userID, recordID); err != nil {
+		return nil, err
+	}
+	var revisions []models.RecordRevision
+	err := s.db.Where("record_id = ?", recordID).Order("created_at DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// RevertRecord restores one of the user's records to a revision. The values it replaces become
+// a revision themselves, so a revert can be undone too.
+func (s *RecordService) RevertRecord(userID, recordID, revisionID, editor string) (*models.DailyRecord, error) {
+	stored, err := s.userRecord(userID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	var revision models.RecordRevision
+	err = s.db.Where("id = ? AND record_id = ?", revisionID, recordID).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := *stored
+	revision.ApplyTo(&reverted)
+	if err := s.editRecord(*stored, &reverted, editor); err != nil {
+		return nil, err
+	}
+	return &reverted, nil
+}
+
+// GetPendingRecords returns the user's records waiting for review, including those with an
+// inferred satisfaction, oldest first
+func (s *RecordService) GetPendingRecords(userID string) ([]models.DailyRecord, error) {
+	var records []models.DailyRecord
+	err := s.db.Where("user_id = ? AND review_status IN ?", userID, []string{models.ReviewStatusPending, models.ReviewStatusInferred}).Order("date ASC").Find(&records).Error
+	return records, err
+}
+
+// ReviewRecord accepts a record that was waiting for review, storing reviewed as its values.
+// Records the user changed are marked corrected, keeping what the automation recorded as a
+// revision; either way the record is used for training from now on, unless the user skipped
+// heating that day.
+func (s *RecordService) ReviewRecord(userID string, reviewed *models.DailyRecord, editor string) error {
+	stored, err := s.userRecord(userID, reviewed.ID)
+	if err != nil {
+		return err
+	}
+	if !models.AwaitingReview(stored.ReviewStatus) {
+		return ErrRecordNotPending
+	}
+
+	reviewed.UserID = stored.UserID
+	reviewed.CreatedAt = stored.CreatedAt
+	reviewed.ExcludedFromTraining = false
+	s.excludeUnrepresentative(reviewed)
+	reviewed.ReviewStatus = models.ReviewStatusConfirmed
+	if reviewed.ShowerDuration != stored.ShowerDuration ||
+		reviewed.AverageTemperature != stored.AverageTemperature ||
+		!sameOptionalFloat(reviewed.InletTemperature, stored.InletTemperature) ||
+		reviewed.HeatingTime != stored.HeatingTime ||
+		reviewed.Satisfaction != stored.Satisfaction {
+		reviewed.ReviewStatus = models.ReviewStatusCorrected
+	}
+	reviewed.FeedbackConfidence = s.confidence.For(*reviewed)
+	if err := s.editRecord(*stored, reviewed, editor); err != nil {
+		return err
+	}
+	if !trainedOn(*stored) {
+		s.recordTrained(*reviewed)
+	}
+	return nil
+}
 
-	return s.db.Create(record).Error
+// userRecord loads a record, reporting ErrRecordNotFound when it belongs to someone else
+func (s *RecordService) userRecord(userID, recordID string) (*models.DailyRecord, error) {
+	record, err := s.GetRecordByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+	if record.UserID != userID {
+		return nil, ErrRecordNotFound
+	}
+	return record, nil
+}
+
+// editRecord replaces a stored record's values, keeping the old ones as a revision. Edits can't
+// be buffered like new records, so they wait for storage to recover.
+func (s *RecordService) editRecord(stored models.DailyRecord, updated *models.DailyRecord, editor string) error {
+	if s.health != nil && s.health.ReadOnly() {
+		return ErrDatabaseReadOnly
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		revision := models.NewRecordRevision(stored, editor)
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		return tx.Save(updated).Error
+	})
+	if s.health != nil && s.health.Observe(err) {
+		return ErrDatabaseReadOnly
+	}
+	if err != nil {
+		return err
+	}
+	recordsChanged(s.onUpdated, *updated)
+	return nil
+}
+
+// recordEditor names who submitted a record: the household member when it has one
+func recordEditor(record *models.DailyRecord) string {
+	if record.MemberID != "" {
+		return record.MemberID
+	}
+	return record.UserID
+}
+
+// CreateRecords stores a batch of records in one transaction and returns what happened to
+// each, in order: nil when it was created, or the error CreateRecord would have returned.
+// Records whose client ID is already stored, or repeated earlier in the batch, are resolved
+// as duplicates or conflicts and left out; the rest are stored together or not at all.
+func (s *RecordService) CreateRecords(records []*models.DailyRecord) []error {
+	results := make([]error, len(records))
+	seen := make(map[string]*models.DailyRecord)
+	var fresh []int
+	for i, record := range records {
+		if record.Date.IsZero() {
+			record.Date = time.Now()
+		}
+		s.excludeUnrepresentative(record)
+		record.FeedbackConfidence = s.confidence.For(*record)
+		s.stampModel(record)
+		if record.ID != "" {
+			if earlier, ok := seen[record.ID]; ok {
+				results[i] = resolveExistingRecord(earlier, record)
+				continue
+			}
+			if existing, err := s.GetRecordByID(record.ID); err == nil {
+				results[i] = resolveExistingRecord(existing, record)
+				continue
+			}
+			seen[record.ID] = record
+		}
+		fresh = append(fresh, i)
+	}
+
+	bufferFresh := func() []error {
+		for _, i := range fresh {
+			results[i] = s.health.Buffer(*records[i])
+		}
+		return results
+	}
+	if s.health != nil && s.health.ReadOnly() {
+		return bufferFresh()
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, i := range fresh {
+			if err := tx.Create(records[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if s.health != nil && s.health.Observe(err) {
+		return bufferFresh()
+	}
+	if err != nil {
+		for _, i := range fresh {
+			results[i] = err
+		}
+		return results
+	}
+
+	for _, i := range fresh {
+		s.recordCreated(*records[i])
+	}
+	return results
+}
+
+// SeedRecords stores records in an empty database, such as demo showers on a fresh install.
+// A database that already holds records is left alone; it returns how many were stored.
+func (s *RecordService) SeedRecords(records []models.DailyRecord) (int, error) {
+	count, err := s.Count(RecordQuery{})
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	batch := make([]*models.DailyRecord, len(records))
+	for i := range records {
+		batch[i] = &records[i]
+	}
+	for i, err := range s.CreateRecords(batch) {
+		if err != nil {
+			return 0, fmt.Errorf("record %s: %w", batch[i].ID, err)
+		}
+	}
+	return len(batch), nil
 }
 
 // GetAllRecords retrieves all daily records, ordered by last update descending
@@ -39,57 +570,256 @@ func (s *RecordService) GetAllRecords() ([]models.DailyRecord, error) {
 	return records, err
 }
 
+// GetRecordsBySources retrieves records that entered through any of the given sources,
+// ordered by last update descending. No sources means all records.
+func (s *RecordService) GetRecordsBySources(sources []string) ([]models.DailyRecord, error) {
+	if len(sources) == 0 {
+		return s.GetAllRecords()
+	}
+	var records []models.DailyRecord
+	err := s.db.Where("source IN ?", sources).Order("updated_at DESC").Find(&records).Error
+	return records, err
+}
+
+// HistoryChanges is a page of a user's change feed
+type HistoryChanges struct {
+	Records []models.DailyRecord     // created or updated after the cursor, content as it is now
+	Deleted []models.RecordTombstone // deleted after the cursor
+	Next    SyncCursor               // where the next page starts
+	HasMore bool
+}
+
+// GetHistoryChanges returns up to limit of the user's changes after the cursor, oldest first.
+// Records are ordered by UpdatedAt and deletions by DeletedAt on the same timeline, so one
+// cursor continues both. A cursor with a time and no ID starts at that time.
+func (s *RecordService) GetHistoryChanges(userID string, after SyncCursor, limit int) (*HistoryChanges, error) {
+	if err := s.CheckSyncCursor(after); err != nil {
+		return nil, err
+	}
+	// Stored timestamps use the local zone, so compare in it too
+	at := after.UpdatedAt.Local()
+	started := !after.UpdatedAt.IsZero() || after.ID != ""
+
+	var records []models.DailyRecord
+	query := s.db.Where("user_id = ?", userID)
+	if started {
+		query = query.Where("updated_at > ? OR (updated_at = ? AND id > ?)", at, at, after.ID)
+	}
+	if err := query.Order("updated_at ASC, id ASC").Limit(limit + 1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	var tombstones []models.RecordTombstone
+	query = s.db.Where("user_id = ?", userID)
+	if started {
+		query = query.Where("deleted_at > ? OR (deleted_at = ? AND record_id > ?)", at, at, after.ID)
+	}
+	if err := query.Order("deleted_at ASC, record_id ASC").Limit(limit + 1).Find(&tombstones).Error; err != nil {
+		return nil, err
+	}
+
+	// Merge the two feeds, keeping the first limit changes
+	changes := &HistoryChanges{Records: []models.DailyRecord{}, Deleted: []models.RecordTombstone{}, Next: after}
+	r, d := 0, 0
+	for r+d < limit && (r < len(records) || d < len(tombstones)) {
+		if d == len(tombstones) || (r < len(records) && syncBefore(records[r].UpdatedAt, records[r].ID, tombstones[d].DeletedAt, tombstones[d].RecordID)) {
+			changes.Records = append(changes.Records, records[r])
+			changes.Next = SyncCursor{UpdatedAt: records[r].UpdatedAt, ID: records[r].ID}
+			r++
+		} else {
+			changes.Deleted = append(changes.Deleted, tombstones[d])
+			changes.Next = SyncCursor{UpdatedAt: tombstones[d].DeletedAt, ID: tombstones[d].RecordID}
+			d++
+		}
+	}
+	changes.HasMore = r < len(records) || d < len(tombstones)
+	return changes, nil
+}
+
+// CheckSyncCursor returns ErrSyncCursorExpired when deletions after the cursor may have been
+// purged, so a client continuing from it would keep records deleted since
+func (s *RecordService) CheckSyncCursor(after SyncCursor) error {
+	if s.tombstoneRetention > 0 && !after.UpdatedAt.IsZero() && after.UpdatedAt.Before(time.Now().Add(-s.tombstoneRetention)) {
+		return ErrSyncCursorExpired
+	}
+	return nil
+}
+
+// PurgeTombstones removes the tombstones past the retention window
+func (s *RecordService) PurgeTombstones() error {
+	return s.purgeTombstones(s.db)
+}
+
+func (s *RecordService) purgeTombstones(db *gorm.DB) error {
+	if s.tombstoneRetention <= 0 {
+		return nil
+	}
+	return db.Where("deleted_at < ?", time.Now().Add(-s.tombstoneRetention)).Delete(&models.RecordTombstone{}).Error
+}
+
+// syncBefore reports whether a change comes before another in the change feed
+func syncBefore(at time.Time, id string, otherAt time.Time, otherID string) bool {
+	if !at.Equal(otherAt) {
+		return at.Before(otherAt)
+	}
+	return id < otherID
+}
+
+// GetRecordsByUser retrieves all records for a user, ordered by last update descending
+func (s *RecordService) GetRecordsByUser(userID string) ([]models.DailyRecord, error) {
+	var records []models.DailyRecord
+	err := s.db.Where("user_id = ?", userID).Order("updated_at DESC").Find(&records).Error
+	return records, err
+}
+
 // GetRecordByID retrieves a record by its ID
 func (s *RecordService) GetRecordByID(id string) (*models.DailyRecord, error) {
 	var record models.DailyRecord
 	err := s.db.Where("id = ?", id).First(&record).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("record not found")
+			return nil, ErrRecordNotFound
 		}
 		return nil, err
 	}
 	return &record, nil
 }
 
-// DeleteRecord deletes a record by its ID
-func (s *RecordService) DeleteRecord(id string) error {
-	result := s.db.Where("id = ?", id).Delete(&models.DailyRecord{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("record not found")
+// DeleteRecord deletes a record by its ID, leaving a tombstone for the change feed. An empty
+// actor stands for the record's owner.
+func (s *RecordService) DeleteRecord(id, actor string) error {
+	var record models.DailyRecord
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "user_id").Where("id = ?", id).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.DailyRecord{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		if actor == "" {
+			actor = record.UserID
+		}
+		// Old tombstones go opportunistically so the table doesn't grow unbounded
+		if err := s.purgeTombstones(tx); err != nil {
+			return err
+		}
+		// A record stored again under a deleted ID may be deleted again
+		tombstone := models.RecordTombstone{RecordID: id, UserID: record.UserID, DeletedAt: time.Now(), Actor: actor}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&tombstone).Error; err != nil {
+			return err
+		}
+		return tx.Where("record_id = ?", id).Delete(&models.RecordRevision{}).Error
+	})
+	if err != nil {
+		return err
 	}
+	recordsChanged(s.onDeleted, record)
 	return nil
 }
 
-// DeleteAllRecords deletes all records
+// DeleteAllRecords deletes all records, leaving a tombstone for each
 func (s *RecordService) DeleteAllRecords() error {
-	return s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.DailyRecord{}).Error
+	var deleted []models.DailyRecord
+	err := s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Transaction(func(tx *gorm.DB) error {
+		if err := s.purgeTombstones(tx); err != nil {
+			return err
+		}
+		if err := tx.Select("id", "user_id").Find(&deleted).Error; err != nil {
+			return err
+		}
+		err := tx.Exec("INSERT OR REPLACE INTO record_tombstones (record_id, user_id, deleted_at, actor) SELECT id, user_id, ?, ? FROM daily_records",
+			time.Now(), deleteAllActor).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.RecordRevision{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.DailyRecord{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	recordsChanged(s.onDeleted, deleted...)
+	return nil
+}
+
+// PreviewDeleteAllRecords reports what DeleteAllRecords would delete, sampling the latest records
+func (s *RecordService) PreviewDeleteAllRecords() (*DryRunResult, error) {
+	var records, revisions int64
+	if err := s.db.Model(&models.DailyRecord{}).Count(&records).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.RecordRevision{}).Count(&revisions).Error; err != nil {
+		return nil, err
+	}
+	preview := &DryRunResult{
+		Counts:    map[string]int64{"records": records, "revisions": revisions},
+		SampleIDs: []string{},
+	}
+	err := s.db.Model(&models.DailyRecord{}).Order("date DESC").Limit(dryRunSampleSize).Pluck("id", &preview.SampleIDs).Error
+	return preview, err
+}
+
+// predictionColumns are the record fields the predictors read. Records loaded for prediction
+// leave every other field, such as the ID and timestamps, at its zero value, which saves the
+// allocations for them on every request.
+var predictionColumns = []string{
+	"user_id", "member_id", "device_id", "date", "shower_duration", "average_temperature",
+	"inlet_temperature", "heating_time", "satisfaction", "source", "feedback_confidence",
+}
+
+// HasFeedbackSince reports whether the user stored or changed a record after the given time
+func (s *RecordService) HasFeedbackSince(userID string, since time.Time) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.DailyRecord{}).Where("user_id = ? AND updated_at > ?", userID, since).Count(&count).Error
+	return count > 0, err
 }
 
 // GetRecordsForPrediction retrieves recent records for ML prediction
 func (s *RecordService) GetRecordsForPrediction(limit int) ([]models.DailyRecord, error) {
 	var records []models.DailyRecord
-	err := s.db.Order("updated_at DESC").Limit(limit).Find(&records).Error
+	err := s.db.Select(predictionColumns).Where("excluded_from_training = ?", false).Order("updated_at DESC").Limit(limit).Find(&records).Error
 	return records, err
 }
 
 // GetRecordsForPredictionByUser retrieves recent records for a specific user for ML prediction
 func (s *RecordService) GetRecordsForPredictionByUser(userID string, limit int) ([]models.DailyRecord, error) {
 	var records []models.DailyRecord
-	err := s.db.Where("user_id = ?", userID).Order("date DESC").Limit(limit).Find(&records).Error
+	err := s.db.Select(predictionColumns).Where("user_id = ? AND excluded_from_training = ?", userID, false).Order("date DESC").Limit(limit).Find(&records).Error
+	return records, err
+}
+
+// candidateRecords retrieves the latest training records of a user, or of everyone when userID
+// is empty, with their IDs, for the candidate index
+func (s *RecordService) candidateRecords(userID string, limit int) ([]models.DailyRecord, error) {
+	var records []models.DailyRecord
+	query := s.db.Select(append([]string{"id"}, predictionColumns...)).Where("excluded_from_training = ?", false).Order("date DESC").Limit(limit)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Find(&records).Error
 	return records, err
 }
 
 // GetGlobalRecordsForPrediction retrieves recent global records (excluding specific user) for ML prediction
 func (s *RecordService) GetGlobalRecordsForPrediction(excludeUserID string, limit int) ([]models.DailyRecord, error) {
 	var records []models.DailyRecord
-	query := s.db.Order("date DESC").Limit(limit)
+	query := s.db.Select(predictionColumns).Where("excluded_from_training = ?", false).Order("date DESC").Limit(limit)
 	if excludeUserID != "" {
 		query = query.Where("user_id != ?", excludeUserID)
 	}
+	if s.contributors != nil {
+		if excluded := s.contributors.Excluded(); len(excluded) > 0 {
+			query = query.Where("user_id NOT IN ?", excluded)
+		}
+	}
 	err := query.Find(&records).Error
 	return records, err
 }
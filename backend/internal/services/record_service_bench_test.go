@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm/logger"
+)
+
+// BenchmarkRecordsForPrediction compares loading the 1600 records a v2 prediction reads as
+// full rows with the projected load the predictors use and with the record set cache. Run
+// with -bench RecordsForPrediction.
+func BenchmarkRecordsForPrediction(b *testing.B) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(b.TempDir(), "bench.db")}}
+	if err := database.InitDatabase(cfg); err != nil {
+		b.Fatal(err)
+	}
+	db := database.GetDB()
+
+	records := make([]models.DailyRecord, 1600)
+	start := time.Now().AddDate(0, 0, -len(records))
+	for i := range records {
+		records[i] = models.DailyRecord{
+			UserID:             fmt.Sprintf("user%d", i%4),
+			Date:               start.AddDate(0, 0, i),
+			ShowerDuration:     float64(5 + i%20),
+			AverageTemperature: float64(i % 30),
+			HeatingTime:        float64(10 + i%40),
+			Satisfaction:       float64(1 + i%100),
+		}
+	}
+	if err := db.CreateInBatches(records, 200).Error; err != nil {
+		b.Fatal(err)
+	}
+	service := NewRecordService()
+
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var loaded []models.DailyRecord
+			if err := db.Where("excluded_from_training = ?", false).Order("date DESC").Limit(len(records)).Find(&loaded).Error; err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("projected", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := service.GetGlobalRecordsForPrediction("", len(records)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		cache := NewRecordSetCache(service, 64)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := cache.GetGlobalRecordsForPrediction("", len(records)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
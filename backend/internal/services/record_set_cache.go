@@ -0,0 +1,231 @@
+package services
+
+import (
+	"container/list"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+	"unsafe"
+
+	"heat-logger/internal/models"
+)
+
+const (
+	// warmupUserRecords and warmupGlobalRecords are the most records either predictor reads
+	// for one request; smaller sets are served from the start of these
+	warmupUserRecords   = 400
+	warmupGlobalRecords = 1200
+	// warmupActivityWindow is how far back a user's records count towards how active they are
+	warmupActivityWindow = 30 * 24 * time.Hour
+)
+
+// recordsVersion identifies the state of the records table. Creating, editing, excluding or
+// deleting records changes it.
+type recordsVersion struct {
+	count  int64
+	latest string
+}
+
+// recordSet is one cached query result
+type recordSet struct {
+	key     string
+	records []models.DailyRecord
+	limit   int // the limit the set was loaded with
+	size    int64
+}
+
+// RecordSetCache keeps the record sets the predictors load for each user in memory, within a
+// memory budget, dropping the least recently used ones first. Each read checks that no record
+// changed since the sets were loaded, which costs one small query instead of loading and
+// decoding up to 1600 records. WarmUp loads the most active users' sets at startup, and their
+// candidate indices when the cache has one, so their first prediction after a restart is as
+// fast as the later ones.
+type RecordSetCache struct {
+	records *RecordService
+	budget  *memoryBudget
+	index   *CandidateIndex
+
+	mu      sync.Mutex
+	version recordsVersion
+	sets    map[string]*list.Element
+	order   *list.List // most recently used first
+	size    int64      // bytes the sets take of the budget
+}
+
+// NewRecordSetCache creates a record set cache over the record service, taking at most memoryMB
+func NewRecordSetCache(records *RecordService, memoryMB float64) *RecordSetCache {
+	return &RecordSetCache{
+		records: records,
+		budget:  newMemoryBudget(memoryMB),
+		sets:    make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// SetCandidateIndex warms the index up along with the record sets, within the cache's memory
+// budget, which the index then shares. Set it before either is used.
+func (c *RecordSetCache) SetCandidateIndex(index *CandidateIndex) {
+	c.index = index
+	index.budget = c.budget
+}
+
+// GetRecordsForPredictionByUser returns the user's latest records, cached
+func (c *RecordSetCache) GetRecordsForPredictionByUser(userID string, limit int) ([]models.DailyRecord, error) {
+	return c.get("user\x00"+userID, limit, func(limit int) ([]models.DailyRecord, error) {
+		return c.records.GetRecordsForPredictionByUser(userID, limit)
+	})
+}
+
+// GetGlobalRecordsForPrediction returns the latest records of everyone but the user, cached
+func (c *RecordSetCache) GetGlobalRecordsForPrediction(excludeUserID string, limit int) ([]models.DailyRecord, error) {
+	return c.get("global\x00"+excludeUserID, limit, func(limit int) ([]models.DailyRecord, error) {
+		return c.records.GetGlobalRecordsForPrediction(excludeUserID, limit)
+	})
+}
+
+// GetRecordsForPrediction isn't keyed by user and is read straight from the database
+func (c *RecordSetCache) GetRecordsForPrediction(limit int) ([]models.DailyRecord, error) {
+	return c.records.GetRecordsForPrediction(limit)
+}
+
+// get serves a set from the cache when it holds at least limit records of it, loading it
+// otherwise. Sets are ordered newest first, so a smaller limit is served from their start.
+func (c *RecordSetCache) get(key string, limit int, load func(limit int) ([]models.DailyRecord, error)) ([]models.DailyRecord, error) {
+	version, err := c.records.recordsVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if version != c.version {
+		c.clear()
+		c.version = version
+	}
+	if element, ok := c.sets[key]; ok {
+		set := element.Value.(*recordSet)
+		if set.limit >= limit || len(set.records) < set.limit {
+			c.order.MoveToFront(element)
+			records := set.records[:min(limit, len(set.records))]
+			c.mu.Unlock()
+			// Copy: the predictors may reorder what they are given
+			return append([]models.DailyRecord(nil), records...), nil
+		}
+	}
+	c.mu.Unlock()
+
+	records, err := load(limit)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if version == c.version {
+		c.store(&recordSet{key: key, records: records, limit: limit, size: recordSetSize(records)})
+	}
+	c.mu.Unlock()
+	return append([]models.DailyRecord(nil), records...), nil
+}
+
+// store adds a set, replacing an earlier one under its key and evicting the least recently
+// used sets until it fits. A set that doesn't fit even without them isn't kept.
+func (c *RecordSetCache) store(set *recordSet) {
+	if element, ok := c.sets[set.key]; ok {
+		c.remove(element)
+	}
+	for !c.budget.reserve(set.size) {
+		back := c.order.Back()
+		if back == nil {
+			return
+		}
+		c.remove(back)
+	}
+	c.sets[set.key] = c.order.PushFront(set)
+	c.size += set.size
+}
+
+// remove drops one cached set
+func (c *RecordSetCache) remove(element *list.Element) {
+	set := c.order.Remove(element).(*recordSet)
+	delete(c.sets, set.key)
+	c.size -= set.size
+	c.budget.release(set.size)
+}
+
+// clear drops every cached set
+func (c *RecordSetCache) clear() {
+	c.sets = make(map[string]*list.Element)
+	c.order.Init()
+	c.budget.release(c.size)
+	c.size = 0
+}
+
+// WarmUp loads the record sets, and candidate index, of the users with the most records over
+// the last 30 days, most active first, until users are loaded or the budget is used up
+func (c *RecordSetCache) WarmUp(users int) {
+	start := time.Now()
+	active, err := c.records.MostActiveUsers(start.Add(-warmupActivityWindow), users)
+	if err != nil {
+		log.Printf("Prediction warm-up failed: %v", err)
+		return
+	}
+	warmed := 0
+	for _, userID := range active {
+		if c.budget.full() {
+			break
+		}
+		if _, err := c.GetRecordsForPredictionByUser(userID, warmupUserRecords); err != nil {
+			log.Printf("Prediction warm-up failed for user %s: %v", userID, err)
+			return
+		}
+		if _, err := c.GetGlobalRecordsForPrediction(userID, warmupGlobalRecords); err != nil {
+			log.Printf("Prediction warm-up failed for user %s: %v", userID, err)
+			return
+		}
+		if c.index != nil {
+			if err := c.index.WarmUp(userID); err != nil {
+				log.Printf("Prediction warm-up failed for user %s: %v", userID, err)
+				return
+			}
+		}
+		warmed++
+	}
+	log.Printf("Prediction warm-up loaded the records of %d users in %s", warmed, time.Since(start).Round(time.Millisecond))
+}
+
+// recordSetSize estimates the memory a set of records takes
+func recordSetSize(records []models.DailyRecord) int64 {
+	size := int64(unsafe.Sizeof(recordSet{}))
+	for _, record := range records {
+		size += recordSize(record)
+	}
+	return size
+}
+
+// recordSize estimates the memory a record loaded for prediction takes
+func recordSize(record models.DailyRecord) int64 {
+	size := int64(unsafe.Sizeof(record)) + int64(len(record.UserID)+len(record.MemberID)+len(record.DeviceID)+len(record.Source))
+	if record.InletTemperature != nil {
+		size += int64(unsafe.Sizeof(*record.InletTemperature))
+	}
+	return size
+}
+
+// recordsVersion returns the current version of the records table
+func (s *RecordService) recordsVersion() (recordsVersion, error) {
+	var version recordsVersion
+	var latest sql.NullString
+	err := s.db.Model(&models.DailyRecord{}).Select("COUNT(*), MAX(updated_at)").Row().Scan(&version.count, &latest)
+	version.latest = latest.String
+	return version, err
+}
+
+// MostActiveUsers returns up to limit users with the most records dated since the given time,
+// most records first
+func (s *RecordService) MostActiveUsers(since time.Time, limit int) ([]string, error) {
+	var users []string
+	err := s.db.Model(&models.DailyRecord{}).
+		Where("date >= ? AND excluded_from_training = ?", since, false).
+		Group("user_id").Order("COUNT(*) DESC").Limit(limit).
+		Pluck("user_id", &users).Error
+	return users, err
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestRecordSetCache(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "cache.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	for i := 0; i < 30; i++ {
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: fmt.Sprintf("user%d", i%3), Date: time.Now().AddDate(0, 0, -i),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: float64(10 + i), Satisfaction: 50,
+		}))
+	}
+
+	cache := NewRecordSetCache(records, 1)
+	cache.WarmUp(2)
+	assert.Len(t, cache.sets, 4, "the user and global sets of the two most active users")
+
+	user, err := cache.GetRecordsForPredictionByUser("user0", 5)
+	require.NoError(t, err)
+	assert.Len(t, user, 5)
+	fromDB, err := records.GetRecordsForPredictionByUser("user0", 5)
+	require.NoError(t, err)
+	assert.Equal(t, fromDB, user)
+
+	// A new record invalidates every set
+	require.NoError(t, records.CreateRecord(&models.DailyRecord{
+		UserID: "user0", Date: time.Now(), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 99, Satisfaction: 50,
+	}))
+	user, err = cache.GetRecordsForPredictionByUser("user0", 5)
+	require.NoError(t, err)
+	assert.Equal(t, 99.0, user[0].HeatingTime)
+	assert.Len(t, cache.sets, 1)
+
+	// Sets that don't fit the budget aren't kept
+	tiny := NewRecordSetCache(records, 0.001)
+	tiny.WarmUp(3)
+	assert.LessOrEqual(t, tiny.budget.used.Load(), tiny.budget.limit)
+}
+
+func TestRecordSetCache_WarmsCandidateIndex(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "cache.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	for i := 0; i < 30; i++ {
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: fmt.Sprintf("user%d", i%3), Date: time.Now().AddDate(0, 0, -i),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: float64(10 + i), Satisfaction: 50,
+		}))
+	}
+
+	cache := NewRecordSetCache(records, 1)
+	index := NewCandidateIndex(records, 1)
+	cache.SetCandidateIndex(index)
+	cache.WarmUp(2)
+	assert.Len(t, index.users, 2, "the indices of the two most active users")
+	assert.NotNil(t, index.global)
+	var indexSize int64
+	for _, element := range index.users {
+		indexSize += element.Value.(*candidateSet).size
+	}
+	assert.Equal(t, cache.size+indexSize+index.global.size, cache.budget.used.Load(), "the index counts against the cache's budget")
+
+	// The first prediction after warm-up reads the index as warmed
+	builds := index.builds
+	predictor := NewPredictionServiceV2(cache, nil)
+	predictor.SetCandidateIndex(index)
+	_, err := predictor.Predict(context.Background(), PredictionRequest{UserID: index.order.Front().Value.(*candidateSet).userID, Duration: 10, Temperature: 15})
+	require.NoError(t, err)
+	assert.Equal(t, builds, index.builds)
+}
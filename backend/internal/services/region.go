@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Coarse climate regions. Heating needs differ far more between them than within one, so the
+// v2 predictor prefers other users' records from the requesting user's own region.
+const (
+	RegionTropical    = "tropical"
+	RegionSubtropical = "subtropical"
+	RegionTemperate   = "temperate"
+	RegionCold        = "cold"
+	RegionPolar       = "polar"
+)
+
+// Regions lists the regions a user can declare, warmest first
+var Regions = []string{RegionTropical, RegionSubtropical, RegionTemperate, RegionCold, RegionPolar}
+
+// regionRefreshInterval is how often declared regions are reloaded, picking up changes made
+// through other instances
+const regionRefreshInterval = 15 * time.Minute
+
+// ErrInvalidRegion is returned for a region not in Regions
+var ErrInvalidRegion = errors.New("unknown region")
+
+// RegionLookup reports the climate region of a user, empty when it is unknown
+type RegionLookup interface {
+	Region(userID string) string
+}
+
+// RegionForLatitude returns the coarse climate region of a latitude in degrees
+func RegionForLatitude(latitude float64) string {
+	switch lat := math.Abs(latitude); {
+	case lat < 23.5:
+		return RegionTropical
+	case lat < 35:
+		return RegionSubtropical
+	case lat < 50:
+		return RegionTemperate
+	case lat < 66.5:
+		return RegionCold
+	default:
+		return RegionPolar
+	}
+}
+
+// RegionDirectory knows each user's climate region: the one they declared, or else the
+// instance's own, derived from its weather location. Declared regions are kept in memory so
+// predictions can look up every record's region without touching the database.
+type RegionDirectory struct {
+	db       *gorm.DB
+	fallback string
+	mu       sync.Mutex // serializes updates to declared
+	declared atomic.Pointer[map[string]string]
+}
+
+// NewRegionDirectory creates a new region directory; fallback is the region of users who
+// haven't declared one, empty when unknown
+func NewRegionDirectory(fallback string) *RegionDirectory {
+	return &RegionDirectory{
+		db:       database.GetDB(),
+		fallback: fallback,
+	}
+}
+
+// Start loads the declared regions now and keeps reloading them in the background
+func (d *RegionDirectory) Start() {
+	go func() {
+		ticker := time.NewTicker(regionRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if err := d.Refresh(); err != nil {
+				log.Printf("Failed to load user regions: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Refresh reloads the declared regions
+func (d *RegionDirectory) Refresh() error {
+	var users []models.User
+	if err := d.db.Select("id", "region").Where("region <> ''").Find(&users).Error; err != nil {
+		return err
+	}
+	declared := make(map[string]string, len(users))
+	for _, user := range users {
+		declared[user.ID] = user.Region
+	}
+	d.mu.Lock()
+	d.declared.Store(&declared)
+	d.mu.Unlock()
+	return nil
+}
+
+// Region returns the user's declared region, or the instance's when they haven't declared one
+func (d *RegionDirectory) Region(userID string) string {
+	if region := d.Declared(userID); region != "" {
+		return region
+	}
+	return d.fallback
+}
+
+// Declared returns the region the user declared, empty when they haven't
+func (d *RegionDirectory) Declared(userID string) string {
+	if declared := d.declared.Load(); declared != nil {
+		return (*declared)[userID]
+	}
+	return ""
+}
+
+// Fallback returns the region of users who haven't declared one, empty when unknown
+func (d *RegionDirectory) Fallback() string {
+	return d.fallback
+}
+
+// SetRegion stores the user's declared region; empty restores the instance's
+func (d *RegionDirectory) SetRegion(userID, region string) error {
+	if region != "" && !slices.Contains(Regions, region) {
+		return ErrInvalidRegion
+	}
+	err := d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"region", "updated_at"}),
+	}).Create(&models.User{ID: userID, Region: region}).Error
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	declared := map[string]string{}
+	if current := d.declared.Load(); current != nil {
+		for id, r := range *current {
+			declared[id] = r
+		}
+	}
+	if region == "" {
+		delete(declared, userID)
+	} else {
+		declared[userID] = region
+	}
+	d.declared.Store(&declared)
+	return nil
+}
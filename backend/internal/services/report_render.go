@@ -0,0 +1,166 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportColumns returns the header and cells of a report as text, groups first; metrics
+// without a value are empty
+func reportColumns(report *Report) ([]string, [][]string) {
+	header := append(append([]string{}, report.GroupBy...), report.Metrics...)
+	rows := make([][]string, 0, len(report.Rows))
+	for _, row := range report.Rows {
+		cells := make([]string, 0, len(header))
+		for _, group := range report.GroupBy {
+			cells = append(cells, row.Group[group])
+		}
+		for _, metric := range report.Metrics {
+			cell := ""
+			if value := row.Values[metric]; value != nil {
+				cell = strconv.FormatFloat(*value, 'f', -1, 64)
+			}
+			cells = append(cells, cell)
+		}
+		rows = append(rows, cells)
+	}
+	return header, rows
+}
+
+// RenderReportCSV writes a report as CSV with a header row
+func RenderReportCSV(report *Report) ([]byte, error) {
+	header, rows := reportColumns(report)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PDF page layout, in points: A4 in a monospaced font, so the table lines up without measuring text
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfFontSize     = 9
+	pdfLineHeight   = 12
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+	pdfLineChars    = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6) // Courier is 0.6 em wide
+	pdfMaxCellChars = 24
+)
+
+// RenderReportPDF lays a report out as a plain table in a PDF, titled with the report's name
+// and when it was generated
+func RenderReportPDF(title string, report *Report, generated time.Time) []byte {
+	header, rows := reportColumns(report)
+	widths := make([]int, len(header))
+	for _, cells := range append([][]string{header}, rows...) {
+		for i, cell := range cells {
+			widths[i] = min(pdfMaxCellChars, max(widths[i], len(cell)))
+		}
+	}
+	line := func(cells []string) string {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			if len(cell) > widths[i] {
+				cell = cell[:widths[i]]
+			}
+			parts[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		return strings.TrimRight(strings.Join(parts, "  "), " ")
+	}
+
+	lines := []string{title, generated.Format("2006-01-02 15:04 MST"), "", line(header)}
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = strings.Repeat("-", widths[i])
+	}
+	lines = append(lines, line(separator))
+	for _, cells := range rows {
+		lines = append(lines, line(cells))
+	}
+	if report.Truncated {
+		lines = append(lines, "", "(truncated)")
+	}
+	return writePDF(lines)
+}
+
+// writePDF writes lines of text onto as many pages as they need
+func writePDF(lines []string) []byte {
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects: 1 catalog, 2 page tree, 3 font, then a page and its content per page
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i))
+
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin-pdfFontSize)
+		for _, text := range page {
+			fmt.Fprintf(&content, "(%s) '\n", pdfString(text))
+		}
+		content.WriteString("ET")
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfString escapes text for a PDF string literal. The font only has Latin-1 glyphs, so other
+// characters are replaced, and lines are cut to the width of the page.
+func pdfString(text string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range text {
+		if n == pdfLineChars {
+			break
+		}
+		n++
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/cron"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSavedReportNotFound is returned when a saved report does not exist for the user
+	ErrSavedReportNotFound = errors.New("saved report not found")
+	// ErrSavedReportNameTaken is returned when the user already has a saved report with the same name
+	ErrSavedReportNameTaken = errors.New("a saved report with this name already exists")
+	// ErrReportSubscriptionNotFound is returned when a subscription does not exist for the user
+	ErrReportSubscriptionNotFound = errors.New("report subscription not found")
+	// ErrReportSchedule is returned for an invalid cron expression or time zone
+	ErrReportSchedule = errors.New("invalid cron expression or time zone")
+	// ErrReportChannel is returned for a channel other than email or webhook
+	ErrReportChannel = errors.New("channel must be email or webhook")
+	// ErrReportEmailDisabled is returned for email subscriptions when no mail server is configured
+	ErrReportEmailDisabled = errors.New("email delivery is not configured")
+	// ErrReportTarget is returned when the target doesn't suit the channel
+	ErrReportTarget = errors.New("target must be an email address for email or an http(s) URL for webhook")
+	// ErrReportFormat is returned for a format other than csv or pdf
+	ErrReportFormat = errors.New("format must be csv or pdf")
+)
+
+// reportDueInterval is how often subscriptions are checked for reports to deliver; cron
+// schedules have minute resolution
+const reportDueInterval = time.Minute
+
+// ReportService keeps users' saved custom reports and delivers the ones they subscribed to on
+// their cron schedules, by email or to a webhook, as CSV or PDF. Due subscriptions are claimed
+// before delivery, so each run is delivered once even with several instances. A failed
+// delivery is not retried; the error is kept on the subscription until its next run.
+type ReportService struct {
+	db         *gorm.DB
+	aggregates RecordAggregates
+	cfg        config.ReportsConfig
+	client     *http.Client
+}
+
+// NewReportService creates a new report service instance
+func NewReportService(aggregates RecordAggregates, cfg config.ReportsConfig) *ReportService {
+	return &ReportService{
+		db:         database.GetDB(),
+		aggregates: aggregates,
+		cfg:        cfg,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SaveReport validates the query and keeps it under the name
+func (s *ReportService) SaveReport(userID, name string, query ReportQuery) (*models.SavedReport, error) {
+	query.UserID = userID
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	var taken int64
+	if err := s.db.Model(&models.SavedReport{}).Where("user_id = ? AND name = ?", userID, name).Count(&taken).Error; err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, ErrSavedReportNameTaken
+	}
+	definition, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &models.SavedReport{UserID: userID, Name: name, Query: definition}
+	if err := s.db.Create(saved).Error; err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// ListReports returns the user's saved reports by name
+func (s *ReportService) ListReports(userID string) ([]models.SavedReport, error) {
+	reports := []models.SavedReport{}
+	err := s.db.Where("user_id = ?", userID).Order("name ASC").Find(&reports).Error
+	return reports, err
+}
+
+// report loads one of the user's saved reports
+func (s *ReportService) report(userID, id string) (*models.SavedReport, error) {
+	var saved models.SavedReport
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&saved).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSavedReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// DeleteReport removes a saved report with its subscriptions
+func (s *ReportService) DeleteReport(userID, id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.SavedReport{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSavedReportNotFound
+		}
+		return tx.Where("report_id = ?", id).Delete(&models.ReportSubscription{}).Error
+	})
+}
+
+// RunReport runs one of the user's saved reports over their current records
+func (s *ReportService) RunReport(userID, id string) (*models.SavedReport, *Report, error) {
+	saved, err := s.report(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	report, err := s.run(saved)
+	return saved, report, err
+}
+
+func (s *ReportService) run(saved *models.SavedReport) (*Report, error) {
+	var query ReportQuery
+	if err := json.Unmarshal(saved.Query, &query); err != nil {
+		return nil, err
+	}
+	// A report only ever covers its owner's records
+	query.UserID = saved.UserID
+	return s.aggregates.Report(query)
+}
+
+// CreateSubscription validates the subscription and schedules its first delivery after now
+func (s *ReportService) CreateSubscription(subscription *models.ReportSubscription, now time.Time) error {
+	if _, err := s.report(subscription.UserID, subscription.ReportID); err != nil {
+		return err
+	}
+	if err := s.schedule(subscription, now); err != nil {
+		return err
+	}
+	return s.db.Create(subscription).Error
+}
+
+// ListSubscriptions returns the user's report subscriptions, oldest first
+func (s *ReportService) ListSubscriptions(userID string) ([]models.ReportSubscription, error) {
+	subscriptions := []models.ReportSubscription{}
+	err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// UpdateSubscription replaces the schedule and delivery settings of one of the user's
+// subscriptions, rescheduling it from now; the report it delivers stays the same
+func (s *ReportService) UpdateSubscription(userID, id string, changes models.ReportSubscription, now time.Time) (*models.ReportSubscription, error) {
+	var subscription models.ReportSubscription
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReportSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Cron = changes.Cron
+	subscription.Timezone = changes.Timezone
+	subscription.Channel = changes.Channel
+	subscription.Target = changes.Target
+	subscription.Format = changes.Format
+	if err := s.schedule(&subscription, now); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// DeleteSubscription stops one of the user's subscriptions
+func (s *ReportService) DeleteSubscription(userID, id string) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ReportSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportSubscriptionNotFound
+	}
+	return nil
+}
+
+// schedule validates the subscription's delivery settings and sets its next run after now
+func (s *ReportService) schedule(subscription *models.ReportSubscription, now time.Time) error {
+	next, err := nextReportRun(subscription, now)
+	if err != nil {
+		return err
+	}
+	switch subscription.Channel {
+	case models.ReportChannelEmail:
+		if !s.cfg.EmailEnabled() {
+			return ErrReportEmailDisabled
+		}
+		if _, err := mail.ParseAddress(subscription.Target); err != nil {
+			return ErrReportTarget
+		}
+	case models.ReportChannelWebhook:
+		target, err := url.Parse(subscription.Target)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return ErrReportTarget
+		}
+	default:
+		return ErrReportChannel
+	}
+	if subscription.Format != models.ReportFormatCSV && subscription.Format != models.ReportFormatPDF {
+		return ErrReportFormat
+	}
+	subscription.NextRunAt = next
+	return nil
+}
+
+// nextReportRun returns when the subscription's schedule next fires after now, in its time zone
+func nextReportRun(subscription *models.ReportSubscription, now time.Time) (time.Time, error) {
+	schedule, err := cron.Parse(subscription.Cron)
+	if err != nil {
+		return time.Time{}, ErrReportSchedule
+	}
+	loc := now.Location()
+	if subscription.Timezone != "" {
+		if loc, err = time.LoadLocation(subscription.Timezone); err != nil {
+			return time.Time{}, ErrReportSchedule
+		}
+	}
+	next := schedule.Next(now.In(loc))
+	if next.IsZero() {
+		return time.Time{}, ErrReportSchedule
+	}
+	return next.UTC(), nil
+}
+
+// Start checks for due subscriptions every minute in the background
+func (s *ReportService) Start() {
+	go func() {
+		ticker := time.NewTicker(reportDueInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if _, err := s.DeliverDue(now); err != nil {
+				log.Printf("Failed to deliver scheduled reports: %v", err)
+			}
+		}
+	}()
+}
+
+// DeliverDue delivers every subscription whose run is due at now and schedules its next run,
+// returning how many were delivered
+func (s *ReportService) DeliverDue(now time.Time) (int, error) {
+	var due []models.ReportSubscription
+	if err := s.db.Where("next_run_at <= ?", now.UTC()).Order("next_run_at ASC").Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, subscription := range due {
+		next, err := nextReportRun(&subscription, now)
+		if err != nil {
+			// The stored schedule was valid when saved; only a time zone database change breaks it
+			log.Printf("Report subscription %s has an invalid schedule: %v", subscription.ID, err)
+			continue
+		}
+		// Claim the run so no other instance delivers it too
+		claim := s.db.Model(&models.ReportSubscription{}).
+			Where("id = ? AND next_run_at = ?", subscription.ID, subscription.NextRunAt).
+			Update("next_run_at", next)
+		if claim.Error != nil {
+			return delivered, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		lastError := ""
+		if err := s.deliver(&subscription, now); err != nil {
+			log.Printf("Failed to deliver report subscription %s: %v", subscription.ID, err)
+			lastError = err.Error()
+		} else {
+			delivered++
+		}
+		err = s.db.Model(&models.ReportSubscription{}).Where("id = ?", subscription.ID).
+			Updates(map[string]interface{}{"last_run_at": now, "last_error": lastError}).Error
+		if err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// deliver runs the subscription's report and sends it
+func (s *ReportService) deliver(subscription *models.ReportSubscription, now time.Time) error {
+	saved, err := s.report(subscription.UserID, subscription.ReportID)
+	if err != nil {
+		return err
+	}
+	report, err := s.run(saved)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	contentType := "text/csv"
+	if subscription.Format == models.ReportFormatPDF {
+		content, contentType = RenderReportPDF(saved.Name, report, now), "application/pdf"
+	} else if content, err = RenderReportCSV(report); err != nil {
+		return err
+	}
+	filename := reportFilename(saved.Name, now, subscription.Format)
+
+	if subscription.Channel == models.ReportChannelEmail {
+		return s.email(subscription.Target, saved.Name, filename, contentType, content)
+	}
+	return s.post(subscription, filename, contentType, content)
+}
+
+// reportFilename names a delivered report after the saved report and the day it ran
+func reportFilename(name string, now time.Time, format string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+	return fmt.Sprintf("%s-%s.%s", safe, now.Format("2006-01-02"), format)
+}
+
+// post sends a report to a webhook, signed like incoming webhooks when a secret is configured
+func (s *ReportService) post(subscription *models.ReportSubscription, filename, contentType string, content []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, subscription.Target, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	req.Header.Set("X-Report-Id", subscription.ReportID)
+	req.Header.Set("X-Report-Subscription-Id", subscription.ID)
+	if s.cfg.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(s.cfg.WebhookSecret))
+		mac.Write(content)
+		req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// email sends a report as the attachment of an email
+func (s *ReportService) email(to, name, filename, contentType string, content []byte) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fmt.Fprintf(&body, "From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		s.cfg.SMTPFrom, to, mime.QEncoding.Encode("utf-8", "Heat Logger report: "+name), writer.Boundary())
+
+	text, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(text, "Your scheduled report %q is attached.\r\n", name)
+
+	attachment, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filename})},
+	})
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		fmt.Fprintf(attachment, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(attachment, "%s\r\n", encoded)
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", s.cfg.SMTPUsername, s.cfg.SMTPPassword, s.cfg.SMTPHost)
+	}
+	addr := s.cfg.SMTPHost + ":" + strconv.Itoa(s.cfg.SMTPPort)
+	return smtp.SendMail(addr, auth, s.cfg.SMTPFrom, []string{to}, body.Bytes())
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestReportService_DeliversDueSubscriptions(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "reports.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	for _, heating := range []float64{20, 30} {
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: "user1", Date: time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: heating, Satisfaction: 50,
+		}))
+	}
+
+	type delivery struct {
+		contentType, signature string
+		body                   []byte
+	}
+	deliveries := make(chan delivery, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{r.Header.Get("Content-Type"), r.Header.Get("X-Webhook-Signature"), body}
+	}))
+	defer server.Close()
+
+	reports := NewReportService(records, config.ReportsConfig{WebhookSecret: "secret"})
+	saved, err := reports.SaveReport("user1", "Monthly", ReportQuery{Metrics: []string{"count", "avgHeatingTime"}, GroupBy: []string{"month"}})
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	subscription := models.ReportSubscription{
+		UserID: "user1", ReportID: saved.ID, Cron: "0 8 1 * *", Timezone: "UTC",
+		Channel: models.ReportChannelWebhook, Target: server.URL, Format: models.ReportFormatCSV,
+	}
+	require.NoError(t, reports.CreateSubscription(&subscription, now))
+	assert.Equal(t, time.Date(2025, 4, 1, 8, 0, 0, 0, time.UTC), subscription.NextRunAt)
+
+	delivered, err := reports.DeliverDue(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, delivered, "not due yet")
+
+	due := subscription.NextRunAt.Add(30 * time.Second)
+	delivered, err = reports.DeliverDue(due)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	got := <-deliveries
+	assert.Equal(t, "text/csv", got.contentType)
+	assert.Equal(t, "month,count,avgHeatingTime\n2025-03,2,25\n", string(got.body))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(got.body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), got.signature)
+
+	// The run is claimed, so it isn't delivered twice
+	delivered, err = reports.DeliverDue(due)
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+	list, err := reports.ListSubscriptions("user1")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC), list[0].NextRunAt.UTC())
+	assert.Empty(t, list[0].LastError)
+
+	// Email needs a mail server
+	subscription = models.ReportSubscription{UserID: "user1", ReportID: saved.ID, Cron: "0 8 * * MON",
+		Channel: models.ReportChannelEmail, Target: "me@example.com", Format: models.ReportFormatPDF}
+	assert.ErrorIs(t, reports.CreateSubscription(&subscription, now), ErrReportEmailDisabled)
+}
+
+func TestRenderReportPDF(t *testing.T) {
+	value := 25.0
+	report := &Report{
+		Metrics: []string{"avgHeatingTime"},
+		GroupBy: []string{"device"},
+		Rows:    []ReportRow{{Group: map[string]string{"device": "boiler (attic)"}, Values: map[string]*float64{"avgHeatingTime": &value}}},
+	}
+	pdf := RenderReportPDF("Weekly", report, time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC))
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), `(boiler \(attic\)  25) '`)
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRescoreJobNotFound is returned when a re-score job does not exist
+	ErrRescoreJobNotFound = errors.New("re-score job not found")
+	// ErrRescoreJobActive is returned when a re-score is requested while another is still running
+	ErrRescoreJobActive = errors.New("a re-score job is already in progress")
+)
+
+// PredictorFactory builds a predictor over the given record source and clock. The re-score job
+// uses it to run the current model against history as it looked at the time of each record,
+// with the clock frozen at that time.
+type PredictorFactory func(records RecordServiceInterface, clock Clock) Predictor
+
+// RescoreService replays recent feedback through the current predictor so predictor changes
+// can be validated against real data before and after a release. Jobs run as operations.
+type RescoreService struct {
+	db           *gorm.DB
+	operations   *OperationService
+	newPredictor PredictorFactory
+	predictor    string
+	clock        Clock
+}
+
+// NewRescoreService creates a new re-score service. predictor names the model version being
+// scored and is stored with every result.
+func NewRescoreService(operations *OperationService, newPredictor PredictorFactory, predictor string) *RescoreService {
+	return &RescoreService{
+		db:           database.GetDB(),
+		operations:   operations,
+		newPredictor: newPredictor,
+		predictor:    predictor,
+		clock:        SystemClock,
+	}
+}
+
+// SetClock sets the clock re-score windows and completion times are taken from
+func (s *RescoreService) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// Start marks jobs left in flight by a previous process as failed; they will never finish
+func (s *RescoreService) Start() {
+	s.db.Model(&models.RescoreJob{}).
+		Where("status IN ?", []string{models.RescoreJobPending, models.RescoreJobRunning}).
+		Updates(map[string]interface{}{"status": models.RescoreJobFailed, "error": "interrupted by server restart"})
+}
+
+// CreateJob starts a re-score of the records from the last days days. Only one job runs at a
+// time; the job's operation reports its progress and cancels it.
+func (s *RescoreService) CreateJob(requestedBy string, days int) (*models.RescoreJob, error) {
+	job := &models.RescoreJob{
+		RequestedBy: requestedBy,
+		Predictor:   s.predictor,
+		Since:       s.clock.Now().AddDate(0, 0, -days),
+		Status:      models.RescoreJobPending,
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	operation, err := s.operations.Run(models.OperationRescore, requestedBy, job.ID, func(ctx context.Context, progress func(float64)) (string, error) {
+		return "", s.run(ctx, job.ID, progress)
+	})
+	if err != nil {
+		s.db.Delete(job)
+		if errors.Is(err, ErrOperationActive) {
+			return nil, ErrRescoreJobActive
+		}
+		return nil, err
+	}
+	job.OperationID = operation.ID
+	if err := s.db.Model(job).Update("operation_id", operation.ID).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListJobs returns the most recent re-score jobs, newest first
+func (s *RescoreService) ListJobs(limit int) ([]models.RescoreJob, error) {
+	var jobs []models.RescoreJob
+	err := s.db.Order("created_at DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// GetJob retrieves a re-score job and its report
+func (s *RescoreService) GetJob(id string) (*models.RescoreJob, error) {
+	var job models.RescoreJob
+	err := s.db.Where("id = ?", id).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRescoreJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListResults returns the per-record predictions stored by a job
+func (s *RescoreService) ListResults(id string) ([]models.PredictionAudit, error) {
+	if _, err := s.GetJob(id); err != nil {
+		return nil, err
+	}
+	var results []models.PredictionAudit
+	err := s.db.Where("job_id = ?", id).Order("created_at ASC").Find(&results).Error
+	return results, err
+}
+
+// run replays every record in the job's window and stores the predictions and report
+func (s *RescoreService) run(ctx context.Context, id string, progress func(float64)) error {
+	job, err := s.GetJob(id)
+	if err != nil {
+		return err
+	}
+	s.db.Model(job).Update("status", models.RescoreJobRunning)
+
+	var history []models.DailyRecord
+	if err := s.db.Where("excluded_from_training = ?", false).Find(&history).Error; err != nil {
+		s.fail(id, err)
+		return err
+	}
+
+	results, report, err := s.rescore(ctx, job, history, progress)
+	if err != nil {
+		s.fail(id, err)
+		return err
+	}
+	if len(results) > 0 {
+		if err := s.db.CreateInBatches(results, 100).Error; err != nil {
+			s.fail(id, err)
+			return err
+		}
+	}
+
+	now := s.clock.Now()
+	report["status"] = models.RescoreJobCompleted
+	report["completed_at"] = &now
+	return s.db.Model(&models.RescoreJob{}).Where("id = ?", id).Updates(report).Error
+}
+
+// rescore predicts each record in the window using only the history dated before it, and
+// compares both the prediction and the heating time actually used against the ideal time
+// implied by the record's satisfaction. It stops with ctx's error once ctx is canceled.
+func (s *RescoreService) rescore(ctx context.Context, job *models.RescoreJob, history []models.DailyRecord, progress func(float64)) ([]models.PredictionAudit, map[string]interface{}, error) {
+	var targets []models.DailyRecord
+	for _, r := range history {
+		if !r.Date.Before(job.Since) {
+			targets = append(targets, r)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Date.Before(targets[j].Date) })
+
+	var results []models.PredictionAudit
+	var baselineSum, candidateSum float64
+	improved, worsened := 0, 0
+	for i, record := range targets {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		progress(float64(i) / float64(len(targets)) * 100)
+		ideal := impliedTarget(record)
+		if ideal <= 0 {
+			continue
+		}
+
+		predictor := s.newPredictor(&pointInTimeRecords{history: history, before: record.Date}, NewFrozenClock(record.Date))
+		resp, err := predictor.Predict(ctx, PredictionRequest{
+			UserID:      record.UserID,
+			Duration:    record.ShowerDuration,
+			Temperature: record.AverageTemperature,
+			MemberID:    record.MemberID,
+
+			InletTemperature: record.InletTemperature,
+		})
+		if err != nil {
+			log.Printf("Re-score job %s skipped record %s: %v", job.ID, record.ID, err)
+			continue
+		}
+
+		baselineErr := math.Abs(record.HeatingTime - ideal)
+		candidateErr := math.Abs(resp.HeatingTime - ideal)
+		baselineSum += baselineErr
+		candidateSum += candidateErr
+		switch {
+		case candidateErr < baselineErr:
+			improved++
+		case candidateErr > baselineErr:
+			worsened++
+		}
+
+		results = append(results, models.PredictionAudit{
+			JobID:                job.ID,
+			RecordID:             record.ID,
+			UserID:               record.UserID,
+			Predictor:            job.Predictor,
+			UsedHeatingTime:      record.HeatingTime,
+			PredictedHeatingTime: resp.HeatingTime,
+			TargetHeatingTime:    ideal,
+		})
+	}
+
+	report := map[string]interface{}{
+		"record_count": len(targets),
+		"scored":       len(results),
+		"improved":     improved,
+		"worsened":     worsened,
+	}
+	if len(results) > 0 {
+		baselineMAE := baselineSum / float64(len(results))
+		candidateMAE := candidateSum / float64(len(results))
+		report["baseline_mae"] = &baselineMAE
+		report["candidate_mae"] = &candidateMAE
+	}
+	return results, report, nil
+}
+
+// fail ends a job that stopped with runErr: canceled when its operation was, failed otherwise
+func (s *RescoreService) fail(id string, runErr error) {
+	now := s.clock.Now()
+	status := models.RescoreJobFailed
+	if errors.Is(runErr, context.Canceled) {
+		status = models.RescoreJobCanceled
+	} else {
+		log.Printf("Re-score job %s failed: %v", id, runErr)
+	}
+	s.db.Model(&models.RescoreJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       status,
+		"error":        runErr.Error(),
+		"completed_at": &now,
+	})
+}
+
+// pointInTimeRecords serves predictors only the records dated before a cutoff, so a replayed
+// prediction never sees the outcome it is being scored against
+type pointInTimeRecords struct {
+	history []models.DailyRecord
+	before  time.Time
+}
+
+func (p *pointInTimeRecords) GetRecordsForPredictionByUser(userID string, limit int) ([]models.DailyRecord, error) {
+	return p.matching(func(r models.DailyRecord) bool { return r.UserID == userID }, limit), nil
+}
+
+func (p *pointInTimeRecords) GetGlobalRecordsForPrediction(excludeUserID string, limit int) ([]models.DailyRecord, error) {
+	return p.matching(func(r models.DailyRecord) bool { return excludeUserID == "" || r.UserID != excludeUserID }, limit), nil
+}
+
+func (p *pointInTimeRecords) GetRecordsForPrediction(limit int) ([]models.DailyRecord, error) {
+	return p.matching(func(models.DailyRecord) bool { return true }, limit), nil
+}
+
+// matching returns up to limit matching records from before the cutoff, newest first
+func (p *pointInTimeRecords) matching(match func(models.DailyRecord) bool, limit int) []models.DailyRecord {
+	var out []models.DailyRecord
+	for _, r := range p.history {
+		if r.Date.Before(p.before) && match(r) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date.After(out[j].Date) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyCountingPredictor answers with a fixed value and records how much history it was shown
+type historyCountingPredictor struct {
+	records RecordServiceInterface
+	clock   Clock
+	answer  float64
+	seen    *[]int
+	at      *[]time.Time
+}
+
+func (p *historyCountingPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	history, _ := p.records.GetRecordsForPredictionByUser(req.UserID, 400)
+	*p.seen = append(*p.seen, len(history))
+	if p.at != nil {
+		*p.at = append(*p.at, p.clock.Now())
+	}
+	return &PredictionResponse{HeatingTime: p.answer}, nil
+}
+
+func TestRescoreService_ReplaysWithHistoryKnownAtTheTime(t *testing.T) {
+	now := time.Now()
+	history := []models.DailyRecord{
+		{ID: "old", UserID: "user1", Date: now.AddDate(0, 0, -40), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50},
+		{ID: "a", UserID: "user1", Date: now.AddDate(0, 0, -3), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50},
+		{ID: "b", UserID: "user1", Date: now.AddDate(0, 0, -2), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 10, Satisfaction: 50},
+	}
+
+	var seen []int
+	var at []time.Time
+	service := &RescoreService{
+		newPredictor: func(records RecordServiceInterface, clock Clock) Predictor {
+			return &historyCountingPredictor{records: records, clock: clock, answer: 15, seen: &seen, at: &at}
+		},
+	}
+	job := &models.RescoreJob{ID: "job1", Predictor: "v2", Since: now.AddDate(0, 0, -30)}
+
+	var progress []float64
+	results, report, err := service.rescore(context.Background(), job, history, func(percent float64) { progress = append(progress, percent) })
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 50}, progress)
+
+	// Only records inside the window are scored, each seeing only what came before it
+	assert.Len(t, results, 2)
+	assert.Equal(t, []int{1, 2}, seen)
+	// and predicting as if it were the record's own time
+	assert.Equal(t, []time.Time{history[1].Date, history[2].Date}, at)
+	assert.Equal(t, "a", results[0].RecordID)
+	assert.Equal(t, 15.0, results[0].PredictedHeatingTime)
+	assert.Equal(t, 20.0, results[0].TargetHeatingTime)
+
+	// Perfect records mean the time used was right, so any other prediction is worse
+	assert.Equal(t, 0, report["improved"])
+	assert.Equal(t, 2, report["worsened"])
+	assert.InDelta(t, 0.0, *report["baseline_mae"].(*float64), 1e-9)
+	assert.InDelta(t, 5.0, *report["candidate_mae"].(*float64), 1e-9)
+}
+
+func TestRescoreService_StopsWhenCanceled(t *testing.T) {
+	now := time.Now()
+	history := []models.DailyRecord{
+		{ID: "a", UserID: "user1", Date: now.AddDate(0, 0, -3), ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50},
+	}
+	var seen []int
+	service := &RescoreService{
+		newPredictor: func(records RecordServiceInterface, clock Clock) Predictor {
+			return &historyCountingPredictor{records: records, clock: clock, answer: 15, seen: &seen}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := service.rescore(ctx, &models.RescoreJob{ID: "job1", Since: now.AddDate(0, 0, -30)}, history, func(float64) {})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, seen)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// ResidualHeatModel estimates how much of the last heating session is still in the tank.
+// Stored heat leaks away exponentially; how fast depends on the tank's insulation, expressed
+// as the time it takes to lose half of it. The remainder is worth that many minutes of heating.
+type ResidualHeatModel struct {
+	HalfLife       time.Duration            // for devices without their own value; 0 disables the model
+	DeviceHalfLife map[string]time.Duration // per device insulation
+}
+
+// NewResidualHeatModel builds a model from the device configuration
+func NewResidualHeatModel(cfg config.DeviceConfig) ResidualHeatModel {
+	model := ResidualHeatModel{
+		HalfLife:       cfg.InsulationHalfLife,
+		DeviceHalfLife: make(map[string]time.Duration, len(cfg.InsulationHalfLives)),
+	}
+	for device, hours := range cfg.InsulationHalfLives {
+		model.DeviceHalfLife[device] = time.Duration(hours * float64(time.Hour))
+	}
+	return model
+}
+
+// Minutes returns the heating minutes still stored from the household's latest session
+func (m ResidualHeatModel) Minutes(deviceID string, userRecords []models.DailyRecord, now time.Time) float64 {
+	if m.halfLife(deviceID) <= 0 {
+		return 0
+	}
+
+	last, ok := latestUserRecord(userRecords)
+	if !ok || last.Date.After(now) {
+		return 0
+	}
+	return last.HeatingTime * m.Retained(deviceID, now.Sub(last.Date))
+}
+
+// Retained returns the share of a tank's heat still stored after elapsed, 1 when the model is disabled
+func (m ResidualHeatModel) Retained(deviceID string, elapsed time.Duration) float64 {
+	halfLife := m.halfLife(deviceID)
+	if halfLife <= 0 || elapsed <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, elapsed.Hours()/halfLife.Hours())
+}
+
+func (m ResidualHeatModel) halfLife(deviceID string) time.Duration {
+	if deviceHalfLife, ok := m.DeviceHalfLife[deviceID]; ok && deviceID != "" {
+		return deviceHalfLife
+	}
+	return m.HalfLife
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"math"
+)
+
+// Rounding modes for v2 heating times
+const (
+	RoundingSmart   = "smart"   // up after cold feedback, down near the step below after hot feedback
+	RoundingNearest = "nearest" // unbiased
+	RoundingCeil    = "ceil"    // never under-heat
+)
+
+// ErrInvalidRounding is returned for a rounding policy with an unknown mode or out-of-range values
+var ErrInvalidRounding = errors.New("rounding mode must be smart, nearest or ceil, step between 0.1 and 10 minutes and hysteresis between 0 and 0.5")
+
+// RoundingPolicy is how a predicted heating time is rounded for display. Zero fields of a
+// user's policy fall back to the deployment's.
+type RoundingPolicy struct {
+	Mode       string  `json:"mode,omitempty"`
+	Step       float64 `json:"step,omitempty"`       // granularity in minutes, e.g. 0.5 for boilers with finer timers
+	Hysteresis float64 `json:"hysteresis,omitempty"` // smart: after hot feedback, estimates this fraction of a step above a boundary snap down
+}
+
+// ResolutionLookup reports the step in minutes a device's timer can be set in, 0 for whole minutes
+type ResolutionLookup interface {
+	Resolution(userID, deviceID string) float64
+}
+
+// DefaultRoundingPolicy rounds to whole minutes, following the last feedback
+func DefaultRoundingPolicy() RoundingPolicy {
+	return RoundingPolicy{Mode: RoundingSmart, Step: 1, Hysteresis: 0.25}
+}
+
+// Validate checks the policy's set fields
+func (p RoundingPolicy) Validate() error {
+	switch p.Mode {
+	case "", RoundingSmart, RoundingNearest, RoundingCeil:
+	default:
+		return ErrInvalidRounding
+	}
+	if p.Step != 0 && (p.Step < 0.1 || p.Step > 10) {
+		return ErrInvalidRounding
+	}
+	if p.Hysteresis < 0 || p.Hysteresis > 0.5 {
+		return ErrInvalidRounding
+	}
+	return nil
+}
+
+// With returns the policy with the set fields of override applied
+func (p RoundingPolicy) With(override RoundingPolicy) RoundingPolicy {
+	if override.Mode != "" {
+		p.Mode = override.Mode
+	}
+	if override.Step > 0 {
+		p.Step = override.Step
+	}
+	if override.Hysteresis > 0 {
+		p.Hysteresis = override.Hysteresis
+	}
+	return p
+}
+
+// Round rounds est to the policy's step. lastSat is the user's latest satisfaction, used by
+// smart rounding; without it smart rounding is unbiased.
+func (p RoundingPolicy) Round(est float64, lastSat *float64) float64 {
+	step := p.Step
+	if step <= 0 {
+		step = 1
+	}
+	units := est / step
+
+	switch {
+	case p.Mode == RoundingCeil:
+		units = math.Ceil(units)
+	case p.Mode != RoundingSmart || lastSat == nil:
+		units = math.Round(units)
+	case *lastSat > 50 && units-math.Floor(units) <= p.Hysteresis: // recently hot -> allow snap-down if close (avoids a 48.0x → ceil → 49 loop)
+		units = math.Floor(units)
+	case *lastSat < 50: // recently cold -> keep bias-to-hot
+		units = math.Ceil(units)
+	default: // near-perfect recently -> unbiased
+		units = math.Round(units)
+	}
+	return roundTo(units*step, 6)
+}
+
+// keepOnGrid moves a rounded value that left [lo, hi] back to the nearest step inside it
+func keepOnGrid(value, step, lo, hi float64) float64 {
+	if step <= 0 {
+		step = 1
+	}
+	if value < lo {
+		return roundTo(math.Ceil(lo/step-1e-9)*step, 6)
+	}
+	if value > hi {
+		return roundTo(math.Floor(hi/step+1e-9)*step, 6)
+	}
+	return value
+}
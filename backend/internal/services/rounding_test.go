@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundingPolicy_Round(t *testing.T) {
+	hot, cold, perfect := 70.0, 30.0, 50.0
+	smart := DefaultRoundingPolicy()
+
+	tests := []struct {
+		name    string
+		policy  RoundingPolicy
+		est     float64
+		lastSat *float64
+		want    float64
+	}{
+		{"smart snaps down just above a minute after hot feedback", smart, 48.2, &hot, 48},
+		{"smart rounds normally further above it", smart, 48.4, &hot, 48},
+		{"smart rounds up after cold feedback", smart, 48.1, &cold, 49},
+		{"smart is unbiased after perfect feedback", smart, 48.6, &perfect, 49},
+		{"smart is unbiased without feedback", smart, 48.4, nil, 48},
+		{"wider hysteresis snaps down further", smart.With(RoundingPolicy{Hysteresis: 0.45}), 48.4, &hot, 48},
+		{"nearest ignores feedback", RoundingPolicy{Mode: RoundingNearest, Step: 1}, 48.1, &cold, 48},
+		{"ceil always rounds up", RoundingPolicy{Mode: RoundingCeil, Step: 1}, 48.1, &hot, 49},
+		{"half-minute steps", smart.With(RoundingPolicy{Step: 0.5}), 48.3, &cold, 48.5},
+		{"half-minute hysteresis is a fraction of the step", smart.With(RoundingPolicy{Step: 0.5}), 48.1, &hot, 48},
+		{"quarter-minute resolution", RoundingPolicy{Mode: RoundingNearest, Step: 0.25}, 7.4, nil, 7.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.policy.Round(tt.est, tt.lastSat), 1e-9)
+		})
+	}
+}
+
+func TestRoundingPolicy_Validate(t *testing.T) {
+	assert.NoError(t, RoundingPolicy{}.Validate())
+	assert.NoError(t, RoundingPolicy{Mode: RoundingCeil, Step: 0.5, Hysteresis: 0.3}.Validate())
+	assert.ErrorIs(t, RoundingPolicy{Mode: "floor"}.Validate(), ErrInvalidRounding)
+	assert.ErrorIs(t, RoundingPolicy{Step: 0.01}.Validate(), ErrInvalidRounding)
+	assert.ErrorIs(t, RoundingPolicy{Hysteresis: 0.9}.Validate(), ErrInvalidRounding)
+}
+
+func TestKeepOnGrid(t *testing.T) {
+	assert.Equal(t, 7.5, keepOnGrid(7.5, 0.25, 5, 120))
+	assert.InDelta(t, 5.1, keepOnGrid(4.8, 0.3, 5, 120), 1e-9, "below the minimum, moves up to the next step")
+	assert.InDelta(t, 119.7, keepOnGrid(120.3, 0.3, 5, 119.9), 1e-9, "above the maximum, moves down to the step below")
+}
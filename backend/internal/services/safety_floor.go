@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"heat-logger/internal/models"
+)
+
+// ErrInvalidSafetyFloor is returned for a safety floor that is negative or above the longest
+// heating time predictions are clamped to
+var ErrInvalidSafetyFloor = errors.New("safety floor must be between 0 and the maximum heating time")
+
+// SafetyFloorLookup reports the heating time the request's device never drops below in cold
+// weather; 0 means it has none
+type SafetyFloorLookup interface {
+	SafetyFloor(req PredictionRequest) float64
+}
+
+// SafetyFloorInfo explains a prediction that was raised to its device's safety floor
+type SafetyFloorInfo struct {
+	Minutes          float64 `json:"minutes"`          // the floor served
+	Below            float64 `json:"below"`            // °C under which the floor applies
+	ModelHeatingTime float64 `json:"modelHeatingTime"` // what the predictor answered
+}
+
+// SafetyFloorPredictor keeps heating up in cold weather: below the configured temperature, a
+// prediction under the device's safety floor is raised to it, whatever "too hot" feedback
+// pulled it down, so pipes and a tank left barely warm don't breed legionella. It runs after
+// every other wrapper, so cached, shed and held answers are floored too.
+type SafetyFloorPredictor struct {
+	inner  Predictor
+	floors SafetyFloorLookup
+	below  float64
+}
+
+// NewSafetyFloorPredictor wraps inner with the safety floor applied below the temperature below, °C
+func NewSafetyFloorPredictor(inner Predictor, floors SafetyFloorLookup, below float64) *SafetyFloorPredictor {
+	return &SafetyFloorPredictor{inner: inner, floors: floors, below: below}
+}
+
+// Predict runs the wrapped predictor and raises its answer to the floor when it is cold
+func (p *SafetyFloorPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	resp, err := p.inner.Predict(ctx, req)
+	if err != nil || req.Temperature >= p.below {
+		return resp, err
+	}
+	floor := p.floors.SafetyFloor(req)
+	if floor <= 0 || resp.HeatingTime >= floor {
+		return resp, nil
+	}
+
+	// Copy: cached predictions are shared
+	floored := *resp
+	var expl PredictionExplanation
+	if resp.Explanation != nil {
+		expl = *resp.Explanation
+	}
+	expl.SafetyFloor = &SafetyFloorInfo{Minutes: floor, Below: p.below, ModelHeatingTime: resp.HeatingTime}
+	floored.HeatingTime = floor
+	floored.RawHeatingTime = math.Max(resp.RawHeatingTime, floor)
+	floored.Explanation = &expl
+	return &floored, nil
+}
+
+// SetSafetyFloorDefaults sets the safety floor of devices that don't set their own and the
+// highest floor a device may set, the longest heating time predictions are clamped to
+func (s *BoilerService) SetSafetyFloorDefaults(minutes, max float64) {
+	s.safetyFloor = minutes
+	s.maxSafetyFloor = max
+}
+
+// SafetyFloor returns the request's device's own safety floor, or the configured one
+func (s *BoilerService) SafetyFloor(req PredictionRequest) float64 {
+	if req.DeviceID == "" {
+		return s.safetyFloor
+	}
+	profile, err := s.SharedProfile(req.UserID, req.DeviceID)
+	if err != nil || profile.SafetyFloor == nil {
+		return s.safetyFloor
+	}
+	return *profile.SafetyFloor
+}
+
+// SetSafetyFloor sets the device's safety floor, minutes; nil restores the configured one and
+// 0 turns it off for the device
+func (s *BoilerService) SetSafetyFloor(userID, deviceID string, minutes *float64) (*models.BoilerProfile, error) {
+	if minutes != nil && (*minutes < 0 || (s.maxSafetyFloor > 0 && *minutes > s.maxSafetyFloor)) {
+		return nil, ErrInvalidSafetyFloor
+	}
+	profile, err := s.profileOrNew(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	profile.SafetyFloor = minutes
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// floorOf gives every device the same safety floor
+type floorOf float64
+
+func (f floorOf) SafetyFloor(req PredictionRequest) float64 { return float64(f) }
+
+func TestSafetyFloorPredictor(t *testing.T) {
+	floored := NewSafetyFloorPredictor(fixedPredictor(8), floorOf(15), 5)
+
+	// Cold: raised to the floor and explained
+	resp, err := floored.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 15.0, resp.HeatingTime)
+	require.NotNil(t, resp.Explanation)
+	assert.Equal(t, &SafetyFloorInfo{Minutes: 15, Below: 5, ModelHeatingTime: 8}, resp.Explanation.SafetyFloor)
+
+	// Mild weather, answers above the floor and devices without one pass
+	resp, err = floored.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 8.0, resp.HeatingTime)
+	assert.Nil(t, resp.Explanation)
+	resp, err = NewSafetyFloorPredictor(fixedPredictor(20), floorOf(15), 5).Predict(context.Background(), PredictionRequest{Temperature: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, resp.HeatingTime)
+	resp, err = NewSafetyFloorPredictor(fixedPredictor(8), floorOf(0), 5).Predict(context.Background(), PredictionRequest{Temperature: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 8.0, resp.HeatingTime)
+}
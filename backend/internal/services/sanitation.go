@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSanitationDay is returned for a cycle day other than monday to sunday
+	ErrInvalidSanitationDay = errors.New("sanitation day must be monday to sunday")
+	// ErrInvalidSanitationTime is returned for a cycle start that isn't HH:MM
+	ErrInvalidSanitationTime = errors.New("sanitation time must be in HH:MM format")
+	// ErrInvalidSanitationMinutes is returned for a cycle heating time outside 1-240 minutes
+	ErrInvalidSanitationMinutes = errors.New("sanitation minutes must be between 1 and 240")
+)
+
+// recentSanitationCycles is how many past cycles a device's sanitation status lists
+const recentSanitationCycles = 5
+
+// SanitationLookup reports whether a shower on the device at a time was taken in water heated
+// by a legionella cycle
+type SanitationLookup interface {
+	SanitizedAt(deviceID string, at time.Time) (bool, error)
+}
+
+// SanitationSchedule is a device's weekly legionella cycle
+type SanitationSchedule struct {
+	Day     string  `json:"day"`  // monday to sunday
+	Time    string  `json:"time"` // HH:MM, server time
+	Minutes float64 `json:"minutes"`
+}
+
+// SanitationStatus is a device's cycle schedule, when it next runs and the cycles that ran
+type SanitationStatus struct {
+	DeviceID string                   `json:"deviceId"`
+	Schedule *SanitationSchedule      `json:"schedule,omitempty"` // nil when the device has none
+	Next     *time.Time               `json:"next,omitempty"`
+	Overdue  bool                     `json:"overdue"` // the last scheduled cycle since the schedule was set didn't run
+	Recent   []models.SanitationCycle `json:"recent"`  // latest first
+}
+
+// SanitationService schedules each tank's weekly legionella cycle: the heater runs long enough
+// to take the whole tank past 60 °C whatever comfort predictions say. Integrations read the
+// next cycle, run it and report it as a sanitation_start device event; the cycle is stored
+// apart from shower records, and showers on the device during it and for a while after, in
+// water much hotter than usual, are kept out of training.
+type SanitationService struct {
+	db      *gorm.DB
+	cfg     config.SanitationConfig
+	boilers *BoilerService
+	now     func() time.Time
+}
+
+// NewSanitationService creates a new sanitation service instance
+func NewSanitationService(cfg config.SanitationConfig, boilers *BoilerService) *SanitationService {
+	return &SanitationService{
+		db:      database.GetDB(),
+		cfg:     cfg,
+		boilers: boilers,
+		now:     time.Now,
+	}
+}
+
+// SetClock sets the clock upcoming cycles are worked out from
+func (s *SanitationService) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// SetSchedule sets the device's weekly cycle; an empty day removes it, and 0 minutes uses the
+// configured heating time
+func (s *SanitationService) SetSchedule(userID, deviceID string, schedule SanitationSchedule) (*models.BoilerProfile, error) {
+	day := strings.ToLower(schedule.Day)
+	if day != "" {
+		if _, ok := parseWeekday(day); !ok {
+			return nil, ErrInvalidSanitationDay
+		}
+		if _, err := time.Parse("15:04", schedule.Time); err != nil {
+			return nil, ErrInvalidSanitationTime
+		}
+		if schedule.Minutes != 0 && (schedule.Minutes < 1 || schedule.Minutes > 240) {
+			return nil, ErrInvalidSanitationMinutes
+		}
+	} else {
+		schedule = SanitationSchedule{}
+	}
+
+	profile, err := s.boilers.profileOrNew(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	profile.SanitationDay = day
+	profile.SanitationTime = schedule.Time
+	profile.SanitationMinutes = schedule.Minutes
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Status returns the device's cycle schedule as the user sees it, shared within a household
+func (s *SanitationService) Status(userID, deviceID string) (*SanitationStatus, error) {
+	status := &SanitationStatus{DeviceID: deviceID, Recent: []models.SanitationCycle{}}
+	err := s.db.Where("device_id = ?", deviceID).Order("started_at DESC").
+		Limit(recentSanitationCycles).Find(&status.Recent).Error
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.boilers.SharedProfile(userID, deviceID)
+	if errors.Is(err, ErrBoilerProfileNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	weekday, ok := parseWeekday(profile.SanitationDay)
+	if !ok {
+		return status, nil
+	}
+	status.Schedule = &SanitationSchedule{Day: profile.SanitationDay, Time: profile.SanitationTime, Minutes: profile.SanitationMinutes}
+	if status.Schedule.Minutes == 0 {
+		status.Schedule.Minutes = s.cfg.Minutes
+	}
+
+	next, ok := nextWeekly(weekday, profile.SanitationTime, s.now().Local())
+	if !ok {
+		return status, nil
+	}
+	status.Next = &next
+	// Integrations may start a little early
+	last := next.AddDate(0, 0, -7)
+	ran := len(status.Recent) > 0 && !status.Recent[0].StartedAt.Before(last.Add(-time.Hour))
+	status.Overdue = last.After(profile.UpdatedAt) && !ran
+	return status, nil
+}
+
+// Record stores a cycle the scheduler started; it is the DeviceStateService callback, so
+// failures are only logged
+func (s *SanitationService) Record(deviceID string, at time.Time, minutes float64) {
+	// Stored in UTC so cycles compare by time whatever zone they were reported in
+	at = at.UTC()
+	cycle := models.SanitationCycle{
+		DeviceID:  deviceID,
+		StartedAt: at,
+		EndsAt:    at.Add(time.Duration(minutes * float64(time.Minute))),
+		Minutes:   minutes,
+	}
+	if err := s.db.Create(&cycle).Error; err != nil {
+		log.Printf("Failed to record sanitation cycle on device %s: %v", deviceID, err)
+	}
+}
+
+// SanitizedAt reports whether a cycle on the device was running at, or ended less than the
+// configured time before it
+func (s *SanitationService) SanitizedAt(deviceID string, at time.Time) (bool, error) {
+	if deviceID == "" {
+		return false, nil
+	}
+	at = at.UTC()
+	var count int64
+	err := s.db.Model(&models.SanitationCycle{}).
+		Where("device_id = ? AND started_at <= ? AND ends_at >= ?", deviceID, at, at.Add(-s.cfg.ExcludeFor)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// parseWeekday reads a lower-case weekday name
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToLower(day.String()) == name {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// nextWeekly returns the first time after after that falls on weekday at the HH:MM clock
+// time, in after's location
+func nextWeekly(weekday time.Weekday, clock string, after time.Time) (time.Time, bool) {
+	for days := 0; days <= 7; days++ {
+		day := after.AddDate(0, 0, days)
+		if day.Weekday() != weekday {
+			continue
+		}
+		at, err := time.ParseInLocation("2006-01-02 15:04", day.Format("2006-01-02")+" "+clock, after.Location())
+		if err != nil {
+			return time.Time{}, false
+		}
+		if at.After(after) {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestNextWeekly(t *testing.T) {
+	// Sunday 4 January 2026
+	sunday := time.Date(2026, 1, 4, 2, 0, 0, 0, time.UTC)
+	next, ok := nextWeekly(time.Sunday, "03:00", sunday)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 1, 4, 3, 0, 0, 0, time.UTC), next)
+
+	next, ok = nextWeekly(time.Sunday, "03:00", sunday.Add(2*time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 1, 11, 3, 0, 0, 0, time.UTC), next)
+
+	next, ok = nextWeekly(time.Wednesday, "22:30", sunday)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 1, 7, 22, 30, 0, 0, time.UTC), next)
+}
+
+func TestSanitationService(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "sanitation.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	boilers := NewBoilerService(config.DeviceConfig{}, NewInletModel(config.InletConfig{}))
+	sanitation := NewSanitationService(config.SanitationConfig{Minutes: 90, ExcludeFor: 2 * time.Hour}, boilers)
+	records := NewRecordService()
+	records.SetSanitationLookup(sanitation)
+	var stored, trained []string
+	records.OnRecordCreated(func(record models.DailyRecord) { stored = append(stored, record.ID) })
+	records.OnRecordTrained(func(record models.DailyRecord) { trained = append(trained, record.ID) })
+
+	_, err := sanitation.SetSchedule("user1", "boiler", SanitationSchedule{Day: "Funday", Time: "03:00"})
+	assert.ErrorIs(t, err, ErrInvalidSanitationDay)
+	_, err = sanitation.SetSchedule("user1", "boiler", SanitationSchedule{Day: "sunday", Time: "3am"})
+	assert.ErrorIs(t, err, ErrInvalidSanitationTime)
+	_, err = sanitation.SetSchedule("user1", "boiler", SanitationSchedule{Day: "sunday", Time: "03:00", Minutes: 500})
+	assert.ErrorIs(t, err, ErrInvalidSanitationMinutes)
+
+	profile, err := sanitation.SetSchedule("user1", "boiler", SanitationSchedule{Day: "Sunday", Time: "03:00"})
+	require.NoError(t, err)
+	assert.Equal(t, "sunday", profile.SanitationDay)
+	status, err := sanitation.Status("user1", "boiler")
+	require.NoError(t, err)
+	require.NotNil(t, status.Schedule)
+	assert.Equal(t, 90.0, status.Schedule.Minutes, "the configured heating time")
+	require.NotNil(t, status.Next)
+	assert.Equal(t, time.Sunday, status.Next.Weekday())
+	assert.False(t, status.Overdue, "no cycle was due since the schedule was set")
+
+	// A shower during the cycle and one within the configured time after it aren't learned from
+	started := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	sanitation.Record("boiler", started, 60)
+	for _, tc := range []struct {
+		deviceID string
+		at       time.Time
+		excluded bool
+	}{
+		{"boiler", started.Add(30 * time.Minute), true},
+		{"boiler", started.Add(150 * time.Minute), true},
+		{"boiler", started.Add(-time.Minute), false},
+		{"annex", started.Add(30 * time.Minute), false},
+	} {
+		record := &models.DailyRecord{
+			UserID: "user1", DeviceID: tc.deviceID, Date: tc.at,
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 90,
+		}
+		require.NoError(t, records.CreateRecord(record))
+		assert.Equal(t, tc.excluded, record.ExcludedFromTraining, tc)
+		assert.Contains(t, stored, record.ID, "stored records are always reported")
+		if tc.excluded {
+			assert.NotContains(t, trained, record.ID, tc)
+		} else {
+			assert.Contains(t, trained, record.ID, tc)
+		}
+	}
+	sanitized, err := sanitation.SanitizedAt("boiler", started.Add(4*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, sanitized)
+
+	status, err = sanitation.Status("user1", "boiler")
+	require.NoError(t, err)
+	require.Len(t, status.Recent, 1)
+	assert.Equal(t, 60.0, status.Recent[0].Minutes)
+
+	// Removing the schedule keeps the history
+	_, err = sanitation.SetSchedule("user1", "boiler", SanitationSchedule{})
+	require.NoError(t, err)
+	status, err = sanitation.Status("user1", "boiler")
+	require.NoError(t, err)
+	assert.Nil(t, status.Schedule)
+	assert.Len(t, status.Recent, 1)
+}
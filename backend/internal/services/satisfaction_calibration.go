@@ -0,0 +1,166 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"heat-logger/internal/models"
+)
+
+// Fitting a user's rating scale
+const (
+	ratingScaleMin        = 0.5 // a user's ratings are stretched at most twice...
+	ratingScaleMax        = 2.0 // ...and squeezed at most by half
+	ratingScaleMinSpread  = 1.0 // users rating nearly everything 50 say nothing about their slider
+	ratingHistogramBins   = 10  // of 10 points each, 1-10 to 91-100
+	ratingHistogramPoints = 10
+)
+
+// RatingCalibrationLookup reports how far to stretch a user's ratings around 50 so they spread
+// as widely as everyone else's
+type RatingCalibrationLookup interface {
+	RatingScale(userID string) float64
+}
+
+// RatingDistribution describes a set of ratings after rescaling by their users' targets
+type RatingDistribution struct {
+	Ratings   int     `json:"ratings"`
+	Mean      float64 `json:"mean"`
+	Median    float64 `json:"median"`
+	Spread    float64 `json:"spread"`    // mean distance from 50
+	Histogram []int   `json:"histogram"` // ratings per 10 points, 1-10 first
+}
+
+// SatisfactionCalibration compares how a user rates with how everyone rates. Scale and Offset
+// map the user's ratings onto the population's, scale*rating + offset, once there are enough
+// of them; Applied reports whether records entering the global pool are rescaled by it.
+type SatisfactionCalibration struct {
+	UserID     string             `json:"userId"`
+	Target     float64            `json:"target"`
+	User       RatingDistribution `json:"user"`
+	Population RatingDistribution `json:"population"`
+	Scale      *float64           `json:"scale,omitempty"`
+	Offset     *float64           `json:"offset,omitempty"`
+	Applied    bool               `json:"applied"`
+}
+
+// SetCalibrationApplied records whether predictors rescale other users' records by their scale
+func (d *SatisfactionTargetDirectory) SetCalibrationApplied(applied bool) {
+	d.calibrationApplied = applied
+}
+
+// RatingScale returns the user's fitted scale, 1 until there are enough ratings to fit one
+func (d *SatisfactionTargetDirectory) RatingScale(userID string) float64 {
+	targets := d.targets.Load()
+	if targets == nil {
+		return 1
+	}
+	return targets.RatingScale(userID)
+}
+
+// RatingScale returns the user's scale from this load of the targets
+func (t *satisfactionTargets) RatingScale(userID string) float64 {
+	if scale, ok := t.scales[userID]; ok {
+		return scale
+	}
+	return 1
+}
+
+// Calibration reports the user's rating distribution against the population's
+func (d *SatisfactionTargetDirectory) Calibration(userID string) SatisfactionCalibration {
+	calibration := SatisfactionCalibration{
+		UserID:     userID,
+		Target:     d.Target(userID),
+		User:       describeRatings(nil),
+		Population: describeRatings(nil),
+		Applied:    d.calibrationApplied,
+	}
+	targets := d.targets.Load()
+	if targets == nil {
+		return calibration
+	}
+	calibration.User = describeRatings(targets.neutral[userID])
+	calibration.Population = targets.population
+	if scale, ok := targets.scales[userID]; ok {
+		offset := roundTo(models.SatisfactionPerfect*(1-scale), 2)
+		calibration.Scale = &scale
+		calibration.Offset = &offset
+	}
+	return calibration
+}
+
+// fitRatingScales describes everyone's ratings, rescaled by their targets, and fits each user
+// with enough of them a scale that gives their ratings the population's spread around 50. The
+// scale keeps 50 at 50: the target already moved the user's perfect there.
+func (t *satisfactionTargets) fitRatingScales(ratings map[string][]float64, target func(userID string) float64) {
+	t.neutral = make(map[string][]float64, len(ratings))
+	t.scales = make(map[string]float64)
+	var all []float64
+	for userID, userRatings := range ratings {
+		userTarget := target(userID)
+		neutral := make([]float64, len(userRatings))
+		for i, rating := range userRatings {
+			neutral[i] = neutralizeSatisfaction(rating, userTarget)
+		}
+		t.neutral[userID] = neutral
+		all = append(all, neutral...)
+	}
+	t.population = describeRatings(all)
+
+	for userID, neutral := range t.neutral {
+		if len(neutral) < satisfactionTargetMinRatings {
+			continue
+		}
+		spread := describeRatings(neutral).Spread
+		if spread < ratingScaleMinSpread || t.population.Spread < ratingScaleMinSpread {
+			continue
+		}
+		t.scales[userID] = roundTo(math.Max(ratingScaleMin, math.Min(ratingScaleMax, t.population.Spread/spread)), 2)
+	}
+}
+
+// describeRatings summarises ratings
+func describeRatings(ratings []float64) RatingDistribution {
+	dist := RatingDistribution{Ratings: len(ratings), Histogram: make([]int, ratingHistogramBins)}
+	if len(ratings) == 0 {
+		return dist
+	}
+	sorted := append([]float64(nil), ratings...)
+	sort.Float64s(sorted)
+	var sum, spread float64
+	for _, rating := range sorted {
+		sum += rating
+		spread += math.Abs(rating - models.SatisfactionPerfect)
+		bin := int(math.Ceil(rating)-1) / ratingHistogramPoints
+		dist.Histogram[max(0, min(ratingHistogramBins-1, bin))]++
+	}
+	n := float64(len(sorted))
+	dist.Mean = roundTo(sum/n, 2)
+	dist.Median = roundTo(percentileOf(sorted, 0.5), 2)
+	dist.Spread = roundTo(spread/n, 2)
+	return dist
+}
+
+// calibrateRecords returns the records with each rating stretched around 50 by its user's
+// scale. The records may be shared with a cache, so they are copied when anything changes.
+func calibrateRecords(records []models.DailyRecord, calibration RatingCalibrationLookup) []models.DailyRecord {
+	if calibration == nil {
+		return records
+	}
+	var out []models.DailyRecord
+	for i, record := range records {
+		scale := calibration.RatingScale(record.UserID)
+		if scale == 1 {
+			continue
+		}
+		if out == nil {
+			out = append([]models.DailyRecord(nil), records...)
+		}
+		calibrated := models.SatisfactionPerfect + (record.Satisfaction-models.SatisfactionPerfect)*scale
+		out[i].Satisfaction = math.Max(models.SatisfactionMin, math.Min(models.SatisfactionMax, calibrated))
+	}
+	if out == nil {
+		return records
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// inferenceEditor names the inference in the revisions it leaves
+const inferenceEditor = "inference"
+
+// SatisfactionInferenceService turns the user boosting the heater by hand right after a shower
+// into "too cold" feedback for the shower, so the predictors learn from showers nobody rated.
+// Only records still waiting for review are changed: a rating the user gave always wins, and
+// the user can still correct the inferred one when reviewing.
+type SatisfactionInferenceService struct {
+	cfg     config.SatisfactionInferenceConfig
+	records *RecordService
+}
+
+// NewSatisfactionInferenceService creates a new satisfaction inference service instance
+func NewSatisfactionInferenceService(cfg config.SatisfactionInferenceConfig, records *RecordService) *SatisfactionInferenceService {
+	return &SatisfactionInferenceService{cfg: cfg, records: records}
+}
+
+// ManualBoost is the DeviceStateService callback; failures are logged, as the boost itself
+// has already been applied
+func (s *SatisfactionInferenceService) ManualBoost(deviceID string, at time.Time) {
+	if _, err := s.Infer(deviceID, at); err != nil {
+		log.Printf("Failed to infer satisfaction for device %s: %v", deviceID, err)
+	}
+}
+
+// Infer marks the latest unreviewed shower on the device within the window before the boost
+// as too cold, returning the changed record, or nil when there was none
+func (s *SatisfactionInferenceService) Infer(deviceID string, boostedAt time.Time) (*models.DailyRecord, error) {
+	if !s.cfg.Enabled() || deviceID == "" {
+		return nil, nil
+	}
+	stored, err := s.records.latestPendingOnDevice(deviceID, boostedAt.Add(-s.cfg.Window), boostedAt)
+	if err != nil || stored == nil {
+		return nil, err
+	}
+
+	updated := *stored
+	updated.Satisfaction = s.cfg.Satisfaction
+	updated.ReviewStatus = models.ReviewStatusInferred
+	updated.ExcludedFromTraining = false
+	s.records.excludeUnrepresentative(&updated)
+	updated.FeedbackConfidence = s.records.confidence.For(updated)
+	if err := s.records.editRecord(*stored, &updated, inferenceEditor); err != nil {
+		return nil, err
+	}
+	s.records.recordTrained(updated)
+	return &updated, nil
+}
+
+// latestPendingOnDevice returns the device's latest record dated from from to to that still
+// waits for review, or nil when there is none. Stored dates keep the client's zone, so the
+// query is loose and the exact check is made on the loaded records.
+func (s *RecordService) latestPendingOnDevice(deviceID string, from, to time.Time) (*models.DailyRecord, error) {
+	var records []models.DailyRecord
+	err := s.db.Where("device_id = ? AND review_status = ? AND date BETWEEN ? AND ?",
+		deviceID, models.ReviewStatusPending, from.Add(-14*time.Hour), to.Add(14*time.Hour)).
+		Order("date DESC").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	var latest *models.DailyRecord
+	for i, record := range records {
+		if record.Date.Before(from) || record.Date.After(to) {
+			continue
+		}
+		if latest == nil || record.Date.After(latest.Date) {
+			latest = &records[i]
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestSatisfactionInferenceService_TrainsOnInferredRecords(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "inference.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	var trained []models.DailyRecord
+	records.OnRecordTrained(func(record models.DailyRecord) { trained = append(trained, record) })
+	inference := NewSatisfactionInferenceService(config.SatisfactionInferenceConfig{Window: time.Hour, Satisfaction: 30}, records)
+
+	record := &models.DailyRecord{
+		UserID: "user1", DeviceID: "boiler", Source: models.RecordSourceSensor, Date: time.Now().Add(-10 * time.Minute),
+		ShowerDuration: 8, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50,
+	}
+	require.NoError(t, records.CreateRecord(record))
+	assert.Empty(t, trained, "pending records wait for the user")
+
+	// The inferred rating is learned from right away, as the predictors read it from the database
+	inferred, err := inference.Infer("boiler", time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, inferred)
+	require.Len(t, trained, 1)
+	assert.Equal(t, record.ID, trained[0].ID)
+	assert.Equal(t, models.ReviewStatusInferred, trained[0].ReviewStatus)
+
+	// Accepting it later doesn't count it twice
+	reviewed := *inferred
+	reviewed.Satisfaction = 45
+	require.NoError(t, records.ReviewRecord("user1", &reviewed, "user1"))
+	assert.Len(t, trained, 1)
+}
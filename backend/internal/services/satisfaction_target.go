@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bounds of a user's target satisfaction. Outside them a rating says more about the water than
+// about how the user rates it.
+const (
+	SatisfactionTargetMin = 30
+	SatisfactionTargetMax = 70
+)
+
+// Learning a user's target from their ratings
+const (
+	satisfactionTargetRatings    = 50 // latest ratings looked at
+	satisfactionTargetMinRatings = 20 // fewer can't tell a habit from a run of bad showers
+	satisfactionTargetDeadband   = 5  // medians closer than this to 50 are noise, not a habit
+	satisfactionTargetMaxSpread  = 10 // interquartile range above which the ratings are still moving
+)
+
+// satisfactionTargetRefreshInterval is how often explicit targets are reloaded and learned ones
+// recomputed
+const satisfactionTargetRefreshInterval = time.Hour
+
+// ErrInvalidSatisfactionTarget is returned for a target outside SatisfactionTargetMin to
+// SatisfactionTargetMax
+var ErrInvalidSatisfactionTarget = errors.New("target satisfaction out of range")
+
+// SatisfactionTargetLookup reports the rating a user gives water that was just right
+type SatisfactionTargetLookup interface {
+	Target(userID string) float64
+}
+
+// SatisfactionTarget is the rating a user gives water that was just right, and where it came from
+type SatisfactionTarget struct {
+	Target   float64  `json:"target"`
+	Explicit *float64 `json:"explicit,omitempty"` // set by the user; wins over the learned target
+	Learned  *float64 `json:"learned,omitempty"`  // from the user's ratings, once there are enough of them
+	Ratings  int      `json:"ratings"`            // ratings the learned target looked at
+}
+
+// satisfactionTargets is one load of everyone's targets
+type satisfactionTargets struct {
+	explicit map[string]float64
+	learned  map[string]float64
+	ratings  map[string]int
+
+	neutral    map[string][]float64 // each user's ratings rescaled by their target
+	scales     map[string]float64   // fitted rating scales, see fitRatingScales
+	population RatingDistribution
+}
+
+// SatisfactionTargetDirectory knows each user's target satisfaction. Some users rate 60 when
+// the water was perfect; predictors rescale their ratings so that target reads as 50, the
+// neutral point the learning math is built around. The target is what the user set, else what
+// their rating distribution shows, else 50. Targets are kept in memory so predictions can
+// rescale every record without touching the database.
+type SatisfactionTargetDirectory struct {
+	db      *gorm.DB
+	mu      sync.Mutex // serializes updates to targets
+	targets atomic.Pointer[satisfactionTargets]
+
+	calibrationApplied bool
+}
+
+// NewSatisfactionTargetDirectory creates a new satisfaction target directory
+func NewSatisfactionTargetDirectory() *SatisfactionTargetDirectory {
+	return &SatisfactionTargetDirectory{
+		db: database.GetDB(),
+	}
+}
+
+// Start loads the targets now and keeps reloading them in the background
+func (d *SatisfactionTargetDirectory) Start() {
+	go func() {
+		ticker := time.NewTicker(satisfactionTargetRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if err := d.Refresh(); err != nil {
+				log.Printf("Failed to load satisfaction targets: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Refresh reloads the explicit targets and learns the others from each user's latest ratings.
+// Inferred satisfaction isn't a rating the user gave, so it is left out.
+func (d *SatisfactionTargetDirectory) Refresh() error {
+	var users []models.User
+	if err := d.db.Select("id", "target_satisfaction").Where("target_satisfaction IS NOT NULL").Find(&users).Error; err != nil {
+		return err
+	}
+	var rows []struct {
+		UserID       string
+		Satisfaction float64
+	}
+	err := d.db.Raw(`SELECT user_id, satisfaction FROM (
+		SELECT user_id, satisfaction, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY date DESC) AS n
+		FROM daily_records WHERE excluded_from_training = ? AND COALESCE(review_status, '') <> ?
+	) WHERE n <= ?`, false, models.ReviewStatusInferred, satisfactionTargetRatings).Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	targets := &satisfactionTargets{
+		explicit: make(map[string]float64, len(users)),
+		learned:  make(map[string]float64),
+		ratings:  make(map[string]int),
+	}
+	for _, user := range users {
+		targets.explicit[user.ID] = *user.TargetSatisfaction
+	}
+	ratings := make(map[string][]float64)
+	for _, row := range rows {
+		ratings[row.UserID] = append(ratings[row.UserID], row.Satisfaction)
+	}
+	for userID, userRatings := range ratings {
+		targets.ratings[userID] = len(userRatings)
+		if target, ok := LearnSatisfactionTarget(userRatings); ok {
+			targets.learned[userID] = target
+		}
+	}
+	targets.fitRatingScales(ratings, targets.target)
+
+	d.mu.Lock()
+	d.targets.Store(targets)
+	d.mu.Unlock()
+	return nil
+}
+
+// LearnSatisfactionTarget estimates the rating a user gives water that was just right from
+// their latest ratings. Predictions keep moving towards what the user calls perfect, so once
+// they have settled the ratings cluster around it from both sides and the median finds it. It
+// reports false when there are too few ratings, when they are spread too widely to have
+// settled (a model still correcting a cold streak mustn't mistake it for a habit), or when the
+// median is close enough to 50 to be noise.
+func LearnSatisfactionTarget(ratings []float64) (float64, bool) {
+	if len(ratings) < satisfactionTargetMinRatings {
+		return 0, false
+	}
+	sorted := append([]float64(nil), ratings...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + median) / 2
+	}
+	if sorted[len(sorted)*3/4]-sorted[len(sorted)/4] > satisfactionTargetMaxSpread {
+		return 0, false
+	}
+	if math.Abs(median-models.SatisfactionPerfect) < satisfactionTargetDeadband {
+		return 0, false
+	}
+	return math.Round(math.Max(SatisfactionTargetMin, math.Min(SatisfactionTargetMax, median))), true
+}
+
+// Target returns the user's target satisfaction: the one they set, else the learned one, else 50
+func (d *SatisfactionTargetDirectory) Target(userID string) float64 {
+	targets := d.targets.Load()
+	if targets == nil {
+		return models.SatisfactionPerfect
+	}
+	return targets.target(userID)
+}
+
+// target returns the user's target from this load of the targets
+func (t *satisfactionTargets) target(userID string) float64 {
+	if target, ok := t.explicit[userID]; ok {
+		return target
+	}
+	if target, ok := t.learned[userID]; ok {
+		return target
+	}
+	return models.SatisfactionPerfect
+}
+
+// Get returns the user's target with the explicit and learned targets behind it
+func (d *SatisfactionTargetDirectory) Get(userID string) SatisfactionTarget {
+	target := SatisfactionTarget{Target: d.Target(userID)}
+	if targets := d.targets.Load(); targets != nil {
+		if explicit, ok := targets.explicit[userID]; ok {
+			target.Explicit = &explicit
+		}
+		if learned, ok := targets.learned[userID]; ok {
+			target.Learned = &learned
+		}
+		target.Ratings = targets.ratings[userID]
+	}
+	return target
+}
+
+// SetTarget stores the user's explicit target satisfaction; nil goes back to learning it. Rating
+// scales are fitted to the new target by the next refresh.
+func (d *SatisfactionTargetDirectory) SetTarget(userID string, target *float64) error {
+	if target != nil && (*target < SatisfactionTargetMin || *target > SatisfactionTargetMax) {
+		return ErrInvalidSatisfactionTarget
+	}
+	err := d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target_satisfaction", "updated_at"}),
+	}).Create(&models.User{ID: userID, TargetSatisfaction: target}).Error
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	updated := &satisfactionTargets{explicit: map[string]float64{}, learned: map[string]float64{}, ratings: map[string]int{}}
+	if current := d.targets.Load(); current != nil {
+		updated.learned, updated.ratings = current.learned, current.ratings
+		updated.neutral, updated.scales, updated.population = current.neutral, current.scales, current.population
+		for id, t := range current.explicit {
+			updated.explicit[id] = t
+		}
+	}
+	if target == nil {
+		delete(updated.explicit, userID)
+	} else {
+		updated.explicit[userID] = *target
+	}
+	d.targets.Store(updated)
+	return nil
+}
+
+// ImpliedMultiplierFor is ImpliedMultiplier for a rating by a user whose perfect rating is target
+func ImpliedMultiplierFor(satisfaction, target float64) float64 {
+	return ImpliedMultiplier(neutralizeSatisfaction(satisfaction, target))
+}
+
+// neutralizeSatisfaction rescales a rating so the user's target reads as 50, stretching each
+// side of the target to keep the ends of the scale where they are
+func neutralizeSatisfaction(satisfaction, target float64) float64 {
+	switch {
+	case target == models.SatisfactionPerfect:
+		return satisfaction
+	case satisfaction == target:
+		return models.SatisfactionPerfect
+	case satisfaction < target:
+		return models.SatisfactionMin + (satisfaction-models.SatisfactionMin)*(models.SatisfactionPerfect-models.SatisfactionMin)/(target-models.SatisfactionMin)
+	default:
+		return models.SatisfactionPerfect + (satisfaction-target)*(models.SatisfactionMax-models.SatisfactionPerfect)/(models.SatisfactionMax-target)
+	}
+}
+
+// neutralizeRecords returns the records with each rating rescaled by its user's target. The
+// records may be shared with a cache, so they are copied when anything changes.
+func neutralizeRecords(records []models.DailyRecord, targets SatisfactionTargetLookup) []models.DailyRecord {
+	if targets == nil {
+		return records
+	}
+	var out []models.DailyRecord
+	for i, record := range records {
+		target := targets.Target(record.UserID)
+		if target == models.SatisfactionPerfect {
+			continue
+		}
+		if out == nil {
+			out = append([]models.DailyRecord(nil), records...)
+		}
+		out[i].Satisfaction = neutralizeSatisfaction(record.Satisfaction, target)
+	}
+	if out == nil {
+		return records
+	}
+	return out
+}
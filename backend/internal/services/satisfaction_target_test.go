@@ -0,0 +1,152 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestLearnSatisfactionTarget(t *testing.T) {
+	settled := func(center float64, n int) []float64 {
+		ratings := make([]float64, n)
+		for i := range ratings {
+			ratings[i] = center + float64(i%5) - 2
+		}
+		return ratings
+	}
+
+	target, ok := LearnSatisfactionTarget(settled(60, 30))
+	assert.True(t, ok)
+	assert.Equal(t, 60.0, target)
+
+	_, ok = LearnSatisfactionTarget(settled(60, 10))
+	assert.False(t, ok, "too few ratings")
+	_, ok = LearnSatisfactionTarget(settled(52, 30))
+	assert.False(t, ok, "close enough to 50")
+
+	// Ratings all over the scale haven't settled
+	spread := make([]float64, 30)
+	for i := range spread {
+		spread[i] = float64(20 + 2*i)
+	}
+	_, ok = LearnSatisfactionTarget(spread)
+	assert.False(t, ok)
+
+	target, ok = LearnSatisfactionTarget(settled(85, 30))
+	assert.True(t, ok)
+	assert.Equal(t, float64(SatisfactionTargetMax), target)
+}
+
+func TestNeutralizeSatisfaction(t *testing.T) {
+	assert.Equal(t, 50.0, neutralizeSatisfaction(60, 60))
+	assert.Equal(t, 1.0, neutralizeSatisfaction(1, 60))
+	assert.Equal(t, 100.0, neutralizeSatisfaction(100, 60))
+	assert.InDelta(t, 75, neutralizeSatisfaction(80, 60), 0.001)
+	assert.InDelta(t, 25.5, neutralizeSatisfaction(30.5, 60), 0.1)
+	assert.Equal(t, 42.0, neutralizeSatisfaction(42, 50))
+}
+
+func TestQuickFeedbackRecord_UserScale(t *testing.T) {
+	prediction := models.PredictionLog{ID: "p1", UserID: "user1", Duration: 8, Temperature: 12, HeatingTime: 20, CreatedAt: time.Now()}
+
+	perfect, err := QuickFeedbackRecord(prediction, QuickVerdictPerfect, 60)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, perfect.Satisfaction)
+	assert.Equal(t, prediction.CreatedAt.Add(20*time.Minute), perfect.Date)
+
+	for _, verdict := range []string{QuickVerdictTooCold, QuickVerdictTooHot} {
+		record, err := QuickFeedbackRecord(prediction, verdict, 60)
+		require.NoError(t, err)
+		assert.InDelta(t, quickVerdictSatisfaction[verdict], neutralizeSatisfaction(record.Satisfaction, 60), 1, verdict)
+	}
+
+	_, err = QuickFeedbackRecord(prediction, "lukewarm", 50)
+	assert.ErrorIs(t, err, ErrUnknownVerdict)
+}
+
+func TestSatisfactionTargetDirectory(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "targets.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	records := NewRecordService()
+	now := time.Now()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, records.CreateRecord(&models.DailyRecord{
+			UserID: "warm", Date: now.AddDate(0, 0, -i),
+			ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: float64(58 + i%5),
+		}))
+	}
+
+	targets := NewSatisfactionTargetDirectory()
+	require.NoError(t, targets.Refresh())
+	assert.Equal(t, 60.0, targets.Target("warm"))
+	assert.Equal(t, 50.0, targets.Target("someone"))
+	got := targets.Get("warm")
+	assert.Equal(t, 25, got.Ratings)
+	assert.Nil(t, got.Explicit)
+
+	// Rescaled by the target, the ratings center on 50; the only rater is the population
+	calibration := targets.Calibration("warm")
+	assert.Equal(t, 25, calibration.User.Ratings)
+	assert.Equal(t, calibration.User, calibration.Population)
+	assert.InDelta(t, 50, calibration.User.Median, 1)
+	require.NotNil(t, calibration.Scale)
+	assert.Equal(t, 1.0, *calibration.Scale)
+	assert.Equal(t, 0.0, *calibration.Offset)
+	assert.False(t, calibration.Applied)
+	assert.Equal(t, 0, targets.Calibration("someone").User.Ratings)
+
+	// An explicit target wins until it is cleared
+	target := 65.0
+	require.NoError(t, targets.SetTarget("warm", &target))
+	assert.Equal(t, 65.0, targets.Target("warm"))
+	require.NoError(t, targets.Refresh())
+	assert.Equal(t, 65.0, targets.Target("warm"))
+	require.NoError(t, targets.SetTarget("warm", nil))
+	assert.Equal(t, 60.0, targets.Target("warm"))
+
+	target = 80
+	assert.ErrorIs(t, targets.SetTarget("warm", &target), ErrInvalidSatisfactionTarget)
+}
+
+func TestFitRatingScales(t *testing.T) {
+	alternate := func(low, high float64, n int) []float64 {
+		ratings := make([]float64, n)
+		for i := range ratings {
+			ratings[i] = low
+			if i%2 == 1 {
+				ratings[i] = high
+			}
+		}
+		return ratings
+	}
+	ratings := map[string][]float64{
+		"timid": alternate(45, 55, 20), // barely moves the slider
+		"bold":  alternate(20, 80, 20), // swings it all the way
+		"new":   alternate(20, 80, 5),
+	}
+	targets := &satisfactionTargets{}
+	targets.fitRatingScales(ratings, func(string) float64 { return models.SatisfactionPerfect })
+
+	assert.Equal(t, 45, targets.population.Ratings)
+	assert.InDelta(t, 18.89, targets.population.Spread, 0.01)
+	assert.Equal(t, 2.0, targets.scales["timid"], "stretching is capped")
+	assert.Equal(t, 0.63, targets.scales["bold"])
+	_, fitted := targets.scales["new"]
+	assert.False(t, fitted, "too few ratings")
+
+	records := calibrateRecords([]models.DailyRecord{
+		{UserID: "timid", Satisfaction: 45}, {UserID: "bold", Satisfaction: 80}, {UserID: "new", Satisfaction: 80},
+	}, targets)
+	assert.Equal(t, 40.0, records[0].Satisfaction)
+	assert.InDelta(t, 68.9, records[1].Satisfaction, 0.01)
+	assert.Equal(t, 80.0, records[2].Satisfaction)
+}
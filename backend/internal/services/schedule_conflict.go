@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"heat-logger/internal/models"
+)
+
+// scheduleConflictDays is how many days ahead a template is checked against the others. Two
+// weeks covers every weekday and weekend pattern.
+const scheduleConflictDays = 14
+
+// scheduleSuggestionStep rounds suggested shower times to a clock time people would pick
+const scheduleSuggestionStep = 5 * time.Minute
+
+// ScheduleConflict is another template whose showers draw on the same boiler while one of the
+// template's own showers is still running
+type ScheduleConflict struct {
+	TemplateID   string               `json:"templateId"`
+	Name         string               `json:"name"`
+	UserID       string               `json:"userId"`
+	Dates        []string             `json:"dates"`        // days in the next two weeks the showers overlap
+	ReadyBy      time.Time            `json:"readyBy"`      // the checked template's first overlapping shower
+	OtherReadyBy time.Time            `json:"otherReadyBy"` // the other template's shower it overlaps
+	Suggestions  []ScheduleSuggestion `json:"suggestions,omitempty"`
+}
+
+// ScheduleSuggestion is a time for the checked template's shower that staggers it with the
+// other one, leaving the tank time to reheat in between
+type ScheduleSuggestion struct {
+	Time          string    `json:"time"` // HH:MM in the template's time zone
+	ReadyBy       time.Time `json:"readyBy"`
+	ReheatMinutes float64   `json:"reheatMinutes"` // heating needed between the two showers
+}
+
+// ScheduleConflictService finds household schedules that would have two people showering on one
+// boiler at once, and staggers them with the sequence planner
+type ScheduleConflictService struct {
+	templates    *ScheduleTemplateService
+	plans        *ShowerPlanService
+	temperatures *TemperatureService
+	boilers      *BoilerService
+	records      *RecordService
+}
+
+// NewScheduleConflictService creates a schedule conflict service
+func NewScheduleConflictService(templates *ScheduleTemplateService, plans *ShowerPlanService, temperatures *TemperatureService, boilers *BoilerService, records *RecordService) *ScheduleConflictService {
+	return &ScheduleConflictService{
+		templates:    templates,
+		plans:        plans,
+		temperatures: temperatures,
+		boilers:      boilers,
+		records:      records,
+	}
+}
+
+// Check compares the template, saved or not, with the other templates of the user's household on
+// the same device. Showers overlap when one starts before the other, at its user's typical
+// length, is over.
+func (s *ScheduleConflictService) Check(ctx context.Context, template models.ScheduleTemplate, from time.Time) ([]ScheduleConflict, error) {
+	others, err := s.templates.ListHouseholdTemplates(template.UserID)
+	if err != nil {
+		return nil, err
+	}
+	to := from.AddDate(0, 0, scheduleConflictDays)
+	durations := make(map[string]float64)
+	durationOf := func(userID string) (float64, error) {
+		if duration, ok := durations[userID]; ok {
+			return duration, nil
+		}
+		duration, err := typicalDuration(s.records, userID)
+		durations[userID] = duration
+		return duration, err
+	}
+	duration, err := durationOf(template.UserID)
+	if err != nil {
+		return nil, err
+	}
+	own := readyTimesBetween(template, from, to)
+
+	conflicts := []ScheduleConflict{}
+	for _, other := range others {
+		if other.ID == template.ID || other.DeviceID != template.DeviceID {
+			continue
+		}
+		otherDuration, err := durationOf(other.UserID)
+		if err != nil {
+			return nil, err
+		}
+		var conflict *ScheduleConflict
+		for _, theirs := range readyTimesBetween(other, from, to) {
+			for _, mine := range own {
+				if !mine.Before(theirs.Add(minutes(otherDuration))) || !theirs.Before(mine.Add(minutes(duration))) {
+					continue
+				}
+				if conflict == nil {
+					conflict = &ScheduleConflict{TemplateID: other.ID, Name: other.Name, UserID: other.UserID, ReadyBy: mine, OtherReadyBy: theirs}
+				}
+				if date := mine.Format("2006-01-02"); len(conflict.Dates) == 0 || conflict.Dates[len(conflict.Dates)-1] != date {
+					conflict.Dates = append(conflict.Dates, date)
+				}
+			}
+		}
+		if conflict == nil {
+			continue
+		}
+		if conflict.Suggestions, err = s.suggest(ctx, template, conflict.OtherReadyBy, duration, otherDuration); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, *conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].ReadyBy.Before(conflicts[j].ReadyBy) })
+	return conflicts, nil
+}
+
+// suggest plans the two showers back to back both ways round: the template's shower late enough
+// after the other one for the tank to reheat, or early enough before it. Without a temperature
+// to plan at there are no suggestions.
+func (s *ScheduleConflictService) suggest(ctx context.Context, template models.ScheduleTemplate, theirs time.Time, duration, otherDuration float64) ([]ScheduleSuggestion, error) {
+	temperature, err := s.temperatures.ForecastAt(ctx, theirs)
+	if err != nil {
+		if temperature, err = s.temperatures.Resolve(ctx, TemperatureQuery{UserID: template.UserID}); err != nil {
+			return nil, nil
+		}
+	}
+	plan := func(first, second float64) (float64, error) {
+		plan, err := s.plans.Plan(ctx, SequenceRequest{
+			UserID:      template.UserID,
+			Temperature: temperature.Value,
+			DeviceID:    template.DeviceID,
+			Showers:     []SequenceShower{{Duration: first}, {Duration: second}},
+			Tank:        s.boilers.Tank(template.UserID, template.DeviceID),
+		})
+		if err != nil {
+			return 0, err
+		}
+		return plan.Showers[1].ReheatMinutes, nil
+	}
+
+	loc := template.Location(time.Local)
+	after, err := plan(otherDuration, duration)
+	if err != nil {
+		return nil, err
+	}
+	before, err := plan(duration, otherDuration)
+	if err != nil {
+		return nil, err
+	}
+	later := roundUpTo(theirs.Add(minutes(otherDuration+after)), scheduleSuggestionStep).In(loc)
+	earlier := theirs.Add(-minutes(duration + before)).Truncate(scheduleSuggestionStep).In(loc)
+	return []ScheduleSuggestion{
+		{Time: earlier.Format("15:04"), ReadyBy: earlier, ReheatMinutes: before},
+		{Time: later.Format("15:04"), ReadyBy: later, ReheatMinutes: after},
+	}, nil
+}
+
+// readyTimesBetween returns when the template has the water ready in [from, to), ignoring skips
+// and snoozes
+func readyTimesBetween(template models.ScheduleTemplate, from, to time.Time) []time.Time {
+	loc := template.Location(time.Local)
+	from, to = from.In(loc), to.In(loc)
+	var times []time.Time
+	for day := startOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if readyBy, ok := template.ReadyAt(day); ok && !readyBy.Before(from) && readyBy.Before(to) {
+			times = append(times, readyBy)
+		}
+	}
+	return times
+}
+
+// minutes converts fractional minutes to a duration
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+// roundUpTo rounds t up to a multiple of step
+func roundUpTo(t time.Time, step time.Duration) time.Time {
+	if rounded := t.Truncate(step); !rounded.Equal(t) {
+		return rounded.Add(step)
+	}
+	return t
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrScheduleTemplateNotFound is returned when a schedule template does not exist for the user
+	ErrScheduleTemplateNotFound = errors.New("schedule template not found")
+	// ErrScheduleTemplateNameTaken is returned when the user already has a template with the same name
+	ErrScheduleTemplateNameTaken = errors.New("a schedule template with this name already exists")
+	// ErrNoScheduledShower is returned when none of the user's templates has a shower coming up
+	ErrNoScheduledShower = errors.New("no scheduled shower")
+	// ErrSnoozePastShower is returned when a snooze would start heating after the water is needed
+	ErrSnoozePastShower = errors.New("snooze would delay heating past the shower")
+)
+
+// scheduleHorizon is how far ahead the next scheduled shower is looked for
+const scheduleHorizon = 366
+
+// ScheduleTemplateService handles recurring shower schedules
+type ScheduleTemplateService struct {
+	db         *gorm.DB
+	households HouseholdLookup
+}
+
+// NewScheduleTemplateService creates a new schedule template service instance
+func NewScheduleTemplateService() *ScheduleTemplateService {
+	return &ScheduleTemplateService{
+		db: database.GetDB(),
+	}
+}
+
+// ScheduledShower is one occurrence of a template
+type ScheduledShower struct {
+	TemplateID   string     `json:"templateId"`
+	Name         string     `json:"name"`
+	DeviceID     string     `json:"deviceId,omitempty"`
+	Date         string     `json:"date"` // YYYY-MM-DD
+	ReadyBy      time.Time  `json:"readyBy"`
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty"` // heating doesn't start before this
+}
+
+// CreateTemplate stores a new template with its exceptions
+func (s *ScheduleTemplateService) CreateTemplate(template *models.ScheduleTemplate) error {
+	if err := s.ensureNameFree(template.UserID, template.Name, ""); err != nil {
+		return err
+	}
+	return s.db.Create(template).Error
+}
+
+// ListTemplates returns the user's templates ordered by name
+func (s *ScheduleTemplateService) ListTemplates(userID string) ([]models.ScheduleTemplate, error) {
+	var templates []models.ScheduleTemplate
+	err := s.db.Preload("Exceptions", func(db *gorm.DB) *gorm.DB { return db.Order("date ASC") }).
+		Where("user_id = ?", userID).Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+// SetHouseholdLookup has users see the templates of everyone in their household
+func (s *ScheduleTemplateService) SetHouseholdLookup(households HouseholdLookup) {
+	s.households = households
+}
+
+// ListHouseholdTemplates returns the templates of everyone in the user's household ordered by
+// name, or only the user's without a household lookup. The others' templates can be read but
+// only changed by whoever created them.
+func (s *ScheduleTemplateService) ListHouseholdTemplates(userID string) ([]models.ScheduleTemplate, error) {
+	if s.households == nil {
+		return s.ListTemplates(userID)
+	}
+	userIDs, err := s.households.HouseholdUserIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	var templates []models.ScheduleTemplate
+	err = s.db.Preload("Exceptions", func(db *gorm.DB) *gorm.DB { return db.Order("date ASC") }).
+		Where("user_id IN ?", userIDs).Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+// GetTemplate retrieves one of the user's templates
+func (s *ScheduleTemplateService) GetTemplate(userID, id string) (*models.ScheduleTemplate, error) {
+	var template models.ScheduleTemplate
+	err := s.db.Preload("Exceptions", func(db *gorm.DB) *gorm.DB { return db.Order("date ASC") }).
+		Where("id = ? AND user_id = ?", id, userID).First(&template).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduleTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// UpdateTemplate overwrites an existing template, replacing its exceptions
+func (s *ScheduleTemplateService) UpdateTemplate(template *models.ScheduleTemplate) error {
+	existing, err := s.GetTemplate(template.UserID, template.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureNameFree(template.UserID, template.Name, template.ID); err != nil {
+		return err
+	}
+
+	existing.Name = template.Name
+	existing.DeviceID = template.DeviceID
+	existing.WeekdayTime = template.WeekdayTime
+	existing.WeekendTime = template.WeekendTime
+	existing.Cron = template.Cron
+	existing.Timezone = template.Timezone
+	existing.Exceptions = template.Exceptions
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", existing.ID).Delete(&models.ScheduleException{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(existing).Error
+	})
+	if err != nil {
+		return err
+	}
+	*template = *existing
+	return nil
+}
+
+// DeleteTemplate deletes one of the user's templates and its exceptions
+func (s *ScheduleTemplateService) DeleteTemplate(userID, id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ScheduleTemplate{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrScheduleTemplateNotFound
+		}
+		if err := tx.Where("template_id = ?", id).Delete(&models.ScheduleOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("template_id = ?", id).Delete(&models.ScheduleException{}).Error
+	})
+}
+
+// NextShower returns the first scheduled shower of any of the user's templates after after,
+// passing over skipped ones. Template times are in the server's time zone.
+func (s *ScheduleTemplateService) NextShower(userID string, after time.Time) (*ScheduledShower, error) {
+	return s.nextShower(userID, "", after)
+}
+
+// NextTemplateShower is NextShower limited to one template; an empty templateID means any
+func (s *ScheduleTemplateService) NextTemplateShower(userID, templateID string, after time.Time) (*ScheduledShower, error) {
+	return s.nextShower(userID, templateID, after)
+}
+
+func (s *ScheduleTemplateService) nextShower(userID, templateID string, after time.Time) (*ScheduledShower, error) {
+	templates, err := s.ListTemplates(userID)
+	if err != nil {
+		return nil, err
+	}
+	if templateID != "" {
+		var matching []models.ScheduleTemplate
+		for _, template := range templates {
+			if template.ID == templateID {
+				matching = append(matching, template)
+			}
+		}
+		if len(matching) == 0 {
+			return nil, ErrScheduleTemplateNotFound
+		}
+		templates = matching
+	}
+	overrides, err := s.overridesFrom(userID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var upcoming []ScheduledShower
+	for _, template := range templates {
+		// Days run in the template's time zone so its dates line up with its times
+		after := after.In(template.Location(time.Local))
+		for day := startOfDay(after); day.Before(after.AddDate(0, 0, scheduleHorizon)); day = day.AddDate(0, 0, 1) {
+			readyBy, ok := template.ReadyAt(day)
+			if !ok || !readyBy.After(after) {
+				continue
+			}
+			shower := ScheduledShower{TemplateID: template.ID, Name: template.Name, DeviceID: template.DeviceID, Date: day.Format("2006-01-02"), ReadyBy: readyBy}
+			if override, ok := overrides[template.ID+"/"+shower.Date]; ok {
+				if override.Skipped {
+					continue
+				}
+				shower.SnoozedUntil = override.SnoozedUntil
+			}
+			upcoming = append(upcoming, shower)
+			break
+		}
+	}
+	if len(upcoming) == 0 {
+		return nil, ErrNoScheduledShower
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].ReadyBy.Before(upcoming[j].ReadyBy) })
+	return &upcoming[0], nil
+}
+
+// Preview returns the template's next n shower times after after, taking its exceptions into
+// account but not skips or snoozes, so a user can check a schedule before saving it
+func Preview(template models.ScheduleTemplate, after time.Time, n int) []time.Time {
+	after = after.In(template.Location(time.Local))
+	times := []time.Time{}
+	for day := startOfDay(after); len(times) < n && day.Before(after.AddDate(0, 0, scheduleHorizon)); day = day.AddDate(0, 0, 1) {
+		if readyBy, ok := template.ReadyAt(day); ok && readyBy.After(after) {
+			times = append(times, readyBy)
+		}
+	}
+	return times
+}
+
+// ShowersBetween returns the user's scheduled showers with their water due in (from, to],
+// oldest first, leaving out skipped ones
+func (s *ScheduleTemplateService) ShowersBetween(userID string, from, to time.Time) ([]ScheduledShower, error) {
+	templates, err := s.ListTemplates(userID)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := s.overridesFrom(userID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var showers []ScheduledShower
+	for _, template := range templates {
+		loc := template.Location(time.Local)
+		from, to := from.In(loc), to.In(loc)
+		for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+			readyBy, ok := template.ReadyAt(day)
+			if !ok || !readyBy.After(from) || readyBy.After(to) {
+				continue
+			}
+			date := day.Format("2006-01-02")
+			if override, ok := overrides[template.ID+"/"+date]; ok && override.Skipped {
+				continue
+			}
+			showers = append(showers, ScheduledShower{TemplateID: template.ID, Name: template.Name, DeviceID: template.DeviceID, Date: date, ReadyBy: readyBy})
+		}
+	}
+	sort.Slice(showers, func(i, j int) bool { return showers[i].ReadyBy.Before(showers[j].ReadyBy) })
+	return showers, nil
+}
+
+// ScheduledUsers returns the IDs of users with at least one schedule template
+func (s *ScheduleTemplateService) ScheduledUsers() ([]string, error) {
+	var userIDs []string
+	err := s.db.Model(&models.ScheduleTemplate{}).Distinct().Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// ShowerOn returns the template's shower on date (YYYY-MM-DD), reporting ErrNoScheduledShower
+// when the template has none that day or it was skipped
+func (s *ScheduleTemplateService) ShowerOn(userID, templateID, date string) (*ScheduledShower, error) {
+	template, err := s.GetTemplate(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, template.Location(time.Local))
+	if err != nil {
+		return nil, ErrNoScheduledShower
+	}
+	readyBy, ok := template.ReadyAt(day)
+	if !ok {
+		return nil, ErrNoScheduledShower
+	}
+	shower := &ScheduledShower{TemplateID: template.ID, Name: template.Name, DeviceID: template.DeviceID, Date: date, ReadyBy: readyBy}
+	override, err := s.override(templateID, date)
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		if override.Skipped {
+			return nil, ErrNoScheduledShower
+		}
+		shower.SnoozedUntil = override.SnoozedUntil
+	}
+	return shower, nil
+}
+
+// Skip cancels heating for one shower. Records from that day are kept out of training, since
+// a cold shower after a skipped run says nothing about how long to heat.
+func (s *ScheduleTemplateService) Skip(userID string, shower *ScheduledShower) error {
+	return s.saveOverride(&models.ScheduleOverride{
+		UserID:     userID,
+		TemplateID: shower.TemplateID,
+		Date:       shower.Date,
+		Skipped:    true,
+	})
+}
+
+// Snooze holds heating for a shower back by minutes, counted from an earlier snooze that is
+// still running or otherwise from now
+func (s *ScheduleTemplateService) Snooze(userID string, shower *ScheduledShower, minutes int, now time.Time) error {
+	from := now
+	if shower.SnoozedUntil != nil && shower.SnoozedUntil.After(now) {
+		from = *shower.SnoozedUntil
+	}
+	until := from.Add(time.Duration(minutes) * time.Minute).Truncate(time.Second)
+	if !until.Before(shower.ReadyBy) {
+		return ErrSnoozePastShower
+	}
+	if err := s.saveOverride(&models.ScheduleOverride{
+		UserID:       userID,
+		TemplateID:   shower.TemplateID,
+		Date:         shower.Date,
+		SnoozedUntil: &until,
+	}); err != nil {
+		return err
+	}
+	shower.SnoozedUntil = &until
+	return nil
+}
+
+// SkippedOn reports whether the user skipped any scheduled heating on day's date
+func (s *ScheduleTemplateService) SkippedOn(userID string, day time.Time) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.ScheduleOverride{}).
+		Where("user_id = ? AND date = ? AND skipped = ?", userID, day.Local().Format("2006-01-02"), true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *ScheduleTemplateService) saveOverride(override *models.ScheduleOverride) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "template_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"skipped", "snoozed_until", "updated_at"}),
+	}).Create(override).Error
+}
+
+// override returns the template's override on date, or nil when there is none
+func (s *ScheduleTemplateService) override(templateID, date string) (*models.ScheduleOverride, error) {
+	var override models.ScheduleOverride
+	err := s.db.Where("template_id = ? AND date = ?", templateID, date).First(&override).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// overridesFrom loads the user's overrides from after's date on, keyed by template and date
+func (s *ScheduleTemplateService) overridesFrom(userID string, after time.Time) (map[string]models.ScheduleOverride, error) {
+	var overrides []models.ScheduleOverride
+	err := s.db.Where("user_id = ? AND date >= ?", userID, after.Local().Format("2006-01-02")).Find(&overrides).Error
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.ScheduleOverride, len(overrides))
+	for _, override := range overrides {
+		byKey[override.TemplateID+"/"+override.Date] = override
+	}
+	return byKey, nil
+}
+
+func (s *ScheduleTemplateService) ensureNameFree(userID, name, exceptID string) error {
+	var count int64
+	query := s.db.Model(&models.ScheduleTemplate{}).Where("user_id = ? AND LOWER(name) = ?", userID, strings.ToLower(name))
+	if exceptID != "" {
+		query = query.Where("id != ?", exceptID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrScheduleTemplateNameTaken
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionNotFound is returned when a session token is unknown or expired
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService manages browser login sessions
+type SessionService struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewSessionService creates a new session service instance
+func NewSessionService(ttl time.Duration) *SessionService {
+	return &SessionService{
+		db:  database.GetDB(),
+		ttl: ttl,
+	}
+}
+
+// Create starts a session for the user and returns the token to hand to the browser
+func (s *SessionService) Create(userID, role string) (string, *models.Session, error) {
+	// Opportunistically clear out old logins so the table doesn't grow unbounded
+	if err := s.PurgeExpired(); err != nil {
+		return "", nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(raw)
+
+	session := &models.Session{
+		ID:        hashSessionToken(token),
+		UserID:    userID,
+		Role:      role,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.db.Create(session).Error; err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// Lookup returns the unexpired session for a token
+func (s *SessionService) Lookup(token string) (*models.Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+	var session models.Session
+	err := s.db.Where("id = ? AND expires_at > ?", hashSessionToken(token), time.Now()).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Delete ends the session for a token
+func (s *SessionService) Delete(token string) error {
+	return s.db.Where("id = ?", hashSessionToken(token)).Delete(&models.Session{}).Error
+}
+
+// PurgeExpired removes sessions past their expiry
+func (s *SessionService) PurgeExpired() error {
+	return s.db.Where("expires_at <= ?", time.Now()).Delete(&models.Session{}).Error
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setup steps, in the order the wizard offers them
+const (
+	SetupStepAdmin    = "admin"    // create the admin user
+	SetupStepDatabase = "database" // confirm the database backend
+	SetupStepLocation = "location" // locate the house for the weather provider, or skip it
+	SetupStepDevice   = "device"   // create the first water heater profile
+)
+
+// SetupSteps are the steps the wizard needs done before it can finish
+var SetupSteps = []string{SetupStepAdmin, SetupStepDatabase, SetupStepLocation, SetupStepDevice}
+
+// DatabaseBackends are the database backends this build can run on
+var DatabaseBackends = []string{"sqlite"}
+
+var (
+	// ErrSetupComplete is returned for a setup step after the wizard finished
+	ErrSetupComplete = errors.New("setup is already complete")
+	// ErrSetupAdminExists is returned when the admin step is run a second time
+	ErrSetupAdminExists = errors.New("the setup admin was already created")
+	// ErrSetupStepMissing is returned when a step, or finishing, needs a step that isn't done
+	ErrSetupStepMissing = errors.New("setup step isn't done yet")
+	// ErrUnsupportedDatabase is returned for a database backend this build doesn't include
+	ErrUnsupportedDatabase = errors.New("database backend isn't available in this build")
+	// ErrInvalidLocation is returned for coordinates outside the globe, or only one of them
+	ErrInvalidLocation = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+)
+
+// SetupStepStatus is whether one step of the wizard is done
+type SetupStepStatus struct {
+	Name   string     `json:"name"`
+	Done   bool       `json:"done"`
+	DoneAt *time.Time `json:"doneAt,omitempty"`
+}
+
+// SetupStatus is how far the first-run setup got
+type SetupStatus struct {
+	Required      bool              `json:"required"` // routes besides /api/setup are closed until it is complete
+	Complete      bool              `json:"complete"`
+	CompletedAt   *time.Time        `json:"completedAt,omitempty"`
+	AutoCompleted bool              `json:"autoCompleted,omitempty"` // the install had data before the wizard existed
+	Steps         []SetupStepStatus `json:"steps"`
+	Next          string            `json:"next,omitempty"` // the first step not done yet
+
+	DatabaseBackends []string       `json:"databaseBackends"`
+	BoilerPresets    []BoilerPreset `json:"boilerPresets"`
+}
+
+// SetupService runs the first-run setup wizard. The admin step hands out a token that the
+// other steps, and later the admin API, accept. Until the wizard is finished every other route
+// is closed, so a fresh install isn't used half configured. Installs that already have users or
+// records are taken as set up the first time the wizard runs.
+type SetupService struct {
+	db           *gorm.DB
+	cfg          config.SetupConfig
+	weather      config.WeatherConfig
+	boilers      *BoilerService
+	temperatures *TemperatureService
+	now          func() time.Time
+
+	mu    sync.RWMutex
+	state models.Setup
+}
+
+// NewSetupService creates a new setup service instance; Load reads how far setup got
+func NewSetupService(cfg config.SetupConfig, weather config.WeatherConfig, boilers *BoilerService, temperatures *TemperatureService) *SetupService {
+	return &SetupService{
+		db:           database.GetDB(),
+		cfg:          cfg,
+		weather:      weather,
+		boilers:      boilers,
+		temperatures: temperatures,
+		now:          time.Now,
+	}
+}
+
+// Load reads how far setup got. A location chosen during setup is handed to the weather
+// provider unless one is configured.
+func (s *SetupService) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := s.db.Where("id = ?", 1).Limit(1).Find(&s.state)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 && s.cfg.Wizard {
+		if err := s.adoptExistingInstall(); err != nil {
+			return err
+		}
+	}
+	if s.state.Latitude != nil && s.state.Longitude != nil && !s.weatherConfigured() {
+		s.temperatures.SetWeatherLocation(*s.state.Latitude, *s.state.Longitude)
+	}
+	return nil
+}
+
+// SetClock sets the clock steps are stamped with
+func (s *SetupService) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// adoptExistingInstall marks setup complete for an install that has data from before the
+// wizard, so upgrading doesn't lock it out; callers hold mu
+func (s *SetupService) adoptExistingInstall() error {
+	var users, records int64
+	if err := s.db.Model(&models.User{}).Count(&users).Error; err != nil {
+		return err
+	}
+	if err := s.db.Model(&models.DailyRecord{}).Count(&records).Error; err != nil {
+		return err
+	}
+	if users == 0 && records == 0 {
+		return nil
+	}
+	now := s.now()
+	s.state = models.Setup{ID: 1, CompletedAt: &now, AutoCompleted: true}
+	return s.db.Create(&s.state).Error
+}
+
+// weatherConfigured reports whether WEATHER_LATITUDE and WEATHER_LONGITUDE locate the house,
+// which take precedence over the location chosen during setup
+func (s *SetupService) weatherConfigured() bool {
+	return s.weather.Latitude != nil && s.weather.Longitude != nil
+}
+
+// Complete reports whether routes besides /api/setup may be used: the wizard is off or finished
+func (s *SetupService) Complete() bool {
+	if !s.cfg.Wizard {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.CompletedAt != nil
+}
+
+// Status reports every step and whether the wizard is finished
+func (s *SetupService) Status() *SetupStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status := &SetupStatus{
+		Required:         s.cfg.Wizard && s.state.CompletedAt == nil,
+		Complete:         s.state.CompletedAt != nil,
+		CompletedAt:      s.state.CompletedAt,
+		AutoCompleted:    s.state.AutoCompleted,
+		DatabaseBackends: DatabaseBackends,
+		BoilerPresets:    BoilerPresets,
+	}
+	for _, step := range SetupSteps {
+		doneAt := s.doneAt(step)
+		status.Steps = append(status.Steps, SetupStepStatus{Name: step, Done: doneAt != nil, DoneAt: doneAt})
+		if doneAt == nil && status.Next == "" {
+			status.Next = step
+		}
+	}
+	return status
+}
+
+// doneAt is when a step was done, nil while it isn't; callers hold mu
+func (s *SetupService) doneAt(step string) *time.Time {
+	switch step {
+	case SetupStepAdmin:
+		return s.state.AdminAt
+	case SetupStepDatabase:
+		return s.state.DatabaseAt
+	case SetupStepLocation:
+		return s.state.LocationAt
+	case SetupStepDevice:
+		return s.state.DeviceAt
+	}
+	return nil
+}
+
+// AdminByToken returns the admin the setup token was handed to
+func (s *SetupService) AdminByToken(token string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if token == "" || s.state.AdminTokenHash == "" {
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSessionToken(token)), []byte(s.state.AdminTokenHash)) != 1 {
+		return "", false
+	}
+	return s.state.AdminUserID, true
+}
+
+// HasAdmin reports whether the admin step is done, after which the other steps need its token
+func (s *SetupService) HasAdmin() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.AdminAt != nil
+}
+
+// update applies change to a copy of the state and stores it, refusing once setup is complete
+// or when the wizard is off. The state only changes when the store succeeds.
+func (s *SetupService) update(change func(state *models.Setup) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.cfg.Wizard || s.state.CompletedAt != nil {
+		return ErrSetupComplete
+	}
+	state := s.state
+	state.ID = 1
+	if err := change(&state); err != nil {
+		return err
+	}
+	if err := s.db.Save(&state).Error; err != nil {
+		return err
+	}
+	s.state = state
+	return nil
+}
+
+// requireAdmin fails the other steps until the admin step is done, since they need its token
+func requireAdmin(state *models.Setup) error {
+	if state.AdminAt == nil {
+		return fmt.Errorf("%w: %s", ErrSetupStepMissing, SetupStepAdmin)
+	}
+	return nil
+}
+
+// CreateAdmin creates the admin user and returns the admin token, which is only shown once.
+// It works once; the token stays valid for the admin API after setup.
+func (s *SetupService) CreateAdmin(userID, name, email string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	err := s.update(func(state *models.Setup) error {
+		if state.AdminAt != nil {
+			return ErrSetupAdminExists
+		}
+		err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role", "name", "email", "updated_at"}),
+		}).Create(&models.User{ID: userID, Role: models.RoleAdmin, Name: name, Email: email}).Error
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		state.AdminUserID, state.AdminTokenHash, state.AdminAt = userID, hashSessionToken(token), &now
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ChooseDatabase records the database backend. Only backends this build includes are accepted,
+// and the database in use is the one DATABASE_PATH points at.
+func (s *SetupService) ChooseDatabase(backend string) error {
+	supported := false
+	for _, available := range DatabaseBackends {
+		supported = supported || available == backend
+	}
+	if !supported {
+		return fmt.Errorf("%w: %q", ErrUnsupportedDatabase, backend)
+	}
+	return s.update(func(state *models.Setup) error {
+		if err := requireAdmin(state); err != nil {
+			return err
+		}
+		now := s.now()
+		state.DatabaseBackend, state.DatabaseAt = backend, &now
+		return nil
+	})
+}
+
+// SetLocation locates the house and enables the weather provider with it, unless the
+// configuration locates it already. Without coordinates the weather provider is skipped.
+func (s *SetupService) SetLocation(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return ErrInvalidLocation
+	}
+	if latitude != nil && (*latitude < -90 || *latitude > 90 || *longitude < -180 || *longitude > 180) {
+		return ErrInvalidLocation
+	}
+	err := s.update(func(state *models.Setup) error {
+		if err := requireAdmin(state); err != nil {
+			return err
+		}
+		now := s.now()
+		state.Latitude, state.Longitude, state.LocationAt = latitude, longitude, &now
+		return nil
+	})
+	if err == nil && latitude != nil && !s.weatherConfigured() {
+		s.temperatures.SetWeatherLocation(*latitude, *longitude)
+	}
+	return err
+}
+
+// CreateDevice sets up the first water heater from a boiler preset
+func (s *SetupService) CreateDevice(userID, deviceID, preset string) (*models.BoilerProfile, error) {
+	var profile *models.BoilerProfile
+	err := s.update(func(state *models.Setup) error {
+		if err := requireAdmin(state); err != nil {
+			return err
+		}
+		var err error
+		if profile, err = s.boilers.ApplyPreset(userID, deviceID, preset); err != nil {
+			return err
+		}
+		now := s.now()
+		state.DeviceUserID, state.DeviceID, state.DeviceAt = userID, deviceID, &now
+		return nil
+	})
+	return profile, err
+}
+
+// Finish completes setup once every step is done, opening the other routes
+func (s *SetupService) Finish() error {
+	return s.update(func(state *models.Setup) error {
+		for _, step := range SetupSteps {
+			if s.doneAt(step) == nil {
+				return fmt.Errorf("%w: %s", ErrSetupStepMissing, step)
+			}
+		}
+		now := s.now()
+		state.CompletedAt = &now
+		return nil
+	})
+}
+
+// AdminUserID is the user the admin step created, empty before it
+func (s *SetupService) AdminUserID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.AdminUserID
+}
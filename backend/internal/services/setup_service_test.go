@@ -0,0 +1,57 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestSetupService_AdoptsExistingInstall(t *testing.T) {
+	logger.Default = logger.Discard
+	require.NoError(t, database.InitDatabase(&config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "setup.db")}}))
+	require.NoError(t, database.GetDB().Create(&models.DailyRecord{UserID: "user1", Date: time.Now(), ShowerDuration: 10, HeatingTime: 20, Satisfaction: 50}).Error)
+
+	setup := NewSetupService(config.SetupConfig{Wizard: true}, config.WeatherConfig{}, nil, NewTemperatureService(config.TemperatureConfig{}))
+	require.NoError(t, setup.Load())
+	assert.True(t, setup.Complete(), "an install with records isn't locked out by an upgrade")
+	assert.True(t, setup.Status().AutoCompleted)
+	_, err := setup.CreateAdmin("intruder", "", "")
+	assert.ErrorIs(t, err, ErrSetupComplete)
+}
+
+func TestSetupService_Location(t *testing.T) {
+	logger.Default = logger.Discard
+	require.NoError(t, database.InitDatabase(&config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "setup.db")}}))
+
+	temperatures := NewTemperatureService(config.TemperatureConfig{})
+	setup := NewSetupService(config.SetupConfig{Wizard: true}, config.WeatherConfig{}, nil, temperatures)
+	require.NoError(t, setup.Load())
+	assert.False(t, setup.Complete(), "a fresh install waits for setup")
+
+	latitude, longitude := 32.1, 34.8
+	assert.ErrorIs(t, setup.SetLocation(&latitude, &longitude), ErrSetupStepMissing, "the admin comes first")
+	_, err := setup.CreateAdmin("owner", "", "")
+	require.NoError(t, err)
+	require.NoError(t, setup.SetLocation(&latitude, &longitude))
+	assert.True(t, temperatures.HasWeather(), "the weather provider is enabled straight away")
+	assert.ErrorIs(t, setup.Finish(), ErrSetupStepMissing)
+
+	// After a restart the location is handed to the weather provider again
+	temperatures = NewTemperatureService(config.TemperatureConfig{})
+	require.NoError(t, NewSetupService(config.SetupConfig{Wizard: true}, config.WeatherConfig{}, nil, temperatures).Load())
+	assert.True(t, temperatures.HasWeather())
+
+	// The wizard does nothing when it is off
+	off := NewSetupService(config.SetupConfig{}, config.WeatherConfig{}, nil, temperatures)
+	require.NoError(t, off.Load())
+	assert.True(t, off.Complete())
+	assert.ErrorIs(t, off.SetLocation(nil, nil), ErrSetupComplete)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// Kinds of resource a share link can grant access to
+const (
+	ShareKindStats  = "stats"
+	ShareKindExport = "export"
+	// Action links act on one scheduled shower instead of reading anything
+	ShareKindSnooze = "snooze"
+	ShareKindSkip   = "skip"
+)
+
+var (
+	// ErrShareLinkInvalid is returned when a share token is malformed or its signature doesn't match
+	ErrShareLinkInvalid = errors.New("invalid share link")
+	// ErrShareLinkExpired is returned when a share token is past its expiry
+	ErrShareLinkExpired = errors.New("share link has expired")
+)
+
+// ShareGrant is the read-only access encoded in a share link
+type ShareGrant struct {
+	Kind      string `json:"k"`
+	UserID    string `json:"u"`
+	Target    string `json:"t,omitempty"` // export job ID for export links; template ID/date[/minutes] for action links
+	ExpiresAt int64  `json:"e"`           // unix seconds
+}
+
+// ShareService signs and verifies expiring read-only links. Links are stateless: anyone
+// holding one can read the resource until it expires, and rotating the secret revokes all of them.
+type ShareService struct {
+	secret []byte
+	maxTTL time.Duration
+}
+
+// NewShareService creates a share service. With an empty secret a random one is generated,
+// which means links stop working when the server restarts.
+func NewShareService(secret string, maxTTL time.Duration) *ShareService {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("failed to generate share link secret: " + err.Error())
+		}
+		log.Println("SHARE_LINK_SECRET is not set; share links will not survive a restart")
+	}
+	return &ShareService{
+		secret: key,
+		maxTTL: maxTTL,
+	}
+}
+
+// Sign creates a token for the grant, valid for ttl (capped at the configured maximum)
+func (s *ShareService) Sign(kind, userID, target string, ttl time.Duration) (string, time.Time) {
+	if ttl <= 0 || ttl > s.maxTTL {
+		ttl = s.maxTTL
+	}
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	payload, _ := json.Marshal(ShareGrant{
+		Kind:      kind,
+		UserID:    userID,
+		Target:    target,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signature(encoded), expiresAt
+}
+
+// Verify checks a token's signature and expiry and returns its grant
+func (s *ShareService) Verify(token string) (*ShareGrant, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.signature(encoded))) {
+		return nil, ErrShareLinkInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	var grant ShareGrant
+	if err := json.Unmarshal(payload, &grant); err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	if time.Now().Unix() >= grant.ExpiresAt {
+		return nil, ErrShareLinkExpired
+	}
+	return &grant, nil
+}
+
+func (s *ShareService) signature(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
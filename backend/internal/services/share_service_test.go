@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareService_SignAndVerify(t *testing.T) {
+	service := NewShareService("secret", 24*time.Hour)
+
+	token, expiresAt := service.Sign(ShareKindExport, "user1", "job1", 0)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiresAt, 2*time.Second)
+
+	grant, err := service.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, ShareKindExport, grant.Kind)
+	assert.Equal(t, "user1", grant.UserID)
+	assert.Equal(t, "job1", grant.Target)
+
+	// Tampering with the payload or using another secret invalidates the link
+	_, err = service.Verify("x" + token)
+	assert.ErrorIs(t, err, ErrShareLinkInvalid)
+	_, err = NewShareService("other", time.Hour).Verify(token)
+	assert.ErrorIs(t, err, ErrShareLinkInvalid)
+}
+
+func TestShareService_Expired(t *testing.T) {
+	service := NewShareService("secret", time.Hour)
+	token, _ := service.Sign(ShareKindStats, "user1", "", time.Nanosecond)
+
+	_, err := service.Verify(token)
+	assert.ErrorIs(t, err, ErrShareLinkExpired)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// humidityEndDrop is the share of a spike's rise the humidity has to fall from its peak before
+// the shower counts as over. Humidity peaks about when the water stops, so the peak is the end.
+const humidityEndDrop = 0.2
+
+// DetectedShower is a shower recognised in a bathroom's humidity readings
+type DetectedShower struct {
+	UserID   string    `json:"userId"`
+	DeviceID string    `json:"deviceId,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration float64   `json:"duration"` // minutes
+	Baseline float64   `json:"baseline"` // % relative humidity before the shower
+	Peak     float64   `json:"peak"`     // % relative humidity at its end
+	RecordID string    `json:"recordId,omitempty"`
+}
+
+type humidityReading struct {
+	value float64
+	at    time.Time
+}
+
+// humidityTrack is one bathroom's recent readings and the shower in progress, if any
+type humidityTrack struct {
+	readings []humidityReading // within the baseline window, oldest first
+	start    *time.Time
+	baseline float64
+	peak     float64
+	peakAt   time.Time
+}
+
+// ShowerDetectionService recognises showers in bathroom humidity readings and stores each one
+// as a sensor record for the user to review, with the measured duration and the heating time
+// they were last served. Like device state, the readings are kept in memory.
+type ShowerDetectionService struct {
+	cfg          config.ShowerDetectionConfig
+	records      *RecordService
+	temperatures *TemperatureService
+	predictions  *PredictionLogService
+
+	mu     sync.Mutex
+	tracks map[string]*humidityTrack
+}
+
+// NewShowerDetectionService creates a new shower detection service instance
+func NewShowerDetectionService(cfg config.ShowerDetectionConfig, records *RecordService, temperatures *TemperatureService, predictions *PredictionLogService) *ShowerDetectionService {
+	return &ShowerDetectionService{
+		cfg:          cfg,
+		records:      records,
+		temperatures: temperatures,
+		predictions:  predictions,
+		tracks:       make(map[string]*humidityTrack),
+	}
+}
+
+// Report takes a humidity reading from the bathroom of the user's device. When it completes a
+// shower, the shower is returned with the pending record created for it; the record is left
+// out when no heating time was served in the day before, as there is nothing to learn from.
+func (s *ShowerDetectionService) Report(ctx context.Context, userID, deviceID string, humidity float64, at time.Time) (*DetectedShower, error) {
+	if !s.cfg.Enabled() {
+		return nil, nil
+	}
+	s.mu.Lock()
+	key := userID + "\x00" + deviceID
+	track, ok := s.tracks[key]
+	if !ok {
+		track = &humidityTrack{}
+		s.tracks[key] = track
+	}
+	shower := track.add(humidityReading{value: humidity, at: at}, s.cfg)
+	s.mu.Unlock()
+	if shower == nil {
+		return nil, nil
+	}
+
+	shower.UserID = userID
+	shower.DeviceID = deviceID
+	return shower, s.createRecord(ctx, shower)
+}
+
+// add records a reading and returns the shower it completes, if any
+func (t *humidityTrack) add(reading humidityReading, cfg config.ShowerDetectionConfig) *DetectedShower {
+	var shower *DetectedShower
+	switch {
+	case t.start == nil:
+		baseline, ok := t.lowest()
+		if !ok || reading.value-baseline < cfg.Rise {
+			break
+		}
+		// The shower started after the last reading still near the baseline
+		start := reading.at
+		for i := len(t.readings) - 1; i >= 0; i-- {
+			if t.readings[i].value <= baseline+cfg.Rise/4 {
+				start = t.readings[i].at
+				break
+			}
+		}
+		t.start = &start
+		t.baseline = baseline
+		t.peak, t.peakAt = reading.value, reading.at
+
+	case reading.value > t.peak:
+		t.peak, t.peakAt = reading.value, reading.at
+		if reading.at.Sub(*t.start) > cfg.MaxDuration {
+			t.start = nil // still rising: the weather, not a shower
+		}
+
+	case t.peak-reading.value >= (t.peak-t.baseline)*humidityEndDrop:
+		duration := t.peakAt.Sub(*t.start)
+		if duration >= cfg.MinDuration && duration <= cfg.MaxDuration {
+			shower = &DetectedShower{
+				Start:    *t.start,
+				End:      t.peakAt,
+				Duration: roundTo(duration.Minutes(), 1),
+				Baseline: t.baseline,
+				Peak:     t.peak,
+			}
+		}
+		t.start = nil
+	}
+
+	t.readings = append(t.readings, reading)
+	for len(t.readings) > 0 && reading.at.Sub(t.readings[0].at) > cfg.Window {
+		t.readings = t.readings[1:]
+	}
+	return shower
+}
+
+// lowest returns the lowest reading in the window, false when there is none yet
+func (t *humidityTrack) lowest() (float64, bool) {
+	if len(t.readings) == 0 {
+		return 0, false
+	}
+	lowest := t.readings[0].value
+	for _, reading := range t.readings[1:] {
+		lowest = min(lowest, reading.value)
+	}
+	return lowest, true
+}
+
+// createRecord stores the shower as a sensor record, which waits for the user's review
+func (s *ShowerDetectionService) createRecord(ctx context.Context, shower *DetectedShower) error {
+	heatingTime, ok, err := s.predictions.LatestHeatingTime(shower.UserID, shower.DeviceID, shower.Start)
+	if err != nil || !ok {
+		return err
+	}
+	record := &models.DailyRecord{
+		UserID:         shower.UserID,
+		DeviceID:       shower.DeviceID,
+		Date:           shower.End,
+		ShowerDuration: shower.Duration,
+		HeatingTime:    heatingTime,
+		Satisfaction:   models.SatisfactionPerfect, // a guess until the user reviews the record
+		Source:         models.RecordSourceSensor,
+	}
+	if reading, err := s.temperatures.Resolve(ctx, TemperatureQuery{UserID: shower.UserID}); err == nil {
+		record.AverageTemperature = reading.Value
+	}
+	err = s.records.CreateRecord(record)
+	switch {
+	case errors.Is(err, ErrWriteBuffered):
+		return nil // stored once the database recovers
+	case err != nil:
+		return err
+	}
+	shower.RecordID = record.ID
+	return nil
+}
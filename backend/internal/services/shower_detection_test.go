@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func showerDetectionConfig() config.ShowerDetectionConfig {
+	return config.ShowerDetectionConfig{Rise: 10, Window: 10 * time.Minute, MinDuration: 2 * time.Minute, MaxDuration: 45 * time.Minute}
+}
+
+// feedHumidity adds one reading a minute and returns the showers they complete
+func feedHumidity(track *humidityTrack, start time.Time, values ...float64) []*DetectedShower {
+	var showers []*DetectedShower
+	for i, value := range values {
+		if shower := track.add(humidityReading{value: value, at: start.Add(time.Duration(i) * time.Minute)}, showerDetectionConfig()); shower != nil {
+			showers = append(showers, shower)
+		}
+	}
+	return showers
+}
+
+func TestHumidityTrack_DetectsShower(t *testing.T) {
+	start := time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC)
+	track := &humidityTrack{}
+
+	// Steady at 55%, the water runs from minute 3 to minute 11, then the room airs out
+	showers := feedHumidity(track, start, 55, 56, 55, 55, 62, 70, 76, 80, 83, 85, 86, 87, 86, 83, 79, 74)
+
+	require.Len(t, showers, 1)
+	assert.Equal(t, start.Add(3*time.Minute), showers[0].Start)
+	assert.Equal(t, start.Add(11*time.Minute), showers[0].End)
+	assert.Equal(t, 8.0, showers[0].Duration)
+	assert.Equal(t, 55.0, showers[0].Baseline)
+	assert.Equal(t, 87.0, showers[0].Peak)
+}
+
+func TestHumidityTrack_IgnoresShortAndSlowRises(t *testing.T) {
+	start := time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC)
+
+	// A minute of hot water at the sink
+	assert.Empty(t, feedHumidity(&humidityTrack{}, start, 50, 50, 50, 65, 60, 55, 52))
+
+	// Humidity creeping up with the weather never rises 10 points within the window
+	var creeping []float64
+	for i := 0; i < 60; i++ {
+		creeping = append(creeping, 50+float64(i)*0.5)
+	}
+	assert.Empty(t, feedHumidity(&humidityTrack{}, start, creeping...))
+}
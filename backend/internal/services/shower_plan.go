@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ErrEmptyShowerSequence is returned when a sequence has no showers to plan
+var ErrEmptyShowerSequence = errors.New("shower sequence is empty")
+
+// ShowerPlanService plans the heating for several showers taken back to back from one tank
+type ShowerPlanService struct {
+	predictor Predictor
+}
+
+// NewShowerPlanService creates a new shower plan service instance
+func NewShowerPlanService(predictor Predictor) *ShowerPlanService {
+	return &ShowerPlanService{
+		predictor: predictor,
+	}
+}
+
+// SequenceShower is one shower of a back-to-back sequence
+type SequenceShower struct {
+	MemberID string  `json:"memberId,omitempty"`
+	Duration float64 `json:"duration"`
+}
+
+// SequenceRequest describes showers taken one after the other under shared conditions
+type SequenceRequest struct {
+	UserID      string
+	Temperature float64
+	DeviceID    string
+	Showers     []SequenceShower
+	Tank        TankCapacity // the device's calibrated or configured capacity
+
+	InletTemperature *float64
+}
+
+// PlannedShower is a shower's place in the plan
+type PlannedShower struct {
+	SequenceShower
+	HeatingTime   float64 `json:"heatingTime"`   // what the shower would need on its own
+	ReheatMinutes float64 `json:"reheatMinutes"` // heating to run right before it because the tank ran short
+}
+
+// ShowerPlan is the heating plan for a sequence
+type ShowerPlan struct {
+	HeatingTime      float64         `json:"heatingTime"`      // heating before the first shower
+	TotalHeatingTime float64         `json:"totalHeatingTime"` // including reheats between showers
+	Showers          []PlannedShower `json:"showers"`
+}
+
+// Plan predicts each shower and works out when the tank has to be heated. The tank stores at
+// most a full tank's minutes of hot water, so the first heating fills it as far as the sequence
+// needs; whenever the water left over from earlier showers can't cover the next one, the heater
+// runs again for the shortfall before it.
+func (s *ShowerPlanService) Plan(ctx context.Context, req SequenceRequest) (*ShowerPlan, error) {
+	if len(req.Showers) == 0 {
+		return nil, ErrEmptyShowerSequence
+	}
+
+	plan := &ShowerPlan{Showers: make([]PlannedShower, len(req.Showers))}
+	for i, shower := range req.Showers {
+		prediction, err := s.predictor.Predict(ctx, PredictionRequest{
+			UserID:      req.UserID,
+			Duration:    shower.Duration,
+			Temperature: req.Temperature,
+			MemberID:    shower.MemberID,
+			// Heat left from the last session only helps the first shower
+			DeviceID:         deviceForShower(req.DeviceID, i),
+			InletTemperature: req.InletTemperature,
+		})
+		if err != nil {
+			return nil, err
+		}
+		plan.Showers[i] = PlannedShower{SequenceShower: shower, HeatingTime: prediction.HeatingTime}
+	}
+
+	// Minutes of hot water still to be heated for each shower
+	demand := make([]float64, len(plan.Showers))
+	var totalDemand float64
+	for i, shower := range plan.Showers {
+		demand[i] = shower.Duration
+		totalDemand += shower.Duration
+	}
+	capacity := req.Tank.Minutes()
+
+	// Filling the tank is paid at the heating rate of the showers the water is for
+	fill := func(from int, minutes float64) float64 {
+		var heating float64
+		for i := from; i < len(plan.Showers) && minutes > 0; i++ {
+			take := math.Min(minutes, demand[i])
+			demand[i] -= take
+			minutes -= take
+			heating += take * plan.Showers[i].HeatingTime / plan.Showers[i].Duration
+		}
+		return heating
+	}
+
+	// stored is the hot water in the tank, in shower minutes
+	stored := math.Min(totalDemand, capacity)
+	plan.HeatingTime = fill(0, stored)
+	for i := range plan.Showers {
+		if shortfall := plan.Showers[i].Duration - stored; shortfall > 0 {
+			// Refill for this shower and as many of the following ones as the tank holds. A
+			// shower longer than the whole tank keeps the heater running while it lasts.
+			refill := math.Max(shortfall, math.Min(capacity-stored, remainingDemand(demand, i)))
+			plan.Showers[i].ReheatMinutes = roundTo(fill(i, refill), 1)
+			stored += refill
+		}
+		stored -= plan.Showers[i].Duration
+		plan.TotalHeatingTime += plan.Showers[i].ReheatMinutes
+	}
+
+	plan.HeatingTime = roundTo(plan.HeatingTime, 1)
+	plan.TotalHeatingTime = roundTo(plan.TotalHeatingTime+plan.HeatingTime, 1)
+	return plan, nil
+}
+
+// deviceForShower passes the device to the first shower's prediction only
+func deviceForShower(deviceID string, index int) string {
+	if index > 0 {
+		return ""
+	}
+	return deviceID
+}
+
+// remainingDemand sums the hot water still to be heated from the given shower on
+func remainingDemand(demand []float64, from int) float64 {
+	var total float64
+	for _, d := range demand[from:] {
+		total += d
+	}
+	return total
+}
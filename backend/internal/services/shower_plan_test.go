@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// perMinutePredictor needs three minutes of heating per shower minute and records the devices it saw
+type perMinutePredictor struct {
+	devices []string
+}
+
+func (p *perMinutePredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	p.devices = append(p.devices, req.DeviceID)
+	return &PredictionResponse{HeatingTime: req.Duration * 3}, nil
+}
+
+func TestShowerPlanService_ReheatsWhenTankRunsShort(t *testing.T) {
+	predictor := &perMinutePredictor{}
+	service := NewShowerPlanService(predictor)
+
+	plan, err := service.Plan(context.Background(), SequenceRequest{
+		UserID: "user1", Temperature: 15, DeviceID: "boiler", Tank: TankCapacity{Size: 75, FlowRate: 3},
+		Showers: []SequenceShower{{MemberID: "a", Duration: 10}, {MemberID: "b", Duration: 10}, {MemberID: "c", Duration: 10}},
+	})
+	require.NoError(t, err)
+
+	// The tank holds 25 of the 30 minutes: the third shower waits for the last 5
+	assert.Equal(t, 75.0, plan.HeatingTime)
+	assert.Equal(t, []float64{0, 0, 15}, []float64{plan.Showers[0].ReheatMinutes, plan.Showers[1].ReheatMinutes, plan.Showers[2].ReheatMinutes})
+	assert.Equal(t, 90.0, plan.TotalHeatingTime)
+	assert.Equal(t, 30.0, plan.Showers[2].HeatingTime)
+
+	// Residual heat only counts towards the first shower
+	assert.Equal(t, []string{"boiler", "", ""}, predictor.devices)
+}
+
+func TestShowerPlanService_HeatsOnceWhenTankIsLargeEnough(t *testing.T) {
+	service := NewShowerPlanService(&perMinutePredictor{})
+
+	plan, err := service.Plan(context.Background(), SequenceRequest{
+		UserID: "user1", Temperature: 15, Tank: TankCapacity{Size: 120, FlowRate: 3},
+		Showers: []SequenceShower{{Duration: 8}, {Duration: 12}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, plan.HeatingTime)
+	assert.Equal(t, 60.0, plan.TotalHeatingTime)
+
+	_, err = service.Plan(context.Background(), SequenceRequest{UserID: "user1"})
+	assert.ErrorIs(t, err, ErrEmptyShowerSequence)
+}
+
+func TestTankCapacity_Enough(t *testing.T) {
+	tank := TankCapacity{Size: 80, FlowRate: 3}
+
+	enough, maxDuration := tank.Enough(20)
+	assert.True(t, enough)
+	assert.Equal(t, 26.6, maxDuration)
+
+	enough, _ = tank.Enough(30)
+	assert.False(t, enough)
+
+	// An unknown tank never warns
+	enough, _ = TankCapacity{}.Enough(60)
+	assert.True(t, enough)
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// Objectives predictions are held to
+const (
+	SLOAccuracy = "accuracy" // rated predictions whose satisfaction landed near a perfect 50
+	SLOLatency  = "latency"  // predictions answered in time
+)
+
+// sloWindows are the windows burn rates are reported over, besides the whole period: a high
+// burn rate over the short ones is a regression happening now, over the long ones a slow leak
+var sloWindows = []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// SLOWindow is how an objective fared over one window
+type SLOWindow struct {
+	Window    string  `json:"window"` // e.g. 1h, 6h, 1d, 30d
+	Events    int64   `json:"events"`
+	Bad       int64   `json:"bad"`
+	ErrorRate float64 `json:"errorRate"`
+	BurnRate  float64 `json:"burnRate"` // error rate over the allowed one; above 1 spends the budget before the period ends
+}
+
+// SLOObjective is one objective with its burn rates and the error budget left
+type SLOObjective struct {
+	Name            string      `json:"name"`
+	Target          float64     `json:"target"`    // share of events that must be good
+	Threshold       float64     `json:"threshold"` // satisfaction points for accuracy, milliseconds for latency
+	Windows         []SLOWindow `json:"windows"`   // shortest first, the whole period last
+	BudgetRemaining float64     `json:"budgetRemaining"`
+}
+
+// SLOReport is every tracked objective over the budget period
+type SLOReport struct {
+	Period     string         `json:"period"`
+	Objectives []SLOObjective `json:"objectives"`
+}
+
+// sloMinute counts the predictions answered in one minute
+type sloMinute struct {
+	minute int64 // Unix minute the counts belong to
+	events int64
+	slow   int64
+}
+
+// SLOService tracks the objectives predictions are held to and how fast their error budgets
+// burn. Accuracy is worked out from the prediction log: a prediction that got confirmed
+// feedback is good when the satisfaction is within the tolerance of 50. Latency is timed by
+// SLOPredictor around everything that serves a prediction and kept in memory per minute, so
+// it starts over on a restart.
+type SLOService struct {
+	db   *gorm.DB // the operational database, for feedback
+	logs *gorm.DB // the log store
+	cfg  config.SLOConfig
+	now  func() time.Time
+
+	mu      sync.Mutex
+	minutes []sloMinute // ring over the period
+	served  int64       // since start, for the Prometheus counter
+	slow    int64
+}
+
+// NewSLOService creates a new SLO service instance
+func NewSLOService(cfg config.SLOConfig) *SLOService {
+	s := &SLOService{
+		db:   database.GetDB(),
+		logs: database.GetLogDB(),
+		cfg:  cfg,
+		now:  time.Now,
+	}
+	if cfg.LatencyTarget > 0 {
+		s.minutes = make([]sloMinute, int(cfg.Period/time.Minute))
+	}
+	return s
+}
+
+// SetClock sets the clock the windows end at
+func (s *SLOService) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// ObserveLatency counts a prediction answered in took
+func (s *SLOService) ObserveLatency(took time.Duration) {
+	if len(s.minutes) == 0 {
+		return
+	}
+	minute := s.now().Unix() / 60
+	slow := took > s.cfg.LatencyThreshold
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket := &s.minutes[minute%int64(len(s.minutes))]
+	if bucket.minute != minute {
+		*bucket = sloMinute{minute: minute}
+	}
+	bucket.events++
+	s.served++
+	if slow {
+		bucket.slow++
+		s.slow++
+	}
+}
+
+// Report works out every tracked objective's burn rates and remaining budget
+func (s *SLOService) Report() (*SLOReport, error) {
+	report := &SLOReport{Period: sloWindowName(s.cfg.Period), Objectives: []SLOObjective{}}
+	var windows []time.Duration
+	for _, window := range sloWindows {
+		if window < s.cfg.Period {
+			windows = append(windows, window)
+		}
+	}
+	windows = append(windows, s.cfg.Period)
+	now := s.now()
+
+	if s.cfg.AccuracyTarget > 0 {
+		objective := SLOObjective{Name: SLOAccuracy, Target: s.cfg.AccuracyTarget, Threshold: s.cfg.AccuracyTolerance}
+		outcomes, err := s.accuracy(now.Add(-s.cfg.Period))
+		if err != nil {
+			return nil, err
+		}
+		for _, window := range windows {
+			var events, bad int64
+			for _, outcome := range outcomes {
+				if !outcome.at.Before(now.Add(-window)) {
+					events++
+					if !outcome.good {
+						bad++
+					}
+				}
+			}
+			objective.Windows = append(objective.Windows, s.window(window, events, bad, objective.Target))
+		}
+		objective.BudgetRemaining = budgetRemaining(objective.Windows)
+		report.Objectives = append(report.Objectives, objective)
+	}
+	if s.cfg.LatencyTarget > 0 {
+		objective := SLOObjective{Name: SLOLatency, Target: s.cfg.LatencyTarget, Threshold: float64(s.cfg.LatencyThreshold.Milliseconds())}
+		for _, window := range windows {
+			events, bad := s.latency(now, window)
+			objective.Windows = append(objective.Windows, s.window(window, events, bad, objective.Target))
+		}
+		objective.BudgetRemaining = budgetRemaining(objective.Windows)
+		report.Objectives = append(report.Objectives, objective)
+	}
+	return report, nil
+}
+
+// sloOutcome is a rated prediction: when it was served and whether it landed near 50
+type sloOutcome struct {
+	at   time.Time
+	good bool
+}
+
+// accuracy returns the predictions since since that got confirmed feedback. The feedback is
+// looked up separately, since the log may be kept apart from the records.
+func (s *SLOService) accuracy(since time.Time) ([]sloOutcome, error) {
+	var logged []models.PredictionLog
+	err := s.logs.Select("created_at", "record_id").
+		Where("created_at >= ? AND record_id <> ?", since, "").
+		Find(&logged).Error
+	if err != nil {
+		return nil, err
+	}
+	recordIDs := make([]string, len(logged))
+	for i, entry := range logged {
+		recordIDs[i] = entry.RecordID
+	}
+	records, err := recordsByID(s.db, recordIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var outcomes []sloOutcome
+	for _, entry := range logged {
+		record, ok := records[entry.RecordID]
+		if !ok || record.ReviewStatus == models.ReviewStatusPending {
+			continue
+		}
+		good := math.Abs(record.Satisfaction-50) <= s.cfg.AccuracyTolerance
+		outcomes = append(outcomes, sloOutcome{at: entry.CreatedAt, good: good})
+	}
+	return outcomes, nil
+}
+
+// latency counts the predictions of the last window before now, and the slow ones
+func (s *SLOService) latency(now time.Time, window time.Duration) (int64, int64) {
+	last := now.Unix() / 60
+	first := last - int64(window/time.Minute) + 1
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events, slow int64
+	for _, bucket := range s.minutes {
+		if bucket.minute >= first && bucket.minute <= last {
+			events += bucket.events
+			slow += bucket.slow
+		}
+	}
+	return events, slow
+}
+
+// window works out the error and burn rate of one window
+func (s *SLOService) window(window time.Duration, events, bad int64, target float64) SLOWindow {
+	w := SLOWindow{Window: sloWindowName(window), Events: events, Bad: bad}
+	if events > 0 {
+		w.ErrorRate = roundTo(float64(bad)/float64(events), 4)
+		w.BurnRate = roundTo(float64(bad)/float64(events)/(1-target), 2)
+	}
+	return w
+}
+
+// budgetRemaining is the share of the error budget the whole period, the last window, left
+// unspent; it goes negative once the budget is overspent
+func budgetRemaining(windows []SLOWindow) float64 {
+	return roundTo(1-windows[len(windows)-1].BurnRate, 2)
+}
+
+// sloWindowName writes a window in days when it is whole days, in hours otherwise
+func sloWindowName(window time.Duration) string {
+	if window >= 24*time.Hour && window%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", window/(24*time.Hour))
+	}
+	return fmt.Sprintf("%gh", math.Round(window.Hours()*100)/100)
+}
+
+// WritePrometheus writes the objectives in the Prometheus text exposition format: the served
+// and slow prediction counters, for burn rate alerts computed by Prometheus, and the burn rates
+// and remaining budgets of the report
+func (s *SLOService) WritePrometheus(w io.Writer) error {
+	report, err := s.Report()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	served, slow := s.served, s.slow
+	s.mu.Unlock()
+
+	if s.cfg.LatencyTarget > 0 {
+		_, err := fmt.Fprintf(w, "# HELP heat_logger_predictions_total Predictions served.\n# TYPE heat_logger_predictions_total counter\nheat_logger_predictions_total %d\n"+
+			"# HELP heat_logger_predictions_slow_total Predictions slower than the latency objective.\n# TYPE heat_logger_predictions_slow_total counter\nheat_logger_predictions_slow_total %d\n",
+			served, slow)
+		if err != nil {
+			return err
+		}
+	}
+
+	metrics := []struct {
+		name, help string
+		write      func(SLOObjective) error
+	}{
+		{"heat_logger_slo_target", "Share of events an objective requires to be good.", func(o SLOObjective) error {
+			_, err := fmt.Fprintf(w, "heat_logger_slo_target{objective=%q} %g\n", o.Name, o.Target)
+			return err
+		}},
+		{"heat_logger_slo_burn_rate", "Error rate over the rate the objective allows, per window.", func(o SLOObjective) error {
+			for _, window := range o.Windows {
+				if _, err := fmt.Fprintf(w, "heat_logger_slo_burn_rate{objective=%q,window=%q} %g\n", o.Name, window.Window, window.BurnRate); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"heat_logger_slo_error_budget_remaining", "Share of the period's error budget left.", func(o SLOObjective) error {
+			_, err := fmt.Fprintf(w, "heat_logger_slo_error_budget_remaining{objective=%q} %g\n", o.Name, o.BudgetRemaining)
+			return err
+		}},
+	}
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name); err != nil {
+			return err
+		}
+		for _, objective := range report.Objectives {
+			if err := metric.write(objective); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SLOPredictor times every prediction for the latency objective. It wraps all the others, so
+// queueing, timeouts and cache hits count as the client saw them.
+type SLOPredictor struct {
+	inner Predictor
+	slo   *SLOService
+}
+
+// NewSLOPredictor wraps inner with latency tracking
+func NewSLOPredictor(inner Predictor, slo *SLOService) *SLOPredictor {
+	return &SLOPredictor{inner: inner, slo: slo}
+}
+
+// Predict runs the wrapped predictor and records how long it took
+func (p *SLOPredictor) Predict(ctx context.Context, req PredictionRequest) (*PredictionResponse, error) {
+	start := time.Now()
+	resp, err := p.inner.Predict(ctx, req)
+	if err == nil {
+		p.slo.ObserveLatency(time.Since(start))
+	}
+	return resp, err
+}
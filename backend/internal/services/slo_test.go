@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestSLOService(t *testing.T) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "slo.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	db := database.GetDB()
+
+	now := time.Now()
+	clock := NewFrozenClock(now)
+	slo := NewSLOService(config.SLOConfig{
+		AccuracyTarget: 0.9, AccuracyTolerance: 10,
+		LatencyTarget: 0.99, LatencyThreshold: 100 * time.Millisecond,
+		Period: 7 * 24 * time.Hour,
+	})
+	slo.SetClock(clock)
+
+	// Two predictions an hour ago landed near 50, one two days ago was far too cold, one was
+	// rated by a sensor nobody confirmed and one got no feedback
+	for i, outcome := range []struct {
+		age          time.Duration
+		satisfaction float64
+		review       string
+	}{
+		{time.Hour / 2, 55, ""}, {time.Hour / 2, 42, ""}, {48 * time.Hour, 20, ""}, {time.Hour / 2, 10, models.ReviewStatusPending}, {time.Hour / 2, 0, "none"},
+	} {
+		entry := models.PredictionLog{UserID: "user1", HeatingTime: 20, CreatedAt: now.Add(-outcome.age)}
+		if outcome.review != "none" {
+			record := models.DailyRecord{UserID: "user1", Date: now, ShowerDuration: 10, HeatingTime: 20, Satisfaction: outcome.satisfaction, ReviewStatus: outcome.review}
+			require.NoError(t, db.Create(&record).Error, i)
+			entry.RecordID = record.ID
+		}
+		require.NoError(t, db.Create(&entry).Error, i)
+	}
+
+	predictor := NewSLOPredictor(fixedPredictor(20), slo)
+	for i := 0; i < 3; i++ {
+		_, err := predictor.Predict(context.Background(), PredictionRequest{UserID: "user1", Duration: 10, Temperature: 15})
+		require.NoError(t, err)
+	}
+	clock.Set(now.Add(-2 * time.Hour))
+	slo.ObserveLatency(time.Second)
+	clock.Set(now)
+
+	report, err := slo.Report()
+	require.NoError(t, err)
+	assert.Equal(t, "7d", report.Period)
+	require.Len(t, report.Objectives, 2)
+
+	accuracy := report.Objectives[0]
+	assert.Equal(t, SLOAccuracy, accuracy.Name)
+	require.Len(t, accuracy.Windows, 4)
+	assert.Equal(t, SLOWindow{Window: "1h", Events: 2}, accuracy.Windows[0], "unconfirmed and unanswered predictions don't count")
+	assert.Equal(t, SLOWindow{Window: "7d", Events: 3, Bad: 1, ErrorRate: 0.3333, BurnRate: 3.33}, accuracy.Windows[3])
+	assert.Equal(t, -2.33, accuracy.BudgetRemaining, "a third of the predictions missed where a tenth may")
+
+	latency := report.Objectives[1]
+	assert.Equal(t, SLOLatency, latency.Name)
+	assert.Equal(t, 100.0, latency.Threshold)
+	assert.Equal(t, SLOWindow{Window: "1h", Events: 3}, latency.Windows[0])
+	assert.Equal(t, SLOWindow{Window: "6h", Events: 4, Bad: 1, ErrorRate: 0.25, BurnRate: 25}, latency.Windows[1])
+
+	var out strings.Builder
+	require.NoError(t, slo.WritePrometheus(&out))
+	assert.Contains(t, out.String(), "heat_logger_predictions_slow_total 1\n")
+	assert.Contains(t, out.String(), `heat_logger_slo_burn_rate{objective="latency",window="6h"} 25`)
+	assert.Contains(t, out.String(), `heat_logger_slo_error_budget_remaining{objective="accuracy"} -2.33`)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+// SolarPoint is the PV production expected over one interval of a forecast
+type SolarPoint struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Watts float64   `json:"watts"`
+}
+
+// SolarService keeps the house's solar production forecast and the latest live reading, and
+// tells the heating scheduler how much surplus power there is to heat water with. Forecasts
+// and readings are pushed by whatever talks to the inverter or forecast service (an HTTP
+// poller, or an MQTT bridge posting to the API).
+type SolarService struct {
+	cfg config.SolarConfig
+
+	mu       sync.Mutex
+	forecast []SolarPoint
+	live     *SolarPoint
+}
+
+// NewSolarService creates a solar service with no forecast
+func NewSolarService(cfg config.SolarConfig) *SolarService {
+	return &SolarService{cfg: cfg}
+}
+
+// FeedInPrice is what exporting a kWh earns instead, the cost of heating water with it
+func (s *SolarService) FeedInPrice() float64 {
+	return s.cfg.FeedInPrice
+}
+
+// SetForecast replaces the forecast from the first new point onwards; earlier points are kept
+// so a forecast updated during the day doesn't lose the morning
+func (s *SolarService) SetForecast(points []SolarPoint) {
+	if len(points) == 0 {
+		return
+	}
+	points = append([]SolarPoint(nil), points...)
+	sort.Slice(points, func(i, j int) bool { return points[i].Start.Before(points[j].Start) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept []SolarPoint
+	for _, point := range s.forecast {
+		if !point.End.After(points[0].Start) {
+			kept = append(kept, point)
+		}
+	}
+	s.forecast = append(kept, points...)
+}
+
+// ReportProduction stores a live production reading, which stands in for the forecast until
+// the live window passes
+func (s *SolarService) ReportProduction(watts float64, at time.Time) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.live != nil && s.live.Start.After(at) {
+		return
+	}
+	s.live = &SolarPoint{Start: at, End: at.Add(s.cfg.LiveWindow), Watts: watts}
+}
+
+// Covers reports whether a forecast or live reading says anything about the window
+func (s *SolarService) Covers(start, end time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, point := range s.points() {
+		if point.Start.Before(end) && point.End.After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// SurplusAt returns the production above the house's base load at t, in W
+func (s *SolarService) SurplusAt(t time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	production := 0.0
+	for _, point := range s.points() {
+		if !t.Before(point.Start) && t.Before(point.End) {
+			production = point.Watts
+			break
+		}
+	}
+	if production <= s.cfg.BaseLoad {
+		return 0
+	}
+	return production - s.cfg.BaseLoad
+}
+
+// points lists the live reading first so it wins over the forecast
+func (s *SolarService) points() []SolarPoint {
+	if s.live == nil {
+		return s.forecast
+	}
+	return append([]SolarPoint{*s.live}, s.forecast...)
+}
+
+// boundaries returns the times production changes within [from, to]
+func (s *SolarService) boundaries(from, to time.Time) []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var times []time.Time
+	for _, point := range s.points() {
+		for _, t := range []time.Time{point.Start, point.End} {
+			if !t.Before(from) && !t.After(to) {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
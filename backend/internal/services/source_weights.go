@@ -0,0 +1,35 @@
+package services
+
+import "heat-logger/internal/models"
+
+// SourceWeights scales how much predictors trust records by how they entered the system.
+// Sources without an entry count fully.
+type SourceWeights map[string]float64
+
+// DefaultSourceWeights trusts real feedback fully and gives simulated records a small say
+func DefaultSourceWeights() SourceWeights {
+	return SourceWeights{
+		models.RecordSourceSimulator: 0.25,
+	}
+}
+
+// For returns the weight multiplier for a record source. Records saved before sources were
+// tracked have no source and count as manual.
+func (w SourceWeights) For(source string) float64 {
+	if source == "" {
+		source = models.RecordSourceManual
+	}
+	if weight, ok := w[source]; ok {
+		return weight
+	}
+	return 1.0
+}
+
+// withDefaults returns the default weights overridden by w
+func (w SourceWeights) withDefaults() SourceWeights {
+	merged := DefaultSourceWeights()
+	for source, weight := range w {
+		merged[source] = weight
+	}
+	return merged
+}
@@ -0,0 +1,172 @@
+package services
+
+import "time"
+
+// StatsService computes per-user statistics over recorded history
+type StatsService struct {
+	recordService     RecordServiceInterface
+	changePointConfig ChangePointConfig
+	aggregates        RecordAggregates
+	neighborhood      DistributionWindow
+}
+
+// RecordAggregates computes summaries of stored records in the database
+type RecordAggregates interface {
+	Count(q RecordQuery) (int64, error)
+	AverageByWeek(q RecordQuery) ([]WeeklyAverage, error)
+	HeatingTimePercentiles(q RecordQuery, ps ...float64) ([]float64, error)
+	SatisfactionByBucket(q RecordQuery) ([]BucketSatisfaction, error)
+	Report(q ReportQuery) (*Report, error)
+}
+
+// NewStatsService creates a new stats service instance
+func NewStatsService(recordService RecordServiceInterface, changePointConfig ChangePointConfig) *StatsService {
+	return &StatsService{
+		recordService:     recordService,
+		changePointConfig: changePointConfig,
+		neighborhood:      DistributionWindow{Duration: 8, Temperature: 6},
+	}
+}
+
+// SetAggregates enables the record summary
+func (s *StatsService) SetAggregates(aggregates RecordAggregates) {
+	s.aggregates = aggregates
+}
+
+// summaryWeeks is how many weeks of weekly averages a summary covers
+const summaryWeeks = 12
+
+// RecordSummary describes stored records without listing them
+type RecordSummary struct {
+	UserID      string          `json:"userId,omitempty"` // empty for all users
+	Records     int64           `json:"records"`
+	Weekly      []WeeklyAverage `json:"weekly"`                // the last 12 weeks
+	HeatingTime *Percentiles    `json:"heatingTime,omitempty"` // over all the records; nil without any
+}
+
+// Percentiles of a value across records
+type Percentiles struct {
+	P10 float64 `json:"p10"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+}
+
+// Summary counts and summarises the user's records, or everyone's for an empty userID
+func (s *StatsService) Summary(userID string) (*RecordSummary, error) {
+	query := RecordQuery{UserID: userID}
+	count, err := s.aggregates.Count(query)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().AddDate(0, 0, -7*summaryWeeks)
+	weekly, err := s.aggregates.AverageByWeek(RecordQuery{UserID: userID, From: &since})
+	if err != nil {
+		return nil, err
+	}
+	summary := &RecordSummary{UserID: userID, Records: count, Weekly: weekly}
+
+	values, err := s.aggregates.HeatingTimePercentiles(query, 0.1, 0.5, 0.9)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 3 {
+		summary.HeatingTime = &Percentiles{P10: values[0], P50: values[1], P90: values[2]}
+	}
+	return summary, nil
+}
+
+// Report runs a custom report over the user's records
+func (s *StatsService) Report(q ReportQuery) (*Report, error) {
+	return s.aggregates.Report(q)
+}
+
+// Heatmap is the user's average satisfaction per context bucket, laid out as a grid: one
+// row per temperature range, one column per duration range. Both axes are contiguous from the
+// lowest to the highest bucket with records, so cells without records are null.
+type Heatmap struct {
+	UserID       string           `json:"userId"`
+	Durations    []HeatmapRange   `json:"durations"`    // columns, minutes
+	Temperatures []HeatmapRange   `json:"temperatures"` // rows, °C
+	Cells        [][]*HeatmapCell `json:"cells"`        // [row][column]
+}
+
+// HeatmapRange is the [min, max) range of one heatmap axis position
+type HeatmapRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// HeatmapCell summarises the feedback given in one context bucket
+type HeatmapCell struct {
+	Bucket       string  `json:"bucket"` // ContextBucket key
+	Records      int64   `json:"records"`
+	Satisfaction float64 `json:"satisfaction"` // mean 1-100, 50 is perfect
+	Deviation    float64 `json:"deviation"`    // mean distance from 50, how wrong the heating times were
+}
+
+// Heatmap buckets the user's training records by shower duration and temperature
+func (s *StatsService) Heatmap(userID string) (*Heatmap, error) {
+	buckets, err := s.aggregates.SatisfactionByBucket(RecordQuery{UserID: userID, TrainingOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	heatmap := &Heatmap{UserID: userID, Durations: []HeatmapRange{}, Temperatures: []HeatmapRange{}, Cells: [][]*HeatmapCell{}}
+	if len(buckets) == 0 {
+		return heatmap, nil
+	}
+
+	minD, maxD := buckets[0].Duration, buckets[0].Duration
+	minT, maxT := buckets[0].Temperature, buckets[0].Temperature
+	for _, b := range buckets {
+		minD, maxD = min(minD, b.Duration), max(maxD, b.Duration)
+		minT, maxT = min(minT, b.Temperature), max(maxT, b.Temperature)
+	}
+	for d := minD; d <= maxD; d++ {
+		lo := float64(d) * bucketDurationWidth
+		heatmap.Durations = append(heatmap.Durations, HeatmapRange{Min: lo, Max: lo + bucketDurationWidth})
+	}
+	for t := minT; t <= maxT; t++ {
+		lo := float64(t) * bucketTemperatureWidth
+		heatmap.Temperatures = append(heatmap.Temperatures, HeatmapRange{Min: lo, Max: lo + bucketTemperatureWidth})
+		heatmap.Cells = append(heatmap.Cells, make([]*HeatmapCell, maxD-minD+1))
+	}
+	for _, b := range buckets {
+		heatmap.Cells[b.Temperature-minT][b.Duration-minD] = &HeatmapCell{
+			Bucket:       BucketFor(float64(b.Duration)*bucketDurationWidth, float64(b.Temperature)*bucketTemperatureWidth).Key,
+			Records:      b.Records,
+			Satisfaction: roundTo(b.Satisfaction, 2),
+			Deviation:    roundTo(b.Deviation, 2),
+		}
+	}
+	return heatmap, nil
+}
+
+// ChangePointReport lists detected regime changes and where the effective training window starts
+type ChangePointReport struct {
+	UserID               string        `json:"userId"`
+	RecordsAnalyzed      int           `json:"recordsAnalyzed"`
+	ChangePoints         []ChangePoint `json:"changePoints"`
+	EffectiveWindowStart *time.Time    `json:"effectiveWindowStart,omitempty"`
+}
+
+// ChangePoints detects regime changes in the user's training history
+func (s *StatsService) ChangePoints(userID string) (*ChangePointReport, error) {
+	records, err := s.recordService.GetRecordsForPredictionByUser(userID, 400)
+	if err != nil {
+		return nil, err
+	}
+
+	points := DetectChangePoints(records, s.changePointConfig)
+	report := &ChangePointReport{
+		UserID:          userID,
+		RecordsAnalyzed: len(records),
+		ChangePoints:    points,
+	}
+	if report.ChangePoints == nil {
+		report.ChangePoints = []ChangePoint{}
+	}
+	if len(points) > 0 {
+		report.EffectiveWindowStart = &points[len(points)-1].Date
+	}
+	return report, nil
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrSyncSameInstance is returned when an instance is asked to import its own export
+	ErrSyncSameInstance = errors.New("sync bundle came from this instance")
+	// ErrSyncNoPeer is returned when a sync run is requested without a configured peer
+	ErrSyncNoPeer = errors.New("no sync peer configured")
+)
+
+// SyncBundle carries the records and per-user settings changed on one instance since a point
+// in time, and the records deleted since
+type SyncBundle struct {
+	InstanceID  string                   `json:"instanceId"`
+	GeneratedAt time.Time                `json:"generatedAt"`
+	Records     []models.DailyRecord     `json:"records"`
+	Presets     []models.Preset          `json:"presets"`
+	Members     []models.Member          `json:"members"`
+	Deletions   []models.RecordTombstone `json:"deletions"`
+}
+
+// SyncResult counts what an import did with each item of a bundle
+type SyncResult struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Skipped   int `json:"skipped"`   // the local copy was at least as recent
+	Conflicts int `json:"conflicts"` // the item clashed with a different local item, e.g. a preset name
+	Deleted   int `json:"deleted"`   // local records a deletion from the other instance removed
+}
+
+// SyncRunResult describes one push/pull exchange with the peer
+type SyncRunResult struct {
+	PeerInstanceID string     `json:"peerInstanceId"`
+	Pulled         SyncResult `json:"pulled"`
+	Pushed         SyncResult `json:"pushed"`
+}
+
+// SyncService exchanges records, presets and members between instances. Conflicts are
+// resolved per item by ID: the copy with the later UpdatedAt wins. Record deletions travel as
+// tombstones, which win over copies of the record last updated before the deletion.
+type SyncService struct {
+	db         *gorm.DB
+	cfg        config.SyncConfig
+	instanceID string
+	client     *http.Client
+	onImported []func()
+}
+
+// NewSyncService creates a new sync service instance
+func NewSyncService(cfg config.SyncConfig, instanceID string) *SyncService {
+	return &SyncService{
+		db:         database.GetDB(),
+		cfg:        cfg,
+		instanceID: instanceID,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ResolveInstanceID returns the configured instance ID, or the one generated and stored on first start
+func ResolveInstanceID(configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	db := database.GetDB()
+	var instance models.Instance
+	err := db.First(&instance).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		instance = models.Instance{ID: uuid.New().String()}
+		err = db.Create(&instance).Error
+	}
+	if err != nil {
+		return "", err
+	}
+	return instance.ID, nil
+}
+
+// InstanceID returns this deployment's identifier
+func (s *SyncService) InstanceID() string {
+	return s.instanceID
+}
+
+// Start launches periodic syncing with the peer when an interval is configured
+func (s *SyncService) Start() {
+	if s.cfg.PeerURL == "" || s.cfg.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := s.Run(context.Background(), ""); err != nil {
+				log.Printf("Sync with %s failed: %v", s.cfg.PeerURL, err)
+			}
+		}
+	}()
+}
+
+// Export returns everything changed since the given time, for one user or all users when userID is empty
+func (s *SyncService) Export(userID string, since time.Time) (*SyncBundle, error) {
+	bundle := &SyncBundle{InstanceID: s.instanceID, GeneratedAt: time.Now()}
+
+	query := func() *gorm.DB {
+		q := s.db.Where("updated_at > ?", since.Local())
+		if userID != "" {
+			q = q.Where("user_id = ?", userID)
+		}
+		return q
+	}
+	if err := query().Find(&bundle.Records).Error; err != nil {
+		return nil, err
+	}
+	if err := query().Find(&bundle.Presets).Error; err != nil {
+		return nil, err
+	}
+	if err := query().Find(&bundle.Members).Error; err != nil {
+		return nil, err
+	}
+	deletions := s.db.Where("deleted_at > ?", since.Local())
+	if userID != "" {
+		deletions = deletions.Where("user_id = ?", userID)
+	}
+	if err := deletions.Find(&bundle.Deletions).Error; err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// OnImported registers a callback run after an import changed anything, for state kept about
+// records outside the database. Imported records don't pass through RecordService, so its
+// record callbacks don't see them.
+func (s *SyncService) OnImported(fn func()) {
+	s.onImported = append(s.onImported, fn)
+}
+
+// Import applies a bundle from another instance, keeping whichever copy of each item was updated last
+func (s *SyncService) Import(bundle *SyncBundle) (*SyncResult, error) {
+	if bundle.InstanceID == s.instanceID {
+		return nil, ErrSyncSameInstance
+	}
+	result := &SyncResult{}
+	err := s.importBundle(s.db, bundle, result, nil)
+	// A failed import may have applied some items before it stopped
+	if result.Inserted+result.Updated+result.Deleted > 0 {
+		for _, fn := range s.onImported {
+			fn()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PreviewImport reports what Import would do with a bundle. The import is made in a transaction
+// that is rolled back, so items clashing with local ones are counted exactly; the sample lists
+// items that would be inserted or updated.
+func (s *SyncService) PreviewImport(bundle *SyncBundle) (*DryRunResult, error) {
+	if bundle.InstanceID == s.instanceID {
+		return nil, ErrSyncSameInstance
+	}
+	result := &SyncResult{}
+	preview := &DryRunResult{SampleIDs: []string{}}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.importBundle(tx, bundle, result, preview); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	preview.Counts = map[string]int64{
+		"inserted":  int64(result.Inserted),
+		"updated":   int64(result.Updated),
+		"skipped":   int64(result.Skipped),
+		"conflicts": int64(result.Conflicts),
+		"deleted":   int64(result.Deleted),
+	}
+	return preview, nil
+}
+
+// errDryRun rolls back the transaction of a preview
+var errDryRun = errors.New("dry run")
+
+// importBundle applies every item of a bundle through db, adding the changed ones to the
+// preview when there is one
+func (s *SyncService) importBundle(db *gorm.DB, bundle *SyncBundle, result *SyncResult, preview *DryRunResult) error {
+	apply := func(incoming interface{}, id string, updatedAt time.Time) error {
+		changed, err := s.applyNewer(db, incoming, id, updatedAt, result)
+		if changed && preview != nil {
+			preview.sample(id)
+		}
+		return err
+	}
+	for i := range bundle.Records {
+		// A record deleted here after the other instance's last change stays deleted
+		var deleted int64
+		err := db.Model(&models.RecordTombstone{}).Where("record_id = ? AND deleted_at >= ?", bundle.Records[i].ID, bundle.Records[i].UpdatedAt.Local()).Count(&deleted).Error
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			result.Skipped++
+			continue
+		}
+		if err := apply(&bundle.Records[i], bundle.Records[i].ID, bundle.Records[i].UpdatedAt); err != nil {
+			return err
+		}
+	}
+	for i := range bundle.Presets {
+		if err := apply(&bundle.Presets[i], bundle.Presets[i].ID, bundle.Presets[i].UpdatedAt); err != nil {
+			return err
+		}
+	}
+	for i := range bundle.Members {
+		if err := apply(&bundle.Members[i], bundle.Members[i].ID, bundle.Members[i].UpdatedAt); err != nil {
+			return err
+		}
+	}
+	for i := range bundle.Deletions {
+		deleted, err := s.applyDeletion(db, &bundle.Deletions[i], result)
+		if err != nil {
+			return err
+		}
+		if deleted && preview != nil {
+			preview.sample(bundle.Deletions[i].RecordID)
+		}
+	}
+	return nil
+}
+
+// applyNewer stores incoming unless the local copy with the same ID is at least as recent,
+// reporting whether it did. Items are replaced rather than updated so their timestamps are
+// kept as exported.
+func (s *SyncService) applyNewer(db *gorm.DB, incoming interface{}, id string, updatedAt time.Time, result *SyncResult) (bool, error) {
+	if id == "" {
+		result.Conflicts++
+		return false, nil
+	}
+
+	var current struct{ UpdatedAt time.Time }
+	err := db.Model(incoming).Select("updated_at").Where("id = ?", id).Take(&current).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := db.Create(incoming).Error; err != nil {
+			result.Conflicts++
+			return false, nil
+		}
+		result.Inserted++
+	case err != nil:
+		return false, err
+	case !updatedAt.After(current.UpdatedAt):
+		result.Skipped++
+		return false, nil
+	default:
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("id = ?", id).Delete(incoming).Error; err != nil {
+				return err
+			}
+			return tx.Create(incoming).Error
+		})
+		if err != nil {
+			result.Conflicts++
+			return false, nil
+		}
+		result.Updated++
+	}
+	return true, nil
+}
+
+// applyDeletion deletes the local copy of a record deleted on the other instance unless it was
+// updated after the deletion, reporting whether it did. The tombstone is kept either way, so a
+// copy of the record arriving later from a third instance doesn't bring it back.
+func (s *SyncService) applyDeletion(db *gorm.DB, tombstone *models.RecordTombstone, result *SyncResult) (bool, error) {
+	if tombstone.RecordID == "" {
+		result.Conflicts++
+		return false, nil
+	}
+
+	var existing models.RecordTombstone
+	err := db.Where("record_id = ?", tombstone.RecordID).Take(&existing).Error
+	switch {
+	case err == nil && !tombstone.DeletedAt.After(existing.DeletedAt):
+		result.Skipped++
+		return false, nil
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return false, err
+	}
+
+	deleted := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		removed := tx.Where("id = ? AND updated_at <= ?", tombstone.RecordID, tombstone.DeletedAt.Local()).Delete(&models.DailyRecord{})
+		if removed.Error != nil {
+			return removed.Error
+		}
+		if deleted = removed.RowsAffected > 0; deleted {
+			if err := tx.Where("record_id = ?", tombstone.RecordID).Delete(&models.RecordRevision{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(tombstone).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	if deleted {
+		result.Deleted++
+	} else {
+		result.Skipped++
+	}
+	return deleted, nil
+}
+
+// Run pulls the peer's changes and pushes local ones, for one user or all users when userID is empty
+func (s *SyncService) Run(ctx context.Context, userID string) (*SyncRunResult, error) {
+	if s.cfg.PeerURL == "" {
+		return nil, ErrSyncNoPeer
+	}
+
+	state := models.SyncState{Peer: s.cfg.PeerURL, UserID: userID}
+	if err := s.db.Where("peer = ? AND user_id = ?", state.Peer, state.UserID).FirstOrInit(&state).Error; err != nil {
+		return nil, err
+	}
+
+	// Pull
+	query := url.Values{"since": {state.LastPulledAt.Format(time.RFC3339Nano)}}
+	if userID != "" {
+		query.Set("userId", userID)
+	}
+	var remote SyncBundle
+	if err := s.call(ctx, http.MethodGet, "/api/sync/export", query, nil, &remote); err != nil {
+		return nil, fmt.Errorf("pull from peer failed: %w", err)
+	}
+	pulled, err := s.Import(&remote)
+	if err != nil {
+		return nil, err
+	}
+	state.LastPulledAt = remote.GeneratedAt
+
+	// Push
+	local, err := s.Export(userID, state.LastPushedAt)
+	if err != nil {
+		return nil, err
+	}
+	var pushed struct {
+		Result SyncResult `json:"result"`
+	}
+	if err := s.call(ctx, http.MethodPost, "/api/sync/import", nil, local, &pushed); err != nil {
+		return nil, fmt.Errorf("push to peer failed: %w", err)
+	}
+	state.LastPushedAt = local.GeneratedAt
+
+	if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&state).Error; err != nil {
+		return nil, err
+	}
+	return &SyncRunResult{PeerInstanceID: remote.InstanceID, Pulled: *pulled, Pushed: pushed.Result}, nil
+}
+
+// call sends an authenticated request to the peer's sync API
+func (s *SyncService) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	target := s.cfg.PeerURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
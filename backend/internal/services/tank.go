@@ -0,0 +1,33 @@
+package services
+
+import (
+	"math"
+
+	"heat-logger/internal/config"
+)
+
+// TankCapacity describes how much hot water one full tank delivers
+type TankCapacity struct {
+	Size     float64 // litres; 0 means the capacity isn't known
+	FlowRate float64 // litres of hot water drawn per minute of showering
+}
+
+// NewTankCapacity builds the capacity from the device configuration
+func NewTankCapacity(cfg config.DeviceConfig) TankCapacity {
+	return TankCapacity{Size: cfg.TankSize, FlowRate: cfg.FlowRate}
+}
+
+// Minutes returns how long one full tank lasts, or +Inf when the capacity isn't known
+func (t TankCapacity) Minutes() float64 {
+	if t.Size <= 0 || t.FlowRate <= 0 {
+		return math.Inf(1)
+	}
+	return t.Size / t.FlowRate
+}
+
+// Enough reports whether one full tank covers a shower of the given length, returning the
+// longest shower it does cover
+func (t TankCapacity) Enough(duration float64) (bool, float64) {
+	maxDuration := t.Minutes()
+	return duration <= maxDuration, math.Floor(maxDuration*10) / 10
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNoTemperature is returned when no provider could supply a temperature
+	ErrNoTemperature = errors.New("no temperature available")
+	// ErrUnknownTemperatureSource is returned when a priority list names an unknown source
+	ErrUnknownTemperatureSource = errors.New("unknown temperature source")
+)
+
+// TemperatureQuery describes who a temperature is needed for and what the client already supplied
+type TemperatureQuery struct {
+	UserID string
+	Manual *float64 // the temperature typed into the request, if any
+}
+
+// TemperatureReading is a temperature and the source that supplied it
+type TemperatureReading struct {
+	Value  float64   `json:"value"`
+	Source string    `json:"source"`
+	At     time.Time `json:"at"`
+}
+
+// TemperatureProvider supplies the current temperature from one source. Providers return
+// ErrNoTemperature when they have nothing to offer so the next one in priority order is tried.
+type TemperatureProvider interface {
+	Source() string
+	Temperature(ctx context.Context, query TemperatureQuery) (*TemperatureReading, error)
+}
+
+// manualTemperature passes through the temperature the client supplied
+type manualTemperature struct{}
+
+func (manualTemperature) Source() string { return models.TemperatureSourceManual }
+
+func (manualTemperature) Temperature(ctx context.Context, query TemperatureQuery) (*TemperatureReading, error) {
+	if query.Manual == nil {
+		return nil, ErrNoTemperature
+	}
+	return &TemperatureReading{Value: *query.Manual, Source: models.TemperatureSourceManual, At: time.Now()}, nil
+}
+
+// SensorTemperatureProvider keeps the latest reading published by each user's MQTT sensor.
+// Readings are delivered by a bridge (e.g. a broker rule or a Node-RED flow) posting to the API;
+// readings reported without a user apply to everyone in the house.
+type SensorTemperatureProvider struct {
+	maxAge time.Duration
+
+	mu       sync.Mutex
+	readings map[string]TemperatureReading
+}
+
+// NewSensorTemperatureProvider creates an empty sensor temperature provider
+func NewSensorTemperatureProvider(maxAge time.Duration) *SensorTemperatureProvider {
+	if maxAge <= 0 {
+		maxAge = time.Hour
+	}
+	return &SensorTemperatureProvider{
+		maxAge:   maxAge,
+		readings: make(map[string]TemperatureReading),
+	}
+}
+
+// Source identifies the provider
+func (p *SensorTemperatureProvider) Source() string { return models.TemperatureSourceMQTT }
+
+// Report stores a sensor reading for a user, or for the whole house when userID is empty
+func (p *SensorTemperatureProvider) Report(userID string, value float64, at time.Time) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if current, ok := p.readings[userID]; ok && current.At.After(at) {
+		return
+	}
+	p.readings[userID] = TemperatureReading{Value: value, Source: models.TemperatureSourceMQTT, At: at}
+}
+
+// Temperature returns the user's latest fresh reading, falling back to the house reading
+func (p *SensorTemperatureProvider) Temperature(ctx context.Context, query TemperatureQuery) (*TemperatureReading, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range []string{query.UserID, ""} {
+		reading, ok := p.readings[key]
+		if ok && time.Since(reading.At) <= p.maxAge {
+			return &reading, nil
+		}
+	}
+	return nil, ErrNoTemperature
+}
+
+// WeatherTemperatureProvider reads the current outdoor temperature from the Open-Meteo API
+type WeatherTemperatureProvider struct {
+	cfg    config.WeatherConfig
+	client *http.Client
+
+	mu         sync.Mutex
+	cached     *TemperatureReading
+	forecast   []TemperatureReading
+	forecastAt time.Time
+}
+
+// weatherCacheTTL is how long a weather API answer is reused
+const weatherCacheTTL = 10 * time.Minute
+
+// weatherForecastTTL is how long an hourly forecast is reused; Open-Meteo updates hourly
+const weatherForecastTTL = time.Hour
+
+// weatherForecastDays is how many days of hourly forecast are fetched, today included
+const weatherForecastDays = 8
+
+// NewWeatherTemperatureProvider creates a weather temperature provider
+func NewWeatherTemperatureProvider(cfg config.WeatherConfig) *WeatherTemperatureProvider {
+	return &WeatherTemperatureProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Source identifies the provider
+func (p *WeatherTemperatureProvider) Source() string { return models.TemperatureSourceWeather }
+
+// Temperature returns the current outdoor temperature at the configured location
+func (p *WeatherTemperatureProvider) Temperature(ctx context.Context, query TemperatureQuery) (*TemperatureReading, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cached != nil && time.Since(p.cached.At) < weatherCacheTTL {
+		reading := *p.cached
+		return &reading, nil
+	}
+
+	params := url.Values{
+		"latitude":  {strconv.FormatFloat(*p.cfg.Latitude, 'f', -1, 64)},
+		"longitude": {strconv.FormatFloat(*p.cfg.Longitude, 'f', -1, 64)},
+		"current":   {"temperature_2m"},
+	}
+	var body struct {
+		Current struct {
+			Temperature *float64 `json:"temperature_2m"`
+		} `json:"current"`
+	}
+	if err := getJSON(ctx, p.client, p.cfg.URL+"/v1/forecast?"+params.Encode(), "", &body); err != nil {
+		return nil, err
+	}
+	if body.Current.Temperature == nil {
+		return nil, ErrNoTemperature
+	}
+
+	p.cached = &TemperatureReading{Value: *body.Current.Temperature, Source: models.TemperatureSourceWeather, At: time.Now()}
+	reading := *p.cached
+	return &reading, nil
+}
+
+// Forecast returns the hourly outdoor temperature forecast at the configured location for today
+// and the following week, oldest first
+func (p *WeatherTemperatureProvider) Forecast(ctx context.Context) ([]TemperatureReading, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.forecast != nil && time.Since(p.forecastAt) < weatherForecastTTL {
+		return p.forecast, nil
+	}
+
+	params := url.Values{
+		"latitude":      {strconv.FormatFloat(*p.cfg.Latitude, 'f', -1, 64)},
+		"longitude":     {strconv.FormatFloat(*p.cfg.Longitude, 'f', -1, 64)},
+		"hourly":        {"temperature_2m"},
+		"forecast_days": {strconv.Itoa(weatherForecastDays)},
+		"timeformat":    {"unixtime"},
+	}
+	var body struct {
+		Hourly struct {
+			Time        []int64    `json:"time"`
+			Temperature []*float64 `json:"temperature_2m"`
+		} `json:"hourly"`
+	}
+	if err := getJSON(ctx, p.client, p.cfg.URL+"/v1/forecast?"+params.Encode(), "", &body); err != nil {
+		return nil, err
+	}
+	var forecast []TemperatureReading
+	for i, at := range body.Hourly.Time {
+		if i < len(body.Hourly.Temperature) && body.Hourly.Temperature[i] != nil {
+			forecast = append(forecast, TemperatureReading{Value: *body.Hourly.Temperature[i], Source: models.TemperatureSourceWeather, At: time.Unix(at, 0)})
+		}
+	}
+	if len(forecast) == 0 {
+		return nil, ErrNoTemperature
+	}
+
+	p.forecast, p.forecastAt = forecast, time.Now()
+	return forecast, nil
+}
+
+// HomeAssistantTemperatureProvider reads the state of a Home Assistant temperature entity
+type HomeAssistantTemperatureProvider struct {
+	cfg    config.HomeAssistantConfig
+	client *http.Client
+}
+
+// NewHomeAssistantTemperatureProvider creates a Home Assistant temperature provider
+func NewHomeAssistantTemperatureProvider(cfg config.HomeAssistantConfig) *HomeAssistantTemperatureProvider {
+	return &HomeAssistantTemperatureProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Source identifies the provider
+func (p *HomeAssistantTemperatureProvider) Source() string {
+	return models.TemperatureSourceHomeAssistant
+}
+
+// Temperature returns the entity's state when the sensor is reporting a number. Home Assistant
+// only bumps last_updated when the value changes, so a steady temperature isn't treated as stale.
+func (p *HomeAssistantTemperatureProvider) Temperature(ctx context.Context, query TemperatureQuery) (*TemperatureReading, error) {
+	var state struct {
+		State       string    `json:"state"`
+		LastUpdated time.Time `json:"last_updated"`
+	}
+	target := p.cfg.URL + "/api/states/" + url.PathEscape(p.cfg.Entity)
+	if err := getJSON(ctx, p.client, target, p.cfg.Token, &state); err != nil {
+		return nil, err
+	}
+
+	value, err := strconv.ParseFloat(state.State, 64)
+	if err != nil {
+		// "unavailable" or "unknown"
+		return nil, ErrNoTemperature
+	}
+	return &TemperatureReading{Value: value, Source: models.TemperatureSourceHomeAssistant, At: state.LastUpdated}, nil
+}
+
+// getJSON fetches a JSON document, optionally with a bearer token
+func getJSON(ctx context.Context, client *http.Client, target, token string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Redacted(), resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// TemperatureService resolves the temperature for a prediction by asking the available
+// providers in the user's priority order and taking the first answer
+type TemperatureService struct {
+	db         *gorm.DB
+	defaults   []string
+	sensor     *SensorTemperatureProvider
+	weatherURL string
+
+	mu        sync.RWMutex // providers and weather change when the setup wizard locates the house
+	providers map[string]TemperatureProvider
+	weather   *WeatherTemperatureProvider // nil without a configured location
+}
+
+// NewTemperatureService creates a temperature service with every provider the configuration enables
+func NewTemperatureService(cfg config.TemperatureConfig) *TemperatureService {
+	defaults := cfg.Sources
+	if len(defaults) == 0 {
+		defaults = models.TemperatureSources
+	}
+	s := &TemperatureService{
+		db:         database.GetDB(),
+		defaults:   defaults,
+		sensor:     NewSensorTemperatureProvider(cfg.MaxAge),
+		weatherURL: cfg.Weather.URL,
+		providers:  make(map[string]TemperatureProvider),
+	}
+	s.Register(manualTemperature{})
+	s.Register(s.sensor)
+	if cfg.Weather.Latitude != nil && cfg.Weather.Longitude != nil {
+		s.SetWeatherLocation(*cfg.Weather.Latitude, *cfg.Weather.Longitude)
+	}
+	if ha := cfg.HomeAssistant; ha.URL != "" && ha.Token != "" && ha.Entity != "" {
+		s.Register(NewHomeAssistantTemperatureProvider(ha))
+	}
+	return s
+}
+
+// Register adds or replaces the provider for its source
+func (s *TemperatureService) Register(provider TemperatureProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.providers[provider.Source()] = provider
+}
+
+// SetWeatherLocation points the weather provider at the house, enabling it if it wasn't
+func (s *TemperatureService) SetWeatherLocation(latitude, longitude float64) {
+	weather := NewWeatherTemperatureProvider(config.WeatherConfig{URL: s.weatherURL, Latitude: &latitude, Longitude: &longitude})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weather = weather
+	s.providers[weather.Source()] = weather
+}
+
+// HasWeather reports whether the weather provider knows where the house is
+func (s *TemperatureService) HasWeather() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.weather != nil
+}
+
+// ReportSensorReading stores a reading published by an MQTT sensor
+func (s *TemperatureService) ReportSensorReading(userID string, value float64, at time.Time) {
+	s.sensor.Report(userID, value, at)
+}
+
+// Resolve returns the temperature from the first source in the user's priority order that has one.
+// Sources that aren't configured are skipped and provider failures fall through to the next source.
+func (s *TemperatureService) Resolve(ctx context.Context, query TemperatureQuery) (*TemperatureReading, error) {
+	order, err := s.Sources(query.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range order {
+		s.mu.RLock()
+		provider, ok := s.providers[source]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		reading, err := provider.Temperature(ctx, query)
+		if err == nil {
+			return reading, nil
+		}
+		if !errors.Is(err, ErrNoTemperature) {
+			log.Printf("Temperature provider %s failed: %v", source, err)
+		}
+	}
+	return nil, ErrNoTemperature
+}
+
+// ForecastAt returns the forecast outdoor temperature for the hour nearest at, or
+// ErrNoTemperature when no weather location is configured or the forecast doesn't reach at
+func (s *TemperatureService) ForecastAt(ctx context.Context, at time.Time) (*TemperatureReading, error) {
+	s.mu.RLock()
+	weather := s.weather
+	s.mu.RUnlock()
+	if weather == nil {
+		return nil, ErrNoTemperature
+	}
+	forecast, err := weather.Forecast(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var nearest *TemperatureReading
+	for i := range forecast {
+		if gap := forecast[i].At.Sub(at).Abs(); gap <= time.Hour && (nearest == nil || gap < nearest.At.Sub(at).Abs()) {
+			nearest = &forecast[i]
+		}
+	}
+	if nearest == nil {
+		return nil, ErrNoTemperature
+	}
+	reading := *nearest
+	return &reading, nil
+}
+
+// Sources returns the user's temperature source priority, or the default when they haven't set one
+func (s *TemperatureService) Sources(userID string) ([]string, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	if user.TemperatureSources == "" {
+		return s.defaults, nil
+	}
+	return strings.Split(user.TemperatureSources, ","), nil
+}
+
+// Available lists the sources this deployment can currently read from
+func (s *TemperatureService) Available() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var available []string
+	for _, source := range models.TemperatureSources {
+		if _, ok := s.providers[source]; ok {
+			available = append(available, source)
+		}
+	}
+	return available
+}
+
+// SetSources stores the user's temperature source priority; an empty list restores the default
+func (s *TemperatureService) SetSources(userID string, sources []string) error {
+	seen := make(map[string]bool, len(sources))
+	order := make([]string, 0, len(sources))
+	for _, source := range sources {
+		if !models.IsValidTemperatureSource(source) {
+			return fmt.Errorf("%w: %q", ErrUnknownTemperatureSource, source)
+		}
+		if !seen[source] {
+			seen[source] = true
+			order = append(order, source)
+		}
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"temperature_sources", "updated_at"}),
+	}).Create(&models.User{ID: userID, TemperatureSources: strings.Join(order, ",")}).Error
+}
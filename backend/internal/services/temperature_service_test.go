@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"heat-logger/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHomeAssistantTemperatureProvider(t *testing.T) {
+	state := `{"state": "21.4", "last_updated": "2026-01-05T07:00:00Z"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/states/sensor.bathroom", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Write([]byte(state))
+	}))
+	defer server.Close()
+
+	provider := NewHomeAssistantTemperatureProvider(config.HomeAssistantConfig{URL: server.URL, Token: "secret", Entity: "sensor.bathroom"})
+
+	reading, err := provider.Temperature(context.Background(), TemperatureQuery{UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, 21.4, reading.Value)
+	assert.Equal(t, "home_assistant", reading.Source)
+
+	// An unavailable sensor hands over to the next source
+	state = `{"state": "unavailable", "last_updated": "2026-01-05T07:00:00Z"}`
+	_, err = provider.Temperature(context.Background(), TemperatureQuery{UserID: "user1"})
+	assert.ErrorIs(t, err, ErrNoTemperature)
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrMergeSameUser is returned when a user is merged into themselves
+var ErrMergeSameUser = errors.New("cannot merge a user into itself")
+
+// errMergeDryRun rolls back a merge that was only asked what it would do
+var errMergeDryRun = errors.New("dry run")
+
+// mergedTables hold per-user rows that move to the merged user as they are. Sessions, sync
+// peers and admin jobs stay with the user they were made for.
+var mergedTables = []struct {
+	name  string // key in the report's counts
+	table string
+}{
+	{"annotations", "annotations"},
+	{"scheduleOverrides", "schedule_overrides"},
+	{"feedbackPrompts", "feedback_prompts"},
+	{"notifications", "notifications"},
+	{"savedReports", "saved_reports"},
+	{"reportSubscriptions", "report_subscriptions"},
+	{"exportJobs", "export_jobs"},
+}
+
+// namedTables hold per-user rows whose names are unique per user. A merged row whose name the
+// other user already uses is renamed.
+var namedTables = []struct {
+	name  string
+	table string
+}{
+	{"presets", "presets"},
+	{"members", "members"},
+	{"scheduleTemplates", "schedule_templates"},
+	{"importMappings", "import_mappings"},
+}
+
+// UserMergeReport is what merging one user into another moved, or would move on a dry run
+type UserMergeReport struct {
+	Into      string            `json:"into"`
+	From      string            `json:"from"`
+	Counts    map[string]int64  `json:"counts"`    // rows moved, by kind
+	SampleIDs []string          `json:"sampleIds"` // some of the records moved
+	Renamed   []UserMergeRename `json:"renamed"`
+	Dropped   map[string]int64  `json:"dropped"`  // rows left behind because the other user had their own
+	Settings  []string          `json:"settings"` // settings taken over because the other user had none
+}
+
+// UserMergeRename is a merged row renamed because the other user had one with the same name
+type UserMergeRename struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MergeUsers moves everything of from into into: records, presets, members, schedules,
+// devices, reports, notifications and their routes, and the prediction log, with the settings into hasn't set
+// itself. Records are re-signed for their new owner. from is disabled afterwards so nothing is
+// logged under it again, and both users' cached learning state is cleared. A dry run reports the
+// same without changing anything.
+func (s *UserService) MergeUsers(into, from string, dryRun bool) (*UserMergeReport, error) {
+	if into == from {
+		return nil, ErrMergeSameUser
+	}
+	if _, err := s.GetUser(from); err != nil {
+		return nil, err
+	}
+
+	report := &UserMergeReport{
+		Into:      into,
+		From:      from,
+		Counts:    map[string]int64{},
+		SampleIDs: []string{},
+		Renamed:   []UserMergeRename{},
+		Dropped:   map[string]int64{},
+		Settings:  []string{},
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := mergeRecords(tx, into, from, report); err != nil {
+			return err
+		}
+		for _, named := range namedTables {
+			if err := mergeNamed(tx, named.table, named.name, into, from, report); err != nil {
+				return err
+			}
+		}
+		for _, moved := range mergedTables {
+			result := tx.Table(moved.table).Where("user_id = ?", from).Update("user_id", into)
+			if result.Error != nil {
+				return result.Error
+			}
+			report.Counts[moved.name] = result.RowsAffected
+		}
+		if err := mergeBoilerProfiles(tx, into, from, report); err != nil {
+			return err
+		}
+		if err := mergeNotificationRoutes(tx, into, from, report); err != nil {
+			return err
+		}
+		if err := mergeHousehold(tx, into, from, report); err != nil {
+			return err
+		}
+		if err := mergeSettings(tx, into, from, report); err != nil {
+			return err
+		}
+		// A log kept in the operational database moves in the same transaction
+		if s.logs == s.db {
+			if err := mergePredictionLogs(tx, into, from, report); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errMergeDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errMergeDryRun) {
+		return nil, err
+	}
+	// A log store of its own can't join the transaction; should it fail, merging again moves the rest
+	if s.logs != s.db {
+		err := s.logs.Transaction(func(tx *gorm.DB) error {
+			if err := mergePredictionLogs(tx, into, from, report); err != nil {
+				return err
+			}
+			if dryRun {
+				return errMergeDryRun
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errMergeDryRun) {
+			return nil, err
+		}
+	}
+
+	if !dryRun {
+		s.resetLearning(into)
+		s.resetLearning(from)
+		for _, fn := range s.onMerged {
+			fn(into, from)
+		}
+	}
+	return report, nil
+}
+
+// mergePredictionLogs moves the prediction log
+func mergePredictionLogs(tx *gorm.DB, into, from string, report *UserMergeReport) error {
+	result := tx.Model(&models.PredictionLog{}).Where("user_id = ?", from).Update("user_id", into)
+	report.Counts["predictionLogs"] = result.RowsAffected
+	return result.Error
+}
+
+// mergeRecords moves the records one at a time, since the owner is part of their checksum
+func mergeRecords(tx *gorm.DB, into, from string, report *UserMergeReport) error {
+	var records []models.DailyRecord
+	if err := tx.Where("user_id = ?", from).Order("date DESC").Find(&records).Error; err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].UserID = into
+		if err := tx.Save(&records[i]).Error; err != nil {
+			return err
+		}
+		if len(report.SampleIDs) < dryRunSampleSize {
+			report.SampleIDs = append(report.SampleIDs, records[i].ID)
+		}
+	}
+	report.Counts["records"] = int64(len(records))
+	return nil
+}
+
+// mergeNamed moves rows whose names are unique per user, suffixing the old owner to the names
+// the new one already uses
+func mergeNamed(tx *gorm.DB, table, kind, into, from string, report *UserMergeReport) error {
+	var rows []struct {
+		ID   string
+		Name string
+	}
+	if err := tx.Table(table).Select("id", "name").Where("user_id = ?", from).Find(&rows).Error; err != nil {
+		return err
+	}
+	var names []string
+	if err := tx.Table(table).Where("user_id = ?", into).Pluck("name", &names).Error; err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(names)+len(rows))
+	for _, name := range names {
+		taken[strings.ToLower(name)] = true
+	}
+
+	for _, row := range rows {
+		name := row.Name
+		for n := 1; taken[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%s)", row.Name, from)
+			if n > 1 {
+				name = fmt.Sprintf("%s (%s %d)", row.Name, from, n)
+			}
+		}
+		taken[strings.ToLower(name)] = true
+		if err := tx.Table(table).Where("id = ?", row.ID).Updates(map[string]interface{}{"user_id": into, "name": name}).Error; err != nil {
+			return err
+		}
+		if name != row.Name {
+			report.Renamed = append(report.Renamed, UserMergeRename{Kind: kind, ID: row.ID, From: row.Name, To: name})
+		}
+	}
+	report.Counts[kind] = int64(len(rows))
+	return nil
+}
+
+// mergeBoilerProfiles moves the profiles of devices into has none for. Its own calibration of a
+// device wins over the merged user's.
+func mergeBoilerProfiles(tx *gorm.DB, into, from string, report *UserMergeReport) error {
+	var devices []string
+	if err := tx.Model(&models.BoilerProfile{}).Where("user_id = ?", into).Pluck("device_id", &devices).Error; err != nil {
+		return err
+	}
+	moved := tx.Model(&models.BoilerProfile{}).Where("user_id = ?", from)
+	if len(devices) > 0 {
+		moved = moved.Where("device_id NOT IN ?", devices)
+	}
+	result := moved.Update("user_id", into)
+	if result.Error != nil {
+		return result.Error
+	}
+	report.Counts["boilerProfiles"] = result.RowsAffected
+
+	result = tx.Where("user_id = ?", from).Delete(&models.BoilerProfile{})
+	if result.Error != nil {
+		return result.Error
+	}
+	report.Dropped["boilerProfiles"] = result.RowsAffected
+	return nil
+}
+
+// mergeNotificationRoutes moves the notification routes into has none like: a route to a channel
+// and target into already has would only deliver everything twice, so its own is kept
+func mergeNotificationRoutes(tx *gorm.DB, into, from string, report *UserMergeReport) error {
+	var kept []models.NotificationRoute
+	if err := tx.Select("channel", "target").Where("user_id = ?", into).Find(&kept).Error; err != nil {
+		return err
+	}
+	var routes []models.NotificationRoute
+	if err := tx.Where("user_id = ?", from).Find(&routes).Error; err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(kept))
+	for _, route := range kept {
+		taken[route.Channel+"\x00"+route.Target] = true
+	}
+
+	var moved, dropped []string
+	for _, route := range routes {
+		key := route.Channel + "\x00" + route.Target
+		if taken[key] {
+			dropped = append(dropped, route.ID)
+			continue
+		}
+		taken[key] = true
+		moved = append(moved, route.ID)
+	}
+	if len(moved) > 0 {
+		if err := tx.Model(&models.NotificationRoute{}).Where("id IN ?", moved).Update("user_id", into).Error; err != nil {
+			return err
+		}
+	}
+	if len(dropped) > 0 {
+		if err := tx.Where("id IN ?", dropped).Delete(&models.NotificationRoute{}).Error; err != nil {
+			return err
+		}
+	}
+	report.Counts["notificationRoutes"] = int64(len(moved))
+	report.Dropped["notificationRoutes"] = int64(len(dropped))
+	return nil
+}
+
+// mergeHousehold hands from's household to into. Accounts in from's household, and invites to
+// it, move to into's; into leaves from's household, since it is now into's own. from's place in
+// another household moves to into unless into already has one.
+func mergeHousehold(tx *gorm.DB, into, from string, report *UserMergeReport) error {
+	if err := tx.Where("user_id = ? AND household_id = ?", into, from).Delete(&models.HouseholdMembership{}).Error; err != nil {
+		return err
+	}
+	result := tx.Model(&models.HouseholdMembership{}).Where("household_id = ?", from).Update("household_id", into)
+	if result.Error != nil {
+		return result.Error
+	}
+	report.Counts["householdMembers"] = result.RowsAffected
+	if err := tx.Model(&models.HouseholdInvite{}).Where("household_id = ?", from).Update("household_id", into).Error; err != nil {
+		return err
+	}
+
+	var memberships int64
+	if err := tx.Model(&models.HouseholdMembership{}).Where("user_id = ?", into).Count(&memberships).Error; err != nil {
+		return err
+	}
+	if memberships == 0 {
+		result = tx.Model(&models.HouseholdMembership{}).Where("user_id = ? AND household_id <> ?", from, into).Update("user_id", into)
+		if result.Error != nil {
+			return result.Error
+		}
+		report.Counts["householdMemberships"] = result.RowsAffected
+	}
+	result = tx.Where("user_id = ?", from).Delete(&models.HouseholdMembership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	report.Dropped["householdMemberships"] = result.RowsAffected
+	return nil
+}
+
+// mergeSettings copies the account settings into left at their defaults from from, then
+// disables from
+func mergeSettings(tx *gorm.DB, into, from string, report *UserMergeReport) error {
+	var source, target models.User
+	if err := tx.Where("id = ?", from).Limit(1).Find(&source).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("id = ?", into).Limit(1).Find(&target).Error; err != nil {
+		return err
+	}
+	target.ID = into
+
+	take := func(name string, unset bool, set func()) {
+		if unset {
+			set()
+			report.Settings = append(report.Settings, name)
+		}
+	}
+	take("email", target.Email == "" && source.Email != "", func() { target.Email = source.Email })
+	take("name", target.Name == "" && source.Name != "", func() { target.Name = source.Name })
+	take("temperatureSources", target.TemperatureSources == "" && source.TemperatureSources != "", func() { target.TemperatureSources = source.TemperatureSources })
+	take("quietHours", target.QuietHours == "" && source.QuietHours != "", func() { target.QuietHours = source.QuietHours })
+	take("notificationDailyLimit", target.NotificationDailyLimit == nil && source.NotificationDailyLimit != nil, func() { target.NotificationDailyLimit = source.NotificationDailyLimit })
+	take("stepCapFraction", target.StepCapFraction == nil && source.StepCapFraction != nil, func() { target.StepCapFraction = source.StepCapFraction })
+	// Rounding settings only make sense together
+	take("rounding", target.RoundingMode == "" && target.RoundingStep == 0 && (source.RoundingMode != "" || source.RoundingStep != 0), func() {
+		target.RoundingMode, target.RoundingStep, target.RoundingHysteresis = source.RoundingMode, source.RoundingStep, source.RoundingHysteresis
+	})
+	take("region", target.Region == "" && source.Region != "", func() { target.Region = source.Region })
+	take("targetSatisfaction", target.TargetSatisfaction == nil && source.TargetSatisfaction != nil, func() { target.TargetSatisfaction = source.TargetSatisfaction })
+	if err := tx.Save(&target).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	source.ID, source.Disabled, source.DisabledAt = from, true, &now
+	return tx.Save(&source).Error
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Model maturity levels, derived from how many records a user's model can learn from
+const (
+	MaturityColdStart   = "cold_start" // no records, or too few to personalise with yet
+	MaturityLearning    = "learning"
+	MaturityEstablished = "established"
+	MaturityMature      = "mature"
+)
+
+var (
+	// ErrUserNotFound is returned when a user has neither records nor account state
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidStepCap is returned for a step cap outside (0, 1]
+	ErrInvalidStepCap = errors.New("step cap must be greater than 0 and at most 1")
+)
+
+// UserService handles account-level operations for users
+type UserService struct {
+	db          *gorm.DB
+	logs        *gorm.DB // the log store, which may be apart from db
+	minPersonal int64    // training records before the predictor personalises
+	onReset     []func(userID string)
+	onMerged    []func(into, from string)
+}
+
+// NewUserService creates a new user service instance
+func NewUserService() *UserService {
+	return &UserService{
+		db:   database.GetDB(),
+		logs: database.GetLogDB(),
+	}
+}
+
+// UserSummary describes a user for the admin API
+type UserSummary struct {
+	UserID                   string     `json:"userId"`
+	RecordCount              int64      `json:"recordCount"`
+	TrainingRecordCount      int64      `json:"trainingRecordCount"`
+	ModelMaturity            string     `json:"modelMaturity"`
+	RecordsUntilPersonalized int64      `json:"recordsUntilPersonalized,omitempty"` // training records still needed before the predictor uses the user's own
+	Disabled                 bool       `json:"disabled"`
+	ModelResetAt             *time.Time `json:"modelResetAt,omitempty"`
+}
+
+// ModelMaturity classifies how established a user's model is from its training record count.
+// Below minPersonal records the predictor doesn't use them yet, so the model is still cold.
+func ModelMaturity(trainingRecords, minPersonal int64) string {
+	switch {
+	case trainingRecords == 0 || trainingRecords < minPersonal:
+		return MaturityColdStart
+	case trainingRecords < 10:
+		return MaturityLearning
+	case trainingRecords < 30:
+		return MaturityEstablished
+	default:
+		return MaturityMature
+	}
+}
+
+// SetMinPersonalRecords sets how many training records the predictor needs before it uses a
+// user's own history, for the maturity reported
+func (s *UserService) SetMinPersonalRecords(n int) {
+	s.minPersonal = int64(n)
+}
+
+// setMaturity fills in the summary's maturity from its training record count
+func (s *UserService) setMaturity(summary *UserSummary) {
+	summary.ModelMaturity = ModelMaturity(summary.TrainingRecordCount, s.minPersonal)
+	if summary.TrainingRecordCount < s.minPersonal {
+		summary.RecordsUntilPersonalized = s.minPersonal - summary.TrainingRecordCount
+	}
+}
+
+// OnModelReset registers a callback that clears cached learning state for a user
+func (s *UserService) OnModelReset(fn func(userID string)) {
+	s.onReset = append(s.onReset, fn)
+}
+
+// OnUsersMerged registers a callback run after one user was merged into another, for state
+// kept about either of them outside the database
+func (s *UserService) OnUsersMerged(fn func(into, from string)) {
+	s.onMerged = append(s.onMerged, fn)
+}
+
+type userRecordCounts struct {
+	UserID              string
+	RecordCount         int64
+	TrainingRecordCount int64
+}
+
+// ListUsers returns a summary for every user with records or account state
+func (s *UserService) ListUsers() ([]UserSummary, error) {
+	var counts []userRecordCounts
+	err := s.db.Model(&models.DailyRecord{}).
+		Select("user_id, COUNT(*) AS record_count, SUM(CASE WHEN excluded_from_training THEN 0 ELSE 1 END) AS training_record_count").
+		Group("user_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := s.db.Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]*UserSummary, len(counts))
+	for _, c := range counts {
+		summaries[c.UserID] = &UserSummary{
+			UserID:              c.UserID,
+			RecordCount:         c.RecordCount,
+			TrainingRecordCount: c.TrainingRecordCount,
+		}
+	}
+	for _, u := range users {
+		summary, ok := summaries[u.ID]
+		if !ok {
+			summary = &UserSummary{UserID: u.ID}
+			summaries[u.ID] = summary
+		}
+		summary.Disabled = u.Disabled
+		summary.ModelResetAt = u.ModelResetAt
+	}
+
+	result := make([]UserSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		s.setMaturity(summary)
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result, nil
+}
+
+// GetUser returns the summary for a single user
+func (s *UserService) GetUser(userID string) (*UserSummary, error) {
+	summary := &UserSummary{UserID: userID}
+
+	err := s.db.Model(&models.DailyRecord{}).Where("user_id = ?", userID).Count(&summary.RecordCount).Error
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Model(&models.DailyRecord{}).
+		Where("user_id = ? AND excluded_from_training = ?", userID, false).
+		Count(&summary.TrainingRecordCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	result := s.db.Where("id = ?", userID).Limit(1).Find(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 && summary.RecordCount == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	summary.Disabled = user.Disabled
+	summary.ModelResetAt = user.ModelResetAt
+	s.setMaturity(summary)
+	return summary, nil
+}
+
+// IsDisabled reports whether the user's account has been disabled
+func (s *UserService) IsDisabled(userID string) (bool, error) {
+	var user models.User
+	result := s.db.Where("id = ?", userID).Limit(1).Find(&user)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return user.Disabled, nil
+}
+
+// SetDisabled enables or disables a user's account
+func (s *UserService) SetDisabled(userID string, disabled bool) error {
+	user := models.User{ID: userID, Disabled: disabled}
+	if disabled {
+		now := time.Now()
+		user.DisabledAt = &now
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"disabled", "disabled_at", "updated_at"}),
+	}).Create(&user).Error
+}
+
+// ResetModel excludes the user's records from training and clears cached learning state.
+// A nil from/to leaves that side of the date range open. Raw history is kept.
+func (s *UserService) ResetModel(userID string, from, to *time.Time) (int64, error) {
+	var excluded int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.DailyRecord{}).Where("user_id = ? AND excluded_from_training = ?", userID, false)
+		if from != nil {
+			query = query.Where("date >= ?", *from)
+		}
+		if to != nil {
+			query = query.Where("date <= ?", *to)
+		}
+		result := query.Update("excluded_from_training", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		excluded = result.RowsAffected
+
+		now := time.Now()
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"model_reset_at", "updated_at"}),
+		}).Create(&models.User{ID: userID, ModelResetAt: &now}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.resetLearning(userID)
+	return excluded, nil
+}
+
+// resetLearning clears the cached learning state of a user whose training records changed
+func (s *UserService) resetLearning(userID string) {
+	for _, fn := range s.onReset {
+		fn(userID)
+	}
+}
+
+// StepCap returns the user's own prediction step cap, nil when they use the default
+func (s *UserService) StepCap(userID string) (*float64, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	return user.StepCapFraction, nil
+}
+
+// SetStepCap stores the user's prediction step cap; nil restores the default
+func (s *UserService) SetStepCap(userID string, stepCap *float64) error {
+	if stepCap != nil && (*stepCap <= 0 || *stepCap > 1) {
+		return ErrInvalidStepCap
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"step_cap_fraction", "updated_at"}),
+	}).Create(&models.User{ID: userID, StepCapFraction: stepCap}).Error
+}
+
+// RoundingPolicy returns the user's own rounding preferences; unset fields are zero
+func (s *UserService) RoundingPolicy(userID string) (RoundingPolicy, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return RoundingPolicy{}, err
+	}
+	return RoundingPolicy{Mode: user.RoundingMode, Step: user.RoundingStep, Hysteresis: user.RoundingHysteresis}, nil
+}
+
+// SetRoundingPolicy stores the user's rounding preferences; zero fields restore the default
+func (s *UserService) SetRoundingPolicy(userID string, policy RoundingPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rounding_mode", "rounding_step", "rounding_hysteresis", "updated_at"}),
+	}).Create(&models.User{ID: userID, RoundingMode: policy.Mode, RoundingStep: policy.Step, RoundingHysteresis: policy.Hysteresis}).Error
+}
+
+// UpsertIdentity records the profile and role of a user who logged in through an identity provider
+func (s *UserService) UpsertIdentity(userID, email, name, role string) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email", "name", "role", "updated_at"}),
+	}).Create(&models.User{ID: userID, Email: email, Name: name, Role: role}).Error
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+	"heat-logger/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestModelMaturity(t *testing.T) {
+	for _, tc := range []struct {
+		records, minPersonal int64
+		want                 string
+	}{
+		{0, 0, MaturityColdStart},
+		{1, 0, MaturityLearning},
+		{2, 3, MaturityColdStart},
+		{3, 3, MaturityLearning},
+		{12, 3, MaturityEstablished},
+		{30, 3, MaturityMature},
+	} {
+		assert.Equal(t, tc.want, ModelMaturity(tc.records, tc.minPersonal), "%d records, %d needed", tc.records, tc.minPersonal)
+	}
+
+	service := &UserService{}
+	service.SetMinPersonalRecords(3)
+	summary := &UserSummary{TrainingRecordCount: 1}
+	service.setMaturity(summary)
+	assert.Equal(t, MaturityColdStart, summary.ModelMaturity)
+	assert.Equal(t, int64(2), summary.RecordsUntilPersonalized)
+}
+
+// newResetDB stores a shower a day for user1 over the last five days, and one for user2, returning
+// the service and the days of user1's showers, oldest first
+func newResetDB(t *testing.T) (*UserService, []time.Time) {
+	logger.Default = logger.Discard
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "users.db")}}
+	require.NoError(t, database.InitDatabase(cfg))
+	db := database.GetDB()
+
+	today := time.Now().Truncate(24 * time.Hour)
+	var days []time.Time
+	for i := 5; i >= 1; i-- {
+		day := today.AddDate(0, 0, -i)
+		days = append(days, day)
+		require.NoError(t, db.Create(&models.DailyRecord{UserID: "user1", Date: day, ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50}).Error)
+	}
+	require.NoError(t, db.Create(&models.DailyRecord{UserID: "user2", Date: days[2], ShowerDuration: 10, AverageTemperature: 15, HeatingTime: 20, Satisfaction: 50}).Error)
+	return NewUserService(), days
+}
+
+// excludedDays returns the days of the user's records that are out of training, oldest first
+func excludedDays(t *testing.T, userID string) []time.Time {
+	var records []models.DailyRecord
+	require.NoError(t, database.GetDB().Where("user_id = ? AND excluded_from_training = ?", userID, true).Order("date").Find(&records).Error)
+	days := []time.Time{}
+	for _, record := range records {
+		days = append(days, record.Date)
+	}
+	return days
+}
+
+func TestUserService_ResetModel(t *testing.T) {
+	service, days := newResetDB(t)
+	var reset []string
+	service.OnModelReset(func(userID string) { reset = append(reset, userID) })
+
+	excluded, err := service.ResetModel("user1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), excluded)
+	assert.Len(t, excludedDays(t, "user1"), len(days))
+	assert.Empty(t, excludedDays(t, "user2"), "other users keep their training records")
+	assert.Equal(t, []string{"user1"}, reset)
+
+	var user models.User
+	require.NoError(t, database.GetDB().First(&user, "id = ?", "user1").Error)
+	assert.NotNil(t, user.ModelResetAt)
+
+	// Records already out of training aren't counted again
+	excluded, err = service.ResetModel("user1", nil, nil)
+	require.NoError(t, err)
+	assert.Zero(t, excluded)
+}
+
+func TestUserService_ResetModelRange(t *testing.T) {
+	t.Run("both bounds, inclusive", func(t *testing.T) {
+		service, days := newResetDB(t)
+		excluded, err := service.ResetModel("user1", &days[1], &days[3])
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), excluded)
+		assert.Len(t, excludedDays(t, "user1"), 3)
+		assert.True(t, excludedDays(t, "user1")[0].Equal(days[1]))
+		assert.Empty(t, excludedDays(t, "user2"))
+	})
+
+	t.Run("open start", func(t *testing.T) {
+		service, days := newResetDB(t)
+		excluded, err := service.ResetModel("user1", nil, &days[1])
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), excluded)
+	})
+
+	t.Run("open end", func(t *testing.T) {
+		service, days := newResetDB(t)
+		excluded, err := service.ResetModel("user1", &days[4], nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), excluded)
+		assert.True(t, excludedDays(t, "user1")[0].Equal(days[4]))
+	})
+
+	t.Run("empty range", func(t *testing.T) {
+		service, days := newResetDB(t)
+		from, to := days[1].Add(time.Hour), days[2].Add(-time.Hour)
+		excluded, err := service.ResetModel("user1", &from, &to)
+		require.NoError(t, err)
+		assert.Zero(t, excluded)
+		assert.Empty(t, excludedDays(t, "user1"))
+	})
+
+	t.Run("inverted range", func(t *testing.T) {
+		service, days := newResetDB(t)
+		var reset []string
+		service.OnModelReset(func(userID string) { reset = append(reset, userID) })
+		excluded, err := service.ResetModel("user1", &days[3], &days[1])
+		require.NoError(t, err)
+		assert.Zero(t, excluded)
+		assert.Empty(t, excludedDays(t, "user1"), "nothing falls between a start after the end")
+		assert.Equal(t, []string{"user1"}, reset, "the reset itself is still recorded")
+	})
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageActivity counts something a user did, over all time and recently
+type UsageActivity struct {
+	Total       int64      `json:"total"`
+	Last24Hours int64      `json:"last24Hours"`
+	Last30Days  int64      `json:"last30Days"`
+	LastAt      *time.Time `json:"lastAt"` // null when there was none
+}
+
+// UsageStorage is how much a user's rows take, per table. Bytes add up the stored values, so
+// they are approximate: indexes and page overhead aren't counted.
+type UsageStorage struct {
+	Rows   int64                   `json:"rows"`
+	Bytes  int64                   `json:"bytes"`
+	Tables map[string]UsageStorage `json:"tables,omitempty"` // by table name
+}
+
+// UserUsage is what the app knows about a user and how much they use it
+type UserUsage struct {
+	UserID           string           `json:"userId"`
+	Predictions      UsageActivity    `json:"predictions"`      // heating times served
+	Feedback         UsageActivity    `json:"feedback"`         // records stored
+	FeedbackBySource map[string]int64 `json:"feedbackBySource"` // records by source
+	LastSyncedAt     *time.Time       `json:"lastSyncedAt"`     // last delta sync from a client; null when it never synced
+	Storage          UsageStorage     `json:"storage"`
+}
+
+// MarkSynced records when the user's client last ran a delta sync
+func (s *UserService) MarkSynced(userID string, at time.Time) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_synced_at", "updated_at"}),
+	}).Create(&models.User{ID: userID, LastSyncedAt: &at}).Error
+}
+
+// Usage reports how many predictions and records the user has, when their client last synced
+// and how much they take in the database. Every table with a user_id column is counted, in the
+// log store too; JSONL log archives aren't.
+func (s *UserService) Usage(userID string, now time.Time) (*UserUsage, error) {
+	usage := &UserUsage{UserID: userID, FeedbackBySource: map[string]int64{}}
+	var err error
+	if usage.Predictions, err = usageActivity(s.logs, &models.PredictionLog{}, userID, now); err != nil {
+		return nil, err
+	}
+	if usage.Feedback, err = usageActivity(s.db, &models.DailyRecord{}, userID, now); err != nil {
+		return nil, err
+	}
+
+	var sources []struct {
+		Source  string
+		Records int64
+	}
+	err = s.db.Model(&models.DailyRecord{}).Select("source, COUNT(*) AS records").
+		Where("user_id = ?", userID).Group("source").Scan(&sources).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		usage.FeedbackBySource[source.Source] = source.Records
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	usage.LastSyncedAt = user.LastSyncedAt
+
+	usage.Storage = UsageStorage{Tables: map[string]UsageStorage{}}
+	stores := []*gorm.DB{s.db}
+	if s.logs != s.db {
+		stores = append(stores, s.logs)
+	}
+	for _, db := range stores {
+		if err := userStorage(db, userID, &usage.Storage); err != nil {
+			return nil, err
+		}
+	}
+	return usage, nil
+}
+
+// usageActivity counts the user's rows of model by their created_at
+func usageActivity(db *gorm.DB, model interface{}, userID string, now time.Time) (UsageActivity, error) {
+	var activity UsageActivity
+	err := db.Model(model).Where("user_id = ?", userID).
+		Select("COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0) AS last24_hours, "+
+			"COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0) AS last30_days",
+			now.Add(-24*time.Hour), now.AddDate(0, 0, -30)).
+		Scan(&activity).Error
+	if err != nil || activity.Total == 0 {
+		return activity, err
+	}
+	// Fetched as a column rather than MAX(), which SQLite hands back as text
+	var last []time.Time
+	err = db.Model(model).Where("user_id = ?", userID).Order("created_at DESC").Limit(1).Pluck("created_at", &last).Error
+	if err == nil && len(last) == 1 {
+		activity.LastAt = &last[0]
+	}
+	return activity, err
+}
+
+// userStorage adds the user's rows in every table of db with a user_id column, and their row
+// in users, to storage
+func userStorage(db *gorm.DB, userID string, storage *UsageStorage) error {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return err
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		key := "user_id"
+		if table == (models.User{}).TableName() {
+			key = "id"
+		} else if !db.Migrator().HasColumn(table, "user_id") {
+			continue
+		}
+		columns, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			return err
+		}
+		sizes := make([]string, len(columns))
+		for i, column := range columns {
+			sizes[i] = fmt.Sprintf("COALESCE(LENGTH(CAST(%q AS BLOB)), 0)", column.Name())
+		}
+		var row struct {
+			Records int64
+			Bytes   int64
+		}
+		err = db.Table(table).Select("COUNT(*) AS records, COALESCE(SUM("+strings.Join(sizes, " + ")+"), 0) AS bytes").
+			Where(fmt.Sprintf("%q = ?", key), userID).Scan(&row).Error
+		if err != nil {
+			return err
+		}
+		if row.Records == 0 {
+			continue
+		}
+		entry := storage.Tables[table]
+		entry.Rows += row.Records
+		entry.Bytes += row.Bytes
+		storage.Tables[table] = entry
+		storage.Rows += row.Records
+		storage.Bytes += row.Bytes
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"heat-logger/internal/config"
+	"heat-logger/internal/models"
+)
+
+// WebhookPayloadError is returned when an event matched a rule but lacks a value the rule maps
+type WebhookPayloadError struct {
+	Rule string
+	Path string
+}
+
+func (e *WebhookPayloadError) Error() string {
+	return fmt.Sprintf("webhook rule %q has no usable number at %s", e.Rule, e.Path)
+}
+
+// WebhookResult is what one matched rule did with an event
+type WebhookResult struct {
+	Rule     string          `json:"rule"`
+	Action   string          `json:"action"`
+	Device   *DeviceStatus   `json:"device,omitempty"`   // for device_event
+	Shower   *DetectedShower `json:"shower,omitempty"`   // for a humidity_reading that completed a shower
+	RecordID string          `json:"recordId,omitempty"` // for record; empty while the write is buffered
+}
+
+// WebhookService turns events pushed by third-party services into device events, sensor
+// readings and records, through the configured rules
+type WebhookService struct {
+	secret       []byte
+	rules        []config.WebhookRule
+	devices      *DeviceStateService
+	temperatures *TemperatureService
+	records      *RecordService
+	showers      *ShowerDetectionService
+}
+
+// NewWebhookService creates a new webhook service instance
+func NewWebhookService(cfg config.WebhookConfig, devices *DeviceStateService, temperatures *TemperatureService, records *RecordService, showers *ShowerDetectionService) *WebhookService {
+	return &WebhookService{
+		secret:       []byte(cfg.Secret),
+		rules:        cfg.Rules,
+		devices:      devices,
+		temperatures: temperatures,
+		records:      records,
+		showers:      showers,
+	}
+}
+
+// Authorized reports whether a request carries either the HMAC-SHA256 of its body in the form
+// "sha256=<hex>", or the secret itself as a bearer token for services that can't sign
+func (s *WebhookService) Authorized(body []byte, signature, bearer string) bool {
+	if len(s.secret) == 0 {
+		return false
+	}
+	if hexSum, ok := strings.CutPrefix(signature, "sha256="); ok {
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(body)
+		provided, err := hex.DecodeString(hexSum)
+		return err == nil && hmac.Equal(provided, mac.Sum(nil))
+	}
+	return bearer != "" && subtle.ConstantTimeCompare([]byte(bearer), s.secret) == 1
+}
+
+// webhookAction is a matched rule with the values it read from the event
+type webhookAction struct {
+	rule   config.WebhookRule
+	values map[string]float64
+	at     time.Time
+}
+
+// Handle applies every rule of the source that matches the event, in order. The values of all
+// matched rules are read before any is applied, so an event missing one is rejected as a whole.
+func (s *WebhookService) Handle(ctx context.Context, source string, payload map[string]interface{}, now time.Time) ([]WebhookResult, error) {
+	var actions []webhookAction
+	for _, rule := range s.rules {
+		if rule.Source != source || !webhookMatches(rule, payload) {
+			continue
+		}
+		action := webhookAction{rule: rule, values: map[string]float64{}, at: now}
+		for field, path := range rule.Fields {
+			value, _ := webhookValue(payload, path) // nil when missing, which is no number or time
+			if field == "at" {
+				if at, ok := webhookTime(value); ok {
+					action.at = at
+				}
+				continue
+			}
+			number, ok := webhookNumber(value)
+			if rule.Action == config.WebhookActionRecord && (field == "showerDuration" || field == "heatingTime") && number <= 0 {
+				ok = false // the record would be impossible
+			}
+			if !ok {
+				return nil, &WebhookPayloadError{Rule: rule.Name, Path: path}
+			}
+			action.values[field] = number
+		}
+		actions = append(actions, action)
+	}
+
+	results := []WebhookResult{}
+	for _, action := range actions {
+		result, err := s.apply(ctx, action)
+		if err != nil {
+			return results, fmt.Errorf("webhook rule %q: %w", action.rule.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// apply carries out one matched rule
+func (s *WebhookService) apply(ctx context.Context, action webhookAction) (WebhookResult, error) {
+	rule := action.rule
+	result := WebhookResult{Rule: rule.Name, Action: rule.Action}
+	switch rule.Action {
+	case config.WebhookActionDeviceEvent:
+		event := DeviceEvent{Type: rule.Event, HeatingTime: action.values["heatingTime"], At: action.at}
+		if temperature, ok := action.values["temperature"]; ok {
+			event.Temperature = &temperature
+		}
+		status, err := s.devices.Apply(rule.DeviceID, event)
+		if err != nil {
+			return result, err
+		}
+		result.Device = &status
+
+	case config.WebhookActionSensorReading:
+		s.temperatures.ReportSensorReading(rule.UserID, action.values["temperature"], action.at)
+
+	case config.WebhookActionHumidity:
+		shower, err := s.showers.Report(ctx, rule.UserID, rule.DeviceID, action.values["humidity"], action.at)
+		if err != nil {
+			return result, err
+		}
+		result.Shower = shower
+
+	case config.WebhookActionRecord:
+		record := &models.DailyRecord{
+			UserID:         rule.UserID,
+			DeviceID:       rule.DeviceID,
+			Date:           action.at,
+			ShowerDuration: action.values["showerDuration"],
+			HeatingTime:    action.values["heatingTime"],
+			Satisfaction:   models.SatisfactionPerfect, // a guess until the user reviews the record
+			Source:         models.RecordSourceSensor,
+		}
+		if satisfaction, ok := action.values["satisfaction"]; ok {
+			record.Satisfaction = satisfaction
+		}
+		if inlet, ok := action.values["inletTemperature"]; ok {
+			record.InletTemperature = &inlet
+		}
+		if temperature, ok := action.values["averageTemperature"]; ok {
+			record.AverageTemperature = temperature
+		} else if reading, err := s.temperatures.Resolve(ctx, TemperatureQuery{UserID: rule.UserID}); err == nil {
+			record.AverageTemperature = reading.Value
+		}
+		err := s.records.CreateRecord(record)
+		switch {
+		case errors.Is(err, ErrWriteBuffered):
+			// stored once the database recovers
+		case err != nil:
+			return result, err
+		default:
+			result.RecordID = record.ID
+		}
+	}
+	return result, nil
+}
+
+// webhookMatches reports whether every match path of the rule has its expected value
+func webhookMatches(rule config.WebhookRule, payload map[string]interface{}) bool {
+	for path, expected := range rule.Match {
+		value, ok := webhookValue(payload, path)
+		if !ok || fmt.Sprint(value) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookValue looks up a dotted path in a JSON payload
+func webhookValue(payload map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = payload
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// webhookNumber reads a JSON number, or a number sent as a string as IFTTT does
+func webhookNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// webhookTime reads an RFC 3339 time or Unix seconds
+func webhookTime(value interface{}) (time.Time, bool) {
+	if s, isString := value.(string); isString {
+		if at, err := time.Parse(time.RFC3339, s); err == nil {
+			return at, true
+		}
+	}
+	if seconds, ok := webhookNumber(value); ok {
+		return time.Unix(int64(seconds), 0), true
+	}
+	return time.Time{}, false
+}
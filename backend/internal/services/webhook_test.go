@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"heat-logger/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookService_Authorized(t *testing.T) {
+	s := NewWebhookService(config.WebhookConfig{Secret: "secret"}, nil, nil, nil, nil)
+	body := []byte(`{"event":"on"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, s.Authorized(body, signature, ""))
+	assert.False(t, s.Authorized([]byte(`{"event":"off"}`), signature, ""), "signature of another body")
+	assert.True(t, s.Authorized(body, "", "secret"))
+	assert.False(t, s.Authorized(body, "", "guess"))
+	assert.False(t, s.Authorized(body, "sha256=zz", "secret"), "a bad signature isn't rescued by the token")
+
+	disabled := NewWebhookService(config.WebhookConfig{}, nil, nil, nil, nil)
+	assert.False(t, disabled.Authorized(body, "", ""))
+}
+
+func TestWebhookService_DeviceEvents(t *testing.T) {
+	devices := NewDeviceStateService(config.DeviceConfig{})
+	s := NewWebhookService(config.WebhookConfig{Secret: "secret", Rules: []config.WebhookRule{
+		{Name: "on", Source: "shelly", Match: map[string]string{"params.switch.output": "true"}, Action: config.WebhookActionDeviceEvent, DeviceID: "boiler", Event: DeviceEventPlugOn},
+		{Name: "off", Source: "shelly", Match: map[string]string{"params.switch.output": "false"}, Action: config.WebhookActionDeviceEvent, DeviceID: "boiler", Event: DeviceEventPlugOff},
+		{Name: "water", Source: "shelly", Match: map[string]string{"params.sensor": "water"}, Action: config.WebhookActionDeviceEvent, DeviceID: "boiler", Event: DeviceEventTemperature,
+			Fields: map[string]string{"temperature": "params.value"}},
+	}}, devices, nil, nil, nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	results, err := s.Handle(ctx, "shelly", map[string]interface{}{
+		"params": map[string]interface{}{"switch": map[string]interface{}{"output": true}},
+	}, now)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "on", results[0].Rule)
+	assert.True(t, devices.State("boiler").PlugOn)
+
+	results, err = s.Handle(ctx, "ifttt", map[string]interface{}{
+		"params": map[string]interface{}{"switch": map[string]interface{}{"output": false}},
+	}, now)
+	require.NoError(t, err)
+	assert.Empty(t, results, "rules only apply to their own source")
+	assert.True(t, devices.State("boiler").PlugOn)
+
+	results, err = s.Handle(ctx, "shelly", map[string]interface{}{
+		"params": map[string]interface{}{"sensor": "water", "value": "47.5"},
+	}, now)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NotNil(t, devices.State("boiler").Temperature)
+	assert.Equal(t, 47.5, *devices.State("boiler").Temperature)
+
+	_, err = s.Handle(ctx, "shelly", map[string]interface{}{
+		"params": map[string]interface{}{"sensor": "water"},
+	}, now)
+	var payloadErr *WebhookPayloadError
+	require.ErrorAs(t, err, &payloadErr)
+	assert.Equal(t, "params.value", payloadErr.Path)
+}
@@ -0,0 +1,66 @@
+package testsupport
+
+import (
+	"testing"
+	"time"
+
+	"heat-logger/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_GoldenDatasets(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, name := range Datasets {
+		t.Run(name, func(t *testing.T) {
+			dataset, err := Load(name, now)
+			require.NoError(t, err)
+			require.NotEmpty(t, dataset.Records)
+
+			coldest, warmest := dataset.Records[0].AverageTemperature, dataset.Records[0].AverageTemperature
+			for i, record := range dataset.Records {
+				assert.Positive(t, record.ShowerDuration)
+				assert.Positive(t, record.HeatingTime)
+				assert.GreaterOrEqual(t, record.Satisfaction, float64(models.SatisfactionMin))
+				assert.LessOrEqual(t, record.Satisfaction, float64(models.SatisfactionMax))
+				assert.True(t, record.Date.Before(now))
+				if i > 0 {
+					assert.False(t, record.Date.Before(dataset.Records[i-1].Date), "records are oldest first")
+				}
+				coldest, warmest = min(coldest, record.AverageTemperature), max(warmest, record.AverageTemperature)
+			}
+			// Every dataset sees a range of weather, not one season's
+			assert.Greater(t, warmest-coldest, 10.0)
+		})
+	}
+
+	_, err := Load("missing", now)
+	assert.Error(t, err)
+}
+
+func TestDataset_RecordSource(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	dataset := MustLoad(HouseholdTwoSeasons, now)
+	assert.Equal(t, []string{"alice", "ben"}, dataset.Users())
+
+	alice, err := dataset.GetRecordsForPredictionByUser("alice", 5)
+	require.NoError(t, err)
+	require.Len(t, alice, 5)
+	for i, record := range alice {
+		assert.Equal(t, "alice", record.UserID)
+		if i > 0 {
+			assert.True(t, record.Date.Before(alice[i-1].Date), "newest first")
+		}
+	}
+	others, err := dataset.GetGlobalRecordsForPrediction("alice", 1000)
+	require.NoError(t, err)
+	for _, record := range others {
+		assert.Equal(t, "ben", record.UserID)
+	}
+
+	cutoff := now.AddDate(0, 0, -30)
+	for _, record := range dataset.Before(cutoff).Records {
+		assert.True(t, record.Date.Before(cutoff))
+	}
+}
@@ -0,0 +1,129 @@
+// Package client is a Go client for the Heat Logger API.
+//
+// The endpoint methods in routes_gen.go are generated from the server's route table
+// by `make sdk`; `make sdk-check` fails when they are out of date.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls the Heat Logger API
+type Client struct {
+	BaseURL        string // e.g. http://localhost:8080
+	HTTPClient     *http.Client
+	AcceptLanguage string // locale for error messages, e.g. "he"
+	AdminToken     string // sent as a bearer token when set, for /api/admin endpoints
+	SyncToken      string // sent as a bearer token for /api/sync endpoints instead of AdminToken
+	GridToken      string // sent as a bearer token for /api/grid endpoints instead of AdminToken
+	WebhookSecret  string // signs the body of /api/webhooks requests in X-Webhook-Signature
+}
+
+// New creates a client for the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	StatusCode int          `json:"-"`
+	Message    string       `json:"error"`
+	Details    []FieldError `json:"details,omitempty"`
+	Body       []byte       `json:"-"` // the raw response, for errors that carry more than a message
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("heat-logger API error %d: %s", e.StatusCode, e.Message)
+}
+
+// do sends a request and decodes the response into out. When out is an io.Writer the
+// raw body is copied into it instead, which is how file downloads are read.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.AcceptLanguage)
+	}
+	if strings.HasPrefix(path, "/api/webhooks/") && c.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(c.WebhookSecret))
+		mac.Write(payload)
+		req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	if strings.HasPrefix(path, "/api/sync/") && c.SyncToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.SyncToken)
+	} else if strings.HasPrefix(path, "/api/grid/") && c.GridToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.GridToken)
+	} else if c.AdminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AdminToken)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		apiErr.Body, _ = io.ReadAll(resp.Body)
+		if err := json.Unmarshal(apiErr.Body, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+
+	switch dst := out.(type) {
+	case nil:
+		return nil
+	case io.Writer:
+		_, err := io.Copy(dst, resp.Body)
+		return err
+	default:
+		return json.NewDecoder(resp.Body).Decode(dst)
+	}
+}
@@ -0,0 +1,195 @@
+// Package cron parses standard five-field cron expressions ("minute hour day-of-month month
+// day-of-week") and works out when they fire.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit i set when value i matches
+	domAny, dowAny                bool   // the field started with *, so only the other day field restricts
+}
+
+// field describes the values one position of an expression takes
+type field struct {
+	name     string
+	min, max int
+	names    []string // names for min, min+1, ... e.g. JAN for 1
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// Parse reads an expression such as "0 6 * * MON-FRI". Fields accept *, numbers, names for
+// months and weekdays, ranges (a-b), lists (a,b) and steps (*/n, a-b/n). Sunday is 0 or 7.
+// When both day fields are restricted a day matches either, as in standard cron.
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression needs %d fields, got %d", len(fields), len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		value, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = value
+	}
+	schedule := &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}
+	// 7 is another name for Sunday
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	return schedule, nil
+}
+
+// parseField turns one comma-separated field into its bit set
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", item[i+1:], f.name)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("range %q in %s field runs backwards", rangePart, f.name)
+			}
+		default:
+			value, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = value, value
+			// a/n means from a to the end of the range
+			if step > 1 {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value reads a number or name of the field
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, f.name)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s must be between %d and %d, got %d", f.name, f.min, f.max, n)
+	}
+	return n, nil
+}
+
+// searchDays is how far ahead Next looks; a schedule such as 30 February never fires
+const searchDays = 5 * 366
+
+// Next returns the first time the schedule fires after after, in after's location. Wall-clock
+// times skipped by a daylight saving change don't fire. It returns the zero time when the
+// schedule never fires.
+func (s *Schedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < searchDays; i++ {
+		if s.matchesDay(day) {
+			for hour := 0; hour < 24; hour++ {
+				if s.hour&(1<<uint(hour)) == 0 {
+					continue
+				}
+				for minute := 0; minute < 60; minute++ {
+					if s.minute&(1<<uint(minute)) == 0 {
+						continue
+					}
+					at := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+					if at.Hour() != hour || at.Minute() != minute {
+						continue // doesn't exist on this day
+					}
+					if at.After(after) {
+						return at
+					}
+				}
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return time.Time{}
+}
+
+// NextN returns the next n firing times after after
+func (s *Schedule) NextN(after time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for len(times) < n {
+		next := s.Next(after)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+		after = next
+	}
+	return times
+}
+
+// matchesDay reports whether the schedule fires at some time on day
+func (s *Schedule) matchesDay(day time.Time) bool {
+	if s.month&(1<<uint(day.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(day.Day())) != 0
+	dowMatch := s.dow&(1<<uint(day.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
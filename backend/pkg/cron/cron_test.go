@@ -0,0 +1,62 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_RejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"0 6 * *",           // too few fields
+		"60 6 * * *",        // minute out of range
+		"0 6 * * FUNDAY",    // unknown name
+		"0 18-6 * * *",      // backwards range
+		"*/0 * * * *",       // zero step
+		"0 6 * * MON-FRI X", // too many fields
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 6 * * MON-FRI", time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)},
+		{"30 9 * * sat,sun", time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)},
+		{"*/15 20 * * *", time.Date(2026, 10, 16, 20, 15, 0, 0, time.UTC)},
+		{"0 7 1 * *", time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC)},
+		{"0 7 1 * 0", time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)}, // either day field matches
+		{"0 7 * * 7", time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)}, // 7 is Sunday
+		{"0 0 29 FEB *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, schedule.Next(friday), tt.expr)
+	}
+
+	never, err := Parse("0 6 30 FEB *")
+	require.NoError(t, err)
+	assert.True(t, never.Next(friday).IsZero())
+}
+
+func TestSchedule_NextSkipsDaylightSavingGap(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	schedule, err := Parse("30 2 * * *")
+	require.NoError(t, err)
+
+	// 02:30 doesn't exist on 29 March 2026 in Berlin
+	got := schedule.NextN(time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), 2)
+	assert.Equal(t, []time.Time{
+		time.Date(2026, 3, 30, 2, 30, 0, 0, berlin),
+		time.Date(2026, 3, 31, 2, 30, 0, 0, berlin),
+	}, got)
+}
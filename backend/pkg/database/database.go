@@ -1,20 +1,69 @@
 package database
 
 import (
+	"fmt"
 	"heat-logger/internal/config"
 	"log"
+	"time"
 
 	"heat-logger/internal/models"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-// InitDatabase initializes the database connection and runs migrations
+// LogDB keeps prediction and audit logs when they are routed to a SQLite file of their own;
+// nil while they share DB
+var LogDB *gorm.DB
+
+// queryMetrics collects per-caller query statistics for the metrics endpoint
+var queryMetrics *QueryMetrics
+
+// InitDatabase initializes the database connection and runs migrations. A database that isn't
+// available yet, e.g. on a NAS that is still mounting, is retried with backoff for the
+// configured startup timeout; with the wait policy it is retried until it comes up.
 func InitDatabase(cfg *config.Config) error {
+	startup := cfg.Database.Startup
+	deadline := time.Now().Add(startup.Timeout)
+	backoff := startup.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		err := openAndMigrate(cfg)
+		if err == nil {
+			break
+		}
+		if time.Now().Add(backoff).After(deadline) && startup.OnTimeout != config.StartupWait {
+			return err
+		}
+		log.Printf("Database not ready (attempt %d): %v; retrying in %s", attempt, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if startup.MaxBackoff > 0 && backoff > startup.MaxBackoff {
+			backoff = startup.MaxBackoff
+		}
+	}
+
+	// Migrate existing records to have 'global' as default UserID
+	if err := migrateExistingRecords(); err != nil {
+		log.Printf("Warning: Failed to migrate existing records: %v", err)
+	}
+
+	if err := openLogStore(cfg.Database.Logs); err != nil {
+		return fmt.Errorf("log store: %w", err)
+	}
+
+	log.Printf("Database initialized successfully at %s", cfg.Database.Path)
+	return nil
+}
+
+// openAndMigrate connects to the database and brings the schema up to date
+func openAndMigrate(cfg *config.Config) error {
 	var err error
 
 	// Connect to SQLite database
@@ -26,19 +75,117 @@ func InitDatabase(cfg *config.Config) error {
 		return err
 	}
 
+	queryMetrics = NewQueryMetrics(cfg.Database.SlowQueryThreshold, cfg.Metrics.Enabled)
+	if err := DB.Use(queryMetrics); err != nil {
+		return err
+	}
+
 	// Auto migrate the schema
-	err = DB.AutoMigrate(&models.DailyRecord{})
+	err = DB.AutoMigrate(
+		&models.DailyRecord{},
+		&models.ExportJob{},
+		&models.User{},
+		&models.AuditLog{},
+		&models.Annotation{},
+		&models.Preset{},
+		&models.Member{},
+		&models.Session{},
+		&models.HealthProbe{},
+		&models.RescoreJob{},
+		&models.PredictionAudit{},
+		&models.Instance{},
+		&models.SyncState{},
+		&models.BoilerProfile{},
+		&models.RecordRevision{},
+		&models.RecordTombstone{},
+		&models.ScheduleTemplate{},
+		&models.ScheduleException{},
+		&models.ScheduleOverride{},
+		&models.FeedbackPrompt{},
+		&models.Notification{},
+		&models.NotificationRoute{},
+		&models.PredictionLog{},
+		&models.Operation{},
+		&models.HouseholdMembership{},
+		&models.HouseholdInvite{},
+		&models.SavedReport{},
+		&models.ReportSubscription{},
+		&models.ImportMapping{},
+		&models.ContextBucketTree{},
+		&models.SanitationCycle{},
+		&models.Setup{},
+		&models.DemandResponseEvent{},
+		&models.CorrelationEvent{},
+	)
 	if err != nil {
-		return err
+		// Don't leak the connection of a failed attempt
+		if sqlDB, dbErr := DB.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
 	}
+	return err
+}
 
-	// Migrate existing records to have 'global' as default UserID
-	err = migrateExistingRecords()
+// logMoveBatch is how many log rows are moved to a new log store per statement
+const logMoveBatch = 500
+
+// openLogStore opens the SQLite file prediction and audit logs are routed to, if they are. The
+// first time, the logs kept so far move over from the operational database.
+func openLogStore(cfg config.LogStoreConfig) error {
+	LogDB = nil
+	if cfg.Store != config.LogStoreSQLite {
+		return nil
+	}
+
+	db, err := gorm.Open(sqlite.Open(cfg.Path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
 	if err != nil {
-		log.Printf("Warning: Failed to migrate existing records: %v", err)
+		return err
+	}
+	if err := db.Use(queryMetrics); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.PredictionLog{}, &models.AuditLog{}); err != nil {
+		return err
+	}
+	if err := moveLogs(db); err != nil {
+		return err
 	}
+	LogDB = db
+	log.Printf("Log store initialized at %s", cfg.Path)
+	return nil
+}
 
-	log.Printf("Database initialized successfully at %s", cfg.Database.Path)
+// moveLogs copies the logs in the operational database to the log store and deletes them
+// there. A batch is only deleted once it is stored, so an interrupted move resumes next start.
+func moveLogs(to *gorm.DB) error {
+	moved := 0
+	for _, table := range []string{"prediction_logs", "audit_logs"} {
+		for {
+			var rows []map[string]interface{}
+			if err := DB.Table(table).Order("created_at ASC").Limit(logMoveBatch).Find(&rows).Error; err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				break
+			}
+			ids := make([]interface{}, len(rows))
+			for i, row := range rows {
+				ids[i] = row["id"]
+			}
+			if err := to.Table(table).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+				return err
+			}
+			if err := DB.Exec("DELETE FROM "+table+" WHERE id IN ?", ids).Error; err != nil {
+				return err
+			}
+			moved += len(rows)
+		}
+	}
+	if moved > 0 {
+		log.Printf("Moved %d log entries to the log store", moved)
+	}
 	return nil
 }
 
@@ -57,7 +204,20 @@ func migrateExistingRecords() error {
 	return nil
 }
 
+// GetQueryMetrics returns the query metrics plugin, or nil before InitDatabase
+func GetQueryMetrics() *QueryMetrics {
+	return queryMetrics
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// GetLogDB returns the database prediction and audit logs are kept in
+func GetLogDB() *gorm.DB {
+	if LogDB != nil {
+		return LogDB
+	}
+	return DB
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "query_metrics:start"
+
+// QueryMetrics is a GORM plugin that counts queries, their durations and rows per caller,
+// and logs queries slower than a threshold together with the calling handler. Naming the
+// caller walks the stack, so it is only done for queries that are counted or logged.
+type QueryMetrics struct {
+	slowThreshold time.Duration
+	collect       bool
+
+	mu    sync.Mutex
+	stats map[queryKey]*queryStats
+}
+
+type queryKey struct {
+	caller    string
+	operation string
+}
+
+type queryStats struct {
+	count    uint64
+	duration time.Duration
+	rows     int64
+}
+
+// NewQueryMetrics creates the plugin, counting queries when collect is set. A zero threshold
+// disables the slow-query log.
+func NewQueryMetrics(slowThreshold time.Duration, collect bool) *QueryMetrics {
+	return &QueryMetrics{
+		slowThreshold: slowThreshold,
+		collect:       collect,
+		stats:         make(map[queryKey]*queryStats),
+	}
+}
+
+// Name implements gorm.Plugin
+func (m *QueryMetrics) Name() string {
+	return "query_metrics"
+}
+
+// Initialize implements gorm.Plugin by timing every callback chain
+func (m *QueryMetrics) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registrations := []error{
+		cb.Create().Before("gorm:create").Register("query_metrics:before_create", m.before),
+		cb.Create().After("gorm:create").Register("query_metrics:after_create", m.after("create")),
+		cb.Query().Before("gorm:query").Register("query_metrics:before_query", m.before),
+		cb.Query().After("gorm:query").Register("query_metrics:after_query", m.after("select")),
+		cb.Update().Before("gorm:update").Register("query_metrics:before_update", m.before),
+		cb.Update().After("gorm:update").Register("query_metrics:after_update", m.after("update")),
+		cb.Delete().Before("gorm:delete").Register("query_metrics:before_delete", m.before),
+		cb.Delete().After("gorm:delete").Register("query_metrics:after_delete", m.after("delete")),
+		cb.Row().Before("gorm:row").Register("query_metrics:before_row", m.before),
+		cb.Row().After("gorm:row").Register("query_metrics:after_row", m.after("row")),
+		cb.Raw().Before("gorm:raw").Register("query_metrics:before_raw", m.before),
+		cb.Raw().After("gorm:raw").Register("query_metrics:after_raw", m.after("raw")),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *QueryMetrics) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (m *QueryMetrics) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(value.(time.Time))
+		slow := m.slowThreshold > 0 && elapsed >= m.slowThreshold
+		if !m.collect && !slow {
+			return
+		}
+		caller := queryCaller()
+
+		if m.collect {
+			m.mu.Lock()
+			key := queryKey{caller: caller, operation: operation}
+			stats, ok := m.stats[key]
+			if !ok {
+				stats = &queryStats{}
+				m.stats[key] = stats
+			}
+			stats.count++
+			stats.duration += elapsed
+			stats.rows += db.Statement.RowsAffected
+			m.mu.Unlock()
+		}
+
+		if slow {
+			sql := db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)
+			log.Printf("Slow query (%s) from %s: %s", elapsed.Round(time.Microsecond), caller, sql)
+		}
+	}
+}
+
+// queryCaller names the code that issued the query: the outermost HTTP handler when the
+// query runs on the request goroutine, otherwise the outermost named service function
+// (e.g. a predictor running under a timeout, or a background worker).
+func queryCaller() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	handler, service := "", ""
+	for {
+		frame, more := frames.Next()
+		name := strings.TrimPrefix(frame.Function, "heat-logger/internal/")
+		isClosure := strings.Contains(name, ".func")
+		switch {
+		case strings.HasPrefix(name, "handler.") && !isClosure:
+			handler = name
+		case strings.HasPrefix(name, "services.") && !isClosure:
+			service = name
+		}
+		if !more {
+			break
+		}
+	}
+	switch {
+	case handler != "":
+		return handler
+	case service != "":
+		return service
+	default:
+		return "unknown"
+	}
+}
+
+// WritePrometheus writes the collected metrics in the Prometheus text exposition format
+func (m *QueryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	keys := make([]queryKey, 0, len(m.stats))
+	snapshot := make(map[queryKey]queryStats, len(m.stats))
+	for key, stats := range m.stats {
+		keys = append(keys, key)
+		snapshot[key] = *stats
+	}
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].caller != keys[j].caller {
+			return keys[i].caller < keys[j].caller
+		}
+		return keys[i].operation < keys[j].operation
+	})
+
+	metrics := []struct {
+		name, help, kind string
+		value            func(queryStats) string
+	}{
+		{"heat_logger_db_queries_total", "Database queries issued.", "counter",
+			func(s queryStats) string { return fmt.Sprintf("%d", s.count) }},
+		{"heat_logger_db_query_duration_seconds_total", "Time spent in database queries.", "counter",
+			func(s queryStats) string { return fmt.Sprintf("%g", s.duration.Seconds()) }},
+		{"heat_logger_db_rows_total", "Rows returned or affected by database queries.", "counter",
+			func(s queryStats) string { return fmt.Sprintf("%d", s.rows) }},
+	}
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			_, err := fmt.Fprintf(w, "%s{caller=%q,operation=%q} %s\n", metric.name, key.caller, key.operation, metric.value(snapshot[key]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
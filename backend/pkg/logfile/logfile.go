@@ -0,0 +1,266 @@
+// Package logfile writes logs to a file that rotates by size and age, compresses what it
+// rotated and deletes it again once there are too many rotated files or they are too old, so
+// a home server's SD card doesn't fill up.
+package logfile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"heat-logger/internal/config"
+)
+
+// stampLayout is the time a rotated file is named after; it sorts by time
+const stampLayout = "20060102-150405"
+
+// Options decide when a file rotates and how long rotated files are kept. A zero value rotates
+// by neither and keeps everything.
+type Options struct {
+	MaxBytes int64         // rotate before a write would take the file past this; 0 never rotates by size
+	Interval time.Duration // rotate on the first write of each interval, e.g. daily at midnight UTC; 0 never rotates by time
+	Compress bool          // gzip rotated files
+	MaxFiles int           // rotated files kept, the oldest are deleted first; 0 keeps any number
+	MaxAge   time.Duration // rotated files older than this are deleted; 0 keeps them
+}
+
+// OptionsFrom translates the rotation settings of the logging configuration
+func OptionsFrom(cfg config.LogRotationConfig) Options {
+	return Options{
+		MaxBytes: int64(cfg.MaxSizeMB) << 20,
+		Interval: cfg.Interval,
+		Compress: cfg.Compress,
+		MaxFiles: cfg.MaxFiles,
+		MaxAge:   cfg.MaxAge,
+	}
+}
+
+// File is an io.Writer appending to path. Rotated files are renamed after the time they were
+// rotated, e.g. server.log becomes server-20260316-071500.log, and compressed and pruned in
+// the background. A single write is never split across files, so each log line or JSONL
+// entry should be written at once.
+type File struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	lastSave time.Time // when the current file was last written, for time-based rotation
+
+	cleanupMu sync.Mutex // one rotation's files are compressed and pruned at a time
+	cleanup   sync.WaitGroup
+}
+
+// New creates a file writing path; the file is opened on the first write
+func New(path string, opts Options) *File {
+	return &File{path: path, opts: opts, now: time.Now}
+}
+
+// Path is the file entries are currently appended to
+func (f *File) Path() string {
+	return f.path
+}
+
+// Write appends p, rotating the file first when it is due
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	now := f.now()
+	if f.size > 0 && f.due(now, int64(len(p))) {
+		if err := f.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	f.lastSave = now
+	return n, err
+}
+
+// due reports whether writing n more bytes at now should start a new file
+func (f *File) due(now time.Time, n int64) bool {
+	if f.opts.MaxBytes > 0 && f.size+n > f.opts.MaxBytes {
+		return true
+	}
+	return f.opts.Interval > 0 && !now.Truncate(f.opts.Interval).Equal(f.lastSave.Truncate(f.opts.Interval))
+}
+
+// open opens the current file for appending, picking up its size and last write after a restart
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.lastSave = file, info.Size(), info.ModTime()
+	return nil
+}
+
+// rotate closes the current file, renames it after now and opens a new one. Compressing and
+// pruning run in the background so writers aren't held up.
+func (f *File) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	base, ext := f.split()
+	stamp := now.UTC().Format(stampLayout)
+	rotated := base + "-" + stamp + ext
+	// Two rotations in one second keep both files
+	for n := 2; exists(rotated) || exists(rotated+".gz"); n++ {
+		rotated = fmt.Sprintf("%s-%s-%d%s", base, stamp, n, ext)
+	}
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	f.cleanup.Add(1)
+	go func() {
+		defer f.cleanup.Done()
+		f.cleanupMu.Lock()
+		defer f.cleanupMu.Unlock()
+		if f.opts.Compress {
+			if err := compress(rotated); err != nil {
+				fmt.Fprintf(os.Stderr, "logfile: failed to compress %s: %v\n", rotated, err)
+			}
+		}
+		if err := f.prune(now); err != nil {
+			fmt.Fprintf(os.Stderr, "logfile: failed to prune rotated files of %s: %v\n", f.path, err)
+		}
+	}()
+	return f.open()
+}
+
+// split splits the path into what rotated names start and end with
+func (f *File) split() (string, string) {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext), ext
+}
+
+// Rotated lists the rotated files of the path, oldest first
+func (f *File) Rotated() ([]string, error) {
+	base, ext := f.split()
+	var rotated []string
+	order := map[string]string{} // the stamp and sequence number, which sort by age
+	for _, pattern := range []string{base + "-*" + ext, base + "-*" + ext + ".gz"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			// Only names with a stamp, so server.log doesn't claim server-debug.log
+			rest := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(match, base+"-"), ".gz"), ext)
+			stamp, n := rest, 1
+			if len(rest) > len(stampLayout) {
+				seq, err := strconv.Atoi(strings.TrimPrefix(rest[len(stampLayout):], "-"))
+				if rest[len(stampLayout)] != '-' || err != nil {
+					continue
+				}
+				stamp, n = rest[:len(stampLayout)], seq
+			}
+			if _, err := time.Parse(stampLayout, stamp); err != nil {
+				continue
+			}
+			rotated = append(rotated, match)
+			order[match] = fmt.Sprintf("%s-%09d", stamp, n)
+		}
+	}
+	sort.Slice(rotated, func(i, j int) bool { return order[rotated[i]] < order[rotated[j]] })
+	return rotated, nil
+}
+
+// prune deletes the rotated files past MaxFiles and those older than MaxAge
+func (f *File) prune(now time.Time) error {
+	rotated, err := f.Rotated()
+	if err != nil {
+		return err
+	}
+	for i, path := range rotated {
+		remove := f.opts.MaxFiles > 0 && i < len(rotated)-f.opts.MaxFiles
+		if !remove && f.opts.MaxAge > 0 {
+			info, err := os.Stat(path)
+			remove = err == nil && info.ModTime().Before(now.Add(-f.opts.MaxAge))
+		}
+		if remove {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close waits for rotated files to be compressed and pruned, then closes the current file
+func (f *File) Close() error {
+	f.cleanup.Wait()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// compress gzips path to path.gz, keeping its modification time, and removes path
+func compress(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(path)
+	zw.ModTime = info.ModTime()
+	_, err = io.Copy(zw, in)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := os.Chtimes(path+".gz", info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(path)
+}
+
+// exists reports whether something exists at path
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,79 @@
+package logfile
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_RotatesDailyAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 16, 23, 50, 0, 0, time.UTC)
+	file := New(filepath.Join(dir, "server.log"), Options{Interval: 24 * time.Hour, Compress: true})
+	file.now = func() time.Time { return now }
+
+	_, err := file.Write([]byte("monday\n"))
+	require.NoError(t, err)
+	now = now.Add(5 * time.Minute)
+	_, err = file.Write([]byte("still monday\n"))
+	require.NoError(t, err)
+	now = now.Add(10 * time.Minute)
+	_, err = file.Write([]byte("tuesday\n"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	rotated, err := file.Rotated()
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "server-20260317-000500.log.gz")}, rotated)
+	assert.Equal(t, "monday\nstill monday\n", gunzip(t, rotated[0]))
+	current, err := os.ReadFile(file.Path())
+	require.NoError(t, err)
+	assert.Equal(t, "tuesday\n", string(current))
+}
+
+func TestFile_PrunesRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC)
+	// Left by an earlier run: one too old to keep, and a log of another name
+	old := filepath.Join(dir, "server-20260101-000000.log")
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0o644))
+	require.NoError(t, os.Chtimes(old, now.AddDate(0, -2, 0), now.AddDate(0, -2, 0)))
+	other := filepath.Join(dir, "server-debug.log")
+	require.NoError(t, os.WriteFile(other, []byte("other\n"), 0o644))
+
+	file := New(filepath.Join(dir, "server.log"), Options{MaxBytes: 10, MaxFiles: 3, MaxAge: 30 * 24 * time.Hour})
+	file.now = func() time.Time { return now }
+	for i := 0; i < 4; i++ {
+		_, err := file.Write([]byte("line 0123\n")) // 10 bytes, a file each
+		require.NoError(t, err)
+	}
+	require.NoError(t, file.Close())
+
+	rotated, err := file.Rotated()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "server-20260316-120000.log"),
+		filepath.Join(dir, "server-20260316-120000-2.log"),
+		filepath.Join(dir, "server-20260316-120000-3.log"),
+	}, rotated, "the three newest are kept, but not the old one")
+	assert.FileExists(t, other)
+}
+
+// gunzip reads a compressed file
+func gunzip(t *testing.T, path string) string {
+	t.Helper()
+	in, err := os.Open(path)
+	require.NoError(t, err)
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return string(data)
+}
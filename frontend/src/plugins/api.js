@@ -2,7 +2,8 @@ import axios from 'axios';
 
 // Create axios instance with base configuration
 const api = axios.create({
-    baseURL: 'http://localhost:8080/api',
+    // Release builds are served by the backend itself, so they set VITE_API_BASE_URL=/api
+    baseURL: import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080/api',
     headers: {
         'Content-Type': 'application/json'
     }